@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/breez/lspd/audit"
+)
+
+// auditLog returns the audit log records of the intercepted htlcs, newest
+// first, filtered by the nodePubkey, paymentHash, peerId, from and to query
+// parameters. The payment hash matches htlcs with that payment hash and
+// probes of the registered payment. from and to are RFC3339 times. limit
+// caps the number of records, see audit.QueryLimit.
+func (s *adminServer) auditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := &audit.Filter{}
+	var err error
+	if p := query.Get("paymentHash"); p != "" {
+		filter.PaymentHash, err = hex.DecodeString(p)
+		if err != nil || len(filter.PaymentHash) != 32 {
+			http.Error(w, "invalid paymentHash", http.StatusBadRequest)
+			return
+		}
+	}
+	if p := query.Get("peerId"); p != "" {
+		filter.PeerID, err = hex.DecodeString(p)
+		if err != nil || len(filter.PeerID) != 33 {
+			http.Error(w, "invalid peerId", http.StatusBadRequest)
+			return
+		}
+	}
+	if f := query.Get("from"); f != "" {
+		filter.From, err = time.Parse(time.RFC3339, f)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid from: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if t := query.Get("to"); t != "" {
+		filter.To, err = time.Parse(time.RFC3339, t)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid to: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if l := query.Get("limit"); l != "" {
+		filter.Limit, err = strconv.Atoi(l)
+		if err != nil || filter.Limit <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	nodePubkey := query.Get("nodePubkey")
+	found := false
+	result := []*audit.Record{}
+	for _, l := range s.auditLogs {
+		if !inTenant(r, l.Node()) || nodePubkey != "" && l.Node().NodePubkey != nodePubkey {
+			continue
+		}
+
+		found = true
+		records, err := l.Query(r.Context(), filter)
+		if err != nil {
+			log.Printf("audit: Query() for %s error: %v", l.Node().Name, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		result = append(result, records...)
+	}
+
+	if !found {
+		http.Error(w, "audit log not enabled", http.StatusNotFound)
+		return
+	}
+
+	// Every node returned at most the limit, so the newest of all nodes are
+	// among them.
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].InterceptedAt.After(result[j].InterceptedAt)
+	})
+	limit := audit.QueryLimit(filter.Limit)
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	writeJson(w, "audit", result)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/breez/lspd/tenant"
+)
+
+const unixSocketPrefix = "unix:"
+
+// adminAuth requires the admin token as bearer token on every request to the
+// admin api.
+func adminAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminTenant runs the request in the context of the tenant in the tenant
+// query parameter, the default tenant without it. Admin routes only see the
+// nodes and the rows of that tenant.
+func adminTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("tenant")
+		if !tenant.Valid(id) {
+			http.Error(w, "invalid tenant", http.StatusBadRequest)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(tenant.NewContext(r.Context(), id)))
+	})
+}
+
+// adminListen listens on the admin address. An address starting with unix:
+// is a unix socket, an address without a host, like :8080, is bound to
+// localhost.
+func adminListen(address string) (net.Listener, error) {
+	if path, ok := unixSocketPath(address); ok {
+		// A socket left behind by a previous run would fail the listen.
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove socket %s: %w", path, err)
+		}
+
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only the user running lspd can connect.
+		err = os.Chmod(path, 0600)
+		if err != nil {
+			l.Close()
+			return nil, fmt.Errorf("failed to set the permissions of socket %s: %w", path, err)
+		}
+
+		return l, nil
+	}
+
+	return net.Listen("tcp", adminHostPort(address))
+}
+
+func unixSocketPath(address string) (string, bool) {
+	if !strings.HasPrefix(address, unixSocketPrefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(address, unixSocketPrefix), true
+}
+
+// adminHostPort returns the address with localhost as host if it has none.
+func adminHostPort(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host != "" {
+		return address
+	}
+
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// adminClient calls the admin api from the command line.
+type adminClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func newAdminClient(address string, token string, timeout time.Duration) *adminClient {
+	client := &http.Client{Timeout: timeout}
+	baseURL := "http://" + adminHostPort(address)
+	if path, ok := unixSocketPath(address); ok {
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+		baseURL = "http://lspd"
+	}
+
+	return &adminClient{
+		baseURL: baseURL,
+		token:   token,
+		client:  client,
+	}
+}
+
+func (c *adminClient) Get(path string) (*http.Response, error) {
+	return c.do(http.MethodGet, path, "", nil)
+}
+
+func (c *adminClient) Post(path string, contentType string, body io.Reader) (*http.Response, error) {
+	return c.do(http.MethodPost, path, contentType, body)
+}
+
+func (c *adminClient) do(method string, path string, contentType string, body io.Reader) (*http.Response, error) {
+	r, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	r.Header.Set("Authorization", "Bearer "+c.token)
+
+	return c.client.Do(r)
+}
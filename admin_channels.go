@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/breez/lspd/lifecycle"
+)
+
+type keepChannelRequest struct {
+	ChannelPoint string `json:"channelPoint"`
+	KeepOpen     bool   `json:"keepOpen"`
+}
+
+type closeChannelRequest struct {
+	ChannelPoint string `json:"channelPoint"`
+}
+
+type closeChannelResponse struct {
+	Txid string `json:"txid"`
+}
+
+// channels lists the channels opened by lspd, with their activity and whether
+// they are due to be closed.
+func (s *adminServer) channels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result := []*lifecycle.ChannelStatus{}
+	for _, t := range s.channelTrackers {
+		if !inTenant(r, t.Node()) {
+			continue
+		}
+
+		channels, err := t.Channels(r.Context())
+		if err != nil {
+			log.Printf("channels: Channels() for %s error: %v", t.Node().Name, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		result = append(result, channels...)
+	}
+
+	writeJson(w, "channels", result)
+}
+
+// keepChannel excludes a channel from automatic closes, or includes it again.
+func (s *adminServer) keepChannel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req keepChannelRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, t := range s.channelTrackers {
+		if !inTenant(r, t.Node()) {
+			continue
+		}
+
+		err = t.SetKeepOpen(r.Context(), req.ChannelPoint, req.KeepOpen)
+		if errors.Is(err, lifecycle.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Printf("channels: SetKeepOpen(%s) error: %v", req.ChannelPoint, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("channels: set keepOpen of channel %s to %v", req.ChannelPoint, req.KeepOpen)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	http.Error(w, "channel not found", http.StatusNotFound)
+}
+
+// closeChannel cooperatively closes a channel opened by lspd.
+func (s *adminServer) closeChannel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req closeChannelRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, t := range s.channelTrackers {
+		if !inTenant(r, t.Node()) {
+			continue
+		}
+
+		txid, err := t.Close(r.Context(), req.ChannelPoint)
+		if errors.Is(err, lifecycle.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeJson(w, "channels", &closeChannelResponse{Txid: txid})
+		return
+	}
+
+	http.Error(w, "channel not found", http.StatusNotFound)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+type reloadResult struct {
+	Nodes []string `json:"nodes"`
+}
+
+// reload reloads the nodes config, like SIGHUP. Nothing is changed if the new
+// config is invalid or changes settings that require a restart.
+func (s *adminServer) reload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := s.reloadConfig()
+	if err != nil {
+		log.Printf("reload: failed to reload the nodes config: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := reloadResult{Nodes: []string{}}
+	for _, node := range s.nodes {
+		if !inTenant(r, node) {
+			continue
+		}
+
+		result.Nodes = append(result.Nodes, node.Label())
+	}
+
+	writeJson(w, "reload", result)
+}
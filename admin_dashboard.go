@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/postgresql"
+)
+
+type dashboard struct {
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Nodes       []*nodeDashboard  `json:"nodes"`
+	Alerts      []*dashboardAlert `json:"alerts"`
+}
+
+type nodeDashboard struct {
+	Node                 string                           `json:"node"`
+	NodePubkey           string                           `json:"nodePubkey"`
+	InterceptorConnected bool                             `json:"interceptorConnected"`
+	PendingOpens         *pendingOpens                    `json:"pendingOpens"`
+	Today                *postgresql.ForwardingStats      `json:"today,omitempty"`
+	Wallet               *liquidityStatus                 `json:"wallet"`
+	CircuitBreaker       *interceptor.CircuitBreakerState `json:"circuitBreaker,omitempty"`
+	RecentHtlcs          []*interceptor.HtlcRecord        `json:"recentHtlcs"`
+}
+
+type pendingOpens struct {
+	// Channel opens currently in progress for intercepted htlcs.
+	InProgress int64 `json:"inProgress"`
+	// Channels opened that are not confirmed yet.
+	Unconfirmed int64 `json:"unconfirmed"`
+}
+
+type dashboardAlert struct {
+	Node    string `json:"node"`
+	Message string `json:"message"`
+}
+
+// dashboard returns the consolidated operational picture of all nodes in a
+// single call, for simple front-ends and terminal tools.
+func (s *adminServer) dashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	result := &dashboard{
+		GeneratedAt: now,
+		Nodes:       []*nodeDashboard{},
+		Alerts:      []*dashboardAlert{},
+	}
+	alert := func(node *config.NodeConfig, format string, a ...interface{}) {
+		result.Alerts = append(result.Alerts, &dashboardAlert{
+			Node:    node.Label(),
+			Message: fmt.Sprintf(format, a...),
+		})
+	}
+
+	for i, node := range s.nodes {
+		if !inTenant(r, node) {
+			continue
+		}
+
+		d := &nodeDashboard{
+			Node:         node.Name,
+			NodePubkey:   node.NodePubkey,
+			PendingOpens: &pendingOpens{},
+			RecentHtlcs:  []*interceptor.HtlcRecord{},
+			Wallet: &liquidityStatus{
+				Node:       node.Name,
+				NodePubkey: node.NodePubkey,
+			},
+		}
+		result.Nodes = append(result.Nodes, d)
+
+		if i < len(s.htlcInterceptors) {
+			d.InterceptorConnected = s.htlcInterceptors[i].Alive()
+		}
+		if !d.InterceptorConnected {
+			alert(node, "htlc interceptor is not connected")
+		}
+
+		if i < len(s.interceptors) {
+			d.PendingOpens.InProgress = s.interceptors[i].PendingOpens()
+			d.RecentHtlcs = s.interceptors[i].RecentHtlcs()
+		}
+
+		lspNodeID, _ := hex.DecodeString(node.NodePubkey)
+		unconfirmed, err := s.statsStore.UnconfirmedChannelCount(r.Context(), lspNodeID)
+		if err != nil {
+			log.Printf("dashboard: %v", err)
+			alert(node, "failed to get unconfirmed channels")
+		} else {
+			d.PendingOpens.Unconfirmed = unconfirmed
+		}
+
+		d.Today, err = s.statsStore.ForwardingStats(r.Context(), lspNodeID, midnight)
+		if err != nil {
+			log.Printf("dashboard: %v", err)
+			alert(node, "failed to get forwarding stats")
+		}
+
+		if m, ok := s.liquidityManagers[node.NodePubkey]; ok {
+			l, err := m.Liquidity()
+			if err != nil {
+				d.Wallet.Error = err.Error()
+				alert(node, "failed to get wallet balance: %v", err)
+			} else {
+				// The amount of a minimal jit channel open.
+				capacity := basetypes.Satoshi(node.AdditionalChannelCapacity)
+				d.Wallet.Liquidity = l
+				d.Wallet.AffordableOpens = l.AffordableOpens(capacity)
+				if d.Wallet.AffordableOpens == 0 {
+					alert(node, "on-chain balance is too low to open new channels")
+				}
+			}
+		}
+
+		for _, b := range s.breakers {
+			if b.Node() != node {
+				continue
+			}
+
+			// Use the state of the last check, the dashboard may be polled
+			// frequently.
+			state := b.State()
+			d.CircuitBreaker = &state
+			if state.Open {
+				alert(node, "circuit breaker is open: %s", state.Reason)
+			}
+		}
+	}
+
+	writeJson(w, "dashboard", result)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/notifications"
+	"github.com/breez/lspd/postgresql"
+)
+
+type nodeHtlcRecord struct {
+	Node string `json:"node"`
+	*interceptor.HtlcRecord
+}
+
+// paymentDebugBundle is everything known about a payment hash, for support
+// escalations. Decisions and notification attempts are only kept in memory
+// for the most recent htlcs.
+type paymentDebugBundle struct {
+	PaymentHash          string                               `json:"paymentHash"`
+	GeneratedAt          time.Time                            `json:"generatedAt"`
+	Stored               *postgresql.PaymentDebugInfo         `json:"stored"`
+	Decisions            []*nodeHtlcRecord                    `json:"decisions"`
+	NotificationAttempts []*notifications.NotificationAttempt `json:"notificationAttempts"`
+}
+
+// paymentDebug returns everything known about a payment hash as a json file,
+// with secrets like the payment secret, preimage and token redacted.
+func (s *adminServer) paymentDebug(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash := strings.ToLower(r.URL.Query().Get("paymentHash"))
+	paymentHash, err := hex.DecodeString(hash)
+	if err != nil || len(paymentHash) != 32 {
+		http.Error(w, "invalid paymentHash", http.StatusBadRequest)
+		return
+	}
+
+	stored, err := s.debugStore.PaymentDebugInfo(r.Context(), paymentHash)
+	if err != nil {
+		log.Printf("payments: PaymentDebugInfo(%x) error: %v", paymentHash, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	bundle := &paymentDebugBundle{
+		PaymentHash:          hash,
+		GeneratedAt:          time.Now().UTC(),
+		Stored:               stored,
+		Decisions:            []*nodeHtlcRecord{},
+		NotificationAttempts: []*notifications.NotificationAttempt{},
+	}
+	for _, i := range s.interceptors {
+		if !inTenant(r, i.Node()) {
+			continue
+		}
+
+		records := i.RecentHtlcs()
+		for j := len(records) - 1; j >= 0; j-- {
+			if records[j].PaymentHash == hash {
+				bundle.Decisions = append(bundle.Decisions, &nodeHtlcRecord{
+					Node:       i.Node().Name,
+					HtlcRecord: records[j],
+				})
+			}
+		}
+	}
+	if s.notificationService != nil {
+		bundle.NotificationAttempts = s.notificationService.Attempts(r.Context(), hash)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"payment-%s.json\"", hash))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(bundle)
+	if err != nil {
+		log.Printf("paymentDebug: failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/breez/lspd/postgresql"
+	"github.com/breez/lspd/tenant"
+)
+
+const exportDateLayout = "2006-01-02"
+
+// transactionFees looks up the fee paid by a funding transaction.
+type transactionFees interface {
+	TransactionFee(ctx context.Context, txid string) (int64, error)
+}
+
+type tokenRevenue struct {
+	Token            string `json:"token"`
+	ChannelCount     int64  `json:"channelCount"`
+	PaymentCount     int64  `json:"paymentCount"`
+	FeeCollectedMsat int64  `json:"feeCollectedMsat"`
+	OnchainFeeSat    int64  `json:"onchainFeeSat"`
+	NetMsat          int64  `json:"netMsat"`
+}
+
+type nodeExport struct {
+	Node          string                          `json:"node"`
+	NodePubkey    string                          `json:"nodePubkey"`
+	Totals        *postgresql.AccountingTotals    `json:"totals"`
+	OnchainFeeSat int64                           `json:"onchainFeeSat"`
+	Channels      []*postgresql.AccountingChannel `json:"channels"`
+	Tokens        []*tokenRevenue                 `json:"tokens"`
+}
+
+type financialExport struct {
+	From  time.Time     `json:"from"`
+	To    time.Time     `json:"to"`
+	Nodes []*nodeExport `json:"nodes"`
+}
+
+// export returns the channel opens, fees collected, on-chain costs and
+// revenue per token of all nodes in [from, to) for accounting, as json or as
+// one of the channels, tokens or summary reports in csv. from and to are
+// dates, defaulting to the previous calendar month.
+func (s *adminServer) export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, -1, 0)
+	var err error
+	if f := query.Get("from"); f != "" {
+		from, err = time.Parse(exportDateLayout, f)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid from: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if t := query.Get("to"); t != "" {
+		to, err = time.Parse(exportDateLayout, t)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid to: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	format := query.Get("format")
+	report := query.Get("report")
+	switch format {
+	case "", "json":
+	case "csv":
+		switch report {
+		case "":
+			report = "channels"
+		case "channels", "tokens", "summary":
+		default:
+			http.Error(w, "invalid report, expected channels, tokens or summary", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "invalid format, expected json or csv", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.buildExport(r.Context(), from, to)
+	if err != nil {
+		log.Printf("export: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if format != "csv" {
+		writeJson(w, "export", result)
+		return
+	}
+
+	filename := fmt.Sprintf("lspd-%s-%s-%s.csv", report, from.Format(exportDateLayout), to.Format(exportDateLayout))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	err = writeExportCsv(csv.NewWriter(w), report, result)
+	if err != nil {
+		log.Printf("export: failed to write csv: %v", err)
+	}
+}
+
+func (s *adminServer) buildExport(ctx context.Context, from, to time.Time) (*financialExport, error) {
+	result := &financialExport{
+		From:  from,
+		To:    to,
+		Nodes: []*nodeExport{},
+	}
+	for _, node := range s.nodes {
+		if node.Tenant != tenant.FromContext(ctx) {
+			continue
+		}
+
+		lspNodeID, _ := hex.DecodeString(node.NodePubkey)
+		totals, err := s.accountingStore.Totals(ctx, lspNodeID, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		channels, err := s.accountingStore.ChannelOpens(ctx, lspNodeID, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		n := &nodeExport{
+			Node:       node.Name,
+			NodePubkey: node.NodePubkey,
+			Totals:     totals,
+			Channels:   channels,
+			Tokens:     []*tokenRevenue{},
+		}
+		tokens := make(map[string]*tokenRevenue)
+		for _, c := range channels {
+			s.lookupOnchainFee(ctx, c)
+
+			t, ok := tokens[c.Token]
+			if !ok {
+				t = &tokenRevenue{Token: c.Token}
+				tokens[c.Token] = t
+				n.Tokens = append(n.Tokens, t)
+			}
+			t.ChannelCount++
+			t.PaymentCount += c.PaymentCount
+			t.FeeCollectedMsat += c.FeeCollectedMsat
+			if c.OnchainFeeSat != nil {
+				t.OnchainFeeSat += *c.OnchainFeeSat
+				n.OnchainFeeSat += *c.OnchainFeeSat
+			}
+		}
+		for _, t := range n.Tokens {
+			t.NetMsat = t.FeeCollectedMsat - t.OnchainFeeSat*1000
+		}
+		sort.Slice(n.Tokens, func(i, j int) bool {
+			return n.Tokens[i].NetMsat > n.Tokens[j].NetMsat
+		})
+
+		result.Nodes = append(result.Nodes, n)
+	}
+
+	return result, nil
+}
+
+// lookupOnchainFee sets the fee of the funding transaction of the channel.
+// Fees are cached, so repeated exports don't hit the fee source again.
+func (s *adminServer) lookupOnchainFee(ctx context.Context, c *postgresql.AccountingChannel) {
+	if s.transactionFees == nil {
+		return
+	}
+
+	txid := strings.Split(c.ChannelPoint, ":")[0]
+	s.onchainFeesMtx.Lock()
+	fee, ok := s.onchainFees[txid]
+	s.onchainFeesMtx.Unlock()
+	if !ok {
+		var err error
+		fee, err = s.transactionFees.TransactionFee(ctx, txid)
+		if err != nil {
+			log.Printf("export: TransactionFee(%s) error: %v", txid, err)
+			return
+		}
+
+		s.onchainFeesMtx.Lock()
+		s.onchainFees[txid] = fee
+		s.onchainFeesMtx.Unlock()
+	}
+
+	c.OnchainFeeSat = &fee
+}
+
+func writeExportCsv(w *csv.Writer, report string, result *financialExport) error {
+	i := strconv.FormatInt
+	switch report {
+	case "channels":
+		w.Write([]string{"node", "channel_point", "peer_id", "opened_at", "token", "payments", "fee_collected_msat", "onchain_fee_sat"})
+		for _, n := range result.Nodes {
+			for _, c := range n.Channels {
+				onchainFee := ""
+				if c.OnchainFeeSat != nil {
+					onchainFee = i(*c.OnchainFeeSat, 10)
+				}
+				w.Write([]string{n.Node, c.ChannelPoint, c.PeerID, c.OpenedAt.Format(time.RFC3339), c.Token,
+					i(c.PaymentCount, 10), i(c.FeeCollectedMsat, 10), onchainFee})
+			}
+		}
+	case "tokens":
+		w.Write([]string{"node", "token", "channels", "payments", "fee_collected_msat", "onchain_fee_sat", "net_msat"})
+		for _, n := range result.Nodes {
+			for _, t := range n.Tokens {
+				w.Write([]string{n.Node, t.Token, i(t.ChannelCount, 10), i(t.PaymentCount, 10),
+					i(t.FeeCollectedMsat, 10), i(t.OnchainFeeSat, 10), i(t.NetMsat, 10)})
+			}
+		}
+	case "summary":
+		w.Write([]string{"node", "from", "to", "channels", "opening_fee_msat", "forwards", "routing_fee_msat", "onchain_fee_sat"})
+		for _, n := range result.Nodes {
+			w.Write([]string{n.Node, result.From.Format(exportDateLayout), result.To.Format(exportDateLayout),
+				i(int64(len(n.Channels)), 10), i(n.Totals.OpeningFeeMsat, 10), i(n.Totals.ForwardCount, 10),
+				i(n.Totals.RoutingFeeMsat, 10), i(n.OnchainFeeSat, 10)})
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
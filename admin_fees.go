@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/breez/lspd/postgresql"
+	"github.com/breez/lspd/reconcile"
+)
+
+type nodeFeeReport struct {
+	Node       string `json:"node"`
+	NodePubkey string `json:"nodePubkey"`
+	*postgresql.FeeReport
+}
+
+// fees returns the fees deducted from htlcs of registered payments, the
+// credits owed to clients from whose payments more than the promised fee was
+// deducted, and the fees refunded for failed channel opens. The optional since
+// parameter is a duration, defaulting to 30 days.
+func (s *adminServer) fees(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	period := 30 * 24 * time.Hour
+	if p := r.URL.Query().Get("since"); p != "" {
+		d, err := time.ParseDuration(p)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid since: %v", err), http.StatusBadRequest)
+			return
+		}
+		period = d
+	}
+	since := time.Now().Add(-period)
+
+	result := []*nodeFeeReport{}
+	for _, node := range s.nodes {
+		if !inTenant(r, node) {
+			continue
+		}
+
+		lspNodeID, _ := hex.DecodeString(node.NodePubkey)
+		report, err := s.statsStore.FeeReport(r.Context(), lspNodeID, since)
+		if err != nil {
+			log.Printf("fees: FeeReport(%s) error: %v", node.Name, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		result = append(result, &nodeFeeReport{
+			Node:       node.Name,
+			NodePubkey: node.NodePubkey,
+			FeeReport:  report,
+		})
+	}
+
+	writeJson(w, "fees", result)
+}
+
+// reconciliation returns the last reconciliation of the forwards of the nodes
+// with reconciliation configured: the revenue per token and client, and the
+// payments a channel was opened for that were not forwarded with the
+// promised fee.
+func (s *adminServer) reconciliation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result := []*reconcile.Report{}
+	for _, reconciler := range s.reconcilers {
+		if !inTenant(r, reconciler.Node()) {
+			continue
+		}
+
+		if report := reconciler.Report(); report != nil {
+			result = append(result, report)
+		}
+	}
+
+	writeJson(w, "reconciliation", result)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/breez/lspd/macaroon"
+)
+
+type macaroonStatus struct {
+	ID         string     `json:"id"`
+	NodePubkey string     `json:"nodePubkey"`
+	Token      string     `json:"token"`
+	Name       string     `json:"name"`
+	Caveats    []string   `json:"caveats"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	Active     bool       `json:"active"`
+
+	// The macaroon itself is only returned when it's minted.
+	Macaroon string `json:"macaroon,omitempty"`
+}
+
+type mintMacaroonRequest struct {
+	// The token, from the config or stored in the database, the macaroon
+	// authenticates as.
+	Token string `json:"token"`
+	Name  string `json:"name"`
+	// Time in seconds the macaroon is valid, unlimited if zero.
+	Expiry        int64    `json:"expiry"`
+	Methods       []string `json:"methods,omitempty"`
+	MaxChannelSat int64    `json:"maxChannelSat,string,omitempty"`
+	Destinations  []string `json:"destinations,omitempty"`
+}
+
+type revokeMacaroonRequest struct {
+	ID string `json:"id"`
+}
+
+// macaroons lists all minted macaroons on GET, and mints a new macaroon on
+// POST.
+func (s *adminServer) macaroons(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listMacaroons(w, r)
+	case http.MethodPost:
+		s.mintMacaroon(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *adminServer) listMacaroons(w http.ResponseWriter, r *http.Request) {
+	records, err := s.macaroonAuthority.List(r.Context())
+	if err != nil {
+		log.Printf("macaroons: List() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	result := []*macaroonStatus{}
+	for _, record := range records {
+		result = append(result, newMacaroonStatus(record))
+	}
+
+	writeJson(w, "macaroons", result)
+}
+
+func (s *adminServer) mintMacaroon(w http.ResponseWriter, r *http.Request) {
+	var req mintMacaroonRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.Expiry < 0 || req.MaxChannelSat < 0 {
+		http.Error(w, "expiry and maxChannelSat cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	lspNodeID, err := s.tokenNodeID(r, req.Token)
+	if err != nil {
+		log.Printf("macaroons: tokenNodeID() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if lspNodeID == nil {
+		http.Error(w, "token not found or disabled", http.StatusNotFound)
+		return
+	}
+
+	var caveats []string
+	if req.Expiry > 0 {
+		caveats = append(caveats, macaroon.Expires(time.Now().Add(time.Duration(req.Expiry)*time.Second)))
+	}
+	if len(req.Methods) > 0 {
+		caveats = append(caveats, macaroon.Methods(req.Methods...))
+	}
+	if req.MaxChannelSat > 0 {
+		caveats = append(caveats, macaroon.MaxChannelSat(req.MaxChannelSat))
+	}
+	if len(req.Destinations) > 0 {
+		caveats = append(caveats, macaroon.Destinations(req.Destinations...))
+	}
+
+	// Mint checks the caveats as well, but invalid caveats are a bad
+	// request rather than an internal error.
+	_, err = macaroon.ParseCaveats(caveats)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	m, record, err := s.macaroonAuthority.Mint(r.Context(), lspNodeID, req.Token, req.Name, caveats...)
+	if err != nil {
+		log.Printf("macaroons: Mint() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("macaroons: minted macaroon %x '%s' for node %x", record.ID, record.Name, lspNodeID)
+	status := newMacaroonStatus(record)
+	status.Macaroon = m
+	writeJson(w, "macaroons", status)
+}
+
+func (s *adminServer) revokeMacaroon(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req revokeMacaroonRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	id, err := hex.DecodeString(req.ID)
+	if err != nil || len(id) == 0 {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	err = s.macaroonAuthority.Revoke(r.Context(), id)
+	if errors.Is(err, macaroon.ErrNotFound) {
+		http.Error(w, "macaroon not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("macaroons: Revoke() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("macaroons: revoked macaroon %x", id)
+	w.WriteHeader(http.StatusOK)
+}
+
+// tokenNodeID returns the pubkey of the node of a token in the config, or of
+// an active token stored in the database. Returns nil if there is no such
+// token.
+func (s *adminServer) tokenNodeID(r *http.Request, token string) ([]byte, error) {
+	for _, node := range s.nodes {
+		if !inTenant(r, node) {
+			continue
+		}
+
+		for _, t := range node.Tokens {
+			if t == token {
+				return hex.DecodeString(node.NodePubkey)
+			}
+		}
+	}
+
+	t, err := s.tokenStore.Get(r.Context(), token)
+	if err != nil {
+		return nil, fmt.Errorf("tokenStore.Get() error: %w", err)
+	}
+	if t == nil || !t.Active() {
+		return nil, nil
+	}
+
+	return t.LspNodeID, nil
+}
+
+func newMacaroonStatus(r *macaroon.Record) *macaroonStatus {
+	caveats := r.Caveats
+	if caveats == nil {
+		caveats = []string{}
+	}
+
+	return &macaroonStatus{
+		ID:         hex.EncodeToString(r.ID),
+		NodePubkey: hex.EncodeToString(r.LspNodeID),
+		Token:      r.Token,
+		Name:       r.Name,
+		Caveats:    caveats,
+		CreatedAt:  r.CreatedAt,
+		RevokedAt:  r.RevokedAt,
+		Active:     r.RevokedAt == nil || time.Now().Before(*r.RevokedAt),
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+type cancelPaymentRequest struct {
+	PaymentHash string `json:"paymentHash"`
+}
+
+// cancelPayment deletes a payment registration for which no channel was
+// opened yet, and fails the htlcs held for it.
+func (s *adminServer) cancelPayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req cancelPaymentRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	paymentHash, err := hex.DecodeString(req.PaymentHash)
+	if err != nil || len(paymentHash) != 32 {
+		http.Error(w, "invalid paymentHash", http.StatusBadRequest)
+		return
+	}
+
+	cancelled, err := s.interceptStore.CancelPayment(r.Context(), paymentHash, nil)
+	if err != nil {
+		log.Printf("payments: interceptStore.CancelPayment() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if !cancelled {
+		http.Error(w, "payment not found or channel already opened", http.StatusNotFound)
+		return
+	}
+
+	for _, i := range s.interceptors {
+		if !inTenant(r, i.Node()) {
+			continue
+		}
+
+		i.RegistrationCancelled(paymentHash)
+	}
+
+	log.Printf("payments: cancelled registration %x", paymentHash)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/breez/lspd/funding"
+)
+
+type signPsbtRequest struct {
+	ID string `json:"id"`
+	// The funded and signed psbt, base64 encoded.
+	Psbt []byte `json:"psbt"`
+}
+
+type rejectPsbtRequest struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// psbtFundings lists the channel opens awaiting a signed funding psbt, and
+// the recently completed ones.
+func (s *adminServer) psbtFundings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result := []*funding.PsbtFunding{}
+	for _, c := range s.psbtCoordinators {
+		if !inTenant(r, c.Node()) {
+			continue
+		}
+
+		result = append(result, c.List()...)
+	}
+
+	writeJson(w, "psbt", result)
+}
+
+func (s *adminServer) signPsbtFunding(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req signPsbtRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Psbt) == 0 {
+		http.Error(w, "psbt is required", http.StatusBadRequest)
+		return
+	}
+
+	s.resolvePsbtFunding(w, r, req.ID, func(c *funding.Coordinator) error {
+		return c.Submit(req.ID, req.Psbt)
+	})
+}
+
+func (s *adminServer) rejectPsbtFunding(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req rejectPsbtRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	s.resolvePsbtFunding(w, r, req.ID, func(c *funding.Coordinator) error {
+		return c.Reject(req.ID, req.Reason)
+	})
+}
+
+func (s *adminServer) resolvePsbtFunding(w http.ResponseWriter, r *http.Request, id string, resolve func(c *funding.Coordinator) error) {
+	for _, c := range s.psbtCoordinators {
+		if !inTenant(r, c.Node()) {
+			continue
+		}
+
+		err := resolve(c)
+		if errors.Is(err, funding.ErrNotFound) {
+			continue
+		}
+		if errors.Is(err, funding.ErrNotPending) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("psbt: failed to resolve psbt funding %s: %v", id, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	http.Error(w, "psbt funding not found", http.StatusNotFound)
+}
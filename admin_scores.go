@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/breez/lspd/scoring"
+)
+
+type resetScoreRequest struct {
+	// Pubkey of the lsp node. Resets the score on all nodes if empty.
+	NodePubkey string `json:"nodePubkey"`
+	PeerID     string `json:"peerId"`
+}
+
+// scores lists the scores of the clients with recent events, lowest first
+// per node.
+func (s *adminServer) scores(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result := []*scoring.Score{}
+	for _, scorer := range s.scorers {
+		if !inTenant(r, scorer.Node()) {
+			continue
+		}
+
+		scores, err := scorer.Scores(r.Context())
+		if err != nil {
+			log.Printf("scores: Scores() for %s error: %v", scorer.Node().Name, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		result = append(result, scores...)
+	}
+
+	writeJson(w, "scores", result)
+}
+
+// resetScore resets the score of a client to the maximum, so it is no longer
+// denied service.
+func (s *adminServer) resetScore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req resetScoreRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	peerID, err := hex.DecodeString(req.PeerID)
+	if err != nil || len(peerID) != 33 {
+		http.Error(w, "invalid peerId", http.StatusBadRequest)
+		return
+	}
+
+	found := false
+	for _, scorer := range s.scorers {
+		if !inTenant(r, scorer.Node()) || req.NodePubkey != "" && scorer.Node().NodePubkey != req.NodePubkey {
+			continue
+		}
+
+		found = true
+		err = scorer.Reset(r.Context(), peerID)
+		if err != nil {
+			log.Printf("scores: Reset(%s) for %s error: %v", req.PeerID, scorer.Node().Name, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if !found {
+		http.Error(w, "node not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/breez/lspd/audit"
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/funding"
+	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/lifecycle"
+	"github.com/breez/lspd/liquidity"
+	"github.com/breez/lspd/macaroon"
+	"github.com/breez/lspd/notifications"
+	"github.com/breez/lspd/postgresql"
+	"github.com/breez/lspd/reconcile"
+	"github.com/breez/lspd/scoring"
+	"github.com/breez/lspd/tenant"
+	"github.com/breez/lspd/tokens"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type adminServer struct {
+	address             string
+	token               string
+	nodes               []*config.NodeConfig
+	reloadConfig        func() error
+	htlcInterceptors    []interceptor.HtlcInterceptor
+	interceptors        []*interceptor.Interceptor
+	breakers            []*interceptor.CircuitBreaker
+	liquidityManagers   map[string]*liquidity.Manager
+	interceptStore      interceptor.InterceptStore
+	tokenStore          tokens.Store
+	macaroonAuthority   *macaroon.Authority
+	statsStore          *postgresql.StatsStore
+	psbtCoordinators    []*funding.Coordinator
+	channelTrackers     []*lifecycle.Tracker
+	reconcilers         []*reconcile.Reconciler
+	debugStore          *postgresql.DebugStore
+	notificationService *notifications.NotificationService
+	accountingStore     *postgresql.AccountingStore
+	transactionFees     transactionFees
+	scorers             []*scoring.Scorer
+	auditLogs           []*audit.Log
+	onchainFees         map[string]int64
+	onchainFeesMtx      sync.Mutex
+	srv                 *http.Server
+}
+
+type circuitBreakerStatus struct {
+	Node       string                          `json:"node"`
+	NodePubkey string                          `json:"nodePubkey"`
+	State      interceptor.CircuitBreakerState `json:"state"`
+}
+
+type liquidityStatus struct {
+	Node            string               `json:"node"`
+	NodePubkey      string               `json:"nodePubkey"`
+	Liquidity       *liquidity.Liquidity `json:"liquidity,omitempty"`
+	AffordableOpens uint64               `json:"affordableOpens"`
+	Error           string               `json:"error,omitempty"`
+}
+
+func NewAdminServer(
+	address string,
+	token string,
+	nodes []*config.NodeConfig,
+	reloadConfig func() error,
+	htlcInterceptors []interceptor.HtlcInterceptor,
+	interceptors []*interceptor.Interceptor,
+	breakers []*interceptor.CircuitBreaker,
+	liquidityManagers map[string]*liquidity.Manager,
+	interceptStore interceptor.InterceptStore,
+	tokenStore tokens.Store,
+	macaroons *macaroon.Authority,
+	statsStore *postgresql.StatsStore,
+	psbtCoordinators []*funding.Coordinator,
+	channelTrackers []*lifecycle.Tracker,
+	reconcilers []*reconcile.Reconciler,
+	debugStore *postgresql.DebugStore,
+	notificationService *notifications.NotificationService,
+	accountingStore *postgresql.AccountingStore,
+	transactionFees transactionFees,
+	scorers []*scoring.Scorer,
+	auditLogs []*audit.Log,
+) *adminServer {
+	s := &adminServer{
+		address:             address,
+		token:               token,
+		nodes:               nodes,
+		reloadConfig:        reloadConfig,
+		htlcInterceptors:    htlcInterceptors,
+		interceptors:        interceptors,
+		breakers:            breakers,
+		liquidityManagers:   liquidityManagers,
+		interceptStore:      interceptStore,
+		tokenStore:          tokenStore,
+		macaroonAuthority:   macaroons,
+		statsStore:          statsStore,
+		psbtCoordinators:    psbtCoordinators,
+		channelTrackers:     channelTrackers,
+		reconcilers:         reconcilers,
+		debugStore:          debugStore,
+		notificationService: notificationService,
+		accountingStore:     accountingStore,
+		transactionFees:     transactionFees,
+		scorers:             scorers,
+		auditLogs:           auditLogs,
+		onchainFees:         make(map[string]int64),
+	}
+
+	// The server is created here, so Stop shuts it down also when it runs
+	// before Start.
+	s.srv = &http.Server{
+		Handler: adminAuth(token, adminTenant(s.routes())),
+	}
+	return s
+}
+
+func (s *adminServer) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/circuitbreaker", s.circuitBreaker)
+	mux.HandleFunc("/liquidity", s.liquidity)
+	mux.HandleFunc("/dashboard", s.dashboard)
+	mux.HandleFunc("/tokens", s.tokens)
+	mux.HandleFunc("/tokens/rotate", s.rotateToken)
+	mux.HandleFunc("/tokens/disable", s.disableToken)
+	mux.HandleFunc("/macaroons", s.macaroons)
+	mux.HandleFunc("/macaroons/revoke", s.revokeMacaroon)
+	mux.HandleFunc("/payments/cancel", s.cancelPayment)
+	mux.HandleFunc("/payments/debug", s.paymentDebug)
+	mux.HandleFunc("/psbt", s.psbtFundings)
+	mux.HandleFunc("/psbt/sign", s.signPsbtFunding)
+	mux.HandleFunc("/psbt/reject", s.rejectPsbtFunding)
+	mux.HandleFunc("/fees", s.fees)
+	mux.HandleFunc("/reconciliation", s.reconciliation)
+	mux.HandleFunc("/export", s.export)
+	mux.HandleFunc("/channels", s.channels)
+	mux.HandleFunc("/channels/keep", s.keepChannel)
+	mux.HandleFunc("/channels/close", s.closeChannel)
+	mux.HandleFunc("/scores", s.scores)
+	mux.HandleFunc("/scores/reset", s.resetScore)
+	mux.HandleFunc("/audit", s.auditLog)
+	mux.HandleFunc("/config/reload", s.reload)
+	return mux
+}
+
+func (s *adminServer) Start() error {
+	l, err := adminListen(s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %v", err)
+	}
+
+	err = s.srv.Serve(l)
+	if err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("failed to serve: %v", err)
+	}
+
+	return nil
+}
+
+func (s *adminServer) Stop() {
+	s.srv.Shutdown(context.Background())
+}
+
+func (s *adminServer) circuitBreaker(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var result []*circuitBreakerStatus
+	for _, b := range s.breakers {
+		if !inTenant(r, b.Node()) {
+			continue
+		}
+
+		result = append(result, &circuitBreakerStatus{
+			Node:       b.Node().Name,
+			NodePubkey: b.Node().NodePubkey,
+			State:      b.State(),
+		})
+	}
+
+	writeJson(w, "circuitBreaker", result)
+}
+
+func (s *adminServer) liquidity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var result []*liquidityStatus
+	for _, m := range s.liquidityManagers {
+		node := m.Node()
+		if !inTenant(r, node) {
+			continue
+		}
+
+		status := &liquidityStatus{
+			Node:       node.Name,
+			NodePubkey: node.NodePubkey,
+		}
+		l, err := m.Liquidity()
+		if err != nil {
+			status.Error = err.Error()
+		} else {
+			// The amount of a minimal jit channel open.
+			capacity := basetypes.Satoshi(node.AdditionalChannelCapacity)
+			status.Liquidity = l
+			status.AffordableOpens = l.AffordableOpens(capacity)
+		}
+
+		result = append(result, status)
+	}
+
+	writeJson(w, "liquidity", result)
+}
+
+// inTenant returns whether the node belongs to the tenant of the request.
+func inTenant(r *http.Request, node *config.NodeConfig) bool {
+	return node.Tenant == tenant.FromContext(r.Context())
+}
+
+func writeJson(w http.ResponseWriter, name string, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(result)
+	if err != nil {
+		log.Printf("%s: failed to encode response: %v", name, err)
+	}
+}
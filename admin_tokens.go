@@ -0,0 +1,317 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/tenant"
+	"github.com/breez/lspd/tokens"
+)
+
+type tokenFeeParams struct {
+	// Validity of the fee params in seconds.
+	Validity             int64  `json:"validity"`
+	MinMsat              uint64 `json:"minMsat,string"`
+	Proportional         uint32 `json:"proportional"`
+	MaxIdleTime          uint32 `json:"maxIdleTime"`
+	MaxClientToSelfDelay uint32 `json:"maxClientToSelfDelay"`
+}
+
+type tokenStatus struct {
+	Token                     string            `json:"token"`
+	NodePubkey                string            `json:"nodePubkey"`
+	Tenant                    string            `json:"tenant,omitempty"`
+	Name                      string            `json:"name"`
+	AdditionalChannelCapacity *int64            `json:"additionalChannelCapacity,omitempty"`
+	TimeLockDelta             *uint32           `json:"timeLockDelta,omitempty"`
+	TaprootChannels           *bool             `json:"taprootChannels,omitempty"`
+	PublicChannels            *bool             `json:"publicChannels,omitempty"`
+	CommitmentType            *string           `json:"commitmentType,omitempty"`
+	FeeParams                 []*tokenFeeParams `json:"feeParams"`
+	CreatedAt                 time.Time         `json:"createdAt"`
+	DisabledAt                *time.Time        `json:"disabledAt,omitempty"`
+	Active                    bool              `json:"active"`
+}
+
+type createTokenRequest struct {
+	NodePubkey                string            `json:"nodePubkey"`
+	Name                      string            `json:"name"`
+	AdditionalChannelCapacity *int64            `json:"additionalChannelCapacity,omitempty"`
+	TimeLockDelta             *uint32           `json:"timeLockDelta,omitempty"`
+	TaprootChannels           *bool             `json:"taprootChannels,omitempty"`
+	PublicChannels            *bool             `json:"publicChannels,omitempty"`
+	CommitmentType            *string           `json:"commitmentType,omitempty"`
+	FeeParams                 []*tokenFeeParams `json:"feeParams"`
+}
+
+type rotateTokenRequest struct {
+	Token string `json:"token"`
+	// Time in seconds the old token remains valid after rotation, so
+	// partners can switch to the new token without downtime.
+	GracePeriod int64 `json:"gracePeriod"`
+}
+
+type disableTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// tokens lists the tokens of the tenant stored in the database on GET, and
+// creates a new token on POST.
+func (s *adminServer) tokens(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listTokens(w, r)
+	case http.MethodPost:
+		s.createToken(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *adminServer) listTokens(w http.ResponseWriter, r *http.Request) {
+	ts, err := s.tokenStore.List(r.Context())
+	if err != nil {
+		log.Printf("tokens: tokenStore.List() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	result := []*tokenStatus{}
+	for _, t := range ts {
+		status, err := s.tokenStatus(r, t)
+		if err != nil {
+			log.Printf("tokens: tokenStatus() error: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		result = append(result, status)
+	}
+
+	writeJson(w, "tokens", result)
+}
+
+func (s *adminServer) createToken(w http.ResponseWriter, r *http.Request) {
+	var req createTokenRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if !s.hasNode(r, req.NodePubkey) {
+		http.Error(w, "unknown nodePubkey", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.FeeParams) == 0 {
+		http.Error(w, "feeParams is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.CommitmentType != nil {
+		if _, err := lightning.ParseCommitmentType(*req.CommitmentType); err != nil || *req.CommitmentType == "" {
+			http.Error(w, "commitmentType must be taproot, anchors or static_remote_key", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var feeParams []*tokens.FeeParams
+	for _, p := range req.FeeParams {
+		if p.Validity <= 0 {
+			http.Error(w, "feeParams validity must be positive", http.StatusBadRequest)
+			return
+		}
+
+		feeParams = append(feeParams, &tokens.FeeParams{
+			Validity:             time.Duration(p.Validity) * time.Second,
+			MinMsat:              p.MinMsat,
+			Proportional:         p.Proportional,
+			MaxIdleTime:          p.MaxIdleTime,
+			MaxClientToSelfDelay: p.MaxClientToSelfDelay,
+		})
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.Printf("tokens: newToken() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	lspNodeID, _ := hex.DecodeString(req.NodePubkey)
+	t := &tokens.Token{
+		Token:                     token,
+		LspNodeID:                 lspNodeID,
+		Name:                      req.Name,
+		AdditionalChannelCapacity: req.AdditionalChannelCapacity,
+		TimeLockDelta:             req.TimeLockDelta,
+		TaprootChannels:           req.TaprootChannels,
+		PublicChannels:            req.PublicChannels,
+		CommitmentType:            req.CommitmentType,
+		CreatedAt:                 time.Now().UTC(),
+	}
+	err = s.tokenStore.Create(r.Context(), t, feeParams)
+	if err != nil {
+		log.Printf("tokens: tokenStore.Create() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("tokens: created token '%s' for node %s", t.Name, req.NodePubkey)
+	s.writeToken(w, r, token)
+}
+
+func (s *adminServer) rotateToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req rotateTokenRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if req.GracePeriod < 0 {
+		http.Error(w, "gracePeriod cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.Printf("tokens: newToken() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	disableAt := time.Now().Add(time.Duration(req.GracePeriod) * time.Second)
+	err = s.tokenStore.Rotate(r.Context(), req.Token, token, disableAt)
+	if errors.Is(err, tokens.ErrNotFound) {
+		http.Error(w, "token not found or disabled", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("tokens: tokenStore.Rotate() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("tokens: rotated token, old token disabled at %v", disableAt)
+	s.writeToken(w, r, token)
+}
+
+func (s *adminServer) disableToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req disableTokenRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	err = s.tokenStore.Disable(r.Context(), req.Token, time.Now())
+	if errors.Is(err, tokens.ErrNotFound) {
+		http.Error(w, "token not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("tokens: tokenStore.Disable() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("tokens: disabled token")
+	s.writeToken(w, r, req.Token)
+}
+
+func (s *adminServer) writeToken(w http.ResponseWriter, r *http.Request, token string) {
+	t, err := s.tokenStore.Get(r.Context(), token)
+	if err == nil && t == nil {
+		err = tokens.ErrNotFound
+	}
+	if err != nil {
+		log.Printf("tokens: tokenStore.Get() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	status, err := s.tokenStatus(r, t)
+	if err != nil {
+		log.Printf("tokens: tokenStatus() error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJson(w, "tokens", status)
+}
+
+func (s *adminServer) tokenStatus(r *http.Request, t *tokens.Token) (*tokenStatus, error) {
+	feeParams, err := s.tokenStore.GetFeeParams(r.Context(), t.Token)
+	if err != nil {
+		return nil, fmt.Errorf("GetFeeParams() error: %w", err)
+	}
+
+	status := &tokenStatus{
+		Token:                     t.Token,
+		NodePubkey:                hex.EncodeToString(t.LspNodeID),
+		Tenant:                    tenant.FromContext(r.Context()),
+		Name:                      t.Name,
+		AdditionalChannelCapacity: t.AdditionalChannelCapacity,
+		TimeLockDelta:             t.TimeLockDelta,
+		TaprootChannels:           t.TaprootChannels,
+		PublicChannels:            t.PublicChannels,
+		CommitmentType:            t.CommitmentType,
+		FeeParams:                 []*tokenFeeParams{},
+		CreatedAt:                 t.CreatedAt,
+		DisabledAt:                t.DisabledAt,
+		Active:                    t.Active(),
+	}
+	for _, p := range feeParams {
+		status.FeeParams = append(status.FeeParams, &tokenFeeParams{
+			Validity:             int64(p.Validity / time.Second),
+			MinMsat:              p.MinMsat,
+			Proportional:         p.Proportional,
+			MaxIdleTime:          p.MaxIdleTime,
+			MaxClientToSelfDelay: p.MaxClientToSelfDelay,
+		})
+	}
+
+	return status, nil
+}
+
+// hasNode returns whether the node with the pubkey is configured for the
+// tenant of the request.
+func (s *adminServer) hasNode(r *http.Request, pubkey string) bool {
+	for _, node := range s.nodes {
+		if node.NodePubkey == pubkey && inTenant(r, node) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newToken generates a random api token.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
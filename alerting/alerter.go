@@ -0,0 +1,311 @@
+// Package alerting pushes critical conditions of the nodes to the operator,
+// through Slack, Telegram or PagerDuty.
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/events"
+	"github.com/breez/lspd/lightning"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultInterval          = time.Minute
+	defaultMaxOpenFailures   = 3
+	defaultOpenFailureWindow = time.Hour
+	databasePingTimeout      = 10 * time.Second
+)
+
+// Conditions alerted about.
+const (
+	conditionStreamDown         = "interceptor_stream_down"
+	conditionDatabase           = "database_unreachable"
+	conditionOpenFailures       = "channel_open_failures"
+	conditionLowBalance         = "low_onchain_balance"
+	conditionFundingDoubleSpent = "funding_double_spent"
+)
+
+var alertFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_alert_failures_total",
+	Help: "Alerts that could not be pushed to a sink.",
+}, []string{"node", "sink"})
+
+// Alert is a critical condition of a node, or its resolution.
+type Alert struct {
+	Node string
+	// Identifies the condition, so an alert and its resolution can be
+	// matched.
+	Key      string
+	Message  string
+	Resolved bool
+	Time     time.Time
+}
+
+// StreamStatus tells whether the htlc interceptor stream with the node is
+// up.
+type StreamStatus interface {
+	Alive() bool
+}
+
+// Pinger checks whether the database is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// WalletBalance returns the on-chain balance of the node.
+type WalletBalance interface {
+	GetWalletBalance() (*lightning.GetWalletBalanceResult, error)
+}
+
+// Alerter checks the conditions of a node periodically, and pushes an alert
+// to the sinks when a condition starts, and a resolution when it ends.
+// Conditions are the htlc interceptor stream being down, an unreachable
+// database, too many failed channel opens and a low on-chain balance.
+// Double spent channel fundings are pushed right away.
+type Alerter struct {
+	sinks             []Sink
+	stream            StreamStatus
+	db                Pinger
+	wallet            WalletBalance
+	node              *config.NodeConfig
+	logger            *log.Logger
+	interval          time.Duration
+	minBalanceSat     uint64
+	maxOpenFailures   int
+	openFailureWindow time.Duration
+
+	// Conditions alerted about that didn't resolve yet, with their message.
+	firing map[string]string
+
+	failuresMtx  sync.Mutex
+	openFailures []time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	mtx    sync.Mutex
+}
+
+// NewAlerter returns the alerter of the node. Conditions of nil dependencies
+// are not checked.
+func NewAlerter(
+	stream StreamStatus,
+	db Pinger,
+	wallet WalletBalance,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) *Alerter {
+	cfg := node.Alerting
+	a := &Alerter{
+		stream:            stream,
+		db:                db,
+		wallet:            wallet,
+		node:              node,
+		logger:            logger,
+		interval:          parseDuration(cfg.Interval, defaultInterval, logger),
+		minBalanceSat:     cfg.MinOnchainBalanceSat,
+		maxOpenFailures:   defaultMaxOpenFailures,
+		openFailureWindow: parseDuration(cfg.OpenFailureWindow, defaultOpenFailureWindow, logger),
+		firing:            make(map[string]string),
+	}
+	if a.minBalanceSat == 0 {
+		a.minBalanceSat = uint64(node.MinOnchainReserveSat) + uint64(node.AdditionalChannelCapacity)
+	}
+	if cfg.MaxOpenFailures > 0 {
+		a.maxOpenFailures = cfg.MaxOpenFailures
+	}
+	if cfg.Slack != nil {
+		a.sinks = append(a.sinks, newSlackSink(cfg.Slack))
+	}
+	if cfg.Telegram != nil {
+		a.sinks = append(a.sinks, newTelegramSink(cfg.Telegram))
+	}
+	if cfg.PagerDuty != nil {
+		a.sinks = append(a.sinks, newPagerDutySink(cfg.PagerDuty))
+	}
+
+	return a
+}
+
+// Subscribe counts the failed channel opens of the node and alerts about
+// double spent channel fundings.
+func (a *Alerter) Subscribe(bus *events.Bus) {
+	bus.Subscribe("alerting_"+a.node.Label(), func(e *events.Event) {
+		if e.Node == nil || e.Node.NodePubkey != a.node.NodePubkey {
+			return
+		}
+
+		switch e.Kind {
+		case events.ChannelOpenFailed:
+			a.failuresMtx.Lock()
+			a.openFailures = append(a.openFailures, e.Time)
+			a.failuresMtx.Unlock()
+		case events.FundingDoubleSpent:
+			a.send(context.Background(), &Alert{
+				Node: a.node.Label(),
+				Key:  conditionFundingDoubleSpent + "/" + e.ChannelPoint,
+				Message: fmt.Sprintf(
+					"funding of channel %s with %x was double spent by transaction %s",
+					e.ChannelPoint,
+					e.PeerID,
+					e.SpendingTxID,
+				),
+				Time: e.Time,
+			})
+		}
+	}, events.ChannelOpenFailed, events.FundingDoubleSpent)
+}
+
+func (a *Alerter) Start() error {
+	a.mtx.Lock()
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+	ctx := a.ctx
+	a.mtx.Unlock()
+
+	var names []string
+	for _, s := range a.sinks {
+		names = append(names, s.Name())
+	}
+	a.logger.Printf("alerting: checking every %v, pushing alerts to %s", a.interval, strings.Join(names, ", "))
+
+	// The first check waits an interval, so the htlc interceptor stream
+	// has connected.
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(a.interval):
+		}
+		a.check(ctx, time.Now())
+	}
+}
+
+func (a *Alerter) Stop() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if a.cancel != nil {
+		a.cancel()
+	}
+}
+
+// check alerts about the conditions that started since the last check, and
+// resolves the conditions that ended.
+func (a *Alerter) check(ctx context.Context, now time.Time) {
+	conditions := a.conditions(ctx, now)
+	if ctx.Err() != nil {
+		return
+	}
+
+	for key, message := range conditions {
+		if _, ok := a.firing[key]; ok {
+			continue
+		}
+
+		a.firing[key] = message
+		a.send(ctx, &Alert{Node: a.node.Label(), Key: key, Message: message, Time: now})
+	}
+
+	for key, message := range a.firing {
+		if _, ok := conditions[key]; ok {
+			continue
+		}
+
+		delete(a.firing, key)
+		a.send(ctx, &Alert{Node: a.node.Label(), Key: key, Message: message, Resolved: true, Time: now})
+	}
+}
+
+// conditions returns the conditions that hold now, with their message.
+func (a *Alerter) conditions(ctx context.Context, now time.Time) map[string]string {
+	conditions := make(map[string]string)
+	if a.stream != nil && !a.stream.Alive() {
+		conditions[conditionStreamDown] = "htlc interceptor stream with the node is down"
+	}
+
+	if a.db != nil {
+		pingCtx, cancel := context.WithTimeout(ctx, databasePingTimeout)
+		err := a.db.Ping(pingCtx)
+		cancel()
+		if err != nil {
+			conditions[conditionDatabase] = fmt.Sprintf("database is unreachable: %v", err)
+		}
+	}
+
+	if n := a.recentOpenFailures(now); n >= a.maxOpenFailures {
+		conditions[conditionOpenFailures] = fmt.Sprintf("%d channel opens failed in the last %v", n, a.openFailureWindow)
+	}
+
+	if a.wallet != nil && a.minBalanceSat > 0 {
+		balance, err := a.wallet.GetWalletBalance()
+		if err != nil {
+			// Keep the last state while the balance is unknown.
+			a.logger.Printf("alerting: GetWalletBalance() error: %v", err)
+			if message, ok := a.firing[conditionLowBalance]; ok {
+				conditions[conditionLowBalance] = message
+			}
+		} else if uint64(balance.ConfirmedSat) < a.minBalanceSat {
+			conditions[conditionLowBalance] = fmt.Sprintf(
+				"confirmed on-chain balance of %v sat is below %v sat",
+				balance.ConfirmedSat,
+				a.minBalanceSat,
+			)
+		}
+	}
+
+	return conditions
+}
+
+// recentOpenFailures returns the number of channel opens that failed within
+// the window, and forgets the older failures.
+func (a *Alerter) recentOpenFailures(now time.Time) int {
+	a.failuresMtx.Lock()
+	defer a.failuresMtx.Unlock()
+	cutoff := now.Add(-a.openFailureWindow)
+	recent := a.openFailures[:0]
+	for _, t := range a.openFailures {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	a.openFailures = recent
+	return len(recent)
+}
+
+// send pushes the alert to every sink. Failures are logged, the alert is not
+// retried.
+func (a *Alerter) send(ctx context.Context, alert *Alert) {
+	if alert.Resolved {
+		a.logger.Printf("alerting: resolved: %s", alert.Message)
+	} else {
+		a.logger.Printf("alerting: ALERT: %s", alert.Message)
+	}
+
+	for _, s := range a.sinks {
+		err := s.Send(ctx, alert)
+		if err != nil {
+			alertFailures.WithLabelValues(a.node.Label(), s.Name()).Inc()
+			a.logger.Printf("alerting: failed to push alert to %s: %v", s.Name(), err)
+		}
+	}
+}
+
+func parseDuration(s string, def time.Duration, logger *log.Logger) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		logger.Printf("WARN: Invalid alerting duration '%s'. Using default %v", s, def)
+		return def
+	}
+
+	return d
+}
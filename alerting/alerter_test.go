@@ -0,0 +1,126 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSink struct {
+	alerts []*Alert
+}
+
+func (s *fakeSink) Name() string {
+	return "fake"
+}
+
+func (s *fakeSink) Send(_ context.Context, a *Alert) error {
+	s.alerts = append(s.alerts, a)
+	return nil
+}
+
+type fakeStream struct {
+	alive bool
+}
+
+func (s *fakeStream) Alive() bool {
+	return s.alive
+}
+
+type fakeWallet struct {
+	confirmedSat uint64
+	err          error
+}
+
+func (w *fakeWallet) GetWalletBalance() (*lightning.GetWalletBalanceResult, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	return &lightning.GetWalletBalanceResult{ConfirmedSat: basetypes.Satoshi(w.confirmedSat)}, nil
+}
+
+func TestAlertAndResolve(t *testing.T) {
+	node := &config.NodeConfig{
+		Name:     "node",
+		Alerting: &config.AlertingConfig{MinOnchainBalanceSat: 100_000, MaxOpenFailures: 2},
+	}
+	stream := &fakeStream{alive: true}
+	wallet := &fakeWallet{confirmedSat: 200_000}
+	a := NewAlerter(stream, nil, wallet, node, log.New(io.Discard, "", 0))
+	sink := &fakeSink{}
+	a.sinks = []Sink{sink}
+
+	now := time.Now()
+	a.check(context.Background(), now)
+	assert.Len(t, sink.alerts, 0)
+
+	// Alerted once while the stream is down, resolved when it's back.
+	stream.alive = false
+	a.check(context.Background(), now)
+	a.check(context.Background(), now)
+	assert.Len(t, sink.alerts, 1)
+	assert.Equal(t, conditionStreamDown, sink.alerts[0].Key)
+	assert.False(t, sink.alerts[0].Resolved)
+
+	stream.alive = true
+	a.check(context.Background(), now)
+	assert.Len(t, sink.alerts, 2)
+	assert.True(t, sink.alerts[1].Resolved)
+
+	// Low balance stays alerted while the balance is unknown.
+	wallet.confirmedSat = 50_000
+	a.check(context.Background(), now)
+	wallet.err = errors.New("node down")
+	a.check(context.Background(), now)
+	assert.Len(t, sink.alerts, 3)
+	assert.Equal(t, conditionLowBalance, sink.alerts[2].Key)
+
+	// Channel open failures are counted within the window.
+	wallet.err = nil
+	wallet.confirmedSat = 200_000
+	a.openFailures = []time.Time{now.Add(-2 * time.Hour), now.Add(-time.Minute)}
+	a.check(context.Background(), now)
+	assert.Len(t, sink.alerts, 4)
+	assert.True(t, sink.alerts[3].Resolved)
+	a.openFailures = append(a.openFailures, now)
+	a.check(context.Background(), now)
+	assert.Len(t, sink.alerts, 5)
+	assert.Equal(t, conditionOpenFailures, sink.alerts[4].Key)
+}
+
+func TestPagerDutyResolve(t *testing.T) {
+	var events []*pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e pagerDutyEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		events = append(events, &e)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	s := newPagerDutySink(&config.PagerDutyAlertConfig{RoutingKey: "key"})
+	s.eventsUrl = server.URL
+	alert := &Alert{Node: "node", Key: conditionStreamDown, Message: "down", Time: time.Now()}
+	assert.NoError(t, s.Send(context.Background(), alert))
+	alert.Resolved = true
+	assert.NoError(t, s.Send(context.Background(), alert))
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, "trigger", events[0].EventAction)
+	assert.Equal(t, "down", events[0].Payload.Summary)
+	assert.Equal(t, "resolve", events[1].EventAction)
+	assert.Nil(t, events[1].Payload)
+	assert.Equal(t, events[0].DedupKey, events[1].DedupKey)
+}
@@ -0,0 +1,66 @@
+package alerting
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/breez/lspd/config"
+)
+
+const pagerDutyEventsUrl = "https://events.pagerduty.com/v2/enqueue"
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary   string `json:"summary"`
+	Source    string `json:"source"`
+	Severity  string `json:"severity"`
+	Timestamp string `json:"timestamp"`
+}
+
+// pagerDutySink triggers and resolves PagerDuty incidents through the Events
+// API v2. The alert of a condition and its resolution share the dedup key,
+// so the resolution resolves the incident.
+type pagerDutySink struct {
+	eventsUrl  string
+	routingKey string
+	client     *http.Client
+}
+
+func newPagerDutySink(c *config.PagerDutyAlertConfig) *pagerDutySink {
+	return &pagerDutySink{
+		eventsUrl:  pagerDutyEventsUrl,
+		routingKey: c.RoutingKey,
+		client:     &http.Client{Timeout: sinkTimeout},
+	}
+}
+
+func (s *pagerDutySink) Name() string {
+	return "pagerduty"
+}
+
+func (s *pagerDutySink) Send(ctx context.Context, a *Alert) error {
+	event := &pagerDutyEvent{
+		RoutingKey:  s.routingKey,
+		EventAction: "trigger",
+		DedupKey:    "lspd/" + a.Node + "/" + a.Key,
+	}
+	if a.Resolved {
+		event.EventAction = "resolve"
+	} else {
+		event.Payload = &pagerDutyPayload{
+			Summary:   a.Message,
+			Source:    a.Node,
+			Severity:  "critical",
+			Timestamp: a.Time.UTC().Format(time.RFC3339),
+		}
+	}
+
+	return postJSON(ctx, s.client, s.eventsUrl, event)
+}
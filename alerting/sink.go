@@ -0,0 +1,53 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const sinkTimeout = 10 * time.Second
+
+// Sink delivers alerts to the operator.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, a *Alert) error
+}
+
+// text returns the alert as a single line message for chat sinks.
+func text(a *Alert) string {
+	if a.Resolved {
+		return fmt.Sprintf("RESOLVED [%s] %s", a.Node, a.Message)
+	}
+
+	return fmt.Sprintf("ALERT [%s] %s", a.Node, a.Message)
+}
+
+// postJSON posts the body as json to the url.
+func postJSON(ctx context.Context, client *http.Client, url string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package alerting
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/breez/lspd/config"
+)
+
+// slackSink posts alerts to a Slack channel through an incoming webhook.
+type slackSink struct {
+	webhookURL string
+	client     *http.Client
+}
+
+func newSlackSink(c *config.SlackAlertConfig) *slackSink {
+	return &slackSink{
+		webhookURL: c.WebhookURL,
+		client:     &http.Client{Timeout: sinkTimeout},
+	}
+}
+
+func (s *slackSink) Name() string {
+	return "slack"
+}
+
+func (s *slackSink) Send(ctx context.Context, a *Alert) error {
+	return postJSON(ctx, s.client, s.webhookURL, map[string]string{
+		"text": text(a),
+	})
+}
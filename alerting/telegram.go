@@ -0,0 +1,48 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/breez/lspd/config"
+)
+
+const telegramApiUrl = "https://api.telegram.org"
+
+// telegramSink posts alerts to a Telegram chat through the Bot API.
+type telegramSink struct {
+	apiUrl   string
+	botToken string
+	chatID   string
+	client   *http.Client
+}
+
+func newTelegramSink(c *config.TelegramAlertConfig) *telegramSink {
+	return &telegramSink{
+		apiUrl:   telegramApiUrl,
+		botToken: c.BotToken,
+		chatID:   c.ChatID,
+		client:   &http.Client{Timeout: sinkTimeout},
+	}
+}
+
+func (s *telegramSink) Name() string {
+	return "telegram"
+}
+
+func (s *telegramSink) Send(ctx context.Context, a *Alert) error {
+	err := postJSON(ctx, s.client, s.apiUrl+"/bot"+s.botToken+"/sendMessage", map[string]string{
+		"chat_id": s.chatID,
+		"text":    text(a),
+	})
+
+	// Keep the bot token in the url out of the logs.
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+
+	return err
+}
@@ -0,0 +1,269 @@
+// Package audit records every decision about an intercepted htlc in an
+// append-only log, to resolve disputes with wallet partners about what
+// happened to a payment.
+package audit
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/events"
+	"github.com/breez/lspd/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultRetention = 90 * 24 * time.Hour
+
+	// Records are written in batches every flush interval.
+	flushInterval = time.Second
+	pruneInterval = time.Hour
+
+	// Records waiting to be written. Further records are dropped until the
+	// database catches up.
+	maxPending = 10000
+
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+var droppedRecords = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_audit_log_dropped_total",
+	Help: "Htlc audit log records that could not be written.",
+}, []string{"node"})
+
+// Record is the decision about an intercepted htlc. Payment hashes and peer
+// ids are hex encoded.
+type Record struct {
+	Node           string    `json:"node"`
+	InterceptedAt  time.Time `json:"interceptedAt"`
+	PaymentHash    string    `json:"paymentHash"`
+	ShortChannelID string    `json:"scid"`
+	AmountMsat     int64     `json:"amountMsat"`
+	IncomingExpiry uint32    `json:"incomingExpiry"`
+	OutgoingExpiry uint32    `json:"outgoingExpiry"`
+	// Payment hash of the registered payment the htlc matched, differs from
+	// the payment hash of the htlc for probes. Empty if the htlc didn't
+	// match a registered payment.
+	RegisteredPaymentHash string `json:"registeredPaymentHash,omitempty"`
+	// The client the htlc was forwarded to, if any.
+	PeerID      string `json:"peerId,omitempty"`
+	Action      string `json:"action"`
+	FailureCode string `json:"failureCode,omitempty"`
+	// Why lspd failed the htlc, like latency_budget_exceeded.
+	FailureReason string `json:"failureReason,omitempty"`
+	ChannelPoint  string `json:"channelPoint,omitempty"`
+	// Whether a channel was opened for the htlc.
+	ChannelOpened bool `json:"channelOpened"`
+	// Time it took to decide about the htlc.
+	LatencyMicros int64 `json:"latencyMicros"`
+}
+
+// Filter selects records of a node. Unset fields match all records.
+type Filter struct {
+	PaymentHash []byte
+	PeerID      []byte
+	From        time.Time
+	To          time.Time
+	Limit       int
+}
+
+type Store interface {
+	Insert(ctx context.Context, lspNodeID []byte, records []*Record) error
+	// Query returns the records matching the filter, newest first.
+	Query(ctx context.Context, lspNodeID []byte, filter *Filter) ([]*Record, error)
+	// DeleteBefore deletes the records intercepted before the given time.
+	DeleteBefore(ctx context.Context, lspNodeID []byte, before time.Time) (int64, error)
+}
+
+// Log writes the htlcs intercepted on a node, as published on the event
+// bus, to the audit log, and deletes records older than the retention.
+type Log struct {
+	store     Store
+	node      *config.NodeConfig
+	logger    *log.Logger
+	lspNodeID []byte
+	retention time.Duration
+	pending   []*Record
+	ctx       context.Context
+	cancel    context.CancelFunc
+	mtx       sync.Mutex
+}
+
+func NewLog(store Store, node *config.NodeConfig, logger *log.Logger) *Log {
+	lspNodeID, _ := hex.DecodeString(node.NodePubkey)
+	l := &Log{
+		store:     store,
+		node:      node,
+		logger:    logger,
+		lspNodeID: lspNodeID,
+		retention: defaultRetention,
+	}
+
+	if node.AuditLog != nil {
+		l.retention = parseDuration(node.AuditLog.Retention, defaultRetention, logger)
+	}
+
+	return l
+}
+
+func parseDuration(s string, def time.Duration, logger *log.Logger) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		logger.Printf("WARN: Invalid audit log duration '%s'. Using default %v", s, def)
+		return def
+	}
+
+	return d
+}
+
+func (l *Log) Node() *config.NodeConfig {
+	return l.node
+}
+
+// Subscribe records the htlcs intercepted on the node of the log.
+func (l *Log) Subscribe(bus *events.Bus) {
+	bus.Subscribe("audit_log_"+l.node.Label(), func(e *events.Event) {
+		if e.Node == nil || e.Node.NodePubkey != l.node.NodePubkey {
+			return
+		}
+
+		l.add(newRecord(l.node, e))
+	}, events.HtlcIntercepted)
+}
+
+func newRecord(node *config.NodeConfig, e *events.Event) *Record {
+	return &Record{
+		Node:                  node.Name,
+		InterceptedAt:         e.Time,
+		PaymentHash:           hex.EncodeToString(e.PaymentHash),
+		ShortChannelID:        e.ShortChannelID,
+		AmountMsat:            e.AmountMsat,
+		IncomingExpiry:        e.IncomingExpiry,
+		OutgoingExpiry:        e.OutgoingExpiry,
+		RegisteredPaymentHash: hex.EncodeToString(e.RegisteredPaymentHash),
+		PeerID:                hex.EncodeToString(e.PeerID),
+		Action:                e.Action,
+		FailureCode:           e.FailureCode,
+		FailureReason:         e.FailureReason,
+		ChannelPoint:          e.ChannelPoint,
+		ChannelOpened:         e.Opened,
+		LatencyMicros:         e.Duration.Microseconds(),
+	}
+}
+
+func (l *Log) add(r *Record) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if len(l.pending) >= maxPending {
+		droppedRecords.WithLabelValues(l.node.Label()).Inc()
+		return
+	}
+
+	l.pending = append(l.pending, r)
+}
+
+// Start writes the recorded htlcs every second, and deletes expired records
+// every hour, until Stop is called.
+func (l *Log) Start() error {
+	l.mtx.Lock()
+	l.ctx, l.cancel = context.WithCancel(tenant.NewContext(context.Background(), l.node.Tenant))
+	ctx := l.ctx
+	l.mtx.Unlock()
+
+	l.logger.Printf("audit log: recording intercepted htlcs for %v", l.retention)
+	lastPrune := time.Time{}
+	for {
+		if time.Since(lastPrune) >= pruneInterval {
+			l.prune(ctx)
+			lastPrune = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			// Write what was recorded while stopping.
+			l.flush(tenant.NewContext(context.Background(), l.node.Tenant))
+			return nil
+		case <-time.After(flushInterval):
+			l.flush(ctx)
+		}
+	}
+}
+
+func (l *Log) Stop() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.cancel != nil {
+		l.cancel()
+	}
+}
+
+func (l *Log) flush(ctx context.Context) {
+	l.mtx.Lock()
+	records := l.pending
+	l.pending = nil
+	l.mtx.Unlock()
+	if len(records) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	err := l.store.Insert(ctx, l.lspNodeID, records)
+	if err != nil {
+		l.logger.Printf("audit log: failed to write %d records: %v", len(records), err)
+		droppedRecords.WithLabelValues(l.node.Label()).Add(float64(len(records)))
+	}
+}
+
+func (l *Log) prune(ctx context.Context) {
+	deleted, err := l.store.DeleteBefore(ctx, l.lspNodeID, time.Now().Add(-l.retention))
+	if err != nil {
+		l.logger.Printf("audit log: failed to delete expired records: %v", err)
+		return
+	}
+
+	if deleted > 0 {
+		l.logger.Printf("audit log: deleted %d records older than %v", deleted, l.retention)
+	}
+}
+
+// QueryLimit returns the number of records returned for the requested limit:
+// 100 if not set, and at most 1000.
+func QueryLimit(limit int) int {
+	if limit <= 0 {
+		return defaultQueryLimit
+	}
+	if limit > maxQueryLimit {
+		return maxQueryLimit
+	}
+
+	return limit
+}
+
+// Query returns the records of the node matching the filter, newest first,
+// up to the QueryLimit of the filter.
+func (l *Log) Query(ctx context.Context, filter *Filter) ([]*Record, error) {
+	f := *filter
+	f.Limit = QueryLimit(f.Limit)
+
+	records, err := l.store.Query(ctx, l.lspNodeID, &f)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range records {
+		r.Node = l.node.Name
+	}
+
+	return records, nil
+}
@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/events"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockStore struct {
+	inserted []*Record
+	filter   *Filter
+}
+
+func (s *mockStore) Insert(ctx context.Context, lspNodeID []byte, records []*Record) error {
+	s.inserted = append(s.inserted, records...)
+	return nil
+}
+
+func (s *mockStore) Query(ctx context.Context, lspNodeID []byte, filter *Filter) ([]*Record, error) {
+	s.filter = filter
+	return s.inserted, nil
+}
+
+func (s *mockStore) DeleteBefore(ctx context.Context, lspNodeID []byte, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestLog(t *testing.T) {
+	store := &mockStore{}
+	node := &config.NodeConfig{Name: "node1", NodePubkey: "02aa"}
+	other := &config.NodeConfig{Name: "node2", NodePubkey: "02bb"}
+	l := NewLog(store, node, log.New(os.Stderr, "", 0))
+	bus := events.NewBus(log.New(os.Stderr, "", 0))
+	l.Subscribe(bus)
+
+	bus.Publish(&events.Event{
+		Kind:                  events.HtlcIntercepted,
+		Node:                  node,
+		PaymentHash:           []byte{1},
+		RegisteredPaymentHash: []byte{2},
+		PeerID:                []byte{3},
+		Action:                "FAIL_HTLC_WITH_CODE",
+		FailureCode:           "TEMPORARY_CHANNEL_FAILURE",
+		Duration:              2 * time.Millisecond,
+	})
+	bus.Publish(&events.Event{Kind: events.HtlcIntercepted, Node: other, PaymentHash: []byte{4}})
+	bus.Publish(&events.Event{Kind: events.ChannelOpened, Node: node, PaymentHash: []byte{5}})
+	bus.Close()
+	l.flush(context.Background())
+
+	assert.Len(t, store.inserted, 1)
+	r := store.inserted[0]
+	assert.Equal(t, "01", r.PaymentHash)
+	assert.Equal(t, "02", r.RegisteredPaymentHash)
+	assert.Equal(t, "03", r.PeerID)
+	assert.Equal(t, "TEMPORARY_CHANNEL_FAILURE", r.FailureCode)
+	assert.Equal(t, int64(2000), r.LatencyMicros)
+
+	records, err := l.Query(context.Background(), &Filter{Limit: 5000})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, maxQueryLimit, store.filter.Limit)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/breez/lspd/cln"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/ldk"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/lnd"
+	"github.com/breez/lspd/secrets"
+)
+
+// The backend specific parts of setting up a node. A new backend adds a
+// case to newLightningNode and newHtlcInterceptor, the rest of lspd only uses
+// the lightning.LightningNode.
+
+// newLightningNode returns the client of the lightning node of the config.
+func newLightningNode(node *config.NodeConfig, resolver *secrets.Resolver, logger *log.Logger) (lightning.LightningNode, error) {
+	switch {
+	case node.Lnd != nil:
+		client, err := lnd.NewLndClient(node.Lnd, resolver, logger)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case node.Cln != nil:
+		client, err := cln.NewClnClient(node.Cln, logger)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case node.Ldk != nil:
+		client, err := ldk.NewLdkClient(node.Ldk, logger)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+
+	return nil, fmt.Errorf("node has to be either lnd, cln or ldk")
+}
+
+// nodeAddress returns where lspd connects to the node, for log messages.
+func nodeAddress(node *config.NodeConfig) string {
+	switch {
+	case node.Lnd != nil:
+		return node.Lnd.Address
+	case node.Cln != nil:
+		return node.Cln.SocketPath
+	case node.Ldk != nil:
+		return node.Ldk.Address
+	}
+
+	return ""
+}
+
+// htlcInterceptorStores are the stores the htlc interceptors of the backends
+// keep their state in.
+type htlcInterceptorStores struct {
+	interceptStore  interceptor.InterceptStore
+	forwardingStore lnd.ForwardingEventStore
+	htlcStore       cln.HtlcStore
+}
+
+// newHtlcInterceptor returns the htlc interceptor passing the htlcs of the
+// node to the interceptor.
+func newHtlcInterceptor(
+	node *config.NodeConfig,
+	client lightning.LightningNode,
+	i *interceptor.Interceptor,
+	stores *htlcInterceptorStores,
+	logger *log.Logger,
+) (interceptor.HtlcInterceptor, error) {
+	switch c := client.(type) {
+	case *lnd.LndClient:
+		fwsync := lnd.NewForwardingHistorySync(c, stores.interceptStore, stores.forwardingStore, node, logger)
+		return lnd.NewLndHtlcInterceptor(node, c, fwsync, i, logger)
+	case *cln.ClnClient:
+		return cln.NewClnHtlcInterceptor(node, c, stores.htlcStore, i, logger)
+	case *ldk.LdkClient:
+		return ldk.NewLdkHtlcInterceptor(node, c, i, logger)
+	}
+
+	return nil, fmt.Errorf("no htlc interceptor for %s nodes", client.Backend())
+}
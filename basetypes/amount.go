@@ -0,0 +1,84 @@
+package basetypes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrNegativeAmount = errors.New("amount is negative")
+	ErrAmountOverflow = errors.New("amount overflows")
+)
+
+// MilliSatoshi is an amount in millisatoshi, the unit lspd quotes amounts in.
+type MilliSatoshi uint64
+
+// Satoshi is an amount in satoshi, used for on-chain amounts like channel
+// capacities.
+type Satoshi uint64
+
+// NewMilliSatoshi converts an amount in millisatoshi as stored in the
+// database or received over rpc.
+func NewMilliSatoshi(msat int64) (MilliSatoshi, error) {
+	if msat < 0 {
+		return 0, fmt.Errorf("%d msat: %w", msat, ErrNegativeAmount)
+	}
+
+	return MilliSatoshi(msat), nil
+}
+
+// NewSatoshi converts an amount in satoshi as stored in the database or
+// received over rpc.
+func NewSatoshi(sat int64) (Satoshi, error) {
+	if sat < 0 {
+		return 0, fmt.Errorf("%d sat: %w", sat, ErrNegativeAmount)
+	}
+
+	return Satoshi(sat), nil
+}
+
+// ToSatoshi rounds the amount down to whole satoshi.
+func (m MilliSatoshi) ToSatoshi() Satoshi {
+	return Satoshi(m / 1000)
+}
+
+// ToSatoshiCeil rounds the amount up to whole satoshi.
+func (m MilliSatoshi) ToSatoshiCeil() Satoshi {
+	return Satoshi((m + 999) / 1000)
+}
+
+// Int64 returns the amount for a bigint database column.
+func (m MilliSatoshi) Int64() (int64, error) {
+	if m > math.MaxInt64 {
+		return 0, fmt.Errorf("%d msat: %w", uint64(m), ErrAmountOverflow)
+	}
+
+	return int64(m), nil
+}
+
+func (m MilliSatoshi) String() string {
+	return fmt.Sprintf("%d msat", uint64(m))
+}
+
+// ToMilliSatoshi converts the amount to millisatoshi.
+func (s Satoshi) ToMilliSatoshi() (MilliSatoshi, error) {
+	if s > math.MaxUint64/1000 {
+		return 0, fmt.Errorf("%d sat: %w", uint64(s), ErrAmountOverflow)
+	}
+
+	return MilliSatoshi(s * 1000), nil
+}
+
+// Int64 returns the amount for a bigint database column.
+func (s Satoshi) Int64() (int64, error) {
+	if s > math.MaxInt64 {
+		return 0, fmt.Errorf("%d sat: %w", uint64(s), ErrAmountOverflow)
+	}
+
+	return int64(s), nil
+}
+
+func (s Satoshi) String() string {
+	return fmt.Sprintf("%d sat", uint64(s))
+}
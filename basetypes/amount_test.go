@@ -0,0 +1,40 @@
+package basetypes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMilliSatoshiConversions(t *testing.T) {
+	assert.Equal(t, Satoshi(1), MilliSatoshi(1999).ToSatoshi())
+	assert.Equal(t, Satoshi(2), MilliSatoshi(1001).ToSatoshiCeil())
+	assert.Equal(t, Satoshi(2), MilliSatoshi(2000).ToSatoshiCeil())
+
+	msat, err := Satoshi(21).ToMilliSatoshi()
+	assert.NoError(t, err)
+	assert.Equal(t, MilliSatoshi(21000), msat)
+
+	_, err = Satoshi(math.MaxUint64 / 100).ToMilliSatoshi()
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+}
+
+func TestAmountValidation(t *testing.T) {
+	_, err := NewMilliSatoshi(-1)
+	assert.ErrorIs(t, err, ErrNegativeAmount)
+	_, err = NewSatoshi(-1)
+	assert.ErrorIs(t, err, ErrNegativeAmount)
+
+	msat, err := NewMilliSatoshi(1000)
+	assert.NoError(t, err)
+	assert.Equal(t, MilliSatoshi(1000), msat)
+
+	_, err = MilliSatoshi(math.MaxUint64).Int64()
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = Satoshi(math.MaxUint64).Int64()
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+
+	assert.Equal(t, "1000 msat", MilliSatoshi(1000).String())
+	assert.Equal(t, "1 sat", Satoshi(1).String())
+}
@@ -1,7 +1,10 @@
 package basetypes
 
 import (
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
@@ -17,3 +20,23 @@ func NewOutPoint(fundingTxID []byte, index uint32) (*wire.OutPoint, error) {
 
 	return wire.NewOutPoint(&h, index), nil
 }
+
+// ParseOutPoint parses an outpoint in the txid:index format.
+func ParseOutPoint(s string) (*wire.OutPoint, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid outpoint '%s'", s)
+	}
+
+	h, err := chainhash.NewHashFromStr(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid outpoint txid '%s': %w", parts[0], err)
+	}
+
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid outpoint index '%s': %w", parts[1], err)
+	}
+
+	return wire.NewOutPoint(h, uint32(index)), nil
+}
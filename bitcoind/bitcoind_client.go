@@ -0,0 +1,143 @@
+package bitcoind
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/breez/lspd/chain"
+)
+
+// BitcoindClient estimates feerates with the estimatesmartfee rpc of
+// bitcoind.
+type BitcoindClient struct {
+	address    string
+	user       string
+	password   string
+	httpClient *http.Client
+}
+
+type rpcRequest struct {
+	JsonRpc string        `json:"jsonrpc"`
+	Id      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type estimateSmartFeeResponse struct {
+	Result *struct {
+		// Feerate in BTC/kvB.
+		FeeRate *float64 `json:"feerate"`
+		Errors  []string `json:"errors"`
+		Blocks  int      `json:"blocks"`
+	} `json:"result"`
+	Error *rpcError `json:"error"`
+}
+
+func NewBitcoindClient(address string, user string, password string) (*BitcoindClient, error) {
+	if address == "" {
+		return nil, fmt.Errorf("address not set")
+	}
+
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = "http://" + address
+	}
+
+	return &BitcoindClient{
+		address:    address,
+		user:       user,
+		password:   password,
+		httpClient: http.DefaultClient,
+	}, nil
+}
+
+// The confirmation target and estimate mode of estimatesmartfee per strategy.
+func estimateParams(strategy chain.FeeStrategy) (uint32, string, error) {
+	switch strategy {
+	case chain.FeeStrategyFastest:
+		return 1, "conservative", nil
+	case chain.FeeStrategyHalfHour:
+		return 3, "conservative", nil
+	case chain.FeeStrategyHour:
+		return 6, "economical", nil
+	case chain.FeeStrategyEconomy:
+		return 144, "economical", nil
+	case chain.FeeStrategyMinimum:
+		return 1008, "economical", nil
+	default:
+		return 0, "", fmt.Errorf("unsupported fee strategy: %v", strategy)
+	}
+}
+
+func (b *BitcoindClient) EstimateFeeRate(
+	ctx context.Context,
+	strategy chain.FeeStrategy,
+) (*chain.FeeEstimation, error) {
+	target, mode, err := estimateParams(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	reqBody, err := json.Marshal(&rpcRequest{
+		JsonRpc: "1.0",
+		Id:      "lspd",
+		Method:  "estimatesmartfee",
+		Params:  []interface{}{target, mode},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		b.address,
+		bytes.NewReader(reqBody),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext error: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.user != "" {
+		req.SetBasicAuth(b.user, b.password)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("httpClient.Do error: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	// bitcoind returns rpc errors with a 500 status code and a json body.
+	var body estimateSmartFeeResponse
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response with statuscode %v: %w", resp.StatusCode, err)
+	}
+
+	if body.Error != nil {
+		return nil, fmt.Errorf("estimatesmartfee error %d: %s", body.Error.Code, body.Error.Message)
+	}
+
+	if body.Result == nil || body.Result.FeeRate == nil {
+		var errs []string
+		if body.Result != nil {
+			errs = body.Result.Errors
+		}
+		return nil, fmt.Errorf("estimatesmartfee returned no feerate: %v", errs)
+	}
+
+	// BTC/kvB to sat/vB.
+	return &chain.FeeEstimation{
+		SatPerVByte: *body.Result.FeeRate * 1e8 / 1000,
+	}, nil
+}
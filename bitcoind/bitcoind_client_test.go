@@ -0,0 +1,44 @@
+package bitcoind
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/breez/lspd/chain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateFeeRate(t *testing.T) {
+	var got rpcRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "pass", password)
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"result":{"feerate":0.00012,"blocks":144},"error":null,"id":"lspd"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewBitcoindClient(srv.URL, "user", "pass")
+	assert.NoError(t, err)
+	fee, err := client.EstimateFeeRate(context.Background(), chain.FeeStrategyEconomy)
+	assert.NoError(t, err)
+	assert.True(t, math.Abs(fee.SatPerVByte-12) < 1e-9, fee.SatPerVByte)
+	assert.Equal(t, "estimatesmartfee", got.Method)
+	assert.Equal(t, []interface{}{float64(144), "economical"}, got.Params)
+}
+
+func TestEstimateFeeRateNoEstimate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":{"errors":["Insufficient data or no feerate found"],"blocks":0},"error":null,"id":"lspd"}`))
+	}))
+	defer srv.Close()
+
+	client, _ := NewBitcoindClient(srv.URL, "", "")
+	_, err := client.EstimateFeeRate(context.Background(), chain.FeeStrategyFastest)
+	assert.Error(t, err)
+}
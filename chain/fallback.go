@@ -0,0 +1,41 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// FallbackFeeEstimator asks its estimators in order, and returns the first
+// estimation that succeeds.
+type FallbackFeeEstimator struct {
+	estimators []FeeEstimator
+}
+
+func NewFallbackFeeEstimator(estimators ...FeeEstimator) *FallbackFeeEstimator {
+	return &FallbackFeeEstimator{estimators: estimators}
+}
+
+func (f *FallbackFeeEstimator) EstimateFeeRate(
+	ctx context.Context,
+	strategy FeeStrategy,
+) (*FeeEstimation, error) {
+	var lastErr error
+	for i, e := range f.estimators {
+		fee, err := e.EstimateFeeRate(ctx, strategy)
+		if err == nil {
+			return fee, nil
+		}
+
+		if i < len(f.estimators)-1 {
+			log.Printf("Fee estimation with %T failed, falling back: %v", e, err)
+		}
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		return nil, fmt.Errorf("no fee estimators")
+	}
+
+	return nil, lastErr
+}
@@ -0,0 +1,30 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingEstimator struct{}
+
+func (failingEstimator) EstimateFeeRate(context.Context, FeeStrategy) (*FeeEstimation, error) {
+	return nil, fmt.Errorf("unavailable")
+}
+
+func TestFallbackFeeEstimator(t *testing.T) {
+	static, err := NewStaticFeeEstimator(5)
+	assert.NoError(t, err)
+
+	fee, err := NewFallbackFeeEstimator(failingEstimator{}, static).EstimateFeeRate(context.Background(), FeeStrategyEconomy)
+	assert.NoError(t, err)
+	assert.Equal(t, 5.0, fee.SatPerVByte)
+
+	_, err = NewFallbackFeeEstimator(failingEstimator{}).EstimateFeeRate(context.Background(), FeeStrategyEconomy)
+	assert.Error(t, err)
+
+	_, err = NewStaticFeeEstimator(0)
+	assert.Error(t, err)
+}
@@ -1,6 +1,9 @@
 package chain
 
-import "context"
+import (
+	"context"
+	"strings"
+)
 
 type FeeStrategy int
 
@@ -12,6 +15,25 @@ const (
 	FeeStrategyMinimum  FeeStrategy = 4
 )
 
+// ParseFeeStrategy parses the name of a fee strategy: fastest, halfhour,
+// hour, economy or minimum. Returns false if the name is unknown.
+func ParseFeeStrategy(s string) (FeeStrategy, bool) {
+	switch strings.ToLower(s) {
+	case "minimum":
+		return FeeStrategyMinimum, true
+	case "economy":
+		return FeeStrategyEconomy, true
+	case "hour":
+		return FeeStrategyHour, true
+	case "halfhour":
+		return FeeStrategyHalfHour, true
+	case "fastest":
+		return FeeStrategyFastest, true
+	default:
+		return FeeStrategyEconomy, false
+	}
+}
+
 type FeeEstimation struct {
 	SatPerVByte float64
 }
@@ -0,0 +1,29 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+)
+
+// StaticFeeEstimator estimates the same fixed feerate for every strategy. It
+// is meant as a fallback when the other estimators are unavailable.
+type StaticFeeEstimator struct {
+	satPerVByte float64
+}
+
+func NewStaticFeeEstimator(satPerVByte float64) (*StaticFeeEstimator, error) {
+	if satPerVByte <= 0 {
+		return nil, fmt.Errorf("invalid static feerate %v sat/vbyte", satPerVByte)
+	}
+
+	return &StaticFeeEstimator{satPerVByte: satPerVByte}, nil
+}
+
+func (s *StaticFeeEstimator) EstimateFeeRate(
+	ctx context.Context,
+	strategy FeeStrategy,
+) (*FeeEstimation, error) {
+	return &FeeEstimation{
+		SatPerVByte: s.satPerVByte,
+	}, nil
+}
@@ -0,0 +1,7 @@
+package chain
+
+import "errors"
+
+// ErrTransactionNotFound is returned for transactions that are neither in the
+// mempool nor in the chain.
+var ErrTransactionNotFound = errors.New("transaction not found")
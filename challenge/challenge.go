@@ -0,0 +1,280 @@
+// Package challenge guards OpenChannel with a proof of work or captcha
+// challenge, so public tokens can't be used to drain the on-chain funds of
+// the lsp with channels to many sybil nodes.
+package challenge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/breez/lspd/tokens"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultDifficulty = 20
+	defaultExpiry     = 10 * time.Minute
+	captchaTimeout    = 5 * time.Second
+
+	// A challenge is the expiry, the difficulty, random bytes and the mac of
+	// all of them and the token, so challenges don't have to be stored.
+	randomLen    = 16
+	macLen       = 16
+	challengeLen = 8 + 4 + randomLen + macLen
+)
+
+var failuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_open_channel_challenge_failures_total",
+	Help: "OpenChannel requests denied because of a missing or invalid challenge.",
+}, []string{"node", "reason"})
+
+// Challenge is a proof of work challenge issued to a client.
+type Challenge struct {
+	Challenge      []byte
+	Difficulty     uint32
+	ExpiresAt      time.Time
+	CaptchaSiteKey string
+}
+
+// Challenger issues and verifies the challenges of a node.
+type Challenger struct {
+	node   *config.NodeConfig
+	secret []byte
+	client *http.Client
+	logger *log.Logger
+}
+
+// NewChallenger returns a challenger for the node. Challenges are
+// authenticated with the secret, so they are valid as long as the secret
+// doesn't change.
+func NewChallenger(node *config.NodeConfig, secret []byte, logger *log.Logger) *Challenger {
+	return &Challenger{
+		node:   node,
+		secret: secret,
+		client: &http.Client{Timeout: captchaTimeout},
+		logger: logger,
+	}
+}
+
+// Required returns whether OpenChannel requests of the token need a solved
+// challenge. Safe to call on a nil challenger.
+func (c *Challenger) Required(t *tokens.Token, token string) bool {
+	if c == nil {
+		return false
+	}
+
+	cfg := c.node.OpenChannelChallenge
+	if cfg == nil {
+		return false
+	}
+
+	if len(cfg.Tokens) == 0 {
+		return true
+	}
+
+	for _, required := range cfg.Tokens {
+		if required == token || (t != nil && t.Name != "" && required == t.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Challenger) difficulty() uint32 {
+	if d := c.node.OpenChannelChallenge.Difficulty; d > 0 {
+		return d
+	}
+
+	return defaultDifficulty
+}
+
+func (c *Challenger) expiry() time.Duration {
+	s := c.node.OpenChannelChallenge.Expiry
+	if s == "" {
+		return defaultExpiry
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		c.logger.Printf("WARN: Invalid challenge expiry '%s'. Using default %v", s, defaultExpiry)
+		return defaultExpiry
+	}
+
+	return d
+}
+
+// Issue returns a new challenge for a client of the token. The caller checks
+// the challenge is required.
+func (c *Challenger) Issue(token string) (*Challenge, error) {
+	expiresAt := time.Now().Add(c.expiry()).Truncate(time.Second)
+	difficulty := c.difficulty()
+
+	challenge := make([]byte, challengeLen)
+	binary.BigEndian.PutUint64(challenge, uint64(expiresAt.Unix()))
+	binary.BigEndian.PutUint32(challenge[8:], difficulty)
+	_, err := rand.Read(challenge[12 : 12+randomLen])
+	if err != nil {
+		return nil, fmt.Errorf("rand.Read() error: %w", err)
+	}
+	copy(challenge[12+randomLen:], c.mac(challenge[:12+randomLen], token))
+
+	return &Challenge{
+		Challenge:      challenge,
+		Difficulty:     difficulty,
+		ExpiresAt:      expiresAt,
+		CaptchaSiteKey: c.captchaSiteKey(),
+	}, nil
+}
+
+func (c *Challenger) mac(data []byte, token string) []byte {
+	m := hmac.New(sha256.New, c.secret)
+	m.Write(data)
+	m.Write([]byte(token))
+	return m.Sum(nil)[:macLen]
+}
+
+func (c *Challenger) captchaSiteKey() string {
+	cfg := c.node.OpenChannelChallenge
+	if cfg.CaptchaVerifyURL == "" {
+		return ""
+	}
+
+	return cfg.CaptchaSiteKey
+}
+
+// Verify checks the client of the token solved a challenge before opening a
+// channel to peerID, either the proof of work of a challenge issued to the
+// token, or a captcha. Returns lsperrors.ErrChallengeRequired if the client
+// didn't try, lsperrors.ErrInvalidChallenge if the solution is invalid.
+func (c *Challenger) Verify(ctx context.Context, token string, peerID []byte, challenge []byte, nonce []byte, captchaToken string) error {
+	err := c.verify(ctx, token, peerID, challenge, nonce, captchaToken)
+	if err != nil {
+		c.logger.Printf("challenge: denying OpenChannel to %x: %v", peerID, err)
+		reason := "invalid"
+		if errors.Is(err, lsperrors.ErrChallengeRequired) {
+			reason = "missing"
+		}
+		failuresTotal.WithLabelValues(c.node.Label(), reason).Inc()
+	}
+
+	return err
+}
+
+func (c *Challenger) verify(ctx context.Context, token string, peerID []byte, challenge []byte, nonce []byte, captchaToken string) error {
+	if captchaToken != "" && c.captchaSiteKey() != "" {
+		return c.verifyCaptcha(ctx, captchaToken)
+	}
+
+	if len(challenge) == 0 {
+		return lsperrors.ErrChallengeRequired
+	}
+
+	if len(challenge) != challengeLen {
+		return fmt.Errorf("%w: invalid length %d", lsperrors.ErrInvalidChallenge, len(challenge))
+	}
+
+	if !hmac.Equal(challenge[12+randomLen:], c.mac(challenge[:12+randomLen], token)) {
+		return fmt.Errorf("%w: not issued to the token", lsperrors.ErrInvalidChallenge)
+	}
+
+	expiresAt := time.Unix(int64(binary.BigEndian.Uint64(challenge)), 0)
+	if time.Now().After(expiresAt) {
+		return fmt.Errorf("%w: expired at %v", lsperrors.ErrInvalidChallenge, expiresAt)
+	}
+
+	difficulty := binary.BigEndian.Uint32(challenge[8:])
+	if zeroBits(hash(challenge, peerID, nonce)) < difficulty {
+		return fmt.Errorf("%w: proof of work below difficulty %d", lsperrors.ErrInvalidChallenge, difficulty)
+	}
+
+	return nil
+}
+
+// verifyCaptcha posts the captcha token to the siteverify url of the
+// captcha provider. hCaptcha and Cloudflare Turnstile share the api.
+func (c *Challenger) verifyCaptcha(ctx context.Context, captchaToken string) error {
+	cfg := c.node.OpenChannelChallenge
+	form := url.Values{
+		"secret":   {cfg.CaptchaSecret},
+		"response": {captchaToken},
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.CaptchaVerifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return lsperrors.Wrap(lsperrors.ErrInternal, err)
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(r)
+	if err != nil {
+		return lsperrors.Wrap(lsperrors.ErrInternal, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return lsperrors.Wrap(lsperrors.ErrInternal, fmt.Errorf("captcha verification returned %s", resp.Status))
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return lsperrors.Wrap(lsperrors.ErrInternal, fmt.Errorf("invalid captcha verification: %w", err))
+	}
+
+	if !result.Success {
+		return fmt.Errorf("%w: captcha not solved: %s", lsperrors.ErrInvalidChallenge, strings.Join(result.ErrorCodes, ", "))
+	}
+
+	return nil
+}
+
+func hash(challenge []byte, peerID []byte, nonce []byte) []byte {
+	h := sha256.New()
+	h.Write(challenge)
+	h.Write(peerID)
+	h.Write(nonce)
+	return h.Sum(nil)
+}
+
+// zeroBits returns the number of leading zero bits of b.
+func zeroBits(b []byte) uint32 {
+	var n uint32
+	for _, x := range b {
+		if x != 0 {
+			return n + uint32(bits.LeadingZeros8(x))
+		}
+		n += 8
+	}
+
+	return n
+}
+
+// Solve returns a nonce solving the challenge for a channel to peerID, the
+// work a client does before OpenChannel.
+func Solve(challenge []byte, peerID []byte, difficulty uint32) []byte {
+	nonce := make([]byte, 8)
+	for i := uint64(0); ; i++ {
+		binary.BigEndian.PutUint64(nonce, i)
+		if zeroBits(hash(challenge, peerID, nonce)) >= difficulty {
+			return nonce
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package challenge
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/breez/lspd/tokens"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequired(t *testing.T) {
+	node := &config.NodeConfig{}
+	c := NewChallenger(node, []byte("secret"), log.New(os.Stderr, "", 0))
+	assert.False(t, c.Required(nil, "public"))
+
+	node.OpenChannelChallenge = &config.ChallengeConfig{}
+	assert.True(t, c.Required(nil, "private"))
+
+	node.OpenChannelChallenge.Tokens = []string{"public", "app"}
+	assert.True(t, c.Required(nil, "public"))
+	assert.True(t, c.Required(&tokens.Token{Name: "app"}, "stored"))
+	assert.False(t, c.Required(nil, "private"))
+
+	var none *Challenger
+	assert.False(t, none.Required(nil, "public"))
+}
+
+func TestVerify(t *testing.T) {
+	node := &config.NodeConfig{OpenChannelChallenge: &config.ChallengeConfig{Difficulty: 8}}
+	c := NewChallenger(node, []byte("secret"), log.New(os.Stderr, "", 0))
+	ctx := context.Background()
+	peer := []byte{0x02, 0xaa}
+
+	ch, err := c.Issue("public")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(8), ch.Difficulty)
+	assert.Empty(t, ch.CaptchaSiteKey)
+	nonce := Solve(ch.Challenge, peer, ch.Difficulty)
+	assert.NoError(t, c.Verify(ctx, "public", peer, ch.Challenge, nonce, ""))
+
+	err = c.Verify(ctx, "public", peer, nil, nil, "")
+	assert.True(t, errors.Is(err, lsperrors.ErrChallengeRequired))
+
+	// The solution is bound to the token and the peer.
+	err = c.Verify(ctx, "other", peer, ch.Challenge, nonce, "")
+	assert.True(t, errors.Is(err, lsperrors.ErrInvalidChallenge))
+	other := []byte{0x02, 0xbb}
+	if zeroBits(hash(ch.Challenge, other, nonce)) < ch.Difficulty {
+		err = c.Verify(ctx, "public", other, ch.Challenge, nonce, "")
+		assert.True(t, errors.Is(err, lsperrors.ErrInvalidChallenge))
+	}
+
+	// The difficulty can't be lowered by the client.
+	tampered := append([]byte{}, ch.Challenge...)
+	tampered[11] = 0
+	err = c.Verify(ctx, "public", peer, tampered, Solve(tampered, peer, 0), "")
+	assert.True(t, errors.Is(err, lsperrors.ErrInvalidChallenge))
+
+	expired := append([]byte{}, ch.Challenge...)
+	binary.BigEndian.PutUint64(expired, uint64(time.Now().Add(-time.Second).Unix()))
+	copy(expired[12+randomLen:], c.mac(expired[:12+randomLen], "public"))
+	err = c.Verify(ctx, "public", peer, expired, Solve(expired, peer, ch.Difficulty), "")
+	assert.True(t, errors.Is(err, lsperrors.ErrInvalidChallenge))
+}
+
+func TestVerifyCaptcha(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("secret") == "captcha-secret" && r.Form.Get("response") == "solved" {
+			w.Write([]byte(`{"success": true}`))
+			return
+		}
+		w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+	}))
+	defer srv.Close()
+
+	node := &config.NodeConfig{OpenChannelChallenge: &config.ChallengeConfig{
+		CaptchaVerifyURL: srv.URL,
+		CaptchaSiteKey:   "site-key",
+		CaptchaSecret:    "captcha-secret",
+	}}
+	c := NewChallenger(node, []byte("secret"), log.New(os.Stderr, "", 0))
+	ctx := context.Background()
+
+	ch, err := c.Issue("public")
+	assert.NoError(t, err)
+	assert.Equal(t, "site-key", ch.CaptchaSiteKey)
+
+	assert.NoError(t, c.Verify(ctx, "public", []byte{0x02}, nil, nil, "solved"))
+	err = c.Verify(ctx, "public", []byte{0x02}, nil, nil, "guessed")
+	assert.True(t, errors.Is(err, lsperrors.ErrInvalidChallenge))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/lsperrors"
+	lspdrpc "github.com/breez/lspd/rpc"
+	"github.com/golang/protobuf/proto"
+)
+
+// RenewChannelLease registers a payment to the destination that renews the
+// lease of one of its channels. The payment is forwarded over the channel
+// with the renewal fee deducted, after which the lease is extended.
+func (s *channelOpenerServer) RenewChannelLease(
+	ctx context.Context,
+	in *lspdrpc.RenewChannelLeaseRequest,
+) (*lspdrpc.RenewChannelLeaseReply, error) {
+	node, token, err := s.getNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	manager, ok := s.leaseManagers[node.nodeConfig.NodePubkey]
+	if !ok {
+		return nil, fmt.Errorf("%w channel leases", lsperrors.ErrUnsupported)
+	}
+
+	data, err := decryptBlob(node, in.KeyId, in.Blob)
+	if err != nil {
+		return nil, err
+	}
+
+	var ri lspdrpc.RenewChannelLeaseInformation
+	err = proto.Unmarshal(data, &ri)
+	if err != nil {
+		node.logger.Printf("proto.Unmarshal(%x) error: %v", data, err)
+		return nil, fmt.Errorf("%w: proto.Unmarshal(%x) error: %v", lsperrors.ErrInvalidRequest, data, err)
+	}
+	node.logger.Printf("RenewChannelLease - Destination: %x, ChannelPoint: %s, PaymentHash: %x, IncomingAmountMsat: %v",
+		ri.Destination, ri.ChannelPoint, ri.PaymentHash, ri.IncomingAmountMsat)
+
+	// Only the destination can renew the lease of its channel, so the
+	// signature is always required.
+	if len(in.Signature) == 0 {
+		return nil, lsperrors.ErrSignatureRequired
+	}
+	err = s.verifySignedRequest(ctx, ri.Destination, in.Signature, data, ri.Timestamp, ri.Nonce)
+	if err != nil {
+		node.logger.Printf("verifySignedRequest(%x) error: %v", ri.Destination, err)
+		return nil, err
+	}
+
+	channelPoint, err := basetypes.ParseOutPoint(ri.ChannelPoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid channel point %s", lsperrors.ErrInvalidRequest, ri.ChannelPoint)
+	}
+	if len(ri.PaymentHash) != 32 || len(ri.PaymentSecret) != 32 {
+		return nil, fmt.Errorf("%w: invalid payment hash or secret", lsperrors.ErrInvalidRequest)
+	}
+
+	// The channel has to be an open channel lspd opened to the destination.
+	channels, err := s.channelTrackers[node.nodeConfig.NodePubkey].PeerChannels(ctx, hex.EncodeToString(ri.Destination), false)
+	if err != nil {
+		node.logger.Printf("PeerChannels(%x) error: %v", ri.Destination, err)
+		s.forgetNonce(ctx, node, ri.Destination, ri.Nonce)
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, fmt.Errorf("PeerChannels() error: %w", err))
+	}
+	var leaseExpiresAt int64
+	found := false
+	for _, c := range channels {
+		if c.ChannelPoint == ri.ChannelPoint {
+			found = true
+			leaseExpiresAt = unixOrZero(c.LeaseExpiresAt)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: no open channel %s with the destination", lsperrors.ErrInvalidRequest, ri.ChannelPoint)
+	}
+
+	peerChannels, err := node.client.ListPeerChannels(ctx, ri.Destination)
+	if err != nil {
+		node.logger.Printf("ListPeerChannels(%x) error: %v", ri.Destination, err)
+		s.forgetNonce(ctx, node, ri.Destination, ri.Nonce)
+		return nil, lsperrors.Wrap(lsperrors.ErrNodeUnavailable, fmt.Errorf("ListPeerChannels() error: %w", err))
+	}
+	var capacitySat uint64
+	for _, p := range peerChannels {
+		if p.ChannelPoint == ri.ChannelPoint && !p.Pending {
+			capacitySat = p.CapacitySat
+		}
+	}
+	if capacitySat == 0 {
+		return nil, fmt.Errorf("%w: channel %s is not active", lsperrors.ErrChannelNotReady, ri.ChannelPoint)
+	}
+
+	fee := manager.RenewalFee(capacitySat)
+	if ri.IncomingAmountMsat <= int64(fee) {
+		return nil, fmt.Errorf("%w: the renewal fee is %v msat", lsperrors.ErrNotEnoughFees, fee)
+	}
+	outgoingAmountMsat := ri.IncomingAmountMsat - int64(fee)
+
+	// A payment hash that is registered already can't be used for a renewal.
+	_, _, _, paymentSecret, _, _, _, _, _, err := s.store.PaymentInfo(ctx, ri.PaymentHash)
+	if err != nil {
+		node.logger.Printf("PaymentInfo(%x) error: %v", ri.PaymentHash, err)
+		s.forgetNonce(ctx, node, ri.Destination, ri.Nonce)
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, fmt.Errorf("PaymentInfo() error: %w", err))
+	}
+	if paymentSecret != nil {
+		return nil, fmt.Errorf("%w: payment hash already registered", lsperrors.ErrInvalidRequest)
+	}
+
+	// The renewal is recorded first, so the payment is never forwarded
+	// without extending the lease. The payment is registered with the
+	// channel it is forwarded over, so no channel is opened for it. Its
+	// params record the fee and the idle time, in blocks, the renewal buys.
+	params := &interceptor.OpeningFeeParams{
+		MinMsat:     fee,
+		ValidUntil:  time.Now().UTC().Add(24 * time.Hour).Format(basetypes.TIME_FORMAT),
+		MaxIdleTime: uint32(manager.Duration() / (10 * time.Minute)),
+	}
+	err = manager.AddRenewal(ctx, ri.PaymentHash, ri.ChannelPoint, fee)
+	if err == nil {
+		lspNodeID, _ := hex.DecodeString(node.nodeConfig.NodePubkey)
+		err = s.store.RegisterPayment(ctx, token, lspNodeID, params, ri.Destination, ri.PaymentHash, ri.PaymentSecret, ri.IncomingAmountMsat, outgoingAmountMsat, "", "", "")
+	}
+	if err == nil {
+		err = s.store.SetFundingTx(ctx, ri.PaymentHash, channelPoint)
+	}
+	if err != nil {
+		node.logger.Printf("RenewChannelLease(%x) error: %v", ri.PaymentHash, err)
+		s.forgetNonce(ctx, node, ri.Destination, ri.Nonce)
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, fmt.Errorf("RenewChannelLease() error: %w", err))
+	}
+
+	return &lspdrpc.RenewChannelLeaseReply{
+		FeeMsat:            fee,
+		OutgoingAmountMsat: outgoingAmountMsat,
+		LeaseDuration:      int64(manager.Duration().Seconds()),
+		LeaseExpiresAt:     leaseExpiresAt,
+	}, nil
+}
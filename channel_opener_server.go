@@ -2,23 +2,33 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"sort"
 	"time"
 
 	"github.com/breez/lspd/basetypes"
 	"github.com/breez/lspd/btceclegacy"
+	"github.com/breez/lspd/config"
 	"github.com/breez/lspd/interceptor"
-	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/lifecycle"
+	"github.com/breez/lspd/liquidity"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/breez/lspd/lsps0"
+	"github.com/breez/lspd/macaroon"
+	"github.com/breez/lspd/openchannel"
+	"github.com/breez/lspd/quota"
 	lspdrpc "github.com/breez/lspd/rpc"
+	"github.com/breez/lspd/scoring"
+	"github.com/breez/lspd/tenant"
+	"github.com/breez/lspd/tokens"
 	ecies "github.com/ecies/go/v2"
 	"github.com/golang/protobuf/proto"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
@@ -29,19 +39,68 @@ import (
 
 type channelOpenerServer struct {
 	lspdrpc.ChannelOpenerServer
-	store interceptor.InterceptStore
+	store             interceptor.InterceptStore
+	tokenStore        tokens.Store
+	liquidityManagers map[string]*liquidity.Manager
+	interceptors      map[string]*interceptor.Interceptor
+	// The runners of the channel opens requested with OpenChannel, by node
+	// pubkey.
+	openChannelRunners map[string]*openchannel.Runner
+	// The scorers of the clients, by node pubkey. Nodes without scoring have
+	// no scorer.
+	scorers map[string]*scoring.Scorer
+	// The limiters of the channels opened per token, by node pubkey.
+	quotas map[string]*quota.Limiter
+	// The LSPS servers, by node pubkey. Nodes that don't speak LSPS have
+	// none.
+	lsps0Servers map[string]*lsps0.Server
+	// The trackers of the channels opened by lspd, by node pubkey.
+	channelTrackers map[string]*lifecycle.Tracker
+	// The lease managers, by node pubkey. Nodes that don't lease channels
+	// have none.
+	leaseManagers map[string]*lifecycle.LeaseManager
 }
 
 func NewChannelOpenerServer(
 	store interceptor.InterceptStore,
+	tokenStore tokens.Store,
+	liquidityManagers map[string]*liquidity.Manager,
+	interceptors map[string]*interceptor.Interceptor,
+	openChannelRunners map[string]*openchannel.Runner,
+	scorers map[string]*scoring.Scorer,
+	quotas map[string]*quota.Limiter,
+	lsps0Servers map[string]*lsps0.Server,
+	channelTrackers map[string]*lifecycle.Tracker,
+	leaseManagers map[string]*lifecycle.LeaseManager,
 ) *channelOpenerServer {
 	return &channelOpenerServer{
-		store: store,
+		store:              store,
+		tokenStore:         tokenStore,
+		liquidityManagers:  liquidityManagers,
+		interceptors:       interceptors,
+		openChannelRunners: openChannelRunners,
+		scorers:            scorers,
+		quotas:             quotas,
+		lsps0Servers:       lsps0Servers,
+		channelTrackers:    channelTrackers,
+		leaseManagers:      leaseManagers,
 	}
 }
 
 type contextKey string
 
+// getToken returns the settings of the token stored in the database, or nil
+// if the token is configured on the node.
+func (s *channelOpenerServer) getToken(ctx context.Context, node *node, token string) *tokens.Token {
+	t, err := s.tokenStore.Get(ctx, token)
+	if err != nil {
+		node.logger.Printf("tokenStore.Get() error, using node settings: %v", err)
+		return nil
+	}
+
+	return t
+}
+
 func (s *channelOpenerServer) ChannelInformation(ctx context.Context, in *lspdrpc.ChannelInformationRequest) (*lspdrpc.ChannelInformationReply, error) {
 	node, token, err := s.getNode(ctx)
 	if err != nil {
@@ -53,6 +112,40 @@ func (s *channelOpenerServer) ChannelInformation(ctx context.Context, in *lspdrp
 		return nil, err
 	}
 
+	tok := s.getToken(ctx, node, token)
+	key := node.keys.active(time.Now())
+	if key == nil {
+		node.logger.Printf("ERROR: No active encryption key.")
+		return nil, lsperrors.ErrNoEncryptionKey
+	}
+
+	var openChannelChallenge *lspdrpc.OpenChannelChallenge
+	if node.challenger.Required(tok, token) {
+		c, err := node.challenger.Issue(token)
+		if err != nil {
+			node.logger.Printf("challenger.Issue() error: %v", err)
+			return nil, lsperrors.Wrap(lsperrors.ErrInternal, err)
+		}
+
+		openChannelChallenge = &lspdrpc.OpenChannelChallenge{
+			Challenge:      c.Challenge,
+			Difficulty:     c.Difficulty,
+			ExpiresAt:      c.ExpiresAt.Unix(),
+			CaptchaSiteKey: c.CaptchaSiteKey,
+		}
+	}
+
+	// Clients create their route hints with the fees of the channel policy
+	// of their token.
+	baseFeeMsat := node.nodeConfig.BaseFeeMsat
+	feeRate := node.nodeConfig.FeeRate
+	timeLockDelta := tokens.TimeLockDelta(tok, node.nodeConfig)
+	if policy := tokens.ChannelPolicy(tok, token, node.nodeConfig); policy != nil {
+		baseFeeMsat = policy.BaseFeeMsat
+		feeRate = policy.FeeRate
+		timeLockDelta = policy.TimeLockDelta
+	}
+
 	return &lspdrpc.ChannelInformationReply{
 		Name:                  node.nodeConfig.Name,
 		Pubkey:                node.nodeConfig.NodePubkey,
@@ -60,17 +153,40 @@ func (s *channelOpenerServer) ChannelInformation(ctx context.Context, in *lspdrp
 		ChannelCapacity:       int64(node.nodeConfig.PublicChannelAmount),
 		TargetConf:            int32(node.nodeConfig.TargetConf),
 		MinHtlcMsat:           int64(node.nodeConfig.MinHtlcMsat),
-		BaseFeeMsat:           int64(node.nodeConfig.BaseFeeMsat),
-		FeeRate:               node.nodeConfig.FeeRate,
-		TimeLockDelta:         node.nodeConfig.TimeLockDelta,
+		BaseFeeMsat:           int64(baseFeeMsat),
+		FeeRate:               feeRate,
+		TimeLockDelta:         timeLockDelta,
 		ChannelFeePermyriad:   int64(node.nodeConfig.ChannelFeePermyriad),
 		ChannelMinimumFeeMsat: int64(node.nodeConfig.ChannelMinimumFeeMsat),
-		LspPubkey:             node.publicKey.SerializeCompressed(), // TODO: Is the publicKey different from the ecies public key?
+		LspPubkey:             key.publicKey.SerializeCompressed(),
 		MaxInactiveDuration:   int64(node.nodeConfig.MaxInactiveDuration),
 		OpeningFeeParamsMenu:  params,
+		LspKeyId:              key.id,
+		MinPaymentSizeMsat:    uint64(node.nodeConfig.MinPaymentSizeMsat),
+		PriorityTiers:         priorityTiers(node.nodeConfig),
+		OpenChannelChallenge:  openChannelChallenge,
 	}, nil
 }
 
+// priorityTiers returns the priority tiers of the node, sorted by name.
+func priorityTiers(nodeConfig *config.NodeConfig) []*lspdrpc.PriorityTier {
+	var tiers []*lspdrpc.PriorityTier
+	for name, t := range nodeConfig.PriorityTiers {
+		if t == nil {
+			continue
+		}
+		tiers = append(tiers, &lspdrpc.PriorityTier{
+			Name:         name,
+			ExtraFeeMsat: t.ExtraFeeMsat,
+		})
+	}
+
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].Name < tiers[j].Name
+	})
+	return tiers
+}
+
 func (s *channelOpenerServer) createOpeningParamsMenu(
 	ctx context.Context,
 	node *node,
@@ -78,10 +194,17 @@ func (s *channelOpenerServer) createOpeningParamsMenu(
 ) ([]*lspdrpc.OpeningFeeParams, error) {
 	var menu []*lspdrpc.OpeningFeeParams
 
-	settings, err := s.store.GetFeeParamsSettings(token)
+	settings, err := s.store.GetFeeParamsSettings(ctx, token)
 	if err != nil {
-		log.Printf("Failed to fetch fee params settings: %v", err)
-		return nil, fmt.Errorf("failed to get opening_fee_params")
+		node.logger.Printf("Failed to fetch fee params settings: %v", err)
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, fmt.Errorf("failed to get opening_fee_params: %w", err))
+	}
+
+	// The opening fee covers at least the chain fee of the funding
+	// transaction at the current feerate.
+	var minFeeMsat uint64
+	if i, ok := s.interceptors[node.nodeConfig.NodePubkey]; ok {
+		minFeeMsat = i.MinOpeningFeeMsat(ctx)
 	}
 
 	for _, setting := range settings {
@@ -93,10 +216,13 @@ func (s *channelOpenerServer) createOpeningParamsMenu(
 			MaxIdleTime:          setting.Params.MaxIdleTime,
 			MaxClientToSelfDelay: setting.Params.MaxClientToSelfDelay,
 		}
+		if params.MinMsat < minFeeMsat {
+			params.MinMsat = minFeeMsat
+		}
 
-		promise, err := createPromise(node, params)
+		promise, err := createPromise(ctx, node, params)
 		if err != nil {
-			log.Printf("Failed to create promise: %v", err)
+			node.logger.Printf("Failed to create promise: %v", err)
 			return nil, err
 		}
 
@@ -128,15 +254,15 @@ func paramsHash(params *lspdrpc.OpeningFeeParams) ([]byte, error) {
 	return hash[:], nil
 }
 
-func createPromise(node *node, params *lspdrpc.OpeningFeeParams) (*string, error) {
+func createPromise(ctx context.Context, node *node, params *lspdrpc.OpeningFeeParams) (*string, error) {
 	hash, err := paramsHash(params)
 	if err != nil {
 		return nil, err
 	}
 	// Sign the hash with the private key of the LSP id.
-	sig, err := ecdsa.SignCompact(node.privateKey, hash[:], true)
+	sig, err := node.signer.SignCompact(ctx, hash[:])
 	if err != nil {
-		log.Printf("createPromise: SignCompact error: %v", err)
+		node.logger.Printf("createPromise: SignCompact error: %v", err)
 		return nil, err
 	}
 	promise := hex.EncodeToString(sig)
@@ -150,16 +276,16 @@ func verifyPromise(node *node, params *lspdrpc.OpeningFeeParams) error {
 	}
 	sig, err := hex.DecodeString(params.Promise)
 	if err != nil {
-		log.Printf("verifyPromise: hex.DecodeString error: %v", err)
+		node.logger.Printf("verifyPromise: hex.DecodeString error: %v", err)
 		return err
 	}
 	pub, _, err := ecdsa.RecoverCompact(sig, hash)
 	if err != nil {
-		log.Printf("verifyPromise: RecoverCompact(%x) error: %v", sig, err)
+		node.logger.Printf("verifyPromise: RecoverCompact(%x) error: %v", sig, err)
 		return err
 	}
-	if !node.publicKey.IsEqual(pub) {
-		log.Print("verifyPromise: not signed by us", err)
+	if !node.signer.PubKey().IsEqual(pub) {
+		node.logger.Print("verifyPromise: not signed by us", err)
 		return fmt.Errorf("invalid promise")
 	}
 	return nil
@@ -177,18 +303,98 @@ func validateOpeningFeeParams(node *node, params *lspdrpc.OpeningFeeParams) bool
 
 	t, err := time.Parse(basetypes.TIME_FORMAT, params.ValidUntil)
 	if err != nil {
-		log.Printf("validateOpeningFeeParams: time.Parse(%v, %v) error: %v", basetypes.TIME_FORMAT, params.ValidUntil, err)
+		node.logger.Printf("validateOpeningFeeParams: time.Parse(%v, %v) error: %v", basetypes.TIME_FORMAT, params.ValidUntil, err)
 		return false
 	}
 
 	if time.Now().UTC().After(t) {
-		log.Printf("validateOpeningFeeParams: promise not valid anymore: %v", t)
+		node.logger.Printf("validateOpeningFeeParams: promise not valid anymore: %v", t)
 		return false
 	}
 
 	return true
 }
 
+// Signed payment registrations are accepted this long before and after their
+// timestamp.
+const maxPaymentSignatureAge = 10 * time.Minute
+
+// A nonce has to be remembered as long as its request can be accepted, which
+// is up to twice the maximum age after it was stored.
+const nonceRetention = 2 * maxPaymentSignatureAge
+
+// Time to remove the nonce of a request that ran out of time.
+const nonceCleanupTimeout = 5 * time.Second
+
+// verifyPaymentSignature verifies the payment registration in data was signed
+// by the destination node and is not a replay of an earlier registration.
+func (s *channelOpenerServer) verifyPaymentSignature(
+	ctx context.Context,
+	node *node,
+	signature []byte,
+	data []byte,
+	pi *lspdrpc.PaymentInformation,
+) error {
+	if len(signature) == 0 {
+		if node.nodeConfig.RequireSignedPayments {
+			return lsperrors.ErrSignatureRequired
+		}
+
+		node.logger.Printf("DEPRECATED: RegisterPayment without signature.")
+		return nil
+	}
+
+	return s.verifySignedRequest(ctx, pi.Destination, signature, data, pi.Timestamp, pi.Nonce)
+}
+
+// verifySignedRequest verifies data was signed by the destination node, and
+// is not a replay of an earlier request.
+func (s *channelOpenerServer) verifySignedRequest(
+	ctx context.Context,
+	destination []byte,
+	signature []byte,
+	data []byte,
+	timestamp int64,
+	nonce []byte,
+) error {
+	pubkey, err := btcec.ParsePubKey(destination)
+	if err != nil {
+		return fmt.Errorf("%w: unable to parse destination: %v", lsperrors.ErrInvalidSignature, err)
+	}
+	wireSig, err := lnwire.NewSigFromRawSignature(signature)
+	if err != nil {
+		return fmt.Errorf("%w: failed to decode signature: %v", lsperrors.ErrInvalidSignature, err)
+	}
+	sig, err := wireSig.ToSignature()
+	if err != nil {
+		return fmt.Errorf("%w: failed to convert from wire format: %v", lsperrors.ErrInvalidSignature, err)
+	}
+	// The signature is over the sha256 hash of the request.
+	digest := chainhash.HashB(data)
+	if !sig.Verify(digest, pubkey) {
+		return lsperrors.ErrInvalidSignature
+	}
+
+	if len(nonce) < 16 || len(nonce) > 64 {
+		return fmt.Errorf("%w length %d", lsperrors.ErrInvalidNonce, len(nonce))
+	}
+
+	t := time.Unix(timestamp, 0)
+	if time.Since(t) > maxPaymentSignatureAge || time.Until(t) > maxPaymentSignatureAge {
+		return fmt.Errorf("%w: %v", lsperrors.ErrTimestampOutOfRange, t.UTC())
+	}
+
+	unused, err := s.store.RegisterNonce(ctx, destination, nonce)
+	if err != nil {
+		return lsperrors.Wrap(lsperrors.ErrInternal, fmt.Errorf("RegisterNonce() error: %w", err))
+	}
+	if !unused {
+		return lsperrors.ErrNonceReused
+	}
+
+	return nil
+}
+
 func (s *channelOpenerServer) RegisterPayment(
 	ctx context.Context,
 	in *lspdrpc.RegisterPaymentRequest,
@@ -198,34 +404,49 @@ func (s *channelOpenerServer) RegisterPayment(
 		return nil, err
 	}
 
-	data, err := ecies.Decrypt(node.eciesPrivateKey, in.Blob)
+	data, err := decryptBlob(node, in.KeyId, in.Blob)
 	if err != nil {
-		log.Printf("ecies.Decrypt(%x) error: %v", in.Blob, err)
-		data, err = btceclegacy.Decrypt(node.privateKey, in.Blob)
-		if err != nil {
-			log.Printf("btcec.Decrypt(%x) error: %v", in.Blob, err)
-			return nil, fmt.Errorf("btcec.Decrypt(%x) error: %w", in.Blob, err)
-		}
+		return nil, err
 	}
 
 	var pi lspdrpc.PaymentInformation
 	err = proto.Unmarshal(data, &pi)
 	if err != nil {
-		log.Printf("proto.Unmarshal(%x) error: %v", data, err)
-		return nil, fmt.Errorf("proto.Unmarshal(%x) error: %w", data, err)
+		node.logger.Printf("proto.Unmarshal(%x) error: %v", data, err)
+		return nil, fmt.Errorf("%w: proto.Unmarshal(%x) error: %v", lsperrors.ErrInvalidRequest, data, err)
+	}
+	node.logger.Printf("RegisterPayment - Destination: %x, pi.PaymentHash: %x, pi.PaymentSecret: %x, pi.IncomingAmountMsat: %v, pi.OutgoingAmountMsat: %v, pi.Tag: %v, pi.Priority: %v, pi.CommitmentType: %v",
+		pi.Destination, pi.PaymentHash, pi.PaymentSecret, pi.IncomingAmountMsat, pi.OutgoingAmountMsat, pi.Tag, pi.Priority, pi.CommitmentType)
+
+	err = s.verifyPaymentSignature(ctx, node, in.Signature, data, &pi)
+	if err != nil {
+		node.logger.Printf("verifyPaymentSignature(%x) error: %v", pi.Destination, err)
+		return nil, err
+	}
+
+	err = s.scorers[node.nodeConfig.NodePubkey].Check(ctx, pi.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkAddressHints(pi.AddressHints)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pi.ClientAgent) > 100 {
+		return nil, fmt.Errorf("%w: client agent too long", lsperrors.ErrInvalidRequest)
 	}
-	log.Printf("RegisterPayment - Destination: %x, pi.PaymentHash: %x, pi.PaymentSecret: %x, pi.IncomingAmountMsat: %v, pi.OutgoingAmountMsat: %v, pi.Tag: %v",
-		pi.Destination, pi.PaymentHash, pi.PaymentSecret, pi.IncomingAmountMsat, pi.OutgoingAmountMsat, pi.Tag)
 
 	if len(pi.Tag) > 1000 {
-		return nil, fmt.Errorf("tag too long")
+		return nil, fmt.Errorf("%w: too long", lsperrors.ErrInvalidTag)
 	}
 
 	if len(pi.Tag) != 0 {
 		var tag json.RawMessage
 		err = json.Unmarshal([]byte(pi.Tag), &tag)
 		if err != nil {
-			return nil, fmt.Errorf("tag is not a valid json object")
+			return nil, fmt.Errorf("%w: not a valid json object", lsperrors.ErrInvalidTag)
 		}
 	}
 
@@ -234,10 +455,10 @@ func (s *channelOpenerServer) RegisterPayment(
 	if pi.OpeningFeeParams != nil {
 		valid := validateOpeningFeeParams(node, pi.OpeningFeeParams)
 		if !valid {
-			return nil, fmt.Errorf("invalid opening_fee_params")
+			return nil, lsperrors.ErrInvalidFeeParams
 		}
 	} else {
-		log.Printf("DEPRECATED: RegisterPayment with deprecated fee mechanism.")
+		node.logger.Printf("DEPRECATED: RegisterPayment with deprecated fee mechanism.")
 		pi.OpeningFeeParams = &lspdrpc.OpeningFeeParams{
 			MinMsat:              uint64(node.nodeConfig.ChannelMinimumFeeMsat),
 			Proportional:         uint32(node.nodeConfig.ChannelFeePermyriad * 100),
@@ -247,10 +468,54 @@ func (s *channelOpenerServer) RegisterPayment(
 		}
 	}
 
-	err = checkPayment(pi.OpeningFeeParams, pi.IncomingAmountMsat, pi.OutgoingAmountMsat)
+	// Payments registered with a priority tier pay its fee on top of the
+	// opening fee.
+	var extraFeeMsat uint64
+	if pi.Priority != "" {
+		tier := node.nodeConfig.PriorityTiers[pi.Priority]
+		if tier == nil {
+			return nil, fmt.Errorf("%w: unknown priority tier '%s'", lsperrors.ErrInvalidRequest, pi.Priority)
+		}
+		extraFeeMsat = tier.ExtraFeeMsat
+	}
+
+	tok := s.getToken(ctx, node, token)
+	commitmentType, err := requestedCommitmentType(node, pi.CommitmentType, !tokens.PublicChannels(tok, node.nodeConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkPayment(pi.OpeningFeeParams, extraFeeMsat, pi.IncomingAmountMsat, pi.OutgoingAmountMsat)
 	if err != nil {
-		log.Printf("checkPayment(%v, %v) error: %v", pi.IncomingAmountMsat, pi.OutgoingAmountMsat, err)
-		return nil, fmt.Errorf("checkPayment(%v, %v) error: %v", pi.IncomingAmountMsat, pi.OutgoingAmountMsat, err)
+		node.logger.Printf("checkPayment(%v, %v) error: %v", pi.IncomingAmountMsat, pi.OutgoingAmountMsat, err)
+		return nil, fmt.Errorf("checkPayment(%v, %v) error: %w", pi.IncomingAmountMsat, pi.OutgoingAmountMsat, err)
+	}
+
+	if min := node.nodeConfig.MinPaymentSizeMsat; min > 0 && pi.IncomingAmountMsat < int64(min) {
+		return nil, fmt.Errorf("%w of %v msat", lsperrors.ErrBelowMinimum, min)
+	}
+
+	err = checkWumbo(node.nodeConfig.Wumbo, pi.IncomingAmountMsat, pi.OutgoingAmountMsat)
+	if err != nil {
+		node.logger.Printf("checkWumbo(%v, %v) error: %v", pi.IncomingAmountMsat, pi.OutgoingAmountMsat, err)
+		return nil, err
+	}
+
+	capacity := basetypes.Satoshi(tokens.ChannelCapacity(tok, token, node.nodeConfig, pi.IncomingAmountMsat))
+	if !getRestrictions(ctx).AllowChannel(pi.Destination, int64(capacity)) {
+		return nil, lsperrors.ErrCaveatViolated
+	}
+
+	// Reject the registration early if the node cannot afford to open the
+	// channel. Otherwise the payment would only fail once the htlc arrives.
+	if m, ok := s.liquidityManagers[node.nodeConfig.NodePubkey]; ok {
+		canAfford, err := m.CanAfford(capacity)
+		if err != nil {
+			node.logger.Printf("CanAfford(%v) error: %v", capacity, err)
+		} else if !canAfford {
+			node.logger.Printf("RegisterPayment: insufficient liquidity to open channel with capacity %v", capacity)
+			return nil, lsperrors.ErrInsufficientLiquidity
+		}
 	}
 	params := &interceptor.OpeningFeeParams{
 		MinMsat:              pi.OpeningFeeParams.MinMsat,
@@ -260,96 +525,404 @@ func (s *channelOpenerServer) RegisterPayment(
 		MaxClientToSelfDelay: pi.OpeningFeeParams.MaxClientToSelfDelay,
 		Promise:              pi.OpeningFeeParams.Promise,
 	}
-	err = s.store.RegisterPayment(token, params, pi.Destination, pi.PaymentHash, pi.PaymentSecret, pi.IncomingAmountMsat, pi.OutgoingAmountMsat, pi.Tag)
+	lspNodeID, _ := hex.DecodeString(node.nodeConfig.NodePubkey)
+	err = s.store.RegisterPayment(ctx, token, lspNodeID, params, pi.Destination, pi.PaymentHash, pi.PaymentSecret, pi.IncomingAmountMsat, pi.OutgoingAmountMsat, pi.Tag, pi.Priority, commitmentType)
 	if err != nil {
-		log.Printf("RegisterPayment() error: %v", err)
-		return nil, fmt.Errorf("RegisterPayment() error: %w", err)
+		node.logger.Printf("RegisterPayment() error: %v", err)
+		s.forgetNonce(ctx, node, pi.Destination, pi.Nonce)
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, fmt.Errorf("RegisterPayment() error: %w", err))
+	}
+
+	if len(pi.AddressHints) > 0 {
+		err = s.store.SaveAddressHints(ctx, lspNodeID, pi.Destination, pi.AddressHints)
+		if err != nil {
+			node.logger.Printf("SaveAddressHints(%x) error: %v", pi.Destination, err)
+		}
+	}
+
+	if pi.ClientAgent != "" {
+		err = s.store.SaveClientAgent(ctx, lspNodeID, pi.Destination, pi.ClientAgent)
+		if err != nil {
+			node.logger.Printf("SaveClientAgent(%x) error: %v", pi.Destination, err)
+		}
 	}
-	return &lspdrpc.RegisterPaymentReply{}, nil
+
+	reply := &lspdrpc.RegisterPaymentReply{}
+	if i, ok := s.interceptors[node.nodeConfig.NodePubkey]; ok {
+		// Clients that put the fake scid in their route hint get their htlcs
+		// matched by the scid. Others keep working with the payment hash, so
+		// the registration doesn't fail without a fake scid.
+		fakeScid, err := i.AssignFakeScid(ctx, pi.PaymentHash)
+		if err != nil {
+			node.logger.Printf("AssignFakeScid(%x) error: %v", pi.PaymentHash, err)
+		} else {
+			reply.FakeScid = uint64(fakeScid)
+		}
+
+		// The registration may have been updated, so htlcs held for the
+		// payment are intercepted again with the new registration.
+		i.RegistrationUpdated(pi.PaymentHash)
+	}
+	return reply, nil
 }
 
-func (s *channelOpenerServer) OpenChannel(ctx context.Context, in *lspdrpc.OpenChannelRequest) (*lspdrpc.OpenChannelReply, error) {
+// CancelPayment deletes the registration of a payment the client abandoned,
+// unless a channel was already opened for it, and fails the htlcs held for
+// it.
+func (s *channelOpenerServer) CancelPayment(
+	ctx context.Context,
+	in *lspdrpc.CancelPaymentRequest,
+) (*lspdrpc.CancelPaymentReply, error) {
 	node, _, err := s.getNode(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	r, err, _ := node.openChannelReqGroup.Do(in.Pubkey, func() (interface{}, error) {
-		pubkey, err := hex.DecodeString(in.Pubkey)
-		if err != nil {
-			return nil, err
-		}
+	data, err := decryptBlob(node, in.KeyId, in.Blob)
+	if err != nil {
+		return nil, err
+	}
+
+	var ci lspdrpc.CancelPaymentInformation
+	err = proto.Unmarshal(data, &ci)
+	if err != nil {
+		node.logger.Printf("proto.Unmarshal(%x) error: %v", data, err)
+		return nil, fmt.Errorf("%w: proto.Unmarshal(%x) error: %v", lsperrors.ErrInvalidRequest, data, err)
+	}
+	node.logger.Printf("CancelPayment - Destination: %x, PaymentHash: %x", ci.Destination, ci.PaymentHash)
 
-		channelCount, err := node.client.GetNodeChannelCount(pubkey)
+	// Only the destination can cancel its registration, so the signature is
+	// always required.
+	if len(in.Signature) == 0 {
+		return nil, lsperrors.ErrSignatureRequired
+	}
+	err = s.verifySignedRequest(ctx, ci.Destination, in.Signature, data, ci.Timestamp, ci.Nonce)
+	if err != nil {
+		node.logger.Printf("verifySignedRequest(%x) error: %v", ci.Destination, err)
+		return nil, err
+	}
+
+	cancelled, err := s.store.CancelPayment(ctx, ci.PaymentHash, ci.Destination)
+	if err != nil {
+		node.logger.Printf("CancelPayment() error: %v", err)
+		s.forgetNonce(ctx, node, ci.Destination, ci.Nonce)
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, fmt.Errorf("CancelPayment() error: %w", err))
+	}
+	if !cancelled {
+		return nil, fmt.Errorf("%w or channel already opened", lsperrors.ErrPaymentNotFound)
+	}
+
+	if i, ok := s.interceptors[node.nodeConfig.NodePubkey]; ok {
+		i.RegistrationCancelled(ci.PaymentHash)
+	}
+	return &lspdrpc.CancelPaymentReply{}, nil
+}
+
+// GeneratePaymentHash generates a preimage for the destination and returns its
+// hash, for wallets where the lsp creates the payment hash of the invoice. The
+// preimage is only released to the destination with ClaimPreimage once a
+// channel was opened for the payment, so the lsp doesn't take custody of the
+// funds.
+func (s *channelOpenerServer) GeneratePaymentHash(
+	ctx context.Context,
+	in *lspdrpc.GeneratePaymentHashRequest,
+) (*lspdrpc.GeneratePaymentHashReply, error) {
+	node, _, err := s.getNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !node.nodeConfig.PreimageHold {
+		return nil, fmt.Errorf("%w lsp generated payment hashes", lsperrors.ErrUnsupported)
+	}
+
+	data, err := decryptBlob(node, in.KeyId, in.Blob)
+	if err != nil {
+		return nil, err
+	}
+
+	var gi lspdrpc.GeneratePaymentHashInformation
+	err = proto.Unmarshal(data, &gi)
+	if err != nil {
+		node.logger.Printf("proto.Unmarshal(%x) error: %v", data, err)
+		return nil, fmt.Errorf("%w: proto.Unmarshal(%x) error: %v", lsperrors.ErrInvalidRequest, data, err)
+	}
+
+	// The preimage is only released to the destination, so the signature is
+	// always required.
+	if len(in.Signature) == 0 {
+		return nil, lsperrors.ErrSignatureRequired
+	}
+	err = s.verifySignedRequest(ctx, gi.Destination, in.Signature, data, gi.Timestamp, gi.Nonce)
+	if err != nil {
+		node.logger.Printf("verifySignedRequest(%x) error: %v", gi.Destination, err)
+		return nil, err
+	}
+
+	preimage := make([]byte, 32)
+	_, err = rand.Read(preimage)
+	if err != nil {
+		node.logger.Printf("rand.Read() error: %v", err)
+		return nil, fmt.Errorf("rand.Read() error: %w", err)
+	}
+	paymentHash := sha256.Sum256(preimage)
+
+	lspNodeID, _ := hex.DecodeString(node.nodeConfig.NodePubkey)
+	err = s.store.InsertPreimage(ctx, lspNodeID, gi.Destination, paymentHash[:], preimage)
+	if err != nil {
+		node.logger.Printf("InsertPreimage() error: %v", err)
+		s.forgetNonce(ctx, node, gi.Destination, gi.Nonce)
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, fmt.Errorf("InsertPreimage() error: %w", err))
+	}
+
+	node.logger.Printf("GeneratePaymentHash - Destination: %x, PaymentHash: %x", gi.Destination, paymentHash)
+	return &lspdrpc.GeneratePaymentHashReply{PaymentHash: paymentHash[:]}, nil
+}
+
+// ClaimPreimage releases the preimage generated with GeneratePaymentHash to
+// the destination, once a channel was opened for the payment.
+func (s *channelOpenerServer) ClaimPreimage(
+	ctx context.Context,
+	in *lspdrpc.ClaimPreimageRequest,
+) (*lspdrpc.ClaimPreimageReply, error) {
+	node, _, err := s.getNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decryptBlob(node, in.KeyId, in.Blob)
+	if err != nil {
+		return nil, err
+	}
+
+	var ci lspdrpc.ClaimPreimageInformation
+	err = proto.Unmarshal(data, &ci)
+	if err != nil {
+		node.logger.Printf("proto.Unmarshal(%x) error: %v", data, err)
+		return nil, fmt.Errorf("%w: proto.Unmarshal(%x) error: %v", lsperrors.ErrInvalidRequest, data, err)
+	}
+	node.logger.Printf("ClaimPreimage - Destination: %x, PaymentHash: %x", ci.Destination, ci.PaymentHash)
+
+	if len(in.Signature) == 0 {
+		return nil, lsperrors.ErrSignatureRequired
+	}
+	err = s.verifySignedRequest(ctx, ci.Destination, in.Signature, data, ci.Timestamp, ci.Nonce)
+	if err != nil {
+		node.logger.Printf("verifySignedRequest(%x) error: %v", ci.Destination, err)
+		return nil, err
+	}
+
+	preimage, opened, err := s.store.ClaimPreimage(ctx, ci.PaymentHash, ci.Destination)
+	if err != nil {
+		node.logger.Printf("ClaimPreimage() error: %v", err)
+		s.forgetNonce(ctx, node, ci.Destination, ci.Nonce)
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, fmt.Errorf("ClaimPreimage() error: %w", err))
+	}
+	if preimage == nil {
+		return nil, lsperrors.ErrPaymentNotFound
+	}
+	if !opened {
+		return nil, fmt.Errorf("%w: no channel opened for the payment yet", lsperrors.ErrChannelNotReady)
+	}
+
+	return &lspdrpc.ClaimPreimageReply{Preimage: preimage}, nil
+}
+
+// forgetNonce removes the nonce of a signed request that was cancelled or ran
+// out of time after the nonce was stored, so the client can retry the same
+// signed request.
+func (s *channelOpenerServer) forgetNonce(ctx context.Context, node *node, destination []byte, nonce []byte) {
+	if ctx.Err() == nil || len(nonce) == 0 {
+		return
+	}
+
+	// The nonce is stored for the tenant of the request.
+	cleanupCtx, cancel := context.WithTimeout(tenant.NewContext(context.Background(), tenant.FromContext(ctx)), nonceCleanupTimeout)
+	defer cancel()
+	err := s.store.ForgetNonce(cleanupCtx, destination, nonce)
+	if err != nil {
+		node.logger.Printf("ForgetNonce(%x, %x) error: %v", destination, nonce, err)
+	}
+}
+
+// decryptBlob decrypts a blob sent by a client with the encryption key of the
+// node with the given id.
+func decryptBlob(node *node, keyID string, blob []byte) ([]byte, error) {
+	data, _, _, err := node.keys.decrypt(keyID, blob, node.logger)
+	if err != nil {
+		node.logger.Printf("decryptBlob(%s) error: %v", keyID, err)
+		return nil, fmt.Errorf("%w: %v", lsperrors.ErrInvalidRequest, err)
+	}
+
+	return data, nil
+}
+
+func (s *channelOpenerServer) OpenChannel(ctx context.Context, in *lspdrpc.OpenChannelRequest) (*lspdrpc.OpenChannelReply, error) {
+	node, token, err := s.getNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	runner, ok := s.openChannelRunners[node.nodeConfig.NodePubkey]
+	if !ok {
+		return nil, lsperrors.ErrUnsupported
+	}
+
+	pubkey, err := hex.DecodeString(in.Pubkey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid pubkey: %v", lsperrors.ErrInvalidRequest, err)
+	}
+
+	if !getRestrictions(ctx).AllowChannel(pubkey, int64(node.nodeConfig.ChannelAmount)) {
+		return nil, lsperrors.ErrCaveatViolated
+	}
+
+	tok := s.getToken(ctx, node, token)
+	if node.challenger.Required(tok, token) {
+		err = node.challenger.Verify(ctx, token, pubkey, in.Challenge, in.Nonce, in.CaptchaToken)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		var outPoint *wire.OutPoint
-		if channelCount == 0 {
-			outPoint, err = node.client.OpenChannel(&lightning.OpenChannelRequest{
-				CapacitySat: node.nodeConfig.ChannelAmount,
-				Destination: pubkey,
-				TargetConf:  &node.nodeConfig.TargetConf,
-				MinHtlcMsat: node.nodeConfig.MinHtlcMsat,
-				IsPrivate:   node.nodeConfig.ChannelPrivate,
-			})
-
-			if err != nil {
-				log.Printf("Error in OpenChannel: %v", err)
-				return nil, err
-			}
-
-			log.Printf("Response from OpenChannel: (TX: %v)", outPoint.String())
-		}
+	err = s.scorers[node.nodeConfig.NodePubkey].Check(ctx, pubkey)
+	if err != nil {
+		return nil, err
+	}
 
-		return &lspdrpc.OpenChannelReply{TxHash: outPoint.Hash.String(), OutputIndex: outPoint.Index}, nil
-	})
+	commitmentType, err := requestedCommitmentType(node, in.CommitmentType, node.nodeConfig.ChannelPrivate)
+	if err != nil {
+		return nil, err
+	}
+	if commitmentType == "" {
+		commitmentType = tokens.CommitmentType(tok, node.nodeConfig)
+	}
 
+	// Clients retrying their request after the channel was opened get the
+	// existing channel instead of a second one.
+	existing, err := runner.ExistingChannel(ctx, pubkey)
+	if err != nil {
+		node.logger.Printf("OpenChannel(%s) error: %v", in.Pubkey, err)
+		return nil, lsperrors.Wrap(lsperrors.ErrNodeUnavailable, err)
+	}
+	if existing != nil {
+		return nil, lsperrors.WithMetadata(
+			fmt.Errorf("%w: %s", lsperrors.ErrChannelExists, existing.ChannelPoint),
+			map[string]string{"channel_point": existing.ChannelPoint},
+		)
+	}
+
+	limiter := s.quotas[node.nodeConfig.NodePubkey]
+	reservation, err := limiter.Reserve(ctx, tok, token, int64(node.nodeConfig.ChannelAmount))
 	if err != nil {
 		return nil, err
 	}
-	return r.(*lspdrpc.OpenChannelReply), err
+
+	// The channel is opened in the background, so the open completes even if
+	// the client gives up waiting. A retry by the client gets the same job,
+	// which counted towards the quota already.
+	submittedAt := time.Now()
+	job, err := runner.Submit(ctx, pubkey, commitmentType)
+	if err != nil {
+		limiter.Release(ctx, reservation)
+		node.logger.Printf("OpenChannel(%s) error: %v", in.Pubkey, err)
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, err)
+	}
+	if job.CreatedAt.Before(submittedAt) {
+		limiter.Release(ctx, reservation)
+	}
+
+	reply := &lspdrpc.OpenChannelReply{JobId: job.ID}
+	if outPoint := jobOutPoint(node, job); outPoint != nil {
+		reply.TxHash = outPoint.Hash.String()
+		reply.OutputIndex = outPoint.Index
+	}
+
+	return reply, nil
 }
 
-func (n *node) getSignedEncryptedData(in *lspdrpc.Encrypted) (string, []byte, bool, error) {
-	usedEcies := true
-	signedBlob, err := ecies.Decrypt(n.eciesPrivateKey, in.Data)
+func (s *channelOpenerServer) GetChannelOpenStatus(ctx context.Context, in *lspdrpc.GetChannelOpenStatusRequest) (*lspdrpc.GetChannelOpenStatusReply, error) {
+	node, _, err := s.getNode(ctx)
 	if err != nil {
-		log.Printf("ecies.Decrypt(%x) error: %v", in.Data, err)
-		usedEcies = false
-		signedBlob, err = btceclegacy.Decrypt(n.privateKey, in.Data)
-		if err != nil {
-			log.Printf("btcec.Decrypt(%x) error: %v", in.Data, err)
-			return "", nil, usedEcies, fmt.Errorf("btcec.Decrypt(%x) error: %w", in.Data, err)
-		}
+		return nil, err
+	}
+
+	runner, ok := s.openChannelRunners[node.nodeConfig.NodePubkey]
+	if !ok {
+		return nil, lsperrors.ErrUnsupported
+	}
+
+	job, err := runner.Get(ctx, in.JobId)
+	if err != nil {
+		node.logger.Printf("GetChannelOpenStatus(%s) error: %v", in.JobId, err)
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, err)
+	}
+	if job == nil {
+		return nil, lsperrors.ErrJobNotFound
+	}
+
+	reply := &lspdrpc.GetChannelOpenStatusReply{
+		JobId:    job.ID,
+		Pubkey:   hex.EncodeToString(job.PeerID),
+		Status:   job.Status,
+		Attempts: uint32(job.Attempts),
+		Error:    job.LastError,
+	}
+	if outPoint := jobOutPoint(node, job); outPoint != nil {
+		reply.TxHash = outPoint.Hash.String()
+		reply.OutputIndex = outPoint.Index
+	}
+
+	return reply, nil
+}
+
+// jobOutPoint returns the funding outpoint of the channel opened by the job,
+// or nil if it didn't open a channel.
+func jobOutPoint(node *node, job *openchannel.Job) *wire.OutPoint {
+	if job.ChannelPoint == "" {
+		return nil
+	}
+
+	outPoint, err := basetypes.ParseOutPoint(job.ChannelPoint)
+	if err != nil {
+		node.logger.Printf("Invalid channel point %s of channel open job %s: %v", job.ChannelPoint, job.ID, err)
+		return nil
+	}
+
+	return outPoint
+}
+
+func (n *node) getSignedEncryptedData(in *lspdrpc.Encrypted) (string, []byte, *encryptionKey, bool, error) {
+	signedBlob, key, usedEcies, err := n.keys.decrypt(in.KeyId, in.Data, n.logger)
+	if err != nil {
+		n.logger.Printf("decrypt(%s) error: %v", in.KeyId, err)
+		return "", nil, nil, usedEcies, err
 	}
 	var signed lspdrpc.Signed
 	err = proto.Unmarshal(signedBlob, &signed)
 	if err != nil {
-		log.Printf("proto.Unmarshal(%x) error: %v", signedBlob, err)
-		return "", nil, usedEcies, fmt.Errorf("proto.Unmarshal(%x) error: %w", signedBlob, err)
+		n.logger.Printf("proto.Unmarshal(%x) error: %v", signedBlob, err)
+		return "", nil, nil, usedEcies, fmt.Errorf("%w: proto.Unmarshal(%x) error: %v", lsperrors.ErrInvalidRequest, signedBlob, err)
 	}
 	pubkey, err := btcec.ParsePubKey(signed.Pubkey)
 	if err != nil {
-		log.Printf("unable to parse pubkey: %v", err)
-		return "", nil, usedEcies, fmt.Errorf("unable to parse pubkey: %w", err)
+		n.logger.Printf("unable to parse pubkey: %v", err)
+		return "", nil, nil, usedEcies, fmt.Errorf("%w: unable to parse pubkey: %v", lsperrors.ErrInvalidSignature, err)
 	}
 	wireSig, err := lnwire.NewSigFromRawSignature(signed.Signature)
 	if err != nil {
-		return "", nil, usedEcies, fmt.Errorf("failed to decode signature: %v", err)
+		return "", nil, nil, usedEcies, fmt.Errorf("%w: failed to decode signature: %v", lsperrors.ErrInvalidSignature, err)
 	}
 	sig, err := wireSig.ToSignature()
 	if err != nil {
-		return "", nil, usedEcies, fmt.Errorf("failed to convert from wire format: %v",
-			err)
+		return "", nil, nil, usedEcies, fmt.Errorf("%w: failed to convert from wire format: %v", lsperrors.ErrInvalidSignature, err)
 	}
 	// The signature is over the sha256 hash of the message.
 	digest := chainhash.HashB(signed.Data)
 	if !sig.Verify(digest, pubkey) {
-		return "", nil, usedEcies, fmt.Errorf("invalid signature")
+		return "", nil, nil, usedEcies, lsperrors.ErrInvalidSignature
 	}
-	return hex.EncodeToString(signed.Pubkey), signed.Data, usedEcies, nil
+	return hex.EncodeToString(signed.Pubkey), signed.Data, key, usedEcies, nil
 }
 
 func (s *channelOpenerServer) CheckChannels(ctx context.Context, in *lspdrpc.Encrypted) (*lspdrpc.Encrypted, error) {
@@ -358,21 +931,21 @@ func (s *channelOpenerServer) CheckChannels(ctx context.Context, in *lspdrpc.Enc
 		return nil, err
 	}
 
-	nodeID, data, usedEcies, err := node.getSignedEncryptedData(in)
+	nodeID, data, key, usedEcies, err := node.getSignedEncryptedData(in)
 	if err != nil {
-		log.Printf("getSignedEncryptedData error: %v", err)
-		return nil, fmt.Errorf("getSignedEncryptedData error: %v", err)
+		node.logger.Printf("getSignedEncryptedData error: %v", err)
+		return nil, fmt.Errorf("getSignedEncryptedData error: %w", err)
 	}
 	var checkChannelsRequest lspdrpc.CheckChannelsRequest
 	err = proto.Unmarshal(data, &checkChannelsRequest)
 	if err != nil {
-		log.Printf("proto.Unmarshal(%x) error: %v", data, err)
-		return nil, fmt.Errorf("proto.Unmarshal(%x) error: %w", data, err)
+		node.logger.Printf("proto.Unmarshal(%x) error: %v", data, err)
+		return nil, fmt.Errorf("%w: proto.Unmarshal(%x) error: %v", lsperrors.ErrInvalidRequest, data, err)
 	}
-	closedChannels, err := node.client.GetClosedChannels(nodeID, checkChannelsRequest.WaitingCloseChannels)
+	closedChannels, err := node.client.GetClosedChannels(ctx, nodeID, checkChannelsRequest.WaitingCloseChannels)
 	if err != nil {
-		log.Printf("GetClosedChannels(%v) error: %v", checkChannelsRequest.FakeChannels, err)
-		return nil, fmt.Errorf("GetClosedChannels(%v) error: %w", checkChannelsRequest.FakeChannels, err)
+		node.logger.Printf("GetClosedChannels(%v) error: %v", checkChannelsRequest.FakeChannels, err)
+		return nil, lsperrors.Wrap(lsperrors.ErrNodeUnavailable, fmt.Errorf("GetClosedChannels(%v) error: %w", checkChannelsRequest.FakeChannels, err))
 	}
 	checkChannelsReply := lspdrpc.CheckChannelsReply{
 		NotFakeChannels: make(map[string]uint64),
@@ -380,54 +953,114 @@ func (s *channelOpenerServer) CheckChannels(ctx context.Context, in *lspdrpc.Enc
 	}
 	dataReply, err := proto.Marshal(&checkChannelsReply)
 	if err != nil {
-		log.Printf("proto.Marshall() error: %v", err)
+		node.logger.Printf("proto.Marshall() error: %v", err)
 		return nil, fmt.Errorf("proto.Marshal() error: %w", err)
 	}
 	pubkey, err := btcec.ParsePubKey(checkChannelsRequest.EncryptPubkey)
 	if err != nil {
-		log.Printf("unable to parse pubkey: %v", err)
-		return nil, fmt.Errorf("unable to parse pubkey: %w", err)
+		node.logger.Printf("unable to parse pubkey: %v", err)
+		return nil, fmt.Errorf("%w: unable to parse pubkey: %v", lsperrors.ErrInvalidRequest, err)
 	}
 
 	var encrypted []byte
 	if usedEcies {
-		encrypted, err = ecies.Encrypt(node.eciesPublicKey, dataReply)
+		encrypted, err = ecies.Encrypt(key.eciesPublicKey, dataReply)
 		if err != nil {
-			log.Printf("ecies.Encrypt() error: %v", err)
+			node.logger.Printf("ecies.Encrypt() error: %v", err)
 			return nil, fmt.Errorf("ecies.Encrypt() error: %w", err)
 		}
 	} else {
 		encrypted, err = btceclegacy.Encrypt(pubkey, dataReply)
 		if err != nil {
-			log.Printf("btcec.Encrypt() error: %v", err)
+			node.logger.Printf("btcec.Encrypt() error: %v", err)
 			return nil, fmt.Errorf("btcec.Encrypt() error: %w", err)
 		}
 	}
 
-	return &lspdrpc.Encrypted{Data: encrypted}, nil
+	return &lspdrpc.Encrypted{Data: encrypted, KeyId: key.id}, nil
 }
 
 func (s *channelOpenerServer) getNode(ctx context.Context) (*node, string, error) {
 	nd := ctx.Value(contextKey("node"))
 	if nd == nil {
-		return nil, "", status.Errorf(codes.PermissionDenied, "Not authorized")
+		return nil, "", lsperrors.ErrNotAuthorized
 	}
 
 	nodeContext, ok := nd.(*nodeContext)
 	if !ok {
-		return nil, "", status.Errorf(codes.PermissionDenied, "Not authorized")
+		return nil, "", lsperrors.ErrNotAuthorized
 	}
 
 	return nodeContext.node, nodeContext.token, nil
 }
 
-func checkPayment(params *lspdrpc.OpeningFeeParams, incomingAmountMsat, outgoingAmountMsat int64) error {
+// getRestrictions returns the caveats of the macaroon the rpc was
+// authenticated with, or nil for tokens.
+func getRestrictions(ctx context.Context) *macaroon.Restrictions {
+	nodeContext, ok := ctx.Value(contextKey("node")).(*nodeContext)
+	if !ok {
+		return nil
+	}
+
+	return nodeContext.restrictions
+}
+
+// Maximum number of address hints of a registration.
+const maxAddressHints = 5
+
+// checkAddressHints makes sure the address hints of a registration are
+// host:port addresses.
+func checkAddressHints(hints []string) error {
+	if len(hints) > maxAddressHints {
+		return fmt.Errorf("%w: more than %d address hints", lsperrors.ErrInvalidRequest, maxAddressHints)
+	}
+
+	for _, hint := range hints {
+		host, port, err := net.SplitHostPort(hint)
+		if err != nil || host == "" || port == "" || len(hint) > 255 {
+			return fmt.Errorf("%w: invalid address hint '%s'", lsperrors.ErrInvalidRequest, hint)
+		}
+	}
+
+	return nil
+}
+
+// checkWumbo rejects payments that are too large for a channel, or for a
+// single htlc if the payment may not be split.
+func checkWumbo(cfg *config.WumboConfig, incomingAmountMsat, outgoingAmountMsat int64) error {
+	if cfg == nil {
+		return nil
+	}
+
+	incoming, err := basetypes.NewMilliSatoshi(incomingAmountMsat)
+	if err != nil {
+		return fmt.Errorf("%w: incoming amount: %v", lsperrors.ErrInvalidAmount, err)
+	}
+
+	outgoing, err := basetypes.NewMilliSatoshi(outgoingAmountMsat)
+	if err != nil {
+		return fmt.Errorf("%w: outgoing amount: %v", lsperrors.ErrInvalidAmount, err)
+	}
+
+	if cfg.MaxChannelCapacitySat > 0 && incoming.ToSatoshi() >= cfg.MaxChannelCapacitySat {
+		return fmt.Errorf("%w of %v", lsperrors.ErrExceedsMaxCapacity, cfg.MaxChannelCapacitySat)
+	}
+
+	if cfg.SplitPolicy == "reject" && cfg.MaxHtlcMsat > 0 && outgoing > cfg.MaxHtlcMsat {
+		return fmt.Errorf("%w of %v", lsperrors.ErrExceedsMaxHtlc, cfg.MaxHtlcMsat)
+	}
+
+	return nil
+}
+
+func checkPayment(params *lspdrpc.OpeningFeeParams, extraFeeMsat uint64, incomingAmountMsat, outgoingAmountMsat int64) error {
 	fees := incomingAmountMsat * int64(params.Proportional) / 1_000_000 / 1_000 * 1_000
 	if fees < int64(params.MinMsat) {
 		fees = int64(params.MinMsat)
 	}
+	fees += int64(extraFeeMsat)
 	if incomingAmountMsat-outgoingAmountMsat < fees {
-		return fmt.Errorf("not enough fees")
+		return lsperrors.ErrNotEnoughFees
 	}
 	return nil
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/stretchr/testify/assert"
+)
+
+// nonceStore remembers the registered nonces, like the database does.
+type nonceStore struct {
+	interceptor.InterceptStore
+	nonces map[string]bool
+}
+
+func (s *nonceStore) RegisterNonce(ctx context.Context, destination, nonce []byte) (bool, error) {
+	key := hex.EncodeToString(destination) + hex.EncodeToString(nonce)
+	if s.nonces[key] {
+		return false, nil
+	}
+
+	s.nonces[key] = true
+	return true, nil
+}
+
+// signRequest signs the request like the destination node does, with the
+// recovery byte of the compact signature dropped.
+func signRequest(t *testing.T, key *btcec.PrivateKey, data []byte) []byte {
+	sig, err := ecdsa.SignCompact(key, chainhash.HashB(data), true)
+	assert.NoError(t, err)
+	return sig[1:]
+}
+
+func TestVerifySignedRequest(t *testing.T) {
+	key, err := btcec.NewPrivateKey()
+	assert.NoError(t, err)
+	otherKey, err := btcec.NewPrivateKey()
+	assert.NoError(t, err)
+	destination := key.PubKey().SerializeCompressed()
+	data := []byte("payment information")
+	nonce := bytes.Repeat([]byte{1}, 16)
+
+	tests := []struct {
+		name      string
+		key       *btcec.PrivateKey
+		signed    []byte
+		timestamp time.Time
+		nonce     []byte
+		reused    bool
+		err       error
+	}{
+		{name: "valid", nonce: nonce},
+		{name: "longest nonce", nonce: bytes.Repeat([]byte{1}, 64)},
+		{name: "wrong key", key: otherKey, nonce: nonce, err: lsperrors.ErrInvalidSignature},
+		{name: "tampered payload", signed: []byte("payment informatioN"), nonce: nonce, err: lsperrors.ErrInvalidSignature},
+		{name: "nonce too short", nonce: bytes.Repeat([]byte{1}, 15), err: lsperrors.ErrInvalidNonce},
+		{name: "nonce too long", nonce: bytes.Repeat([]byte{1}, 65), err: lsperrors.ErrInvalidNonce},
+		{name: "timestamp too old", timestamp: time.Now().Add(-maxPaymentSignatureAge - time.Minute), nonce: nonce, err: lsperrors.ErrTimestampOutOfRange},
+		{name: "timestamp in the future", timestamp: time.Now().Add(maxPaymentSignatureAge + time.Minute), nonce: nonce, err: lsperrors.ErrTimestampOutOfRange},
+		{name: "nonce reused", nonce: nonce, reused: true, err: lsperrors.ErrNonceReused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &nonceStore{nonces: make(map[string]bool)}
+			s := &channelOpenerServer{store: store}
+			if tt.reused {
+				_, err := store.RegisterNonce(context.Background(), destination, tt.nonce)
+				assert.NoError(t, err)
+			}
+
+			signingKey := key
+			if tt.key != nil {
+				signingKey = tt.key
+			}
+			signed := data
+			if tt.signed != nil {
+				signed = tt.signed
+			}
+			timestamp := time.Now()
+			if !tt.timestamp.IsZero() {
+				timestamp = tt.timestamp
+			}
+
+			err := s.verifySignedRequest(
+				context.Background(),
+				destination,
+				signRequest(t, signingKey, signed),
+				data,
+				timestamp.Unix(),
+				tt.nonce,
+			)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/breez/lspd/interceptor"
+)
+
+// Interval between the runs of the cleanup job.
+const cleanupInterval = 10 * time.Minute
+
+// cleanupJob periodically removes what lspd keeps about requests and
+// registrations that are gone: the nonces of signed requests that can't be
+// accepted anymore and the fake scids of cancelled and expired
+// registrations. It runs on every replica, the cleanup is the same whichever
+// replica runs it.
+type cleanupJob struct {
+	store        interceptor.InterceptStore
+	interceptors []*interceptor.Interceptor
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newCleanupJob(store interceptor.InterceptStore, interceptors []*interceptor.Interceptor) *cleanupJob {
+	// The context is created here, so Stop ends the job also when it runs
+	// before Start.
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cleanupJob{
+		store:        store,
+		interceptors: interceptors,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+func (j *cleanupJob) Start() error {
+	for {
+		select {
+		case <-j.ctx.Done():
+			return nil
+		case <-time.After(cleanupInterval):
+		}
+
+		j.run(j.ctx)
+	}
+}
+
+func (j *cleanupJob) Stop() {
+	j.cancel()
+}
+
+func (j *cleanupJob) run(ctx context.Context) {
+	deleted, err := j.store.DeleteNonces(ctx, time.Now().Add(-nonceRetention))
+	if err != nil {
+		log.Printf("cleanup: DeleteNonces() error: %v", err)
+	} else if deleted > 0 {
+		log.Printf("cleanup: removed %d expired nonces", deleted)
+	}
+
+	for _, i := range j.interceptors {
+		err := i.PruneScids(ctx)
+		if err != nil {
+			log.Printf("cleanup: PruneScids(%s) error: %v", i.Node().Label(), err)
+		}
+	}
+}
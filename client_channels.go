@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/lifecycle"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/lsperrors"
+	lspdrpc "github.com/breez/lspd/rpc"
+	"github.com/golang/protobuf/proto"
+)
+
+// ListClientChannels returns the channels the lsp opened to the destination,
+// with their capacity, state and when they expire, so wallets can show the
+// inbound liquidity they bought.
+func (s *channelOpenerServer) ListClientChannels(
+	ctx context.Context,
+	in *lspdrpc.ListClientChannelsRequest,
+) (*lspdrpc.ListClientChannelsReply, error) {
+	node, _, err := s.getNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decryptBlob(node, in.KeyId, in.Blob)
+	if err != nil {
+		return nil, err
+	}
+
+	var li lspdrpc.ListClientChannelsInformation
+	err = proto.Unmarshal(data, &li)
+	if err != nil {
+		node.logger.Printf("proto.Unmarshal(%x) error: %v", data, err)
+		return nil, fmt.Errorf("%w: proto.Unmarshal(%x) error: %v", lsperrors.ErrInvalidRequest, data, err)
+	}
+
+	// Channels are only listed to the destination itself, so the signature
+	// is always required.
+	if len(in.Signature) == 0 {
+		return nil, lsperrors.ErrSignatureRequired
+	}
+	err = s.verifySignedRequest(ctx, li.Destination, in.Signature, data, li.Timestamp, li.Nonce)
+	if err != nil {
+		node.logger.Printf("verifySignedRequest(%x) error: %v", li.Destination, err)
+		return nil, err
+	}
+
+	tracker, ok := s.channelTrackers[node.nodeConfig.NodePubkey]
+	if !ok {
+		return &lspdrpc.ListClientChannelsReply{}, nil
+	}
+
+	channels, err := tracker.PeerChannels(ctx, hex.EncodeToString(li.Destination), li.IncludeClosed)
+	if err != nil {
+		node.logger.Printf("PeerChannels(%x) error: %v", li.Destination, err)
+		s.forgetNonce(ctx, node, li.Destination, li.Nonce)
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, fmt.Errorf("PeerChannels() error: %w", err))
+	}
+
+	peerChannels, err := node.client.ListPeerChannels(ctx, li.Destination)
+	if err != nil {
+		node.logger.Printf("ListPeerChannels(%x) error: %v", li.Destination, err)
+		s.forgetNonce(ctx, node, li.Destination, li.Nonce)
+		return nil, lsperrors.Wrap(lsperrors.ErrNodeUnavailable, fmt.Errorf("ListPeerChannels() error: %w", err))
+	}
+
+	reply := &lspdrpc.ListClientChannelsReply{}
+	for _, c := range channels {
+		reply.Channels = append(reply.Channels, newClientChannel(c, peerChannels))
+	}
+
+	err = setClientFunding(ctx, node, li.Destination, reply.Channels)
+	if err != nil {
+		node.logger.Printf("ListChannelFunding(%x) error: %v", li.Destination, err)
+		s.forgetNonce(ctx, node, li.Destination, li.Nonce)
+		return nil, lsperrors.Wrap(lsperrors.ErrNodeUnavailable, fmt.Errorf("ListChannelFunding() error: %w", err))
+	}
+
+	return reply, nil
+}
+
+// newClientChannel returns the channel as listed to the client, with the
+// capacity and state the node has for it.
+func newClientChannel(c *lifecycle.ChannelStatus, peerChannels []*lightning.PeerChannel) *lspdrpc.ClientChannel {
+	channel := &lspdrpc.ClientChannel{
+		ChannelPoint:   c.ChannelPoint,
+		State:          lspdrpc.ClientChannelState_CLIENT_CHANNEL_CLOSED,
+		AliasScid:      c.InitialChanID,
+		ConfirmedScid:  c.ConfirmedChanID,
+		OpenedAt:       unixOrZero(c.OpenedAt),
+		ClosedAt:       unixOrZero(c.ClosedAt),
+		LeaseExpiresAt: unixOrZero(c.LeaseExpiresAt),
+		CommitmentType: string(c.CommitmentType),
+	}
+	if c.ClosedAt != nil {
+		return channel
+	}
+
+	// Channels the node doesn't know anymore were closed by the client, or
+	// force closed, without lspd closing them.
+	for _, p := range peerChannels {
+		if p.ChannelPoint != c.ChannelPoint {
+			continue
+		}
+
+		channel.CapacitySat = p.CapacitySat
+		channel.State = lspdrpc.ClientChannelState_CLIENT_CHANNEL_OPEN
+		if p.Pending {
+			channel.State = lspdrpc.ClientChannelState_CLIENT_CHANNEL_PENDING
+		}
+		channel.ExpiresAt = unixOrZero(c.ExpiresAt)
+	}
+
+	return channel
+}
+
+func unixOrZero(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+
+	return t.Unix()
+}
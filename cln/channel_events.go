@@ -0,0 +1,60 @@
+package cln
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/breez/lspd/cln_plugin/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// listenChannelEvents invalidates the cached scids of the client on the
+// channel events of the cln plugin, and refreshes the real scids of the
+// channel aliases.
+func (i *ClnHtlcInterceptor) listenChannelEvents() {
+	ctx := i.ctx
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		stream, err := i.pluginClient.ChannelEventStream(ctx, &proto.ChannelEventRequest{})
+		if err != nil {
+			i.logger.Printf("pluginClient.ChannelEventStream(): %v", err)
+			<-time.After(time.Second)
+			continue
+		}
+
+		// Events before the subscription were missed.
+		i.client.ResetScidCache()
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				status, ok := status.FromError(err)
+				if ok && status.Code() == codes.Unimplemented {
+					i.logger.Printf("WARN: the cln plugin doesn't send channel events, upgrade it. Cached scids expire after the scidCacheTtl instead.")
+					return
+				}
+				if !ok || status.Code() != codes.Canceled {
+					i.logger.Printf("unexpected error in listenChannelEvents: %v", err)
+				}
+				break
+			}
+
+			peerID, err := hex.DecodeString(event.PeerId)
+			if err != nil {
+				i.logger.Printf("Invalid peer id '%s' in channel event: %v", event.PeerId, err)
+				i.client.ResetScidCache()
+				continue
+			}
+
+			i.client.ChannelChanged(peerID)
+			if i.client.aliases != nil {
+				go i.client.aliases.Refresh(ctx, peerID)
+			}
+		}
+
+		<-time.After(time.Second)
+	}
+}
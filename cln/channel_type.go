@@ -0,0 +1,225 @@
+package cln
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/breez/lspd/lightning"
+	"github.com/niftynei/glightning/glightning"
+)
+
+// Feature bits of the channel type of zero conf channels with an alias, and
+// of the commitment types.
+const (
+	staticRemoteKeyFeatureBit = 12
+	anchorsFeatureBit         = 22
+	scidAliasFeatureBit       = 46
+	zeroConfFeatureBit        = 50
+	simpleTaprootFeatureBit   = 180
+)
+
+// commitmentTypeBits are the channel type feature bits of the commitment
+// types.
+var commitmentTypeBits = map[lightning.CommitmentType][]uint32{
+	lightning.CommitmentTypeStaticRemoteKey: {staticRemoteKeyFeatureBit},
+	lightning.CommitmentTypeAnchors:         {staticRemoteKeyFeatureBit, anchorsFeatureBit},
+	lightning.CommitmentTypeTaproot:         {simpleTaprootFeatureBit},
+}
+
+type getInfoFeaturesRequest struct{}
+
+func (r *getInfoFeaturesRequest) Name() string {
+	return "getinfo"
+}
+
+type getInfoFeatures struct {
+	OurFeatures struct {
+		Init string `json:"init"`
+	} `json:"our_features"`
+}
+
+type listPeersFeaturesRequest struct {
+	ID string `json:"id"`
+}
+
+func (r *listPeersFeaturesRequest) Name() string {
+	return "listpeers"
+}
+
+type listPeersFeatures struct {
+	Peers []struct {
+		ID       string `json:"id"`
+		Features string `json:"features"`
+	} `json:"peers"`
+}
+
+// fundChannelRequest is the fundchannel method with the channel_type and
+// utxos parameters, which glightning doesn't support.
+type fundChannelRequest struct {
+	ID          string   `json:"id"`
+	Amount      uint64   `json:"amount"`
+	FeeRate     string   `json:"feerate,omitempty"`
+	Announce    bool     `json:"announce"`
+	MinConf     *uint16  `json:"minconf,omitempty"`
+	MinDepth    *uint16  `json:"mindepth,omitempty"`
+	Reserve     string   `json:"reserve,omitempty"`
+	ChannelType []uint32 `json:"channel_type,omitempty"`
+	Utxos       []string `json:"utxos,omitempty"`
+}
+
+func (r *fundChannelRequest) Name() string {
+	return "fundchannel"
+}
+
+type fundChannelResult struct {
+	TxID   string `json:"txid"`
+	OutNum uint32 `json:"outnum"`
+}
+
+// SupportsCommitmentType returns whether both the node and the peer signal
+// support for channels of the commitment type.
+func (c *ClnClient) SupportsCommitmentType(peerID []byte, t lightning.CommitmentType) (bool, error) {
+	return c.supportsFeature(peerID, lightning.HasCommitmentTypeFeature(t))
+}
+
+// SupportsLargeChannels returns whether both the node and the peer signal
+// support for channels above the standard capacity limit.
+func (c *ClnClient) SupportsLargeChannels(peerID []byte) (bool, error) {
+	return c.supportsFeature(peerID, lightning.HasLargeChannelsFeature)
+}
+
+func (c *ClnClient) supportsFeature(peerID []byte, has func(hasBit func(bit uint32) bool) bool) (bool, error) {
+	var info getInfoFeatures
+	err := c.rpc.request(&getInfoFeaturesRequest{}, &info)
+	if err != nil {
+		c.logger.Printf("CLN: getinfo error: %v", err)
+		return false, err
+	}
+
+	ours, err := lightning.FeatureBits(info.OurFeatures.Init)
+	if err != nil {
+		return false, fmt.Errorf("invalid node features '%s': %w", info.OurFeatures.Init, err)
+	}
+
+	if !has(ours) {
+		return false, nil
+	}
+	if peerID == nil {
+		return true, nil
+	}
+
+	theirs, err := c.PeerFeatures(peerID)
+	if err == lightning.ErrPeerNotConnected {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return has(theirs), nil
+}
+
+// PeerFeatures returns the feature bits the connected peer signals, or
+// ErrPeerNotConnected if it isn't connected.
+func (c *ClnClient) PeerFeatures(peerID []byte) (func(bit uint32) bool, error) {
+	pubkey := hex.EncodeToString(peerID)
+	var peers listPeersFeatures
+	err := c.rpc.request(&listPeersFeaturesRequest{ID: pubkey}, &peers)
+	if err != nil {
+		c.logger.Printf("CLN: listpeers(%s) error: %v", pubkey, err)
+		return nil, err
+	}
+
+	for _, p := range peers.Peers {
+		if p.ID != pubkey {
+			continue
+		}
+
+		theirs, err := lightning.FeatureBits(p.Features)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer features '%s': %w", p.Features, err)
+		}
+
+		return theirs, nil
+	}
+
+	return nil, lightning.ErrPeerNotConnected
+}
+
+// fundChannel opens a channel of the commitment type of the request, funded
+// with the utxos if any, with the same options as FundChannelExt. Without
+// commitment type the channel type is left to cln.
+func (c *ClnClient) fundChannel(
+	pubkey string,
+	req *lightning.OpenChannelRequest,
+	rate *glightning.FeeRate,
+	minConfs *uint16,
+	minDepth *uint16,
+	utxos []string,
+) (*glightning.FundChannelResult, error) {
+	var channelType []uint32
+	if req.CommitmentType != "" {
+		bits, ok := commitmentTypeBits[req.CommitmentType]
+		if !ok {
+			return nil, fmt.Errorf("unknown commitment type '%s'", req.CommitmentType)
+		}
+
+		channelType = append(channelType, bits...)
+		if req.IsZeroConf {
+			channelType = append(channelType, zeroConfFeatureBit)
+		}
+		if req.IsPrivate {
+			channelType = append(channelType, scidAliasFeatureBit)
+		}
+		sort.Slice(channelType, func(i, j int) bool {
+			return channelType[i] < channelType[j]
+		})
+	}
+
+	var result fundChannelResult
+	err := c.rpc.requestOnce(&fundChannelRequest{
+		ID:          pubkey,
+		Amount:      uint64(req.CapacitySat),
+		FeeRate:     formatFeeRate(rate),
+		Announce:    !req.IsPrivate,
+		MinConf:     minConfs,
+		MinDepth:    minDepth,
+		Reserve:     "0sat",
+		ChannelType: channelType,
+		Utxos:       utxos,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &glightning.FundChannelResult{
+		FundingTxId:        result.TxID,
+		FundingTxOutputNum: result.OutNum,
+	}, nil
+}
+
+// formatFeeRate formats the feerate as the feerate parameter of cln.
+func formatFeeRate(rate *glightning.FeeRate) string {
+	if rate == nil {
+		return ""
+	}
+
+	if rate.Rate != 0 {
+		style := "perkb"
+		if rate.Style == glightning.PerKw {
+			style = "perkw"
+		}
+
+		return fmt.Sprintf("%d%s", rate.Rate, style)
+	}
+
+	switch rate.Directive {
+	case glightning.Urgent:
+		return "urgent"
+	case glightning.Slow:
+		return "slow"
+	default:
+		return "normal"
+	}
+}
@@ -1,14 +1,18 @@
 package cln
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"log"
+	"net"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
 	"github.com/breez/lspd/lightning"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
@@ -17,9 +21,23 @@ import (
 )
 
 type ClnClient struct {
-	client *glightning.Lightning
+	conf        *config.ClnConfig
+	rpc         *rpcPool
+	peers       *lightning.PeerTracker
+	scids       *lightning.ScidCache
+	aliases     *lightning.ChannelAliases
+	coinControl *lightning.CoinControl
+	logger      *log.Logger
 }
 
+const (
+	defaultScidCacheTtl = 10 * time.Minute
+
+	// Time an scid that matches no channel is cached. Kept short, because
+	// the channel events that invalidate it may be missed.
+	scidCacheNegativeTtl = 30 * time.Second
+)
+
 var (
 	OPEN_STATUSES    = []string{"CHANNELD_NORMAL"}
 	PENDING_STATUSES = []string{"OPENINGD", "CHANNELD_AWAITING_LOCKIN"}
@@ -27,7 +45,12 @@ var (
 	CLOSED_STATUSES  = []string{"CLOSED"}
 )
 
-func NewClnClient(socketPath string) (*ClnClient, error) {
+func NewClnClient(conf *config.ClnConfig, logger *log.Logger) (*ClnClient, error) {
+	socketPath, err := resolveSocketPath(conf.SocketPath, logger)
+	if err != nil {
+		return nil, err
+	}
+
 	rpcFile := filepath.Base(socketPath)
 	if rpcFile == "" || rpcFile == "." {
 		return nil, fmt.Errorf("invalid socketPath '%s'", socketPath)
@@ -37,48 +60,148 @@ func NewClnClient(socketPath string) (*ClnClient, error) {
 		return nil, fmt.Errorf("invalid socketPath '%s'", socketPath)
 	}
 
-	client := glightning.NewLightning()
-	client.SetTimeout(60)
-	client.StartUp(rpcFile, lightningDir)
 	return &ClnClient{
-		client: client,
+		conf:   conf,
+		rpc:    newRpcPool(rpcFile, lightningDir, conf, logger),
+		scids:  newScidCache(conf.ScidCacheTtl, logger),
+		logger: logger,
 	}, nil
 }
 
+func newScidCache(s string, logger *log.Logger) *lightning.ScidCache {
+	ttl := defaultScidCacheTtl
+	if s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			logger.Printf("WARN: invalid cln scidCacheTtl '%s', using %v: %v", s, defaultScidCacheTtl, err)
+		} else {
+			ttl = d
+		}
+	}
+
+	if ttl == 0 {
+		return nil
+	}
+
+	negativeTtl := scidCacheNegativeTtl
+	if ttl < negativeTtl {
+		negativeTtl = ttl
+	}
+
+	return lightning.NewScidCache(ttl, negativeTtl)
+}
+
+func (c *ClnClient) Backend() string {
+	return "cln"
+}
+
+// SetPeerTracker sets the tracker that is kept up to date with the peer
+// events of the cln plugin. Call it before starting the htlc interceptor.
+func (c *ClnClient) SetPeerTracker(peers *lightning.PeerTracker) {
+	c.peers = peers
+}
+
+// SetChannelAliases sets the aliases of the channels of the node, which are
+// refreshed on the channel events of the cln plugin.
+func (c *ClnClient) SetChannelAliases(aliases *lightning.ChannelAliases) {
+	c.aliases = aliases
+}
+
+// StartListeners does nothing. The peer and channel events of cln are
+// received over the htlc stream of the cln plugin, which the htlc interceptor
+// keeps open.
+func (c *ClnClient) StartListeners() {}
+
+// SyncPeers syncs the peer tracker with the peers connected to the node.
+func (c *ClnClient) SyncPeers() error {
+	if c.peers == nil {
+		return nil
+	}
+
+	peers, err := c.listPeers()
+	if err != nil {
+		c.logger.Printf("CLN: client.ListPeers() error: %v", err)
+		c.peers.Unsync()
+		return fmt.Errorf("CLN: client.ListPeers() error: %w", err)
+	}
+
+	// The addresses of connected peers don't tell whether the connection is
+	// inbound, so the last known addresses are kept.
+	connected := make(map[string]string)
+	for _, p := range peers {
+		if p.Connected {
+			connected[p.Id] = ""
+		}
+	}
+
+	c.peers.Sync(connected)
+	return nil
+}
+
 func (c *ClnClient) GetInfo() (*lightning.GetInfoResult, error) {
-	info, err := c.client.GetInfo()
+	info, err := rpcRead(context.Background(), c.rpc, "getinfo", func(client *glightning.Lightning) (*glightning.NodeInfo, error) {
+		return client.GetInfo()
+	})
 	if err != nil {
-		log.Printf("CLN: client.GetInfo() error: %v", err)
+		c.logger.Printf("CLN: client.GetInfo() error: %v", err)
 		return nil, err
 	}
 
 	return &lightning.GetInfoResult{
-		Alias:  info.Alias,
-		Pubkey: info.Id,
+		Alias:       info.Alias,
+		Pubkey:      info.Id,
+		BlockHeight: uint32(info.Blockheight),
 	}, nil
 }
 
 func (c *ClnClient) IsConnected(destination []byte) (bool, error) {
+	if c.peers != nil {
+		if connected, ok := c.peers.IsConnected(destination); ok {
+			return connected, nil
+		}
+	}
+
 	pubKey := hex.EncodeToString(destination)
-	peer, err := c.client.GetPeer(pubKey)
+	peer, err := c.getPeer(context.Background(), pubKey)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			return false, nil
 		}
 
-		log.Printf("CLN: client.GetPeer(%v) error: %v", pubKey, err)
+		c.logger.Printf("CLN: client.GetPeer(%v) error: %v", pubKey, err)
 		return false, fmt.Errorf("CLN: client.GetPeer(%v) error: %w", pubKey, err)
 	}
 
 	if peer.Connected {
-		log.Printf("CLN: destination online: %x", destination)
+		c.logger.Printf("CLN: destination online: %x", destination)
 		return true, nil
 	}
 
-	log.Printf("CLN: destination offline: %x", destination)
+	c.logger.Printf("CLN: destination offline: %x", destination)
 	return false, nil
 }
 
+func (c *ClnClient) ConnectPeer(ctx context.Context, peerID []byte, address string) error {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address '%s': %w", address, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port in address '%s': %w", address, err)
+	}
+
+	pubkey := hex.EncodeToString(peerID)
+	_, err = rpcWrite(ctx, c.rpc, "connect", func(client *glightning.Lightning) (string, error) {
+		return client.Connect(pubkey, host, uint(port))
+	})
+	if err != nil {
+		return fmt.Errorf("CLN: client.Connect(%s, %s) error: %w", pubkey, address, err)
+	}
+
+	return nil
+}
+
 func (c *ClnClient) OpenChannel(req *lightning.OpenChannelRequest) (*wire.OutPoint, error) {
 	pubkey := hex.EncodeToString(req.Destination)
 	var minConfs *uint16
@@ -114,57 +237,75 @@ func (c *ClnClient) OpenChannel(req *lightning.OpenChannelRequest) (*wire.OutPoi
 		}
 	}
 
-	fundResult, err := c.client.FundChannelExt(
-		pubkey,
-		glightning.NewSat(int(req.CapacitySat)),
-		rate,
-		!req.IsPrivate,
-		minConfs,
-		glightning.NewMsat(0),
-		minDepth,
-		glightning.NewSat(0),
-	)
+	var fundResult *glightning.FundChannelResult
+	var err error
+	if c.coinControl != nil {
+		m := uint16(c.coinControl.MinConfs)
+		minConfs = &m
+		var utxos []string
+		utxos, err = c.selectUtxos(uint64(req.CapacitySat), req.FeeSatPerVByte)
+		if err == nil {
+			fundResult, err = c.fundChannel(pubkey, req, rate, minConfs, minDepth, utxos)
+		}
+	} else if req.CommitmentType != "" {
+		fundResult, err = c.fundChannel(pubkey, req, rate, minConfs, minDepth, nil)
+	} else {
+		fundResult, err = rpcWrite(context.Background(), c.rpc, "fundchannel", func(client *glightning.Lightning) (*glightning.FundChannelResult, error) {
+			return client.FundChannelExt(
+				pubkey,
+				glightning.NewSat(int(req.CapacitySat)),
+				rate,
+				!req.IsPrivate,
+				minConfs,
+				glightning.NewMsat(0),
+				minDepth,
+				glightning.NewSat(0),
+			)
+		})
+	}
 
 	if err != nil {
-		log.Printf("CLN: client.FundChannelExt(%v, %v) error: %v", pubkey, req.CapacitySat, err)
+		c.logger.Printf("CLN: fundchannel(%v, %v, commitment type: %s) error: %v", pubkey, req.CapacitySat, req.CommitmentType, err)
 		return nil, err
 	}
 
 	fundingTxId, err := chainhash.NewHashFromStr(fundResult.FundingTxId)
 	if err != nil {
-		log.Printf("CLN: chainhash.NewHashFromStr(%s) error: %v", fundResult.FundingTxId, err)
+		c.logger.Printf("CLN: chainhash.NewHashFromStr(%s) error: %v", fundResult.FundingTxId, err)
 		return nil, err
 	}
 
 	channelPoint, err := basetypes.NewOutPoint(fundingTxId[:], uint32(fundResult.FundingTxOutputNum))
 	if err != nil {
-		log.Printf("CLN: NewOutPoint(%s, %d) error: %v", fundingTxId.String(), fundResult.FundingTxOutputNum, err)
+		c.logger.Printf("CLN: NewOutPoint(%s, %d) error: %v", fundingTxId.String(), fundResult.FundingTxOutputNum, err)
 		return nil, err
 	}
 
+	// The alias of the new channel may have been cached as unknown.
+	c.scids.InvalidateUnknown()
 	return channelPoint, nil
 }
 
 func (c *ClnClient) GetChannel(peerID []byte, channelPoint wire.OutPoint) (*lightning.GetChannelResult, error) {
 	pubkey := hex.EncodeToString(peerID)
-	peer, err := c.client.GetPeer(pubkey)
+	peer, err := c.getPeer(context.Background(), pubkey)
 	if err != nil {
-		log.Printf("CLN: client.GetPeer(%s) error: %v", pubkey, err)
+		c.logger.Printf("CLN: client.GetPeer(%s) error: %v", pubkey, err)
 		return nil, err
 	}
 
 	fundingTxID := channelPoint.Hash.String()
-	for _, c := range peer.Channels {
-		log.Printf("getChannel destination: %s, Short channel id: %v, local alias: %v , FundingTxID:%v, State:%v ", pubkey, c.ShortChannelId, c.Alias.Local, c.FundingTxId, c.State)
-		if slices.Contains(OPEN_STATUSES, c.State) && c.FundingTxId == fundingTxID {
-			confirmedChanID, err := basetypes.NewShortChannelIDFromString(c.ShortChannelId)
+	for _, ch := range peer.Channels {
+		c.logger.Printf("getChannel destination: %s, Short channel id: %v, local alias: %v , FundingTxID:%v, State:%v ", pubkey, ch.ShortChannelId, ch.Alias.Local, ch.FundingTxId, ch.State)
+		if slices.Contains(OPEN_STATUSES, ch.State) && ch.FundingTxId == fundingTxID {
+			confirmedChanID, err := basetypes.NewShortChannelIDFromString(ch.ShortChannelId)
 			if err != nil {
-				fmt.Printf("NewShortChannelIDFromString %v error: %v", c.ShortChannelId, err)
+				c.logger.Printf("NewShortChannelIDFromString %v error: %v", ch.ShortChannelId, err)
 				return nil, err
 			}
-			initialChanID, err := basetypes.NewShortChannelIDFromString(c.Alias.Local)
+			initialChanID, err := basetypes.NewShortChannelIDFromString(ch.Alias.Local)
 			if err != nil {
-				fmt.Printf("NewShortChannelIDFromString %v error: %v", c.Alias.Local, err)
+				c.logger.Printf("NewShortChannelIDFromString %v error: %v", ch.Alias.Local, err)
 				return nil, err
 			}
 			return &lightning.GetChannelResult{
@@ -174,15 +315,15 @@ func (c *ClnClient) GetChannel(peerID []byte, channelPoint wire.OutPoint) (*ligh
 		}
 	}
 
-	log.Printf("No channel found: getChannel(%v, %v)", pubkey, fundingTxID)
+	c.logger.Printf("No channel found: getChannel(%v, %v)", pubkey, fundingTxID)
 	return nil, fmt.Errorf("no channel found")
 }
 
-func (c *ClnClient) GetNodeChannelCount(nodeID []byte) (int, error) {
+func (c *ClnClient) GetNodeChannelCount(ctx context.Context, nodeID []byte) (int, error) {
 	pubkey := hex.EncodeToString(nodeID)
-	peer, err := c.client.GetPeer(pubkey)
+	peer, err := c.getPeer(ctx, pubkey)
 	if err != nil {
-		log.Printf("CLN: client.GetPeer(%s) error: %v", pubkey, err)
+		c.logger.Printf("CLN: client.GetPeer(%s) error: %v", pubkey, err)
 		return 0, err
 	}
 
@@ -197,29 +338,101 @@ func (c *ClnClient) GetNodeChannelCount(nodeID []byte) (int, error) {
 	return count, nil
 }
 
-func (c *ClnClient) GetClosedChannels(nodeID string, channelPoints map[string]uint64) (map[string]uint64, error) {
+// ListPeerChannels returns the open and pending channels with the peer.
+func (c *ClnClient) ListPeerChannels(ctx context.Context, peerID []byte) ([]*lightning.PeerChannel, error) {
+	pubkey := hex.EncodeToString(peerID)
+	peer, err := c.getPeer(ctx, pubkey)
+	if err != nil {
+		c.logger.Printf("CLN: client.GetPeer(%s) error: %v", pubkey, err)
+		return nil, err
+	}
+
+	var channels []*lightning.PeerChannel
+	for _, ch := range peer.Channels {
+		pending := slices.Contains(PENDING_STATUSES, ch.State)
+		if !pending && !slices.Contains(OPEN_STATUSES, ch.State) {
+			continue
+		}
+
+		var capacitySat uint64
+		if ch.TotalMsat != nil {
+			capacitySat = ch.TotalMsat.Value / 1000
+		}
+		channels = append(channels, &lightning.PeerChannel{
+			ChannelPoint: fmt.Sprintf("%s:%d", ch.FundingTxId, ch.FundingOutnum),
+			CapacitySat:  capacitySat,
+			Pending:      pending,
+		})
+	}
+
+	return channels, nil
+}
+
+// getPeer gets the peer, returning early if the context is done first. The
+// rpc call itself cannot be cancelled, it completes in the background.
+func (c *ClnClient) getPeer(ctx context.Context, pubkey string) (*glightning.Peer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return rpcRead(ctx, c.rpc, "getpeer", func(client *glightning.Lightning) (*glightning.Peer, error) {
+		return client.GetPeer(pubkey)
+	})
+}
+
+func (c *ClnClient) listPeers() ([]*glightning.Peer, error) {
+	return rpcRead(context.Background(), c.rpc, "listpeers", func(client *glightning.Lightning) ([]*glightning.Peer, error) {
+		return client.ListPeers()
+	})
+}
+
+// GetMaxLocalBalanceMsat returns the largest local balance of the open
+// channels with the peer, which bounds the size of a htlc to the peer.
+func (c *ClnClient) GetMaxLocalBalanceMsat(peerID []byte) (uint64, error) {
+	pubkey := hex.EncodeToString(peerID)
+	peer, err := c.getPeer(context.Background(), pubkey)
+	if err != nil {
+		c.logger.Printf("CLN: client.GetPeer(%s) error: %v", pubkey, err)
+		return 0, err
+	}
+
+	var max uint64
+	for _, ch := range peer.Channels {
+		if !slices.Contains(OPEN_STATUSES, ch.State) || ch.ToUsMsat == nil {
+			continue
+		}
+
+		if ch.ToUsMsat.Value > max {
+			max = ch.ToUsMsat.Value
+		}
+	}
+
+	return max, nil
+}
+
+func (c *ClnClient) GetClosedChannels(ctx context.Context, nodeID string, channelPoints map[string]uint64) (map[string]uint64, error) {
 	r := make(map[string]uint64)
 	if len(channelPoints) == 0 {
 		return r, nil
 	}
 
-	peer, err := c.client.GetPeer(nodeID)
+	peer, err := c.getPeer(ctx, nodeID)
 	if err != nil {
-		log.Printf("CLN: client.GetPeer(%s) error: %v", nodeID, err)
+		c.logger.Printf("CLN: client.GetPeer(%s) error: %v", nodeID, err)
 		return nil, err
 	}
 
 	lookup := make(map[string]uint64)
-	for _, c := range peer.Channels {
-		if slices.Contains(CLOSING_STATUSES, c.State) {
-			cid, err := basetypes.NewShortChannelIDFromString(c.ShortChannelId)
+	for _, ch := range peer.Channels {
+		if slices.Contains(CLOSING_STATUSES, ch.State) {
+			cid, err := basetypes.NewShortChannelIDFromString(ch.ShortChannelId)
 			if err != nil {
-				log.Printf("CLN: GetClosedChannels NewShortChannelIDFromString(%v) error: %v", c.ShortChannelId, err)
+				c.logger.Printf("CLN: GetClosedChannels NewShortChannelIDFromString(%v) error: %v", ch.ShortChannelId, err)
 				continue
 			}
 
 			outnum := uint64(*cid) & 0xFFFFFF
-			cp := fmt.Sprintf("%s:%d", c.FundingTxId, outnum)
+			cp := fmt.Sprintf("%s:%d", ch.FundingTxId, outnum)
 			lookup[cp] = uint64(*cid)
 		}
 	}
@@ -235,36 +448,71 @@ func (c *ClnClient) GetClosedChannels(nodeID string, channelPoints map[string]ui
 
 func (c *ClnClient) GetPeerId(scid *basetypes.ShortChannelID) ([]byte, error) {
 	scidStr := scid.ToString()
-	peers, err := c.client.ListPeers()
+	if peer, ok := c.scids.Get(scidStr); ok {
+		return peer, nil
+	}
+
+	peers, err := c.listPeers()
 	if err != nil {
 		return nil, err
 	}
 
-	var dest *string
+	// All channels are cached while at it, so the next htlcs for other
+	// channels don't need a listpeers either.
+	var dest []byte
 	for _, p := range peers {
+		peerID, err := hex.DecodeString(p.Id)
+		if err != nil {
+			continue
+		}
+
 		for _, ch := range p.Channels {
-			if ch.Alias.Local == scidStr ||
-				ch.Alias.Remote == scidStr ||
-				ch.ShortChannelId == scidStr {
-				dest = &p.Id
-				break
+			for _, s := range []string{ch.ShortChannelId, ch.Alias.Local, ch.Alias.Remote} {
+				if s == "" {
+					continue
+				}
+
+				c.scids.Put(s, peerID)
+				if s == scidStr {
+					dest = peerID
+				}
 			}
 		}
 	}
 
 	if dest == nil {
-		return nil, nil
+		c.scids.Put(scidStr, nil)
 	}
 
-	return hex.DecodeString(*dest)
+	return dest, nil
+}
+
+// ChannelChanged invalidates the cached scids of the peer, and the unknown
+// scids, which may belong to the changed channel now.
+func (c *ClnClient) ChannelChanged(peerID []byte) {
+	c.scids.InvalidatePeer(peerID)
+	c.scids.InvalidateUnknown()
+}
+
+// ResetScidCache clears the cached scids, for when channel events may have
+// been missed.
+func (c *ClnClient) ResetScidCache() {
+	c.scids.Clear()
 }
 
 var pollingInterval = 400 * time.Millisecond
 
 func (c *ClnClient) WaitOnline(peerID []byte, deadline time.Time) error {
+	if c.peers != nil {
+		err := c.peers.WaitOnline(peerID, deadline)
+		if err != lightning.ErrPeerTrackerNotSynced {
+			return err
+		}
+	}
+
 	peerIDStr := hex.EncodeToString(peerID)
 	for {
-		peer, err := c.client.GetPeer(peerIDStr)
+		peer, err := c.getPeer(context.Background(), peerIDStr)
 		if err == nil && peer.Connected {
 			return nil
 		}
@@ -280,3 +528,290 @@ func (c *ClnClient) WaitOnline(peerID []byte, deadline time.Time) error {
 func (c *ClnClient) WaitChannelActive(peerID []byte, deadline time.Time) error {
 	return nil
 }
+
+func (c *ClnClient) GetWalletBalance() (*lightning.GetWalletBalanceResult, error) {
+	if c.coinControl != nil {
+		return c.coinControlBalance()
+	}
+
+	funds, err := rpcRead(context.Background(), c.rpc, "listfunds", func(client *glightning.Lightning) (*glightning.FundsResult, error) {
+		return client.ListFunds()
+	})
+	if err != nil {
+		c.logger.Printf("CLN: client.ListFunds() error: %v", err)
+		return nil, fmt.Errorf("CLN: client.ListFunds() error: %w", err)
+	}
+
+	result := &lightning.GetWalletBalanceResult{}
+	for _, o := range funds.Outputs {
+		if o.Reserved {
+			continue
+		}
+
+		switch o.Status {
+		case "confirmed":
+			result.ConfirmedSat += basetypes.Satoshi(o.Value)
+		case "unconfirmed":
+			result.UnconfirmedSat += basetypes.Satoshi(o.Value)
+		}
+	}
+
+	return result, nil
+}
+
+type pluginStartRequest struct {
+	Subcommand string `json:"subcommand"`
+	Plugin     string `json:"plugin"`
+}
+
+func (r *pluginStartRequest) Name() string {
+	return "plugin"
+}
+
+// StartPlugin starts the plugin at the given path on the running node.
+func (c *ClnClient) StartPlugin(path string) error {
+	var resp map[string]interface{}
+	err := c.rpc.requestOnce(&pluginStartRequest{
+		Subcommand: "start",
+		Plugin:     path,
+	}, &resp)
+	if err != nil {
+		c.logger.Printf("CLN: plugin start %s error: %v", path, err)
+		return fmt.Errorf("CLN: plugin start %s error: %w", path, err)
+	}
+
+	return nil
+}
+
+type listPeerChannelsRequest struct {
+	PeerID string `json:"id"`
+}
+
+func (r *listPeerChannelsRequest) Name() string {
+	return "listpeerchannels"
+}
+
+type listPeerChannelsActivity struct {
+	Channels []struct {
+		State            string `json:"state"`
+		ChannelID        string `json:"channel_id"`
+		FundingTxID      string `json:"funding_txid"`
+		FundingOutnum    uint32 `json:"funding_outnum"`
+		InFulfilledMsat  uint64 `json:"in_fulfilled_msat"`
+		OutFulfilledMsat uint64 `json:"out_fulfilled_msat"`
+	} `json:"channels"`
+}
+
+// GetChannelActivity returns the total amount of payments settled over the
+// open channel in both directions, in millisatoshi.
+func (c *ClnClient) GetChannelActivity(peerID []byte, channelPoint wire.OutPoint) (uint64, error) {
+	pubkey := hex.EncodeToString(peerID)
+	var resp listPeerChannelsActivity
+	err := c.rpc.request(&listPeerChannelsRequest{PeerID: pubkey}, &resp)
+	if err != nil {
+		c.logger.Printf("CLN: listpeerchannels(%s) error: %v", pubkey, err)
+		return 0, err
+	}
+
+	for _, ch := range resp.Channels {
+		if slices.Contains(OPEN_STATUSES, ch.State) &&
+			ch.FundingTxID == channelPoint.Hash.String() &&
+			ch.FundingOutnum == channelPoint.Index {
+			return ch.InFulfilledMsat + ch.OutFulfilledMsat, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no open channel found")
+}
+
+// CloseChannel cooperatively closes the channel. It returns the txid of the
+// closing transaction.
+func (c *ClnClient) CloseChannel(peerID []byte, channelPoint wire.OutPoint) (string, error) {
+	pubkey := hex.EncodeToString(peerID)
+	var resp listPeerChannelsActivity
+	err := c.rpc.request(&listPeerChannelsRequest{PeerID: pubkey}, &resp)
+	if err != nil {
+		c.logger.Printf("CLN: listpeerchannels(%s) error: %v", pubkey, err)
+		return "", err
+	}
+
+	for _, ch := range resp.Channels {
+		if ch.FundingTxID != channelPoint.Hash.String() || ch.FundingOutnum != channelPoint.Index {
+			continue
+		}
+
+		channelID := ch.ChannelID
+		result, err := rpcWrite(context.Background(), c.rpc, "close", func(client *glightning.Lightning) (*glightning.CloseResult, error) {
+			return client.CloseNormal(channelID)
+		})
+		if err != nil {
+			c.logger.Printf("CLN: close(%s) error: %v", ch.ChannelID, err)
+			return "", err
+		}
+
+		c.scids.InvalidatePeer(peerID)
+		return result.TxId, nil
+	}
+
+	return "", fmt.Errorf("no channel found")
+}
+
+type setChannelRequest struct {
+	ID      string `json:"id"`
+	FeeBase uint64 `json:"feebase"`
+	FeePpm  uint64 `json:"feeppm"`
+	HtlcMin string `json:"htlcmin,omitempty"`
+	HtlcMax string `json:"htlcmax,omitempty"`
+}
+
+func (r *setChannelRequest) Name() string {
+	return "setchannel"
+}
+
+// SetChannelPolicy updates the fees and htlc limits of the node for the
+// channel.
+// CLN doesn't support a cltv delta per channel, so the time lock delta of the
+// node is used.
+func (c *ClnClient) SetChannelPolicy(peerID []byte, channelPoint wire.OutPoint, policy *lightning.ChannelPolicy) error {
+	pubkey := hex.EncodeToString(peerID)
+	var resp listPeerChannelsActivity
+	err := c.rpc.request(&listPeerChannelsRequest{PeerID: pubkey}, &resp)
+	if err != nil {
+		c.logger.Printf("CLN: listpeerchannels(%s) error: %v", pubkey, err)
+		return err
+	}
+
+	for _, ch := range resp.Channels {
+		if ch.FundingTxID != channelPoint.Hash.String() || ch.FundingOutnum != channelPoint.Index {
+			continue
+		}
+
+		req := &setChannelRequest{
+			ID:      ch.ChannelID,
+			FeeBase: uint64(policy.BaseFeeMsat),
+			FeePpm:  uint64(policy.FeeRate * 1000000),
+		}
+		if policy.MinHtlcMsat > 0 {
+			req.HtlcMin = fmt.Sprintf("%dmsat", policy.MinHtlcMsat)
+		}
+		if policy.MaxHtlcMsat > 0 {
+			req.HtlcMax = fmt.Sprintf("%dmsat", policy.MaxHtlcMsat)
+		}
+		err = c.rpc.requestOnce(req, &struct{}{})
+		if err != nil {
+			c.logger.Printf("CLN: setchannel(%s) error: %v", ch.ChannelID, err)
+			return err
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("no channel found")
+}
+
+type listForwardsRequest struct {
+	Status string `json:"status,omitempty"`
+}
+
+func (r *listForwardsRequest) Name() string {
+	return "listforwards"
+}
+
+type listForwardsResponse struct {
+	Forwards []struct {
+		InChannel    string  `json:"in_channel"`
+		OutChannel   string  `json:"out_channel"`
+		InMsat       uint64  `json:"in_msat"`
+		OutMsat      uint64  `json:"out_msat"`
+		ReceivedTime float64 `json:"received_time"`
+		ResolvedTime float64 `json:"resolved_time"`
+	} `json:"forwards"`
+}
+
+// ListForwards returns the settled forwards of the node since the given time.
+func (c *ClnClient) ListForwards(ctx context.Context, since time.Time) ([]*lightning.Forward, error) {
+	var resp listForwardsResponse
+	err := c.rpc.request(&listForwardsRequest{Status: "settled"}, &resp)
+	if err != nil {
+		c.logger.Printf("CLN: listforwards error: %v", err)
+		return nil, fmt.Errorf("CLN: listforwards error: %w", err)
+	}
+
+	var result []*lightning.Forward
+	for _, f := range resp.Forwards {
+		t := f.ResolvedTime
+		if t == 0 {
+			t = f.ReceivedTime
+		}
+		timestamp := time.Unix(0, int64(t*float64(time.Second)))
+		if timestamp.Before(since) {
+			continue
+		}
+
+		in, err := basetypes.NewShortChannelIDFromString(f.InChannel)
+		if err != nil {
+			c.logger.Printf("CLN: listforwards invalid in_channel %s: %v", f.InChannel, err)
+			continue
+		}
+		out, err := basetypes.NewShortChannelIDFromString(f.OutChannel)
+		if err != nil {
+			c.logger.Printf("CLN: listforwards invalid out_channel %s: %v", f.OutChannel, err)
+			continue
+		}
+
+		result = append(result, &lightning.Forward{
+			Timestamp:     timestamp,
+			ChanIDIn:      *in,
+			ChanIDOut:     *out,
+			AmountInMsat:  f.InMsat,
+			AmountOutMsat: f.OutMsat,
+		})
+	}
+
+	return result, nil
+}
+
+type listPeerChannelsHtlcs struct {
+	Channels []struct {
+		FundingTxID   string `json:"funding_txid"`
+		FundingOutnum uint32 `json:"funding_outnum"`
+		Htlcs         []struct {
+			Direction   string `json:"direction"`
+			AmountMsat  uint64 `json:"amount_msat"`
+			PaymentHash string `json:"payment_hash"`
+		} `json:"htlcs"`
+	} `json:"channels"`
+}
+
+// ListPendingHtlcs returns the htlcs in flight over the channels with the
+// peer.
+func (c *ClnClient) ListPendingHtlcs(ctx context.Context, peerID []byte) ([]*lightning.PendingHtlc, error) {
+	pubkey := hex.EncodeToString(peerID)
+	var resp listPeerChannelsHtlcs
+	err := c.rpc.request(&listPeerChannelsRequest{PeerID: pubkey}, &resp)
+	if err != nil {
+		c.logger.Printf("CLN: listpeerchannels(%s) error: %v", pubkey, err)
+		return nil, fmt.Errorf("CLN: listpeerchannels(%s) error: %w", pubkey, err)
+	}
+
+	var result []*lightning.PendingHtlc
+	for _, ch := range resp.Channels {
+		channelPoint := fmt.Sprintf("%s:%d", ch.FundingTxID, ch.FundingOutnum)
+		for _, h := range ch.Htlcs {
+			paymentHash, err := hex.DecodeString(h.PaymentHash)
+			if err != nil {
+				c.logger.Printf("CLN: listpeerchannels(%s) invalid payment_hash %s: %v", pubkey, h.PaymentHash, err)
+				continue
+			}
+
+			result = append(result, &lightning.PendingHtlc{
+				ChannelPoint: channelPoint,
+				PaymentHash:  paymentHash,
+				AmountMsat:   h.AmountMsat,
+				Incoming:     h.Direction == "in",
+			})
+		}
+	}
+
+	return result, nil
+}
@@ -8,16 +8,19 @@ import (
 	"io"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/breez/lspd/basetypes"
 	"github.com/breez/lspd/cln_plugin/proto"
 	"github.com/breez/lspd/config"
 	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/streamwatch"
 	sphinx "github.com/lightningnetwork/lightning-onion"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/record"
 	"github.com/lightningnetwork/lnd/tlv"
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
@@ -30,21 +33,40 @@ type ClnHtlcInterceptor struct {
 	config        *config.NodeConfig
 	pluginAddress string
 	client        *ClnClient
+	htlcStore     HtlcStore
+	replayGroup   singleflight.Group
 	pluginClient  proto.ClnPluginClient
 	initWg        sync.WaitGroup
 	doneWg        sync.WaitGroup
-	stopRequested bool
-	ctx           context.Context
-	cancel        context.CancelFunc
+	connected     atomic.Bool
+	watchdog      *streamwatch.Watchdog
+	holdTimeout   time.Duration
+	supportsHold  atomic.Bool
+	// The heartbeat timeout of the plugin, zero if it doesn't require
+	// heartbeats.
+	heartbeatTimeout atomic.Int64
+	inflightMtx      sync.Mutex
+	inflight         map[string]map[string]*inflightHtlc
+	sendMtx          sync.Mutex
+	stopRequested    bool
+	ctx              context.Context
+	cancel           context.CancelFunc
+	logger           *log.Logger
 }
 
-func NewClnHtlcInterceptor(conf *config.NodeConfig, client *ClnClient, interceptor *interceptor.Interceptor) (*ClnHtlcInterceptor, error) {
+func NewClnHtlcInterceptor(conf *config.NodeConfig, client *ClnClient, htlcStore HtlcStore, interceptor *interceptor.Interceptor, logger *log.Logger) (*ClnHtlcInterceptor, error) {
 	i := &ClnHtlcInterceptor{
 		config:        conf,
 		pluginAddress: conf.Cln.PluginAddress,
 		client:        client,
+		htlcStore:     htlcStore,
 		interceptor:   interceptor,
+		holdTimeout:   parseHoldTimeout(conf.Cln.HoldTimeout, logger),
+		inflight:      make(map[string]map[string]*inflightHtlc),
+		logger:        logger,
 	}
+	i.watchdog = streamwatch.NewWatchdog(conf, "htlc", i.probe, logger)
+	interceptor.SetOpenObserver(i)
 
 	i.initWg.Add(1)
 	return i, nil
@@ -52,7 +74,7 @@ func NewClnHtlcInterceptor(conf *config.NodeConfig, client *ClnClient, intercept
 
 func (i *ClnHtlcInterceptor) Start() error {
 	ctx, cancel := context.WithCancel(context.Background())
-	log.Printf("Dialing cln plugin on '%s'", i.pluginAddress)
+	i.logger.Printf("Dialing cln plugin on '%s'", i.pluginAddress)
 	conn, err := grpc.DialContext(
 		ctx,
 		i.pluginAddress,
@@ -63,7 +85,7 @@ func (i *ClnHtlcInterceptor) Start() error {
 		}),
 	)
 	if err != nil {
-		log.Printf("grpc.Dial error: %v", err)
+		i.logger.Printf("grpc.Dial error: %v", err)
 		cancel()
 		return err
 	}
@@ -72,17 +94,64 @@ func (i *ClnHtlcInterceptor) Start() error {
 	i.ctx = ctx
 	i.cancel = cancel
 	i.stopRequested = false
+	i.pruneResolutions()
+	if i.client.peers != nil {
+		streamwatch.Go(i.config.Label(), "peer_events", i.listenPeerEvents)
+	}
+	streamwatch.Go(i.config.Label(), "hold_events", i.listenHoldEvents)
+	streamwatch.Go(i.config.Label(), "heartbeats", i.sendHeartbeats)
+	if i.client.scids != nil || i.client.aliases != nil {
+		streamwatch.Go(i.config.Label(), "channel_events", i.listenChannelEvents)
+	}
 	return i.intercept()
 }
 
+// listenPeerEvents keeps the peer tracker of the client up to date with the
+// connect and disconnect events of the cln plugin.
+func (i *ClnHtlcInterceptor) listenPeerEvents() {
+	ctx := i.ctx
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		stream, err := i.pluginClient.PeerEventStream(ctx, &proto.PeerEventRequest{})
+		if err != nil {
+			i.logger.Printf("pluginClient.PeerEventStream(): %v", err)
+			<-time.After(time.Second)
+			continue
+		}
+
+		// Events before the subscription were missed, so sync the connected
+		// peers now.
+		i.client.SyncPeers()
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				status, ok := status.FromError(err)
+				if !ok || status.Code() != codes.Canceled {
+					i.logger.Printf("unexpected error in listenPeerEvents: %v", err)
+				}
+				break
+			}
+
+			i.client.peers.SetConnected(event.PeerId, event.Connected, event.Address)
+		}
+
+		i.client.peers.Unsync()
+		<-time.After(time.Second)
+	}
+}
+
 func (i *ClnHtlcInterceptor) intercept() error {
 	inited := false
 
 	defer func() {
+		i.connected.Store(false)
 		if !inited {
 			i.initWg.Done()
 		}
-		log.Printf("CLN intercept(): stopping. Waiting for in-progress interceptions to complete.")
+		i.logger.Printf("CLN intercept(): stopping. Waiting for in-progress interceptions to complete.")
 		i.doneWg.Wait()
 	}()
 
@@ -91,16 +160,21 @@ func (i *ClnHtlcInterceptor) intercept() error {
 			return i.ctx.Err()
 		}
 
-		log.Printf("Connecting CLN HTLC interceptor.")
-		interceptorClient, err := i.pluginClient.HtlcStream(i.ctx)
+		i.logger.Printf("Connecting CLN HTLC interceptor.")
+		streamCtx, cancelStream := i.watchdog.Watch(i.ctx)
+		interceptorClient, err := i.pluginClient.HtlcStream(streamCtx)
 		if err != nil {
-			log.Printf("pluginClient.HtlcStream(): %v", err)
+			cancelStream()
+			i.logger.Printf("pluginClient.HtlcStream(): %v", err)
 			<-time.After(time.Second)
 			continue
 		}
 
+		i.connected.Store(true)
+		i.checkSupportsHold(streamCtx)
 		for {
 			if i.ctx.Err() != nil {
+				cancelStream()
 				return i.ctx.Err()
 			}
 
@@ -122,55 +196,61 @@ func (i *ClnHtlcInterceptor) intercept() error {
 				// the we exit silently.
 				status, ok := status.FromError(err)
 				if ok && status.Code() == codes.Canceled {
-					log.Printf("Got code canceled. Break.")
+					i.logger.Printf("Got code canceled. Break.")
 					break
 				}
 
 				// Otherwise it an unexpected error, we fail the test.
-				log.Printf("unexpected error in interceptor.Recv() %v", err)
+				i.logger.Printf("unexpected error in interceptor.Recv() %v", err)
 				break
 			}
 
+			i.watchdog.Touch()
 			i.doneWg.Add(1)
-			go func() {
-				paymentHash, err := hex.DecodeString(request.Htlc.PaymentHash)
-				if err != nil {
-					interceptorClient.Send(i.defaultResolution(request))
-					i.doneWg.Done()
-					return
-				}
-
-				scid, err := basetypes.NewShortChannelIDFromString(request.Onion.ShortChannelId)
-				if err != nil {
-					interceptorClient.Send(i.defaultResolution(request))
-					i.doneWg.Done()
-					return
-				}
-
-				interceptResult := i.interceptor.Intercept(scid, paymentHash, request.Onion.ForwardMsat, request.Onion.OutgoingCltvValue, request.Htlc.CltvExpiry)
-				switch interceptResult.Action {
-				case interceptor.INTERCEPT_RESUME_WITH_ONION:
-					interceptorClient.Send(i.resumeWithOnion(request, interceptResult))
-				case interceptor.INTERCEPT_FAIL_HTLC_WITH_CODE:
-					interceptorClient.Send(
-						i.failWithCode(request, interceptResult.FailureCode),
-					)
-				case interceptor.INTERCEPT_RESUME:
-					fallthrough
-				default:
-					interceptorClient.Send(
-						i.defaultResolution(request),
-					)
-				}
-
+			streamwatch.Go(i.config.Label(), "htlc_handler", func() {
+				i.track(request, interceptorClient)
+				resolution := i.resolveOnce(request)
+				i.untrack(request)
+				i.send(interceptorClient, resolution)
 				i.doneWg.Done()
-			}()
+			})
 		}
 
+		cancelStream()
+		i.connected.Store(false)
 		<-time.After(time.Second)
 	}
 }
 
+func (i *ClnHtlcInterceptor) resolve(request *proto.HtlcAccepted) *proto.HtlcResolution {
+	paymentHash, err := hex.DecodeString(request.Htlc.PaymentHash)
+	if err != nil {
+		return i.defaultResolution(request)
+	}
+
+	scid, err := basetypes.NewShortChannelIDFromString(request.Onion.ShortChannelId)
+	if err != nil {
+		return i.defaultResolution(request)
+	}
+
+	interceptResult := i.interceptor.Intercept(scid, paymentHash, request.Onion.ForwardMsat, request.Onion.OutgoingCltvValue, request.Htlc.CltvExpiry)
+	switch interceptResult.Action {
+	case interceptor.INTERCEPT_RESUME_WITH_ONION:
+		return i.resumeWithOnion(request, interceptResult)
+	case interceptor.INTERCEPT_RESUME_ON_CHANNEL:
+		return i.resumeOnChannel(request, interceptResult)
+	case interceptor.INTERCEPT_FAIL_HTLC_WITH_CODE:
+		if interceptResult.FailureMessage != nil {
+			return i.failWithMessage(request, hex.EncodeToString(interceptResult.FailureMessage))
+		}
+		return i.failWithCode(request, interceptResult.FailureCode)
+	case interceptor.INTERCEPT_RESUME:
+		fallthrough
+	default:
+		return i.defaultResolution(request)
+	}
+}
+
 func (i *ClnHtlcInterceptor) Stop() error {
 	// Setting stopRequested to true will make the interceptor stop receiving.
 	i.stopRequested = true
@@ -187,23 +267,71 @@ func (i *ClnHtlcInterceptor) WaitStarted() {
 	i.initWg.Wait()
 }
 
+func (i *ClnHtlcInterceptor) Alive() bool {
+	return i.connected.Load()
+}
+
+// probe checks the plugin answers, and still has lspd subscribed to the htlc
+// stream.
+func (i *ClnHtlcInterceptor) probe(ctx context.Context) error {
+	resp, err := i.pluginClient.Ping(ctx, &proto.PingRequest{})
+	if err != nil {
+		return err
+	}
+	if !resp.HtlcStreamActive {
+		return fmt.Errorf("plugin has no htlc stream subscriber")
+	}
+
+	return nil
+}
+
 func (i *ClnHtlcInterceptor) resumeWithOnion(request *proto.HtlcAccepted, interceptResult interceptor.InterceptResult) *proto.HtlcResolution {
 	//decoding and encoding onion with alias in type 6 record.
 	payload, err := hex.DecodeString(request.Onion.Payload)
 	if err != nil {
-		log.Printf("resumeWithOnion: hex.DecodeString(%v) error: %v", request.Onion.Payload, err)
+		i.logger.Printf("resumeWithOnion: hex.DecodeString(%v) error: %v", request.Onion.Payload, err)
+		return i.failWithCode(request, interceptor.FAILURE_TEMPORARY_CHANNEL_FAILURE)
+	}
+	chanId := lnwire.NewChanIDFromOutPoint(interceptResult.ChannelPoint).String()
+	blinded, err := isBlindedPayload(payload)
+	if err != nil {
+		i.logger.Printf("isBlindedPayload error: %v", err)
 		return i.failWithCode(request, interceptor.FAILURE_TEMPORARY_CHANNEL_FAILURE)
 	}
-	newPayload, err := encodePayloadWithNextHop(payload, interceptResult.ChannelId, interceptResult.AmountMsat)
+
+	// A blinded payload cannot be rewritten, because the next hop is in the
+	// encrypted_recipient_data, and amt_to_forward is not allowed in blinded
+	// hops. cln derives the forward amount from the payment_relay of the
+	// blinded hop, so the payee includes the opening fee there when building
+	// the blinded path. The htlc is only redirected to the new channel, if
+	// that amount leaves the fee of the registered payment.
+	if blinded {
+		err = checkBlindedForward(request.Onion.ForwardMsat, interceptResult.AmountMsat)
+		if err != nil {
+			i.logger.Printf("resumeWithOnion: %v", err)
+			return i.failWithCode(request, interceptor.FAILURE_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS)
+		}
+
+		i.logger.Printf("forwarding blinded htlc to the destination node and a new private channel was opened")
+		return &proto.HtlcResolution{
+			Correlationid: request.Correlationid,
+			Outcome: &proto.HtlcResolution_Continue{
+				Continue: &proto.HtlcContinue{
+					ForwardTo: &chanId,
+				},
+			},
+		}
+	}
+
+	newPayload, err := encodePayloadWithNextHop(payload, interceptResult.ChannelId, uint64(interceptResult.AmountMsat))
 	if err != nil {
-		log.Printf("encodePayloadWithNextHop error: %v", err)
+		i.logger.Printf("encodePayloadWithNextHop error: %v", err)
 		return i.failWithCode(request, interceptor.FAILURE_TEMPORARY_CHANNEL_FAILURE)
 	}
 
 	newPayloadStr := hex.EncodeToString(newPayload)
 
-	chanId := lnwire.NewChanIDFromOutPoint(interceptResult.ChannelPoint).String()
-	log.Printf("forwarding htlc to the destination node and a new private channel was opened")
+	i.logger.Printf("forwarding htlc to the destination node and a new private channel was opened")
 	return &proto.HtlcResolution{
 		Correlationid: request.Correlationid,
 		Outcome: &proto.HtlcResolution_Continue{
@@ -215,6 +343,20 @@ func (i *ClnHtlcInterceptor) resumeWithOnion(request *proto.HtlcAccepted, interc
 	}
 }
 
+// resumeOnChannel forwards the htlc over the channel in the result, with the
+// original payload.
+func (i *ClnHtlcInterceptor) resumeOnChannel(request *proto.HtlcAccepted, interceptResult interceptor.InterceptResult) *proto.HtlcResolution {
+	chanId := lnwire.NewChanIDFromOutPoint(interceptResult.ChannelPoint).String()
+	return &proto.HtlcResolution{
+		Correlationid: request.Correlationid,
+		Outcome: &proto.HtlcResolution_Continue{
+			Continue: &proto.HtlcContinue{
+				ForwardTo: &chanId,
+			},
+		},
+	}
+}
+
 func (i *ClnHtlcInterceptor) defaultResolution(request *proto.HtlcAccepted) *proto.HtlcResolution {
 	return &proto.HtlcResolution{
 		Correlationid: request.Correlationid,
@@ -225,35 +367,81 @@ func (i *ClnHtlcInterceptor) defaultResolution(request *proto.HtlcAccepted) *pro
 }
 
 func (i *ClnHtlcInterceptor) failWithCode(request *proto.HtlcAccepted, code interceptor.InterceptFailureCode) *proto.HtlcResolution {
+	return i.failWithMessage(request, i.mapFailureCode(code))
+}
+
+// failWithMessage fails the htlc with the hex encoded failure message, which
+// starts with the failure code.
+func (i *ClnHtlcInterceptor) failWithMessage(request *proto.HtlcAccepted, message string) *proto.HtlcResolution {
 	return &proto.HtlcResolution{
 		Correlationid: request.Correlationid,
 		Outcome: &proto.HtlcResolution_Fail{
 			Fail: &proto.HtlcFail{
 				Failure: &proto.HtlcFail_FailureMessage{
-					FailureMessage: i.mapFailureCode(code),
+					FailureMessage: message,
 				},
 			},
 		},
 	}
 }
 
-func encodePayloadWithNextHop(payload []byte, channelId uint64, amountToForward uint64) ([]byte, error) {
+// Tlv type of encrypted_recipient_data in a hop payload. The forwarding
+// instructions of a blinded hop are in the encrypted_recipient_data, rather
+// than in the short_channel_id and amt_to_forward records.
+const encryptedRecipientDataOnionType tlv.Type = 10
+
+func decodePayload(payload []byte) ([]byte, tlv.TypeMap, error) {
 	bufReader := bytes.NewBuffer(payload)
 	var b [8]byte
 	varInt, err := sphinx.ReadVarInt(bufReader, &b)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read payload length %x: %v", payload, err)
+		return nil, nil, fmt.Errorf("failed to read payload length %x: %v", payload, err)
 	}
 
 	innerPayload := make([]byte, varInt)
 	if _, err := io.ReadFull(bufReader, innerPayload[:]); err != nil {
-		return nil, fmt.Errorf("failed to decode payload %x: %v", innerPayload[:], err)
+		return nil, nil, fmt.Errorf("failed to decode payload %x: %v", innerPayload[:], err)
 	}
 
 	s, _ := tlv.NewStream()
 	tlvMap, err := s.DecodeWithParsedTypes(bytes.NewReader(innerPayload))
 	if err != nil {
-		return nil, fmt.Errorf("DecodeWithParsedTypes failed for %x: %v", innerPayload[:], err)
+		return nil, nil, fmt.Errorf("DecodeWithParsedTypes failed for %x: %v", innerPayload[:], err)
+	}
+
+	return innerPayload, tlvMap, nil
+}
+
+// isBlindedPayload returns whether the hop payload is part of a blinded path.
+func isBlindedPayload(payload []byte) (bool, error) {
+	_, tlvMap, err := decodePayload(payload)
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := tlvMap[encryptedRecipientDataOnionType]
+	return ok, nil
+}
+
+// checkBlindedForward returns an error if the amount cln forwards for a
+// blinded htlc is unknown, or more than the amount of the htlc left after
+// deducting the fee of the registered payment.
+func checkBlindedForward(forwardMsat uint64, amountMsat basetypes.MilliSatoshi) error {
+	if forwardMsat == 0 {
+		return fmt.Errorf("blinded htlc without forward amount")
+	}
+
+	if forwardMsat > uint64(amountMsat) {
+		return fmt.Errorf("blinded htlc forwards %d msat, more than the %d msat left after the opening fee", forwardMsat, amountMsat)
+	}
+
+	return nil
+}
+
+func encodePayloadWithNextHop(payload []byte, channelId uint64, amountToForward uint64) ([]byte, error) {
+	innerPayload, tlvMap, err := decodePayload(payload)
+	if err != nil {
+		return nil, err
 	}
 
 	tt := record.NewNextHopIDRecord(&channelId)
@@ -282,7 +470,7 @@ func encodePayloadWithNextHop(payload []byte, channelId uint64, amountToForward
 		uTlvMap[uint64(t)] = b
 	}
 	tlvRecords := tlv.MapToRecords(uTlvMap)
-	s, err = tlv.NewStream(tlvRecords...)
+	s, err := tlv.NewStream(tlvRecords...)
 	if err != nil {
 		return nil, fmt.Errorf("tlv.NewStream(%x) error: %v", tlvRecords, err)
 	}
@@ -302,8 +490,19 @@ func (i *ClnHtlcInterceptor) mapFailureCode(original interceptor.InterceptFailur
 		return "2002"
 	case interceptor.FAILURE_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS:
 		return "400F"
+	case interceptor.FAILURE_PERMANENT_NODE_FAILURE:
+		return "4002"
+	case interceptor.FAILURE_PERMANENT_CHANNEL_FAILURE:
+		return "4008"
+	case interceptor.FAILURE_UNKNOWN_NEXT_PEER:
+		return "400A"
+	case interceptor.FAILURE_EXPIRY_TOO_SOON:
+		// expiry_too_soon carries a channel_update, which is left empty.
+		return "100E0000"
+	case interceptor.FAILURE_EXPIRY_TOO_FAR:
+		return "0015"
 	default:
-		log.Printf("Unknown failure code %v, default to temporary channel failure.", original)
+		i.logger.Printf("Unknown failure code %v, default to temporary channel failure.", original)
 		return "1007" // temporary channel failure
 	}
 }
@@ -0,0 +1,112 @@
+package cln
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/breez/lspd/fixtures"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/tlv"
+	"github.com/stretchr/testify/assert"
+)
+
+var blindingPoint = fixtures.Key("blinding").PubKey().SerializeCompressed()
+
+var payloadTests = []struct {
+	name    string
+	hop     *fixtures.Hop
+	types   []tlv.Type
+	blinded bool
+}{
+	{
+		name:  "forward",
+		hop:   &fixtures.Hop{NodeSeed: "lsp", AmountMsat: 10000, OutgoingCltv: 800000, NextScid: 123},
+		types: []tlv.Type{record.AmtOnionType, record.LockTimeOnionType, record.NextHopOnionType},
+	},
+	{
+		name: "final mpp",
+		hop: &fixtures.Hop{
+			NodeSeed:        "payee",
+			AmountMsat:      10000,
+			OutgoingCltv:    800000,
+			PaymentSecret:   bytes.Repeat([]byte{0x01}, 32),
+			TotalAmountMsat: 20000,
+		},
+		types: []tlv.Type{record.AmtOnionType, record.LockTimeOnionType, record.MPPOnionType},
+	},
+	{
+		name:    "blinded introduction",
+		hop:     &fixtures.Hop{NodeSeed: "lsp", EncryptedData: []byte{0x02, 0x03}, BlindingPoint: blindingPoint},
+		types:   []tlv.Type{encryptedRecipientDataOnionType, 12},
+		blinded: true,
+	},
+	{
+		name:    "blinded",
+		hop:     &fixtures.Hop{NodeSeed: "lsp", EncryptedData: []byte{0x02, 0x03}},
+		types:   []tlv.Type{encryptedRecipientDataOnionType},
+		blinded: true,
+	},
+	{
+		name: "blinded final",
+		hop: &fixtures.Hop{
+			NodeSeed:        "payee",
+			AmountMsat:      10000,
+			OutgoingCltv:    800000,
+			EncryptedData:   []byte{0x02, 0x03},
+			TotalAmountMsat: 20000,
+		},
+		types:   []tlv.Type{record.AmtOnionType, record.LockTimeOnionType, encryptedRecipientDataOnionType, 18},
+		blinded: true,
+	},
+}
+
+func TestDecodePayload(t *testing.T) {
+	for _, tt := range payloadTests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := tt.hop.Payload()
+			assert.NoError(t, err)
+			clnPayload, err := tt.hop.ClnPayload()
+			assert.NoError(t, err)
+
+			inner, tlvMap, err := decodePayload(clnPayload)
+			assert.NoError(t, err)
+			assert.Equal(t, payload, inner)
+			assert.Len(t, tlvMap, len(tt.types))
+			for _, typ := range tt.types {
+				assert.Contains(t, tlvMap, typ)
+			}
+		})
+	}
+
+	for name, payload := range map[string][]byte{
+		"empty":     {},
+		"truncated": {0x05, 0x02, 0x01},
+		"invalid":   {0x03, 0x02, 0x05, 0x01},
+	} {
+		_, _, err := decodePayload(payload)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestIsBlindedPayload(t *testing.T) {
+	for _, tt := range payloadTests {
+		t.Run(tt.name, func(t *testing.T) {
+			clnPayload, err := tt.hop.ClnPayload()
+			assert.NoError(t, err)
+
+			blinded, err := isBlindedPayload(clnPayload)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.blinded, blinded)
+		})
+	}
+
+	_, err := isBlindedPayload([]byte{0x05, 0x02, 0x01})
+	assert.Error(t, err)
+}
+
+func TestCheckBlindedForward(t *testing.T) {
+	assert.NoError(t, checkBlindedForward(9000, 9000))
+	assert.NoError(t, checkBlindedForward(8000, 9000))
+	assert.Error(t, checkBlindedForward(9001, 9000))
+	assert.Error(t, checkBlindedForward(0, 9000))
+}
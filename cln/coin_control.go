@@ -0,0 +1,96 @@
+package cln
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/breez/lspd/lightning"
+	"github.com/niftynei/glightning/glightning"
+)
+
+// utxoPsbtRequest is the utxopsbt method, which funds a psbt with the given
+// utxos like fundpsbt.
+type utxoPsbtRequest struct {
+	Satoshi        string   `json:"satoshi"`
+	FeeRate        string   `json:"feerate"`
+	StartWeight    int      `json:"startweight"`
+	Utxos          []string `json:"utxos"`
+	ExcessAsChange bool     `json:"excess_as_change"`
+}
+
+func (r *utxoPsbtRequest) Name() string {
+	return "utxopsbt"
+}
+
+// SetCoinControl makes the node fund channel opens and splices with the
+// utxos coin control selects. Outputs reserved with reserveinputs are never
+// selected.
+func (c *ClnClient) SetCoinControl(cc *lightning.CoinControl) {
+	c.coinControl = cc
+}
+
+// listUtxos returns the outputs of the node wallet.
+func (c *ClnClient) listUtxos() ([]*lightning.Utxo, error) {
+	info, err := c.GetInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	funds, err := rpcRead(context.Background(), c.rpc, "listfunds", func(client *glightning.Lightning) (*glightning.FundsResult, error) {
+		return client.ListFunds()
+	})
+	if err != nil {
+		c.logger.Printf("CLN: client.ListFunds() error: %v", err)
+		return nil, fmt.Errorf("CLN: client.ListFunds() error: %w", err)
+	}
+
+	var utxos []*lightning.Utxo
+	for _, o := range funds.Outputs {
+		var confirmations uint32
+		if o.Status == "confirmed" && o.Blockheight > 0 && uint32(o.Blockheight) <= info.BlockHeight {
+			confirmations = info.BlockHeight - uint32(o.Blockheight) + 1
+		} else if o.Status != "unconfirmed" {
+			// Spent outputs.
+			continue
+		}
+
+		utxos = append(utxos, &lightning.Utxo{
+			Outpoint:      fmt.Sprintf("%s:%d", o.TxId, o.Output),
+			AmountSat:     o.Value,
+			Confirmations: confirmations,
+			Reserved:      o.Reserved,
+		})
+	}
+
+	return utxos, nil
+}
+
+// selectUtxos selects the utxos funding amountSat, as txid:vout.
+func (c *ClnClient) selectUtxos(amountSat uint64, feeSatPerVByte *float64) ([]string, error) {
+	utxos, err := c.listUtxos()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := c.coinControl.SelectUtxos(utxos, amountSat, feeSatPerVByte)
+	if err != nil {
+		c.logger.Printf("CLN: no utxos to fund %v sat: %v", amountSat, err)
+		return nil, err
+	}
+
+	var result []string
+	for _, u := range selected {
+		result = append(result, u.Outpoint)
+	}
+
+	return result, nil
+}
+
+func (c *ClnClient) coinControlBalance() (*lightning.GetWalletBalanceResult, error) {
+	utxos, err := c.listUtxos()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.coinControl.Balance(utxos), nil
+}
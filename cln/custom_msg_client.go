@@ -0,0 +1,183 @@
+package cln
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/breez/lspd/cln_plugin/proto"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+// Number of received custom messages buffered until they are read with Recv.
+const customMsgQueueSize = 10000
+
+// CustomMsgClient receives the custom messages of the node from the custom
+// message stream of the cln plugin, and sends custom messages with the
+// sendcustommsg rpc of cln.
+type CustomMsgClient struct {
+	pluginAddress string
+	client        *ClnClient
+	recvQueue     chan *lightning.CustomMessage
+	ctx           context.Context
+	cancel        context.CancelFunc
+	logger        *log.Logger
+}
+
+// NewCustomMsgClient returns a client of the custom messages of the node,
+// received from the cln plugin.
+func (c *ClnClient) NewCustomMsgClient() lightning.CustomMsgClient {
+	return NewCustomMsgClient(c.conf, c, c.logger)
+}
+
+func NewCustomMsgClient(conf *config.ClnConfig, client *ClnClient, logger *log.Logger) *CustomMsgClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CustomMsgClient{
+		pluginAddress: conf.PluginAddress,
+		client:        client,
+		recvQueue:     make(chan *lightning.CustomMessage, customMsgQueueSize),
+		ctx:           ctx,
+		cancel:        cancel,
+		logger:        logger,
+	}
+}
+
+func (c *CustomMsgClient) Start() error {
+	ctx := c.ctx
+	c.logger.Printf("Dialing cln plugin on '%s' for custom messages", c.pluginAddress)
+	conn, err := grpc.DialContext(
+		ctx,
+		c.pluginAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    time.Duration(10) * time.Second,
+			Timeout: time.Duration(10) * time.Second,
+		}),
+	)
+	if err != nil {
+		c.logger.Printf("grpc.Dial error: %v", err)
+		return err
+	}
+	defer conn.Close()
+
+	c.listen(ctx, proto.NewClnPluginClient(conn))
+	return nil
+}
+
+// listen pushes the custom messages of the plugin to the receive queue until
+// the client is stopped, resubscribing when the stream breaks.
+func (c *CustomMsgClient) listen(ctx context.Context, pluginClient proto.ClnPluginClient) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		stream, err := pluginClient.CustomMsgStream(ctx, &proto.CustomMessageRequest{})
+		if err != nil {
+			c.logger.Printf("pluginClient.CustomMsgStream(): %v", err)
+			<-time.After(time.Second)
+			continue
+		}
+
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				status, ok := status.FromError(err)
+				if ok && status.Code() == codes.Unimplemented {
+					c.logger.Printf("WARN: the cln plugin doesn't forward custom messages, upgrade it.")
+					return
+				}
+				if !ok || status.Code() != codes.Canceled {
+					c.logger.Printf("unexpected error in CustomMsgStream: %v", err)
+				}
+				break
+			}
+
+			m, err := parseCustomMessage(msg)
+			if err != nil {
+				c.logger.Printf("Invalid custom message from %s: %v", msg.PeerId, err)
+				continue
+			}
+
+			select {
+			case c.recvQueue <- m:
+			default:
+				c.logger.Printf("Custom message queue is full, dropping message of type %d from %s", m.Type, msg.PeerId)
+			}
+		}
+
+		<-time.After(time.Second)
+	}
+}
+
+func parseCustomMessage(msg *proto.CustomMessage) (*lightning.CustomMessage, error) {
+	peerID, err := hex.DecodeString(msg.PeerId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer id: %w", err)
+	}
+
+	payload, err := hex.DecodeString(msg.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	if len(payload) < 2 {
+		return nil, fmt.Errorf("payload too short for the type")
+	}
+
+	return &lightning.CustomMessage{
+		PeerID: peerID,
+		Type:   uint32(binary.BigEndian.Uint16(payload)),
+		Data:   payload[2:],
+	}, nil
+}
+
+func (c *CustomMsgClient) Recv() (*lightning.CustomMessage, error) {
+	select {
+	case msg := <-c.recvQueue:
+		return msg, nil
+	case <-c.ctx.Done():
+		return nil, lightning.ErrCustomMsgClientStopped
+	}
+}
+
+type sendCustomMsgRequest struct {
+	NodeID string `json:"node_id"`
+	Msg    string `json:"msg"`
+}
+
+func (r *sendCustomMsgRequest) Name() string {
+	return "sendcustommsg"
+}
+
+func (c *CustomMsgClient) Send(msg *lightning.CustomMessage) error {
+	if msg.Type > 0xffff {
+		return fmt.Errorf("invalid custom message type %d", msg.Type)
+	}
+
+	payload := make([]byte, 2, 2+len(msg.Data))
+	binary.BigEndian.PutUint16(payload, uint16(msg.Type))
+	payload = append(payload, msg.Data...)
+	err := c.client.rpc.requestOnce(&sendCustomMsgRequest{
+		NodeID: hex.EncodeToString(msg.PeerID),
+		Msg:    hex.EncodeToString(payload),
+	}, &struct{}{})
+	if err != nil {
+		c.logger.Printf("CLN: sendcustommsg(%x, %d) error: %v", msg.PeerID, msg.Type, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *CustomMsgClient) Stop() {
+	c.cancel()
+}
@@ -0,0 +1,127 @@
+package cln
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/breez/lspd/lightning"
+)
+
+// funderUpdateRequest is the funderupdate method of the funder plugin, which
+// sets the policy the node contributes to dual-funded channels with and the
+// lease rates of its liquidity ad. Amounts are in millisatoshi.
+type funderUpdateRequest struct {
+	Policy                               string  `json:"policy,omitempty"`
+	PolicyMod                            uint64  `json:"policy_mod"`
+	LeasesOnly                           bool    `json:"leases_only"`
+	MinTheirFundingMsat                  uint64  `json:"min_their_funding_msat"`
+	MaxTheirFundingMsat                  *uint64 `json:"max_their_funding_msat,omitempty"`
+	PerChannelMaxMsat                    *uint64 `json:"per_channel_max_msat,omitempty"`
+	ReserveTankMsat                      uint64  `json:"reserve_tank_msat"`
+	LeaseFeeBaseMsat                     *uint64 `json:"lease_fee_base_msat,omitempty"`
+	LeaseFeeBasis                        *uint32 `json:"lease_fee_basis,omitempty"`
+	FundingWeight                        *uint32 `json:"funding_weight,omitempty"`
+	ChannelFeeMaxBaseMsat                *uint32 `json:"channel_fee_max_base_msat,omitempty"`
+	ChannelFeeMaxProportionalThousandths *uint32 `json:"channel_fee_max_proportional_thousandths,omitempty"`
+}
+
+func (r *funderUpdateRequest) Name() string {
+	return "funderupdate"
+}
+
+type listPeerChannelsFundingRequest struct {
+	ID string `json:"id"`
+}
+
+func (r *listPeerChannelsFundingRequest) Name() string {
+	return "listpeerchannels"
+}
+
+type listPeerChannelsFunding struct {
+	Channels []struct {
+		FundingTxID   string `json:"funding_txid"`
+		FundingOutnum uint32 `json:"funding_outnum"`
+		Funding       *struct {
+			LocalFundsMsat  uint64 `json:"local_funds_msat"`
+			RemoteFundsMsat uint64 `json:"remote_funds_msat"`
+			FeePaidMsat     uint64 `json:"fee_paid_msat"`
+			FeeRcvdMsat     uint64 `json:"fee_rcvd_msat"`
+		} `json:"funding"`
+	} `json:"channels"`
+}
+
+// SetFunderPolicy sets the funder policy of the node. The node has to run
+// with experimental-dual-fund for peers to open dual-funded channels to it,
+// and for the lease rates to be advertised.
+func (c *ClnClient) SetFunderPolicy(ctx context.Context, policy *lightning.FunderPolicy) error {
+	req := &funderUpdateRequest{
+		Policy:              policy.Policy,
+		PolicyMod:           policy.PolicyMod,
+		LeasesOnly:          policy.LeasesOnly,
+		MinTheirFundingMsat: policy.MinTheirFundingSat * 1000,
+		ReserveTankMsat:     policy.ReserveTankSat * 1000,
+	}
+	if policy.MaxTheirFundingSat > 0 {
+		m := policy.MaxTheirFundingSat * 1000
+		req.MaxTheirFundingMsat = &m
+	}
+	if policy.PerChannelMaxSat > 0 {
+		m := policy.PerChannelMaxSat * 1000
+		req.PerChannelMaxMsat = &m
+	}
+	if r := policy.LeaseRates; r != nil {
+		base := r.LeaseFeeBaseSat * 1000
+		req.LeaseFeeBaseMsat = &base
+		req.LeaseFeeBasis = &r.LeaseFeeBasis
+		req.FundingWeight = &r.FundingWeight
+		req.ChannelFeeMaxBaseMsat = &r.ChannelFeeMaxBaseMsat
+		req.ChannelFeeMaxProportionalThousandths = &r.ChannelFeeMaxProportionalThousandths
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var result map[string]interface{}
+	err := c.rpc.request(req, &result)
+	if err != nil {
+		c.logger.Printf("CLN: funderupdate error: %v", err)
+		return fmt.Errorf("funderupdate error: %w", err)
+	}
+
+	return nil
+}
+
+// ListChannelFunding returns the funds both sides contributed to the channels
+// with the peer. Channels opened before dual funding have no funding details,
+// their capacity was funded by the opener.
+func (c *ClnClient) ListChannelFunding(ctx context.Context, peerID []byte) (map[string]*lightning.ChannelFunding, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pubkey := hex.EncodeToString(peerID)
+	var channels listPeerChannelsFunding
+	err := c.rpc.request(&listPeerChannelsFundingRequest{ID: pubkey}, &channels)
+	if err != nil {
+		c.logger.Printf("CLN: listpeerchannels(%s) error: %v", pubkey, err)
+		return nil, err
+	}
+
+	result := make(map[string]*lightning.ChannelFunding)
+	for _, ch := range channels.Channels {
+		if ch.Funding == nil {
+			continue
+		}
+
+		result[fmt.Sprintf("%s:%d", ch.FundingTxID, ch.FundingOutnum)] = &lightning.ChannelFunding{
+			LocalFundsSat:        ch.Funding.LocalFundsMsat / 1000,
+			RemoteFundsSat:       ch.Funding.RemoteFundsMsat / 1000,
+			LeaseFeeReceivedMsat: ch.Funding.FeeRcvdMsat,
+			LeaseFeePaidMsat:     ch.Funding.FeePaidMsat,
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,205 @@
+package cln
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/breez/lspd/cln_plugin/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultHoldTimeout = 5 * time.Minute
+
+var holdDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "lspd_htlc_hold_duration_seconds",
+	Help:    "Time cln held htlcs while a channel was opened for them, as measured by the cln plugin, by outcome.",
+	Buckets: []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+}, []string{"node", "outcome"})
+
+// An htlc that is being intercepted.
+type inflightHtlc struct {
+	stream proto.ClnPlugin_HtlcStreamClient
+	held   bool
+}
+
+// track registers the htlc as being intercepted, so it can be held when a
+// channel is opened for its payment.
+func (i *ClnHtlcInterceptor) track(request *proto.HtlcAccepted, stream proto.ClnPlugin_HtlcStreamClient) {
+	i.inflightMtx.Lock()
+	defer i.inflightMtx.Unlock()
+	htlcs, ok := i.inflight[request.Htlc.PaymentHash]
+	if !ok {
+		htlcs = make(map[string]*inflightHtlc)
+		i.inflight[request.Htlc.PaymentHash] = htlcs
+	}
+
+	htlcs[request.Correlationid] = &inflightHtlc{stream: stream}
+}
+
+func (i *ClnHtlcInterceptor) untrack(request *proto.HtlcAccepted) {
+	i.inflightMtx.Lock()
+	defer i.inflightMtx.Unlock()
+	htlcs := i.inflight[request.Htlc.PaymentHash]
+	delete(htlcs, request.Correlationid)
+	if len(htlcs) == 0 {
+		delete(i.inflight, request.Htlc.PaymentHash)
+	}
+}
+
+// ChannelOpening tells the plugin to hold the htlcs of the payment while a
+// channel is opened for it, so the plugin can fail them if lspd doesn't
+// resolve them in time.
+func (i *ClnHtlcInterceptor) ChannelOpening(paymentHash []byte) {
+	if !i.supportsHold.Load() {
+		return
+	}
+
+	i.inflightMtx.Lock()
+	defer i.inflightMtx.Unlock()
+	for id, h := range i.inflight[hex.EncodeToString(paymentHash)] {
+		if h.held {
+			continue
+		}
+
+		h.held = true
+		err := i.send(h.stream, &proto.HtlcResolution{
+			Correlationid: id,
+			Outcome: &proto.HtlcResolution_Hold{
+				Hold: &proto.HtlcHold{
+					TimeoutSeconds: uint32(i.holdTimeout.Seconds()),
+				},
+			},
+		})
+		if err != nil {
+			i.logger.Printf("Failed to tell the plugin to hold htlc %s: %v", id, err)
+		}
+	}
+}
+
+// send sends the resolution to the plugin. Resolutions are sent from the
+// goroutines of all htlcs, but the stream doesn't support concurrent sends.
+func (i *ClnHtlcInterceptor) send(stream proto.ClnPlugin_HtlcStreamClient, resolution *proto.HtlcResolution) error {
+	i.sendMtx.Lock()
+	defer i.sendMtx.Unlock()
+	return stream.Send(resolution)
+}
+
+// listenHoldEvents records the hold durations the cln plugin reports.
+func (i *ClnHtlcInterceptor) listenHoldEvents() {
+	ctx := i.ctx
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		stream, err := i.pluginClient.HoldEventStream(ctx, &proto.HoldEventRequest{})
+		if err != nil {
+			i.logger.Printf("pluginClient.HoldEventStream(): %v", err)
+			<-time.After(time.Second)
+			continue
+		}
+
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				status, ok := status.FromError(err)
+				if !ok || status.Code() != codes.Canceled {
+					i.logger.Printf("unexpected error in listenHoldEvents: %v", err)
+				}
+				break
+			}
+
+			d := time.Duration(event.HoldDurationMs) * time.Millisecond
+			i.logger.Printf("Plugin held htlc %s for %v, outcome: %s", event.Correlationid, d, event.Outcome)
+			holdDuration.WithLabelValues(i.config.Label(), event.Outcome).Observe(d.Seconds())
+		}
+
+		<-time.After(time.Second)
+	}
+}
+
+// sendHeartbeats tells the plugin lspd is still working on the htlcs it is
+// intercepting, if the plugin requires heartbeats. Heartbeats are sent three
+// times per heartbeat timeout, so one lost heartbeat doesn't make the plugin
+// resolve the htlcs.
+func (i *ClnHtlcInterceptor) sendHeartbeats() {
+	ctx := i.ctx
+	for {
+		interval := time.Duration(i.heartbeatTimeout.Load()) / 3
+		if interval <= 0 {
+			interval = time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		if i.heartbeatTimeout.Load() == 0 {
+			continue
+		}
+
+		i.inflightMtx.Lock()
+		for _, htlcs := range i.inflight {
+			for id, h := range htlcs {
+				err := i.send(h.stream, &proto.HtlcResolution{
+					Correlationid: id,
+					Outcome: &proto.HtlcResolution_Heartbeat{
+						Heartbeat: &proto.HtlcHeartbeat{},
+					},
+				})
+				if err != nil {
+					i.logger.Printf("Failed to send a heartbeat for htlc %s: %v", id, err)
+				}
+			}
+		}
+		i.inflightMtx.Unlock()
+	}
+}
+
+// checkSupportsHold checks whether the plugin supports holding htlcs. Older
+// plugins would continue htlcs on a hold, so htlcs are not held for them. It
+// also picks up the heartbeat timeout of the plugin.
+func (i *ClnHtlcInterceptor) checkSupportsHold(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	resp, err := i.pluginClient.Ping(ctx, &proto.PingRequest{})
+	if err != nil {
+		i.logger.Printf("pluginClient.Ping() error, not holding htlcs: %v", err)
+		i.supportsHold.Store(false)
+		return
+	}
+
+	// Older plugins would continue htlcs on a heartbeat, so heartbeats are
+	// only sent to plugins that ask for them.
+	heartbeatTimeout := time.Duration(resp.HeartbeatTimeoutSeconds) * time.Second
+	if heartbeatTimeout > 0 {
+		i.logger.Printf("The cln plugin resolves htlcs without a heartbeat within %v.", heartbeatTimeout)
+	}
+	i.heartbeatTimeout.Store(int64(heartbeatTimeout))
+
+	if !resp.SupportsHold {
+		i.logger.Printf("WARN: the cln plugin doesn't support holding htlcs, upgrade it to get hold metrics.")
+	}
+	i.supportsHold.Store(resp.SupportsHold)
+}
+
+func parseHoldTimeout(s string, logger *log.Logger) time.Duration {
+	if s == "" {
+		return defaultHoldTimeout
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		logger.Printf("WARN: invalid cln holdTimeout '%s', using %v: %v", s, defaultHoldTimeout, err)
+		return defaultHoldTimeout
+	}
+
+	return d
+}
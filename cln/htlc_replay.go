@@ -0,0 +1,148 @@
+package cln
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/cln_plugin/proto"
+	"github.com/breez/lspd/tenant"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// Htlcs are only replayed while they are pending, which is bounded by their
+// expiry. Resolutions older than this are removed.
+const htlcResolutionRetention = 14 * 24 * time.Hour
+
+// HtlcStore caches the resolutions of intercepted htlcs. CLN replays the
+// htlc_accepted hook for pending htlcs after a restart, and the plugin
+// replays them when lspd reconnects. A replayed htlc gets the stored
+// resolution, rather than being processed again.
+type HtlcStore interface {
+	GetHtlcResolution(ctx context.Context, lspNodeID, paymentHash []byte, incomingScid, htlcID uint64) ([]byte, error)
+	SaveHtlcResolution(ctx context.Context, lspNodeID, paymentHash []byte, incomingScid, htlcID uint64, resolution []byte) error
+	DeleteHtlcResolutions(ctx context.Context, before time.Time) (int64, error)
+}
+
+// htlcKey identifies an htlc by its payment hash and its id on the incoming
+// channel.
+type htlcKey struct {
+	paymentHash  []byte
+	incomingScid uint64
+	htlcID       uint64
+}
+
+func newHtlcKey(request *proto.HtlcAccepted) (*htlcKey, error) {
+	paymentHash, err := hex.DecodeString(request.Htlc.PaymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	scid, err := basetypes.NewShortChannelIDFromString(request.Htlc.ShortChannelId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &htlcKey{
+		paymentHash:  paymentHash,
+		incomingScid: uint64(*scid),
+		htlcID:       request.Htlc.Id,
+	}, nil
+}
+
+func (k *htlcKey) String() string {
+	return fmt.Sprintf("%x:%d:%d", k.paymentHash, k.incomingScid, k.htlcID)
+}
+
+// resolveOnce returns the resolution of the htlc. An htlc that is being
+// processed, or was resolved before, is not processed again.
+func (i *ClnHtlcInterceptor) resolveOnce(request *proto.HtlcAccepted) *proto.HtlcResolution {
+	if i.htlcStore == nil {
+		return i.resolve(request)
+	}
+
+	key, err := newHtlcKey(request)
+	if err != nil {
+		return i.resolve(request)
+	}
+
+	// Replays of an htlc that is still being processed wait for its result.
+	r, _, _ := i.replayGroup.Do(key.String(), func() (interface{}, error) {
+		stored, err := i.storedResolution(key)
+		if err != nil {
+			i.logger.Printf("GetHtlcResolution(%s) error: %v", key, err)
+		}
+		if stored != nil {
+			i.logger.Printf("htlc %s was replayed, sending the stored resolution", key)
+			return stored, nil
+		}
+
+		resolution := i.resolve(request)
+		i.saveResolution(key, resolution)
+		return resolution, nil
+	})
+
+	// The correlation id differs between replays of the htlc.
+	resolution := protobuf.Clone(r.(*proto.HtlcResolution)).(*proto.HtlcResolution)
+	resolution.Correlationid = request.Correlationid
+	return resolution
+}
+
+func (i *ClnHtlcInterceptor) storedResolution(key *htlcKey) (*proto.HtlcResolution, error) {
+	b, err := i.htlcStore.GetHtlcResolution(i.tenantContext(), i.lspNodeID(), key.paymentHash, key.incomingScid, key.htlcID)
+	if err != nil || b == nil {
+		return nil, err
+	}
+
+	resolution := &proto.HtlcResolution{}
+	err = protobuf.Unmarshal(b, resolution)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored resolution: %w", err)
+	}
+
+	return resolution, nil
+}
+
+func (i *ClnHtlcInterceptor) saveResolution(key *htlcKey, resolution *proto.HtlcResolution) {
+	stored := protobuf.Clone(resolution).(*proto.HtlcResolution)
+	stored.Correlationid = ""
+	b, err := protobuf.Marshal(stored)
+	if err != nil {
+		i.logger.Printf("failed to marshal resolution of htlc %s: %v", key, err)
+		return
+	}
+
+	err = i.htlcStore.SaveHtlcResolution(i.tenantContext(), i.lspNodeID(), key.paymentHash, key.incomingScid, key.htlcID, b)
+	if err != nil {
+		i.logger.Printf("SaveHtlcResolution(%s) error: %v", key, err)
+	}
+}
+
+func (i *ClnHtlcInterceptor) pruneResolutions() {
+	if i.htlcStore == nil {
+		return
+	}
+
+	deleted, err := i.htlcStore.DeleteHtlcResolutions(i.tenantContext(), time.Now().Add(-htlcResolutionRetention))
+	if err != nil {
+		i.logger.Printf("DeleteHtlcResolutions() error: %v", err)
+		return
+	}
+
+	if deleted > 0 {
+		i.logger.Printf("Removed %d stored htlc resolutions", deleted)
+	}
+}
+
+func (i *ClnHtlcInterceptor) lspNodeID() []byte {
+	lspNodeID, _ := hex.DecodeString(i.config.NodePubkey)
+	return lspNodeID
+}
+
+// tenantContext returns the context of the tenant of the node, in which the
+// resolutions of its htlcs are stored.
+func (i *ClnHtlcInterceptor) tenantContext() context.Context {
+	return tenant.NewContext(context.Background(), i.config.Tenant)
+}
@@ -0,0 +1,88 @@
+package cln
+
+import (
+	"fmt"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/liquidity"
+)
+
+type peerswapListPeersRequest struct{}
+
+func (r *peerswapListPeersRequest) Name() string {
+	return "peerswap-listpeers"
+}
+
+type peerswapPeer struct {
+	NodeID       string             `json:"node_id"`
+	SwapsAllowed bool               `json:"swaps_allowed"`
+	Channels     []*peerswapChannel `json:"channels"`
+}
+
+type peerswapChannel struct {
+	ShortChannelID string `json:"short_channel_id"`
+	LocalBalance   uint64 `json:"local_balance"`
+	RemoteBalance  uint64 `json:"remote_balance"`
+	Active         bool   `json:"active"`
+}
+
+type peerswapSwapOutRequest struct {
+	ShortChannelID string `json:"short_channel_id"`
+	AmountSat      uint64 `json:"amt_sat"`
+	Asset          string `json:"asset"`
+}
+
+func (r *peerswapSwapOutRequest) Name() string {
+	return "peerswap-swap-out"
+}
+
+type peerswapSwap struct {
+	ID string `json:"id"`
+}
+
+// ListSwapPeers lists the peers running peerswap through the peerswap cln
+// plugin.
+func (c *ClnClient) ListSwapPeers() ([]*liquidity.SwapPeer, error) {
+	var peers []*peerswapPeer
+	err := c.rpc.request(&peerswapListPeersRequest{}, &peers)
+	if err != nil {
+		c.logger.Printf("CLN: peerswap-listpeers error: %v", err)
+		return nil, fmt.Errorf("CLN: peerswap-listpeers error: %w", err)
+	}
+
+	var result []*liquidity.SwapPeer
+	for _, p := range peers {
+		peer := &liquidity.SwapPeer{
+			NodeID:       p.NodeID,
+			SwapsAllowed: p.SwapsAllowed,
+		}
+		for _, ch := range p.Channels {
+			peer.Channels = append(peer.Channels, &liquidity.SwapChannel{
+				ShortChannelID:   ch.ShortChannelID,
+				LocalBalanceSat:  basetypes.Satoshi(ch.LocalBalance),
+				RemoteBalanceSat: basetypes.Satoshi(ch.RemoteBalance),
+				Active:           ch.Active,
+			})
+		}
+		result = append(result, peer)
+	}
+
+	return result, nil
+}
+
+// SwapOut swaps channel balance out to the on-chain wallet through the
+// peerswap cln plugin. Returns the swap id.
+func (c *ClnClient) SwapOut(shortChannelID string, amountSat basetypes.Satoshi, asset string) (string, error) {
+	var swap peerswapSwap
+	err := c.rpc.requestOnce(&peerswapSwapOutRequest{
+		ShortChannelID: shortChannelID,
+		AmountSat:      uint64(amountSat),
+		Asset:          asset,
+	}, &swap)
+	if err != nil {
+		c.logger.Printf("CLN: peerswap-swap-out(%s, %d) error: %v", shortChannelID, amountSat, err)
+		return "", fmt.Errorf("CLN: peerswap-swap-out(%s, %d) error: %w", shortChannelID, amountSat, err)
+	}
+
+	return swap.ID, nil
+}
@@ -0,0 +1,212 @@
+package cln
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/niftynei/glightning/glightning"
+	"github.com/niftynei/glightning/jrpc2"
+)
+
+const (
+	defaultRpcPoolSize = 4
+	defaultRpcTimeout  = 10 * time.Second
+	defaultRpcRetries  = 2
+
+	// Timeout of calls that change state, like fundchannel, which are not
+	// retried and may legitimately take long.
+	rpcWriteTimeout = 60 * time.Second
+
+	rpcRetryBaseDelay = 100 * time.Millisecond
+)
+
+var errRpcTimeout = errors.New("rpc timeout")
+
+// rpcPool is a pool of connections to the json-rpc socket of lightningd.
+// Calls time out, so a slow lightningd can't stall htlc handling. A
+// connection with a call that timed out is only handed out again once the
+// call returns.
+type rpcPool struct {
+	clients chan *glightning.Lightning
+	timeout time.Duration
+	retries int
+	logger  *log.Logger
+}
+
+func newRpcPool(rpcFile string, lightningDir string, conf *config.ClnConfig, logger *log.Logger) *rpcPool {
+	size := defaultRpcPoolSize
+	if conf.RpcPoolSize > 0 {
+		size = conf.RpcPoolSize
+	}
+
+	timeout := defaultRpcTimeout
+	if conf.RpcTimeout != "" {
+		d, err := time.ParseDuration(conf.RpcTimeout)
+		if err != nil || d <= 0 {
+			logger.Printf("WARN: invalid cln rpcTimeout '%s', using %v: %v", conf.RpcTimeout, defaultRpcTimeout, err)
+		} else {
+			timeout = d
+		}
+	}
+
+	retries := defaultRpcRetries
+	if conf.RpcRetries != nil {
+		retries = *conf.RpcRetries
+	}
+
+	p := &rpcPool{
+		clients: make(chan *glightning.Lightning, size),
+		timeout: timeout,
+		retries: retries,
+		logger:  logger,
+	}
+	for i := 0; i < size; i++ {
+		client := glightning.NewLightning()
+		client.SetTimeout(uint(rpcWriteTimeout.Seconds()))
+		client.StartUp(rpcFile, lightningDir)
+		p.clients <- client
+	}
+
+	return p
+}
+
+// rpcCall runs fn with a connection from the pool, and fails with
+// errRpcTimeout if it doesn't return within the timeout. The call itself
+// can't be cancelled, it completes in the background.
+func rpcCall[T any](
+	ctx context.Context,
+	p *rpcPool,
+	method string,
+	timeout time.Duration,
+	fn func(client *glightning.Lightning) (T, error),
+) (T, error) {
+	var zero T
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var client *glightning.Lightning
+	select {
+	case client = <-p.clients:
+	case <-callCtx.Done():
+		return zero, rpcContextError(ctx, method, "waiting for a connection")
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan *result, 1)
+	go func() {
+		defer func() { p.clients <- client }()
+		value, err := fn(client)
+		done <- &result{value: value, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-callCtx.Done():
+		return zero, rpcContextError(ctx, method, "waiting for the response")
+	}
+}
+
+func rpcContextError(ctx context.Context, method string, stage string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%s: %w %s", method, errRpcTimeout, stage)
+}
+
+// rpcRead runs a call that is safe to repeat, retrying it with jitter on
+// timeouts and connection errors.
+func rpcRead[T any](
+	ctx context.Context,
+	p *rpcPool,
+	method string,
+	fn func(client *glightning.Lightning) (T, error),
+) (T, error) {
+	for attempt := 0; ; attempt++ {
+		value, err := rpcCall(ctx, p, method, p.timeout, fn)
+		if err == nil || attempt >= p.retries || !isTransientRpcError(err) {
+			return value, err
+		}
+
+		delay := retryDelay(attempt)
+		p.logger.Printf("CLN: %s failed, retrying in %v: %v", method, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return value, ctx.Err()
+		}
+	}
+}
+
+// rpcWrite runs a call that changes state once, with the longer write
+// timeout.
+func rpcWrite[T any](
+	ctx context.Context,
+	p *rpcPool,
+	method string,
+	fn func(client *glightning.Lightning) (T, error),
+) (T, error) {
+	return rpcCall(ctx, p, method, rpcWriteTimeout, fn)
+}
+
+// request sends the json-rpc request as a read, see rpcRead.
+func (p *rpcPool) request(m jrpc2.Method, resp interface{}) error {
+	return p.do(m, resp, rpcRead[interface{}])
+}
+
+// requestOnce sends the json-rpc request as a write, see rpcWrite.
+func (p *rpcPool) requestOnce(m jrpc2.Method, resp interface{}) error {
+	return p.do(m, resp, rpcWrite[interface{}])
+}
+
+func (p *rpcPool) do(
+	m jrpc2.Method,
+	resp interface{},
+	run func(context.Context, *rpcPool, string, func(*glightning.Lightning) (interface{}, error)) (interface{}, error),
+) error {
+	// Every attempt decodes into its own value, because an attempt that timed
+	// out may still complete in the background.
+	t := reflect.TypeOf(resp).Elem()
+	value, err := run(context.Background(), p, m.Name(), func(client *glightning.Lightning) (interface{}, error) {
+		v := reflect.New(t)
+		err := client.Request(m, v.Interface())
+		return v.Interface(), err
+	})
+	if err != nil {
+		return err
+	}
+
+	reflect.ValueOf(resp).Elem().Set(reflect.ValueOf(value).Elem())
+	return nil
+}
+
+func isTransientRpcError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, errRpcTimeout) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.As(err, &netErr)
+}
+
+// retryDelay returns the exponential backoff before the retry after the
+// attempt, with jitter so concurrent callers don't retry in lockstep.
+func retryDelay(attempt int) time.Duration {
+	base := rpcRetryBaseDelay << attempt
+	return base/2 + time.Duration(rand.Int63n(int64(base)))
+}
@@ -0,0 +1,81 @@
+package cln
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/niftynei/glightning/glightning"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRpcPool(size int, timeout time.Duration, retries int) *rpcPool {
+	p := &rpcPool{
+		clients: make(chan *glightning.Lightning, size),
+		timeout: timeout,
+		retries: retries,
+		logger:  log.New(os.Stderr, "", 0),
+	}
+	for i := 0; i < size; i++ {
+		p.clients <- &glightning.Lightning{}
+	}
+
+	return p
+}
+
+func TestRpcReadTimeout(t *testing.T) {
+	p := newTestRpcPool(2, 50*time.Millisecond, 0)
+	release := make(chan struct{})
+	defer close(release)
+
+	start := time.Now()
+	_, err := rpcRead(context.Background(), p, "getpeer", func(client *glightning.Lightning) (int, error) {
+		<-release
+		return 1, nil
+	})
+	assert.True(t, errors.Is(err, errRpcTimeout))
+	assert.True(t, time.Since(start) < time.Second)
+
+	// The stuck connection is not handed out, the other one is.
+	v, err := rpcRead(context.Background(), p, "getpeer", func(client *glightning.Lightning) (int, error) {
+		return 2, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
+
+func TestRpcReadRetries(t *testing.T) {
+	p := newTestRpcPool(1, time.Second, 2)
+	attempts := 0
+	v, err := rpcRead(context.Background(), p, "listpeers", func(client *glightning.Lightning) (int, error) {
+		attempts++
+		if attempts < 3 {
+			return 0, errRpcTimeout
+		}
+		return 3, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	attempts = 0
+	_, err = rpcRead(context.Background(), p, "getpeer", func(client *glightning.Lightning) (int, error) {
+		attempts++
+		return 0, errors.New("peer not found")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRpcWriteNoRetry(t *testing.T) {
+	p := newTestRpcPool(1, time.Second, 2)
+	attempts := 0
+	_, err := rpcWrite(context.Background(), p, "fundchannel", func(client *glightning.Lightning) (int, error) {
+		attempts++
+		return 0, errRpcTimeout
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
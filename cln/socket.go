@@ -0,0 +1,97 @@
+package cln
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const tcpPrefix = "tcp://"
+
+// socketForwarder exposes a remote cln json-rpc endpoint, for example a unix
+// socket forwarded over tcp with socat, as a local unix socket. This allows
+// running lspd against a cln node inside a container or VM on platforms where
+// the socket cannot be shared directly, like macOS and Windows.
+type socketForwarder struct {
+	address    string
+	socketPath string
+	listener   net.Listener
+	logger     *log.Logger
+}
+
+func newSocketForwarder(address string, logger *log.Logger) (*socketForwarder, error) {
+	dir, err := os.MkdirTemp("", "lspd-cln-")
+	if err != nil {
+		return nil, fmt.Errorf("os.MkdirTemp() error: %w", err)
+	}
+
+	socketPath := filepath.Join(dir, "lightning-rpc")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("net.Listen(%s) error: %w", socketPath, err)
+	}
+
+	f := &socketForwarder{
+		address:    address,
+		socketPath: socketPath,
+		listener:   listener,
+		logger:     logger,
+	}
+	go f.serve()
+	return f, nil
+}
+
+func (f *socketForwarder) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			f.logger.Printf("CLN: socket forwarder stopped: %v", err)
+			return
+		}
+
+		go f.forward(conn)
+	}
+}
+
+func (f *socketForwarder) forward(conn net.Conn) {
+	defer conn.Close()
+	remote, err := net.Dial("tcp", f.address)
+	if err != nil {
+		f.logger.Printf("CLN: failed to connect to %s: %v", f.address, err)
+		return
+	}
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remote, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, remote)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// resolveSocketPath returns the path of the unix socket to connect to. If
+// socketPath is a tcp address, a local socket forwarding to it is created.
+func resolveSocketPath(socketPath string, logger *log.Logger) (string, error) {
+	if !strings.HasPrefix(socketPath, tcpPrefix) {
+		return socketPath, nil
+	}
+
+	address := strings.TrimPrefix(socketPath, tcpPrefix)
+	f, err := newSocketForwarder(address, logger)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Printf("CLN: forwarding %s to %s", f.socketPath, address)
+	return f.socketPath, nil
+}
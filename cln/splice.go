@@ -0,0 +1,232 @@
+package cln
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/lightning"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Weight of the splice transaction without the inputs funded by fundpsbt, as
+// recommended for splice_init.
+const spliceStartWeight = 800
+
+// Maximum number of splice_update rounds before giving up on the splice.
+const maxSpliceUpdates = 10
+
+type fundPsbtRequest struct {
+	Satoshi        string `json:"satoshi"`
+	FeeRate        string `json:"feerate"`
+	StartWeight    int    `json:"startweight"`
+	ExcessAsChange bool   `json:"excess_as_change"`
+}
+
+func (r *fundPsbtRequest) Name() string {
+	return "fundpsbt"
+}
+
+type psbtResult struct {
+	Psbt string `json:"psbt"`
+}
+
+type spliceInitRequest struct {
+	ChannelID      string  `json:"channel_id"`
+	RelativeAmount int64   `json:"relative_amount"`
+	InitialPsbt    string  `json:"initialpsbt"`
+	FeeRatePerKw   *uint64 `json:"feerate_per_kw,omitempty"`
+}
+
+func (r *spliceInitRequest) Name() string {
+	return "splice_init"
+}
+
+type spliceUpdateRequest struct {
+	ChannelID string `json:"channel_id"`
+	Psbt      string `json:"psbt"`
+}
+
+func (r *spliceUpdateRequest) Name() string {
+	return "splice_update"
+}
+
+type spliceUpdateResult struct {
+	Psbt               string `json:"psbt"`
+	CommitmentsSecured bool   `json:"commitments_secured"`
+}
+
+type signPsbtRequest struct {
+	Psbt string `json:"psbt"`
+}
+
+func (r *signPsbtRequest) Name() string {
+	return "signpsbt"
+}
+
+type signPsbtResult struct {
+	SignedPsbt string `json:"signed_psbt"`
+}
+
+type spliceSignedRequest struct {
+	ChannelID string `json:"channel_id"`
+	Psbt      string `json:"psbt"`
+}
+
+func (r *spliceSignedRequest) Name() string {
+	return "splice_signed"
+}
+
+type spliceSignedResult struct {
+	TxID string `json:"txid"`
+}
+
+type unreserveInputsRequest struct {
+	Psbt string `json:"psbt"`
+}
+
+func (r *unreserveInputsRequest) Name() string {
+	return "unreserveinputs"
+}
+
+// SpliceIn splices funds of the node wallet into the channel with the peer,
+// with the fundpsbt, splice_init, splice_update, signpsbt and splice_signed
+// sequence.
+func (c *ClnClient) SpliceIn(peerID []byte, amountSat uint64, feeSatPerVByte *float64) (*wire.OutPoint, error) {
+	pubkey := hex.EncodeToString(peerID)
+	peer, err := c.getPeer(context.Background(), pubkey)
+	if err != nil {
+		c.logger.Printf("CLN: client.GetPeer(%s) error: %v", pubkey, err)
+		return nil, err
+	}
+
+	var channelID, fundingTxID string
+	var fundingOutnum uint32
+	var max uint64
+	for _, ch := range peer.Channels {
+		if ch.State != "CHANNELD_NORMAL" || ch.ToUsMsat == nil {
+			continue
+		}
+
+		if channelID == "" || ch.ToUsMsat.Value > max {
+			channelID = ch.ChannelId
+			fundingTxID = ch.FundingTxId
+			fundingOutnum = ch.FundingOutnum
+			max = ch.ToUsMsat.Value
+		}
+	}
+
+	if channelID == "" {
+		return nil, lightning.ErrNoSpliceableChannel
+	}
+
+	feeRate := "normal"
+	var feeRatePerKw *uint64
+	if feeSatPerVByte != nil {
+		feeRate = fmt.Sprintf("%dperkb", uint64(*feeSatPerVByte*1000))
+		perKw := uint64(*feeSatPerVByte * 250)
+		feeRatePerKw = &perKw
+	}
+
+	var funded psbtResult
+	if c.coinControl != nil {
+		var utxos []string
+		utxos, err = c.selectUtxos(amountSat, feeSatPerVByte)
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.rpc.requestOnce(&utxoPsbtRequest{
+			Satoshi:        fmt.Sprintf("%dsat", amountSat),
+			FeeRate:        feeRate,
+			StartWeight:    spliceStartWeight,
+			Utxos:          utxos,
+			ExcessAsChange: true,
+		}, &funded)
+	} else {
+		err = c.rpc.requestOnce(&fundPsbtRequest{
+			Satoshi:        fmt.Sprintf("%dsat", amountSat),
+			FeeRate:        feeRate,
+			StartWeight:    spliceStartWeight,
+			ExcessAsChange: true,
+		}, &funded)
+	}
+	if err != nil {
+		c.logger.Printf("CLN: funding psbt of %v sat error: %v", amountSat, err)
+		return nil, err
+	}
+
+	txid, err := c.splice(channelID, int64(amountSat), funded.Psbt, feeRatePerKw)
+	if err != nil {
+		c.logger.Printf("CLN: splice(%s, %v) error: %v", channelID, amountSat, err)
+		uerr := c.rpc.requestOnce(&unreserveInputsRequest{Psbt: funded.Psbt}, &struct{}{})
+		if uerr != nil {
+			c.logger.Printf("CLN: unreserveinputs error: %v", uerr)
+		}
+		return nil, err
+	}
+
+	c.logger.Printf("CLN: spliced %v sat into channel %s, splice txid %s", amountSat, channelID, txid)
+	hash, err := chainhash.NewHashFromStr(fundingTxID)
+	if err != nil {
+		c.logger.Printf("CLN: chainhash.NewHashFromStr(%s) error: %v", fundingTxID, err)
+		return nil, err
+	}
+
+	return basetypes.NewOutPoint(hash[:], fundingOutnum)
+}
+
+func (c *ClnClient) splice(channelID string, amountSat int64, initialPsbt string, feeRatePerKw *uint64) (string, error) {
+	var init psbtResult
+	err := c.rpc.requestOnce(&spliceInitRequest{
+		ChannelID:      channelID,
+		RelativeAmount: amountSat,
+		InitialPsbt:    initialPsbt,
+		FeeRatePerKw:   feeRatePerKw,
+	}, &init)
+	if err != nil {
+		return "", fmt.Errorf("splice_init error: %w", err)
+	}
+
+	// splice_update is repeated until both sides have signed the new
+	// commitment transactions.
+	psbt := init.Psbt
+	for j := 0; ; j++ {
+		if j == maxSpliceUpdates {
+			return "", fmt.Errorf("commitments not secured after %d splice_update calls", j)
+		}
+
+		var update spliceUpdateResult
+		err = c.rpc.requestOnce(&spliceUpdateRequest{
+			ChannelID: channelID,
+			Psbt:      psbt,
+		}, &update)
+		if err != nil {
+			return "", fmt.Errorf("splice_update error: %w", err)
+		}
+
+		psbt = update.Psbt
+		if update.CommitmentsSecured {
+			break
+		}
+	}
+
+	var signed signPsbtResult
+	err = c.rpc.requestOnce(&signPsbtRequest{Psbt: psbt}, &signed)
+	if err != nil {
+		return "", fmt.Errorf("signpsbt error: %w", err)
+	}
+
+	var result spliceSignedResult
+	err = c.rpc.requestOnce(&spliceSignedRequest{
+		ChannelID: channelID,
+		Psbt:      signed.SignedPsbt,
+	}, &result)
+	if err != nil {
+		return "", fmt.Errorf("splice_signed error: %w", err)
+	}
+
+	return result.TxID, nil
+}
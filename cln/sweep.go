@@ -0,0 +1,76 @@
+package cln
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/breez/lspd/lightning"
+)
+
+type newAddrRequest struct {
+	AddressType string `json:"addresstype"`
+}
+
+func (r *newAddrRequest) Name() string {
+	return "newaddr"
+}
+
+type newAddrResult struct {
+	Bech32 string `json:"bech32"`
+}
+
+type withdrawRequest struct {
+	Destination string   `json:"destination"`
+	Satoshi     string   `json:"satoshi"`
+	FeeRate     string   `json:"feerate"`
+	MinConf     uint16   `json:"minconf"`
+	Utxos       []string `json:"utxos"`
+}
+
+func (r *withdrawRequest) Name() string {
+	return "withdraw"
+}
+
+type withdrawResult struct {
+	TxID string `json:"txid"`
+}
+
+// ListUtxos returns the unspent outputs of the node wallet, including the
+// outputs reserved by the node.
+func (c *ClnClient) ListUtxos(ctx context.Context) ([]*lightning.Utxo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.listUtxos()
+}
+
+// Sweep spends all of the utxos to a new address of the node wallet with
+// withdraw.
+func (c *ClnClient) Sweep(ctx context.Context, utxos []string, feeSatPerVByte float64) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	var addr newAddrResult
+	err := c.rpc.requestOnce(&newAddrRequest{AddressType: "bech32"}, &addr)
+	if err != nil {
+		c.logger.Printf("CLN: newaddr error: %v", err)
+		return "", fmt.Errorf("CLN: newaddr error: %w", err)
+	}
+
+	var result withdrawResult
+	err = c.rpc.requestOnce(&withdrawRequest{
+		Destination: addr.Bech32,
+		Satoshi:     "all",
+		FeeRate:     fmt.Sprintf("%dperkb", uint64(feeSatPerVByte*1000)),
+		MinConf:     0,
+		Utxos:       utxos,
+	}, &result)
+	if err != nil {
+		c.logger.Printf("CLN: withdraw(%s, %d utxos) error: %v", addr.Bech32, len(utxos), err)
+		return "", fmt.Errorf("CLN: withdraw error: %w", err)
+	}
+
+	return result.TxID, nil
+}
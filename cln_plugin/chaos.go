@@ -0,0 +1,180 @@
+package cln_plugin
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Test-only option to inject faults in the htlc stream, so the reconnection
+// and deduplication logic of lspd can be exercised without a flaky node. A
+// comma separated list of key=value pairs:
+//   - disconnect: chance to drop the subscriber before sending an htlc.
+//   - duplicate: chance to send an htlc to the subscriber twice.
+//   - recvdelay: maximum random delay after receiving a resolution.
+//   - reorder: chance to hold back a resolution, so later ones overtake it.
+//   - reorderdelay: maximum time a resolution is held back. Defaults to 1s.
+//
+// Example: disconnect=0.05,duplicate=0.1,recvdelay=200ms,reorder=0.2
+const ChaosOption = "lsp-chaos"
+
+const defaultReorderDelay = time.Second
+
+type chaos struct {
+	disconnect   float64
+	duplicate    float64
+	reorder      float64
+	recvDelay    time.Duration
+	reorderDelay time.Duration
+	mtx          sync.Mutex
+	rnd          *rand.Rand
+	// The htlcs sent to a subscriber that were not resolved yet. They are
+	// sent again to the next subscriber after a disconnect.
+	inflight map[string]*htlcAcceptedMsg
+	// The ids of the htlcs resolved already. Later resolutions of duplicated
+	// htlcs are dropped, because cln must only get one result per htlc. Never
+	// pruned, chaos mode is for tests only.
+	resolved map[string]struct{}
+}
+
+// parseChaos parses the value of the chaos option. Returns nil if chaos mode
+// is disabled.
+func parseChaos(s string) (*chaos, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	c := &chaos{
+		reorderDelay: defaultReorderDelay,
+		rnd:          rand.New(rand.NewSource(time.Now().UnixNano())),
+		inflight:     make(map[string]*htlcAcceptedMsg),
+		resolved:     make(map[string]struct{}),
+	}
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid chaos setting '%s'", pair)
+		}
+
+		var err error
+		switch key {
+		case "disconnect":
+			c.disconnect, err = parseChance(value)
+		case "duplicate":
+			c.duplicate, err = parseChance(value)
+		case "reorder":
+			c.reorder, err = parseChance(value)
+		case "recvdelay":
+			c.recvDelay, err = time.ParseDuration(value)
+		case "reorderdelay":
+			c.reorderDelay, err = time.ParseDuration(value)
+		default:
+			err = fmt.Errorf("unknown chaos setting")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid chaos setting '%s': %w", pair, err)
+		}
+	}
+
+	return c, nil
+}
+
+func parseChance(s string) (float64, error) {
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if p < 0 || p > 1 {
+		return 0, fmt.Errorf("chance must be between 0 and 1")
+	}
+	return p, nil
+}
+
+func (c *chaos) chance(p float64) bool {
+	if c == nil || p == 0 {
+		return false
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.rnd.Float64() < p
+}
+
+func (c *chaos) delay(max time.Duration) time.Duration {
+	if c == nil || max <= 0 {
+		return 0
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return time.Duration(c.rnd.Int63n(int64(max)))
+}
+
+func (c *chaos) shouldDisconnect() bool {
+	return c != nil && c.chance(c.disconnect)
+}
+
+func (c *chaos) shouldDuplicate() bool {
+	return c != nil && c.chance(c.duplicate)
+}
+
+// recvDelayDuration returns how long to wait after receiving a resolution.
+func (c *chaos) recvDelayDuration() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.delay(c.recvDelay)
+}
+
+// reorderDelayDuration returns how long to hold back a resolution, or zero to
+// pass it on immediately.
+func (c *chaos) reorderDelayDuration() time.Duration {
+	if c == nil || !c.chance(c.reorder) {
+		return 0
+	}
+	return c.delay(c.reorderDelay)
+}
+
+// sent records the htlc as sent to the subscriber.
+func (c *chaos) sent(msg *htlcAcceptedMsg) {
+	if c == nil {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.inflight[msg.id] = msg
+}
+
+// resolve records the htlc as resolved. Returns false if it was resolved
+// already, in which case the resolution must be dropped.
+func (c *chaos) resolve(id string) bool {
+	if c == nil {
+		return true
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if _, ok := c.resolved[id]; ok {
+		return false
+	}
+
+	c.resolved[id] = struct{}{}
+	delete(c.inflight, id)
+	return true
+}
+
+// unresolved returns the htlcs sent to a subscriber that were not resolved
+// yet.
+func (c *chaos) unresolved() []*htlcAcceptedMsg {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	var msgs []*htlcAcceptedMsg
+	for _, msg := range c.inflight {
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
@@ -0,0 +1,44 @@
+package cln_plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChaos(t *testing.T) {
+	c, err := parseChaos("")
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+
+	c, err = parseChaos("disconnect=0.05, duplicate=0.1,recvdelay=200ms,reorder=1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.05, c.disconnect)
+	assert.Equal(t, 0.1, c.duplicate)
+	assert.Equal(t, 1.0, c.reorder)
+	assert.Equal(t, 200*time.Millisecond, c.recvDelay)
+	assert.Equal(t, defaultReorderDelay, c.reorderDelay)
+
+	for _, s := range []string{"disconnect", "disconnect=2", "recvdelay=soon", "explode=0.5"} {
+		_, err = parseChaos(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestChaosResolve(t *testing.T) {
+	c, _ := parseChaos("duplicate=1")
+	c.sent(&htlcAcceptedMsg{id: "1"})
+	c.sent(&htlcAcceptedMsg{id: "2"})
+
+	assert.True(t, c.resolve("1"))
+	assert.False(t, c.resolve("1"))
+	assert.Len(t, c.unresolved(), 1)
+	assert.Equal(t, "2", c.unresolved()[0].id)
+
+	// Without chaos mode every resolution is passed on.
+	var disabled *chaos
+	assert.True(t, disabled.resolve("1"))
+	assert.True(t, disabled.resolve("1"))
+	assert.False(t, disabled.shouldDuplicate())
+}
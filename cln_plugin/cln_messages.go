@@ -2,6 +2,8 @@ package cln_plugin
 
 import (
 	"encoding/json"
+	"net"
+	"strconv"
 )
 
 type Request struct {
@@ -111,6 +113,68 @@ type Htlc struct {
 	PaymentHash        string `json:"payment_hash"`
 }
 
+// PeerNotification is the payload of the connect and disconnect
+// notifications. Older cln versions put the peer at the top level, newer
+// versions wrap it in an object named after the notification.
+type PeerNotification struct {
+	PeerInfo
+	Connect    *PeerInfo `json:"connect"`
+	Disconnect *PeerInfo `json:"disconnect"`
+}
+
+// ChannelStateChangedNotification is the payload of the channel_state_changed
+// notification.
+type ChannelStateChangedNotification struct {
+	ChannelStateChanged struct {
+		PeerId         string `json:"peer_id"`
+		ChannelId      string `json:"channel_id"`
+		ShortChannelId string `json:"short_channel_id"`
+		OldState       string `json:"old_state"`
+		NewState       string `json:"new_state"`
+	} `json:"channel_state_changed"`
+}
+
+type CustomMsg struct {
+	PeerId  string `json:"peer_id"`
+	Payload string `json:"payload"`
+}
+
+type PeerInfo struct {
+	Id        string       `json:"id"`
+	Direction string       `json:"direction"`
+	Address   *PeerAddress `json:"address"`
+}
+
+type PeerAddress struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+}
+
+// Peer returns the peer in the notification.
+func (n *PeerNotification) Peer() *PeerInfo {
+	if n.Connect != nil {
+		return n.Connect
+	}
+
+	if n.Disconnect != nil {
+		return n.Disconnect
+	}
+
+	return &n.PeerInfo
+}
+
+// ListenAddress returns the address the peer accepts connections on as
+// host:port. It is only known for outgoing connections, so it is empty for
+// incoming connections.
+func (p *PeerInfo) ListenAddress() string {
+	if p.Direction != "out" || p.Address == nil || p.Address.Address == "" || p.Address.Port == 0 {
+		return ""
+	}
+
+	return net.JoinHostPort(p.Address.Address, strconv.Itoa(p.Address.Port))
+}
+
 type LogNotification struct {
 	Level   string `json:"level"`
 	Message string `json:"message"`
@@ -212,6 +212,14 @@ func (c *ClnPlugin) processRequest(request *Request) {
 		c.handleShutdown(request)
 	case "htlc_accepted":
 		c.handleHtlcAccepted(request)
+	case "connect":
+		c.handlePeerNotification(request, true)
+	case "disconnect":
+		c.handlePeerNotification(request, false)
+	case "channel_state_changed":
+		c.handleChannelStateChanged(request)
+	case "custommsg":
+		c.handleCustomMsg(request)
 	case "openchannel":
 		// handle open channel in a goroutine, because order doesn't  matter.
 		go c.handleOpenChannel(request)
@@ -255,11 +263,33 @@ func (c *ClnPlugin) handleGetManifest(request *Request) {
 						"if no subscriber is active. golang duration string.",
 					Default: &DefaultSubscriberTimeout,
 				},
+				{
+					Name: HeartbeatTimeoutOption,
+					Type: "string",
+					Description: "resolve htlcs lspd didn't send a heartbeat " +
+						"or resolution for within this duration. golang " +
+						"duration string, 0s disables heartbeats.",
+					Default: &DefaultHeartbeatTimeout,
+				},
+				{
+					Name: HeartbeatActionOption,
+					Type: "string",
+					Description: "how to resolve htlcs of which the " +
+						"heartbeat timed out: continue or fail.",
+					Default: &DefaultHeartbeatAction,
+				},
 				{
 					Name:        channelAcceptScript,
 					Type:        "string",
 					Description: "starlark script for channel acceptor.",
 				},
+				{
+					Name: ChaosOption,
+					Type: "string",
+					Description: "test only: faults to inject in the htlc " +
+						"stream, e.g. disconnect=0.05,duplicate=0.1," +
+						"recvdelay=200ms,reorder=0.2.",
+				},
 			},
 			RpcMethods: []*RpcMethod{
 				{
@@ -276,10 +306,14 @@ func (c *ClnPlugin) handleGetManifest(request *Request) {
 				{Name: "htlc_accepted"},
 				{Name: "openchannel"},
 				{Name: "openchannel2"},
+				{Name: "custommsg"},
 			},
 			NonNumericIds: true,
 			Subscriptions: []string{
 				"shutdown",
+				"connect",
+				"disconnect",
+				"channel_state_changed",
 			},
 		},
 	})
@@ -389,8 +423,55 @@ func (c *ClnPlugin) handleInit(request *Request) {
 		return
 	}
 
+	// Get the heartbeat options. Heartbeats are disabled if the options are
+	// not set.
+	hbTimeout, _ := initMsg.Options[HeartbeatTimeoutOption].(string)
+	if hbTimeout == "" {
+		hbTimeout = DefaultHeartbeatTimeout
+	}
+	hbAction, _ := initMsg.Options[HeartbeatActionOption].(string)
+	if hbAction == "" {
+		hbAction = DefaultHeartbeatAction
+	}
+	hb, err := parseHeartbeat(hbTimeout, hbAction)
+	if err != nil {
+		c.sendError(
+			request.Id,
+			InvalidParams,
+			fmt.Sprintf("Invalid heartbeat options: %v", err),
+		)
+		return
+	}
+	if hb != nil {
+		log.Printf("Resolving htlcs with '%s' if lspd doesn't send a heartbeat within %v.", hb.action, hb.timeout)
+	}
+
+	// Get the chaos option. Chaos mode is disabled if the option is not set.
+	var faults *chaos
+	if ch, ok := initMsg.Options[ChaosOption]; ok {
+		cs, ok := ch.(string)
+		if ok {
+			faults, err = parseChaos(cs)
+		}
+		if !ok || err != nil {
+			c.sendError(
+				request.Id,
+				InvalidParams,
+				fmt.Sprintf(
+					"Invalid value '%v' for option '%s'",
+					ch,
+					ChaosOption,
+				),
+			)
+			return
+		}
+		if faults != nil {
+			log.Printf("WARNING: chaos mode enabled, injecting faults in the htlc stream: %s", cs)
+		}
+	}
+
 	// Start the grpc server.
-	c.server = NewServer(addr, subscriberTimeout)
+	c.server = NewServer(addr, subscriberTimeout, hb, faults)
 	go c.server.Start()
 	err = c.server.WaitStarted()
 	if err != nil {
@@ -437,6 +518,69 @@ func (c *ClnPlugin) handleHtlcAccepted(request *Request) {
 	c.server.Send(idToString(request.Id), &htlc)
 }
 
+// Forwards a connect or disconnect notification to the peer event
+// subscribers of the grpc server. Notifications have no id, so errors are only
+// logged.
+func (c *ClnPlugin) handlePeerNotification(request *Request, connected bool) {
+	var n PeerNotification
+	err := json.Unmarshal(request.Params, &n)
+	if err != nil {
+		log.Printf("Failed to unmarshal %s notification: %v [%s]", request.Method, err, request.Params)
+		return
+	}
+
+	peer := n.Peer()
+	if peer.Id == "" {
+		log.Printf("Got %s notification without peer id: %s", request.Method, request.Params)
+		return
+	}
+
+	if c.server == nil {
+		return
+	}
+
+	c.server.SendPeerEvent(peer.Id, connected, peer.ListenAddress())
+}
+
+// Forwards a channel_state_changed notification to the channel event
+// subscribers of the grpc server.
+func (c *ClnPlugin) handleChannelStateChanged(request *Request) {
+	var n ChannelStateChangedNotification
+	err := json.Unmarshal(request.Params, &n)
+	if err != nil {
+		log.Printf("Failed to unmarshal %s notification: %v [%s]", request.Method, err, request.Params)
+		return
+	}
+
+	if c.server == nil {
+		return
+	}
+
+	ch := n.ChannelStateChanged
+	c.server.SendChannelEvent(ch.PeerId, ch.ChannelId, ch.ShortChannelId, ch.NewState)
+}
+
+// Forwards a message of the custommsg hook to the custom message subscribers
+// of the grpc server. The hook always continues, so other plugins see the
+// message as well.
+func (c *ClnPlugin) handleCustomMsg(request *Request) {
+	var msg CustomMsg
+	err := json.Unmarshal(request.Params, &msg)
+	if err != nil {
+		log.Printf("Failed to unmarshal custommsg params: %v [%s]", err, request.Params)
+	} else if c.server != nil {
+		c.server.SendCustomMessage(msg.PeerId, msg.Payload)
+	}
+
+	c.sendToCln(&Response{
+		JsonRpc: SpecVersion,
+		Id:      request.Id,
+		Result: map[string]interface{}{
+			"result": "continue",
+		},
+	})
+}
+
 func (c *ClnPlugin) handleSetChannelAcceptScript(request *Request) {
 	var params []string
 	err := json.Unmarshal(request.Params, &params)
@@ -0,0 +1,160 @@
+package cln_plugin
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Options to have the plugin resolve htlcs itself when lspd stops working on
+// them. With a heartbeat timeout set, lspd has to send a heartbeat or a
+// resolution for every htlc it received within the timeout, otherwise the
+// plugin resolves the htlc with the heartbeat action, continue or fail. This
+// way a crashed or hanging lspd can't leave lightningd holding htlcs until
+// they expire on chain. Upgrade lspd before setting a heartbeat timeout, older
+// versions of lspd don't send heartbeats.
+const (
+	HeartbeatTimeoutOption = "lsp-heartbeat-timeout"
+	HeartbeatActionOption  = "lsp-heartbeat-action"
+)
+
+var (
+	DefaultHeartbeatTimeout = "0s"
+	DefaultHeartbeatAction  = "fail"
+)
+
+type heartbeat struct {
+	timeout time.Duration
+	action  string
+}
+
+// parseHeartbeat parses the heartbeat options. Returns nil if heartbeats are
+// disabled.
+func parseHeartbeat(timeout string, action string) (*heartbeat, error) {
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid heartbeat timeout '%s': %w", timeout, err)
+	}
+	if d == 0 {
+		return nil, nil
+	}
+	if d < time.Second {
+		return nil, fmt.Errorf("heartbeat timeout must be at least 1s")
+	}
+
+	action = strings.ToLower(strings.TrimSpace(action))
+	if action != "continue" && action != "fail" {
+		return nil, fmt.Errorf("heartbeat action must be continue or fail, got '%s'", action)
+	}
+
+	return &heartbeat{timeout: d, action: action}, nil
+}
+
+// The heartbeat timer of an htlc sent to the subscriber.
+type watchdog struct {
+	timer   *time.Timer
+	expired bool
+}
+
+// watch starts the heartbeat timer of an htlc sent to the subscriber, or
+// restarts it if the htlc was sent before.
+func (s *server) watch(id string) {
+	if s.heartbeat == nil {
+		return
+	}
+
+	timeout := s.heartbeat.timeout
+	s.heartbeatMtx.Lock()
+	defer s.heartbeatMtx.Unlock()
+	if w, ok := s.watchdogs[id]; ok {
+		if !w.expired {
+			w.timer.Reset(timeout)
+		}
+		return
+	}
+
+	w := &watchdog{}
+	w.timer = time.AfterFunc(timeout, func() {
+		s.heartbeatMtx.Lock()
+		if w.expired || s.watchdogs[id] != w {
+			s.heartbeatMtx.Unlock()
+			return
+		}
+		w.expired = true
+		s.heartbeatMtx.Unlock()
+
+		// lspd may still resolve the htlc after the timeout. Keep the
+		// expired watchdog around for a while to drop that resolution.
+		time.AfterFunc(timeout, func() {
+			s.heartbeatMtx.Lock()
+			if s.watchdogs[id] == w {
+				delete(s.watchdogs, id)
+			}
+			s.heartbeatMtx.Unlock()
+		})
+
+		// If the htlc is held, its hold may have timed out at the same
+		// time. Then the htlc is resolved already.
+		if !s.release(id, "heartbeat_timeout") || !s.chaos.resolve(id) {
+			return
+		}
+
+		log.Printf(
+			"WARNING: no heartbeat or resolution of htlc with id '%s' "+
+				"within %v. Resolving htlc with '%s'.",
+			id,
+			timeout,
+			s.heartbeat.action,
+		)
+		s.recvQueue <- &htlcResultMsg{
+			id:     id,
+			result: s.heartbeatResult(),
+		}
+	})
+	s.watchdogs[id] = w
+}
+
+// beat restarts the heartbeat timer of the htlc.
+func (s *server) beat(id string) {
+	if s.heartbeat == nil {
+		return
+	}
+
+	s.heartbeatMtx.Lock()
+	defer s.heartbeatMtx.Unlock()
+	if w, ok := s.watchdogs[id]; ok && !w.expired {
+		w.timer.Reset(s.heartbeat.timeout)
+	}
+}
+
+// unwatch stops the heartbeat timer of the htlc. Returns false if the timer
+// had expired, so the htlc was resolved already.
+func (s *server) unwatch(id string) bool {
+	if s.heartbeat == nil {
+		return true
+	}
+
+	s.heartbeatMtx.Lock()
+	defer s.heartbeatMtx.Unlock()
+	w, ok := s.watchdogs[id]
+	if !ok {
+		return true
+	}
+
+	delete(s.watchdogs, id)
+	w.timer.Stop()
+	return !w.expired
+}
+
+// Returns the result for htlcs of which the heartbeat timed out.
+func (s *server) heartbeatResult() interface{} {
+	if s.heartbeat.action == "continue" {
+		return s.defaultResult()
+	}
+
+	return map[string]interface{}{
+		"result":          "fail",
+		"failure_message": "1007", // temporary channel failure
+	}
+}
@@ -0,0 +1,51 @@
+package cln_plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHeartbeat(t *testing.T) {
+	hb, err := parseHeartbeat("0s", "fail")
+	assert.NoError(t, err)
+	assert.Nil(t, hb)
+
+	hb, err = parseHeartbeat("30s", " Continue")
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, hb.timeout)
+	assert.Equal(t, "continue", hb.action)
+
+	for _, s := range [][2]string{{"soon", "fail"}, {"100ms", "fail"}, {"30s", "settle"}} {
+		_, err = parseHeartbeat(s[0], s[1])
+		assert.Error(t, err, s)
+	}
+}
+
+func TestHeartbeatTimeout(t *testing.T) {
+	s := NewServer("", time.Minute, &heartbeat{timeout: 100 * time.Millisecond, action: "fail"}, nil)
+
+	// A resolution in time stops the timer.
+	s.watch("1")
+	assert.True(t, s.unwatch("1"))
+
+	// Heartbeats keep the htlc pending.
+	s.watch("2")
+	for n := 0; n < 4; n++ {
+		time.Sleep(50 * time.Millisecond)
+		s.beat("2")
+	}
+	assert.Len(t, s.recvQueue, 0)
+
+	select {
+	case msg := <-s.recvQueue:
+		assert.Equal(t, "2", msg.id)
+		assert.Equal(t, "fail", msg.result.(map[string]interface{})["result"])
+	case <-time.After(time.Second):
+		t.Fatal("htlc without heartbeat was not resolved")
+	}
+
+	// A resolution after the timeout is dropped.
+	assert.False(t, s.unwatch("2"))
+}
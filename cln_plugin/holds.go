@@ -0,0 +1,166 @@
+package cln_plugin
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/breez/lspd/cln_plugin/proto"
+)
+
+// Size of the buffer of hold events per subscriber. A subscriber that falls
+// this far behind is dropped.
+const holdEventBufferSize = 1000
+
+// An htlc lspd holds while it opens a channel for it.
+type heldHtlc struct {
+	start   time.Time
+	timer   *time.Timer
+	expired bool
+}
+
+// hold starts the hold timer of the htlc. If lspd doesn't resolve the htlc
+// before the timer expires, the htlc is failed, so cln doesn't hold it until
+// it expires on chain.
+func (s *server) hold(id string, hold *proto.HtlcHold) {
+	s.holdMtx.Lock()
+	defer s.holdMtx.Unlock()
+	if _, ok := s.holds[id]; ok {
+		return
+	}
+
+	timeout := time.Duration(hold.TimeoutSeconds) * time.Second
+	log.Printf("Holding htlc with id '%s' for up to %v while a channel is opened.", id, timeout)
+	h := &heldHtlc{start: time.Now()}
+	h.timer = time.AfterFunc(timeout, func() {
+		s.holdMtx.Lock()
+		if h.expired || s.holds[id] != h {
+			s.holdMtx.Unlock()
+			return
+		}
+		h.expired = true
+		s.holdMtx.Unlock()
+		s.unwatch(id)
+
+		log.Printf("WARNING: hold of htlc with id '%s' timed out after %v. Failing htlc.", id, timeout)
+		s.sendHoldEvent(id, time.Since(h.start), "timeout")
+		s.chaos.resolve(id)
+		s.recvQueue <- &htlcResultMsg{
+			id:     id,
+			result: s.holdTimeoutResult(),
+		}
+
+		// lspd may still resolve the htlc after the timeout. Keep the
+		// expired hold around for a while to drop that resolution.
+		time.AfterFunc(timeout, func() {
+			s.holdMtx.Lock()
+			if s.holds[id] == h {
+				delete(s.holds, id)
+			}
+			s.holdMtx.Unlock()
+		})
+	})
+	s.holds[id] = h
+}
+
+// release ends the hold of the htlc, if it was held. Returns false if the
+// hold had timed out, so the htlc was resolved already.
+func (s *server) release(id string, outcome string) bool {
+	s.holdMtx.Lock()
+	h, ok := s.holds[id]
+	if ok {
+		delete(s.holds, id)
+		h.timer.Stop()
+	}
+	s.holdMtx.Unlock()
+
+	if !ok {
+		return true
+	}
+	if h.expired {
+		log.Printf("Dropping resolution of htlc with id '%s', its hold timed out.", id)
+		return false
+	}
+
+	s.sendHoldEvent(id, time.Since(h.start), outcome)
+	return true
+}
+
+// Grpc method that is called when a client subscribes to hold events. Events
+// that happen while a client is not subscribed are not replayed.
+func (s *server) HoldEventStream(
+	req *proto.HoldEventRequest,
+	stream proto.ClnPlugin_HoldEventStreamServer,
+) error {
+	events := make(chan *proto.HoldEvent, holdEventBufferSize)
+	s.holdMtx.Lock()
+	s.holdSubscribers[events] = struct{}{}
+	s.holdMtx.Unlock()
+	log.Printf("Got a new hold event stream subscription request.")
+
+	defer func() {
+		s.holdMtx.Lock()
+		delete(s.holdSubscribers, events)
+		s.holdMtx.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			log.Printf("HoldEventStream context is done. Return: %v", stream.Context().Err())
+			return stream.Context().Err()
+		case event, ok := <-events:
+			if !ok {
+				log.Printf("Hold event subscriber is too slow. Dropping subscriber.")
+				return fmt.Errorf("subscriber too slow")
+			}
+
+			err := stream.Send(event)
+			if err != nil {
+				log.Printf("Error sending hold event to subscriber: %v", err)
+				return err
+			}
+		}
+	}
+}
+
+// Sends a hold event to all hold event subscribers. Never blocks, subscribers
+// with a full buffer are dropped.
+func (s *server) sendHoldEvent(id string, duration time.Duration, outcome string) {
+	s.holdMtx.Lock()
+	defer s.holdMtx.Unlock()
+	for events := range s.holdSubscribers {
+		select {
+		case events <- &proto.HoldEvent{
+			Correlationid:  id,
+			HoldDurationMs: uint64(duration.Milliseconds()),
+			Outcome:        outcome,
+		}:
+		default:
+			close(events)
+			delete(s.holdSubscribers, events)
+		}
+	}
+}
+
+// Returns the result: fail message for htlcs of which the hold timed out.
+func (s *server) holdTimeoutResult() interface{} {
+	return map[string]interface{}{
+		"result":          "fail",
+		"failure_message": "1007", // temporary channel failure
+	}
+}
+
+// Returns the outcome of a resolution as reported in hold events.
+func outcomeName(outcome interface{}) string {
+	switch outcome.(type) {
+	case *proto.HtlcResolution_Continue:
+		return "continue"
+	case *proto.HtlcResolution_Fail:
+		return "fail"
+	case *proto.HtlcResolution_Resolve:
+		return "resolve"
+	default:
+		return "unknown"
+	}
+}
@@ -275,6 +275,8 @@ type HtlcResolution struct {
 	//	*HtlcResolution_Fail
 	//	*HtlcResolution_Continue
 	//	*HtlcResolution_Resolve
+	//	*HtlcResolution_Hold
+	//	*HtlcResolution_Heartbeat
 	Outcome isHtlcResolution_Outcome `protobuf_oneof:"outcome"`
 }
 
@@ -345,54 +347,672 @@ func (x *HtlcResolution) GetResolve() *HtlcResolve {
 	return nil
 }
 
+func (x *HtlcResolution) GetHold() *HtlcHold {
+	if x, ok := x.GetOutcome().(*HtlcResolution_Hold); ok {
+		return x.Hold
+	}
+	return nil
+}
+
+func (x *HtlcResolution) GetHeartbeat() *HtlcHeartbeat {
+	if x, ok := x.GetOutcome().(*HtlcResolution_Heartbeat); ok {
+		return x.Heartbeat
+	}
+	return nil
+}
+
 type isHtlcResolution_Outcome interface {
 	isHtlcResolution_Outcome()
 }
 
-type HtlcResolution_Fail struct {
-	Fail *HtlcFail `protobuf:"bytes,2,opt,name=fail,proto3,oneof"`
+type HtlcResolution_Fail struct {
+	Fail *HtlcFail `protobuf:"bytes,2,opt,name=fail,proto3,oneof"`
+}
+
+type HtlcResolution_Continue struct {
+	Continue *HtlcContinue `protobuf:"bytes,3,opt,name=continue,proto3,oneof"`
+}
+
+type HtlcResolution_Resolve struct {
+	Resolve *HtlcResolve `protobuf:"bytes,4,opt,name=resolve,proto3,oneof"`
+}
+
+type HtlcResolution_Hold struct {
+	Hold *HtlcHold `protobuf:"bytes,5,opt,name=hold,proto3,oneof"`
+}
+
+type HtlcResolution_Heartbeat struct {
+	Heartbeat *HtlcHeartbeat `protobuf:"bytes,6,opt,name=heartbeat,proto3,oneof"`
+}
+
+func (*HtlcResolution_Fail) isHtlcResolution_Outcome() {}
+
+func (*HtlcResolution_Continue) isHtlcResolution_Outcome() {}
+
+func (*HtlcResolution_Resolve) isHtlcResolution_Outcome() {}
+
+func (*HtlcResolution_Hold) isHtlcResolution_Outcome() {}
+
+func (*HtlcResolution_Heartbeat) isHtlcResolution_Outcome() {}
+
+// Tells the plugin the htlc is held on purpose while a channel is opened for
+// it. The htlc is resolved with another resolution later.
+type HtlcHold struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The plugin fails the htlc itself if it isn't resolved within the
+	// timeout.
+	TimeoutSeconds uint32 `protobuf:"varint,1,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+}
+
+func (x *HtlcHold) Reset() {
+	*x = HtlcHold{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cln_plugin_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HtlcHold) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HtlcHold) ProtoMessage() {}
+
+func (x *HtlcHold) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HtlcHold.ProtoReflect.Descriptor instead.
+func (*HtlcHold) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *HtlcHold) GetTimeoutSeconds() uint32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+// Tells the plugin lspd is still working on the htlc. If the plugin requires
+// heartbeats, it resolves htlcs without a heartbeat or resolution within the
+// heartbeat timeout itself.
+type HtlcHeartbeat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *HtlcHeartbeat) Reset() {
+	*x = HtlcHeartbeat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cln_plugin_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HtlcHeartbeat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HtlcHeartbeat) ProtoMessage() {}
+
+func (x *HtlcHeartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HtlcHeartbeat.ProtoReflect.Descriptor instead.
+func (*HtlcHeartbeat) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{5}
+}
+
+type HtlcContinue struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Payload   *string `protobuf:"bytes,1,opt,name=payload,proto3,oneof" json:"payload,omitempty"`
+	ForwardTo *string `protobuf:"bytes,2,opt,name=forward_to,json=forwardTo,proto3,oneof" json:"forward_to,omitempty"`
+}
+
+func (x *HtlcContinue) Reset() {
+	*x = HtlcContinue{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cln_plugin_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HtlcContinue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HtlcContinue) ProtoMessage() {}
+
+func (x *HtlcContinue) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HtlcContinue.ProtoReflect.Descriptor instead.
+func (*HtlcContinue) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *HtlcContinue) GetPayload() string {
+	if x != nil && x.Payload != nil {
+		return *x.Payload
+	}
+	return ""
+}
+
+func (x *HtlcContinue) GetForwardTo() string {
+	if x != nil && x.ForwardTo != nil {
+		return *x.ForwardTo
+	}
+	return ""
+}
+
+type HtlcFail struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Failure:
+	//	*HtlcFail_FailureMessage
+	//	*HtlcFail_FailureOnion
+	Failure isHtlcFail_Failure `protobuf_oneof:"failure"`
+}
+
+func (x *HtlcFail) Reset() {
+	*x = HtlcFail{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cln_plugin_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HtlcFail) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HtlcFail) ProtoMessage() {}
+
+func (x *HtlcFail) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HtlcFail.ProtoReflect.Descriptor instead.
+func (*HtlcFail) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{7}
+}
+
+func (m *HtlcFail) GetFailure() isHtlcFail_Failure {
+	if m != nil {
+		return m.Failure
+	}
+	return nil
+}
+
+func (x *HtlcFail) GetFailureMessage() string {
+	if x, ok := x.GetFailure().(*HtlcFail_FailureMessage); ok {
+		return x.FailureMessage
+	}
+	return ""
+}
+
+func (x *HtlcFail) GetFailureOnion() string {
+	if x, ok := x.GetFailure().(*HtlcFail_FailureOnion); ok {
+		return x.FailureOnion
+	}
+	return ""
+}
+
+type isHtlcFail_Failure interface {
+	isHtlcFail_Failure()
+}
+
+type HtlcFail_FailureMessage struct {
+	FailureMessage string `protobuf:"bytes,1,opt,name=failure_message,json=failureMessage,proto3,oneof"`
+}
+
+type HtlcFail_FailureOnion struct {
+	FailureOnion string `protobuf:"bytes,2,opt,name=failure_onion,json=failureOnion,proto3,oneof"`
+}
+
+func (*HtlcFail_FailureMessage) isHtlcFail_Failure() {}
+
+func (*HtlcFail_FailureOnion) isHtlcFail_Failure() {}
+
+type HtlcResolve struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PaymentKey string `protobuf:"bytes,1,opt,name=payment_key,json=paymentKey,proto3" json:"payment_key,omitempty"`
+}
+
+func (x *HtlcResolve) Reset() {
+	*x = HtlcResolve{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cln_plugin_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HtlcResolve) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HtlcResolve) ProtoMessage() {}
+
+func (x *HtlcResolve) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HtlcResolve.ProtoReflect.Descriptor instead.
+func (*HtlcResolve) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *HtlcResolve) GetPaymentKey() string {
+	if x != nil {
+		return x.PaymentKey
+	}
+	return ""
+}
+
+type PeerEventRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *PeerEventRequest) Reset() {
+	*x = PeerEventRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cln_plugin_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerEventRequest) ProtoMessage() {}
+
+func (x *PeerEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerEventRequest.ProtoReflect.Descriptor instead.
+func (*PeerEventRequest) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{9}
+}
+
+type PeerEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PeerId    string `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	Connected bool   `protobuf:"varint,2,opt,name=connected,proto3" json:"connected,omitempty"`
+	// Address the peer accepts connections on, as host:port. Only set for
+	// outgoing connections, the address of incoming connections is not the
+	// address the peer listens on.
+	Address string `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+}
+
+func (x *PeerEvent) Reset() {
+	*x = PeerEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cln_plugin_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerEvent) ProtoMessage() {}
+
+func (x *PeerEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerEvent.ProtoReflect.Descriptor instead.
+func (*PeerEvent) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PeerEvent) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *PeerEvent) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+func (x *PeerEvent) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type HoldEventRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *HoldEventRequest) Reset() {
+	*x = HoldEventRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cln_plugin_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HoldEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HoldEventRequest) ProtoMessage() {}
+
+func (x *HoldEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HoldEventRequest.ProtoReflect.Descriptor instead.
+func (*HoldEventRequest) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{11}
+}
+
+// Sent when a held htlc is resolved, or the hold timed out.
+type HoldEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Correlationid string `protobuf:"bytes,1,opt,name=correlationid,proto3" json:"correlationid,omitempty"`
+	// Time between the hold and the resolution of the htlc.
+	HoldDurationMs uint64 `protobuf:"varint,2,opt,name=hold_duration_ms,json=holdDurationMs,proto3" json:"hold_duration_ms,omitempty"`
+	// The result sent to cln: 'continue', 'fail' or 'resolve', or 'timeout'
+	// if the plugin failed the htlc after the hold timed out.
+	Outcome string `protobuf:"bytes,3,opt,name=outcome,proto3" json:"outcome,omitempty"`
+}
+
+func (x *HoldEvent) Reset() {
+	*x = HoldEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cln_plugin_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HoldEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HoldEvent) ProtoMessage() {}
+
+func (x *HoldEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HoldEvent.ProtoReflect.Descriptor instead.
+func (*HoldEvent) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *HoldEvent) GetCorrelationid() string {
+	if x != nil {
+		return x.Correlationid
+	}
+	return ""
+}
+
+func (x *HoldEvent) GetHoldDurationMs() uint64 {
+	if x != nil {
+		return x.HoldDurationMs
+	}
+	return 0
+}
+
+func (x *HoldEvent) GetOutcome() string {
+	if x != nil {
+		return x.Outcome
+	}
+	return ""
+}
+
+type ChannelEventRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ChannelEventRequest) Reset() {
+	*x = ChannelEventRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cln_plugin_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ChannelEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChannelEventRequest) ProtoMessage() {}
+
+func (x *ChannelEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChannelEventRequest.ProtoReflect.Descriptor instead.
+func (*ChannelEventRequest) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{13}
+}
+
+// Sent when the state of a channel changes, like when it is opened, locked in
+// or closed.
+type ChannelEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PeerId    string `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	ChannelId string `protobuf:"bytes,2,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
+	// Empty while the channel is unconfirmed.
+	ShortChannelId string `protobuf:"bytes,3,opt,name=short_channel_id,json=shortChannelId,proto3" json:"short_channel_id,omitempty"`
+	NewState       string `protobuf:"bytes,4,opt,name=new_state,json=newState,proto3" json:"new_state,omitempty"`
+}
+
+func (x *ChannelEvent) Reset() {
+	*x = ChannelEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cln_plugin_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ChannelEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChannelEvent) ProtoMessage() {}
+
+func (x *ChannelEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChannelEvent.ProtoReflect.Descriptor instead.
+func (*ChannelEvent) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ChannelEvent) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *ChannelEvent) GetChannelId() string {
+	if x != nil {
+		return x.ChannelId
+	}
+	return ""
 }
 
-type HtlcResolution_Continue struct {
-	Continue *HtlcContinue `protobuf:"bytes,3,opt,name=continue,proto3,oneof"`
+func (x *ChannelEvent) GetShortChannelId() string {
+	if x != nil {
+		return x.ShortChannelId
+	}
+	return ""
 }
 
-type HtlcResolution_Resolve struct {
-	Resolve *HtlcResolve `protobuf:"bytes,4,opt,name=resolve,proto3,oneof"`
+func (x *ChannelEvent) GetNewState() string {
+	if x != nil {
+		return x.NewState
+	}
+	return ""
 }
 
-func (*HtlcResolution_Fail) isHtlcResolution_Outcome() {}
-
-func (*HtlcResolution_Continue) isHtlcResolution_Outcome() {}
-
-func (*HtlcResolution_Resolve) isHtlcResolution_Outcome() {}
-
-type HtlcContinue struct {
+type CustomMessageRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
-
-	Payload   *string `protobuf:"bytes,1,opt,name=payload,proto3,oneof" json:"payload,omitempty"`
-	ForwardTo *string `protobuf:"bytes,2,opt,name=forward_to,json=forwardTo,proto3,oneof" json:"forward_to,omitempty"`
 }
 
-func (x *HtlcContinue) Reset() {
-	*x = HtlcContinue{}
+func (x *CustomMessageRequest) Reset() {
+	*x = CustomMessageRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_cln_plugin_proto_msgTypes[4]
+		mi := &file_cln_plugin_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *HtlcContinue) String() string {
+func (x *CustomMessageRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HtlcContinue) ProtoMessage() {}
+func (*CustomMessageRequest) ProtoMessage() {}
 
-func (x *HtlcContinue) ProtoReflect() protoreflect.Message {
-	mi := &file_cln_plugin_proto_msgTypes[4]
+func (x *CustomMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -403,53 +1023,40 @@ func (x *HtlcContinue) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HtlcContinue.ProtoReflect.Descriptor instead.
-func (*HtlcContinue) Descriptor() ([]byte, []int) {
-	return file_cln_plugin_proto_rawDescGZIP(), []int{4}
-}
-
-func (x *HtlcContinue) GetPayload() string {
-	if x != nil && x.Payload != nil {
-		return *x.Payload
-	}
-	return ""
-}
-
-func (x *HtlcContinue) GetForwardTo() string {
-	if x != nil && x.ForwardTo != nil {
-		return *x.ForwardTo
-	}
-	return ""
+// Deprecated: Use CustomMessageRequest.ProtoReflect.Descriptor instead.
+func (*CustomMessageRequest) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{15}
 }
 
-type HtlcFail struct {
+// A custom message received from a peer, see the custommsg hook of cln.
+// Messages are sent with the sendcustommsg rpc of cln.
+type CustomMessage struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	// Types that are assignable to Failure:
-	//	*HtlcFail_FailureMessage
-	//	*HtlcFail_FailureOnion
-	Failure isHtlcFail_Failure `protobuf_oneof:"failure"`
+	PeerId string `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	// Hex encoded message, including the 2 byte type prefix.
+	Payload string `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
 }
 
-func (x *HtlcFail) Reset() {
-	*x = HtlcFail{}
+func (x *CustomMessage) Reset() {
+	*x = CustomMessage{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_cln_plugin_proto_msgTypes[5]
+		mi := &file_cln_plugin_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *HtlcFail) String() string {
+func (x *CustomMessage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HtlcFail) ProtoMessage() {}
+func (*CustomMessage) ProtoMessage() {}
 
-func (x *HtlcFail) ProtoReflect() protoreflect.Message {
-	mi := &file_cln_plugin_proto_msgTypes[5]
+func (x *CustomMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -460,73 +1067,96 @@ func (x *HtlcFail) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HtlcFail.ProtoReflect.Descriptor instead.
-func (*HtlcFail) Descriptor() ([]byte, []int) {
-	return file_cln_plugin_proto_rawDescGZIP(), []int{5}
-}
-
-func (m *HtlcFail) GetFailure() isHtlcFail_Failure {
-	if m != nil {
-		return m.Failure
-	}
-	return nil
+// Deprecated: Use CustomMessage.ProtoReflect.Descriptor instead.
+func (*CustomMessage) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{16}
 }
 
-func (x *HtlcFail) GetFailureMessage() string {
-	if x, ok := x.GetFailure().(*HtlcFail_FailureMessage); ok {
-		return x.FailureMessage
+func (x *CustomMessage) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
 	}
 	return ""
 }
 
-func (x *HtlcFail) GetFailureOnion() string {
-	if x, ok := x.GetFailure().(*HtlcFail_FailureOnion); ok {
-		return x.FailureOnion
+func (x *CustomMessage) GetPayload() string {
+	if x != nil {
+		return x.Payload
 	}
 	return ""
 }
 
-type isHtlcFail_Failure interface {
-	isHtlcFail_Failure()
+type PingRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 }
 
-type HtlcFail_FailureMessage struct {
-	FailureMessage string `protobuf:"bytes,1,opt,name=failure_message,json=failureMessage,proto3,oneof"`
+func (x *PingRequest) Reset() {
+	*x = PingRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cln_plugin_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
 }
 
-type HtlcFail_FailureOnion struct {
-	FailureOnion string `protobuf:"bytes,2,opt,name=failure_onion,json=failureOnion,proto3,oneof"`
+func (x *PingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HtlcFail_FailureMessage) isHtlcFail_Failure() {}
+func (*PingRequest) ProtoMessage() {}
 
-func (*HtlcFail_FailureOnion) isHtlcFail_Failure() {}
+func (x *PingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
 
-type HtlcResolve struct {
+// Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
+func (*PingRequest) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{17}
+}
+
+type PingResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	PaymentKey string `protobuf:"bytes,1,opt,name=payment_key,json=paymentKey,proto3" json:"payment_key,omitempty"`
+	// Whether a client is subscribed to the htlc stream.
+	HtlcStreamActive bool `protobuf:"varint,1,opt,name=htlc_stream_active,json=htlcStreamActive,proto3" json:"htlc_stream_active,omitempty"`
+	// Whether the plugin supports holding htlcs with HtlcHold. Older plugins
+	// continue htlcs on unknown resolutions.
+	SupportsHold bool `protobuf:"varint,2,opt,name=supports_hold,json=supportsHold,proto3" json:"supports_hold,omitempty"`
+	// The time within which the plugin expects a heartbeat or a resolution
+	// for every htlc sent to the subscriber. Zero if the plugin doesn't
+	// require heartbeats.
+	HeartbeatTimeoutSeconds uint32 `protobuf:"varint,3,opt,name=heartbeat_timeout_seconds,json=heartbeatTimeoutSeconds,proto3" json:"heartbeat_timeout_seconds,omitempty"`
 }
 
-func (x *HtlcResolve) Reset() {
-	*x = HtlcResolve{}
+func (x *PingResponse) Reset() {
+	*x = PingResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_cln_plugin_proto_msgTypes[6]
+		mi := &file_cln_plugin_proto_msgTypes[18]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *HtlcResolve) String() string {
+func (x *PingResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HtlcResolve) ProtoMessage() {}
+func (*PingResponse) ProtoMessage() {}
 
-func (x *HtlcResolve) ProtoReflect() protoreflect.Message {
-	mi := &file_cln_plugin_proto_msgTypes[6]
+func (x *PingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cln_plugin_proto_msgTypes[18]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -537,16 +1167,30 @@ func (x *HtlcResolve) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HtlcResolve.ProtoReflect.Descriptor instead.
-func (*HtlcResolve) Descriptor() ([]byte, []int) {
-	return file_cln_plugin_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
+func (*PingResponse) Descriptor() ([]byte, []int) {
+	return file_cln_plugin_proto_rawDescGZIP(), []int{18}
 }
 
-func (x *HtlcResolve) GetPaymentKey() string {
+func (x *PingResponse) GetHtlcStreamActive() bool {
 	if x != nil {
-		return x.PaymentKey
+		return x.HtlcStreamActive
 	}
-	return ""
+	return false
+}
+
+func (x *PingResponse) GetSupportsHold() bool {
+	if x != nil {
+		return x.SupportsHold
+	}
+	return false
+}
+
+func (x *PingResponse) GetHeartbeatTimeoutSeconds() uint32 {
+	if x != nil {
+		return x.HeartbeatTimeoutSeconds
+	}
+	return 0
 }
 
 var File_cln_plugin_proto protoreflect.FileDescriptor
@@ -590,7 +1234,7 @@ var file_cln_plugin_proto_rawDesc = []byte{
 	0x78, 0x70, 0x69, 0x72, 0x79, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x76, 0x65, 0x12, 0x21, 0x0a,
 	0x0c, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x06, 0x20,
 	0x01, 0x28, 0x09, 0x52, 0x0b, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68,
-	0x22, 0xb9, 0x01, 0x0a, 0x0e, 0x48, 0x74, 0x6c, 0x63, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x75, 0x74,
+	0x22, 0x8a, 0x02, 0x0a, 0x0e, 0x48, 0x74, 0x6c, 0x63, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x75, 0x74,
 	0x69, 0x6f, 0x6e, 0x12, 0x24, 0x0a, 0x0d, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69,
 	0x6f, 0x6e, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x63, 0x6f, 0x72, 0x72,
 	0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x69, 0x64, 0x12, 0x1f, 0x0a, 0x04, 0x66, 0x61, 0x69,
@@ -601,31 +1245,100 @@ var file_cln_plugin_proto_rawDesc = []byte{
 	0x6f, 0x6e, 0x74, 0x69, 0x6e, 0x75, 0x65, 0x12, 0x28, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x6f, 0x6c,
 	0x76, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x48, 0x74, 0x6c, 0x63, 0x52,
 	0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x48, 0x00, 0x52, 0x07, 0x72, 0x65, 0x73, 0x6f, 0x6c, 0x76,
-	0x65, 0x42, 0x09, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x63, 0x6f, 0x6d, 0x65, 0x22, 0x6c, 0x0a, 0x0c,
-	0x48, 0x74, 0x6c, 0x63, 0x43, 0x6f, 0x6e, 0x74, 0x69, 0x6e, 0x75, 0x65, 0x12, 0x1d, 0x0a, 0x07,
-	0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52,
-	0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x88, 0x01, 0x01, 0x12, 0x22, 0x0a, 0x0a, 0x66,
-	0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x5f, 0x74, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48,
-	0x01, 0x52, 0x09, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x54, 0x6f, 0x88, 0x01, 0x01, 0x42,
-	0x0a, 0x0a, 0x08, 0x5f, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x42, 0x0d, 0x0a, 0x0b, 0x5f,
-	0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x5f, 0x74, 0x6f, 0x22, 0x67, 0x0a, 0x08, 0x48, 0x74,
-	0x6c, 0x63, 0x46, 0x61, 0x69, 0x6c, 0x12, 0x29, 0x0a, 0x0f, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72,
-	0x65, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x48,
-	0x00, 0x52, 0x0e, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67,
-	0x65, 0x12, 0x25, 0x0a, 0x0d, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x5f, 0x6f, 0x6e, 0x69,
-	0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x0c, 0x66, 0x61, 0x69, 0x6c,
-	0x75, 0x72, 0x65, 0x4f, 0x6e, 0x69, 0x6f, 0x6e, 0x42, 0x09, 0x0a, 0x07, 0x66, 0x61, 0x69, 0x6c,
-	0x75, 0x72, 0x65, 0x22, 0x2e, 0x0a, 0x0b, 0x48, 0x74, 0x6c, 0x63, 0x52, 0x65, 0x73, 0x6f, 0x6c,
-	0x76, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x6b, 0x65,
-	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74,
-	0x4b, 0x65, 0x79, 0x32, 0x3d, 0x0a, 0x09, 0x43, 0x6c, 0x6e, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e,
-	0x12, 0x30, 0x0a, 0x0a, 0x48, 0x74, 0x6c, 0x63, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x0f,
-	0x2e, 0x48, 0x74, 0x6c, 0x63, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x1a,
-	0x0d, 0x2e, 0x48, 0x74, 0x6c, 0x63, 0x41, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x28, 0x01,
-	0x30, 0x01, 0x42, 0x28, 0x5a, 0x26, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
-	0x2f, 0x62, 0x72, 0x65, 0x65, 0x7a, 0x2f, 0x6c, 0x73, 0x70, 0x64, 0x2f, 0x63, 0x6c, 0x6e, 0x5f,
-	0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+	0x65, 0x12, 0x1f, 0x0a, 0x04, 0x68, 0x6f, 0x6c, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x09, 0x2e, 0x48, 0x74, 0x6c, 0x63, 0x48, 0x6f, 0x6c, 0x64, 0x48, 0x00, 0x52, 0x04, 0x68, 0x6f,
+	0x6c, 0x64, 0x12, 0x2e, 0x0a, 0x09, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x48, 0x74, 0x6c, 0x63, 0x48, 0x65, 0x61, 0x72,
+	0x74, 0x62, 0x65, 0x61, 0x74, 0x48, 0x00, 0x52, 0x09, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65,
+	0x61, 0x74, 0x42, 0x09, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x63, 0x6f, 0x6d, 0x65, 0x22, 0x33, 0x0a,
+	0x08, 0x48, 0x74, 0x6c, 0x63, 0x48, 0x6f, 0x6c, 0x64, 0x12, 0x27, 0x0a, 0x0f, 0x74, 0x69, 0x6d,
+	0x65, 0x6f, 0x75, 0x74, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x0e, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x53, 0x65, 0x63, 0x6f, 0x6e,
+	0x64, 0x73, 0x22, 0x0f, 0x0a, 0x0d, 0x48, 0x74, 0x6c, 0x63, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62,
+	0x65, 0x61, 0x74, 0x22, 0x6c, 0x0a, 0x0c, 0x48, 0x74, 0x6c, 0x63, 0x43, 0x6f, 0x6e, 0x74, 0x69,
+	0x6e, 0x75, 0x65, 0x12, 0x1d, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x88,
+	0x01, 0x01, 0x12, 0x22, 0x0a, 0x0a, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x5f, 0x74, 0x6f,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x01, 0x52, 0x09, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72,
+	0x64, 0x54, 0x6f, 0x88, 0x01, 0x01, 0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x70, 0x61, 0x79, 0x6c, 0x6f,
+	0x61, 0x64, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x5f, 0x74,
+	0x6f, 0x22, 0x67, 0x0a, 0x08, 0x48, 0x74, 0x6c, 0x63, 0x46, 0x61, 0x69, 0x6c, 0x12, 0x29, 0x0a,
+	0x0f, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x0e, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72,
+	0x65, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x25, 0x0a, 0x0d, 0x66, 0x61, 0x69, 0x6c,
+	0x75, 0x72, 0x65, 0x5f, 0x6f, 0x6e, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48,
+	0x00, 0x52, 0x0c, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x4f, 0x6e, 0x69, 0x6f, 0x6e, 0x42,
+	0x09, 0x0a, 0x07, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x22, 0x2e, 0x0a, 0x0b, 0x48, 0x74,
+	0x6c, 0x63, 0x52, 0x65, 0x73, 0x6f, 0x6c, 0x76, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x61, 0x79,
+	0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
+	0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x4b, 0x65, 0x79, 0x22, 0x12, 0x0a, 0x10, 0x50, 0x65,
+	0x65, 0x72, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x5c,
+	0x0a, 0x09, 0x50, 0x65, 0x65, 0x72, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x70,
+	0x65, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x65,
+	0x65, 0x72, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x65,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74,
+	0x65, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0x12, 0x0a, 0x10,
+	0x48, 0x6f, 0x6c, 0x64, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x22, 0x75, 0x0a, 0x09, 0x48, 0x6f, 0x6c, 0x64, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x24, 0x0a,
+	0x0d, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x69, 0x64, 0x12, 0x28, 0x0a, 0x10, 0x68, 0x6f, 0x6c, 0x64, 0x5f, 0x64, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0e, 0x68,
+	0x6f, 0x6c, 0x64, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4d, 0x73, 0x12, 0x18, 0x0a,
+	0x07, 0x6f, 0x75, 0x74, 0x63, 0x6f, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x6f, 0x75, 0x74, 0x63, 0x6f, 0x6d, 0x65, 0x22, 0x15, 0x0a, 0x13, 0x43, 0x68, 0x61, 0x6e, 0x6e,
+	0x65, 0x6c, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x8d,
+	0x01, 0x0a, 0x0c, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12,
+	0x17, 0x0a, 0x07, 0x70, 0x65, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x70, 0x65, 0x65, 0x72, 0x49, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x68, 0x61, 0x6e,
+	0x6e, 0x65, 0x6c, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x63, 0x68,
+	0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x64, 0x12, 0x28, 0x0a, 0x10, 0x73, 0x68, 0x6f, 0x72, 0x74,
+	0x5f, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49,
+	0x64, 0x12, 0x1b, 0x0a, 0x09, 0x6e, 0x65, 0x77, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6e, 0x65, 0x77, 0x53, 0x74, 0x61, 0x74, 0x65, 0x22, 0x16,
+	0x0a, 0x14, 0x43, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x42, 0x0a, 0x0d, 0x43, 0x75, 0x73, 0x74, 0x6f, 0x6d,
+	0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x70, 0x65, 0x65, 0x72, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x65, 0x65, 0x72, 0x49, 0x64,
+	0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x22, 0x0d, 0x0a, 0x0b, 0x50, 0x69,
+	0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x9d, 0x01, 0x0a, 0x0c, 0x50, 0x69,
+	0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2c, 0x0a, 0x12, 0x68, 0x74,
+	0x6c, 0x63, 0x5f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x68, 0x74, 0x6c, 0x63, 0x53, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x41, 0x63, 0x74, 0x69, 0x76, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x73, 0x75, 0x70, 0x70,
+	0x6f, 0x72, 0x74, 0x73, 0x5f, 0x68, 0x6f, 0x6c, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x0c, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x48, 0x6f, 0x6c, 0x64, 0x12, 0x3a, 0x0a,
+	0x19, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x17, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x32, 0xc3, 0x02, 0x0a, 0x09, 0x43, 0x6c,
+	0x6e, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x12, 0x30, 0x0a, 0x0a, 0x48, 0x74, 0x6c, 0x63, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x0f, 0x2e, 0x48, 0x74, 0x6c, 0x63, 0x52, 0x65, 0x73, 0x6f,
+	0x6c, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x1a, 0x0d, 0x2e, 0x48, 0x74, 0x6c, 0x63, 0x41, 0x63, 0x63,
+	0x65, 0x70, 0x74, 0x65, 0x64, 0x28, 0x01, 0x30, 0x01, 0x12, 0x32, 0x0a, 0x0f, 0x50, 0x65, 0x65,
+	0x72, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x11, 0x2e, 0x50,
+	0x65, 0x65, 0x72, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x0a, 0x2e, 0x50, 0x65, 0x65, 0x72, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x12, 0x23, 0x0a,
+	0x04, 0x50, 0x69, 0x6e, 0x67, 0x12, 0x0c, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x0d, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x32, 0x0a, 0x0f, 0x48, 0x6f, 0x6c, 0x64, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x11, 0x2e, 0x48, 0x6f, 0x6c, 0x64, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0a, 0x2e, 0x48, 0x6f, 0x6c, 0x64, 0x45,
+	0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x12, 0x3b, 0x0a, 0x12, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65,
+	0x6c, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x14, 0x2e, 0x43,
+	0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x0d, 0x2e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x30, 0x01, 0x12, 0x3a, 0x0a, 0x0f, 0x43, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x4d, 0x73, 0x67,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x15, 0x2e, 0x43, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x4d,
+	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e,
+	0x43, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x30, 0x01, 0x42,
+	0x28, 0x5a, 0x26, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62, 0x72,
+	0x65, 0x65, 0x7a, 0x2f, 0x6c, 0x73, 0x70, 0x64, 0x2f, 0x63, 0x6c, 0x6e, 0x5f, 0x70, 0x6c, 0x75,
+	0x67, 0x69, 0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
 }
 
 var (
@@ -640,29 +1353,53 @@ func file_cln_plugin_proto_rawDescGZIP() []byte {
 	return file_cln_plugin_proto_rawDescData
 }
 
-var file_cln_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_cln_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
 var file_cln_plugin_proto_goTypes = []interface{}{
-	(*HtlcAccepted)(nil),   // 0: HtlcAccepted
-	(*Onion)(nil),          // 1: Onion
-	(*Htlc)(nil),           // 2: Htlc
-	(*HtlcResolution)(nil), // 3: HtlcResolution
-	(*HtlcContinue)(nil),   // 4: HtlcContinue
-	(*HtlcFail)(nil),       // 5: HtlcFail
-	(*HtlcResolve)(nil),    // 6: HtlcResolve
+	(*HtlcAccepted)(nil),         // 0: HtlcAccepted
+	(*Onion)(nil),                // 1: Onion
+	(*Htlc)(nil),                 // 2: Htlc
+	(*HtlcResolution)(nil),       // 3: HtlcResolution
+	(*HtlcHold)(nil),             // 4: HtlcHold
+	(*HtlcHeartbeat)(nil),        // 5: HtlcHeartbeat
+	(*HtlcContinue)(nil),         // 6: HtlcContinue
+	(*HtlcFail)(nil),             // 7: HtlcFail
+	(*HtlcResolve)(nil),          // 8: HtlcResolve
+	(*PeerEventRequest)(nil),     // 9: PeerEventRequest
+	(*PeerEvent)(nil),            // 10: PeerEvent
+	(*HoldEventRequest)(nil),     // 11: HoldEventRequest
+	(*HoldEvent)(nil),            // 12: HoldEvent
+	(*ChannelEventRequest)(nil),  // 13: ChannelEventRequest
+	(*ChannelEvent)(nil),         // 14: ChannelEvent
+	(*CustomMessageRequest)(nil), // 15: CustomMessageRequest
+	(*CustomMessage)(nil),        // 16: CustomMessage
+	(*PingRequest)(nil),          // 17: PingRequest
+	(*PingResponse)(nil),         // 18: PingResponse
 }
 var file_cln_plugin_proto_depIdxs = []int32{
-	1, // 0: HtlcAccepted.onion:type_name -> Onion
-	2, // 1: HtlcAccepted.htlc:type_name -> Htlc
-	5, // 2: HtlcResolution.fail:type_name -> HtlcFail
-	4, // 3: HtlcResolution.continue:type_name -> HtlcContinue
-	6, // 4: HtlcResolution.resolve:type_name -> HtlcResolve
-	3, // 5: ClnPlugin.HtlcStream:input_type -> HtlcResolution
-	0, // 6: ClnPlugin.HtlcStream:output_type -> HtlcAccepted
-	6, // [6:7] is the sub-list for method output_type
-	5, // [5:6] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	1,  // 0: HtlcAccepted.onion:type_name -> Onion
+	2,  // 1: HtlcAccepted.htlc:type_name -> Htlc
+	7,  // 2: HtlcResolution.fail:type_name -> HtlcFail
+	6,  // 3: HtlcResolution.continue:type_name -> HtlcContinue
+	8,  // 4: HtlcResolution.resolve:type_name -> HtlcResolve
+	4,  // 5: HtlcResolution.hold:type_name -> HtlcHold
+	5,  // 6: HtlcResolution.heartbeat:type_name -> HtlcHeartbeat
+	3,  // 7: ClnPlugin.HtlcStream:input_type -> HtlcResolution
+	9,  // 8: ClnPlugin.PeerEventStream:input_type -> PeerEventRequest
+	17, // 9: ClnPlugin.Ping:input_type -> PingRequest
+	11, // 10: ClnPlugin.HoldEventStream:input_type -> HoldEventRequest
+	13, // 11: ClnPlugin.ChannelEventStream:input_type -> ChannelEventRequest
+	15, // 12: ClnPlugin.CustomMsgStream:input_type -> CustomMessageRequest
+	0,  // 13: ClnPlugin.HtlcStream:output_type -> HtlcAccepted
+	10, // 14: ClnPlugin.PeerEventStream:output_type -> PeerEvent
+	18, // 15: ClnPlugin.Ping:output_type -> PingResponse
+	12, // 16: ClnPlugin.HoldEventStream:output_type -> HoldEvent
+	14, // 17: ClnPlugin.ChannelEventStream:output_type -> ChannelEvent
+	16, // 18: ClnPlugin.CustomMsgStream:output_type -> CustomMessage
+	13, // [13:19] is the sub-list for method output_type
+	7,  // [7:13] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_cln_plugin_proto_init() }
@@ -720,7 +1457,7 @@ func file_cln_plugin_proto_init() {
 			}
 		}
 		file_cln_plugin_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*HtlcContinue); i {
+			switch v := v.(*HtlcHold); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -732,7 +1469,7 @@ func file_cln_plugin_proto_init() {
 			}
 		}
 		file_cln_plugin_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*HtlcFail); i {
+			switch v := v.(*HtlcHeartbeat); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -744,6 +1481,30 @@ func file_cln_plugin_proto_init() {
 			}
 		}
 		file_cln_plugin_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HtlcContinue); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cln_plugin_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HtlcFail); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cln_plugin_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*HtlcResolve); i {
 			case 0:
 				return &v.state
@@ -755,14 +1516,136 @@ func file_cln_plugin_proto_init() {
 				return nil
 			}
 		}
+		file_cln_plugin_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PeerEventRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cln_plugin_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PeerEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cln_plugin_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HoldEventRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cln_plugin_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HoldEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cln_plugin_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ChannelEventRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cln_plugin_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ChannelEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cln_plugin_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CustomMessageRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cln_plugin_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CustomMessage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cln_plugin_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PingRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cln_plugin_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PingResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_cln_plugin_proto_msgTypes[3].OneofWrappers = []interface{}{
 		(*HtlcResolution_Fail)(nil),
 		(*HtlcResolution_Continue)(nil),
 		(*HtlcResolution_Resolve)(nil),
+		(*HtlcResolution_Hold)(nil),
+		(*HtlcResolution_Heartbeat)(nil),
 	}
-	file_cln_plugin_proto_msgTypes[4].OneofWrappers = []interface{}{}
-	file_cln_plugin_proto_msgTypes[5].OneofWrappers = []interface{}{
+	file_cln_plugin_proto_msgTypes[6].OneofWrappers = []interface{}{}
+	file_cln_plugin_proto_msgTypes[7].OneofWrappers = []interface{}{
 		(*HtlcFail_FailureMessage)(nil),
 		(*HtlcFail_FailureOnion)(nil),
 	}
@@ -772,7 +1655,7 @@ func file_cln_plugin_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_cln_plugin_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   19,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -23,6 +23,11 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ClnPluginClient interface {
 	HtlcStream(ctx context.Context, opts ...grpc.CallOption) (ClnPlugin_HtlcStreamClient, error)
+	PeerEventStream(ctx context.Context, in *PeerEventRequest, opts ...grpc.CallOption) (ClnPlugin_PeerEventStreamClient, error)
+	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
+	HoldEventStream(ctx context.Context, in *HoldEventRequest, opts ...grpc.CallOption) (ClnPlugin_HoldEventStreamClient, error)
+	ChannelEventStream(ctx context.Context, in *ChannelEventRequest, opts ...grpc.CallOption) (ClnPlugin_ChannelEventStreamClient, error)
+	CustomMsgStream(ctx context.Context, in *CustomMessageRequest, opts ...grpc.CallOption) (ClnPlugin_CustomMsgStreamClient, error)
 }
 
 type clnPluginClient struct {
@@ -64,11 +69,153 @@ func (x *clnPluginHtlcStreamClient) Recv() (*HtlcAccepted, error) {
 	return m, nil
 }
 
+func (c *clnPluginClient) PeerEventStream(ctx context.Context, in *PeerEventRequest, opts ...grpc.CallOption) (ClnPlugin_PeerEventStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ClnPlugin_ServiceDesc.Streams[1], "/ClnPlugin/PeerEventStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &clnPluginPeerEventStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ClnPlugin_PeerEventStreamClient interface {
+	Recv() (*PeerEvent, error)
+	grpc.ClientStream
+}
+
+type clnPluginPeerEventStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *clnPluginPeerEventStreamClient) Recv() (*PeerEvent, error) {
+	m := new(PeerEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *clnPluginClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
+	out := new(PingResponse)
+	err := c.cc.Invoke(ctx, "/ClnPlugin/Ping", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clnPluginClient) HoldEventStream(ctx context.Context, in *HoldEventRequest, opts ...grpc.CallOption) (ClnPlugin_HoldEventStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ClnPlugin_ServiceDesc.Streams[2], "/ClnPlugin/HoldEventStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &clnPluginHoldEventStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ClnPlugin_HoldEventStreamClient interface {
+	Recv() (*HoldEvent, error)
+	grpc.ClientStream
+}
+
+type clnPluginHoldEventStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *clnPluginHoldEventStreamClient) Recv() (*HoldEvent, error) {
+	m := new(HoldEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *clnPluginClient) ChannelEventStream(ctx context.Context, in *ChannelEventRequest, opts ...grpc.CallOption) (ClnPlugin_ChannelEventStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ClnPlugin_ServiceDesc.Streams[3], "/ClnPlugin/ChannelEventStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &clnPluginChannelEventStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ClnPlugin_ChannelEventStreamClient interface {
+	Recv() (*ChannelEvent, error)
+	grpc.ClientStream
+}
+
+type clnPluginChannelEventStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *clnPluginChannelEventStreamClient) Recv() (*ChannelEvent, error) {
+	m := new(ChannelEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *clnPluginClient) CustomMsgStream(ctx context.Context, in *CustomMessageRequest, opts ...grpc.CallOption) (ClnPlugin_CustomMsgStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ClnPlugin_ServiceDesc.Streams[4], "/ClnPlugin/CustomMsgStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &clnPluginCustomMsgStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ClnPlugin_CustomMsgStreamClient interface {
+	Recv() (*CustomMessage, error)
+	grpc.ClientStream
+}
+
+type clnPluginCustomMsgStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *clnPluginCustomMsgStreamClient) Recv() (*CustomMessage, error) {
+	m := new(CustomMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ClnPluginServer is the server API for ClnPlugin service.
 // All implementations must embed UnimplementedClnPluginServer
 // for forward compatibility
 type ClnPluginServer interface {
 	HtlcStream(ClnPlugin_HtlcStreamServer) error
+	PeerEventStream(*PeerEventRequest, ClnPlugin_PeerEventStreamServer) error
+	Ping(context.Context, *PingRequest) (*PingResponse, error)
+	HoldEventStream(*HoldEventRequest, ClnPlugin_HoldEventStreamServer) error
+	ChannelEventStream(*ChannelEventRequest, ClnPlugin_ChannelEventStreamServer) error
+	CustomMsgStream(*CustomMessageRequest, ClnPlugin_CustomMsgStreamServer) error
 	mustEmbedUnimplementedClnPluginServer()
 }
 
@@ -79,6 +226,21 @@ type UnimplementedClnPluginServer struct {
 func (UnimplementedClnPluginServer) HtlcStream(ClnPlugin_HtlcStreamServer) error {
 	return status.Errorf(codes.Unimplemented, "method HtlcStream not implemented")
 }
+func (UnimplementedClnPluginServer) PeerEventStream(*PeerEventRequest, ClnPlugin_PeerEventStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method PeerEventStream not implemented")
+}
+func (UnimplementedClnPluginServer) Ping(context.Context, *PingRequest) (*PingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (UnimplementedClnPluginServer) HoldEventStream(*HoldEventRequest, ClnPlugin_HoldEventStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method HoldEventStream not implemented")
+}
+func (UnimplementedClnPluginServer) ChannelEventStream(*ChannelEventRequest, ClnPlugin_ChannelEventStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ChannelEventStream not implemented")
+}
+func (UnimplementedClnPluginServer) CustomMsgStream(*CustomMessageRequest, ClnPlugin_CustomMsgStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method CustomMsgStream not implemented")
+}
 func (UnimplementedClnPluginServer) mustEmbedUnimplementedClnPluginServer() {}
 
 // UnsafeClnPluginServer may be embedded to opt out of forward compatibility for this service.
@@ -118,13 +280,120 @@ func (x *clnPluginHtlcStreamServer) Recv() (*HtlcResolution, error) {
 	return m, nil
 }
 
+func _ClnPlugin_PeerEventStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PeerEventRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ClnPluginServer).PeerEventStream(m, &clnPluginPeerEventStreamServer{stream})
+}
+
+type ClnPlugin_PeerEventStreamServer interface {
+	Send(*PeerEvent) error
+	grpc.ServerStream
+}
+
+type clnPluginPeerEventStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *clnPluginPeerEventStreamServer) Send(m *PeerEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ClnPlugin_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClnPluginServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ClnPlugin/Ping",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClnPluginServer).Ping(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClnPlugin_HoldEventStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(HoldEventRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ClnPluginServer).HoldEventStream(m, &clnPluginHoldEventStreamServer{stream})
+}
+
+type ClnPlugin_HoldEventStreamServer interface {
+	Send(*HoldEvent) error
+	grpc.ServerStream
+}
+
+type clnPluginHoldEventStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *clnPluginHoldEventStreamServer) Send(m *HoldEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ClnPlugin_ChannelEventStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ChannelEventRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ClnPluginServer).ChannelEventStream(m, &clnPluginChannelEventStreamServer{stream})
+}
+
+type ClnPlugin_ChannelEventStreamServer interface {
+	Send(*ChannelEvent) error
+	grpc.ServerStream
+}
+
+type clnPluginChannelEventStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *clnPluginChannelEventStreamServer) Send(m *ChannelEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ClnPlugin_CustomMsgStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CustomMessageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ClnPluginServer).CustomMsgStream(m, &clnPluginCustomMsgStreamServer{stream})
+}
+
+type ClnPlugin_CustomMsgStreamServer interface {
+	Send(*CustomMessage) error
+	grpc.ServerStream
+}
+
+type clnPluginCustomMsgStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *clnPluginCustomMsgStreamServer) Send(m *CustomMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // ClnPlugin_ServiceDesc is the grpc.ServiceDesc for ClnPlugin service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var ClnPlugin_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "ClnPlugin",
 	HandlerType: (*ClnPluginServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ping",
+			Handler:    _ClnPlugin_Ping_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "HtlcStream",
@@ -132,6 +401,26 @@ var ClnPlugin_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "PeerEventStream",
+			Handler:       _ClnPlugin_PeerEventStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "HoldEventStream",
+			Handler:       _ClnPlugin_HoldEventStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ChannelEventStream",
+			Handler:       _ClnPlugin_ChannelEventStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "CustomMsgStream",
+			Handler:       _ClnPlugin_CustomMsgStream_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "cln_plugin.proto",
 }
@@ -1,6 +1,7 @@
 package cln_plugin
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
@@ -27,34 +28,60 @@ type htlcResultMsg struct {
 
 type server struct {
 	proto.ClnPluginServer
-	listenAddress     string
-	subscriberTimeout time.Duration
-	grpcServer        *grpc.Server
-	mtx               sync.Mutex
-	stream            proto.ClnPlugin_HtlcStreamServer
-	newSubscriber     chan struct{}
-	started           chan struct{}
-	done              chan struct{}
-	completed         chan struct{}
-	startError        chan error
-	sendQueue         chan *htlcAcceptedMsg
-	recvQueue         chan *htlcResultMsg
+	listenAddress      string
+	subscriberTimeout  time.Duration
+	heartbeat          *heartbeat
+	grpcServer         *grpc.Server
+	mtx                sync.Mutex
+	stream             proto.ClnPlugin_HtlcStreamServer
+	kick               chan struct{}
+	chaos              *chaos
+	newSubscriber      chan struct{}
+	started            chan struct{}
+	done               chan struct{}
+	completed          chan struct{}
+	startError         chan error
+	sendQueue          chan *htlcAcceptedMsg
+	recvQueue          chan *htlcResultMsg
+	peerMtx            sync.Mutex
+	peerSubscribers    map[chan *proto.PeerEvent]struct{}
+	channelMtx         sync.Mutex
+	channelSubscribers map[chan *proto.ChannelEvent]struct{}
+	customMsgMtx       sync.Mutex
+	customMsgSubs      map[chan *proto.CustomMessage]struct{}
+	holdMtx            sync.Mutex
+	holds              map[string]*heldHtlc
+	holdSubscribers    map[chan *proto.HoldEvent]struct{}
+	heartbeatMtx       sync.Mutex
+	watchdogs          map[string]*watchdog
 }
 
+// Size of the buffer of peer events per subscriber. A subscriber that falls
+// this far behind is dropped, so it resubscribes and resyncs its peers.
+const peerEventBufferSize = 1000
+
 // Creates a new grpc server
-func NewServer(listenAddress string, subscriberTimeout time.Duration) *server {
+func NewServer(listenAddress string, subscriberTimeout time.Duration, heartbeat *heartbeat, chaos *chaos) *server {
 	// TODO: Set a sane max queue size
 	return &server{
 		listenAddress:     listenAddress,
 		subscriberTimeout: subscriberTimeout,
+		heartbeat:         heartbeat,
+		chaos:             chaos,
 		// The send queue exists to buffer messages until a subscriber is active.
 		sendQueue: make(chan *htlcAcceptedMsg, 10000),
 		// The receive queue exists mainly to allow returning timeouts to the
 		// cln plugin. If there is no subscriber active within the subscriber
 		// timeout period these results can be put directly on the receive queue.
-		recvQueue:  make(chan *htlcResultMsg, 10000),
-		started:    make(chan struct{}),
-		startError: make(chan error, 1),
+		recvQueue:          make(chan *htlcResultMsg, 10000),
+		started:            make(chan struct{}),
+		startError:         make(chan error, 1),
+		peerSubscribers:    make(map[chan *proto.PeerEvent]struct{}),
+		channelSubscribers: make(map[chan *proto.ChannelEvent]struct{}),
+		customMsgSubs:      make(map[chan *proto.CustomMessage]struct{}),
+		holds:              make(map[string]*heldHtlc),
+		holdSubscribers:    make(map[chan *proto.HoldEvent]struct{}),
+		watchdogs:          make(map[string]*watchdog),
 	}
 }
 
@@ -142,6 +169,8 @@ func (s *server) HtlcStream(stream proto.ClnPlugin_HtlcStreamServer) error {
 	}
 
 	s.stream = stream
+	kick := make(chan struct{})
+	s.kick = kick
 
 	// Notify listeners that a new subscriber is active. Replace the chan with
 	// a new one immediately in case this subscriber is dropped later.
@@ -149,17 +178,234 @@ func (s *server) HtlcStream(stream proto.ClnPlugin_HtlcStreamServer) error {
 	s.newSubscriber = make(chan struct{})
 	s.mtx.Unlock()
 
-	<-stream.Context().Done()
-	log.Printf("HtlcStream context is done. Return: %v", stream.Context().Err())
+	select {
+	case <-stream.Context().Done():
+		log.Printf("HtlcStream context is done. Return: %v", stream.Context().Err())
+	case <-kick:
+		log.Printf("chaos: dropped the HTLC stream subscriber.")
+		return fmt.Errorf("chaos: disconnected")
+	}
 
-	// Remove the subscriber.
+	// Remove the subscriber, unless it was dropped already.
 	s.mtx.Lock()
-	s.stream = nil
+	if s.stream == stream {
+		s.stream = nil
+	}
 	s.mtx.Unlock()
 
 	return stream.Context().Err()
 }
 
+// Drops the subscriber in chaos mode. The htlcs sent to it that were not
+// resolved yet are sent again to the next subscriber.
+func (s *server) chaosDisconnect(stream proto.ClnPlugin_HtlcStreamServer) {
+	s.mtx.Lock()
+	if s.stream != stream {
+		s.mtx.Unlock()
+		return
+	}
+	s.stream = nil
+	close(s.kick)
+	s.mtx.Unlock()
+
+	unresolved := s.chaos.unresolved()
+	go func() {
+		for _, msg := range unresolved {
+			s.sendQueue <- msg
+		}
+	}()
+}
+
+// Grpc method that is called when a client subscribes to peer events. Unlike
+// the htlc stream, there can be multiple subscribers. Events that happen while
+// a client is not subscribed are not replayed, so a client should list the
+// peers of the node after subscribing.
+func (s *server) PeerEventStream(
+	req *proto.PeerEventRequest,
+	stream proto.ClnPlugin_PeerEventStreamServer,
+) error {
+	events := make(chan *proto.PeerEvent, peerEventBufferSize)
+	s.peerMtx.Lock()
+	s.peerSubscribers[events] = struct{}{}
+	s.peerMtx.Unlock()
+	log.Printf("Got a new peer event stream subscription request.")
+
+	defer func() {
+		s.peerMtx.Lock()
+		delete(s.peerSubscribers, events)
+		s.peerMtx.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			log.Printf("PeerEventStream context is done. Return: %v", stream.Context().Err())
+			return stream.Context().Err()
+		case event, ok := <-events:
+			if !ok {
+				log.Printf("Peer event subscriber is too slow. Dropping subscriber.")
+				return fmt.Errorf("subscriber too slow")
+			}
+
+			err := stream.Send(event)
+			if err != nil {
+				log.Printf("Error sending peer event to subscriber: %v", err)
+				return err
+			}
+		}
+	}
+}
+
+// Grpc method that is called when a client subscribes to channel events.
+// Events that happen while a client is not subscribed are not replayed.
+func (s *server) ChannelEventStream(
+	req *proto.ChannelEventRequest,
+	stream proto.ClnPlugin_ChannelEventStreamServer,
+) error {
+	events := make(chan *proto.ChannelEvent, peerEventBufferSize)
+	s.channelMtx.Lock()
+	s.channelSubscribers[events] = struct{}{}
+	s.channelMtx.Unlock()
+	log.Printf("Got a new channel event stream subscription request.")
+
+	defer func() {
+		s.channelMtx.Lock()
+		delete(s.channelSubscribers, events)
+		s.channelMtx.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			log.Printf("ChannelEventStream context is done. Return: %v", stream.Context().Err())
+			return stream.Context().Err()
+		case event, ok := <-events:
+			if !ok {
+				log.Printf("Channel event subscriber is too slow. Dropping subscriber.")
+				return fmt.Errorf("subscriber too slow")
+			}
+
+			err := stream.Send(event)
+			if err != nil {
+				log.Printf("Error sending channel event to subscriber: %v", err)
+				return err
+			}
+		}
+	}
+}
+
+// Grpc method that is called when a client subscribes to the custom messages
+// peers send to the node. Messages that arrive while no client is subscribed
+// are dropped, the protocols on top of custom messages have the peer retry.
+func (s *server) CustomMsgStream(
+	req *proto.CustomMessageRequest,
+	stream proto.ClnPlugin_CustomMsgStreamServer,
+) error {
+	msgs := make(chan *proto.CustomMessage, peerEventBufferSize)
+	s.customMsgMtx.Lock()
+	s.customMsgSubs[msgs] = struct{}{}
+	s.customMsgMtx.Unlock()
+	log.Printf("Got a new custom message stream subscription request.")
+
+	defer func() {
+		s.customMsgMtx.Lock()
+		delete(s.customMsgSubs, msgs)
+		s.customMsgMtx.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			log.Printf("CustomMsgStream context is done. Return: %v", stream.Context().Err())
+			return stream.Context().Err()
+		case msg, ok := <-msgs:
+			if !ok {
+				log.Printf("Custom message subscriber is too slow. Dropping subscriber.")
+				return fmt.Errorf("subscriber too slow")
+			}
+
+			err := stream.Send(msg)
+			if err != nil {
+				log.Printf("Error sending custom message to subscriber: %v", err)
+				return err
+			}
+		}
+	}
+}
+
+// Grpc method that lets a client check the plugin is responsive, and that
+// the plugin still considers it subscribed to the htlc stream.
+func (s *server) Ping(ctx context.Context, req *proto.PingRequest) (*proto.PingResponse, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var heartbeatTimeout uint32
+	if s.heartbeat != nil {
+		heartbeatTimeout = uint32(s.heartbeat.timeout.Seconds())
+	}
+	return &proto.PingResponse{
+		HtlcStreamActive:        s.stream != nil,
+		SupportsHold:            true,
+		HeartbeatTimeoutSeconds: heartbeatTimeout,
+	}, nil
+}
+
+// Sends a peer event to all peer event subscribers. Never blocks, subscribers
+// with a full buffer are dropped.
+func (s *server) SendPeerEvent(peerId string, connected bool, address string) {
+	s.peerMtx.Lock()
+	defer s.peerMtx.Unlock()
+	for events := range s.peerSubscribers {
+		select {
+		case events <- &proto.PeerEvent{PeerId: peerId, Connected: connected, Address: address}:
+		default:
+			close(events)
+			delete(s.peerSubscribers, events)
+		}
+	}
+}
+
+// Sends a channel event to all channel event subscribers. Never blocks,
+// subscribers with a full buffer are dropped.
+func (s *server) SendChannelEvent(peerId string, channelId string, shortChannelId string, newState string) {
+	event := &proto.ChannelEvent{
+		PeerId:         peerId,
+		ChannelId:      channelId,
+		ShortChannelId: shortChannelId,
+		NewState:       newState,
+	}
+
+	s.channelMtx.Lock()
+	defer s.channelMtx.Unlock()
+	for events := range s.channelSubscribers {
+		select {
+		case events <- event:
+		default:
+			close(events)
+			delete(s.channelSubscribers, events)
+		}
+	}
+}
+
+// Sends a custom message to all custom message subscribers. Never blocks,
+// subscribers with a full buffer are dropped.
+func (s *server) SendCustomMessage(peerId string, payload string) {
+	msg := &proto.CustomMessage{
+		PeerId:  peerId,
+		Payload: payload,
+	}
+
+	s.customMsgMtx.Lock()
+	defer s.customMsgMtx.Unlock()
+	for msgs := range s.customMsgSubs {
+		select {
+		case msgs <- msg:
+		default:
+			close(msgs)
+			delete(s.customMsgSubs, msgs)
+		}
+	}
+}
+
 // Enqueues a htlc_accepted message for send to the grpc client.
 func (s *server) Send(id string, h *HtlcAccepted) {
 	s.sendQueue <- &htlcAcceptedMsg{
@@ -228,6 +474,9 @@ func (s *server) handleHtlcAccepted(msg *htlcAcceptedMsg) {
 				// If the subscriber timeout expires while holding the htlc
 				// we short circuit the htlc by sending the default result
 				// (continue) to cln.
+				if !s.chaos.resolve(msg.id) {
+					return
+				}
 				s.recvQueue <- &htlcResultMsg{
 					id:     msg.id,
 					result: s.defaultResult(),
@@ -237,8 +486,13 @@ func (s *server) handleHtlcAccepted(msg *htlcAcceptedMsg) {
 			}
 		}
 
+		if s.chaos.shouldDisconnect() {
+			s.chaosDisconnect(stream)
+			continue
+		}
+
 		// There is a subscriber. Attempt to send the htlc_accepted message.
-		err := stream.Send(&proto.HtlcAccepted{
+		htlc := &proto.HtlcAccepted{
 			Correlationid: msg.id,
 			Onion: &proto.Onion{
 				Payload:           msg.htlc.Onion.Payload,
@@ -257,10 +511,17 @@ func (s *server) handleHtlcAccepted(msg *htlcAcceptedMsg) {
 				PaymentHash:        msg.htlc.Htlc.PaymentHash,
 			},
 			ForwardTo: msg.htlc.ForwardTo,
-		})
+		}
+		err := stream.Send(htlc)
 
 		// If there is no error, we're done.
 		if err == nil {
+			s.chaos.sent(msg)
+			s.watch(msg.id)
+			if s.chaos.shouldDuplicate() {
+				log.Printf("chaos: sending htlc with id '%s' twice.", msg.id)
+				stream.Send(htlc)
+			}
 			return
 		}
 
@@ -284,10 +545,49 @@ func (s *server) listenHtlcResponses() {
 			return
 		default:
 			resp := s.recv()
-			s.recvQueue <- &htlcResultMsg{
+			if resp == nil {
+				continue
+			}
+
+			// A heartbeat only tells lspd is still working on the htlc.
+			if _, ok := resp.Outcome.(*proto.HtlcResolution_Heartbeat); ok {
+				s.beat(resp.Correlationid)
+				continue
+			}
+
+			// A hold is not a result for cln, the htlc stays pending until
+			// lspd resolves it, or the hold times out.
+			if hold, ok := resp.Outcome.(*proto.HtlcResolution_Hold); ok {
+				s.beat(resp.Correlationid)
+				s.hold(resp.Correlationid, hold.Hold)
+				continue
+			}
+
+			if !s.chaos.resolve(resp.Correlationid) {
+				log.Printf("chaos: dropping duplicate resolution of htlc with id '%s'.", resp.Correlationid)
+				continue
+			}
+
+			if !s.unwatch(resp.Correlationid) {
+				log.Printf("Dropping resolution of htlc with id '%s', its heartbeat timed out.", resp.Correlationid)
+				continue
+			}
+
+			if !s.release(resp.Correlationid, outcomeName(resp.Outcome)) {
+				continue
+			}
+
+			msg := &htlcResultMsg{
 				id:     resp.Correlationid,
 				result: s.mapResult(resp.Outcome),
 			}
+			if d := s.chaos.reorderDelayDuration(); d > 0 {
+				log.Printf("chaos: holding back resolution of htlc with id '%s' for %v.", msg.id, d)
+				time.AfterFunc(d, func() { s.recvQueue <- msg })
+				continue
+			}
+
+			s.recvQueue <- msg
 		}
 	}
 }
@@ -321,6 +621,10 @@ func (s *server) recv() *proto.HtlcResolution {
 		r, err := stream.Recv()
 		if err == nil {
 			log.Printf("Received HtlcResolution %+v", r)
+			if d := s.chaos.recvDelayDuration(); d > 0 {
+				log.Printf("chaos: delaying HtlcResolution for %v.", d)
+				time.Sleep(d)
+			}
 			return r
 		}
 
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+)
+
+// coinControl returns the coin control of the node config. Funding inputs
+// need minInputConfs confirmations, or else the minConfs of the node, or 1.
+func coinControl(node *config.NodeConfig) *lightning.CoinControl {
+	cfg := node.CoinControl
+	cc := &lightning.CoinControl{
+		Account:      cfg.Account,
+		MinConfs:     1,
+		MinChangeSat: cfg.MinChangeSat,
+	}
+	if cfg.MinInputConfs != nil {
+		cc.MinConfs = *cfg.MinInputConfs
+	} else if node.MinConfs != nil {
+		cc.MinConfs = *node.MinConfs
+	}
+
+	// The outpoints are compared in their canonical form.
+	for _, o := range cfg.ExcludeUtxos {
+		if outPoint, err := basetypes.ParseOutPoint(o); err == nil {
+			cc.ExcludeUtxos = append(cc.ExcludeUtxos, outPoint.String())
+		}
+	}
+
+	return cc
+}
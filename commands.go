@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/breez/lspd/cln"
+	"github.com/breez/lspd/cln_plugin"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/fixtures"
+	"github.com/breez/lspd/postgresql"
+)
+
+// runMigrate applies the migrations embedded in the binary to DATABASE_URL.
+func runMigrate() {
+	pool, err := postgresql.PgConnect(os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatalf("pgConnect() error: %v", err)
+	}
+	defer pool.Close()
+
+	err = postgresql.Migrate(context.Background(), pool)
+	if err != nil {
+		log.Fatalf("migrate error: %v", err)
+	}
+
+	log.Printf("migrate: database is up to date")
+}
+
+// runClnPlugin runs lspd as the cln plugin, so the plugin doesn't have to be
+// shipped as a separate binary.
+func runClnPlugin() {
+	plugin := cln_plugin.NewClnPlugin(os.Stdin, os.Stdout)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-c
+		// Stop everything gracefully on stop signal
+		plugin.Stop()
+	}()
+	plugin.Start()
+}
+
+const pluginScript = `#!/bin/sh
+export LISTEN_ADDRESS=%s
+exec %s cln-plugin
+`
+
+// runInstallPlugin writes a script starting lspd as cln plugin to the plugins
+// directory of cln, which cln loads on startup, and starts the plugin on the
+// running node.
+func runInstallPlugin(args []string) {
+	flags := flag.NewFlagSet("install-plugin", flag.ExitOnError)
+	lightningDir := flags.String("lightning-dir", filepath.Join(os.Getenv("HOME"), ".lightning"), "cln base directory")
+	network := flags.String("network", "bitcoin", "cln network subdirectory containing the lightning-rpc socket")
+	listenAddress := flags.String("listen-address", "", "address the plugin listens on for lspd, e.g. 127.0.0.1:12312")
+	noStart := flags.Bool("no-start", false, "only install the plugin, don't start it on the running node")
+	flags.Parse(args)
+
+	if *listenAddress == "" {
+		log.Fatalf("--listen-address is required")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("os.Executable() error: %v", err)
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		log.Fatalf("filepath.EvalSymlinks() error: %v", err)
+	}
+
+	pluginDir := filepath.Join(*lightningDir, "plugins")
+	err = os.MkdirAll(pluginDir, 0755)
+	if err != nil {
+		log.Fatalf("failed to create plugin directory %s: %v", pluginDir, err)
+	}
+
+	pluginPath := filepath.Join(pluginDir, "lspd_plugin")
+	script := fmt.Sprintf(pluginScript, *listenAddress, executable)
+	err = os.WriteFile(pluginPath, []byte(script), 0755)
+	if err != nil {
+		log.Fatalf("failed to write plugin %s: %v", pluginPath, err)
+	}
+	log.Printf("install-plugin: wrote %s", pluginPath)
+
+	if *noStart {
+		return
+	}
+
+	logger := log.New(log.Writer(), "", log.Flags())
+	socketPath := filepath.Join(*lightningDir, *network, "lightning-rpc")
+	client, err := cln.NewClnClient(&config.ClnConfig{SocketPath: socketPath, RpcPoolSize: 1}, logger)
+	if err != nil {
+		log.Fatalf("failed to connect to cln: %v", err)
+	}
+
+	err = client.StartPlugin(pluginPath)
+	if err != nil {
+		log.Fatalf("failed to start plugin, it will be started on the next cln restart: %v", err)
+	}
+
+	log.Printf("install-plugin: started %s", pluginPath)
+}
+
+type onionFixture struct {
+	Onion       fixtures.Hex   `json:"onion"`
+	NodeIDs     []fixtures.Hex `json:"nodeIds"`
+	Payloads    []fixtures.Hex `json:"payloads"`
+	ClnPayloads []fixtures.Hex `json:"clnPayloads"`
+}
+
+// runOnionFixture reads an onion description in json from stdin, and writes
+// the resulting onion, node ids and hop payloads as json to stdout.
+func runOnionFixture() {
+	var o fixtures.Onion
+	err := json.NewDecoder(os.Stdin).Decode(&o)
+	if err != nil {
+		log.Fatalf("failed to decode onion description: %v", err)
+	}
+
+	onion, err := o.Build()
+	if err != nil {
+		log.Fatalf("failed to build onion: %v", err)
+	}
+
+	result := &onionFixture{Onion: onion}
+	for i, h := range o.Hops {
+		payload, err := h.Payload()
+		if err != nil {
+			log.Fatalf("hop %d: %v", i, err)
+		}
+		clnPayload, err := h.ClnPayload()
+		if err != nil {
+			log.Fatalf("hop %d: %v", i, err)
+		}
+
+		result.NodeIDs = append(result.NodeIDs, h.NodeID().SerializeCompressed())
+		result.Payloads = append(result.Payloads, payload)
+		result.ClnPayloads = append(result.ClnPayloads, clnPayload)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(result)
+	if err != nil {
+		log.Fatalf("failed to encode fixture: %v", err)
+	}
+}
+
+// runConfig runs the config subcommands. `lspd config validate` loads and
+// validates the node configs like lspd does on startup, so a config can be
+// checked before deploying or reloading it.
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "usage: lspd config validate [--file <path>]")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("config validate", flag.ExitOnError)
+	file := flags.String("file", os.Getenv("NODES_FILE"), "json, yaml or toml file with the node configs, the NODES env is used if empty")
+	flags.Parse(args[1:])
+
+	nodes, err := config.Load(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = config.Validate(nodes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("config ok: %d nodes\n", len(nodes))
+}
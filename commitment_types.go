@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/lsperrors"
+)
+
+// requestedCommitmentType parses the commitment type a client requested for
+// its channel, and checks that the node can open channels of that type. The
+// empty type leaves the choice to the token.
+func requestedCommitmentType(n *node, s string, private bool) (lightning.CommitmentType, error) {
+	t, err := lightning.ParseCommitmentType(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", lsperrors.ErrInvalidRequest, err)
+	}
+	if t == "" {
+		return "", nil
+	}
+
+	if t == lightning.CommitmentTypeTaproot && !private {
+		return "", fmt.Errorf("%w: taproot channels can only be private", lsperrors.ErrInvalidRequest)
+	}
+
+	supported, err := n.client.SupportsCommitmentType(nil, t)
+	if err != nil {
+		n.logger.Printf("SupportsCommitmentType(%s) error: %v", t, err)
+		return "", lsperrors.Wrap(lsperrors.ErrNodeUnavailable, err)
+	}
+	if !supported {
+		return "", fmt.Errorf("%w: the lsp doesn't open %s channels", lsperrors.ErrInvalidRequest, t)
+	}
+
+	return t, nil
+}
+
+// commitmentTypes returns the commitment types of the channels the node can
+// open, from the most to the least preferred.
+func commitmentTypes(n *node, private bool) []string {
+	var types []string
+	for _, t := range lightning.CommitmentTypes {
+		if t == lightning.CommitmentTypeTaproot && !private {
+			continue
+		}
+
+		supported, err := n.client.SupportsCommitmentType(nil, t)
+		if err != nil {
+			n.logger.Printf("SupportsCommitmentType(%s) error: %v", t, err)
+			continue
+		}
+		if supported {
+			types = append(types, string(t))
+		}
+	}
+
+	return types
+}
@@ -1,16 +1,39 @@
 package config
 
+import (
+	"fmt"
+
+	"github.com/breez/lspd/basetypes"
+)
+
 type NodeConfig struct {
 	// Name of the LSP. If empty, the node's alias will be taken instead.
 	Name string `json:name,omitempty`
 
+	// The LSP operator the node belongs to in multi-tenant mode. Tokens of
+	// the node authenticate rpcs for this tenant, and the notification
+	// subscriptions of its clients are kept apart from those of other
+	// tenants. Lowercase letters, digits, '-' and '_'. Empty is the default
+	// tenant.
+	Tenant string `json:"tenant,omitempty"`
+
 	// The public key of the lightning node.
 	NodePubkey string `json:nodePubkey,omitempty`
 
 	// Hex encoded private key of the LSP. This is used to decrypt traffic from
-	// clients.
+	// clients. Can be a reference to a secret in Vault, like
+	// vault:secret/data/lspd#privateKey, a value encrypted with AWS KMS, like
+	// awskms:<base64 ciphertext>, or an AWS KMS secp256k1 key, like
+	// awskms-key:alias/lspd, that never leaves KMS. A KMS key needs
+	// encryptionKeys, it can only sign.
 	LspdPrivateKey string `json:"lspdPrivateKey"`
 
+	// Keys clients encrypt their requests with, so the encryption key can be
+	// rotated without breaking clients that still use an older key. If empty,
+	// lspdPrivateKey is the only encryption key. lspdPrivateKey keeps signing
+	// the opening fee params either way.
+	EncryptionKeys []*EncryptionKeyConfig `json:"encryptionKeys,omitempty"`
+
 	// Tokens used to authenticate to lspd. These tokens must be unique for each
 	// configured node, so it's obvious which node an rpc call is meant for.
 	Tokens []string `json:"tokens"`
@@ -19,17 +42,26 @@ type NodeConfig struct {
 	// `localhost:10011`
 	Host string `json:"host"`
 
+	// The bitcoin network of the node: mainnet, testnet, signet or regtest.
+	// Only reported to clients in GetLspInfo.
+	Network string `json:"network,omitempty"`
+
 	// Public channel amount is a reserved amount for public channels. If a
 	// zero conf channel is opened, it will never have this exact amount.
 	PublicChannelAmount int64 `json:"publicChannelAmount,string"`
 
 	// The capacity of opened channels through the OpenChannel rpc.
-	ChannelAmount uint64 `json:"channelAmount,string"`
+	ChannelAmount basetypes.Satoshi `json:"channelAmount,string"`
 
 	// Value indicating whether channels opened through the OpenChannel rpc
 	// should be private.
 	ChannelPrivate bool `json:"channelPrivate"`
 
+	// The OpenChannel rpc doesn't open a channel if the client already has
+	// an open or pending channel of at least this capacity, so retried
+	// requests don't open a second channel. Zero means any channel counts.
+	DuplicateChannelThresholdSat uint64 `json:"duplicateChannelThresholdSat,string"`
+
 	// Number of blocks after which an opened channel is considered confirmed.
 	TargetConf uint32 `json:"targetConf,string"`
 
@@ -39,11 +71,17 @@ type NodeConfig struct {
 
 	// Smallest htlc amount routed over channels opened with the OpenChannel
 	// rpc call.
-	MinHtlcMsat uint64 `json:"minHtlcMsat,string"`
+	MinHtlcMsat basetypes.MilliSatoshi `json:"minHtlcMsat,string"`
+
+	// Smallest payment a channel is opened for, because opening channels for
+	// tiny payments is uneconomical. Smaller payments are rejected on
+	// registration, and fail with unknown_next_peer by default if they were
+	// registered before. No minimum if 0.
+	MinPaymentSizeMsat basetypes.MilliSatoshi `json:"minPaymentSizeMsat,string"`
 
 	// The base fee for routing payments over the channel. It is configured on
 	// the node itself, but this value is returned in the ChannelInformation rpc.
-	BaseFeeMsat uint64 `json:"baseFeeMsat,string"`
+	BaseFeeMsat basetypes.MilliSatoshi `json:"baseFeeMsat,string"`
 
 	// The fee rate for routing payments over the channel. It is configured on
 	// the node itself, but this value is returned in the ChannelInformation rpc.
@@ -70,11 +108,808 @@ type NodeConfig struct {
 	// peer is offline.
 	NotificationTimeout string `json:"notificationTimeout,string"`
 
+	// New channel opens are suspended while the estimated chain fee is above
+	// this feerate in sat/vbyte. Zero disables the check.
+	MaxChainFeeSatPerVByte float64 `json:"maxChainFeeSatPerVByte,string"`
+
+	// The size in vbytes of a funding transaction. When set, the minimum
+	// opening fee offered to clients is raised to cover the estimated chain
+	// fee of a funding transaction of this size, if that is higher than the
+	// minimum fee of the opening fee params. Zero disables the check.
+	FundingTxVBytes uint64 `json:"fundingTxVBytes,string"`
+
+	// New channel opens are suspended while the confirmed on-chain balance of
+	// the node is below this amount in satoshi. Zero disables the check.
+	MinOnchainReserveSat basetypes.Satoshi `json:"minOnchainReserveSat,string"`
+
+	// Open simple taproot channels to clients if both the node and the client
+	// signal support for them. Can be overridden per api token.
+	TaprootChannels bool `json:"taprootChannels"`
+
+	// Commitment type of the channels opened to clients: taproot, anchors or
+	// static_remote_key. Takes precedence over taprootChannels. If the client
+	// doesn't support it, the channel is opened with anchors or
+	// static_remote_key. Can be overridden per api token and per request.
+	// Defaults to anchors.
+	CommitmentType string `json:"commitmentType"`
+
+	// Announce the zero conf channels opened for payments to the network,
+	// rather than keeping them private. Public channels are never taproot
+	// channels. Can be overridden per api token.
+	PublicChannels bool `json:"publicChannels"`
+
+	// Reject RegisterPayment calls that are not signed by the destination
+	// node. Signed registrations are verified regardless of this setting.
+	RequireSignedPayments bool `json:"requireSignedPayments"`
+
+	// Allow clients to have lspd generate the payment hash of their invoices
+	// with the GeneratePaymentHash rpc. The client claims the preimage with
+	// the ClaimPreimage rpc once a channel was opened for the payment.
+	PreimageHold bool `json:"preimageHold"`
+
+	// Only log what the interceptor would do with every htlc, like the
+	// channel it would open and the fee it would charge, and always resume
+	// the htlc. Used to validate the configuration against live traffic
+	// before enabling interception.
+	ShadowMode bool `json:"shadowMode"`
+
+	// Minimum number of blocks left before the incoming htlc of a channel
+	// open expires, to open the channel and forward the payment in time.
+	// Htlcs expiring sooner are failed with expiry_too_soon right away rather
+	// than held, so the incoming channel isn't force closed when the held htlc
+	// expires. Disabled if 0.
+	CltvBudget uint32 `json:"cltvBudget,string"`
+
+	// Set this field to fail held htlcs no decision was made about in time,
+	// like while a channel open or a notified client takes too long, rather
+	// than hold them until the incoming channel is force closed. The reason
+	// is recorded in the htlc audit log.
+	LatencyBudget *LatencyBudgetConfig `json:"latencyBudget,omitempty"`
+
+	// Features clients have to signal to get a channel opened for their
+	// payments: 'zero_conf', 'scid_alias' and 'anchors'. Payments to clients
+	// missing any of them are failed before opening, and the client is
+	// notified. Defaults to zero_conf and scid_alias, which the private zero
+	// conf channels lspd opens need. An empty list disables the check.
+	RequiredFeatures []string `json:"requiredFeatures"`
+
+	// What to do with htlcs that were not registered, like keysend payments,
+	// when the channels with the next hop cannot carry them. 'resume'
+	// (default) forwards them as usual, 'fail' fails them right away and
+	// 'open' opens a new channel to the next hop, up to
+	// keysendMaxChannelCapacity.
+	KeysendPolicy string `json:"keysendPolicy"`
+
+	// Maximum capacity in satoshi of channels opened for unregistered htlcs
+	// with the 'open' keysend policy.
+	KeysendMaxChannelCapacity int64 `json:"keysendMaxChannelCapacity,string"`
+
+	// Experimental. Set this field to rebalance channels with trusted peers
+	// using PeerSwap. Only supported on CLN with the peerswap plugin.
+	PeerSwap *PeerSwapConfig `json:"peerSwap,omitempty"`
+
+	// Set this field to fund channels opened for held htlcs with an external
+	// wallet, like a cold wallet or multisig treasury, instead of the node
+	// wallet. Only supported on LND.
+	PsbtFunding *PsbtFundingConfig `json:"psbtFunding,omitempty"`
+
+	// Set this field to splice funds into an existing channel with the
+	// client, instead of opening a second channel, when a registered payment
+	// arrives for a client that already has a channel. Only supported on CLN
+	// with splicing enabled.
+	Splicing *SplicingConfig `json:"splicing,omitempty"`
+
+	// Set this field to contribute to the dual-funded channels clients open
+	// to the node, and to advertise liquidity ads. Channels lspd opens to
+	// clients supporting dual funding are opened dual-funded, so the client
+	// can contribute to them. Only supported on CLN with
+	// experimental-dual-fund enabled.
+	DualFunding *DualFundingConfig `json:"dualFunding,omitempty"`
+
+	// Set this field to select the wallet utxos channel opens and splices are
+	// funded with, so utxos the lsp keeps for other purposes, like its
+	// treasury, aren't spent. Only supported on LND and CLN.
+	CoinControl *CoinControlConfig `json:"coinControl,omitempty"`
+
+	// Set this field to consolidate small utxos of the node wallet into a
+	// single utxo while onchain fees are low, so channel opens don't need
+	// large input sets. Only supported on LND and CLN.
+	Consolidation *ConsolidationConfig `json:"consolidation,omitempty"`
+
+	// Set this field to limit large (wumbo) payments explicitly, rather than
+	// relying on the node defaults.
+	Wumbo *WumboConfig `json:"wumbo,omitempty"`
+
+	// Set this field to connect to clients that are offline when a payment
+	// arrives for them, on the addresses they registered or were last
+	// reached on, before notifying them or failing the payment.
+	Reconnect *ReconnectConfig `json:"reconnect,omitempty"`
+
+	// Set this field to automatically close channels opened by lspd that are
+	// no longer used.
+	ChannelLifecycle *ChannelLifecycleConfig `json:"channelLifecycle,omitempty"`
+
+	// Set this field to guarantee the channels opened by lspd stay open for
+	// a lease period, which clients can renew with RenewChannelLease. Channels
+	// are only closed automatically after their lease expired.
+	ChannelLease *ChannelLeaseConfig `json:"channelLease,omitempty"`
+
+	// Set this field to watch the funding of the channels opened by lspd
+	// until it confirms, recording the confirmed channel and telling the
+	// webhooks registered by the client.
+	FundingConfirmation *FundingConfirmationConfig `json:"fundingConfirmation,omitempty"`
+
+	// Set this field to watch the mempool and the chain for transactions
+	// double spending the unconfirmed funding of the zero-conf channels
+	// opened by lspd. Htlcs over a channel whose funding was double spent
+	// are failed. Requires MEMPOOL_API_BASE_URL.
+	DoubleSpendMonitor *DoubleSpendMonitorConfig `json:"doubleSpendMonitor,omitempty"`
+
+	// Set this field to push critical conditions of the node, like the htlc
+	// interceptor stream being down, an unreachable database, failing
+	// channel opens and a low on-chain balance, to Slack, Telegram or
+	// PagerDuty. Set it in the defaults section to alert about every node.
+	Alerting *AlertingConfig `json:"alerting,omitempty"`
+
+	// Set this field to refund the opening fee of channel opens that failed
+	// after the fee was deducted from the payment, because the funding
+	// transaction never confirmed. The fee is credited to the client and
+	// forwarded to it with its next channel open.
+	Refunds *RefundsConfig `json:"refunds,omitempty"`
+
+	// Set this field to periodically reconcile the forwards of the node with
+	// the channels opened and payments intercepted by lspd, reporting the
+	// revenue per token and client and payments forwarded without the
+	// promised fee.
+	Reconciliation *ReconciliationConfig `json:"reconciliation,omitempty"`
+
+	// Set this field to tune the watchdog of the htlc stream with the node,
+	// which probes the connection when the stream is idle and re-establishes
+	// the stream if the probe fails. Enabled with the defaults if empty.
+	StreamWatchdog *StreamWatchdogConfig `json:"streamWatchdog,omitempty"`
+
+	// The failure to fail htlcs with per scenario, overriding the default
+	// failure code. The scenarios are unknown_payment, below_minimum,
+	// fee_mismatch, liquidity_exhausted, missing_features and
+	// client_offline. Htlcs for
+	// offline clients are resumed by default, so the node fails them with
+	// unknown_next_peer.
+	FailurePolicy map[string]*FailurePolicyConfig `json:"failurePolicy,omitempty"`
+
+	// Forwarding policy set on the channels opened by lspd, by token. Keys
+	// are tokens of the node or names of tokens stored in the database, the
+	// "default" policy applies to all other tokens. Fields a policy doesn't
+	// set are taken from the node settings. The fees and time lock delta of
+	// the policy are returned to clients of the token in ChannelInformation.
+	// Channels of tokens without a policy keep the defaults of the node.
+	ChannelPolicies map[string]*ChannelPolicyConfig `json:"channelPolicies,omitempty"`
+
+	// Formulas computing the capacity of the channels opened for a payment
+	// from the payment amount, by token. Keys are tokens of the node or names
+	// of tokens stored in the database, the "default" formula applies to all
+	// other tokens. Tokens without a formula get the payment amount plus the
+	// additional channel capacity. The wumbo limits apply to the result.
+	CapacityFormulas map[string]*CapacityFormulaConfig `json:"capacityFormulas,omitempty"`
+
+	// Quotas of the channels opened per day and per week, by token. Keys are
+	// tokens of the node or names of tokens stored in the database, the
+	// "default" quota applies to all other tokens, counted separately for
+	// every token. Payments exceeding the quota of their token are failed,
+	// and OpenChannel requests are denied.
+	Quotas map[string]*QuotaConfig `json:"quotas,omitempty"`
+
+	// Priority tiers clients can request when registering a payment, by
+	// name: economy, normal or urgent. The tier sets the feerate of the
+	// funding transaction of the channel opened for the payment, and the fee
+	// charged for it on top of the opening fee. The tiers are returned to
+	// clients in ChannelInformation. Payments registered without a tier use
+	// the fee priority of lspd and pay the opening fee only.
+	PriorityTiers map[string]*PriorityTierConfig `json:"priorityTiers,omitempty"`
+
+	// Set this field to have an external service decide on channel opens,
+	// for example to score the risk of a payment. lspd posts the context of
+	// every htlc it is about to open a channel for to the service, which
+	// allows or denies the open, and may lower the opening fee.
+	PolicyHook *PolicyHookConfig `json:"policyHook,omitempty"`
+
+	// Set this field to serve LSPS0, the json-rpc transport of the LSP
+	// specifications, over custom messages of the node. LND nodes have to
+	// allow custom message type 37913 with protocol.custom-message. CLN nodes
+	// need an up to date cln plugin.
+	Lsps0 *Lsps0Config `json:"lsps0,omitempty"`
+
+	// Quirks of wallet sdk versions, by the client agent clients register
+	// payments with. Payments to clients are handled according to the first
+	// entry matching their agent.
+	ClientQuirks []*ClientQuirksConfig `json:"clientQuirks,omitempty"`
+
+	// Set this field to support clients receiving on hold invoices, which may
+	// not settle htlcs forwarded to them for a long time. The htlcs in flight
+	// to every client are tracked, and htlcs exceeding the limit of the
+	// client are failed with temporary_channel_failure.
+	Hodl *HodlConfig `json:"hodl,omitempty"`
+
+	// Set this field to limit the amount in flight over channels that are
+	// not confirmed yet, which the node loses if the funding transaction is
+	// double spent. Htlcs exceeding the limits are failed with
+	// temporary_channel_failure until the funding confirms or htlcs resolve.
+	ZeroConfExposure *ZeroConfExposureConfig `json:"zeroConfExposure,omitempty"`
+
+	// Set this field to score clients by their behavior: payments a channel
+	// was opened for that never settled, channels the client closed soon
+	// after the open, and probes. Clients whose score drops below the
+	// minimum are denied service.
+	Scoring *ScoringConfig `json:"scoring,omitempty"`
+
+	// Set this field to have clients solve a proof of work or captcha
+	// challenge, returned by ChannelInformation, before OpenChannel. Meant
+	// for public tokens, shipped in apps, which anyone could use to drain
+	// the on-chain funds of the lsp with channels to sybil nodes.
+	OpenChannelChallenge *ChallengeConfig `json:"openChannelChallenge,omitempty"`
+
+	// Set this field to limit the rate of the rpcs of every token of the
+	// node. Requests over the limit fail with RATE_LIMITED.
+	RpcRateLimit *RpcRateLimitConfig `json:"rpcRateLimit,omitempty"`
+
+	// Set this field to record every decision about an intercepted htlc in
+	// the append-only htlc audit log, queryable with the admin api, to
+	// resolve disputes with wallet partners.
+	AuditLog *AuditLogConfig `json:"auditLog,omitempty"`
+
 	// Set this field to connect to an LND node.
 	Lnd *LndConfig `json:"lnd,omitempty"`
 
 	// Set this field to connect to a CLN node.
 	Cln *ClnConfig `json:"cln,omitempty"`
+
+	// Set this field to run on an embedded LDK node, driven through a sidecar
+	// process. Experimental.
+	Ldk *LdkConfig `json:"ldk,omitempty"`
+}
+
+// Label returns a short identifier for the node, used to attribute log lines,
+// metrics and database rows to the node in multi-node deployments.
+func (c *NodeConfig) Label() string {
+	pubkey := c.NodePubkey
+	if len(pubkey) > 8 {
+		pubkey = pubkey[:8]
+	}
+
+	if c.Name == "" {
+		return pubkey
+	}
+
+	if pubkey == "" {
+		return c.Name
+	}
+
+	return fmt.Sprintf("%s/%s", c.Name, pubkey)
+}
+
+type LatencyBudgetConfig struct {
+	// Number of blocks that have to be left before the incoming htlc
+	// expires when the decision is made. Blocks are estimated to be 10
+	// minutes apart. Defaults to 6.
+	SafetyBlocks uint32 `json:"safetyBlocks,string"`
+
+	// Maximum time to make a decision, however far the htlc expires, like
+	// '5m'. Empty means the time is only bounded by the expiry.
+	MaxDuration string `json:"maxDuration"`
+}
+
+type EncryptionKeyConfig struct {
+	// Identifier of the key, returned to clients along with its public key.
+	ID string `json:"id"`
+
+	// Hex encoded private key. Can be a reference to a secret in Vault or a
+	// value encrypted with AWS KMS, like lspdPrivateKey.
+	PrivateKey string `json:"privateKey"`
+
+	// Time from which the key is handed out to clients, in RFC3339 format.
+	// The most recent active key is handed out. Empty means always.
+	ActiveFrom string `json:"activeFrom,omitempty"`
+
+	// Time from which requests encrypted with the key are rejected, in
+	// RFC3339 format. Empty means never.
+	RetireAt string `json:"retireAt,omitempty"`
+}
+
+type PeerSwapConfig struct {
+	// Pubkeys of the peers swaps are done with. Channels with other peers are
+	// never rebalanced.
+	AllowedPeers []string `json:"allowedPeers"`
+
+	// A channel is swapped out when the local balance exceeds this fraction
+	// of the channel capacity, e.g. 0.7.
+	MaxLocalRatio float64 `json:"maxLocalRatio,string"`
+
+	// Amount to swap out per swap in satoshi.
+	SwapAmountSat basetypes.Satoshi `json:"swapAmountSat,string"`
+
+	// Only swap out while the available on-chain balance is below this
+	// amount in satoshi. Zero swaps regardless of the on-chain balance.
+	TargetOnchainSat basetypes.Satoshi `json:"targetOnchainSat,string"`
+
+	// Asset to swap to, btc or lbtc. Defaults to btc.
+	Asset string `json:"asset"`
+
+	// Interval between rebalance checks, e.g. 10m. Defaults to 10m.
+	Interval string `json:"interval"`
+
+	// Minimum time between swaps on the same channel, e.g. 24h. Defaults to
+	// 24h.
+	Cooldown string `json:"cooldown"`
+}
+
+type PsbtFundingConfig struct {
+	// Maximum time to wait for the signed funding psbt, e.g. 2m. The htlcs
+	// the channel is opened for are held in the meantime, so keep this well
+	// below the htlc expiry. Defaults to 2m.
+	SignTimeout string `json:"signTimeout"`
+}
+
+type SplicingConfig struct {
+	// Maximum time to hold the htlcs until the spliced funds can be used,
+	// e.g. 2m. If the splice needs confirmations first, the htlcs are failed
+	// after this time and the payment succeeds once the sender retries.
+	// Defaults to 2m.
+	Timeout string `json:"timeout"`
+}
+
+type DualFundingConfig struct {
+	// How much the node contributes to channels clients open: "match" a
+	// percentage of the client's funds, a percentage of the "available"
+	// funds, or a "fixed" amount in satoshi, set with policyMod. Defaults to
+	// the policy of the node.
+	Policy    string `json:"policy"`
+	PolicyMod uint64 `json:"policyMod,string"`
+
+	// Only contribute to channels of clients buying a lease.
+	LeasesOnly bool `json:"leasesOnly"`
+
+	// Limits of the client's funds the node contributes to channels with,
+	// and of the contribution of the node per channel, in satoshi. Zero
+	// means no limit.
+	MinClientFundingSat uint64 `json:"minClientFundingSat,string"`
+	MaxClientFundingSat uint64 `json:"maxClientFundingSat,string"`
+	PerChannelMaxSat    uint64 `json:"perChannelMaxSat,string"`
+
+	// Funds in satoshi the node keeps in its wallet rather than contribute.
+	ReserveTankSat uint64 `json:"reserveTankSat,string"`
+
+	// Set this field to advertise liquidity for lease as a liquidity ad.
+	LiquidityAds *LiquidityAdsConfig `json:"liquidityAds,omitempty"`
+}
+
+type CoinControlConfig struct {
+	// The lnd wallet account channels are funded from, instead of the
+	// default account. The account has to be managed by lnd, watch-only
+	// accounts can't sign. Only supported on LND.
+	Account string `json:"account"`
+
+	// Minimum number of confirmations of the funding inputs. Applies to all
+	// channel opens, including zero conf channel opens, for which it
+	// overrides minConfs. Defaults to minConfs, or 1.
+	MinInputConfs *uint32 `json:"minInputConfs,string"`
+
+	// Utxos that are never used to fund channels, as txid:vout, like the
+	// utxos of the treasury. Utxos reserved on CLN with reserveinputs, and
+	// leased on LND with leaseoutput, aren't used either.
+	ExcludeUtxos []string `json:"excludeUtxos"`
+
+	// Minimum change in satoshi the funding inputs leave after the channel
+	// capacity and the onchain fee, so the funding transaction has a change
+	// output to bump its fee with CPFP. Zero allows funding transactions
+	// without change output.
+	MinChangeSat uint64 `json:"minChangeSat,string"`
+}
+
+type ConsolidationConfig struct {
+	// Only consolidate while the estimated feerate is at or below this
+	// feerate in sat/vbyte. Required.
+	MaxFeeSatPerVByte float64 `json:"maxFeeSatPerVByte,string"`
+
+	// Fee priority the feerate is estimated with: fastest, halfhour, hour,
+	// economy or minimum. Defaults to economy.
+	FeePriority string `json:"feePriority"`
+
+	// Utxos up to this value in satoshi are consolidated. Defaults to
+	// 1000000.
+	MaxUtxoSat uint64 `json:"maxUtxoSat,string"`
+
+	// Only consolidate when the wallet has at least this many small utxos.
+	// Defaults to 10.
+	MinUtxos int `json:"minUtxos,string"`
+
+	// Maximum number of utxos consolidated in a single transaction. Defaults
+	// to 100.
+	MaxInputs int `json:"maxInputs,string"`
+
+	// Interval between checks of the wallet and the feerate, e.g. 1h.
+	// Defaults to 1h.
+	Interval string `json:"interval"`
+
+	// Only consolidate within this daily time window in UTC, like
+	// '01:00-05:00'. A window ending before it starts spans midnight.
+	// Defaults to any time of the day.
+	Window string `json:"window"`
+}
+
+type LiquidityAdsConfig struct {
+	// The lease fee is leaseFeeBaseSat, plus leaseFeeBasis basis points of
+	// the leased amount, plus the onchain fee of fundingWeight at the
+	// feerate of the funding transaction.
+	LeaseFeeBaseSat uint64 `json:"leaseFeeBaseSat,string"`
+	LeaseFeeBasis   uint32 `json:"leaseFeeBasis"`
+	FundingWeight   uint32 `json:"fundingWeight"`
+
+	// Maximum routing fees charged over a leased channel for the duration
+	// of the lease.
+	ChannelFeeMaxBaseMsat                uint32 `json:"channelFeeMaxBaseMsat"`
+	ChannelFeeMaxProportionalThousandths uint32 `json:"channelFeeMaxProportionalThousandths"`
+}
+
+type ReconnectConfig struct {
+	// Number of connection attempts to every known address of the client.
+	// Defaults to 3.
+	Attempts int `json:"attempts,string"`
+
+	// Time to wait after the first round of attempts, doubled after every
+	// round, e.g. 500ms. Defaults to 500ms.
+	Backoff string `json:"backoff"`
+
+	// Maximum time spent connecting to the client, e.g. 10s. Defaults to 10s.
+	Timeout string `json:"timeout"`
+}
+
+type WumboConfig struct {
+	// Largest htlc in millisatoshi forwarded to clients over channels opened
+	// by lspd. It is also set as the max_htlc of the channel policy of these
+	// channels. Zero disables the limit.
+	MaxHtlcMsat basetypes.MilliSatoshi `json:"maxHtlcMsat,string"`
+
+	// Largest channel in satoshi opened for a payment. Channels above
+	// 16777215 sat are only opened to clients that signal support for large
+	// channels, smaller channels otherwise. Zero disables the limit.
+	MaxChannelCapacitySat basetypes.Satoshi `json:"maxChannelCapacitySat,string"`
+
+	// How payments above maxHtlcMsat are handled. 'mpp' (default) accepts
+	// their registration and fails htlcs above maxHtlcMsat, so the sender
+	// splits the payment. 'reject' rejects their registration.
+	SplitPolicy string `json:"splitPolicy"`
+}
+
+type ChannelLifecycleConfig struct {
+	// Channels without payments for this duration are closed, e.g. 2160h.
+	// Defaults to maxInactiveDuration, which is promised to clients in the
+	// opening fee params.
+	MaxInactive string `json:"maxInactive"`
+
+	// Channels that never had a payment after the initial one are closed
+	// after this duration, e.g. 720h. Defaults to maxInactive.
+	MaxUnused string `json:"maxUnused"`
+
+	// Pubkeys of clients whose channels are never closed automatically.
+	GraceList []string `json:"graceList"`
+
+	// Interval between checks, e.g. 1h. Defaults to 1h.
+	Interval string `json:"interval"`
+
+	// Only log the channels that would be closed.
+	DryRun bool `json:"dryRun"`
+}
+
+type ChannelLeaseConfig struct {
+	// Time a channel is guaranteed to stay open after it was opened or its
+	// lease was renewed, e.g. 2160h for 90 days.
+	Duration string `json:"duration"`
+
+	// Time before the lease expires the webhooks of the client are told, e.g.
+	// 168h. Defaults to 168h.
+	NotifyBefore string `json:"notifyBefore"`
+
+	// Fee in millisatoshi for renewing a lease, plus renewalFeePpm of the
+	// channel capacity. Deducted from the renewal payment.
+	RenewalFeeMsat uint64 `json:"renewalFeeMsat,string"`
+	RenewalFeePpm  uint32 `json:"renewalFeePpm"`
+
+	// Interval between checks of the leases, e.g. 1h. Defaults to 1h.
+	Interval string `json:"interval"`
+}
+
+type RefundsConfig struct {
+	// Time after the open a channel whose funding transaction didn't
+	// confirm is considered failed, e.g. 336h. Defaults to 336h.
+	FailAfter string `json:"failAfter"`
+
+	// Interval between checks for failed channel opens, e.g. 1h. Defaults
+	// to 1h.
+	Interval string `json:"interval"`
+}
+
+type ChannelPolicyConfig struct {
+	// Base fee in millisatoshi. Defaults to baseFeeMsat of the node.
+	BaseFeeMsat *uint64 `json:"baseFeeMsat,omitempty"`
+
+	// Proportional fee, e.g. 0.000001 for 1 ppm. Defaults to feeRate of the
+	// node.
+	FeeRate *float64 `json:"feeRate,omitempty"`
+
+	// Cltv delta of the channel. Defaults to the time lock delta of the
+	// token. Not set on CLN channels, which all use the cltv delta of the
+	// node.
+	TimeLockDelta *uint32 `json:"timeLockDelta,omitempty"`
+
+	// Smallest htlc forwarded over the channel, in millisatoshi. Zero keeps
+	// the minimum of the node.
+	MinHtlcMsat uint64 `json:"minHtlcMsat,string"`
+
+	// Largest htlc forwarded over the channel, in millisatoshi. Zero defaults
+	// to wumbo.maxHtlcMsat, or the maximum of the node if that is not set.
+	MaxHtlcMsat uint64 `json:"maxHtlcMsat,string"`
+}
+
+// Variables that can be used in capacity formula expressions.
+var CapacityFormulaVars = []string{"payment", "additional"}
+
+type QuotaConfig struct {
+	// Channels opened per day, counted in UTC days. Zero is unlimited.
+	DailyOpens int64 `json:"dailyOpens,string"`
+
+	// Channels opened in the last 7 days, including today. Zero is
+	// unlimited.
+	WeeklyOpens int64 `json:"weeklyOpens,string"`
+
+	// Total capacity in satoshi of the channels opened per day. Zero is
+	// unlimited.
+	DailySat int64 `json:"dailySat,string"`
+
+	// Total capacity in satoshi of the channels opened in the last 7 days,
+	// including today. Zero is unlimited.
+	WeeklySat int64 `json:"weeklySat,string"`
+}
+
+type CapacityFormulaConfig struct {
+	// Arithmetic expression of the capacity in satoshi, e.g.
+	// "min(max(payment * 2, 100000), 5000000)". The variables are payment,
+	// the incoming payment amount in satoshi, and additional, the additional
+	// channel capacity of the token. Supports + - * /, parentheses, min and
+	// max.
+	Expression string `json:"expression,omitempty"`
+
+	// Capacity by payment amount, instead of an expression. A payment gets
+	// the capacity of the first tier it fits in.
+	Tiers []*CapacityTierConfig `json:"tiers,omitempty"`
+}
+
+type CapacityTierConfig struct {
+	// Largest payment of the tier in satoshi. Zero matches any payment.
+	UpToSat int64 `json:"upToSat,string"`
+
+	// Capacity of the channel in satoshi.
+	CapacitySat int64 `json:"capacitySat,string"`
+}
+
+type PriorityTierConfig struct {
+	// Fee priority of the funding transaction: fastest, halfhour, hour,
+	// economy or minimum. Defaults to the fee priority of lspd.
+	FeePriority string `json:"feePriority"`
+
+	// Fee in millisatoshi charged on top of the opening fee. The difference
+	// between the incoming and outgoing amount of the payment must cover
+	// both.
+	ExtraFeeMsat uint64 `json:"extraFeeMsat,string"`
+}
+
+type FundingConfirmationConfig struct {
+	// Interval between checks of the unconfirmed channels, e.g. 1m. Defaults
+	// to 1m.
+	Interval string `json:"interval"`
+}
+
+type DoubleSpendMonitorConfig struct {
+	// Interval between checks of the unconfirmed channels, e.g. 30s.
+	// Defaults to 30s.
+	Interval string `json:"interval"`
+
+	// Url double spends are posted to as json, to alert the operator.
+	// Optional.
+	WebhookURL string `json:"webhookUrl,omitempty"`
+}
+
+type AlertingConfig struct {
+	// Interval between checks of the alert conditions, e.g. 1m. Defaults to
+	// 1m.
+	Interval string `json:"interval"`
+
+	// Alert when the confirmed on-chain balance drops below this amount in
+	// satoshi. Defaults to minOnchainReserveSat plus
+	// additionalChannelCapacity, the balance the smallest channel open
+	// needs.
+	MinOnchainBalanceSat uint64 `json:"minOnchainBalanceSat,string"`
+
+	// Alert when this many channel opens failed within openFailureWindow.
+	// Defaults to 3.
+	MaxOpenFailures int `json:"maxOpenFailures,string"`
+
+	// Window the failed channel opens are counted in, e.g. 1h. Defaults to
+	// 1h.
+	OpenFailureWindow string `json:"openFailureWindow"`
+
+	// The sinks alerts are pushed to. At least one is required.
+	Slack     *SlackAlertConfig     `json:"slack,omitempty"`
+	Telegram  *TelegramAlertConfig  `json:"telegram,omitempty"`
+	PagerDuty *PagerDutyAlertConfig `json:"pagerDuty,omitempty"`
+}
+
+type SlackAlertConfig struct {
+	// Url of the incoming webhook of the Slack channel.
+	WebhookURL string `json:"webhookUrl"`
+}
+
+type TelegramAlertConfig struct {
+	// Token of the bot that posts the alerts, as given by BotFather.
+	BotToken string `json:"botToken"`
+
+	// Chat the alerts are posted to. The bot has to be a member.
+	ChatID string `json:"chatId"`
+}
+
+type PagerDutyAlertConfig struct {
+	// Integration key of the Events API v2 integration of the service.
+	RoutingKey string `json:"routingKey"`
+}
+
+type ReconciliationConfig struct {
+	// Interval between reconciliations, e.g. 1h. Defaults to 1h.
+	Interval string `json:"interval"`
+
+	// Period of forwards reconciled, e.g. 168h. Defaults to 168h.
+	Lookback string `json:"lookback"`
+
+	// Time after a channel was opened within which the forwards of the
+	// payment it was opened for are expected, e.g. 1h. Defaults to 1h.
+	SettleWindow string `json:"settleWindow"`
+}
+
+type ScoringConfig struct {
+	// Clients with a score below this are denied service. Scores start at
+	// 100 and drop by the penalty of every event within the memory.
+	MinScore float64 `json:"minScore,string"`
+
+	// Penalty for a payment a channel was opened for that didn't settle
+	// within the settle window of the reconciliation. Only counted if
+	// reconciliation is enabled. Zero uses the default of 10.
+	UnsettledPenalty float64 `json:"unsettledPenalty,string"`
+
+	// Penalty for a channel the client closed within the early close window
+	// after it was opened. Zero uses the default of 25.
+	EarlyClosePenalty float64 `json:"earlyClosePenalty,string"`
+
+	// Penalty for every payment of the client that was probed. Probes are
+	// usually sent by the payer, so they are only counted by default.
+	ProbePenalty float64 `json:"probePenalty,string"`
+
+	// Channels closed within this time after they were opened count as
+	// early closes, e.g. 72h. Defaults to 72h.
+	EarlyCloseWindow string `json:"earlyCloseWindow"`
+
+	// Events older than this no longer count towards the score, e.g. 720h.
+	// Defaults to 720h.
+	Memory string `json:"memory"`
+
+	// Interval between checks for channels closed by clients, e.g. 10m.
+	// Defaults to 10m.
+	Interval string `json:"interval"`
+
+	// Set this field to score clients without denying service.
+	DryRun bool `json:"dryRun"`
+}
+
+type HodlConfig struct {
+	// Maximum amount in flight to a single client over channels of the node,
+	// in millisatoshi. 0 means unlimited, the htlcs are only tracked.
+	MaxClientInflightMsat uint64 `json:"maxClientInflightMsat,string"`
+
+	// Interval between checks for htlcs in flight that were resolved, e.g.
+	// 1m. Defaults to 1m.
+	CheckInterval string `json:"checkInterval"`
+}
+
+type ZeroConfExposureConfig struct {
+	// Maximum amount in flight over all unconfirmed channels of the node, in
+	// millisatoshi. 0 means unlimited.
+	MaxInflightMsat uint64 `json:"maxInflightMsat,string"`
+
+	// Maximum amount in flight over the unconfirmed channels with a single
+	// client, in millisatoshi. 0 means unlimited.
+	MaxClientInflightMsat uint64 `json:"maxClientInflightMsat,string"`
+}
+
+type FailurePolicyConfig struct {
+	// Bolt 4 failure code: temporary_channel_failure, temporary_node_failure,
+	// permanent_channel_failure, permanent_node_failure, unknown_next_peer
+	// or incorrect_or_unknown_payment_details.
+	Code string `json:"code"`
+
+	// Hex encoded failure message, starting with the failure code, returned
+	// instead of the code. Only supported on CLN.
+	Message string `json:"message,omitempty"`
+}
+
+type StreamWatchdogConfig struct {
+	// Time the htlc stream may be idle before the connection is probed, e.g.
+	// 1m. Defaults to 1m. Set to 0 to disable the watchdog.
+	IdleTimeout string `json:"idleTimeout"`
+
+	// Time the probe has to succeed, e.g. 10s. Defaults to 10s.
+	ProbeTimeout string `json:"probeTimeout"`
+}
+
+type ChallengeConfig struct {
+	// Tokens of the node, or names of tokens stored in the database, whose
+	// OpenChannel requests need a solved challenge. All tokens if empty.
+	Tokens []string `json:"tokens"`
+
+	// Number of leading zero bits of the proof of work. Every bit doubles
+	// the work of the client. Defaults to 20.
+	Difficulty uint32 `json:"difficulty,string"`
+
+	// Time a challenge is valid, e.g. 10m. Defaults to 10m.
+	Expiry string `json:"expiry"`
+
+	// Set these fields to accept a solved captcha instead of the proof of
+	// work. The captcha token of the client is posted with the secret to
+	// the siteverify url of the provider, e.g.
+	// https://hcaptcha.com/siteverify or
+	// https://challenges.cloudflare.com/turnstile/v0/siteverify. The site
+	// key is returned to clients in ChannelInformation.
+	CaptchaVerifyURL string `json:"captchaVerifyUrl"`
+	CaptchaSiteKey   string `json:"captchaSiteKey"`
+	CaptchaSecret    string `json:"captchaSecret"`
+}
+
+type RpcRateLimitConfig struct {
+	// Average number of rpcs per second a token may make, e.g. 0.5.
+	RequestsPerSecond float64 `json:"requestsPerSecond,string"`
+
+	// Number of rpcs a token may make at once before it is limited to the
+	// average. Defaults to 10.
+	Burst int `json:"burst,string"`
+}
+
+type PolicyHookConfig struct {
+	// Url lspd posts the interception context to as json. The service
+	// responds with a json object like {"decision": "deny", "reason": "..."}
+	// or {"decision": "allow", "fee_msat": "1000"}.
+	URL string `json:"url"`
+
+	// Time the service has to decide, e.g. 2s. Defaults to 2s.
+	Timeout string `json:"timeout"`
+
+	// Open the channel if the service fails or times out. By default the
+	// htlc is failed.
+	FailOpen bool `json:"failOpen"`
+}
+
+type ClientQuirksConfig struct {
+	// Prefix of the client agents the quirks apply to, e.g. 'breez-sdk/0.2.'
+	// for all 0.2 versions of the breez sdk.
+	Agent string `json:"agent"`
+
+	// The client rejects htlcs of which the total_msat in the onion doesn't
+	// match the amount of its invoice. lnd rewrites the onion for every
+	// client, cln can't, so on cln channels are not opened for these clients.
+	NeedsTotalMsatRewrite bool `json:"needsTotalMsatRewrite"`
+
+	// The client signals option_scid_alias, but can't handle channels with
+	// an alias scid, so it can't use the channels lspd opens.
+	NoAliasScid bool `json:"noAliasScid"`
+
+	// Maximum number of blocks until the expiry of htlcs the client accepts.
+	// Htlcs expiring later are failed with expiry_too_far before opening a
+	// channel. 0 means no maximum.
+	MaxCltvDelta uint32 `json:"maxCltvDelta,string"`
 }
 
 type LndConfig struct {
@@ -84,8 +919,34 @@ type LndConfig struct {
 	// tls cert for the grpc api.
 	Cert string `json:"cert"`
 
-	// macaroon to use.
+	// macaroon to use, hex encoded. Can be a reference to a secret in Vault or
+	// a value encrypted with AWS KMS, like lspdPrivateKey. The secret is
+	// fetched again when it's cached longer than SECRETS_CACHE_TTL, so a
+	// macaroon rotated in Vault is used without a restart.
 	Macaroon string `json:"macaroon"`
+
+	// Only intercept htlcs to scids lspd knows: the fake scids lspd assigned
+	// to registered payments and the aliases of the channels lspd opened. All
+	// other htlcs are resumed right away, without asking lspd, which reduces
+	// the latency of forwards on busy routing nodes. Htlcs to other scids,
+	// like fake scids generated by clients, are not intercepted, so don't set
+	// this while clients depend on those.
+	InterceptLspdScidsOnly bool `json:"interceptLspdScidsOnly"`
+}
+
+type Lsps0Config struct {
+	// Maximum number of requests of a single client handled at the same
+	// time. Further requests of the client are rejected until one completes.
+	// Defaults to 5.
+	MaxConcurrentRequests int `json:"maxConcurrentRequests"`
+
+	// Time to handle a request, e.g. 30s. Defaults to 30s.
+	RequestTimeout string `json:"requestTimeout"`
+}
+
+type AuditLogConfig struct {
+	// Records older than this are deleted, e.g. 2160h. Defaults to 2160h.
+	Retention string `json:"retention"`
 }
 
 type ClnConfig struct {
@@ -93,6 +954,45 @@ type ClnConfig struct {
 	PluginAddress string `json:"pluginAddress"`
 
 	// File path to the cln lightning-roc socket file. Find the path in
-	// cln-dir/mainnet/lightning-rpc
+	// cln-dir/mainnet/lightning-rpc. Alternatively a tcp address in the form
+	// tcp://host:port, where the socket is forwarded to, for example with
+	// socat, when cln runs in a container or VM.
 	SocketPath string `json:"socketPath"`
+
+	// Maximum time the cln plugin holds an htlc while a channel is opened for
+	// it, like '5m'. The plugin fails the htlc itself if lspd didn't resolve it
+	// by then, for example because lspd went away. Defaults to 5m.
+	HoldTimeout string `json:"holdTimeout"`
+
+	// Number of connections to the json-rpc socket of cln. Defaults to 4.
+	RpcPoolSize int `json:"rpcPoolSize,string"`
+
+	// Timeout of rpc calls that read from cln, like '10s'. Calls that change
+	// state, like opening a channel, time out after 60s. Defaults to 10s.
+	RpcTimeout string `json:"rpcTimeout"`
+
+	// Number of retries of reading rpc calls that timed out or lost their
+	// connection. Defaults to 2.
+	RpcRetries *int `json:"rpcRetries,string"`
+
+	// Time the peer of a short channel id is cached for resolving the next
+	// hop of htlcs, like '10m'. The cache is kept up to date with the channel
+	// events of the cln plugin, the ttl bounds staleness when events are
+	// missed. '0' disables the cache. Defaults to 10m.
+	ScidCacheTtl string `json:"scidCacheTtl"`
+}
+
+type LdkConfig struct {
+	// Base url of the http api of the sidecar running the ldk-node, like
+	// http://127.0.0.1:3536.
+	Address string `json:"address"`
+
+	// Key sent in the X-Api-Key header of every request, if the sidecar
+	// requires one.
+	ApiKey string `json:"apiKey"`
+
+	// Timeout of api calls that read from the node, like '10s'. Calls that
+	// change state, like opening a channel, time out after 60s. Defaults to
+	// 10s.
+	RpcTimeout string `json:"rpcTimeout"`
 }
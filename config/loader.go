@@ -0,0 +1,407 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Prefix of the env variables overriding settings of the configured nodes,
+// like NODES_0_FEE_RATE for the feeRate of the first node, or
+// NODES_1_LND_MACAROON for the macaroon in the lnd settings of the second
+// node.
+const envOverridePrefix = "NODES_"
+
+// Defaults of settings that are used when neither the node nor the defaults
+// section of the config file sets them.
+var builtinDefaults = map[string]interface{}{
+	"notificationTimeout": "1m",
+	"keysendPolicy":       "resume",
+}
+
+// The layout of a config file. Json files can also contain only the list of
+// nodes, like the NODES env.
+type fileConfig struct {
+	// Settings applied to every node that doesn't set them itself.
+	Defaults map[string]interface{} `json:"defaults" yaml:"defaults" toml:"defaults"`
+
+	Nodes []map[string]interface{} `json:"nodes" yaml:"nodes" toml:"nodes"`
+}
+
+// Load reads the node configs from the json, yaml or toml file at path, or
+// from the json in the NODES env if path is empty. Settings in the defaults
+// section of the file and the NODES_<index>_<SETTING> env variables are
+// applied to the nodes.
+func Load(path string) ([]*NodeConfig, error) {
+	if path == "" {
+		return parseNodes([]byte(os.Getenv("NODES")), "json", os.Environ())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var format string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = "json"
+	case ".yaml", ".yml":
+		format = "yaml"
+	case ".toml":
+		format = "toml"
+	default:
+		return nil, fmt.Errorf("unknown config file format '%s', use .json, .yaml or .toml", filepath.Ext(path))
+	}
+
+	nodes, err := parseNodes(data, format, os.Environ())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nodes, nil
+}
+
+func parseNodes(data []byte, format string, environ []string) ([]*NodeConfig, error) {
+	var file fileConfig
+	var err error
+	switch format {
+	case "json":
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		if t := bytes.TrimSpace(data); len(t) > 0 && t[0] == '[' {
+			err = d.Decode(&file.Nodes)
+		} else {
+			err = d.Decode(&file)
+		}
+	case "yaml":
+		err = yaml.Unmarshal(data, &file)
+	case "toml":
+		err = toml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", format, err)
+	}
+
+	for i, n := range file.Nodes {
+		if n == nil {
+			n = make(map[string]interface{})
+			file.Nodes[i] = n
+		}
+
+		for k, v := range file.Defaults {
+			if _, ok := n[lookupKey(n, k)]; !ok {
+				n[k] = v
+			}
+		}
+
+		for k, v := range builtinDefaults {
+			if _, ok := n[lookupKey(n, k)]; !ok {
+				n[k] = v
+			}
+		}
+	}
+
+	err = applyEnvOverrides(file.Nodes, environ)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []*NodeConfig
+	for i, n := range file.Nodes {
+		normalized, err := normalize(n, reflect.TypeOf(NodeConfig{}))
+		if err != nil {
+			return nil, fmt.Errorf("node %d: %w", i, err)
+		}
+
+		b, err := json.Marshal(normalized)
+		if err != nil {
+			return nil, fmt.Errorf("node %d: %w", i, err)
+		}
+
+		var node NodeConfig
+		err = json.Unmarshal(b, &node)
+		if err != nil {
+			return nil, fmt.Errorf("node %d: %w", i, err)
+		}
+
+		nodes = append(nodes, &node)
+	}
+
+	return nodes, nil
+}
+
+// applyEnvOverrides sets the settings in the NODES_<index>_<SETTING> env
+// variables on the nodes. Nested settings are addressed by joining the names
+// with an underscore, like NODES_0_LND_MACAROON.
+func applyEnvOverrides(nodes []map[string]interface{}, environ []string) error {
+	// Apply the overrides in a fixed order, so the result doesn't depend on
+	// the order of the environment.
+	sort.Strings(environ)
+	for _, e := range environ {
+		name, value, ok := strings.Cut(e, "=")
+		if !ok || !strings.HasPrefix(name, envOverridePrefix) {
+			continue
+		}
+
+		index, setting, ok := strings.Cut(strings.TrimPrefix(name, envOverridePrefix), "_")
+		if !ok {
+			continue
+		}
+
+		i, err := strconv.Atoi(index)
+		if err != nil {
+			continue
+		}
+
+		if i < 0 || i >= len(nodes) {
+			return fmt.Errorf("%s: there is no node %d", name, i)
+		}
+
+		path, err := settingPath(reflect.TypeOf(NodeConfig{}), strings.Split(strings.ToLower(setting), "_"))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		m := nodes[i]
+		for _, key := range path[:len(path)-1] {
+			key = lookupKey(m, key)
+			next, ok := m[key].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				m[key] = next
+			}
+			m = next
+		}
+
+		// Lists and objects can be set as json.
+		var v interface{} = value
+		if t := strings.TrimSpace(value); strings.HasPrefix(t, "[") || strings.HasPrefix(t, "{") {
+			var parsed interface{}
+			if json.Unmarshal([]byte(t), &parsed) == nil {
+				v = parsed
+			}
+		}
+		m[lookupKey(m, path[len(path)-1])] = v
+	}
+
+	return nil
+}
+
+// lookupKey returns the key in m matching the json name case-insensitively,
+// or the name if there is none.
+func lookupKey(m map[string]interface{}, name string) string {
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+
+	return name
+}
+
+// settingPath returns the json names of the nested settings addressed by the
+// lowercased words of an env variable name.
+func settingPath(t reflect.Type, words []string) ([]string, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unknown setting '%s'", strings.Join(words, "_"))
+	}
+
+	// Match the longest run of words first, so FEE_RATE matches feeRate
+	// rather than a setting named fee.
+	for n := len(words); n > 0; n-- {
+		f, ok := fieldByName(t, strings.Join(words[:n], ""))
+		if !ok {
+			continue
+		}
+
+		if n == len(words) {
+			return []string{jsonName(f)}, nil
+		}
+
+		rest, err := settingPath(f.Type, words[n:])
+		if err != nil {
+			continue
+		}
+
+		return append([]string{jsonName(f)}, rest...), nil
+	}
+
+	return nil, fmt.Errorf("unknown setting '%s'", strings.Join(words, "_"))
+}
+
+// fieldByName returns the field with the json name, matched case-insensitively
+// like encoding/json does.
+func fieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if strings.EqualFold(jsonName(f), name) {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// normalize converts the values parsed from yaml, toml, json or the env to
+// the json encoding/json expects for the config type, where numbers are
+// quoted strings and strings stored as json, like notificationTimeout, are
+// quoted twice.
+func normalize(v interface{}, t reflect.Type) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v, nil
+		}
+
+		result := make(map[string]interface{})
+		for k, value := range m {
+			f, ok := fieldByName(t, k)
+			if !ok {
+				// Unknown settings are ignored, like in the NODES env.
+				result[k] = value
+				continue
+			}
+
+			n, err := normalizeField(value, f)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", jsonName(f), err)
+			}
+			result[k] = n
+		}
+
+		return result, nil
+	case reflect.Slice:
+		if s, ok := v.(string); ok && t.Elem().Kind() == reflect.String {
+			// A comma separated list from the env.
+			var result []interface{}
+			for _, item := range strings.Split(s, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					result = append(result, item)
+				}
+			}
+			return result, nil
+		}
+
+		l, ok := v.([]interface{})
+		if !ok {
+			if m, ok := v.([]map[string]interface{}); ok {
+				for _, item := range m {
+					l = append(l, item)
+				}
+			} else {
+				return v, nil
+			}
+		}
+
+		var result []interface{}
+		for i, item := range l {
+			n, err := normalize(item, t.Elem())
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			result = append(result, n)
+		}
+
+		return result, nil
+	case reflect.Map:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v, nil
+		}
+
+		result := make(map[string]interface{})
+		for k, value := range m {
+			n, err := normalize(value, t.Elem())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			result[k] = n
+		}
+
+		return result, nil
+	case reflect.Bool:
+		if s, ok := v.(string); ok {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid boolean '%s'", s)
+			}
+			return b, nil
+		}
+	case reflect.String:
+		if s, ok := scalarString(v); ok {
+			return s, nil
+		}
+	}
+
+	return v, nil
+}
+
+func normalizeField(v interface{}, f reflect.StructField) (interface{}, error) {
+	if !hasStringOption(f) {
+		return normalize(v, f.Type)
+	}
+
+	s, ok := scalarString(v)
+	if !ok {
+		return nil, fmt.Errorf("expected a number or string, got %v", v)
+	}
+
+	t := f.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.String && !strings.HasPrefix(s, "\"") {
+		return strconv.Quote(s), nil
+	}
+
+	return s, nil
+}
+
+// scalarString formats the number, string or boolean as a string.
+func scalarString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case json.Number:
+		return s.String(), true
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(s), 'f', -1, 32), true
+	case int, int64, int32, uint, uint64, uint32, bool:
+		return fmt.Sprint(s), true
+	}
+
+	return "", false
+}
+
+func hasStringOption(f reflect.StructField) bool {
+	opts := strings.Split(strings.Trim(f.Tag.Get("json"), "\""), ",")
+	for _, o := range opts[1:] {
+		if o == "string" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNodesJson(t *testing.T) {
+	nodes, err := parseNodes([]byte(`[{"name": "a", "tokens": ["t1"], "feeRate": "0.000001", "notificationTimeout": "\"30s\"", "minConfs": "0"}]`), "json", nil)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "a", nodes[0].Name)
+	assert.Equal(t, 0.000001, nodes[0].FeeRate)
+	assert.Equal(t, "30s", nodes[0].NotificationTimeout)
+	assert.Equal(t, uint32(0), *nodes[0].MinConfs)
+	assert.Equal(t, "resume", nodes[0].KeysendPolicy)
+}
+
+func TestParseNodesYaml(t *testing.T) {
+	yaml := `
+defaults:
+  feeRate: 0.000001
+  timeLockDelta: 144
+  notificationTimeout: 30s
+nodes:
+  - name: a
+    tokens: [t1]
+    channelPrivate: true
+    cln:
+      pluginAddress: 127.0.0.1:12312
+      socketPath: /tmp/lightning-rpc
+  - name: b
+    feeRate: 0.00002
+    lnd:
+      address: 127.0.0.1:10009
+`
+	nodes, err := parseNodes([]byte(yaml), "yaml", nil)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, 0.000001, nodes[0].FeeRate)
+	assert.Equal(t, uint32(144), nodes[0].TimeLockDelta)
+	assert.Equal(t, "30s", nodes[0].NotificationTimeout)
+	assert.True(t, nodes[0].ChannelPrivate)
+	assert.Equal(t, "/tmp/lightning-rpc", nodes[0].Cln.SocketPath)
+	assert.Equal(t, 0.00002, nodes[1].FeeRate)
+	assert.Equal(t, uint32(144), nodes[1].TimeLockDelta)
+	assert.Equal(t, "127.0.0.1:10009", nodes[1].Lnd.Address)
+}
+
+func TestParseNodesToml(t *testing.T) {
+	toml := `
+[defaults]
+channelAmount = 100000
+
+[[nodes]]
+name = "a"
+tokens = ["t1"]
+baseFeeMsat = 1000
+
+[nodes.cln]
+pluginAddress = "127.0.0.1:12312"
+holdTimeout = "2m"
+
+[[nodes.clientQuirks]]
+agent = "sdk/0.1"
+maxCltvDelta = 2016
+`
+	nodes, err := parseNodes([]byte(toml), "toml", nil)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, int64(100000), int64(nodes[0].ChannelAmount))
+	assert.Equal(t, int64(1000), int64(nodes[0].BaseFeeMsat))
+	assert.Equal(t, "2m", nodes[0].Cln.HoldTimeout)
+	assert.Len(t, nodes[0].ClientQuirks, 1)
+	assert.Equal(t, uint32(2016), nodes[0].ClientQuirks[0].MaxCltvDelta)
+}
+
+func TestParseNodesEnvOverrides(t *testing.T) {
+	environ := []string{
+		"NODES_FILE=/etc/lspd/nodes.yaml",
+		"NODES_0_FEE_RATE=0.00005",
+		"NODES_0_LND_MACAROON=0201",
+		"NODES_0_TOKENS=t2,t3",
+		"NODES_0_SHADOW_MODE=true",
+	}
+	nodes, err := parseNodes([]byte(`{"nodes": [{"tokens": ["t1"], "feeRate": "0.000001", "lnd": {"address": "a:10009"}}]}`), "json", environ)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.00005, nodes[0].FeeRate)
+	assert.Equal(t, "0201", nodes[0].Lnd.Macaroon)
+	assert.Equal(t, "a:10009", nodes[0].Lnd.Address)
+	assert.Equal(t, []string{"t2", "t3"}, nodes[0].Tokens)
+	assert.True(t, nodes[0].ShadowMode)
+
+	_, err = parseNodes([]byte(`[{}]`), "json", []string{"NODES_1_FEE_RATE=1"})
+	assert.Error(t, err)
+
+	_, err = parseNodes([]byte(`[{}]`), "json", []string{"NODES_0_NO_SUCH_SETTING=1"})
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	nodes := []*NodeConfig{
+		{LspdPrivateKey: "abcd", NodePubkey: "02", KeysendPolicy: "drop", Lnd: &LndConfig{}},
+	}
+
+	err := Validate(nodes)
+	assert.Error(t, err)
+	problems := err.(*ValidationError).Problems
+	assert.Len(t, problems, 6)
+	assert.Contains(t, problems[0], "lspdPrivateKey")
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Settings that are read whenever they are used, so they can change while
+// lspd runs. Other settings are used to set up connections and workers at
+// startup, and require a restart to change.
+var reloadableFields = map[string]struct{}{
+	"Tokens":                       {},
+	"Host":                         {},
+	"Network":                      {},
+	"PublicChannelAmount":          {},
+	"ChannelAmount":                {},
+	"ChannelPrivate":               {},
+	"DuplicateChannelThresholdSat": {},
+	"TargetConf":                   {},
+	"MinConfs":                     {},
+	"MinHtlcMsat":                  {},
+	"MinPaymentSizeMsat":           {},
+	"BaseFeeMsat":                  {},
+	"FeeRate":                      {},
+	"TimeLockDelta":                {},
+	"ChannelFeePermyriad":          {},
+	"ChannelMinimumFeeMsat":        {},
+	"AdditionalChannelCapacity":    {},
+	"CapacityFormulas":             {},
+	"Quotas":                       {},
+	"OpenChannelChallenge":         {},
+	"RpcRateLimit":                 {},
+	"MaxInactiveDuration":          {},
+	"NotificationTimeout":          {},
+	"MaxChainFeeSatPerVByte":       {},
+	"FundingTxVBytes":              {},
+	"PriorityTiers":                {},
+	"MinOnchainReserveSat":         {},
+	"TaprootChannels":              {},
+	"CommitmentType":               {},
+	"PublicChannels":               {},
+	"RequireSignedPayments":        {},
+	"PreimageHold":                 {},
+	"ShadowMode":                   {},
+	"CltvBudget":                   {},
+	"LatencyBudget":                {},
+	"KeysendPolicy":                {},
+	"KeysendMaxChannelCapacity":    {},
+	"Wumbo":                        {},
+	"Reconnect":                    {},
+	"ClientQuirks":                 {},
+}
+
+// Reload applies the reloadable settings of next to the running node configs.
+// The nodes are matched by their position. Either all nodes are updated, or,
+// if any of the new configs is invalid or changes a setting that requires a
+// restart, none is.
+//
+// The settings are replaced in place. A decision in flight may see a mix of
+// the old and new settings of its node.
+func Reload(current []*NodeConfig, next []*NodeConfig) error {
+	if len(next) != len(current) {
+		return fmt.Errorf("nodes can't be added or removed without a restart, running %d nodes, got %d", len(current), len(next))
+	}
+
+	reloaded := make([]*NodeConfig, len(current))
+	for i, n := range next {
+		cur := current[i]
+		r := *n
+
+		// The name and pubkey default to the alias and pubkey of the node.
+		if r.Name == "" {
+			r.Name = cur.Name
+		}
+		if r.NodePubkey == "" {
+			r.NodePubkey = cur.NodePubkey
+		}
+
+		if field := changedRestartField(cur, &r); field != "" {
+			return fmt.Errorf("node %s: changing %s requires a restart", cur.Label(), field)
+		}
+
+		reloaded[i] = &r
+	}
+
+	err := Validate(reloaded)
+	if err != nil {
+		return err
+	}
+
+	for i := range current {
+		*current[i] = *reloaded[i]
+	}
+
+	return nil
+}
+
+// changedRestartField returns the json name of the first setting that differs
+// between the configs and can't be reloaded, or an empty string if there is
+// none.
+func changedRestartField(cur *NodeConfig, next *NodeConfig) string {
+	c := reflect.ValueOf(cur).Elem()
+	n := reflect.ValueOf(next).Elem()
+	t := c.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if _, ok := reloadableFields[f.Name]; ok {
+			continue
+		}
+
+		if !reflect.DeepEqual(c.Field(i).Interface(), n.Field(i).Interface()) {
+			return jsonName(f)
+		}
+	}
+
+	return ""
+}
+
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(strings.Trim(f.Tag.Get("json"), "\""), ",")[0]
+	if name == "" || name == "-" {
+		return f.Name
+	}
+
+	return name
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPrivateKey = "0000000000000000000000000000000000000000000000000000000000000001"
+
+func reloadTestNodes() []*NodeConfig {
+	return []*NodeConfig{
+		{Name: "a", LspdPrivateKey: testPrivateKey, Tokens: []string{"t1"}, FeeRate: 0.001, Cln: &ClnConfig{PluginAddress: "127.0.0.1:12312", SocketPath: "tcp://127.0.0.1:1"}},
+		{Name: "b", LspdPrivateKey: testPrivateKey, Tokens: []string{"t2"}, FeeRate: 0.001, Cln: &ClnConfig{PluginAddress: "127.0.0.1:12313", SocketPath: "tcp://127.0.0.1:2"}},
+	}
+}
+
+func TestReload(t *testing.T) {
+	current := reloadTestNodes()
+	next := reloadTestNodes()
+	next[0].Name = ""
+	next[0].FeeRate = 0.002
+	next[1].Tokens = []string{"t3"}
+
+	err := Reload(current, next)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", current[0].Name)
+	assert.Equal(t, 0.002, current[0].FeeRate)
+	assert.Equal(t, []string{"t3"}, current[1].Tokens)
+}
+
+func TestReloadRollsBack(t *testing.T) {
+	current := reloadTestNodes()
+	next := reloadTestNodes()
+	next[0].FeeRate = 0.002
+	next[1].Cln.SocketPath = "tcp://127.0.0.1:3"
+
+	err := Reload(current, next)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cln requires a restart")
+	assert.Equal(t, 0.001, current[0].FeeRate)
+
+	next = reloadTestNodes()
+	next[0].FeeRate = 0.002
+	next[1].Tokens = []string{"t1"}
+	err = Reload(current, next)
+	assert.Error(t, err)
+	assert.Equal(t, 0.001, current[0].FeeRate)
+}
@@ -0,0 +1,431 @@
+package config
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/formula"
+	"github.com/breez/lspd/secrets"
+	"github.com/breez/lspd/tenant"
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+// ValidationError lists all problems found in the node configs.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid config:\n  %s", strings.Join(e.Problems, "\n  "))
+}
+
+// Validate checks the node configs for problems that would make lspd fail or
+// misbehave at runtime, without connecting to the nodes. It returns a
+// *ValidationError listing every problem found.
+func Validate(nodes []*NodeConfig) error {
+	if len(nodes) == 0 {
+		return &ValidationError{Problems: []string{"need at least one node configured"}}
+	}
+
+	var problems []string
+	tokens := make(map[string]int)
+	for i, n := range nodes {
+		prefix := fmt.Sprintf("node %d", i)
+		if label := n.Label(); label != "" {
+			prefix = fmt.Sprintf("node %d (%s)", i, label)
+		}
+
+		for _, p := range validateNode(n) {
+			problems = append(problems, fmt.Sprintf("%s: %s", prefix, p))
+		}
+
+		for _, token := range n.Tokens {
+			if other, ok := tokens[token]; ok {
+				problems = append(problems, fmt.Sprintf("%s: tokens: token is used by node %d as well", prefix, other))
+				continue
+			}
+			tokens[token] = i
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+func validateNode(n *NodeConfig) []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// Secrets referenced in Vault or KMS are only checked when they are
+	// fetched, on startup.
+	if scheme, _, ok := secrets.ParseReference(n.LspdPrivateKey); ok {
+		if scheme == secrets.SchemeKMSKey && len(n.EncryptionKeys) == 0 {
+			add("encryptionKeys: required with a kms lspdPrivateKey, the kms key can't decrypt requests")
+		}
+	} else if n.LspdPrivateKey == "" {
+		add("lspdPrivateKey: missing, generate one with `lspd genkey`")
+	} else if !isHexKey(n.LspdPrivateKey, 32) {
+		add("lspdPrivateKey: must be 32 bytes hex encoded, generate one with `lspd genkey`")
+	}
+
+	for _, k := range n.EncryptionKeys {
+		if k == nil {
+			continue
+		}
+		if scheme, _, ok := secrets.ParseReference(k.PrivateKey); ok {
+			if scheme == secrets.SchemeKMSKey {
+				add("encryptionKeys: privateKey of key '%s' can't be a kms key, only lspdPrivateKey can", k.ID)
+			}
+		} else if !isHexKey(k.PrivateKey, 32) {
+			add("encryptionKeys: privateKey of key '%s' must be 32 bytes hex encoded", k.ID)
+		}
+		if k.ActiveFrom != "" {
+			if _, err := time.Parse(time.RFC3339, k.ActiveFrom); err != nil {
+				add("encryptionKeys: activeFrom of key '%s' must be an RFC3339 time", k.ID)
+			}
+		}
+		if k.RetireAt != "" {
+			if _, err := time.Parse(time.RFC3339, k.RetireAt); err != nil {
+				add("encryptionKeys: retireAt of key '%s' must be an RFC3339 time", k.ID)
+			}
+		}
+	}
+
+	switch n.Network {
+	case "", "mainnet", "testnet", "signet", "regtest":
+	default:
+		add("network: must be mainnet, testnet, signet or regtest")
+	}
+
+	if !tenant.Valid(n.Tenant) {
+		add("tenant: must be at most 63 lowercase letters, digits, '-' and '_', starting with a letter or digit")
+	}
+
+	if n.NodePubkey != "" {
+		b, err := hex.DecodeString(n.NodePubkey)
+		if err != nil || len(b) != 33 {
+			add("nodePubkey: must be a 33 byte hex encoded public key, or empty to use the pubkey of the node")
+		} else if _, err := btcec.ParsePubKey(b); err != nil {
+			add("nodePubkey: invalid public key: %v", err)
+		}
+	}
+
+	backends := 0
+	for _, set := range []bool{n.Lnd != nil, n.Cln != nil, n.Ldk != nil} {
+		if set {
+			backends++
+		}
+	}
+
+	switch {
+	case backends == 0:
+		add("either lnd, cln or ldk must be configured")
+	case backends > 1:
+		add("node can only be one of lnd, cln and ldk")
+	case n.Lnd != nil:
+		if n.Lnd.Address == "" {
+			add("lnd.address: missing the host:port of the lnd grpc api")
+		}
+		if block, _ := pem.Decode([]byte(n.Lnd.Cert)); block == nil {
+			add("lnd.cert: must be the pem encoded tls certificate of lnd, the content of tls.cert")
+		} else if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			add("lnd.cert: invalid certificate: %v", err)
+		}
+		if scheme, _, ok := secrets.ParseReference(n.Lnd.Macaroon); ok {
+			if scheme == secrets.SchemeKMSKey {
+				add("lnd.macaroon: can't be a kms key, only lspdPrivateKey can")
+			}
+		} else if _, err := hex.DecodeString(n.Lnd.Macaroon); err != nil || n.Lnd.Macaroon == "" {
+			add("lnd.macaroon: must be the hex encoded macaroon")
+		}
+	case n.Cln != nil:
+		if n.Cln.PluginAddress == "" {
+			add("cln.pluginAddress: missing the address the lspd cln plugin listens on")
+		}
+		if n.Cln.SocketPath == "" {
+			add("cln.socketPath: missing the path to the lightning-rpc socket of cln")
+		} else if !strings.HasPrefix(n.Cln.SocketPath, "tcp://") {
+			if _, err := os.Stat(n.Cln.SocketPath); err != nil {
+				add("cln.socketPath: %v", err)
+			}
+		}
+		validateDuration(add, "cln.holdTimeout", n.Cln.HoldTimeout)
+		validateDuration(add, "cln.rpcTimeout", n.Cln.RpcTimeout)
+		validateDuration(add, "cln.scidCacheTtl", n.Cln.ScidCacheTtl)
+	case n.Ldk != nil:
+		if u, err := url.Parse(n.Ldk.Address); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("ldk.address: must be the http(s) url of the ldk sidecar api")
+		}
+		validateDuration(add, "ldk.rpcTimeout", n.Ldk.RpcTimeout)
+	}
+
+	if n.FeeRate < 0 {
+		add("feeRate: can't be negative")
+	}
+
+	if n.ChannelFeePermyriad < 0 {
+		add("channelFeePermyriad: can't be negative")
+	}
+
+	if n.ChannelMinimumFeeMsat < 0 {
+		add("channelMinimumFeeMsat: can't be negative")
+	}
+
+	validateDuration(add, "notificationTimeout", n.NotificationTimeout)
+
+	if n.LatencyBudget != nil {
+		validateDuration(add, "latencyBudget.maxDuration", n.LatencyBudget.MaxDuration)
+	}
+
+	switch strings.ToLower(n.KeysendPolicy) {
+	case "", "resume", "fail", "open":
+	default:
+		add("keysendPolicy: unknown policy '%s', use resume, fail or open", n.KeysendPolicy)
+	}
+
+	if n.PolicyHook != nil {
+		if n.PolicyHook.URL == "" {
+			add("policyHook.url: missing")
+		}
+		validateDuration(add, "policyHook.timeout", n.PolicyHook.Timeout)
+	}
+
+	for name, p := range n.ChannelPolicies {
+		if p == nil {
+			continue
+		}
+		if p.FeeRate != nil && *p.FeeRate < 0 {
+			add("channelPolicies.%s.feeRate: can't be negative", name)
+		}
+		if p.MaxHtlcMsat > 0 && p.MinHtlcMsat > p.MaxHtlcMsat {
+			add("channelPolicies.%s: minHtlcMsat exceeds maxHtlcMsat", name)
+		}
+	}
+
+	if n.Lsps0 != nil {
+		if n.Lsps0.MaxConcurrentRequests < 0 {
+			add("lsps0.maxConcurrentRequests: can't be negative")
+		}
+		validateDuration(add, "lsps0.requestTimeout", n.Lsps0.RequestTimeout)
+	}
+
+	if n.AuditLog != nil {
+		validateDuration(add, "auditLog.retention", n.AuditLog.Retention)
+	}
+
+	if c := n.OpenChannelChallenge; c != nil {
+		validateDuration(add, "openChannelChallenge.expiry", c.Expiry)
+		if c.Difficulty > 32 {
+			add("openChannelChallenge.difficulty: can't be more than 32 bits")
+		}
+		if c.CaptchaVerifyURL != "" && c.CaptchaSecret == "" {
+			add("openChannelChallenge.captchaSecret: required with captchaVerifyUrl")
+		}
+	}
+
+	if r := n.RpcRateLimit; r != nil {
+		if r.RequestsPerSecond <= 0 {
+			add("rpcRateLimit.requestsPerSecond: must be positive")
+		}
+		if r.Burst < 0 {
+			add("rpcRateLimit.burst: can't be negative")
+		}
+	}
+
+	for name, f := range n.CapacityFormulas {
+		if f == nil {
+			continue
+		}
+		if (f.Expression == "") == (len(f.Tiers) == 0) {
+			add("capacityFormulas.%s: set either expression or tiers", name)
+		}
+		if f.Expression != "" {
+			_, err := formula.Parse(f.Expression, CapacityFormulaVars...)
+			if err != nil {
+				add("capacityFormulas.%s.expression: %v", name, err)
+			}
+		}
+		for j, t := range f.Tiers {
+			if t == nil || t.CapacitySat <= 0 {
+				add("capacityFormulas.%s.tiers[%d].capacitySat: must be positive", name, j)
+			}
+		}
+	}
+
+	for name, q := range n.Quotas {
+		if q == nil {
+			continue
+		}
+		if q.DailyOpens < 0 || q.WeeklyOpens < 0 || q.DailySat < 0 || q.WeeklySat < 0 {
+			add("quotas.%s: limits can't be negative", name)
+		}
+	}
+
+	switch n.CommitmentType {
+	case "", "taproot", "anchors", "static_remote_key":
+	default:
+		add("commitmentType: unknown commitment type '%s', use taproot, anchors or static_remote_key", n.CommitmentType)
+	}
+
+	for name, t := range n.PriorityTiers {
+		switch name {
+		case "economy", "normal", "urgent":
+		default:
+			add("priorityTiers: unknown tier '%s', use economy, normal or urgent", name)
+		}
+		if t == nil {
+			continue
+		}
+		switch strings.ToLower(t.FeePriority) {
+		case "", "fastest", "halfhour", "hour", "economy", "minimum":
+		default:
+			add("priorityTiers.%s.feePriority: unknown priority '%s', use fastest, halfhour, hour, economy or minimum", name, t.FeePriority)
+		}
+	}
+
+	if l := n.ChannelLease; l != nil {
+		if d, err := time.ParseDuration(l.Duration); err != nil || d <= 0 {
+			add("channelLease.duration: invalid duration '%s', use a positive duration like '2160h'", l.Duration)
+		}
+		validateDuration(add, "channelLease.notifyBefore", l.NotifyBefore)
+		validateDuration(add, "channelLease.interval", l.Interval)
+	}
+
+	if n.FundingConfirmation != nil {
+		validateDuration(add, "fundingConfirmation.interval", n.FundingConfirmation.Interval)
+	}
+
+	if a := n.Alerting; a != nil {
+		validateDuration(add, "alerting.interval", a.Interval)
+		validateDuration(add, "alerting.openFailureWindow", a.OpenFailureWindow)
+		if a.MaxOpenFailures < 0 {
+			add("alerting.maxOpenFailures: can't be negative")
+		}
+		if a.Slack == nil && a.Telegram == nil && a.PagerDuty == nil {
+			add("alerting: missing sink, set slack, telegram or pagerDuty")
+		}
+		if a.Slack != nil {
+			if u, err := url.Parse(a.Slack.WebhookURL); err != nil || u.Scheme != "https" || u.Host == "" {
+				add("alerting.slack.webhookUrl: invalid url '%s'", a.Slack.WebhookURL)
+			}
+		}
+		if a.Telegram != nil {
+			if a.Telegram.BotToken == "" {
+				add("alerting.telegram.botToken: missing")
+			}
+			if a.Telegram.ChatID == "" {
+				add("alerting.telegram.chatId: missing")
+			}
+		}
+		if a.PagerDuty != nil && a.PagerDuty.RoutingKey == "" {
+			add("alerting.pagerDuty.routingKey: missing")
+		}
+	}
+
+	if d := n.DoubleSpendMonitor; d != nil {
+		validateDuration(add, "doubleSpendMonitor.interval", d.Interval)
+		if d.WebhookURL != "" {
+			if u, err := url.Parse(d.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add("doubleSpendMonitor.webhookUrl: invalid url '%s'", d.WebhookURL)
+			}
+		}
+	}
+
+	if d := n.DualFunding; d != nil {
+		switch d.Policy {
+		case "", "match", "available", "fixed":
+		default:
+			add("dualFunding.policy: invalid policy '%s', use 'match', 'available' or 'fixed'", d.Policy)
+		}
+		if d.MaxClientFundingSat > 0 && d.MinClientFundingSat > d.MaxClientFundingSat {
+			add("dualFunding: minClientFundingSat exceeds maxClientFundingSat")
+		}
+		if d.LiquidityAds != nil && d.LiquidityAds.LeaseFeeBasis > 10_000 {
+			add("dualFunding.liquidityAds.leaseFeeBasis: %d exceeds 10000 basis points", d.LiquidityAds.LeaseFeeBasis)
+		}
+	}
+
+	if c := n.CoinControl; c != nil {
+		if c.Account != "" && n.Lnd == nil {
+			add("coinControl.account: only supported on lnd nodes")
+		}
+		for _, o := range c.ExcludeUtxos {
+			if _, err := basetypes.ParseOutPoint(o); err != nil {
+				add("coinControl.excludeUtxos: invalid utxo '%s', use txid:vout", o)
+			}
+		}
+	}
+
+	if c := n.Consolidation; c != nil {
+		if c.MaxFeeSatPerVByte <= 0 {
+			add("consolidation.maxFeeSatPerVByte: has to be positive")
+		}
+		switch strings.ToLower(c.FeePriority) {
+		case "", "fastest", "halfhour", "hour", "economy", "minimum":
+		default:
+			add("consolidation.feePriority: unknown priority '%s', use fastest, halfhour, hour, economy or minimum", c.FeePriority)
+		}
+		if c.MinUtxos < 0 || c.MaxInputs < 0 {
+			add("consolidation: minUtxos and maxInputs can't be negative")
+		}
+		if c.MinUtxos > 0 && c.MaxInputs > 0 && c.MinUtxos > c.MaxInputs {
+			add("consolidation: minUtxos exceeds maxInputs")
+		}
+		validateDuration(add, "consolidation.interval", c.Interval)
+		if c.Window != "" {
+			if _, _, err := ParseWindow(c.Window); err != nil {
+				add("consolidation.window: %v", err)
+			}
+		}
+	}
+
+	if r := n.Refunds; r != nil {
+		validateDuration(add, "refunds.failAfter", r.FailAfter)
+		validateDuration(add, "refunds.interval", r.Interval)
+	}
+
+	if n.Hodl != nil {
+		validateDuration(add, "hodl.checkInterval", n.Hodl.CheckInterval)
+	}
+
+	if s := n.Scoring; s != nil {
+		if s.MinScore < 0 || s.MinScore > 100 {
+			add("scoring.minScore: has to be between 0 and 100")
+		}
+		if s.UnsettledPenalty < 0 || s.EarlyClosePenalty < 0 || s.ProbePenalty < 0 {
+			add("scoring: penalties can't be negative")
+		}
+		validateDuration(add, "scoring.earlyCloseWindow", s.EarlyCloseWindow)
+		validateDuration(add, "scoring.memory", s.Memory)
+		validateDuration(add, "scoring.interval", s.Interval)
+	}
+
+	return problems
+}
+
+func validateDuration(add func(string, ...interface{}), name string, value string) {
+	if value == "" {
+		return
+	}
+
+	if _, err := time.ParseDuration(value); err != nil {
+		add("%s: invalid duration '%s', use a duration like '30s' or '5m'", name, value)
+	}
+}
+
+func isHexKey(s string, length int) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == length
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ParseWindow parses a daily time window like '01:00-05:00'. Returns the start
+// and end of the window as time since midnight. The end is before the start
+// for windows that span midnight.
+func ParseWindow(s string) (time.Duration, time.Duration, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid window '%s', use a window like '01:00-05:00'", s)
+	}
+
+	start, err := time.Parse("15:04", strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid window start '%s', use a time like '01:00'", parts[0])
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid window end '%s', use a time like '05:00'", parts[1])
+	}
+	if start.Equal(end) {
+		return 0, 0, fmt.Errorf("invalid window '%s', the window is empty", s)
+	}
+
+	midnight := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+	return start.Sub(midnight), end.Sub(midnight), nil
+}
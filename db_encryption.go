@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/breez/lspd/dbcrypt"
+	"github.com/breez/lspd/postgresql"
+)
+
+// databaseCipher returns the cipher of the sensitive database columns, from
+// DATABASE_ENCRYPTION_KEY and the comma separated DATABASE_ENCRYPTION_OLD_KEYS,
+// hex encoded 32 byte keys. Returns nil if encryption isn't enabled.
+func databaseCipher() (*dbcrypt.Cipher, error) {
+	current := os.Getenv("DATABASE_ENCRYPTION_KEY")
+	if current == "" {
+		return nil, nil
+	}
+
+	var keys [][]byte
+	for _, k := range append([]string{current}, strings.Split(os.Getenv("DATABASE_ENCRYPTION_OLD_KEYS"), ",")...) {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+
+		key, err := hex.DecodeString(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid database encryption key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return dbcrypt.NewCipher(keys[0], keys[1:]...)
+}
+
+// runReencryptDb encrypts the sensitive columns of DATABASE_URL with the
+// current DATABASE_ENCRYPTION_KEY, after encryption was enabled or the key
+// rotated. With --decrypt, the columns are decrypted to disable encryption.
+func runReencryptDb(args []string) {
+	flags := flag.NewFlagSet("reencrypt-db", flag.ExitOnError)
+	decrypt := flags.Bool("decrypt", false, "store the values unencrypted, to disable encryption")
+	flags.Parse(args)
+
+	cipher, err := databaseCipher()
+	if err != nil {
+		log.Fatalf("databaseCipher() error: %v", err)
+	}
+	if cipher == nil {
+		log.Fatalf("reencrypt-db: set DATABASE_ENCRYPTION_KEY")
+	}
+	pool, err := postgresql.PgConnect(os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatalf("pgConnect() error: %v", err)
+	}
+	defer pool.Close()
+
+	err = postgresql.Reencrypt(context.Background(), pool, cipher, *decrypt)
+	if err != nil {
+		log.Fatalf("reencrypt error: %v", err)
+	}
+
+	log.Printf("reencrypt-db: done")
+}
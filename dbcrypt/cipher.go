@@ -0,0 +1,206 @@
+// Package dbcrypt encrypts sensitive values before they are stored in the
+// database, with AES-256-GCM.
+//
+// The encryption is deterministic: the nonce is derived from the value, so
+// equal values encrypt to equal ciphertexts under the same key. Encrypted
+// columns can still be looked up and joined on, at the cost of revealing
+// which rows hold the same value. Values stored before encryption was
+// enabled are read as they are, and lookups match every form a value may be
+// stored in, until the rows are encrypted with the current key.
+package dbcrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	keySize   = 32
+	idSize    = 4
+	nonceSize = 12
+	tagSize   = 16
+
+	// stringPrefix marks encrypted strings.
+	stringPrefix = "enc1:"
+)
+
+// marker is the first bytes of encrypted values. Values lspd stores in
+// encrypted columns either don't start with it, like pubkeys, or are shorter
+// than any encrypted value, like preimages.
+var marker = []byte{0xec, 0x01}
+
+// minEncryptedSize is the size of an encrypted empty value.
+var minEncryptedSize = len(marker) + idSize + nonceSize + tagSize
+
+var ErrUnknownKey = errors.New("value encrypted with an unknown key")
+
+type key struct {
+	id       []byte
+	aead     cipher.AEAD
+	nonceKey []byte
+}
+
+// Cipher encrypts values with the current key, and decrypts values encrypted
+// with the current or an old key. A nil Cipher leaves values as they are.
+type Cipher struct {
+	current *key
+	keys    []*key
+}
+
+// NewCipher returns a cipher encrypting with the current key. The old keys
+// are only used to decrypt values, after the key was rotated. Keys are 32
+// bytes.
+func NewCipher(current []byte, old ...[]byte) (*Cipher, error) {
+	c := &Cipher{}
+	for i, k := range append([][]byte{current}, old...) {
+		if len(k) != keySize {
+			return nil, fmt.Errorf("key %d is %d bytes, expected %d", i, len(k), keySize)
+		}
+
+		aesKey := derive(k, "encryption")
+		block, err := aes.NewCipher(aesKey)
+		if err != nil {
+			return nil, fmt.Errorf("aes.NewCipher() error: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("cipher.NewGCM() error: %w", err)
+		}
+
+		c.keys = append(c.keys, &key{
+			id:       derive(k, "id")[:idSize],
+			aead:     aead,
+			nonceKey: derive(k, "nonce"),
+		})
+	}
+
+	c.current = c.keys[0]
+	return c, nil
+}
+
+func derive(k []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, k)
+	mac.Write([]byte("lspd-dbcrypt-" + purpose))
+	return mac.Sum(nil)
+}
+
+func (k *key) encrypt(plaintext []byte) []byte {
+	mac := hmac.New(sha256.New, k.nonceKey)
+	mac.Write(plaintext)
+	nonce := mac.Sum(nil)[:nonceSize]
+
+	out := make([]byte, 0, len(marker)+idSize+nonceSize+len(plaintext)+k.aead.Overhead())
+	out = append(out, marker...)
+	out = append(out, k.id...)
+	out = append(out, nonce...)
+	return k.aead.Seal(out, nonce, plaintext, nil)
+}
+
+// Encrypt returns the value encrypted with the current key. Nil stays nil.
+func (c *Cipher) Encrypt(plaintext []byte) []byte {
+	if c == nil || plaintext == nil {
+		return plaintext
+	}
+
+	return c.current.encrypt(plaintext)
+}
+
+// Decrypt returns the plaintext of an encrypted value. Values that aren't
+// encrypted are returned as they are.
+func (c *Cipher) Decrypt(value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, marker) || len(value) < minEncryptedSize {
+		return value, nil
+	}
+	if c == nil {
+		return nil, ErrUnknownKey
+	}
+
+	rest := value[len(marker):]
+
+	for _, k := range c.keys {
+		if !bytes.Equal(k.id, rest[:idSize]) {
+			continue
+		}
+
+		plaintext, err := k.aead.Open(nil, rest[idSize:idSize+nonceSize], rest[idSize+nonceSize:], nil)
+		if err != nil {
+			return nil, fmt.Errorf("aead.Open() error: %w", err)
+		}
+
+		// Distinguish empty values from nil, which isn't encrypted.
+		if plaintext == nil {
+			plaintext = []byte{}
+		}
+		return plaintext, nil
+	}
+
+	return nil, ErrUnknownKey
+}
+
+// Candidates returns the values the plaintext may be stored as: encrypted
+// with any of the keys, or not encrypted at all. Lookups match any of them.
+func (c *Cipher) Candidates(plaintext []byte) [][]byte {
+	candidates := [][]byte{plaintext}
+	if c == nil || plaintext == nil {
+		return candidates
+	}
+
+	for _, k := range c.keys {
+		candidates = append(candidates, k.encrypt(plaintext))
+	}
+
+	return candidates
+}
+
+// EncryptString is Encrypt for text columns.
+func (c *Cipher) EncryptString(plaintext string) string {
+	if c == nil {
+		return plaintext
+	}
+
+	return encodeString(c.current.encrypt([]byte(plaintext)))
+}
+
+// DecryptString is Decrypt for text columns.
+func (c *Cipher) DecryptString(value string) (string, error) {
+	if !strings.HasPrefix(value, stringPrefix) {
+		return value, nil
+	}
+
+	b, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, stringPrefix))
+	if err != nil || !bytes.HasPrefix(b, marker) || len(b) < minEncryptedSize {
+		return "", fmt.Errorf("invalid encrypted value")
+	}
+
+	plaintext, err := c.Decrypt(b)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// StringCandidates is Candidates for text columns.
+func (c *Cipher) StringCandidates(plaintext string) []string {
+	candidates := []string{plaintext}
+	if c == nil {
+		return candidates
+	}
+
+	for _, k := range c.keys {
+		candidates = append(candidates, encodeString(k.encrypt([]byte(plaintext))))
+	}
+
+	return candidates
+}
+
+func encodeString(b []byte) string {
+	return stringPrefix + base64.RawStdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,76 @@
+package dbcrypt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	oldKey     = bytes.Repeat([]byte{1}, 32)
+	currentKey = bytes.Repeat([]byte{2}, 32)
+	pubkey     = append([]byte{0x02}, bytes.Repeat([]byte{0xaa}, 32)...)
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	c, err := NewCipher(currentKey)
+	assert.NoError(t, err)
+
+	encrypted := c.Encrypt(pubkey)
+	assert.NotEqual(t, pubkey, encrypted)
+	assert.Equal(t, encrypted, c.Encrypt(pubkey), "encryption must be deterministic")
+
+	plaintext, err := c.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, pubkey, plaintext)
+
+	// Values stored before encryption was enabled are read as they are.
+	plaintext, err = c.Decrypt(pubkey)
+	assert.NoError(t, err)
+	assert.Equal(t, pubkey, plaintext)
+
+	// Even when a random value like a preimage starts with the marker.
+	preimage := append([]byte{0xec, 0x01}, bytes.Repeat([]byte{0xaa}, 30)...)
+	plaintext, err = c.Decrypt(preimage)
+	assert.NoError(t, err)
+	assert.Equal(t, preimage, plaintext)
+
+	s := c.EncryptString("token")
+	assert.NotEqual(t, "token", s)
+	token, err := c.DecryptString(s)
+	assert.NoError(t, err)
+	assert.Equal(t, "token", token)
+
+	encrypted[len(encrypted)-1] ^= 1
+	_, err = c.Decrypt(encrypted)
+	assert.Error(t, err)
+}
+
+func TestRotate(t *testing.T) {
+	old, err := NewCipher(oldKey)
+	assert.NoError(t, err)
+	c, err := NewCipher(currentKey, oldKey)
+	assert.NoError(t, err)
+
+	encrypted := old.Encrypt(pubkey)
+	plaintext, err := c.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, pubkey, plaintext)
+	assert.NotEqual(t, encrypted, c.Encrypt(pubkey))
+
+	// Lookups match the value in every form it may be stored in.
+	assert.Equal(t, [][]byte{pubkey, c.Encrypt(pubkey), encrypted}, c.Candidates(pubkey))
+	assert.Equal(t, []string{"token", c.EncryptString("token"), old.EncryptString("token")}, c.StringCandidates("token"))
+
+	current, err := NewCipher(currentKey)
+	assert.NoError(t, err)
+	_, err = current.Decrypt(encrypted)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	var none *Cipher
+	assert.Equal(t, pubkey, none.Encrypt(pubkey))
+	assert.Equal(t, [][]byte{pubkey}, none.Candidates(pubkey))
+	_, err = none.Decrypt(encrypted)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	lspdrpc "github.com/breez/lspd/rpc"
+)
+
+// funderPolicy returns the funder policy of the dual funding config.
+func funderPolicy(cfg *config.DualFundingConfig) *lightning.FunderPolicy {
+	policy := &lightning.FunderPolicy{
+		Policy:             cfg.Policy,
+		PolicyMod:          cfg.PolicyMod,
+		LeasesOnly:         cfg.LeasesOnly,
+		MinTheirFundingSat: cfg.MinClientFundingSat,
+		MaxTheirFundingSat: cfg.MaxClientFundingSat,
+		PerChannelMaxSat:   cfg.PerChannelMaxSat,
+		ReserveTankSat:     cfg.ReserveTankSat,
+	}
+	if ads := cfg.LiquidityAds; ads != nil {
+		policy.LeaseRates = &lightning.LeaseRates{
+			LeaseFeeBaseSat:                      ads.LeaseFeeBaseSat,
+			LeaseFeeBasis:                        ads.LeaseFeeBasis,
+			FundingWeight:                        ads.FundingWeight,
+			ChannelFeeMaxBaseMsat:                ads.ChannelFeeMaxBaseMsat,
+			ChannelFeeMaxProportionalThousandths: ads.ChannelFeeMaxProportionalThousandths,
+		}
+	}
+
+	return policy
+}
+
+// dualFunder returns the dual funder of the node, if dual funding is
+// configured and supported by the node.
+func dualFunder(n *node) (lightning.DualFunder, bool) {
+	if n.nodeConfig.DualFunding == nil {
+		return nil, false
+	}
+
+	funder, ok := n.client.(lightning.DualFunder)
+	return funder, ok
+}
+
+// liquidityAd returns the liquidity ad of the node, or nil if it doesn't
+// advertise liquidity.
+func liquidityAd(n *node) *lspdrpc.LiquidityAd {
+	if _, ok := dualFunder(n); !ok || n.nodeConfig.DualFunding.LiquidityAds == nil {
+		return nil
+	}
+
+	ads := n.nodeConfig.DualFunding.LiquidityAds
+	return &lspdrpc.LiquidityAd{
+		LeaseFeeBaseSat:                      ads.LeaseFeeBaseSat,
+		LeaseFeeBasis:                        ads.LeaseFeeBasis,
+		FundingWeight:                        ads.FundingWeight,
+		ChannelFeeMaxBaseMsat:                ads.ChannelFeeMaxBaseMsat,
+		ChannelFeeMaxProportionalThousandths: ads.ChannelFeeMaxProportionalThousandths,
+	}
+}
+
+// setClientFunding sets what the client contributed to its dual-funded
+// channels. Without dual funding clients contribute nothing.
+func setClientFunding(ctx context.Context, n *node, peerID []byte, channels []*lspdrpc.ClientChannel) error {
+	funder, ok := dualFunder(n)
+	if !ok {
+		return nil
+	}
+
+	funding, err := funder.ListChannelFunding(ctx, peerID)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range channels {
+		if f, ok := funding[c.ChannelPoint]; ok {
+			c.ClientFundingSat = f.RemoteFundsSat
+		}
+	}
+
+	return nil
+}
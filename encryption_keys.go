@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/breez/lspd/btceclegacy"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/secrets"
+	"github.com/btcsuite/btcd/btcec/v2"
+	ecies "github.com/ecies/go/v2"
+)
+
+// encryptionKey is a key clients encrypt the blobs of their requests with.
+type encryptionKey struct {
+	id              string
+	privateKey      *btcec.PrivateKey
+	publicKey       *btcec.PublicKey
+	eciesPrivateKey *ecies.PrivateKey
+	eciesPublicKey  *ecies.PublicKey
+	activeFrom      time.Time
+	retireAt        time.Time
+}
+
+func newEncryptionKey(ctx context.Context, id string, privateKeyHex string, resolver *secrets.Resolver) (*encryptionKey, error) {
+	pk, err := resolvePrivateKey(ctx, resolver, privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key of encryption key '%s': %w", id, err)
+	}
+
+	eciesPrivateKey := ecies.NewPrivateKeyFromBytes(pk)
+	privateKey, publicKey := btcec.PrivKeyFromBytes(pk)
+	return &encryptionKey{
+		id:              id,
+		privateKey:      privateKey,
+		publicKey:       publicKey,
+		eciesPrivateKey: eciesPrivateKey,
+		eciesPublicKey:  eciesPrivateKey.PublicKey,
+	}, nil
+}
+
+func (k *encryptionKey) isActive(now time.Time) bool {
+	return !now.Before(k.activeFrom) && !k.isRetired(now)
+}
+
+func (k *encryptionKey) isRetired(now time.Time) bool {
+	return !k.retireAt.IsZero() && !now.Before(k.retireAt)
+}
+
+// decrypt decrypts the blob with ecies, or the legacy btcec encryption for
+// older clients. Returns whether ecies was used.
+func (k *encryptionKey) decrypt(blob []byte) ([]byte, bool, error) {
+	data, err := ecies.Decrypt(k.eciesPrivateKey, blob)
+	if err == nil {
+		return data, true, nil
+	}
+
+	data, err = btceclegacy.Decrypt(k.privateKey, blob)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return data, false, nil
+}
+
+// keyRing holds the encryption keys of a node, ordered from the most recently
+// activated key to the oldest.
+type keyRing struct {
+	keys []*encryptionKey
+}
+
+func newKeyRing(ctx context.Context, node *config.NodeConfig, resolver *secrets.Resolver) (*keyRing, error) {
+	if len(node.EncryptionKeys) == 0 {
+		key, err := newEncryptionKey(ctx, "", node.LspdPrivateKey, resolver)
+		if err != nil {
+			return nil, err
+		}
+
+		return &keyRing{keys: []*encryptionKey{key}}, nil
+	}
+
+	r := &keyRing{}
+	ids := make(map[string]bool)
+	for _, c := range node.EncryptionKeys {
+		if ids[c.ID] {
+			return nil, fmt.Errorf("duplicate encryption key id '%s'", c.ID)
+		}
+		ids[c.ID] = true
+
+		key, err := newEncryptionKey(ctx, c.ID, c.PrivateKey, resolver)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.ActiveFrom != "" {
+			key.activeFrom, err = time.Parse(time.RFC3339, c.ActiveFrom)
+			if err != nil {
+				return nil, fmt.Errorf("invalid activeFrom of encryption key '%s': %w", c.ID, err)
+			}
+		}
+
+		if c.RetireAt != "" {
+			key.retireAt, err = time.Parse(time.RFC3339, c.RetireAt)
+			if err != nil {
+				return nil, fmt.Errorf("invalid retireAt of encryption key '%s': %w", c.ID, err)
+			}
+		}
+
+		r.keys = append(r.keys, key)
+	}
+
+	sort.SliceStable(r.keys, func(i, j int) bool {
+		return r.keys[i].activeFrom.After(r.keys[j].activeFrom)
+	})
+
+	return r, nil
+}
+
+// active returns the key handed out to clients: the most recently activated
+// key that is not retired. Returns nil if there is none.
+func (r *keyRing) active(now time.Time) *encryptionKey {
+	for _, k := range r.keys {
+		if k.isActive(now) {
+			return k
+		}
+	}
+
+	return nil
+}
+
+// decrypt decrypts a blob sent by a client with the key with the given id. If
+// the id is empty, every key that is not retired is tried, for clients that
+// don't send the key id. Keys are tried even before they are active, so
+// clients that cached a newly announced key are served.
+func (r *keyRing) decrypt(keyID string, blob []byte, logger *log.Logger) ([]byte, *encryptionKey, bool, error) {
+	now := time.Now()
+	for _, k := range r.keys {
+		if keyID != "" && k.id != keyID {
+			continue
+		}
+
+		if k.isRetired(now) {
+			if keyID != "" {
+				return nil, nil, false, fmt.Errorf("encryption key '%s' is retired", keyID)
+			}
+			continue
+		}
+
+		data, usedEcies, err := k.decrypt(blob)
+		if err == nil {
+			return data, k, usedEcies, nil
+		}
+
+		logger.Printf("decrypt(%x) with key '%s' error: %v", blob, k.id, err)
+	}
+
+	if keyID != "" && !r.hasKey(keyID) {
+		return nil, nil, false, fmt.Errorf("unknown encryption key '%s'", keyID)
+	}
+
+	return nil, nil, false, fmt.Errorf("failed to decrypt %x", blob)
+}
+
+func (r *keyRing) hasKey(keyID string) bool {
+	for _, k := range r.keys {
+		if k.id == keyID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,201 @@
+// Package events is the internal event bus of lspd. The interceptors and
+// background components publish what happened on a node, and the side effects,
+// like webhooks, emails and metrics, subscribe to the events they act on.
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Events buffered per subscriber until its handler catches up. Events
+// published while the buffer is full are dropped for that subscriber.
+const subscriberQueueSize = 1000
+
+type Kind string
+
+const (
+	// An htlc of a registered payment arrived, before a decision was made
+	// about it.
+	PaymentIntercepted Kind = "payment_intercepted"
+	// An htlc was intercepted and a decision was made about it.
+	HtlcIntercepted Kind = "htlc_intercepted"
+	// Opening a channel for a payment started.
+	ChannelOpenStarted Kind = "channel_open_started"
+	// A channel was opened for a payment and its funding was broadcast. The
+	// funding is not confirmed yet.
+	ChannelOpened Kind = "channel_opened"
+	// Opening a channel for a payment failed.
+	ChannelOpenFailed Kind = "channel_open_failed"
+	// The funding of a channel opened by lspd confirmed.
+	FundingConfirmed Kind = "funding_confirmed"
+	// The unconfirmed funding of a channel opened by lspd was double spent.
+	FundingDoubleSpent Kind = "funding_double_spent"
+	// The forwards of a payment lspd opened a channel for were found by the
+	// reconciliation.
+	PaymentSettled Kind = "payment_settled"
+	// A peer connected to the node.
+	ClientConnected Kind = "client_connected"
+)
+
+var droppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_events_dropped_total",
+	Help: "Events dropped because the subscriber was too slow.",
+}, []string{"subscriber"})
+
+// Event is something that happened on a node. Only the fields relevant to
+// the kind are set.
+type Event struct {
+	Kind Kind
+	Node *config.NodeConfig
+	Time time.Time
+	// The client the event is about.
+	PeerID      []byte
+	PaymentHash []byte
+	// Channel point and short channel id of the channel, for channel
+	// events.
+	ChannelPoint   string
+	ShortChannelID string
+	// Transaction that double spent the funding of the channel.
+	SpendingTxID string
+	// Incoming amount of the payment, or the amount of the intercepted htlc.
+	AmountMsat  int64
+	CapacitySat int64
+	Tag         *string
+	// Decision about an intercepted htlc, and the failure code and reason,
+	// like latency_budget_exceeded, if it was failed.
+	Action        string
+	FailureCode   string
+	FailureReason string
+	// Expiries of the intercepted htlc, the payment hash of the registered
+	// payment it matched, if any, and whether a channel was opened for it.
+	IncomingExpiry        uint32
+	OutgoingExpiry        uint32
+	RegisteredPaymentHash []byte
+	Opened                bool
+	// Time it took to decide about the intercepted htlc.
+	Duration time.Duration
+}
+
+// Handler handles the events of a subscription, one at a time.
+type Handler func(e *Event)
+
+type subscription struct {
+	name    string
+	kinds   map[Kind]struct{}
+	handler Handler
+	queue   chan *Event
+}
+
+// Bus delivers the published events to the subscribers of their kind.
+// Publishing never blocks the publisher: every subscriber handles its events
+// in its own goroutine, and events are dropped for subscribers that fall too
+// far behind. Events are not persisted, events published while lspd is down
+// are lost.
+type Bus struct {
+	logger        *log.Logger
+	mtx           sync.RWMutex
+	subscriptions []*subscription
+	wg            sync.WaitGroup
+	closed        bool
+}
+
+func NewBus(logger *log.Logger) *Bus {
+	return &Bus{
+		logger: logger,
+	}
+}
+
+// Subscribe calls the handler for the published events of the given kinds,
+// or of all kinds if none are given. The name identifies the subscriber in
+// logs and metrics.
+func (b *Bus) Subscribe(name string, handler Handler, kinds ...Kind) {
+	s := &subscription{
+		name:    name,
+		kinds:   make(map[Kind]struct{}),
+		handler: handler,
+		queue:   make(chan *Event, subscriberQueueSize),
+	}
+	for _, k := range kinds {
+		s.kinds[k] = struct{}{}
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.closed {
+		return
+	}
+
+	b.subscriptions = append(b.subscriptions, s)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for e := range s.queue {
+			b.handle(s, e)
+		}
+	}()
+}
+
+func (b *Bus) handle(s *subscription, e *Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.Printf("event subscriber %s panicked handling %s event: %v", s.name, e.Kind, r)
+		}
+	}()
+
+	s.handler(e)
+}
+
+// Publish delivers the event to the subscribers of its kind. The time of the
+// event is set if it's not set. Safe to call on a nil bus, then the event is
+// dropped.
+func (b *Bus) Publish(e *Event) {
+	if b == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	if b.closed {
+		return
+	}
+
+	for _, s := range b.subscriptions {
+		if _, ok := s.kinds[e.Kind]; len(s.kinds) > 0 && !ok {
+			continue
+		}
+
+		select {
+		case s.queue <- e:
+		default:
+			droppedTotal.WithLabelValues(s.name).Inc()
+			b.logger.Printf("event subscriber %s is too slow, dropping %s event", s.name, e.Kind)
+		}
+	}
+}
+
+// Close stops delivering events, and waits until the subscribers handled
+// the events queued already.
+func (b *Bus) Close() {
+	b.mtx.Lock()
+	if b.closed {
+		b.mtx.Unlock()
+		return
+	}
+
+	b.closed = true
+	for _, s := range b.subscriptions {
+		close(s.queue)
+	}
+	b.mtx.Unlock()
+	b.wg.Wait()
+}
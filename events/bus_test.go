@@ -0,0 +1,40 @@
+package events
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus(t *testing.T) {
+	b := NewBus(log.New(os.Stderr, "", 0))
+	var all, opened []Kind
+	b.Subscribe("all", func(e *Event) {
+		all = append(all, e.Kind)
+	})
+	b.Subscribe("opened", func(e *Event) {
+		opened = append(opened, e.Kind)
+	}, ChannelOpened)
+	b.Subscribe("panics", func(e *Event) {
+		panic("handler failed")
+	})
+
+	b.Publish(&Event{Kind: HtlcIntercepted})
+	b.Publish(&Event{Kind: ChannelOpened})
+	b.Publish(&Event{Kind: FundingConfirmed})
+	b.Close()
+
+	assert.Equal(t, []Kind{HtlcIntercepted, ChannelOpened, FundingConfirmed}, all)
+	assert.Equal(t, []Kind{ChannelOpened}, opened)
+
+	// Events published after closing are dropped.
+	b.Publish(&Event{Kind: ChannelOpened})
+	assert.Len(t, opened, 1)
+}
+
+func TestPublishNilBus(t *testing.T) {
+	var b *Bus
+	b.Publish(&Event{Kind: ClientConnected})
+}
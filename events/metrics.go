@@ -0,0 +1,32 @@
+package events
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	eventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lspd_events_total",
+		Help: "Events published on the event bus by kind.",
+	}, []string{"node", "kind"})
+	interceptedHtlcsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lspd_intercepted_htlcs_total",
+		Help: "Intercepted htlcs by the decision made about them.",
+	}, []string{"node", "action"})
+)
+
+// SubscribeMetrics counts the events published on the bus.
+func SubscribeMetrics(b *Bus) {
+	b.Subscribe("metrics", func(e *Event) {
+		label := ""
+		if e.Node != nil {
+			label = e.Node.Label()
+		}
+
+		eventsTotal.WithLabelValues(label, string(e.Kind)).Inc()
+		if e.Kind == HtlcIntercepted {
+			interceptedHtlcsTotal.WithLabelValues(label, e.Action).Inc()
+		}
+	})
+}
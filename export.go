@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// runExport downloads the financial export of the admin api for a date
+// range, so operators can do their monthly accounting from a cron job.
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	address := flags.String("admin-address", os.Getenv("ADMIN_LISTEN_ADDRESS"), "host:port or unix:<path> of the lspd admin api")
+	token := flags.String("admin-token", os.Getenv("ADMIN_TOKEN"), "token of the lspd admin api")
+	from := flags.String("from", "", "first day of the export (YYYY-MM-DD), defaults to the start of the previous month")
+	to := flags.String("to", "", "day after the last day of the export (YYYY-MM-DD), defaults to the start of this month")
+	format := flags.String("format", "csv", "json or csv")
+	report := flags.String("report", "channels", "csv report: channels, tokens or summary")
+	output := flags.String("output", "", "file to write the export to, stdout if empty")
+	flags.Parse(args)
+
+	if *address == "" {
+		fmt.Fprintln(os.Stderr, "export: set --admin-address or ADMIN_LISTEN_ADDRESS")
+		os.Exit(1)
+	}
+
+	query := url.Values{}
+	query.Set("format", *format)
+	if *format == "csv" {
+		query.Set("report", *report)
+	}
+	if *from != "" {
+		query.Set("from", *from)
+	}
+	if *to != "" {
+		query.Set("to", *to)
+	}
+
+	client := newAdminClient(*address, *token, 5*time.Minute)
+	resp, err := client.Get("/export?" + query.Encode())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "export: unexpected status %s: %s", resp.Status, msg)
+		os.Exit(1)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "export: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,295 @@
+package exposure
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/breez/lspd/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultCheckInterval = time.Minute
+
+	// Htlcs are only considered resolved once they had time to show up as
+	// pending on the node after they were resumed.
+	resolveGrace = time.Minute
+)
+
+var inflightMsat = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "lspd_inflight_htlcs_msat",
+	Help: "Amount in flight to clients in htlcs forwarded by lspd that are not resolved yet.",
+}, []string{"node"})
+
+// Htlc is an htlc lspd forwarded to a client.
+type Htlc struct {
+	ID           int64
+	PeerID       []byte
+	ChannelPoint string
+	PaymentHash  []byte
+	AmountMsat   uint64
+	CreatedAt    time.Time
+	// Whether the channel was not confirmed yet when the htlc was
+	// forwarded, and didn't confirm since.
+	ZeroConf bool
+}
+
+type Store interface {
+	// AddHtlc records an htlc forwarded to a client.
+	AddHtlc(ctx context.Context, lspNodeID []byte, htlc *Htlc) error
+	// ClientExposure returns the amount of the unresolved htlcs forwarded to
+	// the client.
+	ClientExposure(ctx context.Context, lspNodeID []byte, peerID []byte) (uint64, error)
+	// ListUnresolved returns the unresolved htlcs forwarded by the node,
+	// oldest first.
+	ListUnresolved(ctx context.Context, lspNodeID []byte) ([]*Htlc, error)
+	// Resolve marks the htlc as resolved.
+	Resolve(ctx context.Context, id int64, resolvedAt time.Time) error
+	// ZeroConfExposure returns the amount of the unresolved htlcs forwarded
+	// over unconfirmed channels with the client, or with all clients if
+	// peerID is nil.
+	ZeroConfExposure(ctx context.Context, lspNodeID []byte, peerID []byte) (uint64, error)
+	// SetConfirmed marks the htlcs forwarded over the channel as no longer
+	// zero-conf.
+	SetConfirmed(ctx context.Context, lspNodeID []byte, channelPoint string) error
+}
+
+// Tracker tracks the htlcs lspd forwards to clients until they are settled
+// or failed, which may take a long time for clients receiving on hold
+// invoices. It limits the amount in flight to every client, so a client
+// holding htlcs can't tie up an unbounded amount of the liquidity of the
+// node, and limits the amount in flight over unconfirmed channels.
+type Tracker struct {
+	client      lightning.Client
+	store       Store
+	node        *config.NodeConfig
+	logger      *log.Logger
+	lspNodeID   []byte
+	maxInflight uint64
+	zeroConf    *config.ZeroConfExposureConfig
+	confirmed   confirmedChannels
+	interval    time.Duration
+	ctx         context.Context
+	cancel      context.CancelFunc
+	mtx         sync.Mutex
+	reserveMtx  sync.Mutex
+}
+
+func NewTracker(
+	client lightning.Client,
+	store Store,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) *Tracker {
+	lspNodeID, _ := hex.DecodeString(node.NodePubkey)
+	t := &Tracker{
+		client:    client,
+		store:     store,
+		node:      node,
+		logger:    logger,
+		lspNodeID: lspNodeID,
+		interval:  defaultCheckInterval,
+		zeroConf:  node.ZeroConfExposure,
+	}
+
+	if node.Hodl != nil {
+		t.maxInflight = node.Hodl.MaxClientInflightMsat
+		t.interval = parseDuration(node.Hodl.CheckInterval, defaultCheckInterval, logger)
+	}
+
+	return t
+}
+
+func parseDuration(s string, def time.Duration, logger *log.Logger) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		logger.Printf("WARN: Invalid hodl duration '%s'. Using default %v", s, def)
+		return def
+	}
+
+	return d
+}
+
+func (t *Tracker) Node() *config.NodeConfig {
+	return t.node
+}
+
+// Reserve records an htlc about to be forwarded to the client over the
+// channel. It returns lsperrors.ErrExposureLimit if the htlc would exceed the
+// amount the client may have in flight, and lsperrors.ErrZeroConfExposure if
+// the channel is not confirmed and the htlc would exceed the amount that may
+// be in flight over unconfirmed channels.
+func (t *Tracker) Reserve(ctx context.Context, peerID []byte, channelPoint string, paymentHash []byte, amountMsat uint64) error {
+	t.reserveMtx.Lock()
+	defer t.reserveMtx.Unlock()
+
+	zeroConf := t.zeroConf != nil && t.isZeroConf(peerID, channelPoint)
+	if zeroConf {
+		err := t.checkZeroConf(ctx, peerID, paymentHash, amountMsat)
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.maxInflight > 0 {
+		exposure, err := t.store.ClientExposure(ctx, t.lspNodeID, peerID)
+		if err != nil {
+			return err
+		}
+
+		if exposure+amountMsat > t.maxInflight {
+			t.logger.Printf("Htlc of %v msat for payment %x exceeds the in flight limit of %x: %v msat in flight, limit %v msat",
+				amountMsat, paymentHash, peerID, exposure, t.maxInflight)
+			return lsperrors.ErrExposureLimit
+		}
+	}
+
+	err := t.store.AddHtlc(ctx, t.lspNodeID, &Htlc{
+		PeerID:       peerID,
+		ChannelPoint: channelPoint,
+		PaymentHash:  paymentHash,
+		AmountMsat:   amountMsat,
+		CreatedAt:    time.Now(),
+		ZeroConf:     zeroConf,
+	})
+	if err != nil {
+		return err
+	}
+
+	inflightMsat.WithLabelValues(t.node.Label()).Add(float64(amountMsat))
+	return nil
+}
+
+// Start resolves the tracked htlcs that are no longer in flight on the node
+// periodically.
+func (t *Tracker) Start() error {
+	t.mtx.Lock()
+	t.ctx, t.cancel = context.WithCancel(tenant.NewContext(context.Background(), t.node.Tenant))
+	ctx := t.ctx
+	t.mtx.Unlock()
+
+	t.logger.Printf("exposure: tracking htlcs in flight to clients, limit %v msat per client, checking every %v", t.maxInflight, t.interval)
+	for {
+		t.check(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(t.interval):
+		}
+	}
+}
+
+func (t *Tracker) Stop() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+func (t *Tracker) check(ctx context.Context) {
+	htlcs, err := t.store.ListUnresolved(ctx, t.lspNodeID)
+	if err != nil {
+		t.logger.Printf("exposure: ListUnresolved() error: %v", err)
+		return
+	}
+
+	byPeer := make(map[string][]*Htlc)
+	var peers []string
+	for _, h := range htlcs {
+		peer := hex.EncodeToString(h.PeerID)
+		if _, ok := byPeer[peer]; !ok {
+			peers = append(peers, peer)
+		}
+		byPeer[peer] = append(byPeer[peer], h)
+	}
+
+	now := time.Now()
+	var total uint64
+	for _, peer := range peers {
+		if ctx.Err() != nil {
+			return
+		}
+
+		peerHtlcs := byPeer[peer]
+		pending, err := t.client.ListPendingHtlcs(ctx, peerHtlcs[0].PeerID)
+		if err != nil {
+			t.logger.Printf("exposure: ListPendingHtlcs(%s) error: %v", peer, err)
+			total += sum(peerHtlcs)
+			continue
+		}
+
+		resolved := resolvedHtlcs(peerHtlcs, pending, now)
+		for _, h := range peerHtlcs {
+			if !resolved[h.ID] {
+				total += h.AmountMsat
+				continue
+			}
+
+			err = t.store.Resolve(ctx, h.ID, now)
+			if err != nil {
+				t.logger.Printf("exposure: Resolve(%d) error: %v", h.ID, err)
+				total += h.AmountMsat
+			}
+		}
+	}
+
+	inflightMsat.WithLabelValues(t.node.Label()).Set(float64(total))
+	t.refreshZeroConf(ctx)
+}
+
+// resolvedHtlcs returns the ids of the tracked htlcs that are no longer
+// pending on the node. Tracked htlcs younger than the grace period are never
+// resolved, because they may not have been forwarded yet.
+func resolvedHtlcs(htlcs []*Htlc, pending []*lightning.PendingHtlc, now time.Time) map[int64]bool {
+	// Htlcs of the same payment over the same channel can't be told apart,
+	// so they are counted.
+	counts := make(map[string]int)
+	for _, p := range pending {
+		if p.Incoming {
+			continue
+		}
+		counts[key(p.ChannelPoint, p.PaymentHash)]++
+	}
+
+	resolved := make(map[int64]bool)
+	for _, h := range htlcs {
+		k := key(h.ChannelPoint, h.PaymentHash)
+		if counts[k] > 0 {
+			counts[k]--
+			continue
+		}
+
+		if now.Sub(h.CreatedAt) < resolveGrace {
+			continue
+		}
+
+		resolved[h.ID] = true
+	}
+
+	return resolved
+}
+
+func key(channelPoint string, paymentHash []byte) string {
+	return fmt.Sprintf("%s/%x", channelPoint, paymentHash)
+}
+
+func sum(htlcs []*Htlc) uint64 {
+	var total uint64
+	for _, h := range htlcs {
+		total += h.AmountMsat
+	}
+	return total
+}
@@ -0,0 +1,143 @@
+package exposure
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockStore struct {
+	htlcs []*Htlc
+}
+
+func (s *mockStore) AddHtlc(ctx context.Context, lspNodeID []byte, htlc *Htlc) error {
+	htlc.ID = int64(len(s.htlcs) + 1)
+	s.htlcs = append(s.htlcs, htlc)
+	return nil
+}
+
+func (s *mockStore) ClientExposure(ctx context.Context, lspNodeID []byte, peerID []byte) (uint64, error) {
+	var total uint64
+	for _, h := range s.htlcs {
+		if string(h.PeerID) == string(peerID) {
+			total += h.AmountMsat
+		}
+	}
+	return total, nil
+}
+
+func (s *mockStore) ListUnresolved(ctx context.Context, lspNodeID []byte) ([]*Htlc, error) {
+	return s.htlcs, nil
+}
+
+func (s *mockStore) Resolve(ctx context.Context, id int64, resolvedAt time.Time) error {
+	return nil
+}
+
+func (s *mockStore) ZeroConfExposure(ctx context.Context, lspNodeID []byte, peerID []byte) (uint64, error) {
+	var total uint64
+	for _, h := range s.htlcs {
+		if h.ZeroConf && (peerID == nil || string(h.PeerID) == string(peerID)) {
+			total += h.AmountMsat
+		}
+	}
+	return total, nil
+}
+
+func (s *mockStore) SetConfirmed(ctx context.Context, lspNodeID []byte, channelPoint string) error {
+	for _, h := range s.htlcs {
+		if h.ChannelPoint == channelPoint {
+			h.ZeroConf = false
+		}
+	}
+	return nil
+}
+
+func TestReserve(t *testing.T) {
+	store := &mockStore{}
+	node := &config.NodeConfig{
+		Hodl: &config.HodlConfig{MaxClientInflightMsat: 100_000},
+	}
+	tracker := NewTracker(nil, store, node, log.New(os.Stderr, "", 0))
+	ctx := context.Background()
+	client := []byte{0x02, 0xaa}
+
+	assert.NoError(t, tracker.Reserve(ctx, client, "a:0", []byte{1}, 60_000))
+	err := tracker.Reserve(ctx, client, "a:0", []byte{2}, 50_000)
+	assert.True(t, errors.Is(err, lsperrors.ErrExposureLimit))
+
+	// The limit is per client.
+	assert.NoError(t, tracker.Reserve(ctx, []byte{0x02, 0xbb}, "b:0", []byte{2}, 50_000))
+	assert.Len(t, store.htlcs, 2)
+}
+
+func TestResolvedHtlcs(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	htlcs := []*Htlc{
+		{ID: 1, ChannelPoint: "a:0", PaymentHash: []byte{1}, CreatedAt: old},
+		{ID: 2, ChannelPoint: "a:0", PaymentHash: []byte{1}, CreatedAt: old},
+		{ID: 3, ChannelPoint: "a:0", PaymentHash: []byte{2}, CreatedAt: old},
+		{ID: 4, ChannelPoint: "a:0", PaymentHash: []byte{3}, CreatedAt: now},
+	}
+	pending := []*lightning.PendingHtlc{
+		{ChannelPoint: "a:0", PaymentHash: []byte{1}},
+		// Incoming htlcs are not forwarded to the client.
+		{ChannelPoint: "a:0", PaymentHash: []byte{2}, Incoming: true},
+	}
+
+	resolved := resolvedHtlcs(htlcs, pending, now)
+	assert.Equal(t, map[int64]bool{2: true, 3: true}, resolved)
+}
+
+type mockClient struct {
+	lightning.Client
+	confirmed map[string]bool
+}
+
+func (c *mockClient) GetChannel(peerID []byte, channelPoint wire.OutPoint) (*lightning.GetChannelResult, error) {
+	result := &lightning.GetChannelResult{InitialChannelID: 1}
+	if c.confirmed[channelPoint.String()] {
+		result.ConfirmedChannelID = 2
+	}
+	return result, nil
+}
+
+func TestReserveZeroConf(t *testing.T) {
+	store := &mockStore{}
+	client := &mockClient{confirmed: make(map[string]bool)}
+	node := &config.NodeConfig{
+		ZeroConfExposure: &config.ZeroConfExposureConfig{
+			MaxInflightMsat:       150_000,
+			MaxClientInflightMsat: 100_000,
+		},
+	}
+	tracker := NewTracker(client, store, node, log.New(os.Stderr, "", 0))
+	ctx := context.Background()
+	alice, bob := []byte{0x02, 0xaa}, []byte{0x02, 0xbb}
+	aliceChannel := "0101010101010101010101010101010101010101010101010101010101010101:0"
+	bobChannel := "0202020202020202020202020202020202020202020202020202020202020202:0"
+
+	assert.NoError(t, tracker.Reserve(ctx, alice, aliceChannel, []byte{1}, 80_000))
+	err := tracker.Reserve(ctx, alice, aliceChannel, []byte{2}, 30_000)
+	assert.True(t, errors.Is(err, lsperrors.ErrZeroConfExposure))
+
+	// The limit of the node applies over all clients.
+	err = tracker.Reserve(ctx, bob, bobChannel, []byte{3}, 80_000)
+	assert.True(t, errors.Is(err, lsperrors.ErrZeroConfExposure))
+
+	// Htlcs over confirmed channels don't count.
+	client.confirmed[aliceChannel] = true
+	tracker.refreshZeroConf(ctx)
+	assert.NoError(t, tracker.Reserve(ctx, alice, aliceChannel, []byte{2}, 30_000))
+	assert.NoError(t, tracker.Reserve(ctx, bob, bobChannel, []byte{3}, 80_000))
+}
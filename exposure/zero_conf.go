@@ -0,0 +1,150 @@
+package exposure
+
+import (
+	"context"
+	"sync"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var zeroConfInflightMsat = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "lspd_zero_conf_inflight_msat",
+	Help: "Amount in flight over unconfirmed channels in htlcs forwarded by lspd that are not resolved yet.",
+}, []string{"node"})
+
+// The channels seen confirmed, which never become unconfirmed again.
+type confirmedChannels struct {
+	mtx      sync.RWMutex
+	channels map[string]struct{}
+}
+
+func (c *confirmedChannels) add(channelPoint string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.channels == nil {
+		c.channels = make(map[string]struct{})
+	}
+	c.channels[channelPoint] = struct{}{}
+}
+
+func (c *confirmedChannels) contains(channelPoint string) bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	_, ok := c.channels[channelPoint]
+	return ok
+}
+
+// isZeroConf returns whether the channel is not confirmed yet. Errs on the
+// side of zero-conf if the channel can't be found.
+func (t *Tracker) isZeroConf(peerID []byte, channelPoint string) bool {
+	if channelPoint == "" || t.confirmed.contains(channelPoint) {
+		return false
+	}
+
+	outpoint, err := basetypes.ParseOutPoint(channelPoint)
+	if err != nil {
+		t.logger.Printf("exposure: invalid channel point %s: %v", channelPoint, err)
+		return true
+	}
+
+	ch, err := t.client.GetChannel(peerID, *outpoint)
+	if err != nil {
+		t.logger.Printf("exposure: GetChannel(%x, %s) error: %v", peerID, channelPoint, err)
+		return true
+	}
+
+	if ch.ConfirmedChannelID == 0 {
+		return true
+	}
+
+	t.confirmed.add(channelPoint)
+	return false
+}
+
+// checkZeroConf returns lsperrors.ErrZeroConfExposure if forwarding the htlc
+// over an unconfirmed channel with the client would exceed the limits of the
+// node.
+func (t *Tracker) checkZeroConf(ctx context.Context, peerID []byte, paymentHash []byte, amountMsat uint64) error {
+	if max := t.zeroConf.MaxClientInflightMsat; max > 0 {
+		exposure, err := t.store.ZeroConfExposure(ctx, t.lspNodeID, peerID)
+		if err != nil {
+			return err
+		}
+
+		if exposure+amountMsat > max {
+			t.logger.Printf("Htlc of %v msat for payment %x exceeds the zero-conf limit of %x: %v msat in flight, limit %v msat",
+				amountMsat, paymentHash, peerID, exposure, max)
+			return lsperrors.ErrZeroConfExposure
+		}
+	}
+
+	if max := t.zeroConf.MaxInflightMsat; max > 0 {
+		exposure, err := t.store.ZeroConfExposure(ctx, t.lspNodeID, nil)
+		if err != nil {
+			return err
+		}
+
+		if exposure+amountMsat > max {
+			t.logger.Printf("Htlc of %v msat for payment %x exceeds the zero-conf limit of the node: %v msat in flight, limit %v msat",
+				amountMsat, paymentHash, exposure, max)
+			return lsperrors.ErrZeroConfExposure
+		}
+	}
+
+	return nil
+}
+
+// ChannelConfirmed stops counting the htlcs forwarded over the channel
+// towards the zero-conf limits, once the funding of the channel confirmed.
+func (t *Tracker) ChannelConfirmed(ctx context.Context, peerID []byte, channelPoint string) {
+	t.confirmed.add(channelPoint)
+	err := t.store.SetConfirmed(ctx, t.lspNodeID, channelPoint)
+	if err != nil {
+		t.logger.Printf("exposure: SetConfirmed(%s) error: %v", channelPoint, err)
+	}
+}
+
+// refreshZeroConf marks the unresolved htlcs over channels that confirmed
+// since they were forwarded as no longer zero-conf, so they no longer count
+// towards the zero-conf limits.
+func (t *Tracker) refreshZeroConf(ctx context.Context) {
+	if t.zeroConf == nil {
+		return
+	}
+
+	htlcs, err := t.store.ListUnresolved(ctx, t.lspNodeID)
+	if err != nil {
+		t.logger.Printf("exposure: ListUnresolved() error: %v", err)
+		return
+	}
+
+	checked := make(map[string]bool)
+	var total uint64
+	for _, h := range htlcs {
+		if !h.ZeroConf {
+			continue
+		}
+
+		zeroConf, ok := checked[h.ChannelPoint]
+		if !ok {
+			zeroConf = t.isZeroConf(h.PeerID, h.ChannelPoint)
+			if !zeroConf {
+				err = t.store.SetConfirmed(ctx, t.lspNodeID, h.ChannelPoint)
+				if err != nil {
+					t.logger.Printf("exposure: SetConfirmed(%s) error: %v", h.ChannelPoint, err)
+					zeroConf = true
+				}
+			}
+			checked[h.ChannelPoint] = zeroConf
+		}
+
+		if zeroConf {
+			total += h.AmountMsat
+		}
+	}
+
+	zeroConfInflightMsat.WithLabelValues(t.node.Label()).Set(float64(total))
+}
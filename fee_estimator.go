@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/breez/lspd/bitcoind"
+	"github.com/breez/lspd/chain"
+	"github.com/breez/lspd/mempool"
+)
+
+// newFeeEstimator sets up the chain fee estimator selected with
+// FEE_ESTIMATOR, falling back to the static feerate in STATIC_FEE_RATE if it
+// is set. The estimator is used for the feerate of funding transactions and
+// for the opening fees that cover them.
+func newFeeEstimator(mempoolClient *mempool.MempoolClient) (chain.FeeEstimator, chain.FeeStrategy) {
+	var static *chain.StaticFeeEstimator
+	if s := os.Getenv("STATIC_FEE_RATE"); s != "" {
+		rate, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			log.Fatalf("invalid STATIC_FEE_RATE '%s': %v", s, err)
+		}
+		static, err = chain.NewStaticFeeEstimator(rate)
+		if err != nil {
+			log.Fatalf("failed to initialize static fee estimator: %v", err)
+		}
+	}
+
+	var primary chain.FeeEstimator
+	estimator := strings.ToLower(os.Getenv("FEE_ESTIMATOR"))
+	switch estimator {
+	case "", "mempool":
+		estimator = "mempool"
+		if mempoolClient == nil {
+			log.Fatalf("No mempool url configured.")
+		}
+		primary = mempoolClient
+	case "bitcoind":
+		client, err := bitcoind.NewBitcoindClient(
+			os.Getenv("BITCOIND_RPC_ADDRESS"),
+			os.Getenv("BITCOIND_RPC_USER"),
+			os.Getenv("BITCOIND_RPC_PASSWORD"),
+		)
+		if err != nil {
+			log.Fatalf("failed to initialize bitcoind client: %v", err)
+		}
+		primary = client
+	case "static":
+		if static == nil {
+			log.Fatalf("FEE_ESTIMATOR is static, but STATIC_FEE_RATE is not set.")
+		}
+	default:
+		log.Fatalf("unknown FEE_ESTIMATOR '%s'", estimator)
+	}
+
+	var feeEstimator chain.FeeEstimator
+	switch {
+	case primary == nil:
+		feeEstimator = static
+	case static == nil:
+		feeEstimator = primary
+	default:
+		feeEstimator = chain.NewFallbackFeeEstimator(primary, static)
+	}
+
+	envFeeStrategy := os.Getenv("FEE_PRIORITY")
+	if envFeeStrategy == "" {
+		envFeeStrategy = os.Getenv("MEMPOOL_PRIORITY")
+	}
+
+	// Unknown priorities default to economy.
+	feeStrategy, _ := chain.ParseFeeStrategy(envFeeStrategy)
+
+	log.Printf("using %s for fee estimation, static fallback: %v, fee strategy: %v:%v", estimator, static != nil, envFeeStrategy, feeStrategy)
+	return feeEstimator, feeStrategy
+}
@@ -0,0 +1,256 @@
+// Package fixtures constructs deterministic sphinx onions and hop payloads
+// for given parameters, so the onion rewrite logic can be exercised without
+// captured production blobs.
+package fixtures
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg"
+	sphinx "github.com/lightningnetwork/lightning-onion"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+const (
+	encryptedDataOnionType   tlv.Type = 10
+	blindingPointOnionType   tlv.Type = 12
+	totalAmtMsatBlindedType  tlv.Type = 18
+	keysendPreimageOnionType tlv.Type = 5482373484
+)
+
+// Hex is a byte slice that is hex encoded in json.
+type Hex []byte
+
+func (h Hex) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h)), nil
+}
+
+func (h *Hex) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+
+	*h = b
+	return nil
+}
+
+// Key derives a private key from the seed, so the same seed always results
+// in the same node id and onion.
+func Key(seed string) *btcec.PrivateKey {
+	h := sha256.Sum256([]byte(seed))
+	k, _ := btcec.PrivKeyFromBytes(h[:])
+	return k
+}
+
+// Hop describes the payload of a single hop. Fields with a zero value are
+// left out of the payload, so blinded hops without amount and cltv can be
+// described as well.
+type Hop struct {
+	// Seed of the node key of the hop, see Key.
+	NodeSeed     string `json:"nodeSeed"`
+	AmountMsat   uint64 `json:"amountMsat,omitempty"`
+	OutgoingCltv uint32 `json:"outgoingCltv,omitempty"`
+	// Short channel id to forward to. Zero for the final hop.
+	NextScid uint64 `json:"nextScid,omitempty"`
+	// Payment secret of the payment_data record, set for MPP payments.
+	PaymentSecret Hex `json:"paymentSecret,omitempty"`
+	// Total amount of the payment. Encoded in the payment_data record, or in
+	// total_amount_msat for blinded hops.
+	TotalAmountMsat uint64 `json:"totalAmountMsat,omitempty"`
+	// encrypted_recipient_data of a blinded hop.
+	EncryptedData Hex `json:"encryptedData,omitempty"`
+	// Blinding point, set for the introduction node of a blinded path.
+	BlindingPoint Hex `json:"blindingPoint,omitempty"`
+	// Preimage of a keysend payment.
+	KeysendPreimage Hex `json:"keysendPreimage,omitempty"`
+	// Additional records by tlv type.
+	CustomRecords map[uint64]Hex `json:"customRecords,omitempty"`
+}
+
+// NodeID returns the public key of the hop.
+func (h *Hop) NodeID() *btcec.PublicKey {
+	return Key(h.NodeSeed).PubKey()
+}
+
+// Payload returns the tlv stream of the hop payload.
+func (h *Hop) Payload() ([]byte, error) {
+	var records []tlv.Record
+	if h.AmountMsat != 0 {
+		amt := h.AmountMsat
+		records = append(records, record.NewAmtToFwdRecord(&amt))
+	}
+	if h.OutgoingCltv != 0 {
+		cltv := h.OutgoingCltv
+		records = append(records, record.NewLockTimeRecord(&cltv))
+	}
+	if h.NextScid != 0 {
+		scid := h.NextScid
+		records = append(records, record.NewNextHopIDRecord(&scid))
+	}
+	if h.PaymentSecret != nil {
+		if len(h.PaymentSecret) != 32 {
+			return nil, fmt.Errorf("payment secret should be 32 bytes, got %d", len(h.PaymentSecret))
+		}
+
+		var addr [32]byte
+		copy(addr[:], h.PaymentSecret)
+		records = append(records, record.NewMPP(lnwire.MilliSatoshi(h.TotalAmountMsat), addr).Record())
+	}
+	if h.EncryptedData != nil {
+		data := []byte(h.EncryptedData)
+		records = append(records, tlv.MakePrimitiveRecord(encryptedDataOnionType, &data))
+	}
+	if h.BlindingPoint != nil {
+		point, err := btcec.ParsePubKey(h.BlindingPoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blinding point: %w", err)
+		}
+
+		records = append(records, tlv.MakePrimitiveRecord(blindingPointOnionType, &point))
+	}
+	if h.EncryptedData != nil && h.PaymentSecret == nil && h.TotalAmountMsat != 0 {
+		total := h.TotalAmountMsat
+		records = append(records, tlv.MakeDynamicRecord(
+			totalAmtMsatBlindedType, &total, func() uint64 {
+				return tlv.SizeTUint64(total)
+			}, tlv.ETUint64, tlv.DTUint64,
+		))
+	}
+	if h.KeysendPreimage != nil {
+		preimage := []byte(h.KeysendPreimage)
+		records = append(records, tlv.MakePrimitiveRecord(keysendPreimageOnionType, &preimage))
+	}
+	for t, v := range h.CustomRecords {
+		value := []byte(v)
+		records = append(records, tlv.MakePrimitiveRecord(tlv.Type(t), &value))
+	}
+
+	tlv.SortRecords(records)
+	s, err := tlv.NewStream(records...)
+	if err != nil {
+		return nil, fmt.Errorf("tlv.NewStream() error: %w", err)
+	}
+
+	var b bytes.Buffer
+	err = s.Encode(&b)
+	if err != nil {
+		return nil, fmt.Errorf("tlv encode error: %w", err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// ClnPayload returns the hop payload prefixed with its length, in the format
+// of the payload passed to the htlc_accepted hook of cln.
+func (h *Hop) ClnPayload() ([]byte, error) {
+	payload, err := h.Payload()
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	var buf [8]byte
+	err = sphinx.WriteVarInt(&b, uint64(len(payload)), &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Write(payload)
+	return b.Bytes(), nil
+}
+
+// Onion describes a sphinx onion for a route.
+type Onion struct {
+	// Seed of the session key, see Key.
+	SessionSeed string `json:"sessionSeed"`
+	PaymentHash Hex    `json:"paymentHash"`
+	Hops        []*Hop `json:"hops"`
+}
+
+// Build constructs the serialized onion packet. The packet is deterministic
+// for the same parameters.
+func (o *Onion) Build() ([]byte, error) {
+	if len(o.Hops) == 0 || len(o.Hops) > sphinx.NumMaxHops {
+		return nil, fmt.Errorf("route should have 1 to %d hops, got %d", sphinx.NumMaxHops, len(o.Hops))
+	}
+
+	var path sphinx.PaymentPath
+	for i, h := range o.Hops {
+		payload, err := h.Payload()
+		if err != nil {
+			return nil, fmt.Errorf("hop %d: %w", i, err)
+		}
+
+		hopPayload, err := sphinx.NewHopPayload(nil, payload)
+		if err != nil {
+			return nil, fmt.Errorf("hop %d: sphinx.NewHopPayload() error: %w", i, err)
+		}
+
+		path[i] = sphinx.OnionHop{
+			NodePub:    *h.NodeID(),
+			HopPayload: hopPayload,
+		}
+	}
+
+	packet, err := sphinx.NewOnionPacket(
+		&path, Key(o.SessionSeed), o.PaymentHash,
+		sphinx.DeterministicPacketFiller,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sphinx.NewOnionPacket() error: %w", err)
+	}
+
+	var b bytes.Buffer
+	err = packet.Encode(&b)
+	if err != nil {
+		return nil, fmt.Errorf("packet.Encode() error: %w", err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// Peel decrypts the onion with the key of the node derived from nodeSeed. It
+// returns the payload of the node and the onion for the next hop, which is
+// nil if the node is the final hop.
+func Peel(onion []byte, nodeSeed string, paymentHash []byte) ([]byte, []byte, error) {
+	var packet sphinx.OnionPacket
+	err := packet.Decode(bytes.NewReader(onion))
+	if err != nil {
+		return nil, nil, fmt.Errorf("packet.Decode() error: %w", err)
+	}
+
+	router := sphinx.NewRouter(
+		&sphinx.PrivKeyECDH{PrivKey: Key(nodeSeed)},
+		&chaincfg.MainNetParams,
+		sphinx.NewMemoryReplayLog(),
+	)
+	err = router.Start()
+	if err != nil {
+		return nil, nil, fmt.Errorf("router.Start() error: %w", err)
+	}
+	defer router.Stop()
+
+	processed, err := router.ProcessOnionPacket(&packet, paymentHash, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ProcessOnionPacket() error: %w", err)
+	}
+
+	if processed.Action == sphinx.ExitNode {
+		return processed.Payload.Payload, nil, nil
+	}
+
+	var next bytes.Buffer
+	err = processed.NextPacket.Encode(&next)
+	if err != nil {
+		return nil, nil, fmt.Errorf("NextPacket.Encode() error: %w", err)
+	}
+
+	return processed.Payload.Payload, next.Bytes(), nil
+}
@@ -0,0 +1,261 @@
+// Package formula evaluates the arithmetic expressions operators configure
+// lspd with, like "min(max(payment * 2, 100000), 5000000)". Expressions
+// support numbers, variables, + - * /, parentheses and the min and max
+// functions.
+package formula
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a parsed expression.
+type Expr struct {
+	source string
+	root   node
+}
+
+type node interface {
+	eval(vars map[string]float64) float64
+}
+
+type number float64
+
+func (n number) eval(vars map[string]float64) float64 {
+	return float64(n)
+}
+
+type variable string
+
+func (v variable) eval(vars map[string]float64) float64 {
+	return vars[string(v)]
+}
+
+type negate struct {
+	x node
+}
+
+func (n *negate) eval(vars map[string]float64) float64 {
+	return -n.x.eval(vars)
+}
+
+type binary struct {
+	op   byte
+	l, r node
+}
+
+func (b *binary) eval(vars map[string]float64) float64 {
+	l, r := b.l.eval(vars), b.r.eval(vars)
+	switch b.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	default:
+		return l / r
+	}
+}
+
+type call struct {
+	fn   string
+	args []node
+}
+
+func (c *call) eval(vars map[string]float64) float64 {
+	result := c.args[0].eval(vars)
+	for _, arg := range c.args[1:] {
+		v := arg.eval(vars)
+		if c.fn == "min" {
+			result = math.Min(result, v)
+		} else {
+			result = math.Max(result, v)
+		}
+	}
+	return result
+}
+
+// Parse parses the expression. Only the given variables may be used in it.
+func Parse(s string, vars ...string) (*Expr, error) {
+	p := &parser{src: s, vars: vars}
+	p.next()
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "" {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", p.tok, p.tokPos)
+	}
+
+	return &Expr{source: s, root: root}, nil
+}
+
+// Eval evaluates the expression with the given values of the variables.
+// Division by zero results in an infinite value.
+func (e *Expr) Eval(vars map[string]float64) float64 {
+	return e.root.eval(vars)
+}
+
+func (e *Expr) String() string {
+	return e.source
+}
+
+type parser struct {
+	src    string
+	pos    int
+	tok    string
+	tokPos int
+	vars   []string
+}
+
+// next reads the next token into p.tok. The token is empty at the end of the
+// input.
+func (p *parser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+
+	p.tokPos = p.pos
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case isDigit(c) || c == '.':
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.' || p.src[p.pos] == '_') {
+			p.pos++
+		}
+	case isLetter(c):
+		for p.pos < len(p.src) && (isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = p.src[p.tokPos:p.pos]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// expr parses a sum of terms.
+func (p *parser) expr() (node, error) {
+	l, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok == "+" || p.tok == "-" {
+		op := p.tok[0]
+		p.next()
+		r, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+
+	return l, nil
+}
+
+// term parses a product of factors.
+func (p *parser) term() (node, error) {
+	l, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok == "*" || p.tok == "/" {
+		op := p.tok[0]
+		p.next()
+		r, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+
+	return l, nil
+}
+
+func (p *parser) factor() (node, error) {
+	tok, pos := p.tok, p.tokPos
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case tok == "-":
+		p.next()
+		x, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return &negate{x: x}, nil
+	case tok == "(":
+		p.next()
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, fmt.Errorf("missing ')' at position %d", p.tokPos)
+		}
+		p.next()
+		return x, nil
+	case isDigit(tok[0]) || tok[0] == '.':
+		v, err := strconv.ParseFloat(strings.ReplaceAll(tok, "_", ""), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", tok, pos)
+		}
+		p.next()
+		return number(v), nil
+	case isLetter(tok[0]):
+		p.next()
+		if p.tok == "(" {
+			return p.call(tok, pos)
+		}
+		for _, v := range p.vars {
+			if v == tok {
+				return variable(tok), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown variable '%s' at position %d, use %s", tok, pos, strings.Join(p.vars, ", "))
+	}
+
+	return nil, fmt.Errorf("unexpected '%s' at position %d", tok, pos)
+}
+
+func (p *parser) call(fn string, pos int) (node, error) {
+	if fn != "min" && fn != "max" {
+		return nil, fmt.Errorf("unknown function '%s' at position %d, use min or max", fn, pos)
+	}
+
+	p.next()
+	c := &call{fn: fn}
+	for {
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		c.args = append(c.args, arg)
+
+		if p.tok == ")" {
+			p.next()
+			return c, nil
+		}
+		if p.tok != "," {
+			return nil, fmt.Errorf("expected ',' or ')' at position %d", p.tokPos)
+		}
+		p.next()
+	}
+}
@@ -0,0 +1,46 @@
+package formula
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr    string
+		payment float64
+		result  float64
+	}{
+		{"payment", 1000, 1000},
+		{"payment + 100_000", 1000, 101_000},
+		{"2 + 3 * 4 - 6 / 2", 0, 11},
+		{"(2 + 3) * -payment", 2, -10},
+		{"min(max(payment * 2, 100000), 5000000)", 10_000, 100_000},
+		{"min(max(payment * 2, 100000), 5000000)", 1_000_000, 2_000_000},
+		{"min(max(payment * 2, 100000), 5000000)", 4_000_000, 5_000_000},
+		{"max(1, 2, payment)", 3, 3},
+	}
+
+	for _, tc := range tests {
+		e, err := Parse(tc.expr, "payment")
+		assert.NoError(t, err, tc.expr)
+		assert.Equal(t, tc.result, e.Eval(map[string]float64{"payment": tc.payment}), tc.expr)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"payment +",
+		"amount * 2",
+		"avg(payment, 1)",
+		"max(payment 1)",
+		"(payment",
+		"payment)",
+		"1.2.3",
+	} {
+		_, err := Parse(expr, "payment")
+		assert.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,301 @@
+// Package funding coordinates channel opens funded by an external signer,
+// like a cold wallet or multisig treasury, rather than the node wallet.
+package funding
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	defaultSignTimeout = 2 * time.Minute
+
+	// Number of completed psbt fundings kept in memory for the admin api.
+	historySize = 100
+)
+
+type PsbtState string
+
+const (
+	// The funding psbt waits for the external signer.
+	PsbtAwaitingSignature PsbtState = "awaiting_signature"
+	// The signed psbt was submitted and is handed to the node.
+	PsbtSigned PsbtState = "signed"
+	// The node published the funding transaction.
+	PsbtPublished PsbtState = "published"
+	// The signer rejected the funding psbt.
+	PsbtRejected PsbtState = "rejected"
+	// No signed psbt was submitted before the sign timeout.
+	PsbtExpired PsbtState = "expired"
+	// The node failed to open the channel.
+	PsbtFailed PsbtState = "failed"
+)
+
+var (
+	ErrNotFound   = errors.New("psbt funding not found")
+	ErrNotPending = errors.New("psbt funding is not awaiting a signature")
+	ErrRejected   = errors.New("psbt funding rejected")
+	ErrExpired    = errors.New("psbt funding expired")
+)
+
+// PsbtFunding is a channel open waiting for, or funded by, the external
+// signer.
+type PsbtFunding struct {
+	ID             string            `json:"id"`
+	NodePubkey     string            `json:"nodePubkey"`
+	Destination    string            `json:"destination"`
+	CapacitySat    basetypes.Satoshi `json:"capacitySat"`
+	FundingAddress string            `json:"fundingAddress"`
+	FundingAmount  uint64            `json:"fundingAmountSat"`
+	Psbt           []byte            `json:"psbt"`
+	State          PsbtState         `json:"state"`
+	Error          string            `json:"error,omitempty"`
+	ChannelPoint   string            `json:"channelPoint,omitempty"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	ExpiresAt      time.Time         `json:"expiresAt"`
+	UpdatedAt      time.Time         `json:"updatedAt"`
+}
+
+type signResult struct {
+	psbt   []byte
+	reason string
+}
+
+type psbtRequest struct {
+	funding PsbtFunding
+	result  chan *signResult
+}
+
+// Coordinator opens channels with a funding psbt that is signed externally.
+// The channel open blocks until the signed psbt is submitted through Submit,
+// rejected through Reject, or the sign timeout passes. The htlcs the channel
+// is opened for are held all that time, so an expired funding fails them
+// before they expire.
+type Coordinator struct {
+	funder   lightning.PsbtFunder
+	node     *config.NodeConfig
+	timeout  time.Duration
+	logger   *log.Logger
+	mtx      sync.Mutex
+	requests []*psbtRequest
+}
+
+func NewCoordinator(
+	funder lightning.PsbtFunder,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) *Coordinator {
+	timeout := defaultSignTimeout
+	if node.PsbtFunding.SignTimeout != "" {
+		d, err := time.ParseDuration(node.PsbtFunding.SignTimeout)
+		if err != nil || d <= 0 {
+			logger.Printf("WARN: Invalid psbtFunding signTimeout '%s'. Using default %v", node.PsbtFunding.SignTimeout, defaultSignTimeout)
+		} else {
+			timeout = d
+		}
+	}
+
+	return &Coordinator{
+		funder:  funder,
+		node:    node,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+func (c *Coordinator) Node() *config.NodeConfig {
+	return c.node
+}
+
+// OpenChannel opens the channel with a funding psbt for the external signer.
+func (c *Coordinator) OpenChannel(req *lightning.OpenChannelRequest) (*wire.OutPoint, error) {
+	var r *psbtRequest
+	channelPoint, err := c.funder.OpenChannelPsbt(req, func(fundingAddress string, amountSat uint64, psbt []byte) ([]byte, error) {
+		var err error
+		r, err = c.add(req, fundingAddress, amountSat, psbt)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.await(r)
+	})
+
+	if r != nil {
+		c.finish(r, channelPoint, err)
+	}
+
+	return channelPoint, err
+}
+
+func (c *Coordinator) add(
+	req *lightning.OpenChannelRequest,
+	fundingAddress string,
+	amountSat uint64,
+	psbt []byte,
+) (*psbtRequest, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate psbt funding id: %w", err)
+	}
+
+	now := time.Now().UTC()
+	r := &psbtRequest{
+		funding: PsbtFunding{
+			ID:             hex.EncodeToString(id),
+			NodePubkey:     c.node.NodePubkey,
+			Destination:    hex.EncodeToString(req.Destination),
+			CapacitySat:    req.CapacitySat,
+			FundingAddress: fundingAddress,
+			FundingAmount:  amountSat,
+			Psbt:           psbt,
+			State:          PsbtAwaitingSignature,
+			CreatedAt:      now,
+			ExpiresAt:      now.Add(c.timeout),
+			UpdatedAt:      now,
+		},
+		result: make(chan *signResult, 1),
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.requests = append(c.requests, r)
+	c.prune()
+	c.logger.Printf(
+		"Awaiting signature for psbt funding %s of %v sat to %s, channel to %x.",
+		r.funding.ID,
+		amountSat,
+		fundingAddress,
+		req.Destination,
+	)
+	return r, nil
+}
+
+// prune drops the oldest completed fundings beyond the history size.
+func (c *Coordinator) prune() {
+	completed := 0
+	for _, r := range c.requests {
+		if r.funding.State != PsbtAwaitingSignature {
+			completed++
+		}
+	}
+
+	var requests []*psbtRequest
+	for _, r := range c.requests {
+		if r.funding.State != PsbtAwaitingSignature && completed > historySize {
+			completed--
+			continue
+		}
+
+		requests = append(requests, r)
+	}
+	c.requests = requests
+}
+
+func (c *Coordinator) await(r *psbtRequest) ([]byte, error) {
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case result := <-r.result:
+		return result.signed()
+	case <-timer.C:
+	}
+
+	c.mtx.Lock()
+	if r.funding.State == PsbtAwaitingSignature {
+		r.funding.State = PsbtExpired
+		r.funding.UpdatedAt = time.Now().UTC()
+		c.mtx.Unlock()
+		c.logger.Printf("Psbt funding %s expired.", r.funding.ID)
+		return nil, ErrExpired
+	}
+	c.mtx.Unlock()
+
+	// Submitted or rejected right at the timeout.
+	result := <-r.result
+	return result.signed()
+}
+
+func (r *signResult) signed() ([]byte, error) {
+	if r.psbt == nil {
+		return nil, fmt.Errorf("%w: %s", ErrRejected, r.reason)
+	}
+
+	return r.psbt, nil
+}
+
+func (c *Coordinator) finish(r *psbtRequest, channelPoint *wire.OutPoint, err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	r.funding.UpdatedAt = time.Now().UTC()
+	if err == nil {
+		r.funding.State = PsbtPublished
+		r.funding.ChannelPoint = channelPoint.String()
+		return
+	}
+
+	r.funding.Error = err.Error()
+	if r.funding.State == PsbtSigned {
+		r.funding.State = PsbtFailed
+	}
+}
+
+// Submit hands the signed funding psbt to the waiting channel open.
+func (c *Coordinator) Submit(id string, signedPsbt []byte) error {
+	if len(signedPsbt) == 0 {
+		return fmt.Errorf("empty psbt")
+	}
+
+	return c.resolve(id, PsbtSigned, &signResult{psbt: signedPsbt})
+}
+
+// Reject fails the waiting channel open, and the htlcs held for it.
+func (c *Coordinator) Reject(id string, reason string) error {
+	return c.resolve(id, PsbtRejected, &signResult{reason: reason})
+}
+
+func (c *Coordinator) resolve(id string, state PsbtState, result *signResult) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, r := range c.requests {
+		if r.funding.ID != id {
+			continue
+		}
+
+		if r.funding.State != PsbtAwaitingSignature {
+			return ErrNotPending
+		}
+
+		r.funding.State = state
+		r.funding.Error = result.reason
+		r.funding.UpdatedAt = time.Now().UTC()
+		r.result <- result
+		c.logger.Printf("Psbt funding %s %s.", id, state)
+		return nil
+	}
+
+	return ErrNotFound
+}
+
+// List returns the pending and recently completed fundings, newest first.
+func (c *Coordinator) List() []*PsbtFunding {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	result := make([]*PsbtFunding, 0, len(c.requests))
+	for j := len(c.requests) - 1; j >= 0; j-- {
+		f := c.requests[j].funding
+		result = append(result, &f)
+	}
+
+	return result
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/notifications"
+	lspdrpc "github.com/breez/lspd/rpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The json payloads lspd posts to webhooks, and the responses it expects.
+var webhookPayloads = map[string]interface{}{
+	"payment_received": notifications.PaymentReceivedPayload{},
+	"payment_failed":   notifications.PaymentFailedPayload{},
+	"policy_request":   interceptor.PolicyRequest{},
+	"policy_decision":  interceptor.PolicyDecision{},
+}
+
+// runGenApi writes the machine readable contracts of the apis of this build:
+// the proto descriptors of the grpc services, the OpenAPI spec of the REST
+// gateway and the json schemas of the webhook payloads.
+func runGenApi(args []string) {
+	flags := flag.NewFlagSet("gen-api", flag.ExitOnError)
+	out := flags.String("out", "api", "directory to write the api artifacts to")
+	flags.Parse(args)
+
+	err := os.MkdirAll(*out, 0755)
+	if err != nil {
+		log.Fatalf("failed to create %s: %v", *out, err)
+	}
+
+	v := buildVersion()
+	descriptors, err := protoDescriptors(lspdrpc.File_lspd_proto, notifications.File_notifications_proto)
+	if err != nil {
+		log.Fatalf("failed to encode proto descriptors: %v", err)
+	}
+
+	writeApiFile(*out, "descriptors.pb", descriptors)
+	writeApiJson(*out, "openapi.json", openApiSpec(v))
+	writeApiJson(*out, "webhooks.schema.json", webhookSchemas(v))
+	writeApiFile(*out, "VERSION", []byte(v+"\n"))
+	log.Printf("gen-api: wrote the api artifacts of version %s to %s", v, *out)
+}
+
+func writeApiFile(dir string, name string, data []byte) {
+	err := os.WriteFile(filepath.Join(dir, name), data, 0644)
+	if err != nil {
+		log.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+func writeApiJson(dir string, name string, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatalf("failed to encode %s: %v", name, err)
+	}
+
+	writeApiFile(dir, name, append(data, '\n'))
+}
+
+// protoDescriptors returns the serialized FileDescriptorSet of the files and
+// their imports, as used by grpcurl -protoset or buf.
+func protoDescriptors(files ...protoreflect.FileDescriptor) ([]byte, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var add func(f protoreflect.FileDescriptor)
+	add = func(f protoreflect.FileDescriptor) {
+		if seen[f.Path()] {
+			return
+		}
+		seen[f.Path()] = true
+
+		// Imports go first, so the set can be loaded in order.
+		imports := f.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(f))
+	}
+
+	for _, f := range files {
+		add(f)
+	}
+
+	return proto.Marshal(set)
+}
+
+// openApiSpec returns the OpenAPI 3 spec of the REST gateway.
+func openApiSpec(version string) map[string]interface{} {
+	service := lspdrpc.File_lspd_proto.Services().ByName("ChannelOpener")
+	schemas := make(map[string]interface{})
+	paths := make(map[string]interface{})
+
+	var names []string
+	for name := range (&grpcServer{}).restMethods() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		m := service.Methods().ByName(protoreflect.Name(name))
+		if m == nil {
+			continue
+		}
+
+		addMessageSchema(schemas, m.Input())
+		addMessageSchema(schemas, m.Output())
+		paths["/v1/"+name] = map[string]interface{}{
+			"post": map[string]interface{}{
+				"operationId": name,
+				"requestBody": map[string]interface{}{
+					"required": true,
+					"content":  jsonContent(schemaRef(m.Input())),
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "OK",
+						"content":     jsonContent(schemaRef(m.Output())),
+					},
+					"default": map[string]interface{}{
+						"description": "The grpc status of the failed call.",
+						"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/Status"}),
+					},
+				},
+			},
+		}
+	}
+
+	schemas["Status"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"code":    map[string]interface{}{"type": "integer", "format": "int32"},
+			"message": map[string]interface{}{"type": "string"},
+			"details": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+		},
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "lspd REST gateway",
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"token": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"token": []string{}}},
+	}
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+func schemaName(m protoreflect.MessageDescriptor) string {
+	return strings.ReplaceAll(string(m.FullName()), ".", "_")
+}
+
+func schemaRef(m protoreflect.MessageDescriptor) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + schemaName(m)}
+}
+
+// addMessageSchema adds the schema of the protojson encoding of the message
+// and the messages it refers to.
+func addMessageSchema(schemas map[string]interface{}, m protoreflect.MessageDescriptor) {
+	name := schemaName(m)
+	if _, ok := schemas[name]; ok {
+		return
+	}
+
+	properties := make(map[string]interface{})
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	schemas[name] = schema
+
+	fields := m.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.Get(i)
+		var s map[string]interface{}
+		switch {
+		case f.IsMap():
+			s = map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": fieldSchema(schemas, f.MapValue()),
+			}
+		case f.IsList():
+			s = map[string]interface{}{
+				"type":  "array",
+				"items": fieldSchema(schemas, f),
+			}
+		default:
+			s = fieldSchema(schemas, f)
+		}
+		properties[f.JSONName()] = s
+	}
+}
+
+func fieldSchema(schemas map[string]interface{}, f protoreflect.FieldDescriptor) map[string]interface{} {
+	switch f.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]interface{}{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]interface{}{"type": "integer", "format": "int64", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// protojson encodes 64 bit integers as strings.
+		return map[string]interface{}{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case protoreflect.StringKind:
+		return map[string]interface{}{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		var values []string
+		ev := f.Enum().Values()
+		for i := 0; i < ev.Len(); i++ {
+			values = append(values, string(ev.Get(i).Name()))
+		}
+		return map[string]interface{}{"type": "string", "enum": values}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		addMessageSchema(schemas, f.Message())
+		return schemaRef(f.Message())
+	}
+
+	return map[string]interface{}{}
+}
+
+// webhookSchemas returns the json schemas of the webhook payloads.
+func webhookSchemas(version string) map[string]interface{} {
+	defs := make(map[string]interface{})
+	for name, payload := range webhookPayloads {
+		defs[name] = jsonSchema(reflect.TypeOf(payload))
+	}
+
+	return map[string]interface{}{
+		"$schema":  "https://json-schema.org/draft/2020-12/schema",
+		"$id":      fmt.Sprintf("https://github.com/breez/lspd/api/%s/webhooks.schema.json", version),
+		"title":    "lspd webhook payloads",
+		"$comment": fmt.Sprintf("lspd version %s", version),
+		"$defs":    defs,
+	}
+}
+
+// jsonSchema returns the json schema of the encoding/json encoding of the
+// type.
+func jsonSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			tag := strings.Split(f.Tag.Get("json"), ",")
+			name := tag[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+
+			var s map[string]interface{}
+			if hasTagOption(tag, "string") {
+				s = map[string]interface{}{"type": "string"}
+			} else {
+				s = jsonSchema(f.Type)
+			}
+			properties[name] = s
+
+			if !hasTagOption(tag, "omitempty") && f.Type.Kind() != reflect.Ptr {
+				required = append(required, name)
+			}
+		}
+
+		return map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+
+	return map[string]interface{}{}
+}
+
+func hasTagOption(tag []string, option string) bool {
+	for _, o := range tag[1:] {
+		if o == option {
+			return true
+		}
+	}
+
+	return false
+}
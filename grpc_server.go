@@ -4,57 +4,83 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/breez/lspd/cln"
+	"github.com/breez/lspd/challenge"
 	"github.com/breez/lspd/config"
 	"github.com/breez/lspd/lightning"
-	"github.com/breez/lspd/lnd"
+	"github.com/breez/lspd/macaroon"
 	"github.com/breez/lspd/notifications"
 	lspdrpc "github.com/breez/lspd/rpc"
-	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/breez/lspd/secrets"
+	"github.com/breez/lspd/signer"
+	"github.com/breez/lspd/tenant"
+	"github.com/breez/lspd/tokens"
 	"github.com/caddyserver/certmagic"
-	ecies "github.com/ecies/go/v2"
-	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
-	"golang.org/x/sync/singleflight"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 )
 
 type grpcServer struct {
 	address         string
 	certmagicDomain string
+	certReloader    *certReloader
+	restAddress     string
+	rest            *http.Server
+	rpcTimeout      time.Duration
+	chain           grpc.UnaryServerInterceptor
 	lis             net.Listener
 	s               *grpc.Server
+	nodesMtx        sync.RWMutex
 	nodes           map[string]*node
+	nodesByPubkey   map[string]*node
+	tokenStore      tokens.Store
+	macaroons       *macaroon.Authority
 	c               lspdrpc.ChannelOpenerServer
 	n               notifications.NotificationsServer
+
+	// Closed on Stop, to end the open streams, which GracefulStop would
+	// wait for.
+	stopping chan struct{}
+	stopOnce sync.Once
 }
 
 type nodeContext struct {
 	token string
 	node  *node
+
+	// The caveats of the macaroon the rpc was authenticated with, nil for
+	// tokens.
+	restrictions *macaroon.Restrictions
 }
 
 type node struct {
-	client              lightning.Client
-	nodeConfig          *config.NodeConfig
-	privateKey          *btcec.PrivateKey
-	publicKey           *btcec.PublicKey
-	eciesPrivateKey     *ecies.PrivateKey
-	eciesPublicKey      *ecies.PublicKey
-	openChannelReqGroup singleflight.Group
+	client     lightning.Client
+	nodeConfig *config.NodeConfig
+	signer     signer.Signer
+	keys       *keyRing
+	challenger *challenge.Challenger
+	logger     *log.Logger
 }
 
 func NewGrpcServer(
 	configs []*config.NodeConfig,
 	address string,
+	restAddress string,
 	certmagicDomain string,
+	certReloader *certReloader,
+	rpcTimeout time.Duration,
+	middlewareOrder string,
+	tokenStore tokens.Store,
+	macaroons *macaroon.Authority,
+	resolver *secrets.Resolver,
 	c lspdrpc.ChannelOpenerServer,
 	n notifications.NotificationsServer,
 ) (*grpcServer, error) {
@@ -63,46 +89,33 @@ func NewGrpcServer(
 	}
 
 	nodes := make(map[string]*node)
+	nodesByPubkey := make(map[string]*node)
 	for _, config := range configs {
-		pk, err := hex.DecodeString(config.LspdPrivateKey)
+		lspdSigner, challengeSecret, err := newLspdSigner(context.Background(), resolver, config)
 		if err != nil {
-			return nil, fmt.Errorf("hex.DecodeString(config.lspdPrivateKey=%v) error: %v", config.LspdPrivateKey, err)
-		}
-
-		eciesPrivateKey := ecies.NewPrivateKeyFromBytes(pk)
-		eciesPublicKey := eciesPrivateKey.PublicKey
-		privateKey, publicKey := btcec.PrivKeyFromBytes(pk)
-
-		node := &node{
-			nodeConfig:      config,
-			privateKey:      privateKey,
-			publicKey:       publicKey,
-			eciesPrivateKey: eciesPrivateKey,
-			eciesPublicKey:  eciesPublicKey,
+			return nil, fmt.Errorf("invalid lspd key of node %s: %w", config.Label(), err)
 		}
 
-		if config.Lnd == nil && config.Cln == nil {
-			return nil, fmt.Errorf("node has to be either cln or lnd")
-		}
-
-		if config.Lnd != nil && config.Cln != nil {
-			return nil, fmt.Errorf("node cannot be both cln and lnd")
+		keys, err := newKeyRing(context.Background(), config, resolver)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption keys of node %s: %w", config.Label(), err)
 		}
 
-		if config.Lnd != nil {
-			node.client, err = lnd.NewLndClient(config.Lnd)
-			if err != nil {
-				return nil, err
-			}
+		logger := newNodeLogger(config)
+		node := &node{
+			nodeConfig: config,
+			signer:     lspdSigner,
+			keys:       keys,
+			challenger: challenge.NewChallenger(config, challengeSecret, logger),
+			logger:     logger,
 		}
 
-		if config.Cln != nil {
-			node.client, err = cln.NewClnClient(config.Cln.SocketPath)
-			if err != nil {
-				return nil, err
-			}
+		node.client, err = newLightningNode(config, resolver, node.logger)
+		if err != nil {
+			return nil, err
 		}
 
+		nodesByPubkey[config.NodePubkey] = node
 		for _, token := range config.Tokens {
 			_, exists := nodes[token]
 			if exists {
@@ -113,13 +126,28 @@ func NewGrpcServer(
 		}
 	}
 
-	return &grpcServer{
+	s := &grpcServer{
 		address:         address,
+		restAddress:     restAddress,
 		certmagicDomain: certmagicDomain,
+		certReloader:    certReloader,
+		rpcTimeout:      rpcTimeout,
 		nodes:           nodes,
+		nodesByPubkey:   nodesByPubkey,
+		tokenStore:      tokenStore,
+		macaroons:       macaroons,
 		c:               c,
 		n:               n,
-	}, nil
+		stopping:        make(chan struct{}),
+	}
+
+	chain, err := s.newMiddlewareChain(middlewareOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	s.chain = chain
+	return s, nil
 }
 
 func (s *grpcServer) Start() error {
@@ -140,52 +168,36 @@ func (s *grpcServer) Start() error {
 		}
 	}
 
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		log.Fatalf("failed to run certmagic: %v", err)
+	}
+
 	var lis net.Listener
-	if s.certmagicDomain == "" {
-		var err error
+	if tlsConfig == nil {
 		lis, err = net.Listen("tcp", s.address)
-		if err != nil {
-			log.Fatalf("failed to listen: %v", err)
-		}
 	} else {
-		tlsConfig, err := certmagic.TLS([]string{s.certmagicDomain})
-		if err != nil {
-			log.Fatalf("failed to run certmagic: %v", err)
-		}
 		lis, err = tls.Listen("tcp", s.address, tlsConfig)
-		if err != nil {
-			log.Fatalf("failed to listen: %v", err)
-		}
+	}
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
 	}
 
-	srv := grpc.NewServer(
-		grpc_middleware.WithUnaryServerChain(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-			if md, ok := metadata.FromIncomingContext(ctx); ok {
-				for _, auth := range md.Get("authorization") {
-					if !strings.HasPrefix(auth, "Bearer ") {
-						continue
-					}
-
-					token := strings.Replace(auth, "Bearer ", "", 1)
-					node, ok := s.nodes[token]
-					if !ok {
-						continue
-					}
+	if s.certReloader != nil {
+		go s.certReloader.Start()
+	}
 
-					return handler(context.WithValue(ctx, contextKey("node"), &nodeContext{
-						token: token,
-						node:  node,
-					}), req)
-				}
-			}
-			return nil, status.Errorf(codes.PermissionDenied, "Not authorized")
-		}),
-	)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(s.chain), grpc.StreamInterceptor(s.streamAuthMiddleware))
 	lspdrpc.RegisterChannelOpenerServer(srv, s.c)
 	notifications.RegisterNotificationsServer(srv, s.n)
 
 	s.s = srv
 	s.lis = lis
+	if s.restAddress != "" {
+		s.rest = s.newRestGateway(tlsConfig)
+		go s.serveRestGateway()
+	}
+
 	if err := srv.Serve(lis); err != nil {
 		return fmt.Errorf("failed to serve: %v", err)
 	}
@@ -193,7 +205,188 @@ func (s *grpcServer) Start() error {
 	return nil
 }
 
+// tlsConfig returns the tls config of the public listeners, or nil if they
+// don't use tls.
+func (s *grpcServer) tlsConfig() (*tls.Config, error) {
+	if s.certReloader != nil {
+		return s.certReloader.TLSConfig(), nil
+	}
+
+	if s.certmagicDomain != "" {
+		return certmagic.TLS([]string{s.certmagicDomain})
+	}
+
+	return nil, nil
+}
+
+// ReloadTokens maps the tokens in the node configs to their nodes again,
+// after the configs were reloaded.
+func (s *grpcServer) ReloadTokens() {
+	s.nodesMtx.Lock()
+	defer s.nodesMtx.Unlock()
+	nodes := make(map[string]*node)
+	for _, n := range s.nodesByPubkey {
+		for _, token := range n.nodeConfig.Tokens {
+			nodes[token] = n
+		}
+	}
+
+	s.nodes = nodes
+}
+
+// authenticate returns the context with the node belonging to the bearer
+// token in the authorization metadata. Returns false if there is no valid
+// token.
+func (s *grpcServer) authenticate(ctx context.Context) (context.Context, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, false
+	}
+
+	for _, auth := range md.Get("authorization") {
+		if !strings.HasPrefix(auth, "Bearer ") {
+			continue
+		}
+
+		token := strings.Replace(auth, "Bearer ", "", 1)
+		if strings.HasPrefix(token, macaroon.Prefix) {
+			nc, ok := s.getMacaroonContext(ctx, token)
+			if !ok {
+				continue
+			}
+
+			return withNodeContext(ctx, nc), true
+		}
+
+		s.nodesMtx.RLock()
+		node, ok := s.nodes[token]
+		s.nodesMtx.RUnlock()
+		if !ok {
+			node, ok = s.getTokenNode(ctx, token)
+		}
+		if !ok {
+			continue
+		}
+
+		return withNodeContext(ctx, &nodeContext{
+			token: token,
+			node:  node,
+		}), true
+	}
+
+	return ctx, false
+}
+
+// withNodeContext returns the context of an rpc authenticated for the node.
+// The tenant of the node becomes the tenant of the rpc, so the tenant is
+// always derived from the token.
+func withNodeContext(ctx context.Context, nc *nodeContext) context.Context {
+	ctx = tenant.NewContext(ctx, nc.node.nodeConfig.Tenant)
+	return context.WithValue(ctx, contextKey("node"), nc)
+}
+
+// getTokenNode returns the node belonging to an active token stored in the
+// database. Tokens are stored for the tenant of their node, so the token is
+// looked up in the tenants of the configured nodes and only authenticates
+// for a node of the tenant it was found in.
+func (s *grpcServer) getTokenNode(ctx context.Context, token string) (*node, bool) {
+	for _, id := range s.tenants() {
+		t, err := s.tokenStore.Get(tenant.NewContext(ctx, id), token)
+		if err != nil {
+			log.Printf("tokenStore.Get() error: %v", err)
+			return nil, false
+		}
+
+		if t == nil {
+			continue
+		}
+
+		if !t.Active() {
+			return nil, false
+		}
+
+		s.nodesMtx.RLock()
+		node, ok := s.nodesByPubkey[hex.EncodeToString(t.LspNodeID)]
+		s.nodesMtx.RUnlock()
+		if !ok || node.nodeConfig.Tenant != id {
+			return nil, false
+		}
+
+		return node, true
+	}
+
+	return nil, false
+}
+
+// getMacaroonContext returns the node context of a valid macaroon. The
+// macaroon authenticates as the token it was minted for, as long as that
+// token is valid for the node of the macaroon. Like tokens, macaroons are
+// looked up in the tenants of the configured nodes.
+func (s *grpcServer) getMacaroonContext(ctx context.Context, m string) (*nodeContext, bool) {
+	if s.macaroons == nil {
+		return nil, false
+	}
+
+	for _, id := range s.tenants() {
+		tenantCtx := tenant.NewContext(ctx, id)
+		r, restrictions, err := s.macaroons.Verify(tenantCtx, m, time.Now())
+		if errors.Is(err, macaroon.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Printf("macaroons.Verify() error: %v", err)
+			return nil, false
+		}
+
+		s.nodesMtx.RLock()
+		node, ok := s.nodes[r.Token]
+		s.nodesMtx.RUnlock()
+		if !ok {
+			node, ok = s.getTokenNode(ctx, r.Token)
+		}
+		if !ok || node.nodeConfig.NodePubkey != hex.EncodeToString(r.LspNodeID) ||
+			node.nodeConfig.Tenant != id {
+			return nil, false
+		}
+
+		return &nodeContext{
+			token:        r.Token,
+			node:         node,
+			restrictions: restrictions,
+		}, true
+	}
+
+	return nil, false
+}
+
+// tenants returns the distinct tenants of the configured nodes.
+func (s *grpcServer) tenants() []string {
+	s.nodesMtx.RLock()
+	defer s.nodesMtx.RUnlock()
+	seen := make(map[string]bool)
+	var result []string
+	for _, n := range s.nodesByPubkey {
+		if seen[n.nodeConfig.Tenant] {
+			continue
+		}
+
+		seen[n.nodeConfig.Tenant] = true
+		result = append(result, n.nodeConfig.Tenant)
+	}
+
+	return result
+}
+
 func (s *grpcServer) Stop() {
+	if s.certReloader != nil {
+		s.certReloader.Stop()
+	}
+
+	if rest := s.rest; rest != nil {
+		rest.Shutdown(context.Background())
+	}
+
+	s.stopOnce.Do(func() { close(s.stopping) })
 	srv := s.s
 	if srv != nil {
 		srv.GracefulStop()
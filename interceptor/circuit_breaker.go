@@ -0,0 +1,173 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/chain"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	circuitBreakerOpenGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lspd_circuit_breaker_open",
+		Help: "1 if new channel opens are currently suspended by the circuit breaker, 0 otherwise.",
+	}, []string{"node"})
+	circuitBreakerRejectedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lspd_circuit_breaker_rejected_total",
+		Help: "Number of channel opens rejected by the circuit breaker.",
+	}, []string{"node"})
+)
+
+type CircuitBreakerState struct {
+	Open       bool               `json:"open"`
+	Reason     string             `json:"reason,omitempty"`
+	FeeRate    *float64           `json:"feeRate,omitempty"`
+	BalanceSat *basetypes.Satoshi `json:"balanceSat,omitempty"`
+	CheckedAt  time.Time          `json:"checkedAt"`
+}
+
+// Interval between the checks of the circuit breaker.
+const circuitBreakerInterval = 30 * time.Second
+
+// CircuitBreaker suspends new channel opens while the chain feerate is above
+// the configured maximum, or the on-chain balance is below the configured
+// reserve. The conditions are checked in the background, so htlcs only read
+// the state of the last check.
+type CircuitBreaker struct {
+	client       lightning.Client
+	config       *config.NodeConfig
+	feeEstimator chain.FeeEstimator
+	feeStrategy  chain.FeeStrategy
+	logger       *log.Logger
+	mtx          sync.RWMutex
+	state        CircuitBreakerState
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewCircuitBreaker(
+	client lightning.Client,
+	config *config.NodeConfig,
+	feeEstimator chain.FeeEstimator,
+	feeStrategy chain.FeeStrategy,
+	logger *log.Logger,
+) *CircuitBreaker {
+	// The context is created here, so Stop ends the worker also when it runs
+	// before Start.
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CircuitBreaker{
+		client:       client,
+		config:       config,
+		feeEstimator: feeEstimator,
+		feeStrategy:  feeStrategy,
+		logger:       logger,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+func (b *CircuitBreaker) Node() *config.NodeConfig {
+	return b.config
+}
+
+func (b *CircuitBreaker) Start() error {
+	for {
+		b.check(b.ctx)
+		select {
+		case <-b.ctx.Done():
+			return nil
+		case <-time.After(circuitBreakerInterval):
+		}
+	}
+}
+
+func (b *CircuitBreaker) Stop() {
+	b.cancel()
+}
+
+// State returns the result of the last check.
+func (b *CircuitBreaker) State() CircuitBreakerState {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return b.state
+}
+
+// check evaluates the current chain fee and on-chain balance and updates the
+// state of the circuit breaker. If the fee or the balance can't be fetched,
+// the value of the previous check is used, so neither error opens or closes
+// the circuit breaker by itself.
+func (b *CircuitBreaker) check(ctx context.Context) {
+	previous := b.State()
+	state := CircuitBreakerState{
+		CheckedAt: time.Now().UTC(),
+	}
+
+	if b.config.MaxChainFeeSatPerVByte > 0 && b.feeEstimator != nil {
+		fee, err := b.feeEstimator.EstimateFeeRate(ctx, b.feeStrategy)
+		if err != nil {
+			b.logger.Printf("circuit breaker: failed to estimate chain fee, using the previous fee: %v", err)
+			state.FeeRate = previous.FeeRate
+		} else {
+			state.FeeRate = &fee.SatPerVByte
+		}
+
+		if state.FeeRate != nil && *state.FeeRate > b.config.MaxChainFeeSatPerVByte {
+			state.Open = true
+			state.Reason = fmt.Sprintf(
+				"chain fee %.5f sat/vbyte exceeds maximum %.5f sat/vbyte",
+				*state.FeeRate,
+				b.config.MaxChainFeeSatPerVByte,
+			)
+		}
+	}
+
+	if b.config.MinOnchainReserveSat > 0 {
+		balance, err := b.client.GetWalletBalance()
+		if err != nil {
+			b.logger.Printf("circuit breaker: failed to get wallet balance, using the previous balance: %v", err)
+			state.BalanceSat = previous.BalanceSat
+		} else {
+			state.BalanceSat = &balance.ConfirmedSat
+		}
+
+		if !state.Open && state.BalanceSat != nil && *state.BalanceSat < b.config.MinOnchainReserveSat {
+			state.Open = true
+			state.Reason = fmt.Sprintf(
+				"on-chain balance %d sat is below reserve %d sat",
+				*state.BalanceSat,
+				b.config.MinOnchainReserveSat,
+			)
+		}
+	}
+
+	b.mtx.Lock()
+	b.state = state
+	b.mtx.Unlock()
+
+	if state.Open != previous.Open {
+		if state.Open {
+			b.logger.Printf("circuit breaker opened: %s", state.Reason)
+		} else {
+			b.logger.Printf("circuit breaker closed")
+		}
+	}
+
+	var open float64
+	if state.Open {
+		open = 1
+	}
+	circuitBreakerOpenGauge.WithLabelValues(b.config.Label()).Set(open)
+}
+
+func (b *CircuitBreaker) markRejected() {
+	circuitBreakerRejectedCounter.WithLabelValues(b.config.Label()).Inc()
+}
@@ -0,0 +1,67 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/breez/lspd/chain"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/stretchr/testify/assert"
+)
+
+type breakerBackend struct {
+	simulatedBackend
+	fee     *chain.FeeEstimation
+	balance *lightning.GetWalletBalanceResult
+}
+
+func (b *breakerBackend) EstimateFeeRate(context.Context, chain.FeeStrategy) (*chain.FeeEstimation, error) {
+	if b.fee == nil {
+		return nil, errors.New("no fee")
+	}
+	return b.fee, nil
+}
+
+func (b *breakerBackend) GetWalletBalance() (*lightning.GetWalletBalanceResult, error) {
+	if b.balance == nil {
+		return nil, errors.New("no balance")
+	}
+	return b.balance, nil
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	b := &breakerBackend{
+		fee:     &chain.FeeEstimation{SatPerVByte: 10},
+		balance: &lightning.GetWalletBalanceResult{ConfirmedSat: 100_000},
+	}
+	node := &config.NodeConfig{MaxChainFeeSatPerVByte: 50, MinOnchainReserveSat: 50_000}
+	breaker := NewCircuitBreaker(b, node, b, chain.FeeStrategyFastest, log.New(io.Discard, "", 0))
+
+	breaker.check(context.Background())
+	assert.False(t, breaker.State().Open)
+
+	b.fee = &chain.FeeEstimation{SatPerVByte: 100}
+	breaker.check(context.Background())
+	assert.True(t, breaker.State().Open)
+
+	// Errors keep the values of the previous check, whatever the source.
+	b.fee = nil
+	breaker.check(context.Background())
+	assert.True(t, breaker.State().Open)
+
+	b.fee = &chain.FeeEstimation{SatPerVByte: 10}
+	b.balance = nil
+	breaker.check(context.Background())
+	assert.False(t, breaker.State().Open)
+
+	b.balance = &lightning.GetWalletBalanceResult{ConfirmedSat: 10_000}
+	breaker.check(context.Background())
+	assert.True(t, breaker.State().Open)
+	b.balance = nil
+	breaker.check(context.Background())
+	assert.True(t, breaker.State().Open)
+}
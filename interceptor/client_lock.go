@@ -0,0 +1,124 @@
+package interceptor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/lightning"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Channels opened for a client are remembered this long, so payments to the
+// client that waited for the open can use the channel.
+const clientOpenRetention = 10 * time.Minute
+
+// clientLock serializes channel opens per client pubkey. Opens for different
+// clients don't wait for each other.
+type clientLock struct {
+	mtx   sync.Mutex
+	locks map[string]*clientLockEntry
+}
+
+type clientLockEntry struct {
+	mtx  sync.Mutex
+	refs int
+}
+
+// lock locks the client and returns the function that unlocks it.
+func (l *clientLock) lock(client []byte) func() {
+	key := string(client)
+	l.mtx.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*clientLockEntry)
+	}
+	e, ok := l.locks[key]
+	if !ok {
+		e = &clientLockEntry{}
+		l.locks[key] = e
+	}
+	e.refs++
+	l.mtx.Unlock()
+
+	e.mtx.Lock()
+	return func() {
+		e.mtx.Unlock()
+		l.mtx.Lock()
+		e.refs--
+		if e.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mtx.Unlock()
+	}
+}
+
+type clientOpen struct {
+	channelPoint   *wire.OutPoint
+	commitmentType lightning.CommitmentType
+	openedAt       time.Time
+
+	// The outgoing amounts of the payments using the channel.
+	assignedMsat uint64
+}
+
+// channelReserveMsat is the reserve the client requires on the lsp side of a
+// channel, 1% of the capacity like most implementations require.
+func channelReserveMsat(capacitySat uint64) uint64 {
+	return capacitySat * 1000 / 100
+}
+
+// clientOpens remembers the last channel opened for each client.
+type clientOpens struct {
+	mtx   sync.Mutex
+	opens map[string]*clientOpen
+}
+
+func (o *clientOpens) add(client []byte, channelPoint *wire.OutPoint, commitmentType lightning.CommitmentType, amountMsat uint64, now time.Time) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	if o.opens == nil {
+		o.opens = make(map[string]*clientOpen)
+	}
+	for k, open := range o.opens {
+		if now.Sub(open.openedAt) > clientOpenRetention {
+			delete(o.opens, k)
+		}
+	}
+	o.opens[string(client)] = &clientOpen{
+		channelPoint:   channelPoint,
+		commitmentType: commitmentType,
+		openedAt:       now,
+		assignedMsat:   amountMsat,
+	}
+}
+
+func (o *clientOpens) get(client []byte, now time.Time) *clientOpen {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	open, ok := o.opens[string(client)]
+	if !ok || now.Sub(open.openedAt) > clientOpenRetention {
+		return nil
+	}
+
+	return open
+}
+
+// assign assigns the amount to the channel, if the capacity left after the
+// reserve and the amounts assigned before can carry it.
+func (o *clientOpens) assign(open *clientOpen, capacitySat uint64, amountMsat uint64) bool {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	capacityMsat := capacitySat * 1000
+	used := channelReserveMsat(capacitySat) + open.assignedMsat
+	if used > capacityMsat || capacityMsat-used < amountMsat {
+		return false
+	}
+
+	open.assignedMsat += amountMsat
+	return true
+}
+
+func (o *clientOpens) remove(client []byte) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	delete(o.opens, string(client))
+}
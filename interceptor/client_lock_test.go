@@ -0,0 +1,126 @@
+package interceptor
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/chain"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientLock(t *testing.T) {
+	var l clientLock
+	unlock := l.lock([]byte{1})
+
+	// Other clients don't wait.
+	done := make(chan struct{})
+	go func() {
+		l.lock([]byte{2})()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("lock of another client blocked")
+	}
+
+	// The same client waits.
+	locked := make(chan struct{})
+	go func() {
+		l.lock([]byte{1})()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("lock of the same client didn't block")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+
+	l.mtx.Lock()
+	assert.Empty(t, l.locks)
+	l.mtx.Unlock()
+}
+
+func TestClientOpens(t *testing.T) {
+	var o clientOpens
+	now := time.Now()
+	o.add([]byte{1}, &wire.OutPoint{Index: 1}, "", 1000, now)
+
+	assert.NotNil(t, o.get([]byte{1}, now))
+	assert.Nil(t, o.get([]byte{2}, now))
+	assert.Nil(t, o.get([]byte{1}, now.Add(clientOpenRetention+time.Second)))
+}
+
+// pendingChannelClient reports the channel opened for the client as pending.
+type pendingChannelClient struct {
+	simulatedBackend
+	channel *lightning.PeerChannel
+}
+
+func (c *pendingChannelClient) ListPeerChannels(ctx context.Context, peerID []byte) ([]*lightning.PeerChannel, error) {
+	return []*lightning.PeerChannel{c.channel}, nil
+}
+
+func newPendingChannelInterceptor(channelPoint *wire.OutPoint) *Interceptor {
+	c := &pendingChannelClient{channel: &lightning.PeerChannel{
+		ChannelPoint: channelPoint.String(),
+		CapacitySat:  100_000,
+		Pending:      true,
+	}}
+	return NewInterceptor(c, &config.NodeConfig{}, c, c, nil, chain.FeeStrategyFastest, nil, nil, nil, log.New(io.Discard, "", 0))
+}
+
+func TestReusableChannelRace(t *testing.T) {
+	channelPoint := &wire.OutPoint{Index: 1}
+	i := newPendingChannelInterceptor(channelPoint)
+	client := []byte{2}
+
+	// The channel of 100000 sat was opened for a payment of 60000 sat, 1000
+	// sat are the reserve.
+	i.clientOpens.add(client, channelPoint, "", 60_000_000, time.Now())
+
+	// Two payments race for the channel, only one fits next to the first.
+	var wg sync.WaitGroup
+	results := make([]*clientOpen, 2)
+	for n := range results {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			unlock := i.clientLocks.lock(client)
+			defer unlock()
+			results[n] = i.reusableChannel(client, 30_000_000)
+		}(n)
+	}
+	wg.Wait()
+	assert.True(t, (results[0] == nil) != (results[1] == nil))
+	assert.Equal(t, uint64(90_000_000), i.clientOpens.get(client, time.Now()).assignedMsat)
+
+	// The capacity left is used up exactly.
+	assert.NotNil(t, i.reusableChannel(client, 9_000_000))
+	assert.Nil(t, i.reusableChannel(client, 1))
+}
+
+func TestDeductFeeReusedChannel(t *testing.T) {
+	channelPoint := &wire.OutPoint{Index: 1}
+	i := newPendingChannelInterceptor(channelPoint)
+
+	// A payment using a channel opened for another payment of the client
+	// pays the opening fee it was registered with.
+	amt := i.deductFee([]byte{1}, 100_000, InterceptResult{
+		Action:             INTERCEPT_RESUME_WITH_ONION,
+		ChannelPoint:       channelPoint,
+		TotalAmountMsat:    99_000,
+		incomingAmountMsat: 100_000,
+	})
+	assert.Equal(t, basetypes.MilliSatoshi(99_000), amt)
+}
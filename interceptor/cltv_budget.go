@@ -0,0 +1,114 @@
+package interceptor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// The block height is cached for a while, so intercepting an htlc doesn't
+// cost a GetInfo call. Blocks are ~10 minutes apart, so the cached height
+// lags behind by one block at most.
+const blockHeightCacheDuration = time.Minute
+
+const (
+	// Expected time between blocks, to turn blocks left before an htlc
+	// expires into time.
+	blockInterval = 10 * time.Minute
+
+	defaultLatencyBudgetSafetyBlocks = 6
+)
+
+var latencyBudgetExceededCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_latency_budget_exceeded_total",
+	Help: "Htlcs failed because no decision was made about them within the latency budget.",
+}, []string{"node"})
+
+type blockHeightCache struct {
+	mtx       sync.Mutex
+	height    uint32
+	fetchedAt time.Time
+}
+
+// blockHeight returns the current block height of the node.
+func (i *Interceptor) blockHeight() (uint32, error) {
+	c := &i.blockHeightCache
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.height != 0 && time.Since(c.fetchedAt) < blockHeightCacheDuration {
+		return c.height, nil
+	}
+
+	info, err := i.client.GetInfo()
+	if err != nil {
+		return 0, err
+	}
+
+	c.height = info.BlockHeight
+	c.fetchedAt = time.Now()
+	return c.height, nil
+}
+
+// withinCltvBudget returns whether enough blocks are left before the incoming
+// htlc expires to open a channel and forward the payment. Htlcs are assumed to
+// be within budget if the block height is unknown.
+func (i *Interceptor) withinCltvBudget(incomingExpiry uint32) bool {
+	if i.config.CltvBudget == 0 {
+		return true
+	}
+
+	height, err := i.blockHeight()
+	if err != nil {
+		i.logger.Printf("blockHeight() error, not checking the cltv budget: %v", err)
+		return true
+	}
+
+	return int64(incomingExpiry)-int64(height) >= int64(i.config.CltvBudget)
+}
+
+// latencyBudget returns the time from the interception of an htlc expiring at
+// the incoming expiry until it has to be failed: until safetyBlocks blocks
+// are left before it expires, and at most maxDuration. Returns false if the
+// budget is disabled, or if the block height is unknown and there is no
+// maxDuration.
+func (i *Interceptor) latencyBudget(incomingExpiry uint32) (time.Duration, bool) {
+	cfg := i.config.LatencyBudget
+	if cfg == nil {
+		return 0, false
+	}
+
+	var maxDuration time.Duration
+	if cfg.MaxDuration != "" {
+		d, err := time.ParseDuration(cfg.MaxDuration)
+		if err != nil || d <= 0 {
+			i.logger.Printf("WARN: invalid latencyBudget maxDuration '%s', ignoring it: %v", cfg.MaxDuration, err)
+		} else {
+			maxDuration = d
+		}
+	}
+
+	height, err := i.blockHeight()
+	if err != nil {
+		i.logger.Printf("blockHeight() error, the latency budget is only bounded by maxDuration: %v", err)
+		return maxDuration, maxDuration > 0
+	}
+
+	safetyBlocks := cfg.SafetyBlocks
+	if safetyBlocks == 0 {
+		safetyBlocks = defaultLatencyBudgetSafetyBlocks
+	}
+
+	blocks := int64(incomingExpiry) - int64(height) - int64(safetyBlocks)
+	if blocks < 0 {
+		blocks = 0
+	}
+
+	budget := time.Duration(blocks) * blockInterval
+	if maxDuration > 0 && budget > maxDuration {
+		budget = maxDuration
+	}
+
+	return budget, true
+}
@@ -0,0 +1,97 @@
+package interceptor
+
+import (
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/stretchr/testify/assert"
+)
+
+type heightClient struct {
+	lightning.Client
+	height uint32
+	err    error
+	calls  int
+}
+
+func (c *heightClient) GetInfo() (*lightning.GetInfoResult, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &lightning.GetInfoResult{BlockHeight: c.height}, nil
+}
+
+func TestWithinCltvBudget(t *testing.T) {
+	client := &heightClient{height: 800000}
+	i := &Interceptor{
+		client: client,
+		config: &config.NodeConfig{CltvBudget: 40},
+		logger: log.New(os.Stderr, "", 0),
+	}
+
+	assert.True(t, i.withinCltvBudget(800040))
+	assert.False(t, i.withinCltvBudget(800039))
+	assert.False(t, i.withinCltvBudget(799990))
+	// The block height is fetched once.
+	assert.Equal(t, 1, client.calls)
+}
+
+func TestWithinCltvBudgetUnknownHeight(t *testing.T) {
+	i := &Interceptor{
+		client: &heightClient{err: errors.New("connection refused")},
+		config: &config.NodeConfig{CltvBudget: 40},
+		logger: log.New(os.Stderr, "", 0),
+	}
+
+	assert.True(t, i.withinCltvBudget(100))
+}
+
+func TestWithinCltvBudgetDisabled(t *testing.T) {
+	client := &heightClient{height: 800000}
+	i := &Interceptor{
+		client: client,
+		config: &config.NodeConfig{},
+	}
+
+	assert.True(t, i.withinCltvBudget(1))
+	assert.Equal(t, 0, client.calls)
+}
+
+func TestLatencyBudget(t *testing.T) {
+	client := &heightClient{height: 800000}
+	i := &Interceptor{
+		client: client,
+		config: &config.NodeConfig{LatencyBudget: &config.LatencyBudgetConfig{SafetyBlocks: 10}},
+		logger: log.New(os.Stderr, "", 0),
+	}
+
+	budget, ok := i.latencyBudget(800013)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Minute, budget)
+
+	// Htlcs already within the safety blocks are failed right away.
+	budget, ok = i.latencyBudget(800005)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), budget)
+
+	i.config.LatencyBudget.MaxDuration = "2m"
+	budget, _ = i.latencyBudget(800013)
+	assert.Equal(t, 2*time.Minute, budget)
+
+	// Without the block height, only the max duration bounds the budget.
+	client.err = errors.New("node unavailable")
+	i.blockHeightCache = blockHeightCache{}
+	budget, ok = i.latencyBudget(800013)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, budget)
+
+	i.config.LatencyBudget = nil
+	_, ok = i.latencyBudget(800013)
+	assert.False(t, ok)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/breez/lspd/events"
 )
 
 const (
@@ -121,6 +122,21 @@ func sendChannelMismatchNotification(nodeID string, notFakeChannels, closedChann
 	return nil
 }
 
+// SubscribeOpenChannelEmails sends an email about every channel opened for a
+// payment.
+func SubscribeOpenChannelEmails(bus *events.Bus) {
+	bus.Subscribe("open_channel_email", func(e *events.Event) {
+		sendOpenChannelEmailNotification(
+			e.PaymentHash,
+			e.AmountMsat,
+			e.PeerID,
+			e.CapacitySat,
+			e.ChannelPoint,
+			e.Tag,
+		)
+	}, events.ChannelOpened)
+}
+
 func sendOpenChannelEmailNotification(
 	paymentHash []byte, incomingAmountMsat int64,
 	destination []byte, capacity int64,
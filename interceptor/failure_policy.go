@@ -0,0 +1,157 @@
+package interceptor
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"log"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lsperrors"
+)
+
+// FailureScenario is a reason to fail an htlc, the failure of which can be
+// configured in the failure policy of the node.
+type FailureScenario string
+
+const (
+	// The payment is not registered, or its registration was cancelled.
+	ScenarioUnknownPayment FailureScenario = "unknown_payment"
+	// The payment is too small to open a channel for.
+	ScenarioBelowMinimum FailureScenario = "below_minimum"
+	// The opening fee params of the payment are expired or invalid.
+	ScenarioFeeMismatch FailureScenario = "fee_mismatch"
+	// The node can't afford to open a channel.
+	ScenarioLiquidityExhausted FailureScenario = "liquidity_exhausted"
+	// The client doesn't signal the features required to open a channel, or
+	// its wallet sdk can't use the channel.
+	ScenarioMissingFeatures FailureScenario = "missing_features"
+	// The client is offline and didn't come online after a notification.
+	ScenarioClientOffline FailureScenario = "client_offline"
+)
+
+var failureScenarios = map[FailureScenario]struct{}{
+	ScenarioUnknownPayment:     {},
+	ScenarioBelowMinimum:       {},
+	ScenarioFeeMismatch:        {},
+	ScenarioLiquidityExhausted: {},
+	ScenarioMissingFeatures:    {},
+	ScenarioClientOffline:      {},
+}
+
+// scenarioErrors are the errors htlcs are failed with per scenario.
+var scenarioErrors = map[*lsperrors.Error]FailureScenario{
+	lsperrors.ErrPaymentNotFound:       ScenarioUnknownPayment,
+	lsperrors.ErrBelowMinimum:          ScenarioBelowMinimum,
+	lsperrors.ErrFeeParamsExpired:      ScenarioFeeMismatch,
+	lsperrors.ErrInvalidFeeParams:      ScenarioFeeMismatch,
+	lsperrors.ErrNotEnoughFees:         ScenarioFeeMismatch,
+	lsperrors.ErrInsufficientLiquidity: ScenarioLiquidityExhausted,
+	lsperrors.ErrCircuitBreakerOpen:    ScenarioLiquidityExhausted,
+	lsperrors.ErrExposureLimit:         ScenarioLiquidityExhausted,
+	lsperrors.ErrZeroConfExposure:      ScenarioLiquidityExhausted,
+	lsperrors.ErrMissingFeatures:       ScenarioMissingFeatures,
+	lsperrors.ErrClientIncompatible:    ScenarioMissingFeatures,
+}
+
+var failureCodeNames = map[InterceptFailureCode]string{
+	FAILURE_TEMPORARY_CHANNEL_FAILURE:            "temporary_channel_failure",
+	FAILURE_TEMPORARY_NODE_FAILURE:               "temporary_node_failure",
+	FAILURE_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS: "incorrect_or_unknown_payment_details",
+	FAILURE_PERMANENT_NODE_FAILURE:               "permanent_node_failure",
+	FAILURE_PERMANENT_CHANNEL_FAILURE:            "permanent_channel_failure",
+	FAILURE_UNKNOWN_NEXT_PEER:                    "unknown_next_peer",
+	FAILURE_EXPIRY_TOO_SOON:                      "expiry_too_soon",
+	FAILURE_EXPIRY_TOO_FAR:                       "expiry_too_far",
+}
+
+type failurePolicy struct {
+	code    InterceptFailureCode
+	message []byte
+}
+
+// scenarioOf returns the scenario of the error an htlc is failed with, or an
+// empty scenario if the error has no configurable failure.
+func scenarioOf(err error) FailureScenario {
+	var e *lsperrors.Error
+	if !errors.As(err, &e) {
+		return ""
+	}
+
+	return scenarioErrors[e]
+}
+
+// parseFailurePolicy parses the failure policy of the node config. Invalid
+// entries are logged and ignored, so the default failure is used.
+func parseFailurePolicy(conf *config.NodeConfig, logger *log.Logger) map[FailureScenario]*failurePolicy {
+	policy := make(map[FailureScenario]*failurePolicy)
+	for s, c := range conf.FailurePolicy {
+		scenario := FailureScenario(s)
+		if _, ok := failureScenarios[scenario]; !ok {
+			logger.Printf("WARN: unknown failure policy scenario '%s', ignoring it", s)
+			continue
+		}
+		if c == nil {
+			continue
+		}
+
+		p, err := newFailurePolicy(c)
+		if err != nil {
+			logger.Printf("WARN: invalid failure policy for %s, using the default failure: %v", s, err)
+			continue
+		}
+		if p.message != nil && conf.Lnd != nil {
+			logger.Printf("WARN: failure messages are not supported on lnd, failing %s htlcs with %s", s, p.code)
+		}
+
+		policy[scenario] = p
+	}
+
+	return policy
+}
+
+func newFailurePolicy(c *config.FailurePolicyConfig) (*failurePolicy, error) {
+	p := &failurePolicy{}
+	if c.Message != "" {
+		message, err := hex.DecodeString(c.Message)
+		if err != nil || len(message) < 2 {
+			return nil, errors.New("message is not a hex encoded failure message")
+		}
+
+		p.message = message
+		p.code = InterceptFailureCode(binary.BigEndian.Uint16(message))
+		if c.Code == "" {
+			return p, nil
+		}
+	}
+
+	for code, name := range failureCodeNames {
+		if name == c.Code {
+			if p.message == nil {
+				p.code = code
+			} else if p.code != code {
+				return nil, errors.New("code doesn't match the code of the message")
+			}
+
+			return p, nil
+		}
+	}
+
+	return nil, errors.New("unsupported failure code " + c.Code)
+}
+
+// applyFailurePolicy fails the htlc with the failure configured for the
+// scenario of the result, if any.
+func (i *Interceptor) applyFailurePolicy(result InterceptResult) InterceptResult {
+	p, ok := i.failurePolicy[result.failureScenario]
+	if !ok {
+		return result
+	}
+
+	return InterceptResult{
+		Action:          INTERCEPT_FAIL_HTLC_WITH_CODE,
+		FailureCode:     p.code,
+		FailureMessage:  p.message,
+		failureScenario: result.failureScenario,
+	}
+}
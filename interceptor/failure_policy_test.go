@@ -0,0 +1,53 @@
+package interceptor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFailurePolicy(t *testing.T) {
+	conf := &config.NodeConfig{
+		FailurePolicy: map[string]*config.FailurePolicyConfig{
+			"client_offline":      {Code: "unknown_next_peer"},
+			"fee_mismatch":        {Message: "400f00000000000003e8000000c8"},
+			"liquidity_exhausted": {Code: "temporary_node_failure", Message: "1007"},
+			"below_minimum":       {Code: "not_a_code"},
+			"not_a_scenario":      {Code: "temporary_node_failure"},
+		},
+	}
+
+	policy := parseFailurePolicy(conf, log.New(os.Stderr, "", 0))
+	assert.Len(t, policy, 2)
+	assert.Equal(t, FAILURE_UNKNOWN_NEXT_PEER, policy[ScenarioClientOffline].code)
+	assert.Nil(t, policy[ScenarioClientOffline].message)
+	assert.Equal(t, FAILURE_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS, policy[ScenarioFeeMismatch].code)
+	assert.Len(t, policy[ScenarioFeeMismatch].message, 14)
+}
+
+func TestApplyFailurePolicy(t *testing.T) {
+	i := &Interceptor{
+		failurePolicy: map[FailureScenario]*failurePolicy{
+			ScenarioClientOffline: {code: FAILURE_UNKNOWN_NEXT_PEER},
+			ScenarioFeeMismatch:   {code: FAILURE_PERMANENT_CHANNEL_FAILURE},
+		},
+	}
+
+	result := i.applyFailurePolicy(InterceptResult{Action: INTERCEPT_RESUME, failureScenario: ScenarioClientOffline})
+	assert.Equal(t, INTERCEPT_FAIL_HTLC_WITH_CODE, result.Action)
+	assert.Equal(t, FAILURE_UNKNOWN_NEXT_PEER, result.FailureCode)
+
+	result = i.applyFailurePolicy(failHtlc(fmt.Errorf("%w: expired", lsperrors.ErrFeeParamsExpired)))
+	assert.Equal(t, FAILURE_PERMANENT_CHANNEL_FAILURE, result.FailureCode)
+
+	result = i.applyFailurePolicy(failHtlc(lsperrors.ErrCltvDeltaTooLow))
+	assert.Equal(t, FAILURE_TEMPORARY_CHANNEL_FAILURE, result.FailureCode)
+
+	result = i.applyFailurePolicy(InterceptResult{Action: INTERCEPT_RESUME})
+	assert.Equal(t, INTERCEPT_RESUME, result.Action)
+}
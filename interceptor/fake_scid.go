@@ -0,0 +1,195 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/tenant"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+const (
+	// Fake scids point at a block within this many blocks below the tip, so
+	// they look like the scids of recent channels.
+	fakeScidBlockRange = 2016
+
+	// Fake scids point at a transaction index below this, like real scids.
+	fakeScidMaxTxIndex = 4000
+
+	// Number of candidates tried before giving up assigning a fake scid.
+	fakeScidAttempts = 10
+)
+
+// The fake scids assigned to registered payments, so htlcs to them are
+// matched to their registration without a database lookup. The scids are
+// mapped to the payment hash they are assigned to.
+type fakeScids struct {
+	mtx   sync.RWMutex
+	scids map[basetypes.ShortChannelID]string
+}
+
+func (f *fakeScids) add(scid basetypes.ShortChannelID, paymentHash []byte) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.scids == nil {
+		f.scids = make(map[basetypes.ShortChannelID]string)
+	}
+	f.scids[scid] = hex.EncodeToString(paymentHash)
+}
+
+func (f *fakeScids) contains(scid basetypes.ShortChannelID) bool {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	_, ok := f.scids[scid]
+	return ok
+}
+
+// list returns the known fake scids.
+func (f *fakeScids) list() []basetypes.ShortChannelID {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	scids := make([]basetypes.ShortChannelID, 0, len(f.scids))
+	for scid := range f.scids {
+		scids = append(scids, scid)
+	}
+	return scids
+}
+
+func (f *fakeScids) remove(scid basetypes.ShortChannelID) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	delete(f.scids, scid)
+}
+
+// removePayment removes the fake scid assigned to the payment hash.
+func (f *fakeScids) removePayment(paymentHash []byte) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	h := hex.EncodeToString(paymentHash)
+	for scid, hash := range f.scids {
+		if hash == h {
+			delete(f.scids, scid)
+		}
+	}
+}
+
+// LoadScids loads the fake scids assigned to registered payments and the
+// aliases of the channels lspd opened. They are loaded when the replica is
+// elected to lead the node, because the previous leader and the other
+// replicas assigned scids the replica doesn't know.
+func (i *Interceptor) LoadScids(ctx context.Context) error {
+	lspNodeID, _ := hex.DecodeString(i.config.NodePubkey)
+	scids, err := i.store.ListFakeScids(tenant.NewContext(ctx, i.config.Tenant), lspNodeID)
+	if err != nil {
+		return fmt.Errorf("ListFakeScids() error: %w", err)
+	}
+
+	for scid, paymentHash := range scids {
+		i.fakeScids.add(basetypes.ShortChannelID(scid), paymentHash)
+	}
+
+	if i.aliases == nil {
+		return nil
+	}
+
+	err = i.aliases.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("aliases.Load() error: %w", err)
+	}
+
+	return nil
+}
+
+// PruneScids removes the fake scids of registrations that were cancelled, or
+// expired without a channel being opened, also by other replicas. Scids
+// assigned while the registrations are listed are kept.
+func (i *Interceptor) PruneScids(ctx context.Context) error {
+	known := i.fakeScids.list()
+	lspNodeID, _ := hex.DecodeString(i.config.NodePubkey)
+	scids, err := i.store.ListFakeScids(tenant.NewContext(ctx, i.config.Tenant), lspNodeID)
+	if err != nil {
+		return fmt.Errorf("ListFakeScids() error: %w", err)
+	}
+
+	pruned := 0
+	for _, scid := range known {
+		if _, ok := scids[uint64(scid)]; ok {
+			continue
+		}
+
+		i.fakeScids.remove(scid)
+		pruned++
+	}
+
+	if pruned > 0 {
+		i.logger.Printf("Removed %d fake scids of cancelled or expired registrations", pruned)
+	}
+
+	return nil
+}
+
+// AssignFakeScid assigns an unused short channel id to the registered
+// payment, which the client puts in the route hint of its invoice. Htlcs to
+// the fake scid are matched to the registration by the scid. A payment that
+// has a fake scid keeps it when it is registered again.
+func (i *Interceptor) AssignFakeScid(ctx context.Context, paymentHash []byte) (basetypes.ShortChannelID, error) {
+	height, err := i.blockHeight()
+	if err != nil {
+		return 0, fmt.Errorf("blockHeight() error: %w", err)
+	}
+
+	for attempt := 0; attempt < fakeScidAttempts; attempt++ {
+		candidate := newFakeScid(height)
+
+		// The scid must not be a channel of the node.
+		peerID, err := i.client.GetPeerId(&candidate)
+		if err != nil {
+			return 0, fmt.Errorf("GetPeerId(%s) error: %w", candidate.ToString(), err)
+		}
+		if peerID != nil {
+			continue
+		}
+
+		assigned, err := i.store.AssignFakeScid(ctx, paymentHash, uint64(candidate))
+		if err != nil {
+			return 0, err
+		}
+
+		if assigned == 0 {
+			continue
+		}
+
+		scid := basetypes.ShortChannelID(assigned)
+		i.fakeScids.add(scid, paymentHash)
+		return scid, nil
+	}
+
+	return 0, fmt.Errorf("no unused fake scid found in %d attempts", fakeScidAttempts)
+}
+
+// newFakeScid returns a random scid in the recent blocks below the block
+// height.
+func newFakeScid(blockHeight uint32) basetypes.ShortChannelID {
+	block := blockHeight
+	if block > fakeScidBlockRange {
+		block -= 1 + uint32(rand.Intn(fakeScidBlockRange))
+	}
+
+	return basetypes.ShortChannelID(lnwire.ShortChannelID{
+		BlockHeight: block,
+		TxIndex:     uint32(rand.Intn(fakeScidMaxTxIndex)),
+		TxPosition:  uint16(rand.Intn(4)),
+	}.ToUint64())
+}
+
+// probeHash returns the payment hash of probes for the payment hash, as sent
+// by senders implementing the probing-01: prefix.
+func probeHash(paymentHash []byte) []byte {
+	h := sha256.Sum256(append([]byte("probing-01:"), paymentHash...))
+	return h[:]
+}
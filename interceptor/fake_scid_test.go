@@ -0,0 +1,123 @@
+package interceptor
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/chain"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFakeScid(t *testing.T) {
+	for n := 0; n < 100; n++ {
+		scid := lnwire.NewShortChanIDFromInt(uint64(newFakeScid(800000)))
+		assert.True(t, scid.BlockHeight < 800000)
+		assert.True(t, scid.BlockHeight >= 800000-fakeScidBlockRange)
+		assert.True(t, scid.TxIndex < fakeScidMaxTxIndex)
+	}
+
+	// Below the block range the tip is used.
+	scid := lnwire.NewShortChanIDFromInt(uint64(newFakeScid(100)))
+	assert.Equal(t, uint32(100), scid.BlockHeight)
+}
+
+func TestFakeScids(t *testing.T) {
+	var f fakeScids
+	assert.False(t, f.contains(1))
+	f.add(1, []byte{1})
+	f.add(2, []byte{2})
+	assert.True(t, f.contains(1))
+	assert.False(t, f.contains(3))
+
+	f.removePayment([]byte{1})
+	assert.False(t, f.contains(1))
+	assert.True(t, f.contains(2))
+}
+
+// replicaStore is the store of one replica, on the database shared by the
+// replicas.
+type replicaStore struct {
+	simulatedBackend
+	scids map[uint64][]byte
+}
+
+func (s *replicaStore) GetInfo() (*lightning.GetInfoResult, error) {
+	return &lightning.GetInfoResult{BlockHeight: 800000}, nil
+}
+
+func (s *replicaStore) GetPeerId(scid *basetypes.ShortChannelID) ([]byte, error) {
+	return nil, nil
+}
+
+func (s *replicaStore) AssignFakeScid(ctx context.Context, paymentHash []byte, fakeScid uint64) (uint64, error) {
+	s.scids[fakeScid] = paymentHash
+	return fakeScid, nil
+}
+
+func (s *replicaStore) ListFakeScids(ctx context.Context, lspNodeID []byte) (map[uint64][]byte, error) {
+	scids := make(map[uint64][]byte)
+	for scid, paymentHash := range s.scids {
+		scids[scid] = paymentHash
+	}
+	return scids, nil
+}
+
+func (s *replicaStore) PaymentInfoByScid(_ context.Context, fakeScid uint64) (string, *OpeningFeeParams, []byte, []byte, []byte, int64, int64, *wire.OutPoint, *string, error) {
+	paymentHash, ok := s.scids[fakeScid]
+	if !ok {
+		return "", nil, nil, nil, nil, 0, 0, nil, nil, nil
+	}
+	return "", nil, paymentHash, []byte{1}, nil, 0, 0, nil, nil, nil
+}
+
+func TestReplicatedFakeScids(t *testing.T) {
+	db := make(map[uint64][]byte)
+	newReplica := func() *Interceptor {
+		s := &replicaStore{scids: db}
+		i := NewInterceptor(s, &config.NodeConfig{}, s, s, nil, chain.FeeStrategyFastest, nil, nil, nil, log.New(io.Discard, "", 0))
+		i.SetReplicated(true)
+		return i
+	}
+	leader := newReplica()
+	follower := newReplica()
+
+	scid, err := leader.AssignFakeScid(context.Background(), []byte{2})
+	assert.NoError(t, err)
+	assert.True(t, leader.IsLspdScid(scid))
+
+	// The scid assigned by the leader is found in the database.
+	assert.True(t, follower.IsLspdScid(scid))
+	assert.False(t, follower.IsLspdScid(scid+1))
+
+	// A replica elected later loads the scids assigned before.
+	elected := newReplica()
+	assert.NoError(t, elected.LoadScids(context.Background()))
+	elected.SetReplicated(false)
+	assert.True(t, elected.IsLspdScid(scid))
+}
+
+func TestPruneScids(t *testing.T) {
+	db := make(map[uint64][]byte)
+	s := &replicaStore{scids: db}
+	i := NewInterceptor(s, &config.NodeConfig{}, s, s, nil, chain.FeeStrategyFastest, nil, nil, nil, log.New(io.Discard, "", 0))
+	scid1, err := i.AssignFakeScid(context.Background(), []byte{1})
+	assert.NoError(t, err)
+	scid2, err := i.AssignFakeScid(context.Background(), []byte{2})
+	assert.NoError(t, err)
+
+	// The registration was cancelled by another replica, or expired.
+	delete(db, uint64(scid1))
+	assert.NoError(t, i.PruneScids(context.Background()))
+	assert.False(t, i.IsLspdScid(scid1))
+	assert.True(t, i.IsLspdScid(scid2))
+
+	i.RegistrationCancelled([]byte{2})
+	assert.False(t, i.IsLspdScid(scid2))
+}
@@ -0,0 +1,51 @@
+package interceptor
+
+import (
+	"log"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+)
+
+// The private zero conf channels opened for payments need these features.
+var defaultRequiredFeatures = []lightning.Feature{
+	lightning.FeatureZeroConf,
+	lightning.FeatureScidAlias,
+}
+
+// parseRequiredFeatures returns the features clients have to signal to get a
+// channel. Unknown features are logged and ignored.
+func parseRequiredFeatures(conf *config.NodeConfig, logger *log.Logger) []lightning.Feature {
+	if conf.RequiredFeatures == nil {
+		return defaultRequiredFeatures
+	}
+
+	var features []lightning.Feature
+	for _, f := range conf.RequiredFeatures {
+		feature := lightning.Feature(f)
+		if !lightning.IsKnownFeature(feature) {
+			logger.Printf("WARN: unknown required feature '%s', ignoring it", f)
+			continue
+		}
+
+		features = append(features, feature)
+	}
+
+	return features
+}
+
+// missingFeatures returns the required features the client doesn't signal.
+// The open is attempted anyway if the features of the client are unknown.
+func (i *Interceptor) missingFeatures(destination []byte) []lightning.Feature {
+	if len(i.requiredFeatures) == 0 {
+		return nil
+	}
+
+	hasBit, err := i.client.PeerFeatures(destination)
+	if err != nil {
+		i.logger.Printf("PeerFeatures(%x) error, not checking the client features: %v", destination, err)
+		return nil
+	}
+
+	return lightning.MissingFeatures(hasBit, i.requiredFeatures)
+}
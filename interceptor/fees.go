@@ -0,0 +1,84 @@
+package interceptor
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+)
+
+// Ledger entries of payments without new htlcs for this duration are
+// dropped.
+const feeLedgerExpiry = 24 * time.Hour
+
+// feeLedger tracks the amounts forwarded for the htlcs of registered
+// payments, so the fee deducted from the parts of a payment adds up to the
+// fee promised at registration, instead of every part rounding in favor of
+// the lsp.
+type feeLedger struct {
+	mtx       sync.Mutex
+	payments  map[string]*ledgerEntry
+	lastPrune time.Time
+}
+
+type ledgerEntry struct {
+	in      basetypes.MilliSatoshi
+	out     basetypes.MilliSatoshi
+	updated time.Time
+}
+
+// forward returns the amount to forward for an htlc of the payment. The
+// amounts forwarded so far are the incoming amounts so far minus their share
+// of the promised fee, rounded up.
+func (l *feeLedger) forward(
+	paymentHash string,
+	incomingAmountMsat int64,
+	outgoingAmountMsat int64,
+	htlcAmount basetypes.MilliSatoshi,
+) basetypes.MilliSatoshi {
+	if incomingAmountMsat <= 0 {
+		return htlcAmount
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.payments == nil {
+		l.payments = make(map[string]*ledgerEntry)
+	}
+
+	now := time.Now()
+	if now.Sub(l.lastPrune) > time.Hour {
+		for h, e := range l.payments {
+			if now.Sub(e.updated) > feeLedgerExpiry {
+				delete(l.payments, h)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	e, ok := l.payments[paymentHash]
+	if !ok {
+		e = &ledgerEntry{}
+		l.payments[paymentHash] = e
+	}
+
+	e.in += htlcAmount
+	e.updated = now
+
+	// ceil(outgoing * in / incoming)
+	var out, rem big.Int
+	out.Mul(big.NewInt(outgoingAmountMsat), new(big.Int).SetUint64(uint64(e.in)))
+	out.QuoRem(&out, big.NewInt(incomingAmountMsat), &rem)
+	if rem.Sign() > 0 {
+		out.Add(&out, big.NewInt(1))
+	}
+
+	amt := basetypes.MilliSatoshi(out.Uint64()) - e.out
+	if amt > htlcAmount {
+		amt = htlcAmount
+	}
+
+	e.out += amt
+	return amt
+}
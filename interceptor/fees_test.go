@@ -0,0 +1,72 @@
+package interceptor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeeLedgerParts(t *testing.T) {
+	l := &feeLedger{}
+
+	// Every part rounds up, but the parts add up to the outgoing amount.
+	var total basetypes.MilliSatoshi
+	for _, part := range []basetypes.MilliSatoshi{333, 333, 334} {
+		amt := l.forward("hash", 1000, 999, part)
+		assert.Equal(t, basetypes.MilliSatoshi(333), amt)
+		total += amt
+	}
+	assert.Equal(t, basetypes.MilliSatoshi(999), total)
+
+	// The later parts make up for the rounding of the first.
+	total = 0
+	for _, want := range []basetypes.MilliSatoshi{1, 1, 0} {
+		amt := l.forward("other", 3, 2, 1)
+		assert.Equal(t, want, amt)
+		total += amt
+	}
+	assert.Equal(t, basetypes.MilliSatoshi(2), total)
+}
+
+func TestFeeLedgerRetry(t *testing.T) {
+	l := &feeLedger{}
+
+	// A retried htlc is forwarded the same amount as the first attempt.
+	assert.Equal(t, basetypes.MilliSatoshi(900), l.forward("hash", 1000, 900, 1000))
+	assert.Equal(t, basetypes.MilliSatoshi(900), l.forward("hash", 1000, 900, 1000))
+
+	// Also when it is a part of the payment.
+	assert.Equal(t, basetypes.MilliSatoshi(333), l.forward("parts", 1000, 999, 333))
+	assert.Equal(t, basetypes.MilliSatoshi(333), l.forward("parts", 1000, 999, 333))
+}
+
+func TestFeeLedgerCapsAmount(t *testing.T) {
+	l := &feeLedger{}
+
+	// More than the htlc carries is never forwarded.
+	assert.Equal(t, basetypes.MilliSatoshi(1000), l.forward("hash", 1000, 1200, 1000))
+
+	// Without an incoming amount the htlc is forwarded as is.
+	assert.Equal(t, basetypes.MilliSatoshi(1000), l.forward("none", 0, 900, 1000))
+}
+
+func TestFeeLedgerPrune(t *testing.T) {
+	l := &feeLedger{}
+	l.forward("old", 1000, 900, 500)
+	l.forward("recent", 1000, 900, 500)
+
+	now := time.Now()
+	l.payments["old"].updated = now.Add(-feeLedgerExpiry - time.Minute)
+	l.payments["recent"].updated = now.Add(-feeLedgerExpiry + time.Hour)
+	l.lastPrune = now.Add(-2 * time.Hour)
+
+	l.forward("new", 1000, 900, 500)
+	assert.NotContains(t, l.payments, "old")
+	assert.Contains(t, l.payments, "recent")
+	assert.Contains(t, l.payments, "new")
+
+	// A pruned payment starts over.
+	assert.Equal(t, basetypes.MilliSatoshi(450), l.forward("old", 1000, 900, 500))
+}
@@ -0,0 +1,73 @@
+package interceptor
+
+import "sync"
+
+// holds tracks the payment hashes of the htlcs that are currently being
+// intercepted, so a change to the registration of a payment takes effect on
+// the htlcs held for it right away, rather than after the channel open or
+// notification timeout.
+type holds struct {
+	mtx   sync.Mutex
+	holds map[string]*hold
+}
+
+type hold struct {
+	refs        int
+	invalidated chan struct{}
+	failureCode InterceptFailureCode
+	// Scenario of the failure, to look up in the failure policy.
+	failureScenario FailureScenario
+}
+
+func (h *holds) acquire(paymentHash string) *hold {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.holds == nil {
+		h.holds = make(map[string]*hold)
+	}
+
+	hd, ok := h.holds[paymentHash]
+	if !ok {
+		hd = &hold{invalidated: make(chan struct{})}
+		h.holds[paymentHash] = hd
+	}
+
+	hd.refs++
+	return hd
+}
+
+func (h *holds) release(paymentHash string, hd *hold) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	hd.refs--
+	if hd.refs == 0 && h.holds[paymentHash] == hd {
+		delete(h.holds, paymentHash)
+	}
+}
+
+// invalidate fails the htlcs held for the payment hash with the failure code.
+// Htlcs arriving afterwards get a new hold. Returns the number of htlcs that
+// were held.
+func (h *holds) invalidate(paymentHash string, failureCode InterceptFailureCode, scenario FailureScenario) int {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	hd, ok := h.holds[paymentHash]
+	if !ok {
+		return 0
+	}
+
+	delete(h.holds, paymentHash)
+	hd.failureCode = failureCode
+	hd.failureScenario = scenario
+	close(hd.invalidated)
+	return hd.refs
+}
+
+func (hd *hold) isInvalidated() bool {
+	select {
+	case <-hd.invalidated:
+		return true
+	default:
+		return false
+	}
+}
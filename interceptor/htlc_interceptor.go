@@ -4,4 +4,8 @@ type HtlcInterceptor interface {
 	Start() error
 	Stop() error
 	WaitStarted()
+
+	// Alive returns whether the interceptor currently has an open htlc stream
+	// with its node.
+	Alive() bool
 }
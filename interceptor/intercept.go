@@ -4,16 +4,25 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
-	"math/big"
+	"math"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/breez/lspd/basetypes"
 	"github.com/breez/lspd/chain"
 	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/events"
+	"github.com/breez/lspd/funding"
 	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/lsperrors"
 	"github.com/breez/lspd/notifications"
+	"github.com/breez/lspd/quota"
+	"github.com/breez/lspd/tenant"
+	"github.com/breez/lspd/tokens"
 	"github.com/btcsuite/btcd/wire"
 	"golang.org/x/sync/singleflight"
 )
@@ -24,81 +33,270 @@ const (
 	INTERCEPT_RESUME              InterceptAction = 0
 	INTERCEPT_RESUME_WITH_ONION   InterceptAction = 1
 	INTERCEPT_FAIL_HTLC_WITH_CODE InterceptAction = 2
+	// Forward the htlc with the original onion and amount over the channel
+	// in the result.
+	INTERCEPT_RESUME_ON_CHANNEL InterceptAction = 3
 )
 
 type InterceptFailureCode uint16
 
 var (
-	FAILURE_TEMPORARY_CHANNEL_FAILURE            InterceptFailureCode = 0x1007
-	FAILURE_TEMPORARY_NODE_FAILURE               InterceptFailureCode = 0x2002
-	FAILURE_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS InterceptFailureCode = 0x400F
+	FAILURE_TEMPORARY_CHANNEL_FAILURE            = InterceptFailureCode(lsperrors.FailureTemporaryChannelFailure)
+	FAILURE_TEMPORARY_NODE_FAILURE               = InterceptFailureCode(lsperrors.FailureTemporaryNodeFailure)
+	FAILURE_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS = InterceptFailureCode(lsperrors.FailureIncorrectOrUnknownPaymentDetails)
+	FAILURE_PERMANENT_NODE_FAILURE               = InterceptFailureCode(lsperrors.FailurePermanentNodeFailure)
+	FAILURE_PERMANENT_CHANNEL_FAILURE            = InterceptFailureCode(lsperrors.FailurePermanentChannelFailure)
+	FAILURE_UNKNOWN_NEXT_PEER                    = InterceptFailureCode(lsperrors.FailureUnknownNextPeer)
+	FAILURE_EXPIRY_TOO_SOON                      = InterceptFailureCode(lsperrors.FailureExpiryTooSoon)
+	FAILURE_EXPIRY_TOO_FAR                       = InterceptFailureCode(lsperrors.FailureExpiryTooFar)
 )
 
+// failHtlc returns the result that fails the htlc with the failure code of the
+// error that caused it.
+func failHtlc(err error) InterceptResult {
+	result := InterceptResult{
+		Action:          INTERCEPT_FAIL_HTLC_WITH_CODE,
+		FailureCode:     InterceptFailureCode(lsperrors.FailureCode(err, uint16(FAILURE_TEMPORARY_CHANNEL_FAILURE))),
+		failureScenario: scenarioOf(err),
+	}
+	if e := lsperrors.From(err); e != nil {
+		result.failureReason = strings.ToLower(e.Reason)
+	}
+
+	return result
+}
+
 type InterceptResult struct {
 	Action          InterceptAction
 	FailureCode     InterceptFailureCode
 	Destination     []byte
-	AmountMsat      uint64
-	TotalAmountMsat uint64
+	AmountMsat      basetypes.MilliSatoshi
+	TotalAmountMsat basetypes.MilliSatoshi
 	ChannelPoint    *wire.OutPoint
 	ChannelId       uint64
 	PaymentSecret   []byte
+
+	// Failure message including the failure code, returned instead of the
+	// failure code if set. Only supported on CLN.
+	FailureMessage []byte
+
+	// Scenario the htlc is failed or resumed in, to look up the failure in
+	// the failure policy of the node.
+	failureScenario FailureScenario
+
+	// Reason the htlc is failed, like latency_budget_exceeded, for the
+	// audit log.
+	failureReason string
+
+	// Amount the sender pays for the registered payment, to deduct the
+	// promised fee from every htlc.
+	incomingAmountMsat int64
+
+	// Payment hash of the registered payment the htlc matched, and whether
+	// a channel was opened for it, for the audit log.
+	registeredPaymentHash []byte
+	channelOpened         bool
+
+	// What lspd knew about the htlc when deciding, for the interception
+	// log.
+	recorded *RecordedHtlc
+}
+
+// OpenObserver is told when a channel is being opened for a payment, while
+// its htlcs are held, so the htlc interceptor of the node can tell the node.
+type OpenObserver interface {
+	ChannelOpening(paymentHash []byte)
+}
+
+// ExposureLimiter tracks the htlcs forwarded to clients, and limits the
+// amount in flight to every client. Reserve returns
+// lsperrors.ErrExposureLimit if the htlc would exceed the limit of the client,
+// or lsperrors.ErrZeroConfExposure if it would exceed the limits over
+// unconfirmed channels.
+type ExposureLimiter interface {
+	Reserve(ctx context.Context, peerID []byte, channelPoint string, paymentHash []byte, amountMsat uint64) error
+}
+
+// DoubleSpendChecker tells whether the funding of a channel, given by its
+// channel point or scid, was double spent.
+type DoubleSpendChecker interface {
+	IsDoubleSpent(channel string) bool
+}
+
+// PeerScorer scores clients by their behavior. Check returns
+// lsperrors.ErrPeerDenied if the client is denied service because of its
+// score.
+type PeerScorer interface {
+	Check(ctx context.Context, peerID []byte) error
+	RecordProbe(ctx context.Context, peerID []byte, paymentHash []byte)
+}
+
+// RefundCredits holds the refunds of the opening fees of failed channel opens
+// owed to clients. Apply returns the credit to forward to the client with the
+// payment, up to the opening fee of the payment.
+type RefundCredits interface {
+	Apply(ctx context.Context, peerID []byte, paymentHash []byte, feeMsat uint64) uint64
 }
 
 type Interceptor struct {
 	client              lightning.Client
 	config              *config.NodeConfig
 	store               InterceptStore
+	tokenStore          tokens.Store
 	feeEstimator        chain.FeeEstimator
 	feeStrategy         chain.FeeStrategy
 	payHashGroup        singleflight.Group
+	holds               holds
 	notificationService *notifications.NotificationService
+	circuitBreaker      *CircuitBreaker
+	psbtFunding         *funding.Coordinator
+	pendingOpens        atomic.Int64
+	recentHtlcs         recentHtlcs
+	feeLedger           feeLedger
+	clientLocks         clientLock
+	clientOpens         clientOpens
+	failurePolicy       map[FailureScenario]*failurePolicy
+	blockHeightCache    blockHeightCache
+	requiredFeatures    []lightning.Feature
+	openObserver        OpenObserver
+	exposure            ExposureLimiter
+	doubleSpends        DoubleSpendChecker
+	scorer              PeerScorer
+	refunds             RefundCredits
+	aliases             *lightning.ChannelAliases
+	quotas              *quota.Limiter
+	fakeScids           fakeScids
+	replicated          bool
+	policyHook          *policyHook
+	bus                 *events.Bus
+	interceptionLog     *InterceptionLog
+	shadowDecisions     func(result InterceptResult, forwardMsat basetypes.MilliSatoshi)
+	logger              *log.Logger
 }
 
 func NewInterceptor(
 	client lightning.Client,
 	config *config.NodeConfig,
 	store InterceptStore,
+	tokenStore tokens.Store,
 	feeEstimator chain.FeeEstimator,
 	feeStrategy chain.FeeStrategy,
 	notificationService *notifications.NotificationService,
+	circuitBreaker *CircuitBreaker,
+	psbtFunding *funding.Coordinator,
+	logger *log.Logger,
 ) *Interceptor {
 	return &Interceptor{
 		client:              client,
 		config:              config,
 		store:               store,
+		tokenStore:          tokenStore,
 		feeEstimator:        feeEstimator,
 		feeStrategy:         feeStrategy,
 		notificationService: notificationService,
+		circuitBreaker:      circuitBreaker,
+		psbtFunding:         psbtFunding,
+		failurePolicy:       parseFailurePolicy(config, logger),
+		requiredFeatures:    parseRequiredFeatures(config, logger),
+		policyHook:          newPolicyHook(config, logger),
+		logger:              logger,
 	}
 }
 
 func (i *Interceptor) Intercept(scid *basetypes.ShortChannelID, reqPaymentHash []byte, reqOutgoingAmountMsat uint64, reqOutgoingExpiry uint32, reqIncomingExpiry uint32) InterceptResult {
+	start := time.Now()
 	reqPaymentHashStr := hex.EncodeToString(reqPaymentHash)
-	resp, _, _ := i.payHashGroup.Do(reqPaymentHashStr, func() (interface{}, error) {
-		token, params, paymentHash, paymentSecret, destination, incomingAmountMsat, outgoingAmountMsat, channelPoint, tag, err := i.store.PaymentInfo(reqPaymentHash)
+	hold := i.holds.acquire(reqPaymentHashStr)
+	defer i.holds.release(reqPaymentHashStr, hold)
+	respChan := i.payHashGroup.DoChan(reqPaymentHashStr, func() (val interface{}, _ error) {
+		// Htlcs to a fake scid are matched to the registration by the scid,
+		// others by the payment hash.
+		isFakeScid := i.fakeScids.contains(*scid)
+		var token string
+		var params *OpeningFeeParams
+		var paymentHash, paymentSecret, destination []byte
+		var incomingAmountMsat, outgoingAmountMsat int64
+		var channelPoint *wire.OutPoint
+		var tag *string
+		var hop nextHopResult
+		var nextHop []byte
+		var isConnected bool
+		var tok *tokens.Token
+		var registration *RecordedRegistration
+		var err error
+
+		// Whatever the decision, it is made for the registered payment the
+		// htlc matched.
+		defer func() {
+			result, ok := val.(InterceptResult)
+			if !ok {
+				return
+			}
+
+			if paymentSecret != nil {
+				result.registeredPaymentHash = paymentHash
+			}
+			if i.interceptionLog != nil {
+				if registration != nil && tok != nil {
+					registration.TokenName = tok.Name
+				}
+				result.recorded = &RecordedHtlc{
+					NextHop:      hex.EncodeToString(hop.PeerID),
+					NextHopError: hop.Outcome == nextHopLookupFailed,
+					Connected:    isConnected,
+					Registration: registration,
+				}
+			}
+			val = result
+		}()
+
+		if isFakeScid {
+			token, params, paymentHash, paymentSecret, destination, incomingAmountMsat, outgoingAmountMsat, channelPoint, tag, err = i.store.PaymentInfoByScid(i.tenantContext(), uint64(*scid))
+		} else {
+			token, params, paymentHash, paymentSecret, destination, incomingAmountMsat, outgoingAmountMsat, channelPoint, tag, err = i.store.PaymentInfo(i.tenantContext(), reqPaymentHash)
+		}
+		// Other replicas assign fake scids too, those are only known in the
+		// database.
+		if err == nil && paymentSecret == nil && !isFakeScid && i.replicated {
+			token, params, paymentHash, paymentSecret, destination, incomingAmountMsat, outgoingAmountMsat, channelPoint, tag, err = i.store.PaymentInfoByScid(i.tenantContext(), uint64(*scid))
+			if err == nil && paymentSecret != nil {
+				isFakeScid = true
+				i.fakeScids.add(*scid, paymentHash)
+			}
+		}
 		if err != nil {
-			log.Printf("paymentInfo(%x) error: %v", reqPaymentHash, err)
-			return InterceptResult{
-				Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
-				FailureCode: FAILURE_TEMPORARY_NODE_FAILURE,
-			}, nil
+			i.logger.Printf("paymentInfo(%x, %s) error: %v", reqPaymentHash, scid.ToString(), err)
+			return failHtlc(lsperrors.Wrap(lsperrors.ErrInternal, err)), nil
 		}
 
 		isRegistered := paymentSecret != nil
+		if isRegistered && i.interceptionLog != nil {
+			registration = newRecordedRegistration(paymentHash, isFakeScid, destination, incomingAmountMsat, outgoingAmountMsat, params, channelPoint, tag)
+		}
 		// Sanity check. If the payment is registered, the destination is always set.
 		if isRegistered && (destination == nil || len(destination) != 33) {
-			log.Printf("ERROR: Payment was registered without destination. paymentHash: %s", reqPaymentHashStr)
+			i.logger.Printf("ERROR: Payment was registered without destination. paymentHash: %s", reqPaymentHashStr)
 		}
 
 		isProbe := isRegistered && !bytes.Equal(paymentHash, reqPaymentHash)
-		nextHop, _ := i.client.GetPeerId(scid)
-		if err != nil {
-			log.Printf("GetPeerId(%s) error: %v", scid.ToString(), err)
+		if isFakeScid && isProbe && !bytes.Equal(probeHash(paymentHash), reqPaymentHash) {
+			// Only the registered payment, or probes for it, may use the
+			// fake scid.
+			i.logger.Printf("Htlc with payment hash %s to the fake scid %s of payment %x. Failing it.", reqPaymentHashStr, scid.ToString(), paymentHash)
+			return InterceptResult{
+				Action:          INTERCEPT_FAIL_HTLC_WITH_CODE,
+				FailureCode:     FAILURE_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS,
+				failureScenario: ScenarioUnknownPayment,
+			}, nil
+		}
+		hop = i.resolveNextHop(scid)
+		if hop.Outcome == nextHopLookupFailed {
+			i.logger.Printf("GetPeerId(%s) error: %v", scid.ToString(), hop.Err)
 			return InterceptResult{
 				Action: INTERCEPT_RESUME,
 			}, nil
 		}
+		nextHop = hop.PeerID
 
 		// If the payment was registered, but the next hop is not the destination
 		// that means we are not the last hop of the payment, so we'll just forward.
@@ -108,6 +306,19 @@ func (i *Interceptor) Intercept(scid *basetypes.ShortChannelID, reqPaymentHash [
 			}, nil
 		}
 
+		// The client learns about the payment before the channel for it is
+		// opened.
+		if isRegistered && !isProbe && !i.config.ShadowMode {
+			i.bus.Publish(&events.Event{
+				Kind:        events.PaymentIntercepted,
+				Node:        i.config,
+				PeerID:      destination,
+				PaymentHash: paymentHash,
+				AmountMsat:  int64(reqOutgoingAmountMsat),
+				Tag:         tag,
+			})
+		}
+
 		// nextHop is set if the sender's scid corresponds to a known channel.
 		// destination is set if the payment was registered for a channel open.
 		// The 'actual' next hop will be either of those.
@@ -126,16 +337,25 @@ func (i *Interceptor) Intercept(scid *basetypes.ShortChannelID, reqPaymentHash [
 			nextHop = destination
 		}
 
-		isConnected, err := i.client.IsConnected(nextHop)
+		// Fail htlcs that would expire before a channel can be opened and
+		// the payment forwarded right away, rather than holding them until
+		// they expire and the incoming channel is force closed.
+		if isRegistered && !isProbe && channelPoint == nil && !i.withinCltvBudget(reqIncomingExpiry) {
+			i.logger.Printf("Htlc of payment %s expires at block %d, too soon to open a channel. Failing payment.", reqPaymentHashStr, reqIncomingExpiry)
+			return failHtlc(lsperrors.ErrExpiryTooSoon), nil
+		}
+
+		isConnected, err = i.client.IsConnected(nextHop)
 		if err != nil {
-			log.Printf("IsConnected(%x) error: %v", nextHop, err)
-			return &InterceptResult{
-				Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
-				FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
-			}, nil
+			i.logger.Printf("IsConnected(%x) error: %v", nextHop, err)
+			return failHtlc(lsperrors.Wrap(lsperrors.ErrNodeUnavailable, err)), nil
 		}
 
 		if isProbe {
+			if i.scorer != nil {
+				i.scorer.RecordProbe(i.tenantContext(), nextHop, reqPaymentHash)
+			}
+
 			// If this is a known probe, we'll quit early for non-connected clients.
 			if !isConnected {
 				return InterceptResult{
@@ -150,12 +370,19 @@ func (i *Interceptor) Intercept(scid *basetypes.ShortChannelID, reqPaymentHash [
 			// know that the actual payment would probably succeed.
 			if channelPoint == nil {
 				return InterceptResult{
-					Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
-					FailureCode: FAILURE_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS,
+					Action:          INTERCEPT_FAIL_HTLC_WITH_CODE,
+					FailureCode:     FAILURE_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS,
+					failureScenario: ScenarioUnknownPayment,
 				}, nil
 			}
 		}
 
+		// Connect to the client ourselves if we know where to reach it,
+		// which is faster than notifying it.
+		if !isConnected {
+			isConnected = i.reconnect(nextHop)
+		}
+
 		if !isConnected {
 			// Make sure the client is connected by potentially notifying them to come online.
 			notifyResult := i.notify(reqPaymentHashStr, nextHop, isRegistered)
@@ -164,18 +391,21 @@ func (i *Interceptor) Intercept(scid *basetypes.ShortChannelID, reqPaymentHash [
 			}
 		}
 
-		// The peer is online, we can resume the htlc if it's not a channel open.
+		// The peer is online. If it's not a channel open, the keysend policy
+		// decides what happens to the htlc. By default it's resumed.
 		if !isRegistered {
-			return InterceptResult{
-				Action: INTERCEPT_RESUME,
-			}, nil
+			return i.interceptUnregistered(reqPaymentHash, nextHop, reqOutgoingAmountMsat, reqOutgoingExpiry, reqIncomingExpiry), nil
 		}
 
 		// The first htlc of a MPP will open the channel.
+		opened := false
+		reused := false
+		var commitmentType lightning.CommitmentType
+		var policy *lightning.ChannelPolicy
 		if channelPoint == nil {
 			// TODO: When opening_fee_params is enforced, turn this check in a temporary channel failure.
 			if params == nil {
-				log.Printf("DEPRECATED: Intercepted htlc with deprecated fee mechanism. Using default fees. payment hash: %s", reqPaymentHashStr)
+				i.logger.Printf("DEPRECATED: Intercepted htlc with deprecated fee mechanism. Using default fees. payment hash: %s", reqPaymentHashStr)
 				params = &OpeningFeeParams{
 					MinMsat:              uint64(i.config.ChannelMinimumFeeMsat),
 					Proportional:         uint32(i.config.ChannelFeePermyriad * 100),
@@ -185,111 +415,623 @@ func (i *Interceptor) Intercept(scid *basetypes.ShortChannelID, reqPaymentHash [
 				}
 			}
 
+			// Opening channels for tiny payments is uneconomical. The wallet
+			// is told why its payment failed.
+			if min := i.config.MinPaymentSizeMsat; min > 0 && incomingAmountMsat < int64(min) {
+				i.logger.Printf("Payment %s of %v msat is below the minimum payment size of %v msat. Failing payment.", reqPaymentHashStr, incomingAmountMsat, min)
+				if !i.config.ShadowMode {
+					go i.notificationService.NotifyPaymentFailed(i.tenantContext(), hex.EncodeToString(destination), reqPaymentHashStr, string(ScenarioBelowMinimum))
+				}
+				return failHtlc(lsperrors.ErrBelowMinimum), nil
+			}
+
+			// Fail early if the client can't accept the channel, rather
+			// than have the open fail.
+			if missing := i.missingFeatures(nextHop); len(missing) > 0 {
+				i.logger.Printf("Client %x doesn't signal required features %v. Failing payment %s.", nextHop, missing, reqPaymentHashStr)
+				if !i.config.ShadowMode {
+					go i.notificationService.NotifyPaymentFailed(i.tenantContext(), hex.EncodeToString(destination), reqPaymentHashStr, string(ScenarioMissingFeatures))
+				}
+				return failHtlc(lsperrors.ErrMissingFeatures), nil
+			}
+
+			// Some wallet sdk versions can't use the channel either.
+			if err := i.checkClientQuirks(destination, reqOutgoingExpiry); err != nil {
+				i.logger.Printf("Client %x can't receive payment %s: %v", destination, reqPaymentHashStr, err)
+				if !i.config.ShadowMode {
+					go i.notificationService.NotifyPaymentFailed(i.tenantContext(), hex.EncodeToString(destination), reqPaymentHashStr, strings.ToLower(lsperrors.From(err).Reason))
+				}
+				return failHtlc(err), nil
+			}
+
+			// Tokens stored in the database may override the node settings.
+			tok, err = i.tokenStore.Get(i.tenantContext(), token)
+			if err != nil {
+				i.logger.Printf("tokenStore.Get() error, using node settings: %v", err)
+			}
+
 			// Make sure the cltv delta is enough.
-			if int64(reqIncomingExpiry)-int64(reqOutgoingExpiry) < int64(i.config.TimeLockDelta) {
-				return InterceptResult{
-					Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
-					FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
-				}, nil
+			timeLockDelta := tokens.TimeLockDelta(tok, i.config)
+			policy = tokens.ChannelPolicy(tok, token, i.config)
+			if policy != nil {
+				timeLockDelta = policy.TimeLockDelta
+			}
+			if int64(reqIncomingExpiry)-int64(reqOutgoingExpiry) < int64(timeLockDelta) {
+				return failHtlc(lsperrors.ErrCltvDeltaTooLow), nil
 			}
 
 			validUntil, err := time.Parse(basetypes.TIME_FORMAT, params.ValidUntil)
 			if err != nil {
-				log.Printf("time.Parse(%s, %s) failed. Failing channel open: %v", basetypes.TIME_FORMAT, params.ValidUntil, err)
-				return InterceptResult{
-					Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
-					FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
-				}, nil
+				i.logger.Printf("time.Parse(%s, %s) failed. Failing channel open: %v", basetypes.TIME_FORMAT, params.ValidUntil, err)
+				return failHtlc(lsperrors.ErrInvalidFeeParams), nil
 			}
 
 			// Make sure the opening_fee_params are not expired.
 			// If they are expired, but the current chain fee is fine, open channel anyway.
 			if time.Now().UTC().After(validUntil) {
 				if !i.isCurrentChainFeeCheaper(token, params) {
-					log.Printf("Intercepted expired payment registration. Failing payment. payment hash: %x, valid until: %s", paymentHash, params.ValidUntil)
-					return InterceptResult{
-						Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
-						FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
-					}, nil
+					i.logger.Printf("Intercepted expired payment registration. Failing payment. payment hash: %x, valid until: %s", paymentHash, params.ValidUntil)
+					return failHtlc(lsperrors.ErrFeeParamsExpired), nil
 				}
 
-				log.Printf("Intercepted expired payment registration. Opening channel anyway, because it's cheaper at the current rate. paymenthash: %s, params: %+v", reqPaymentHashStr, params)
+				i.logger.Printf("Intercepted expired payment registration. Opening channel anyway, because it's cheaper at the current rate. paymenthash: %s, params: %+v", reqPaymentHashStr, params)
 			}
 
-			channelPoint, err = i.openChannel(reqPaymentHash, destination, incomingAmountMsat, tag)
-			if err != nil {
-				log.Printf("openChannel(%x, %v) err: %v", destination, incomingAmountMsat, err)
+			// Don't open new channels while the circuit breaker is open. The
+			// htlc is failed with TEMPORARY_NODE_FAILURE rather than the
+			// usual TEMPORARY_CHANNEL_FAILURE, because the node itself is
+			// temporarily unable to open channels.
+			if i.circuitBreaker != nil {
+				if state := i.circuitBreaker.State(); state.Open {
+					i.logger.Printf("Circuit breaker open. Not opening channel. payment hash: %s, reason: %s", reqPaymentHashStr, state.Reason)
+					i.circuitBreaker.markRejected()
+					return failHtlc(lsperrors.ErrCircuitBreakerOpen), nil
+				}
+			}
+
+			// Clients that misbehaved before don't get channels.
+			if i.scorer != nil {
+				err = i.scorer.Check(i.tenantContext(), destination)
+				if err != nil {
+					return failHtlc(err), nil
+				}
+			}
+
+			// The operator's policy service has the last word on the open.
+			tokenName := ""
+			if tok != nil {
+				tokenName = tok.Name
+			}
+			allowed, amountMsat := i.checkPolicy(&PolicyRequest{
+				Node:               i.config.Label(),
+				PaymentHash:        reqPaymentHashStr,
+				Destination:        hex.EncodeToString(destination),
+				IncomingAmountMsat: incomingAmountMsat,
+				OutgoingAmountMsat: outgoingAmountMsat,
+				FeeMsat:            incomingAmountMsat - outgoingAmountMsat,
+				IncomingExpiry:     reqIncomingExpiry,
+				OutgoingExpiry:     reqOutgoingExpiry,
+				TokenName:          tokenName,
+				Tag:                tag,
+			})
+			if !allowed {
+				return failHtlc(lsperrors.ErrPolicyDenied), nil
+			}
+			outgoingAmountMsat = amountMsat
+
+			// Don't open a channel if the registration was updated or
+			// cancelled while the htlc was held.
+			if hold.isInvalidated() {
+				i.logger.Printf("Registration changed while intercepting. Not opening channel. payment hash: %s", reqPaymentHashStr)
 				return InterceptResult{
-					Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
-					FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
+					Action:          INTERCEPT_FAIL_HTLC_WITH_CODE,
+					FailureCode:     hold.failureCode,
+					failureScenario: hold.failureScenario,
 				}, nil
 			}
+
+			// Channels for a client are opened one at a time. Payments to
+			// the client arriving simultaneously use the channel opened for
+			// the first one, if it can carry them. They still pay the
+			// opening fee they were registered with: the fee was promised
+			// for the inbound liquidity, and htlcs of the payment forwarded
+			// later can't tell a reused channel from an opened one.
+			unlock := i.clientLocks.lock(destination)
+			defer unlock()
+			if open := i.reusableChannel(destination, outgoingAmountMsat); open != nil {
+				i.logger.Printf("Using channel %v opened for client %x for payment %s", open.channelPoint, destination, reqPaymentHashStr)
+				reused = true
+				channelPoint, commitmentType = open.channelPoint, open.commitmentType
+				err = i.store.SetFundingTx(i.tenantContext(), paymentHash, channelPoint)
+				if err != nil {
+					i.logger.Printf("SetFundingTx(%s, %v) error: %v", reqPaymentHashStr, channelPoint, err)
+					return failHtlc(lsperrors.Wrap(lsperrors.ErrOpenFailed, err)), nil
+				}
+			}
 		}
 
-		var bigProd, bigAmt big.Int
-		amt := (bigAmt.Div(bigProd.Mul(big.NewInt(outgoingAmountMsat), big.NewInt(int64(reqOutgoingAmountMsat))), big.NewInt(incomingAmountMsat))).Int64()
+		if channelPoint == nil {
+			capacity := tokens.ChannelCapacity(tok, token, i.config, incomingAmountMsat)
 
-		deadline := time.Now().Add(60 * time.Second)
+			// The open counts towards the quota of the token, unless it
+			// fails.
+			reservation, err := i.quotas.Reserve(i.tenantContext(), tok, token, capacity)
+			if err != nil {
+				return failHtlc(err), nil
+			}
 
-		for {
-			chanResult, _ := i.client.GetChannel(destination, *channelPoint)
-			if chanResult != nil {
-				log.Printf("channel opened successfully alias: %v, confirmed: %v", chanResult.InitialChannelID.ToString(), chanResult.ConfirmedChannelID.ToString())
+			i.pendingOpens.Add(1)
+			defer i.pendingOpens.Add(-1)
+			if i.openObserver != nil && !i.config.ShadowMode {
+				i.openObserver.ChannelOpening(reqPaymentHash)
+			}
 
-				err := i.store.InsertChannel(
-					uint64(chanResult.InitialChannelID),
-					uint64(chanResult.ConfirmedChannelID),
-					channelPoint.String(),
-					destination,
-					time.Now(),
-				)
+			// Top up an existing channel with the client rather than opening
+			// a second one, if configured.
+			if i.config.Splicing != nil {
+				channelPoint, err = i.spliceIn(paymentHash, destination, incomingAmountMsat, outgoingAmountMsat, capacity)
+				if err != nil {
+					i.quotas.Release(i.tenantContext(), reservation)
+					i.logger.Printf("spliceIn(%x, %v) err: %v", destination, incomingAmountMsat, err)
+					return failHtlc(lsperrors.Wrap(lsperrors.ErrOpenFailed, err)), nil
+				}
+			}
 
+			if channelPoint == nil {
+				opened = true
+				channelPoint, commitmentType, err = i.openChannel(paymentHash, destination, incomingAmountMsat, capacity, i.commitmentType(paymentHash, tok), !tokens.PublicChannels(tok, i.config), tag)
 				if err != nil {
-					log.Printf("insertChannel error: %v", err)
+					i.quotas.Release(i.tenantContext(), reservation)
+				}
+				if errors.Is(err, errShadowMode) {
 					return InterceptResult{
-						Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
-						FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
+						Action:             INTERCEPT_RESUME_WITH_ONION,
+						Destination:        destination,
+						TotalAmountMsat:    basetypes.MilliSatoshi(outgoingAmountMsat),
+						incomingAmountMsat: incomingAmountMsat,
 					}, nil
 				}
-
-				channelID := uint64(chanResult.ConfirmedChannelID)
-				if channelID == 0 {
-					channelID = uint64(chanResult.InitialChannelID)
+				if err != nil {
+					i.logger.Printf("openChannel(%x, %v) err: %v", destination, incomingAmountMsat, err)
+					return failHtlc(lsperrors.Wrap(lsperrors.ErrOpenFailed, err)), nil
 				}
-
-				return InterceptResult{
-					Action:          INTERCEPT_RESUME_WITH_ONION,
-					Destination:     destination,
-					ChannelPoint:    channelPoint,
-					ChannelId:       channelID,
-					PaymentSecret:   paymentSecret,
-					AmountMsat:      uint64(amt),
-					TotalAmountMsat: uint64(outgoingAmountMsat),
-				}, nil
+				i.clientOpens.add(destination, channelPoint, commitmentType, uint64(outgoingAmountMsat), time.Now())
 			}
+		}
+
+		channelID, err := i.awaitChannel(destination, channelPoint, commitmentType)
+		if err != nil {
+			i.logger.Printf("awaitChannel(%x, %v) error: %v", destination, channelPoint.String(), err)
+			return failHtlc(lsperrors.Wrap(lsperrors.ErrOpenFailed, err)), nil
+		}
+
+		if opened {
+			i.setChannelPolicy(destination, channelPoint, policy)
+		}
 
-			log.Printf("waiting for channel to get opened.... %v\n", destination)
-			if time.Now().After(deadline) {
-				log.Printf("Stop retrying getChannel(%v, %v)", destination, channelPoint.String())
-				break
+		// Refunds owed to the client are forwarded with its next channel
+		// open, in place of the opening fee, also when the payment reuses a
+		// channel and pays the fee all the same. Htlcs of the payment
+		// forwarded after the open get the credit applied with it.
+		if i.refunds != nil && incomingAmountMsat > outgoingAmountMsat {
+			var feeMsat uint64
+			if opened || reused {
+				feeMsat = uint64(incomingAmountMsat - outgoingAmountMsat)
 			}
-			<-time.After(1 * time.Second)
+			outgoingAmountMsat += int64(i.refunds.Apply(i.tenantContext(), destination, paymentHash, feeMsat))
 		}
 
-		log.Printf("Error: Channel failed to open... timed out. ")
+		// The amount to forward is set per htlc, because the result is shared
+		// by all the htlcs of the payment.
 		return InterceptResult{
-			Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
-			FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
+			Action:             INTERCEPT_RESUME_WITH_ONION,
+			Destination:        destination,
+			ChannelPoint:       channelPoint,
+			ChannelId:          channelID,
+			PaymentSecret:      paymentSecret,
+			TotalAmountMsat:    basetypes.MilliSatoshi(outgoingAmountMsat),
+			incomingAmountMsat: incomingAmountMsat,
+			channelOpened:      opened,
 		}, nil
 	})
 
-	return resp.(InterceptResult)
+	// Fail the htlc if no decision is made in time, rather than holding it
+	// until the incoming channel is force closed. The decision, like a
+	// channel open, goes on in the background.
+	var budgetExceeded <-chan time.Time
+	if budget, ok := i.latencyBudget(reqIncomingExpiry); ok {
+		timer := time.NewTimer(budget - time.Since(start))
+		defer timer.Stop()
+		budgetExceeded = timer.C
+	}
+
+	var result InterceptResult
+	select {
+	case resp := <-respChan:
+		result = resp.Val.(InterceptResult)
+	case <-hold.invalidated:
+		i.logger.Printf("Registration changed while intercepting. Failing held htlc. payment hash: %s", reqPaymentHashStr)
+		result = InterceptResult{
+			Action:          INTERCEPT_FAIL_HTLC_WITH_CODE,
+			FailureCode:     hold.failureCode,
+			failureScenario: hold.failureScenario,
+		}
+	case <-budgetExceeded:
+		i.logger.Printf("No decision about htlc of payment %s expiring at block %d after %v. Failing it.", reqPaymentHashStr, reqIncomingExpiry, time.Since(start))
+		latencyBudgetExceededCounter.WithLabelValues(i.config.Label()).Inc()
+		result = failHtlc(lsperrors.ErrLatencyBudget)
+	}
+
+	// The final result may differ from the decision, keep what was decided
+	// for the audit log.
+	decision := result
+
+	htlcAmount := basetypes.MilliSatoshi(reqOutgoingAmountMsat)
+	if result.Action == INTERCEPT_RESUME_WITH_ONION && i.exceedsMaxHtlc(htlcAmount) {
+		i.logger.Printf("Htlc of %v exceeds max htlc. Failing it, so the sender splits the payment. payment hash: %s", htlcAmount, reqPaymentHashStr)
+		result = InterceptResult{
+			Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
+			FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
+		}
+	}
+
+	if result.Action != INTERCEPT_FAIL_HTLC_WITH_CODE && i.isDoubleSpent(scid, result) {
+		i.logger.Printf("Htlc of payment %s goes over a channel whose funding was double spent. Failing it.", reqPaymentHashStr)
+		result = failHtlc(lsperrors.ErrFundingDoubleSpent)
+	}
+
+	result = i.applyFailurePolicy(result)
+
+	// In shadow mode the decision is only logged, the htlc is always resumed.
+	if i.config.ShadowMode {
+		i.logShadowDecision(reqPaymentHashStr, htlcAmount, result)
+		if i.shadowDecisions != nil {
+			i.shadowDecisions(result, shadowForwardAmount(htlcAmount, result))
+		}
+		result = InterceptResult{
+			Action: INTERCEPT_RESUME,
+		}
+	}
+
+	if result.Action == INTERCEPT_RESUME_WITH_ONION && i.exposure != nil {
+		result = i.reserveExposure(reqPaymentHash, htlcAmount, result)
+	}
+
+	if result.Action == INTERCEPT_RESUME_WITH_ONION {
+		result.AmountMsat = i.deductFee(reqPaymentHash, htlcAmount, result)
+	}
+
+	i.recentHtlcs.add(start, scid, reqPaymentHash, reqOutgoingAmountMsat, result)
+	i.publishIntercepted(start, scid, reqPaymentHash, reqOutgoingAmountMsat, reqIncomingExpiry, reqOutgoingExpiry, decision, result)
+	if i.interceptionLog != nil {
+		go i.recordHtlc(start, scid, reqPaymentHash, reqOutgoingAmountMsat, reqOutgoingExpiry, reqIncomingExpiry, decision, result)
+	}
+	return result
+}
+
+func (i *Interceptor) publishIntercepted(
+	start time.Time,
+	scid *basetypes.ShortChannelID,
+	paymentHash []byte,
+	amountMsat uint64,
+	incomingExpiry uint32,
+	outgoingExpiry uint32,
+	decision InterceptResult,
+	result InterceptResult,
+) {
+	event := &events.Event{
+		Kind:                  events.HtlcIntercepted,
+		Node:                  i.config,
+		Time:                  start,
+		PeerID:                result.Destination,
+		PaymentHash:           paymentHash,
+		ShortChannelID:        scid.ToString(),
+		AmountMsat:            int64(amountMsat),
+		Action:                result.Action.String(),
+		IncomingExpiry:        incomingExpiry,
+		OutgoingExpiry:        outgoingExpiry,
+		RegisteredPaymentHash: decision.registeredPaymentHash,
+		Opened:                decision.channelOpened,
+		Duration:              time.Since(start),
+	}
+	if result.Action == INTERCEPT_FAIL_HTLC_WITH_CODE {
+		event.FailureCode = result.FailureCode.String()
+		event.FailureReason = decision.failureReason
+	}
+	if decision.ChannelPoint != nil {
+		event.ChannelPoint = decision.ChannelPoint.String()
+	}
+
+	i.bus.Publish(event)
+}
+
+// isDoubleSpent returns whether the htlc goes over a channel whose funding
+// was double spent.
+func (i *Interceptor) isDoubleSpent(scid *basetypes.ShortChannelID, result InterceptResult) bool {
+	if i.doubleSpends == nil {
+		return false
+	}
+	if result.ChannelPoint != nil && i.doubleSpends.IsDoubleSpent(result.ChannelPoint.String()) {
+		return true
+	}
+
+	return i.doubleSpends.IsDoubleSpent(scid.ToString())
+}
+
+// reserveExposure records the htlc as in flight to the client, or fails it if
+// the client has too much in flight already.
+func (i *Interceptor) reserveExposure(paymentHash []byte, htlcAmount basetypes.MilliSatoshi, result InterceptResult) InterceptResult {
+	channelPoint := ""
+	if result.ChannelPoint != nil {
+		channelPoint = result.ChannelPoint.String()
+	}
+
+	err := i.exposure.Reserve(i.tenantContext(), result.Destination, channelPoint, paymentHash, uint64(htlcAmount))
+	if errors.Is(err, lsperrors.ErrExposureLimit) || errors.Is(err, lsperrors.ErrZeroConfExposure) {
+		return i.applyFailurePolicy(failHtlc(err))
+	}
+	if err != nil {
+		i.logger.Printf("Reserve(%x, %x, %v) error: %v", result.Destination, paymentHash, htlcAmount, err)
+		return i.applyFailurePolicy(failHtlc(lsperrors.Wrap(lsperrors.ErrInternal, err)))
+	}
+
+	return result
+}
+
+// deductFee returns the amount to forward for an htlc of a registered
+// payment, and records the fee deducted from it. If the fees deducted from the
+// htlcs of the payment exceed the promised fee, the excess is credited to the
+// client.
+func (i *Interceptor) deductFee(paymentHash []byte, htlcAmount basetypes.MilliSatoshi, result InterceptResult) basetypes.MilliSatoshi {
+	amt := i.feeLedger.forward(
+		hex.EncodeToString(paymentHash),
+		result.incomingAmountMsat,
+		int64(result.TotalAmountMsat),
+		htlcAmount,
+	)
+
+	lspNodeID, _ := hex.DecodeString(i.config.NodePubkey)
+	creditMsat, err := i.store.RecordHtlcFee(i.tenantContext(), lspNodeID, result.Destination, paymentHash, htlcAmount, amt)
+	if err != nil {
+		i.logger.Printf("RecordHtlcFee(%x, %v, %v) error: %v", paymentHash, htlcAmount, amt, err)
+	} else if creditMsat > 0 {
+		i.logger.Printf("WARN: Fee deducted for payment %x exceeds the promised fee. Credited %v msat to %x", paymentHash, creditMsat, result.Destination)
+	}
+
+	return amt
+}
+
+// RegistrationUpdated makes htlcs for the payment hash use the updated
+// registration. Decisions in flight are dropped, and htlcs held for the old
+// registration are failed with TEMPORARY_CHANNEL_FAILURE, so the sender
+// retries against the updated one.
+func (i *Interceptor) RegistrationUpdated(paymentHash []byte) {
+	i.invalidate(paymentHash, FAILURE_TEMPORARY_CHANNEL_FAILURE, "")
+}
+
+// RegistrationCancelled forgets the fake scid of the payment hash, drops
+// decisions in flight for it and fails the htlcs held for it with
+// INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS, like the recipient would for an
+// abandoned invoice.
+func (i *Interceptor) RegistrationCancelled(paymentHash []byte) {
+	i.fakeScids.removePayment(paymentHash)
+	i.invalidate(paymentHash, FAILURE_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS, ScenarioUnknownPayment)
+}
+
+func (i *Interceptor) invalidate(paymentHash []byte, failureCode InterceptFailureCode, scenario FailureScenario) {
+	paymentHashStr := hex.EncodeToString(paymentHash)
+	i.payHashGroup.Forget(paymentHashStr)
+	held := i.holds.invalidate(paymentHashStr, failureCode, scenario)
+	if held > 0 {
+		i.logger.Printf("Failing %d held htlcs for changed registration %s with %s", held, paymentHashStr, failureCode)
+	}
+}
+
+// PendingOpens returns the number of channels currently being opened, until
+// the channel shows up on the node.
+func (i *Interceptor) PendingOpens() int64 {
+	return i.pendingOpens.Load()
+}
+
+// RecentHtlcs returns the last intercepted htlcs, newest first.
+func (i *Interceptor) RecentHtlcs() []*HtlcRecord {
+	return i.recentHtlcs.list()
+}
+
+func (i *Interceptor) Node() *config.NodeConfig {
+	return i.config
+}
+
+// SetOpenObserver sets the observer that is told about channel opens for held
+// htlcs.
+func (i *Interceptor) SetOpenObserver(o OpenObserver) {
+	i.openObserver = o
+}
+
+// SetExposureLimiter sets the limiter of the amount in flight to clients,
+// for clients receiving on hold invoices.
+func (i *Interceptor) SetExposureLimiter(l ExposureLimiter) {
+	i.exposure = l
+}
+
+// SetDoubleSpendChecker sets the checker of the channels whose funding was
+// double spent. Htlcs over those channels are failed.
+func (i *Interceptor) SetDoubleSpendChecker(c DoubleSpendChecker) {
+	i.doubleSpends = c
+}
+
+// SetScorer sets the scorer of the clients, which denies service to clients
+// with a low score.
+func (i *Interceptor) SetScorer(s PeerScorer) {
+	i.scorer = s
+}
+
+// SetRefundCredits sets the refunds forwarded to clients with their next
+// channel open.
+func (i *Interceptor) SetRefundCredits(r RefundCredits) {
+	i.refunds = r
+}
+
+// SetEventBus sets the bus the interceptor publishes intercepted htlcs and
+// opened channels on.
+func (i *Interceptor) SetEventBus(bus *events.Bus) {
+	i.bus = bus
+}
+
+// SetInterceptionLog sets the log the intercepted htlcs are recorded to.
+func (i *Interceptor) SetInterceptionLog(l *InterceptionLog) {
+	i.interceptionLog = l
+}
+
+// tenantContext returns the context of the tenant of the node, in which the
+// rows of the node are stored and looked up.
+func (i *Interceptor) tenantContext() context.Context {
+	return tenant.NewContext(context.Background(), i.config.Tenant)
+}
+
+// SetReplicated tells the interceptor other lspd replicas register payments
+// for the node as well.
+func (i *Interceptor) SetReplicated(replicated bool) {
+	i.replicated = replicated
+}
+
+// SetQuotaLimiter sets the limiter of the channels opened per token.
+func (i *Interceptor) SetQuotaLimiter(l *quota.Limiter) {
+	i.quotas = l
+}
+
+// SetChannelAliases sets the aliases of the channels of the node, so htlcs
+// are forwarded over the alias a channel was opened with.
+func (i *Interceptor) SetChannelAliases(aliases *lightning.ChannelAliases) {
+	i.aliases = aliases
+}
+
+// IsLspdScid returns whether htlcs to the scid need to be intercepted when
+// only intercepting lspd scids: whether it is a fake scid assigned to a
+// registered payment, or the alias of a channel lspd opened.
+func (i *Interceptor) IsLspdScid(scid basetypes.ShortChannelID) bool {
+	if i.fakeScids.contains(scid) || i.aliases.Contains(scid) {
+		return true
+	}
+
+	// Other replicas assign fake scids too, those are only known in the
+	// database.
+	if !i.replicated {
+		return false
+	}
+
+	_, _, paymentHash, paymentSecret, _, _, _, _, _, err := i.store.PaymentInfoByScid(i.tenantContext(), uint64(scid))
+	if err != nil {
+		i.logger.Printf("PaymentInfoByScid(%s) error: %v", scid.ToString(), err)
+		return false
+	}
+	if paymentSecret == nil {
+		return false
+	}
+
+	i.fakeScids.add(scid, paymentHash)
+	return true
+}
+
+// reusableChannel returns the channel recently opened for the client, if it
+// is still open or pending and can carry the amount, and assigns the amount
+// to it. A pending channel has to carry the amount next to the payments
+// already assigned to it, which aren't forwarded yet. The client has to be
+// locked.
+func (i *Interceptor) reusableChannel(destination []byte, amountMsat int64) *clientOpen {
+	open := i.clientOpens.get(destination, time.Now())
+	if open == nil {
+		return nil
+	}
+
+	channels, err := i.client.ListPeerChannels(context.Background(), destination)
+	if err != nil {
+		i.logger.Printf("ListPeerChannels(%x) error: %v", destination, err)
+		return nil
+	}
+
+	for _, c := range channels {
+		if c.ChannelPoint != open.channelPoint.String() {
+			continue
+		}
+
+		// Pending channels are awaited like a new open.
+		if c.Pending {
+			if !i.clientOpens.assign(open, c.CapacitySat, uint64(amountMsat)) {
+				return nil
+			}
+			return open
+		}
+
+		balance, err := i.client.GetMaxLocalBalanceMsat(destination)
+		if err != nil {
+			i.logger.Printf("GetMaxLocalBalanceMsat(%x) error: %v", destination, err)
+			return nil
+		}
+		if balance < uint64(amountMsat) {
+			return nil
+		}
+		return open
+	}
+
+	// The channel closed or its open failed.
+	i.clientOpens.remove(destination)
+	return nil
+}
+
+// awaitChannel waits for the opened channel to become active on the node and
+// stores it with the commitment type it was opened with, if lspd opened it.
+// It returns the channel id to forward htlcs to.
+func (i *Interceptor) awaitChannel(destination []byte, channelPoint *wire.OutPoint, commitmentType lightning.CommitmentType) (uint64, error) {
+	deadline := time.Now().Add(60 * time.Second)
+
+	for {
+		chanResult, _ := i.client.GetChannel(destination, *channelPoint)
+		if chanResult != nil {
+			i.logger.Printf("channel opened successfully alias: %v, confirmed: %v", chanResult.InitialChannelID.ToString(), chanResult.ConfirmedChannelID.ToString())
+
+			lspNodeID, _ := hex.DecodeString(i.config.NodePubkey)
+			err := i.store.InsertChannel(
+				i.tenantContext(),
+				lspNodeID,
+				uint64(chanResult.InitialChannelID),
+				uint64(chanResult.ConfirmedChannelID),
+				channelPoint.String(),
+				destination,
+				commitmentType,
+				time.Now(),
+			)
+			if err != nil {
+				return 0, fmt.Errorf("insertChannel error: %w", err)
+			}
+
+			channelID := i.aliases.Record(i.tenantContext(), destination, channelPoint.String(), chanResult)
+			return uint64(channelID), nil
+		}
+
+		i.logger.Printf("waiting for channel to get opened.... %v\n", destination)
+		if time.Now().After(deadline) {
+			i.logger.Printf("Stop retrying getChannel(%v, %v)", destination, channelPoint.String())
+			break
+		}
+		<-time.After(1 * time.Second)
+	}
+
+	return 0, fmt.Errorf("channel failed to open, timed out")
 }
 
 func (i *Interceptor) notify(reqPaymentHashStr string, nextHop []byte, isRegistered bool) *InterceptResult {
+	// In shadow mode the client is not notified, the decision continues as if
+	// it came online.
+	if i.config.ShadowMode {
+		i.logger.Printf("Shadow mode: would notify %x of pending htlc %s", nextHop, reqPaymentHashStr)
+		return nil
+	}
+
 	// If not connected, send a notification to the registered
 	// notification service for this client if available.
 	notified, err := i.notificationService.Notify(
+		i.tenantContext(),
 		hex.EncodeToString(nextHop),
 		reqPaymentHashStr,
 	)
@@ -299,14 +1041,15 @@ func (i *Interceptor) notify(reqPaymentHashStr string, nextHop []byte, isRegiste
 	// result in UNKOWN_NEXT_PEER)
 	if err != nil || !notified {
 		return &InterceptResult{
-			Action: INTERCEPT_RESUME,
+			Action:          INTERCEPT_RESUME,
+			failureScenario: ScenarioClientOffline,
 		}
 	}
 
-	log.Printf("Notified %x of pending htlc", nextHop)
+	i.logger.Printf("Notified %x of pending htlc", nextHop)
 	d, err := time.ParseDuration(i.config.NotificationTimeout)
 	if err != nil {
-		log.Printf("WARN: No NotificationTimeout set. Using default 1m")
+		i.logger.Printf("WARN: No NotificationTimeout set. Using default 1m")
 		d = time.Minute
 	}
 	timeout := time.Now().Add(d)
@@ -317,17 +1060,18 @@ func (i *Interceptor) notify(reqPaymentHashStr string, nextHop []byte, isRegiste
 	// If there's an error waiting, resume the htlc. It will
 	// probably fail with UNKNOWN_NEXT_PEER.
 	if err != nil {
-		log.Printf(
+		i.logger.Printf(
 			"waiting for peer %x to come online failed with %v",
 			nextHop,
 			err,
 		)
 		return &InterceptResult{
-			Action: INTERCEPT_RESUME,
+			Action:          INTERCEPT_RESUME,
+			failureScenario: ScenarioClientOffline,
 		}
 	}
 
-	log.Printf("Peer %x is back online. Continue htlc.", nextHop)
+	i.logger.Printf("Peer %x is back online. Continue htlc.", nextHop)
 	// At this point we know a few things.
 	// - This is either a channel partner or a registered payment
 	// - they were offline
@@ -339,13 +1083,14 @@ func (i *Interceptor) notify(reqPaymentHashStr string, nextHop []byte, isRegiste
 	if !isRegistered {
 		err = i.client.WaitChannelActive(nextHop, timeout)
 		if err != nil {
-			log.Printf(
+			i.logger.Printf(
 				"waiting for channnel with %x to become active failed with %v",
 				nextHop,
 				err,
 			)
 			return &InterceptResult{
-				Action: INTERCEPT_RESUME,
+				Action:          INTERCEPT_RESUME,
+				failureScenario: ScenarioClientOffline,
 			}
 		}
 	}
@@ -354,14 +1099,19 @@ func (i *Interceptor) notify(reqPaymentHashStr string, nextHop []byte, isRegiste
 }
 
 func (i *Interceptor) isCurrentChainFeeCheaper(token string, params *OpeningFeeParams) bool {
-	settings, err := i.store.GetFeeParamsSettings(token)
+	settings, err := i.store.GetFeeParamsSettings(i.tenantContext(), token)
 	if err != nil {
-		log.Printf("Failed to get fee params settings: %v", err)
+		i.logger.Printf("Failed to get fee params settings: %v", err)
 		return false
 	}
 
+	minFeeMsat := i.MinOpeningFeeMsat(i.tenantContext())
 	for _, setting := range settings {
-		if setting.Params.MinMsat <= params.MinMsat {
+		settingMinMsat := setting.Params.MinMsat
+		if settingMinMsat < minFeeMsat {
+			settingMinMsat = minFeeMsat
+		}
+		if settingMinMsat <= params.MinMsat {
 			return true
 		}
 	}
@@ -369,8 +1119,71 @@ func (i *Interceptor) isCurrentChainFeeCheaper(token string, params *OpeningFeeP
 	return false
 }
 
-func (i *Interceptor) openChannel(paymentHash, destination []byte, incomingAmountMsat int64, tag *string) (*wire.OutPoint, error) {
-	capacity := incomingAmountMsat/1000 + i.config.AdditionalChannelCapacity
+// fundingFeeStrategy returns the fee strategy of the priority tier the
+// payment was registered with, or the fee strategy of lspd.
+func (i *Interceptor) fundingFeeStrategy(paymentHash []byte) chain.FeeStrategy {
+	if len(i.config.PriorityTiers) == 0 {
+		return i.feeStrategy
+	}
+
+	priority, err := i.store.PaymentPriority(i.tenantContext(), paymentHash)
+	if err != nil {
+		i.logger.Printf("PaymentPriority(%x) error, using the default fee strategy: %v", paymentHash, err)
+		return i.feeStrategy
+	}
+
+	tier := i.config.PriorityTiers[priority]
+	if tier == nil {
+		return i.feeStrategy
+	}
+
+	strategy, ok := chain.ParseFeeStrategy(tier.FeePriority)
+	if !ok {
+		return i.feeStrategy
+	}
+
+	return strategy
+}
+
+// commitmentType returns the commitment type the payment was registered
+// with, or the commitment type of the token.
+func (i *Interceptor) commitmentType(paymentHash []byte, tok *tokens.Token) lightning.CommitmentType {
+	commitmentType, err := i.store.PaymentCommitmentType(i.tenantContext(), paymentHash)
+	if err != nil {
+		i.logger.Printf("PaymentCommitmentType(%x) error, using the token commitment type: %v", paymentHash, err)
+	}
+	if commitmentType != "" {
+		return commitmentType
+	}
+
+	return tokens.CommitmentType(tok, i.config)
+}
+
+// MinOpeningFeeMsat returns the opening fee that covers the estimated chain
+// fee of a funding transaction, or zero if the node doesn't set the size of
+// its funding transactions or the fee can't be estimated.
+func (i *Interceptor) MinOpeningFeeMsat(ctx context.Context) uint64 {
+	if i.config.FundingTxVBytes == 0 || i.feeEstimator == nil {
+		return 0
+	}
+
+	fee, err := i.feeEstimator.EstimateFeeRate(ctx, i.feeStrategy)
+	if err != nil {
+		i.logger.Printf("MinOpeningFeeMsat: error estimating chain fee: %v", err)
+		return 0
+	}
+
+	return uint64(math.Ceil(fee.SatPerVByte * float64(i.config.FundingTxVBytes) * 1000))
+}
+
+// openChannel opens a channel to the destination, of the commitment type if
+// both the node and the destination support it. Returns the funding outpoint
+// and the commitment type the channel was opened with.
+func (i *Interceptor) openChannel(paymentHash, destination []byte, incomingAmountMsat int64, capacity int64, commitmentType lightning.CommitmentType, private bool, tag *string) (*wire.OutPoint, lightning.CommitmentType, error) {
+	capacity, err := i.channelCapacity(destination, incomingAmountMsat, capacity)
+	if err != nil {
+		return nil, "", err
+	}
 	if capacity == i.config.PublicChannelAmount {
 		capacity++
 	}
@@ -382,46 +1195,91 @@ func (i *Interceptor) openChannel(paymentHash, destination []byte, incomingAmoun
 	if i.feeEstimator != nil {
 		fee, err := i.feeEstimator.EstimateFeeRate(
 			context.Background(),
-			i.feeStrategy,
+			i.fundingFeeStrategy(paymentHash),
 		)
 		if err == nil {
 			feeEstimation = &fee.SatPerVByte
 			feeStr = fmt.Sprintf("%.5f", *feeEstimation)
 		} else {
-			log.Printf("Error estimating chain fee, fallback to target conf: %v", err)
+			i.logger.Printf("Error estimating chain fee, fallback to target conf: %v", err)
 			targetConf = &i.config.TargetConf
 			confStr = fmt.Sprintf("%v", *targetConf)
 		}
 	}
 
-	log.Printf(
-		"Opening zero conf channel. Destination: %x, capacity: %v, fee: %s, targetConf: %s",
+	// Only open a channel of the commitment type if the client supports
+	// it, otherwise the open would fail.
+	commitmentType = lightning.NegotiateCommitmentType(i.client, destination, commitmentType, private, i.logger)
+
+	i.logger.Printf(
+		"Opening zero conf channel. Destination: %x, capacity: %v, fee: %s, targetConf: %s, commitment type: %s, private: %v, psbt: %v",
 		destination,
 		capacity,
 		feeStr,
 		confStr,
+		commitmentType,
+		private,
+		i.psbtFunding != nil,
 	)
-	channelPoint, err := i.client.OpenChannel(&lightning.OpenChannelRequest{
+	if i.config.ShadowMode {
+		i.logger.Printf("Shadow mode: would open channel to %x with capacity %v", destination, capacity)
+		return nil, "", errShadowMode
+	}
+
+	capacitySat, err := basetypes.NewSatoshi(capacity)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid channel capacity: %w", err)
+	}
+	i.bus.Publish(&events.Event{
+		Kind:        events.ChannelOpenStarted,
+		Node:        i.config,
+		PeerID:      destination,
+		PaymentHash: paymentHash,
+		AmountMsat:  incomingAmountMsat,
+		CapacitySat: capacity,
+		Tag:         tag,
+	})
+
+	req := &lightning.OpenChannelRequest{
 		Destination:    destination,
-		CapacitySat:    uint64(capacity),
+		CapacitySat:    capacitySat,
 		MinConfs:       i.config.MinConfs,
-		IsPrivate:      true,
+		IsPrivate:      private,
 		IsZeroConf:     true,
+		CommitmentType: commitmentType,
 		FeeSatPerVByte: feeEstimation,
 		TargetConf:     targetConf,
-	})
+	}
+
+	var channelPoint *wire.OutPoint
+	if i.psbtFunding != nil {
+		channelPoint, err = i.psbtFunding.OpenChannel(req)
+	} else {
+		channelPoint, err = i.client.OpenChannel(req)
+	}
 	if err != nil {
-		log.Printf("client.OpenChannelSync(%x, %v) error: %v", destination, capacity, err)
-		return nil, err
+		i.logger.Printf("client.OpenChannelSync(%x, %v) error: %v", destination, capacity, err)
+		i.bus.Publish(&events.Event{
+			Kind:        events.ChannelOpenFailed,
+			Node:        i.config,
+			PeerID:      destination,
+			PaymentHash: paymentHash,
+			AmountMsat:  incomingAmountMsat,
+			CapacitySat: capacity,
+			Tag:         tag,
+		})
+		return nil, "", err
 	}
-	sendOpenChannelEmailNotification(
-		paymentHash,
-		incomingAmountMsat,
-		destination,
-		capacity,
-		channelPoint.String(),
-		tag,
-	)
-	err = i.store.SetFundingTx(paymentHash, channelPoint)
-	return channelPoint, err
+	i.bus.Publish(&events.Event{
+		Kind:         events.ChannelOpened,
+		Node:         i.config,
+		PeerID:       destination,
+		PaymentHash:  paymentHash,
+		ChannelPoint: channelPoint.String(),
+		AmountMsat:   incomingAmountMsat,
+		CapacitySat:  capacity,
+		Tag:          tag,
+	})
+	err = i.store.SetFundingTx(i.tenantContext(), paymentHash, channelPoint)
+	return channelPoint, commitmentType, err
 }
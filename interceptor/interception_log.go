@@ -0,0 +1,229 @@
+package interceptor
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// RecordedHtlc is an intercepted htlc as written to the interception log,
+// with what lspd knew about it when deciding, so the decision can be made
+// again offline with another config. Onions, payment secrets and tokens are
+// never written.
+type RecordedHtlc struct {
+	Time           time.Time `json:"time"`
+	Node           string    `json:"node"`
+	ShortChannelID string    `json:"scid"`
+	PaymentHash    string    `json:"payment_hash"`
+	AmountMsat     uint64    `json:"amount_msat"`
+	OutgoingExpiry uint32    `json:"outgoing_expiry"`
+	IncomingExpiry uint32    `json:"incoming_expiry"`
+	BlockHeight    uint32    `json:"block_height,omitempty"`
+
+	// The peer of the outgoing scid, empty if the scid is unknown.
+	NextHop string `json:"next_hop,omitempty"`
+	// Whether looking up the peer of the scid failed.
+	NextHopError bool `json:"next_hop_error,omitempty"`
+	Connected    bool `json:"connected"`
+	// The most an existing channel with the next hop can carry, recorded
+	// for htlcs of unregistered payments.
+	MaxLocalBalanceMsat *uint64 `json:"max_local_balance_msat,omitempty"`
+
+	Registration *RecordedRegistration `json:"registration,omitempty"`
+	Decision     *Decision             `json:"decision"`
+}
+
+// RecordedRegistration is the registered payment an htlc matched.
+type RecordedRegistration struct {
+	PaymentHash        string            `json:"payment_hash"`
+	FakeScid           bool              `json:"fake_scid,omitempty"`
+	Destination        string            `json:"destination"`
+	IncomingAmountMsat int64             `json:"incoming_amount_msat"`
+	OutgoingAmountMsat int64             `json:"outgoing_amount_msat"`
+	Params             *OpeningFeeParams `json:"params,omitempty"`
+	ChannelPoint       string            `json:"channel_point,omitempty"`
+	TokenName          string            `json:"token_name,omitempty"`
+	Tag                *string           `json:"tag,omitempty"`
+}
+
+// Decision is what lspd did, or would do, with an htlc.
+type Decision struct {
+	Action        string `json:"action"`
+	FailureCode   string `json:"failure_code,omitempty"`
+	FailureReason string `json:"failure_reason,omitempty"`
+	ForwardMsat   uint64 `json:"forward_msat,omitempty"`
+	OpensChannel  bool   `json:"opens_channel,omitempty"`
+}
+
+func (d *Decision) String() string {
+	s := d.Action
+	if d.FailureCode != "" {
+		s += " " + d.FailureCode
+	}
+	if d.ForwardMsat > 0 {
+		s += fmt.Sprintf(" %v msat", d.ForwardMsat)
+	}
+	if d.OpensChannel {
+		s += " (open)"
+	}
+	return s
+}
+
+// InterceptionLog appends the intercepted htlcs of all nodes to an NDJSON
+// file, to be replayed with `lspd simulate`.
+type InterceptionLog struct {
+	mtx  sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func OpenInterceptionLog(path string) (*InterceptionLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open interception log: %w", err)
+	}
+
+	return &InterceptionLog{
+		file: file,
+		enc:  json.NewEncoder(file),
+	}, nil
+}
+
+func (l *InterceptionLog) Write(h *RecordedHtlc) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.enc.Encode(h)
+}
+
+func (l *InterceptionLog) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.file.Close()
+}
+
+// ReadInterceptionLog calls fn for every htlc in the interception log, in
+// the order they were intercepted.
+func ReadInterceptionLog(r io.Reader, fn func(*RecordedHtlc) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var h RecordedHtlc
+		err := json.Unmarshal(scanner.Bytes(), &h)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		err = fn(&h)
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func newRecordedRegistration(
+	paymentHash []byte,
+	fakeScid bool,
+	destination []byte,
+	incomingAmountMsat int64,
+	outgoingAmountMsat int64,
+	params *OpeningFeeParams,
+	channelPoint *wire.OutPoint,
+	tag *string,
+) *RecordedRegistration {
+	r := &RecordedRegistration{
+		PaymentHash:        hex.EncodeToString(paymentHash),
+		FakeScid:           fakeScid,
+		Destination:        hex.EncodeToString(destination),
+		IncomingAmountMsat: incomingAmountMsat,
+		OutgoingAmountMsat: outgoingAmountMsat,
+		Tag:                tag,
+	}
+	if params != nil {
+		p := *params
+		p.Promise = ""
+		r.Params = &p
+	}
+	if channelPoint != nil {
+		r.ChannelPoint = channelPoint.String()
+	}
+
+	return r
+}
+
+// newDecision returns the decision for the result of an htlc.
+func newDecision(result InterceptResult, forwardMsat basetypes.MilliSatoshi, opensChannel bool) *Decision {
+	d := &Decision{
+		Action:       result.Action.String(),
+		OpensChannel: opensChannel,
+	}
+	switch result.Action {
+	case INTERCEPT_FAIL_HTLC_WITH_CODE:
+		d.FailureCode = result.FailureCode.String()
+		d.FailureReason = result.failureReason
+	case INTERCEPT_RESUME_WITH_ONION, INTERCEPT_RESUME_ON_CHANNEL:
+		d.ForwardMsat = uint64(forwardMsat)
+	}
+
+	return d
+}
+
+// recordHtlc writes the htlc and what happened to it to the interception
+// log.
+func (i *Interceptor) recordHtlc(
+	start time.Time,
+	scid *basetypes.ShortChannelID,
+	paymentHash []byte,
+	amountMsat uint64,
+	outgoingExpiry uint32,
+	incomingExpiry uint32,
+	decision InterceptResult,
+	result InterceptResult,
+) {
+	var h RecordedHtlc
+	if decision.recorded != nil {
+		h = *decision.recorded
+	}
+	h.Time = start
+	h.Node = i.config.Label()
+	h.ShortChannelID = scid.ToString()
+	h.PaymentHash = hex.EncodeToString(paymentHash)
+	h.AmountMsat = amountMsat
+	h.OutgoingExpiry = outgoingExpiry
+	h.IncomingExpiry = incomingExpiry
+	h.BlockHeight, _ = i.blockHeight()
+
+	if h.Registration == nil && h.NextHop != "" {
+		peerID, _ := hex.DecodeString(h.NextHop)
+		balance, err := i.client.GetMaxLocalBalanceMsat(peerID)
+		if err == nil {
+			h.MaxLocalBalanceMsat = &balance
+		}
+	}
+
+	opens := decision.channelOpened || result.Action == INTERCEPT_RESUME_ON_CHANNEL
+	h.Decision = newDecision(result, result.AmountMsat, opens)
+	if result.Action == INTERCEPT_FAIL_HTLC_WITH_CODE && h.Decision.FailureReason == "" {
+		h.Decision.FailureReason = decision.failureReason
+	}
+
+	err := i.interceptionLog.Write(&h)
+	if err != nil {
+		i.logger.Printf("Failed to write htlc %x to the interception log: %v", paymentHash, err)
+	}
+}
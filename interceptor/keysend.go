@@ -0,0 +1,135 @@
+package interceptor
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/tokens"
+)
+
+const (
+	KeysendPolicyResume = "resume"
+	KeysendPolicyFail   = "fail"
+	KeysendPolicyOpen   = "open"
+)
+
+// interceptUnregistered applies the keysend policy to a htlc to a peer that
+// was not registered for a channel open. Spontaneous payments like keysend
+// are never registered, so the peer may not have a channel that can carry
+// them.
+func (i *Interceptor) interceptUnregistered(
+	reqPaymentHash []byte,
+	nextHop []byte,
+	reqOutgoingAmountMsat uint64,
+	reqOutgoingExpiry uint32,
+	reqIncomingExpiry uint32,
+) InterceptResult {
+	policy := strings.ToLower(i.config.KeysendPolicy)
+	switch policy {
+	case "", KeysendPolicyResume:
+		return InterceptResult{
+			Action: INTERCEPT_RESUME,
+		}
+	case KeysendPolicyFail, KeysendPolicyOpen:
+	default:
+		i.logger.Printf("WARN: unknown keysendPolicy '%s', resuming htlc.", i.config.KeysendPolicy)
+		return InterceptResult{
+			Action: INTERCEPT_RESUME,
+		}
+	}
+
+	// If an existing channel can carry the htlc, it's a regular forward.
+	balance, err := i.client.GetMaxLocalBalanceMsat(nextHop)
+	if err != nil {
+		i.logger.Printf("GetMaxLocalBalanceMsat(%x) error: %v", nextHop, err)
+		return InterceptResult{
+			Action: INTERCEPT_RESUME,
+		}
+	}
+	if balance >= reqOutgoingAmountMsat {
+		return InterceptResult{
+			Action: INTERCEPT_RESUME,
+		}
+	}
+
+	if policy == KeysendPolicyFail {
+		i.logger.Printf("Failing unregistered htlc %x to %x, no channel can carry %v msat.", reqPaymentHash, nextHop, reqOutgoingAmountMsat)
+		return InterceptResult{
+			Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
+			FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
+		}
+	}
+
+	capacity := int64(reqOutgoingAmountMsat/1000) + i.config.AdditionalChannelCapacity
+	if capacity > i.config.KeysendMaxChannelCapacity {
+		capacity = i.config.KeysendMaxChannelCapacity
+	}
+	if capacity <= int64(reqOutgoingAmountMsat/1000) {
+		i.logger.Printf("Unregistered htlc %x of %v msat exceeds keysendMaxChannelCapacity.", reqPaymentHash, reqOutgoingAmountMsat)
+		return InterceptResult{
+			Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
+			FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
+		}
+	}
+
+	// Make sure the cltv delta is enough.
+	if int64(reqIncomingExpiry)-int64(reqOutgoingExpiry) < int64(i.config.TimeLockDelta) {
+		return InterceptResult{
+			Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
+			FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
+		}
+	}
+
+	if i.circuitBreaker != nil {
+		if state := i.circuitBreaker.State(); state.Open {
+			i.logger.Printf("Circuit breaker open. Not opening channel for unregistered htlc %x, reason: %s", reqPaymentHash, state.Reason)
+			i.circuitBreaker.markRejected()
+			return InterceptResult{
+				Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
+				FailureCode: FAILURE_TEMPORARY_NODE_FAILURE,
+			}
+		}
+	}
+
+	i.pendingOpens.Add(1)
+	defer i.pendingOpens.Add(-1)
+	amountSat := int64(reqOutgoingAmountMsat / 1000)
+	channelPoint, commitmentType, err := i.openChannel(reqPaymentHash, nextHop, amountSat*1000, capacity, tokens.CommitmentType(nil, i.config), true, nil)
+	if errors.Is(err, errShadowMode) {
+		return InterceptResult{
+			Action:      INTERCEPT_RESUME_ON_CHANNEL,
+			Destination: nextHop,
+			AmountMsat:  basetypes.MilliSatoshi(reqOutgoingAmountMsat),
+		}
+	}
+	if err != nil {
+		i.logger.Printf("openChannel(%x, %v) err: %v", nextHop, reqOutgoingAmountMsat, err)
+		return InterceptResult{
+			Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
+			FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
+		}
+	}
+
+	channelID, err := i.awaitChannel(nextHop, channelPoint, commitmentType)
+	if err != nil {
+		i.logger.Printf("awaitChannel(%x, %v) error: %v", nextHop, channelPoint.String(), err)
+		return InterceptResult{
+			Action:      INTERCEPT_FAIL_HTLC_WITH_CODE,
+			FailureCode: FAILURE_TEMPORARY_CHANNEL_FAILURE,
+		}
+	}
+
+	// The onion is not rewritten, because the payload for the next hop is
+	// encrypted to the next hop. Rewriting it would drop the keysend
+	// preimage and any other custom records. No opening fee is deducted for
+	// the same reason, the amount has to match the original payload.
+	i.logger.Printf("Opened channel %v for unregistered htlc %x, forwarding the original onion.", channelPoint.String(), reqPaymentHash)
+	return InterceptResult{
+		Action:       INTERCEPT_RESUME_ON_CHANNEL,
+		Destination:  nextHop,
+		ChannelPoint: channelPoint,
+		ChannelId:    channelID,
+		AmountMsat:   basetypes.MilliSatoshi(reqOutgoingAmountMsat),
+	}
+}
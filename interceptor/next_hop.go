@@ -0,0 +1,71 @@
+package interceptor
+
+import (
+	"github.com/breez/lspd/basetypes"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var nextHopCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_next_hop_lookups_total",
+	Help: "Number of next hop lookups of intercepted htlcs, by outcome.",
+}, []string{"node", "outcome"})
+
+type nextHopOutcome int
+
+const (
+	// The scid corresponds to a channel with a known peer.
+	nextHopFound nextHopOutcome = iota
+	// The scid doesn't correspond to a known channel, for example because
+	// it is the fake scid of a registered payment.
+	nextHopUnknown
+	// The node could not be asked for the peer of the scid.
+	nextHopLookupFailed
+)
+
+func (o nextHopOutcome) String() string {
+	switch o {
+	case nextHopFound:
+		return "found"
+	case nextHopUnknown:
+		return "unknown"
+	case nextHopLookupFailed:
+		return "lookup_failed"
+	default:
+		return "invalid"
+	}
+}
+
+type nextHopResult struct {
+	Outcome nextHopOutcome
+	// Peer of the channel. Only set if the outcome is nextHopFound.
+	PeerID []byte
+	// Only set if the outcome is nextHopLookupFailed.
+	Err error
+}
+
+type peerResolver interface {
+	GetPeerId(scid *basetypes.ShortChannelID) ([]byte, error)
+}
+
+// resolveNextHop looks up the peer of the channel the htlc is forwarded to.
+// A failed lookup is reported separately from an unknown channel, so node or
+// database errors are not mistaken for a payment to a fake scid.
+func resolveNextHop(resolver peerResolver, scid *basetypes.ShortChannelID) nextHopResult {
+	peerID, err := resolver.GetPeerId(scid)
+	if err != nil {
+		return nextHopResult{Outcome: nextHopLookupFailed, Err: err}
+	}
+
+	if len(peerID) == 0 {
+		return nextHopResult{Outcome: nextHopUnknown}
+	}
+
+	return nextHopResult{Outcome: nextHopFound, PeerID: peerID}
+}
+
+func (i *Interceptor) resolveNextHop(scid *basetypes.ShortChannelID) nextHopResult {
+	result := resolveNextHop(i.client, scid)
+	nextHopCounter.WithLabelValues(i.config.Label(), result.Outcome.String()).Inc()
+	return result
+}
@@ -0,0 +1,48 @@
+package interceptor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockResolver struct {
+	peerID []byte
+	err    error
+}
+
+func (r *mockResolver) GetPeerId(scid *basetypes.ShortChannelID) ([]byte, error) {
+	return r.peerID, r.err
+}
+
+func TestResolveNextHop(t *testing.T) {
+	scid := basetypes.ShortChannelID(123)
+	peerID := []byte{0x02, 0x01}
+	lookupErr := errors.New("connection refused")
+
+	tests := []struct {
+		name     string
+		resolver *mockResolver
+		outcome  nextHopOutcome
+		peerID   []byte
+		err      error
+	}{
+		{"found", &mockResolver{peerID: peerID}, nextHopFound, peerID, nil},
+		{"unknown", &mockResolver{}, nextHopUnknown, nil, nil},
+		{"empty peer id", &mockResolver{peerID: []byte{}}, nextHopUnknown, nil, nil},
+		{"lookup failed", &mockResolver{err: lookupErr}, nextHopLookupFailed, nil, lookupErr},
+		// An error wins over a returned peer id.
+		{"lookup failed with peer", &mockResolver{peerID: peerID, err: lookupErr}, nextHopLookupFailed, nil, lookupErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := resolveNextHop(tt.resolver, &scid)
+			assert.Equal(t, tt.outcome, result.Outcome)
+			assert.Equal(t, tt.peerID, result.PeerID)
+			assert.ErrorIs(t, result.Err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package interceptor
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/breez/lspd/chain"
+	"github.com/breez/lspd/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinOpeningFeeMsat(t *testing.T) {
+	static, _ := chain.NewStaticFeeEstimator(12.5)
+	i := &Interceptor{
+		config:       &config.NodeConfig{FundingTxVBytes: 200},
+		feeEstimator: static,
+		logger:       log.New(os.Stderr, "", 0),
+	}
+
+	assert.Equal(t, uint64(2_500_000), i.MinOpeningFeeMsat(context.Background()))
+
+	i.config.FundingTxVBytes = 0
+	assert.Equal(t, uint64(0), i.MinOpeningFeeMsat(context.Background()))
+}
+
+type priorityStore struct {
+	InterceptStore
+	priorities map[string]string
+}
+
+func (s *priorityStore) PaymentPriority(ctx context.Context, paymentHash []byte) (string, error) {
+	return s.priorities[string(paymentHash)], nil
+}
+
+func TestFundingFeeStrategy(t *testing.T) {
+	i := &Interceptor{
+		config: &config.NodeConfig{
+			PriorityTiers: map[string]*config.PriorityTierConfig{
+				"urgent":  {FeePriority: "fastest", ExtraFeeMsat: 5000},
+				"economy": {FeePriority: "minimum"},
+			},
+		},
+		store: &priorityStore{priorities: map[string]string{
+			"a": "urgent",
+			"b": "economy",
+			"c": "normal",
+		}},
+		feeStrategy: chain.FeeStrategyHour,
+		logger:      log.New(os.Stderr, "", 0),
+	}
+
+	assert.Equal(t, chain.FeeStrategyFastest, i.fundingFeeStrategy([]byte("a")))
+	assert.Equal(t, chain.FeeStrategyMinimum, i.fundingFeeStrategy([]byte("b")))
+	// Tiers the node doesn't have, and payments without a tier, use the fee
+	// strategy of lspd.
+	assert.Equal(t, chain.FeeStrategyHour, i.fundingFeeStrategy([]byte("c")))
+	assert.Equal(t, chain.FeeStrategyHour, i.fundingFeeStrategy([]byte("d")))
+}
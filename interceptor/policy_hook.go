@@ -0,0 +1,137 @@
+package interceptor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/breez/lspd/config"
+)
+
+const defaultPolicyHookTimeout = 2 * time.Second
+
+// PolicyRequest is the interception context posted to the policy hook before
+// opening a channel.
+type PolicyRequest struct {
+	Node               string  `json:"node"`
+	PaymentHash        string  `json:"payment_hash"`
+	Destination        string  `json:"destination"`
+	IncomingAmountMsat int64   `json:"incoming_amount_msat,string"`
+	OutgoingAmountMsat int64   `json:"outgoing_amount_msat,string"`
+	FeeMsat            int64   `json:"fee_msat,string"`
+	IncomingExpiry     uint32  `json:"incoming_expiry"`
+	OutgoingExpiry     uint32  `json:"outgoing_expiry"`
+	TokenName          string  `json:"token_name,omitempty"`
+	Tag                *string `json:"tag,omitempty"`
+}
+
+// PolicyDecision is the response of the policy hook. Decision is either
+// 'allow' or 'deny'. FeeMsat optionally lowers the opening fee of an allowed
+// open.
+type PolicyDecision struct {
+	Decision string  `json:"decision"`
+	FeeMsat  *uint64 `json:"fee_msat,string,omitempty"`
+	Reason   string  `json:"reason,omitempty"`
+}
+
+type policyHook struct {
+	url      string
+	failOpen bool
+	client   *http.Client
+}
+
+func newPolicyHook(conf *config.NodeConfig, logger *log.Logger) *policyHook {
+	c := conf.PolicyHook
+	if c == nil || c.URL == "" {
+		return nil
+	}
+
+	timeout := defaultPolicyHookTimeout
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			logger.Printf("WARN: invalid policy hook timeout '%s', using %v: %v", c.Timeout, timeout, err)
+		} else {
+			timeout = d
+		}
+	}
+
+	return &policyHook{
+		url:      c.URL,
+		failOpen: c.FailOpen,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// decide posts the request to the policy hook and returns its decision.
+func (h *policyHook) decide(ctx context.Context, req *PolicyRequest) (*PolicyDecision, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var decision PolicyDecision
+	err = json.NewDecoder(resp.Body).Decode(&decision)
+	if err != nil {
+		return nil, fmt.Errorf("invalid decision: %w", err)
+	}
+
+	if decision.Decision != "allow" && decision.Decision != "deny" {
+		return nil, fmt.Errorf("unknown decision '%s'", decision.Decision)
+	}
+
+	return &decision, nil
+}
+
+// checkPolicy asks the policy hook whether to open a channel for the payment.
+// It returns whether the open is allowed, and the outgoing amount of the
+// payment after a fee override of the hook.
+func (i *Interceptor) checkPolicy(req *PolicyRequest) (bool, int64) {
+	if i.policyHook == nil {
+		return true, req.OutgoingAmountMsat
+	}
+
+	decision, err := i.policyHook.decide(context.Background(), req)
+	if err != nil {
+		i.logger.Printf("Policy hook error for payment %s, failOpen: %v: %v", req.PaymentHash, i.policyHook.failOpen, err)
+		return i.policyHook.failOpen, req.OutgoingAmountMsat
+	}
+
+	if decision.Decision == "deny" {
+		i.logger.Printf("Policy hook denied channel open for payment %s: %s", req.PaymentHash, decision.Reason)
+		return false, req.OutgoingAmountMsat
+	}
+
+	// The client expects at least the registered amount, so the fee can only
+	// be lowered.
+	if decision.FeeMsat != nil {
+		if int64(*decision.FeeMsat) > req.FeeMsat {
+			i.logger.Printf("WARN: Policy hook raised the fee of payment %s to %v msat, keeping %v msat", req.PaymentHash, *decision.FeeMsat, req.FeeMsat)
+		} else {
+			i.logger.Printf("Policy hook lowered the fee of payment %s to %v msat", req.PaymentHash, *decision.FeeMsat)
+			return true, req.IncomingAmountMsat - int64(*decision.FeeMsat)
+		}
+	}
+
+	return true, req.OutgoingAmountMsat
+}
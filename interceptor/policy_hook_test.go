@@ -0,0 +1,80 @@
+package interceptor
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/breez/lspd/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPolicyTestInterceptor(t *testing.T, handler http.HandlerFunc, failOpen bool) *Interceptor {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	logger := log.New(os.Stderr, "", 0)
+	conf := &config.NodeConfig{
+		PolicyHook: &config.PolicyHookConfig{URL: srv.URL, FailOpen: failOpen},
+	}
+	return &Interceptor{
+		config:     conf,
+		policyHook: newPolicyHook(conf, logger),
+		logger:     logger,
+	}
+}
+
+func policyRequest() *PolicyRequest {
+	return &PolicyRequest{
+		PaymentHash:        "hash",
+		IncomingAmountMsat: 100_000_000,
+		OutgoingAmountMsat: 98_000_000,
+		FeeMsat:            2_000_000,
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	var received PolicyRequest
+	i := newPolicyTestInterceptor(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte(`{"decision": "allow", "fee_msat": "1000000"}`))
+	}, false)
+
+	allowed, amt := i.checkPolicy(policyRequest())
+	assert.True(t, allowed)
+	assert.Equal(t, int64(99_000_000), amt)
+	assert.Equal(t, int64(2_000_000), received.FeeMsat)
+}
+
+func TestCheckPolicyKeepsFeeOnRaise(t *testing.T) {
+	i := newPolicyTestInterceptor(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"decision": "allow", "fee_msat": "3000000"}`))
+	}, false)
+
+	allowed, amt := i.checkPolicy(policyRequest())
+	assert.True(t, allowed)
+	assert.Equal(t, int64(98_000_000), amt)
+}
+
+func TestCheckPolicyDeny(t *testing.T) {
+	i := newPolicyTestInterceptor(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"decision": "deny", "reason": "risky"}`))
+	}, true)
+
+	allowed, _ := i.checkPolicy(policyRequest())
+	assert.False(t, allowed)
+}
+
+func TestCheckPolicyFailure(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	allowed, _ := newPolicyTestInterceptor(t, handler, false).checkPolicy(policyRequest())
+	assert.False(t, allowed)
+
+	allowed, _ = newPolicyTestInterceptor(t, handler, true).checkPolicy(policyRequest())
+	assert.True(t, allowed)
+}
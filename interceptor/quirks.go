@@ -0,0 +1,69 @@
+package interceptor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lsperrors"
+)
+
+// matchQuirks returns the first quirks entry matching the client agent, or
+// nil if none matches.
+func matchQuirks(quirks []*config.ClientQuirksConfig, agent string) *config.ClientQuirksConfig {
+	if agent == "" {
+		return nil
+	}
+
+	for _, q := range quirks {
+		if q != nil && q.Agent != "" && strings.HasPrefix(agent, q.Agent) {
+			return q
+		}
+	}
+
+	return nil
+}
+
+// checkClientQuirks returns the error to fail the htlc with if the wallet sdk
+// the destination registered with can't use the channel lspd would open for
+// the htlc.
+func (i *Interceptor) checkClientQuirks(destination []byte, outgoingExpiry uint32) error {
+	if len(i.config.ClientQuirks) == 0 {
+		return nil
+	}
+
+	lspNodeID, _ := hex.DecodeString(i.config.NodePubkey)
+	agent, err := i.store.GetClientAgent(i.tenantContext(), lspNodeID, destination)
+	if err != nil {
+		i.logger.Printf("GetClientAgent(%x) error, not checking client quirks: %v", destination, err)
+		return nil
+	}
+
+	q := matchQuirks(i.config.ClientQuirks, agent)
+	if q == nil {
+		return nil
+	}
+
+	if q.NeedsTotalMsatRewrite && i.config.Cln != nil {
+		return fmt.Errorf("%w: %s needs a rewritten total_msat", lsperrors.ErrClientIncompatible, agent)
+	}
+
+	if q.NoAliasScid {
+		return fmt.Errorf("%w: %s can't handle alias scids", lsperrors.ErrClientIncompatible, agent)
+	}
+
+	if q.MaxCltvDelta > 0 {
+		height, err := i.blockHeight()
+		if err != nil {
+			i.logger.Printf("blockHeight() error, not checking the max cltv delta of %s: %v", agent, err)
+			return nil
+		}
+
+		if int64(outgoingExpiry)-int64(height) > int64(q.MaxCltvDelta) {
+			return fmt.Errorf("%w: %s accepts %d blocks at most", lsperrors.ErrExpiryTooFar, agent, q.MaxCltvDelta)
+		}
+	}
+
+	return nil
+}
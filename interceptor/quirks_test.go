@@ -0,0 +1,19 @@
+package interceptor
+
+import (
+	"testing"
+
+	"github.com/breez/lspd/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchQuirks(t *testing.T) {
+	old := &config.ClientQuirksConfig{Agent: "breez-sdk/0.2.", NoAliasScid: true}
+	all := &config.ClientQuirksConfig{Agent: "breez-sdk/", MaxCltvDelta: 2016}
+	quirks := []*config.ClientQuirksConfig{old, all}
+
+	assert.Equal(t, old, matchQuirks(quirks, "breez-sdk/0.2.7"))
+	assert.Equal(t, all, matchQuirks(quirks, "breez-sdk/0.4.0"))
+	assert.Nil(t, matchQuirks(quirks, "other-wallet/1.0"))
+	assert.Nil(t, matchQuirks(quirks, ""))
+}
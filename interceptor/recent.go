@@ -0,0 +1,95 @@
+package interceptor
+
+import (
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+)
+
+// Number of intercepted htlcs kept in memory for the admin api.
+const recentHtlcsSize = 100
+
+type HtlcRecord struct {
+	Time        time.Time `json:"time"`
+	PaymentHash string    `json:"paymentHash"`
+	Scid        string    `json:"scid"`
+	AmountMsat  uint64    `json:"amountMsat"`
+	Action      string    `json:"action"`
+	FailureCode string    `json:"failureCode,omitempty"`
+	Duration    float64   `json:"duration"`
+}
+
+func (a InterceptAction) String() string {
+	switch a {
+	case INTERCEPT_RESUME:
+		return "resume"
+	case INTERCEPT_RESUME_WITH_ONION:
+		return "resume_with_onion"
+	case INTERCEPT_FAIL_HTLC_WITH_CODE:
+		return "fail"
+	case INTERCEPT_RESUME_ON_CHANNEL:
+		return "resume_on_channel"
+	default:
+		return "unknown"
+	}
+}
+
+func (c InterceptFailureCode) String() string {
+	name, ok := failureCodeNames[c]
+	if !ok {
+		return "unknown"
+	}
+
+	return name
+}
+
+// recentHtlcs is a ring buffer of the last intercepted htlcs.
+type recentHtlcs struct {
+	mtx     sync.Mutex
+	records []*HtlcRecord
+	next    int
+}
+
+func (r *recentHtlcs) add(
+	start time.Time,
+	scid *basetypes.ShortChannelID,
+	paymentHash []byte,
+	amountMsat uint64,
+	result InterceptResult,
+) {
+	record := &HtlcRecord{
+		Time:        start.UTC(),
+		PaymentHash: hex.EncodeToString(paymentHash),
+		Scid:        scid.ToString(),
+		AmountMsat:  amountMsat,
+		Action:      result.Action.String(),
+		Duration:    time.Since(start).Seconds(),
+	}
+	if result.Action == INTERCEPT_FAIL_HTLC_WITH_CODE {
+		record.FailureCode = result.FailureCode.String()
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if len(r.records) < recentHtlcsSize {
+		r.records = append(r.records, record)
+		return
+	}
+
+	r.records[r.next] = record
+	r.next = (r.next + 1) % recentHtlcsSize
+}
+
+// list returns the recent htlcs, newest first.
+func (r *recentHtlcs) list() []*HtlcRecord {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	result := make([]*HtlcRecord, 0, len(r.records))
+	for j := len(r.records) - 1; j >= 0; j-- {
+		result = append(result, r.records[(r.next+j)%len(r.records)])
+	}
+
+	return result
+}
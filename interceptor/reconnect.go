@@ -0,0 +1,115 @@
+package interceptor
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultReconnectAttempts = 3
+	defaultReconnectBackoff  = 500 * time.Millisecond
+	defaultReconnectTimeout  = 10 * time.Second
+)
+
+var reconnectCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_reconnects_total",
+	Help: "Number of attempts to connect to offline clients before forwarding, by result.",
+}, []string{"node", "result"})
+
+var reconnectDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "lspd_reconnect_duration_seconds",
+	Help:    "Time spent connecting to offline clients before forwarding, by result.",
+	Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+}, []string{"node", "result"})
+
+// reconnect connects to the offline destination on the addresses it
+// registered or was last reached on, retrying with backoff until the
+// reconnect timeout. Returns whether the destination is connected.
+func (i *Interceptor) reconnect(destination []byte) bool {
+	cfg := i.config.Reconnect
+	if cfg == nil {
+		return false
+	}
+
+	attempts := cfg.Attempts
+	if attempts <= 0 {
+		attempts = defaultReconnectAttempts
+	}
+	backoff := i.reconnectDuration("backoff", cfg.Backoff, defaultReconnectBackoff)
+	timeout := i.reconnectDuration("timeout", cfg.Timeout, defaultReconnectTimeout)
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(i.tenantContext(), timeout)
+	defer cancel()
+
+	lspNodeID, _ := hex.DecodeString(i.config.NodePubkey)
+	addresses, err := i.store.GetPeerAddresses(ctx, lspNodeID, destination)
+	if err != nil {
+		i.logger.Printf("GetPeerAddresses(%x) error: %v", destination, err)
+		i.observeReconnect("error", start)
+		return false
+	}
+	if len(addresses) == 0 {
+		i.observeReconnect("no_address", start)
+		return false
+	}
+
+	for attempt := 1; ; attempt++ {
+		for _, address := range addresses {
+			err = i.client.ConnectPeer(ctx, destination, address)
+			if err == nil {
+				i.logger.Printf("Connected to offline client %x on %s after %v.", destination, address, time.Since(start))
+				i.observeReconnect("connected", start)
+				return true
+			}
+
+			// The client may have connected by itself in the meantime.
+			if connected, err := i.client.IsConnected(destination); err == nil && connected {
+				i.observeReconnect("connected", start)
+				return true
+			}
+
+			i.logger.Printf("reconnect attempt %d to %x on %s failed: %v", attempt, destination, address, err)
+			if ctx.Err() != nil {
+				i.observeReconnect("timeout", start)
+				return false
+			}
+		}
+
+		if attempt >= attempts {
+			i.observeReconnect("failed", start)
+			return false
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			i.observeReconnect("timeout", start)
+			return false
+		}
+	}
+}
+
+func (i *Interceptor) reconnectDuration(name string, value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		i.logger.Printf("WARN: Invalid reconnect %s '%s'. Using default %v", name, value, def)
+		return def
+	}
+
+	return d
+}
+
+func (i *Interceptor) observeReconnect(result string, start time.Time) {
+	reconnectCounter.WithLabelValues(i.config.Label(), result).Inc()
+	reconnectDuration.WithLabelValues(i.config.Label(), result).Observe(time.Since(start).Seconds())
+}
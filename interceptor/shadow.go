@@ -0,0 +1,43 @@
+package interceptor
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/breez/lspd/basetypes"
+)
+
+// errShadowMode is returned instead of opening a channel in shadow mode.
+var errShadowMode = errors.New("shadow mode")
+
+// logShadowDecision logs what would have happened to the htlc if shadow mode
+// was disabled.
+func (i *Interceptor) logShadowDecision(paymentHash string, amount basetypes.MilliSatoshi, result InterceptResult) {
+	switch result.Action {
+	case INTERCEPT_FAIL_HTLC_WITH_CODE:
+		i.logger.Printf("Shadow mode: would fail htlc %s of %v with %s", paymentHash, amount, result.FailureCode)
+	case INTERCEPT_RESUME_WITH_ONION:
+		amt := shadowForwardAmount(amount, result)
+		i.logger.Printf("Shadow mode: would forward htlc %s of %v to %x as %v, fee %v", paymentHash, amount, result.Destination, amt, amount-amt)
+	case INTERCEPT_RESUME_ON_CHANNEL:
+		i.logger.Printf("Shadow mode: would forward htlc %s of %v to %x over a new channel", paymentHash, amount, result.Destination)
+	default:
+		i.logger.Printf("Shadow mode: would resume htlc %s of %v", paymentHash, amount)
+	}
+}
+
+// shadowForwardAmount returns the amount that would be forwarded for the htlc:
+// its share of the amount forwarded for the payment.
+func shadowForwardAmount(amount basetypes.MilliSatoshi, result InterceptResult) basetypes.MilliSatoshi {
+	if result.Action == INTERCEPT_RESUME_ON_CHANNEL {
+		return result.AmountMsat
+	}
+	if result.Action != INTERCEPT_RESUME_WITH_ONION || result.incomingAmountMsat <= 0 {
+		return amount
+	}
+
+	var a big.Int
+	a.Mul(new(big.Int).SetUint64(uint64(result.TotalAmountMsat)), new(big.Int).SetUint64(uint64(amount)))
+	a.Div(&a, big.NewInt(result.incomingAmountMsat))
+	return basetypes.MilliSatoshi(a.Uint64())
+}
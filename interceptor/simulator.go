@@ -0,0 +1,349 @@
+package interceptor
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/chain"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/tokens"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var errSimulated = errors.New("not available in a simulation")
+
+// Simulator decides about recorded htlcs again with a node config, offline.
+// The node and the database are replaced by what the interception log says
+// lspd knew about every htlc, and the interceptor runs in shadow mode, so
+// nothing is opened or notified. Clients are assumed to signal the required
+// features and to have no quirks, and tokens only carry their name, so
+// token settings stored in the database don't apply. The policy hook of the
+// config is called like it would be live.
+type Simulator struct {
+	interceptor *Interceptor
+	backend     *simulatedBackend
+	decision    *Decision
+}
+
+func NewSimulator(node *config.NodeConfig, logger *log.Logger) *Simulator {
+	cfg := *node
+	cfg.ShadowMode = true
+	b := &simulatedBackend{}
+	s := &Simulator{backend: b}
+	s.interceptor = NewInterceptor(b, &cfg, b, b, nil, chain.FeeStrategyFastest, nil, nil, nil, logger)
+	s.interceptor.shadowDecisions = func(result InterceptResult, forwardMsat basetypes.MilliSatoshi) {
+		opens := result.Action == INTERCEPT_RESUME_ON_CHANNEL ||
+			(result.Action == INTERCEPT_RESUME_WITH_ONION && result.ChannelPoint == nil)
+		s.decision = newDecision(result, forwardMsat, opens)
+	}
+	return s
+}
+
+// Simulate returns the decision the interceptor makes about the recorded
+// htlc with the config of the simulator.
+func (s *Simulator) Simulate(h *RecordedHtlc) (*Decision, error) {
+	scid, err := basetypes.NewShortChannelIDFromString(h.ShortChannelID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scid '%s': %w", h.ShortChannelID, err)
+	}
+	paymentHash, err := hex.DecodeString(h.PaymentHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment hash '%s': %w", h.PaymentHash, err)
+	}
+	err = s.backend.set(h)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.Registration != nil && h.Registration.FakeScid {
+		s.interceptor.fakeScids.add(*scid, paymentHash)
+	}
+
+	// Every htlc is decided at the block height it was intercepted at.
+	s.interceptor.blockHeightCache.mtx.Lock()
+	s.interceptor.blockHeightCache.height = 0
+	s.interceptor.blockHeightCache.mtx.Unlock()
+
+	s.decision = nil
+	s.interceptor.Intercept(scid, paymentHash, h.AmountMsat, h.OutgoingExpiry, h.IncomingExpiry)
+	if s.decision == nil {
+		return nil, fmt.Errorf("no decision about htlc %s", h.PaymentHash)
+	}
+
+	return s.decision, nil
+}
+
+// simulatedBackend answers the node and store calls of the interceptor with
+// the recorded htlc.
+type simulatedBackend struct {
+	htlc         *RecordedHtlc
+	nextHop      []byte
+	paymentHash  []byte
+	destination  []byte
+	params       *OpeningFeeParams
+	channelPoint *wire.OutPoint
+}
+
+func (b *simulatedBackend) set(h *RecordedHtlc) error {
+	*b = simulatedBackend{htlc: h}
+	var err error
+	b.nextHop, err = hex.DecodeString(h.NextHop)
+	if err != nil {
+		return fmt.Errorf("invalid next hop '%s': %w", h.NextHop, err)
+	}
+
+	r := h.Registration
+	if r == nil {
+		return nil
+	}
+
+	b.paymentHash, err = hex.DecodeString(r.PaymentHash)
+	if err != nil {
+		return fmt.Errorf("invalid registered payment hash '%s': %w", r.PaymentHash, err)
+	}
+	b.destination, err = hex.DecodeString(r.Destination)
+	if err != nil {
+		return fmt.Errorf("invalid destination '%s': %w", r.Destination, err)
+	}
+	if r.ChannelPoint != "" {
+		b.channelPoint, err = basetypes.ParseOutPoint(r.ChannelPoint)
+		if err != nil {
+			return fmt.Errorf("invalid channel point '%s': %w", r.ChannelPoint, err)
+		}
+	}
+
+	// The fee params are as valid as they were when the htlc arrived.
+	if r.Params != nil {
+		p := *r.Params
+		validUntil, err := time.Parse(basetypes.TIME_FORMAT, p.ValidUntil)
+		if err == nil {
+			p.ValidUntil = validUntil.Add(time.Since(h.Time)).UTC().Format(basetypes.TIME_FORMAT)
+		}
+		b.params = &p
+	}
+
+	return nil
+}
+
+func (b *simulatedBackend) paymentInfo() (string, *OpeningFeeParams, []byte, []byte, []byte, int64, int64, *wire.OutPoint, *string, error) {
+	r := b.htlc.Registration
+	if r == nil {
+		return "", nil, nil, nil, nil, 0, 0, nil, nil, nil
+	}
+
+	// The payment secret isn't recorded, any secret marks the payment as
+	// registered.
+	return r.TokenName, b.params, b.paymentHash, []byte{1}, b.destination, r.IncomingAmountMsat, r.OutgoingAmountMsat, b.channelPoint, r.Tag, nil
+}
+
+func (b *simulatedBackend) PaymentInfo(ctx context.Context, htlcPaymentHash []byte) (string, *OpeningFeeParams, []byte, []byte, []byte, int64, int64, *wire.OutPoint, *string, error) {
+	return b.paymentInfo()
+}
+
+func (b *simulatedBackend) PaymentInfoByScid(ctx context.Context, fakeScid uint64) (string, *OpeningFeeParams, []byte, []byte, []byte, int64, int64, *wire.OutPoint, *string, error) {
+	return b.paymentInfo()
+}
+
+func (b *simulatedBackend) GetInfo() (*lightning.GetInfoResult, error) {
+	if b.htlc.BlockHeight == 0 {
+		return nil, errSimulated
+	}
+	return &lightning.GetInfoResult{BlockHeight: b.htlc.BlockHeight}, nil
+}
+
+func (b *simulatedBackend) GetPeerId(scid *basetypes.ShortChannelID) ([]byte, error) {
+	if b.htlc.NextHopError {
+		return nil, errSimulated
+	}
+	return b.nextHop, nil
+}
+
+func (b *simulatedBackend) IsConnected(destination []byte) (bool, error) {
+	return b.htlc.Connected, nil
+}
+
+func (b *simulatedBackend) GetMaxLocalBalanceMsat(peerID []byte) (uint64, error) {
+	if b.htlc.MaxLocalBalanceMsat == nil {
+		return 0, errSimulated
+	}
+	return *b.htlc.MaxLocalBalanceMsat, nil
+}
+
+func (b *simulatedBackend) GetChannel(peerID []byte, channelPoint wire.OutPoint) (*lightning.GetChannelResult, error) {
+	return &lightning.GetChannelResult{}, nil
+}
+
+func (b *simulatedBackend) PeerFeatures(peerID []byte) (func(bit uint32) bool, error) {
+	return func(bit uint32) bool { return true }, nil
+}
+
+func (b *simulatedBackend) SupportsCommitmentType(peerID []byte, t lightning.CommitmentType) (bool, error) {
+	return true, nil
+}
+
+func (b *simulatedBackend) SupportsLargeChannels(peerID []byte) (bool, error) {
+	return true, nil
+}
+
+func (b *simulatedBackend) ConnectPeer(ctx context.Context, peerID []byte, address string) error {
+	return errSimulated
+}
+
+func (b *simulatedBackend) OpenChannel(req *lightning.OpenChannelRequest) (*wire.OutPoint, error) {
+	return nil, errSimulated
+}
+
+func (b *simulatedBackend) GetNodeChannelCount(ctx context.Context, nodeID []byte) (int, error) {
+	return 0, nil
+}
+
+func (b *simulatedBackend) ListPeerChannels(ctx context.Context, peerID []byte) ([]*lightning.PeerChannel, error) {
+	return nil, nil
+}
+
+func (b *simulatedBackend) SetChannelPolicy(peerID []byte, channelPoint wire.OutPoint, policy *lightning.ChannelPolicy) error {
+	return nil
+}
+
+func (b *simulatedBackend) GetClosedChannels(ctx context.Context, nodeID string, channelPoints map[string]uint64) (map[string]uint64, error) {
+	return nil, errSimulated
+}
+
+func (b *simulatedBackend) WaitOnline(peerID []byte, deadline time.Time) error {
+	return nil
+}
+
+func (b *simulatedBackend) WaitChannelActive(peerID []byte, deadline time.Time) error {
+	return nil
+}
+
+func (b *simulatedBackend) GetWalletBalance() (*lightning.GetWalletBalanceResult, error) {
+	return nil, errSimulated
+}
+
+func (b *simulatedBackend) GetChannelActivity(peerID []byte, channelPoint wire.OutPoint) (uint64, error) {
+	return 0, errSimulated
+}
+
+func (b *simulatedBackend) CloseChannel(peerID []byte, channelPoint wire.OutPoint) (string, error) {
+	return "", errSimulated
+}
+
+func (b *simulatedBackend) ListForwards(ctx context.Context, since time.Time) ([]*lightning.Forward, error) {
+	return nil, errSimulated
+}
+
+func (b *simulatedBackend) ListPendingHtlcs(ctx context.Context, peerID []byte) ([]*lightning.PendingHtlc, error) {
+	return nil, errSimulated
+}
+
+func (b *simulatedBackend) AssignFakeScid(ctx context.Context, paymentHash []byte, fakeScid uint64) (uint64, error) {
+	return 0, errSimulated
+}
+
+func (b *simulatedBackend) ListFakeScids(ctx context.Context, lspNodeID []byte) (map[uint64][]byte, error) {
+	return nil, nil
+}
+
+func (b *simulatedBackend) SetFundingTx(ctx context.Context, paymentHash []byte, channelPoint *wire.OutPoint) error {
+	return nil
+}
+
+func (b *simulatedBackend) RegisterPayment(ctx context.Context, token string, lspNodeID []byte, params *OpeningFeeParams, destination, paymentHash, paymentSecret []byte, incomingAmountMsat, outgoingAmountMsat int64, tag string, priority string, commitmentType lightning.CommitmentType) error {
+	return errSimulated
+}
+
+func (b *simulatedBackend) PaymentPriority(ctx context.Context, paymentHash []byte) (string, error) {
+	return "", nil
+}
+
+func (b *simulatedBackend) PaymentCommitmentType(ctx context.Context, paymentHash []byte) (lightning.CommitmentType, error) {
+	return "", nil
+}
+
+func (b *simulatedBackend) CancelPayment(ctx context.Context, paymentHash, destination []byte) (bool, error) {
+	return false, errSimulated
+}
+
+func (b *simulatedBackend) InsertPreimage(ctx context.Context, lspNodeID, destination, paymentHash, preimage []byte) error {
+	return errSimulated
+}
+
+func (b *simulatedBackend) ClaimPreimage(ctx context.Context, paymentHash, destination []byte) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (b *simulatedBackend) RecordHtlcFee(ctx context.Context, lspNodeID, destination, paymentHash []byte, amountIn, amountOut basetypes.MilliSatoshi) (int64, error) {
+	return 0, nil
+}
+
+func (b *simulatedBackend) InsertChannel(ctx context.Context, lspNodeID []byte, initialChanID, confirmedChanId uint64, channelPoint string, nodeID []byte, commitmentType lightning.CommitmentType, lastUpdate time.Time) error {
+	return nil
+}
+
+func (b *simulatedBackend) GetFeeParamsSettings(ctx context.Context, token string) ([]*OpeningFeeParamsSetting, error) {
+	return nil, nil
+}
+
+func (b *simulatedBackend) RegisterNonce(ctx context.Context, destination, nonce []byte) (bool, error) {
+	return false, errSimulated
+}
+
+func (b *simulatedBackend) ForgetNonce(ctx context.Context, destination, nonce []byte) error {
+	return errSimulated
+}
+
+func (b *simulatedBackend) DeleteNonces(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (b *simulatedBackend) SaveAddressHints(ctx context.Context, lspNodeID, destination []byte, addresses []string) error {
+	return nil
+}
+
+func (b *simulatedBackend) GetPeerAddresses(ctx context.Context, lspNodeID, peerID []byte) ([]string, error) {
+	return nil, nil
+}
+
+func (b *simulatedBackend) SaveClientAgent(ctx context.Context, lspNodeID, destination []byte, agent string) error {
+	return nil
+}
+
+func (b *simulatedBackend) GetClientAgent(ctx context.Context, lspNodeID, destination []byte) (string, error) {
+	return "", nil
+}
+
+// Get returns a token with only the recorded name, the token itself isn't
+// recorded.
+func (b *simulatedBackend) Get(ctx context.Context, token string) (*tokens.Token, error) {
+	if token == "" {
+		return nil, nil
+	}
+	return &tokens.Token{Name: token}, nil
+}
+
+func (b *simulatedBackend) List(ctx context.Context) ([]*tokens.Token, error) {
+	return nil, nil
+}
+
+func (b *simulatedBackend) GetFeeParams(ctx context.Context, token string) ([]*tokens.FeeParams, error) {
+	return nil, nil
+}
+
+func (b *simulatedBackend) Create(ctx context.Context, token *tokens.Token, feeParams []*tokens.FeeParams) error {
+	return errSimulated
+}
+
+func (b *simulatedBackend) Rotate(ctx context.Context, oldToken string, newToken string, disableAt time.Time) error {
+	return errSimulated
+}
+
+func (b *simulatedBackend) Disable(ctx context.Context, token string, at time.Time) error {
+	return errSimulated
+}
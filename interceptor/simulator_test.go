@@ -0,0 +1,68 @@
+package interceptor
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulate(t *testing.T) {
+	recorded := time.Now().Add(-24 * time.Hour)
+	h := &RecordedHtlc{
+		Time:           recorded,
+		Node:           "node",
+		ShortChannelID: "800000x1x0",
+		PaymentHash:    strings.Repeat("ab", 32),
+		AmountMsat:     100_000_000,
+		OutgoingExpiry: 800_100,
+		IncomingExpiry: 800_250,
+		BlockHeight:    800_000,
+		Connected:      true,
+		Registration: &RecordedRegistration{
+			PaymentHash:        strings.Repeat("ab", 32),
+			Destination:        "02" + strings.Repeat("cd", 32),
+			IncomingAmountMsat: 100_000_000,
+			OutgoingAmountMsat: 99_000_000,
+			Params: &OpeningFeeParams{
+				MinMsat:      1_000_000,
+				Proportional: 10_000,
+				// Valid when the htlc arrived, expired by now.
+				ValidUntil: recorded.Add(time.Hour).UTC().Format(basetypes.TIME_FORMAT),
+			},
+		},
+		Decision: &Decision{Action: "resume_with_onion", ForwardMsat: 99_000_000, OpensChannel: true},
+	}
+
+	// The log is read back as written.
+	var b bytes.Buffer
+	assert.NoError(t, (&InterceptionLog{enc: json.NewEncoder(&b)}).Write(h))
+	var read []*RecordedHtlc
+	assert.NoError(t, ReadInterceptionLog(&b, func(r *RecordedHtlc) error {
+		read = append(read, r)
+		return nil
+	}))
+	assert.Len(t, read, 1)
+	assert.Equal(t, h.Registration, read[0].Registration)
+
+	logger := log.New(io.Discard, "", 0)
+	node := &config.NodeConfig{Name: "node", TimeLockDelta: 144}
+	decision, err := NewSimulator(node, logger).Simulate(read[0])
+	assert.NoError(t, err)
+	assert.Equal(t, h.Decision, decision)
+
+	// A higher minimum payment size fails the payment.
+	node.MinPaymentSizeMsat = 200_000_000
+	decision, err = NewSimulator(node, logger).Simulate(read[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "fail", decision.Action)
+	assert.Equal(t, "below_minimum", decision.FailureReason)
+	assert.False(t, node.ShadowMode)
+}
@@ -0,0 +1,89 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/breez/lspd/lightning"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const defaultSpliceTimeout = 2 * time.Minute
+
+// spliceIn adds the capacity of the channel that would be opened for the
+// payment to an existing channel with the destination instead. It returns a
+// nil channel point if there is nothing to splice into, so a new channel is
+// opened instead. The returned channel point is the funding outpoint of the
+// existing channel. If the spliced funds can't be used before the splice
+// timeout, the channel point is returned with an error, so the htlcs are
+// failed and the payment is forwarded over the channel when the sender
+// retries.
+func (i *Interceptor) spliceIn(
+	paymentHash []byte,
+	destination []byte,
+	incomingAmountMsat int64,
+	outgoingAmountMsat int64,
+	capacity int64,
+) (*wire.OutPoint, error) {
+	splicer, ok := i.client.(lightning.Splicer)
+	if !ok || i.config.ShadowMode {
+		return nil, nil
+	}
+
+	timeout := defaultSpliceTimeout
+	if i.config.Splicing.Timeout != "" {
+		d, err := time.ParseDuration(i.config.Splicing.Timeout)
+		if err != nil {
+			i.logger.Printf("WARN: Invalid splicing timeout '%s'. Using default %v", i.config.Splicing.Timeout, defaultSpliceTimeout)
+		} else {
+			timeout = d
+		}
+	}
+
+	var feeEstimation *float64
+	if i.feeEstimator != nil {
+		fee, err := i.feeEstimator.EstimateFeeRate(
+			context.Background(),
+			i.feeStrategy,
+		)
+		if err == nil {
+			feeEstimation = &fee.SatPerVByte
+		} else {
+			i.logger.Printf("Error estimating chain fee for splice, using node default: %v", err)
+		}
+	}
+
+	amountSat := capacity
+	i.logger.Printf("Splicing %v sat into channel with %x for payment %x", amountSat, destination, paymentHash)
+	channelPoint, err := splicer.SpliceIn(destination, uint64(amountSat), feeEstimation)
+	if errors.Is(err, lightning.ErrNoSpliceableChannel) {
+		return nil, nil
+	}
+	if err != nil {
+		i.logger.Printf("SpliceIn(%x, %v) error, opening new channel instead: %v", destination, amountSat, err)
+		return nil, nil
+	}
+
+	err = i.store.SetFundingTx(i.tenantContext(), paymentHash, channelPoint)
+	if err != nil {
+		return channelPoint, err
+	}
+
+	// Wait for the spliced funds to become usable, so the channel can carry
+	// the payment.
+	deadline := time.Now().Add(timeout)
+	for {
+		balance, err := i.client.GetMaxLocalBalanceMsat(destination)
+		if err == nil && balance >= uint64(outgoingAmountMsat) {
+			return channelPoint, nil
+		}
+
+		if time.Now().After(deadline) {
+			i.logger.Printf("Spliced funds in channel %v with %x not usable after %v", channelPoint.String(), destination, timeout)
+			return channelPoint, errors.New("spliced funds not usable in time")
+		}
+
+		<-time.After(time.Second)
+	}
+}
@@ -1,8 +1,11 @@
 package interceptor
 
 import (
+	"context"
 	"time"
 
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/lightning"
 	"github.com/btcsuite/btcd/wire"
 )
 
@@ -20,9 +23,25 @@ type OpeningFeeParams struct {
 }
 
 type InterceptStore interface {
-	PaymentInfo(htlcPaymentHash []byte) (string, *OpeningFeeParams, []byte, []byte, []byte, int64, int64, *wire.OutPoint, *string, error)
-	SetFundingTx(paymentHash []byte, channelPoint *wire.OutPoint) error
-	RegisterPayment(token string, params *OpeningFeeParams, destination, paymentHash, paymentSecret []byte, incomingAmountMsat, outgoingAmountMsat int64, tag string) error
-	InsertChannel(initialChanID, confirmedChanId uint64, channelPoint string, nodeID []byte, lastUpdate time.Time) error
-	GetFeeParamsSettings(token string) ([]*OpeningFeeParamsSetting, error)
+	PaymentInfo(ctx context.Context, htlcPaymentHash []byte) (string, *OpeningFeeParams, []byte, []byte, []byte, int64, int64, *wire.OutPoint, *string, error)
+	PaymentInfoByScid(ctx context.Context, fakeScid uint64) (string, *OpeningFeeParams, []byte, []byte, []byte, int64, int64, *wire.OutPoint, *string, error)
+	AssignFakeScid(ctx context.Context, paymentHash []byte, fakeScid uint64) (uint64, error)
+	ListFakeScids(ctx context.Context, lspNodeID []byte) (map[uint64][]byte, error)
+	SetFundingTx(ctx context.Context, paymentHash []byte, channelPoint *wire.OutPoint) error
+	RegisterPayment(ctx context.Context, token string, lspNodeID []byte, params *OpeningFeeParams, destination, paymentHash, paymentSecret []byte, incomingAmountMsat, outgoingAmountMsat int64, tag string, priority string, commitmentType lightning.CommitmentType) error
+	PaymentPriority(ctx context.Context, paymentHash []byte) (string, error)
+	PaymentCommitmentType(ctx context.Context, paymentHash []byte) (lightning.CommitmentType, error)
+	CancelPayment(ctx context.Context, paymentHash, destination []byte) (bool, error)
+	InsertPreimage(ctx context.Context, lspNodeID, destination, paymentHash, preimage []byte) error
+	ClaimPreimage(ctx context.Context, paymentHash, destination []byte) ([]byte, bool, error)
+	RecordHtlcFee(ctx context.Context, lspNodeID, destination, paymentHash []byte, amountIn, amountOut basetypes.MilliSatoshi) (int64, error)
+	InsertChannel(ctx context.Context, lspNodeID []byte, initialChanID, confirmedChanId uint64, channelPoint string, nodeID []byte, commitmentType lightning.CommitmentType, lastUpdate time.Time) error
+	GetFeeParamsSettings(ctx context.Context, token string) ([]*OpeningFeeParamsSetting, error)
+	RegisterNonce(ctx context.Context, destination, nonce []byte) (bool, error)
+	ForgetNonce(ctx context.Context, destination, nonce []byte) error
+	DeleteNonces(ctx context.Context, before time.Time) (int64, error)
+	SaveAddressHints(ctx context.Context, lspNodeID, destination []byte, addresses []string) error
+	GetPeerAddresses(ctx context.Context, lspNodeID, peerID []byte) ([]string, error)
+	SaveClientAgent(ctx context.Context, lspNodeID, destination []byte, agent string) error
+	GetClientAgent(ctx context.Context, lspNodeID, destination []byte) (string, error)
 }
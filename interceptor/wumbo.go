@@ -0,0 +1,73 @@
+package interceptor
+
+import (
+	"fmt"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/lightning"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// channelCapacity limits the capacity of a channel opened for a payment to
+// the configured maximum, and to the standard maximum if the client doesn't
+// support large channels. It fails if the channel would be too small for the
+// payment.
+func (i *Interceptor) channelCapacity(destination []byte, incomingAmountMsat int64, capacity int64) (int64, error) {
+	cfg := i.config.Wumbo
+	if cfg == nil {
+		return capacity, nil
+	}
+
+	max := int64(cfg.MaxChannelCapacitySat)
+	if capacity > lightning.MaxStandardChannelCapacitySat && (max == 0 || max > lightning.MaxStandardChannelCapacitySat) {
+		supported, err := i.client.SupportsLargeChannels(destination)
+		if err != nil {
+			i.logger.Printf("SupportsLargeChannels(%x) error, opening standard channel: %v", destination, err)
+		}
+		if !supported {
+			max = lightning.MaxStandardChannelCapacitySat
+		}
+	}
+
+	if max == 0 || capacity <= max {
+		return capacity, nil
+	}
+
+	if incomingAmountMsat/1000 >= max {
+		return 0, fmt.Errorf("payment of %v msat exceeds max channel capacity of %v sat", incomingAmountMsat, max)
+	}
+
+	i.logger.Printf("Limiting capacity of channel with %x from %v to %v sat", destination, capacity, max)
+	return max, nil
+}
+
+// setChannelPolicy sets the forwarding policy of the token on a channel
+// opened by lspd. Without a policy for the token, only the max htlc is set if
+// configured, along with the fees and time lock delta returned to clients in
+// ChannelInformation.
+func (i *Interceptor) setChannelPolicy(destination []byte, channelPoint *wire.OutPoint, policy *lightning.ChannelPolicy) {
+	if policy == nil {
+		if i.config.Wumbo == nil || i.config.Wumbo.MaxHtlcMsat == 0 {
+			return
+		}
+
+		policy = &lightning.ChannelPolicy{
+			BaseFeeMsat:   i.config.BaseFeeMsat,
+			FeeRate:       i.config.FeeRate,
+			TimeLockDelta: i.config.TimeLockDelta,
+			MaxHtlcMsat:   i.config.Wumbo.MaxHtlcMsat,
+		}
+	}
+
+	err := i.client.SetChannelPolicy(destination, *channelPoint, policy)
+	if err != nil {
+		i.logger.Printf("SetChannelPolicy(%x, %v) error: %v", destination, channelPoint.String(), err)
+	}
+}
+
+// exceedsMaxHtlc returns whether an htlc of a registered payment is larger
+// than the configured max htlc. The sender has to split the payment.
+func (i *Interceptor) exceedsMaxHtlc(amountMsat basetypes.MilliSatoshi) bool {
+	cfg := i.config.Wumbo
+	return cfg != nil && cfg.MaxHtlcMsat > 0 && amountMsat > cfg.MaxHtlcMsat
+}
@@ -1,3 +1,5 @@
+//go:build itest
+
 package itest
 
 import (
@@ -8,7 +10,6 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sync"
-	"syscall"
 
 	"github.com/breez/lntest"
 	"github.com/breez/lspd/config"
@@ -112,8 +113,7 @@ func (c *ClnLspNode) Start() {
 		Fn:   c.lightningNode.Stop,
 	})
 
-	cmd := exec.CommandContext(c.harness.Ctx, c.lspBase.scriptFilePath)
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd := scriptCommand(c.harness.Ctx, c.lspBase.scriptFilePath)
 	logFile, err := os.Create(c.logFilePath)
 	if err != nil {
 		lntest.PerformCleanup(cleanups)
@@ -141,7 +141,7 @@ func (c *ClnLspNode) Start() {
 				return nil
 			}
 
-			syscall.Kill(-proc.Pid, syscall.SIGINT)
+			interrupt(proc)
 
 			log.Printf("About to wait for lspd to exit")
 			status, err := proc.Wait()
@@ -1,3 +1,5 @@
+//go:build itest
+
 package itest
 
 import (
@@ -10,7 +12,6 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
-	"syscall"
 
 	"github.com/breez/lntest"
 	"github.com/breez/lspd/config"
@@ -135,8 +136,7 @@ func (c *LndLspNode) Start() {
 		}
 	}
 
-	cmd := exec.CommandContext(c.harness.Ctx, c.lspBase.scriptFilePath)
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd := scriptCommand(c.harness.Ctx, c.lspBase.scriptFilePath)
 	logFile, err := os.Create(c.logFilePath)
 	if err != nil {
 		lntest.PerformCleanup(cleanups)
@@ -164,7 +164,7 @@ func (c *LndLspNode) Start() {
 				return nil
 			}
 
-			syscall.Kill(-proc.Pid, syscall.SIGINT)
+			interrupt(proc)
 
 			log.Printf("About to wait for lspd to exit")
 			status, err := proc.Wait()
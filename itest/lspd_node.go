@@ -1,3 +1,5 @@
+//go:build itest
+
 package itest
 
 import (
@@ -121,6 +123,8 @@ func newLspd(h *lntest.TestHarness, mem *mempoolApi, name string, nodeConfig *co
 		if nodeConfig.MinConfs != nil {
 			conf.MinConfs = nodeConfig.MinConfs
 		}
+		conf.Hodl = nodeConfig.Hodl
+		conf.FailurePolicy = nodeConfig.FailurePolicy
 	}
 
 	log.Printf("%s: node config: %+v", name, conf)
@@ -1,3 +1,5 @@
+//go:build itest
+
 package itest
 
 import (
@@ -163,4 +165,17 @@ var allTestCases = []*testCase{
 		name: "testOfflineNotificationZeroConfChannel",
 		test: testOfflineNotificationZeroConfChannel,
 	},
+	{
+		name: "testOpenZeroConfMpp",
+		test: testOpenZeroConfMpp,
+	},
+	{
+		name: "testRestartRecovery",
+		test: testRestartRecovery,
+	},
+	{
+		name:          "testFailureCodes",
+		test:          testFailureCodes,
+		skipCreateLsp: true,
+	},
 }
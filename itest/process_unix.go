@@ -0,0 +1,22 @@
+//go:build itest && !windows
+
+package itest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func scriptCommand(ctx context.Context, scriptFilePath string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, scriptFilePath)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+// interrupt sends SIGINT to the process group of proc, so the script and the
+// lspd process it started both stop.
+func interrupt(proc *os.Process) error {
+	return syscall.Kill(-proc.Pid, syscall.SIGINT)
+}
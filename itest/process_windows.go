@@ -0,0 +1,25 @@
+//go:build itest && windows
+
+package itest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// scriptCommand runs the startup script with bash, e.g. from Git for Windows,
+// because windows cannot execute shell scripts directly.
+func scriptCommand(ctx context.Context, scriptFilePath string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "bash", scriptFilePath)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP,
+	}
+	return cmd
+}
+
+// interrupt kills proc. Windows has no SIGINT for other processes.
+func interrupt(proc *os.Process) error {
+	return proc.Kill()
+}
@@ -0,0 +1,353 @@
+//go:build itest
+
+package itest
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/breez/lntest"
+	lspd "github.com/breez/lspd/rpc"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var (
+	soakDuration = flag.Duration(
+		"soakduration", 0, "duration of the soak test, the soak test is skipped if not set",
+	)
+	soakSeed = flag.Int64(
+		"soakseed", 0, "seed of the randomized soak traffic, a random seed is used if not set",
+	)
+)
+
+var (
+	// Max time a payment may take before its htlcs count as stuck.
+	soakHtlcDeadline = time.Minute
+	// Interval between invariant checks.
+	soakCheckInterval = time.Minute
+	// Refill the lsp wallet after this many channel opens.
+	soakOpensPerFunding = 50
+)
+
+// TestSoak runs lspd for the configured soak duration with randomized
+// traffic and restarts, checking invariants all along the way. Run it with
+// -soakduration, for example -soakduration=4h.
+func TestSoak(t *testing.T) {
+	if *soakDuration == 0 {
+		t.Skip("soak test skipped, set -soakduration to run it")
+	}
+
+	t.Run("LND-lspd: soak", func(t *testing.T) {
+		runSoak(t, lndLspFunc, lndClientFunc)
+	})
+	t.Run("CLN-lspd: soak", func(t *testing.T) {
+		runSoak(t, clnLspFunc, clnClientFunc)
+	})
+}
+
+type soakAction func(s *soakRun)
+
+// soakRun holds the state of a soak test, along with the expectations the
+// invariants are checked against.
+type soakRun struct {
+	p            *testParams
+	alice        *lntest.ClnNode
+	aliceChannel lntest.ShortChannelID
+	rnd          *rand.Rand
+
+	opens         int
+	forwards      int
+	restarts      int
+	receivedMsat  uint64
+	openFeesMsat  uint64
+	lastFundedAt  int
+	lastCheckedAt time.Time
+}
+
+func runSoak(t *testing.T, lspFunc LspFunc, clientFunc ClientFunc) {
+	seed := *soakSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	log.Printf("soak: running for %v with seed %d", *soakDuration, seed)
+
+	deadline := time.Now().Add(*soakDuration + defaultTimeout)
+	h := lntest.NewTestHarness(t, deadline)
+	defer h.TearDown()
+
+	miner := lntest.NewMiner(h)
+	miner.Start()
+	mem := NewMempoolApi(h)
+	mem.Start()
+	lsp := lspFunc(h, miner, mem, nil)
+	lsp.Start()
+	c := clientFunc(h, miner)
+	c.Start()
+
+	s := &soakRun{
+		p: &testParams{
+			t:          t,
+			h:          h,
+			m:          miner,
+			mem:        mem,
+			c:          c,
+			lsp:        lsp,
+			lspFunc:    lspFunc,
+			clientFunc: clientFunc,
+		},
+		rnd: rand.New(rand.NewSource(seed)),
+	}
+	s.setup()
+
+	actions := []struct {
+		weight int
+		action soakAction
+	}{
+		{weight: 5, action: (*soakRun).openChannelOnReceive},
+		{weight: 10, action: (*soakRun).regularForward},
+		{weight: 1, action: (*soakRun).restartLsp},
+	}
+	totalWeight := 0
+	for _, a := range actions {
+		totalWeight += a.weight
+	}
+
+	end := time.Now().Add(*soakDuration)
+	s.lastCheckedAt = time.Now()
+	for time.Now().Before(end) {
+		n := s.rnd.Intn(totalWeight)
+		for _, a := range actions {
+			if n < a.weight {
+				a.action(s)
+				break
+			}
+			n -= a.weight
+		}
+
+		if time.Since(s.lastCheckedAt) > soakCheckInterval {
+			s.checkInvariants()
+		}
+	}
+
+	s.checkInvariants()
+	log.Printf(
+		"soak: done. opens: %d, forwards: %d, restarts: %d, received: %d msat",
+		s.opens,
+		s.forwards,
+		s.restarts,
+		s.receivedMsat,
+	)
+}
+
+func (s *soakRun) setup() {
+	s.alice = lntest.NewClnNode(s.p.h, s.p.m, "Alice")
+	s.alice.Start()
+	s.alice.Fund(20000000)
+	s.p.lsp.LightningNode().Fund(10000000)
+
+	log.Print("soak: opening channel between Alice and the lsp")
+	channel := s.alice.OpenChannel(s.p.lsp.LightningNode(), &lntest.OpenChannelOptions{
+		AmountSat: 16000000,
+	})
+	s.aliceChannel = s.alice.WaitForChannelReady(channel)
+
+	s.p.BreezClient().Node().ConnectPeer(s.p.lsp.LightningNode())
+
+	// TODO: Fix race waiting for htlc interceptor.
+	<-time.After(htlcInterceptorDelay)
+}
+
+// openChannelOnReceive pays a registered payment, which opens a new zero conf
+// channel to the client.
+func (s *soakRun) openChannelOnReceive() {
+	if s.opens-s.lastFundedAt >= soakOpensPerFunding {
+		s.p.lsp.LightningNode().Fund(10000000)
+		s.lastFundedAt = s.opens
+	}
+
+	outerAmountMsat := uint64(2100000 + s.rnd.Intn(1000)*1000)
+	innerAmountMsat := calculateInnerAmountMsat(s.p.lsp, outerAmountMsat, nil)
+	innerInvoice, outerInvoice := GenerateInvoices(s.p.BreezClient(),
+		generateInvoicesRequest{
+			innerAmountMsat: innerAmountMsat,
+			outerAmountMsat: outerAmountMsat,
+			description:     fmt.Sprintf("soak open %d", s.opens),
+			lsp:             s.p.lsp,
+		})
+	s.p.BreezClient().SetHtlcAcceptor(innerAmountMsat)
+
+	RegisterPayment(s.p.lsp, &lspd.PaymentInformation{
+		PaymentHash:        innerInvoice.paymentHash,
+		PaymentSecret:      innerInvoice.paymentSecret,
+		Destination:        s.p.BreezClient().Node().NodeId(),
+		IncomingAmountMsat: int64(outerAmountMsat),
+		OutgoingAmountMsat: int64(innerAmountMsat),
+	}, false)
+
+	route := constructRoute(
+		s.p.lsp.LightningNode(),
+		s.p.BreezClient().Node(),
+		s.aliceChannel,
+		lntest.NewShortChanIDFromString("1x0x0"),
+		outerAmountMsat,
+	)
+	s.withDeadline("open", innerInvoice.paymentHash, func() error {
+		_, err := s.alice.PayViaRoute(outerAmountMsat, outerInvoice.paymentHash, outerInvoice.paymentSecret, route)
+		return err
+	})
+	s.p.BreezClient().ResetHtlcAcceptor()
+
+	s.assertReceived(innerInvoice.paymentHash, innerAmountMsat)
+	s.opens++
+	s.openFeesMsat += outerAmountMsat - innerAmountMsat
+}
+
+// regularForward pays the client over one of the channels opened before.
+func (s *soakRun) regularForward() {
+	chans := s.p.BreezClient().Node().GetChannels()
+	if len(chans) == 0 {
+		return
+	}
+
+	ch := chans[s.rnd.Intn(len(chans))]
+	id := ch.ShortChannelID
+	if ch.RemoteAlias != nil {
+		id = *ch.RemoteAlias
+	} else if ch.LocalAlias != nil {
+		id = *ch.LocalAlias
+	}
+
+	amountMsat := uint64(10000 + s.rnd.Intn(100000)*1000)
+	inv := s.p.BreezClient().Node().CreateBolt11Invoice(&lntest.CreateInvoiceOptions{
+		AmountMsat: amountMsat,
+	})
+	bolt11 := AddHopHint(s.p.BreezClient(), inv.Bolt11, s.p.lsp, id, nil)
+	s.withDeadline("forward", inv.PaymentHash, func() error {
+		s.alice.Pay(bolt11)
+		return nil
+	})
+
+	s.assertReceived(inv.PaymentHash, amountMsat)
+	s.forwards++
+}
+
+// restartLsp restarts lspd along with its lightning node, while keeping the
+// database.
+func (s *soakRun) restartLsp() {
+	log.Printf("soak: restarting lsp")
+	err := s.p.lsp.Stop()
+	lntest.CheckError(s.p.t, err)
+	err = s.p.lsp.PostgresBackend().Start(s.p.h.Ctx)
+	lntest.CheckError(s.p.t, err)
+	s.p.lsp.Start()
+	s.p.BreezClient().Node().ConnectPeer(s.p.lsp.LightningNode())
+	s.alice.ConnectPeer(s.p.lsp.LightningNode())
+
+	// TODO: Fix race waiting for htlc interceptor.
+	<-time.After(htlcInterceptorDelay)
+	s.restarts++
+}
+
+// withDeadline fails the soak test if the payment doesn't resolve before the
+// htlc deadline.
+func (s *soakRun) withDeadline(kind string, paymentHash []byte, pay func() error) {
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		done <- pay()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			s.p.t.Fatalf("soak: %s payment %x failed after %v: %v", kind, paymentHash, time.Since(start), err)
+		}
+	case <-time.After(soakHtlcDeadline):
+		s.p.t.Fatalf("soak: INVARIANT VIOLATED: %s payment %x unresolved after %v", kind, paymentHash, soakHtlcDeadline)
+	}
+}
+
+func (s *soakRun) assertReceived(paymentHash []byte, amountMsat uint64) {
+	inv := s.p.BreezClient().Node().GetInvoice(paymentHash)
+	if inv.AmountReceivedMsat != amountMsat {
+		s.p.t.Fatalf(
+			"soak: INVARIANT VIOLATED: client received %d msat for %x, expected %d msat",
+			inv.AmountReceivedMsat,
+			paymentHash,
+			amountMsat,
+		)
+	}
+
+	s.receivedMsat += amountMsat
+}
+
+// checkInvariants fails the soak test if the state of the client or the
+// lspd database doesn't add up with the traffic sent so far.
+func (s *soakRun) checkInvariants() {
+	s.lastCheckedAt = time.Now()
+	log.Printf("soak: checking invariants after %d opens, %d forwards, %d restarts", s.opens, s.forwards, s.restarts)
+
+	// Every registered payment opens exactly one channel.
+	chans := s.p.BreezClient().Node().GetChannels()
+	if len(chans) != s.opens {
+		s.p.t.Fatalf("soak: INVARIANT VIOLATED: client has %d channels after %d opens", len(chans), s.opens)
+	}
+
+	ctx, cancel := context.WithTimeout(s.p.h.Ctx, time.Minute)
+	defer cancel()
+	pool, err := pgxpool.Connect(ctx, s.p.lsp.PostgresBackend().ConnectionString())
+	if err != nil {
+		s.p.t.Fatalf("soak: failed to connect to postgres: %v", err)
+	}
+	defer pool.Close()
+
+	var openedPayments, fundingTxs int
+	err = pool.QueryRow(ctx,
+		`SELECT COUNT(*), COUNT(DISTINCT (funding_tx_id, funding_tx_outnum))
+		 FROM payments
+		 WHERE funding_tx_id IS NOT NULL`,
+	).Scan(&openedPayments, &fundingTxs)
+	if err != nil {
+		s.p.t.Fatalf("soak: failed to query payments: %v", err)
+	}
+	if openedPayments != s.opens || fundingTxs != s.opens {
+		s.p.t.Fatalf(
+			"soak: INVARIANT VIOLATED: %d payments with %d distinct channels in the database after %d opens",
+			openedPayments,
+			fundingTxs,
+			s.opens,
+		)
+	}
+
+	// The fees deducted from the htlcs of registered payments, net of the
+	// credits for overpaid fees, add up to the promised opening fees.
+	var deductedMsat, creditMsat int64
+	err = pool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(h.amount_in_msat - h.amount_out_msat), 0)::bigint
+		 FROM htlc_fees h
+		 INNER JOIN payments p ON p.payment_hash = h.payment_hash
+		 WHERE p.incoming_amount_msat > 0`,
+	).Scan(&deductedMsat)
+	if err != nil {
+		s.p.t.Fatalf("soak: failed to query htlc fees: %v", err)
+	}
+	err = pool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(credit_msat), 0)::bigint FROM fee_credits`,
+	).Scan(&creditMsat)
+	if err != nil {
+		s.p.t.Fatalf("soak: failed to query fee credits: %v", err)
+	}
+	if uint64(deductedMsat-creditMsat) != s.openFeesMsat {
+		s.p.t.Fatalf(
+			"soak: INVARIANT VIOLATED: deducted %d msat in fees with %d msat credit, expected %d msat",
+			deductedMsat,
+			creditMsat,
+			s.openFeesMsat,
+		)
+	}
+}
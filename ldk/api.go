@@ -0,0 +1,256 @@
+package ldk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The json types of the http api of the ldk sidecar. Node ids, channel ids
+// and payment hashes are hex encoded, short channel ids are decimal strings,
+// because they don't fit the numbers of most json parsers, and funding
+// outpoints are txid:vout.
+
+type nodeInfo struct {
+	NodeID      string `json:"node_id"`
+	Alias       string `json:"alias"`
+	BlockHeight uint32 `json:"block_height"`
+}
+
+type peer struct {
+	NodeID      string `json:"node_id"`
+	Address     string `json:"address"`
+	IsConnected bool   `json:"is_connected"`
+	// Hex encoded feature bits of the init message of the peer, if known.
+	Features string `json:"features"`
+}
+
+type pendingHtlc struct {
+	PaymentHash string `json:"payment_hash"`
+	AmountMsat  uint64 `json:"amount_msat"`
+	Incoming    bool   `json:"incoming"`
+}
+
+type channel struct {
+	ChannelID            string        `json:"channel_id"`
+	CounterpartyNodeID   string        `json:"counterparty_node_id"`
+	FundingTxo           string        `json:"funding_txo"`
+	ChannelValueSats     uint64        `json:"channel_value_sats"`
+	OutboundCapacityMsat uint64        `json:"outbound_capacity_msat"`
+	ShortChannelID       uint64        `json:"short_channel_id,string,omitempty"`
+	OutboundScidAlias    uint64        `json:"outbound_scid_alias,string,omitempty"`
+	InboundScidAlias     uint64        `json:"inbound_scid_alias,string,omitempty"`
+	IsChannelReady       bool          `json:"is_channel_ready"`
+	IsUsable             bool          `json:"is_usable"`
+	PendingHtlcs         []pendingHtlc `json:"pending_htlcs"`
+}
+
+type balance struct {
+	TotalOnchainBalanceSats     uint64 `json:"total_onchain_balance_sats"`
+	SpendableOnchainBalanceSats uint64 `json:"spendable_onchain_balance_sats"`
+}
+
+type forward struct {
+	Timestamp          int64  `json:"timestamp"`
+	PrevScid           uint64 `json:"prev_scid,string"`
+	NextScid           uint64 `json:"next_scid,string"`
+	InboundAmountMsat  uint64 `json:"inbound_amount_msat"`
+	OutboundAmountMsat uint64 `json:"outbound_amount_msat"`
+}
+
+type connectRequest struct {
+	NodeID  string `json:"node_id"`
+	Address string `json:"address"`
+}
+
+type openChannelRequest struct {
+	NodeID            string   `json:"node_id"`
+	ChannelAmountSats uint64   `json:"channel_amount_sats"`
+	Announce          bool     `json:"announce"`
+	ZeroConf          bool     `json:"zero_conf"`
+	HtlcMinimumMsat   uint64   `json:"htlc_minimum_msat,omitempty"`
+	MinConfs          *uint32  `json:"min_confs,omitempty"`
+	FeeSatPerVByte    *float64 `json:"fee_sat_per_vbyte,omitempty"`
+	TargetConf        *uint32  `json:"target_conf,omitempty"`
+}
+
+type openChannelResponse struct {
+	ChannelID  string `json:"channel_id"`
+	FundingTxo string `json:"funding_txo"`
+}
+
+type closeChannelRequest struct {
+	ChannelID          string `json:"channel_id"`
+	CounterpartyNodeID string `json:"counterparty_node_id"`
+}
+
+type closeChannelResponse struct {
+	ClosingTxid string `json:"closing_txid"`
+}
+
+type channelPolicyRequest struct {
+	ChannelID                           string `json:"channel_id"`
+	CounterpartyNodeID                  string `json:"counterparty_node_id"`
+	ForwardingFeeBaseMsat               uint64 `json:"forwarding_fee_base_msat"`
+	ForwardingFeeProportionalMillionths uint64 `json:"forwarding_fee_proportional_millionths"`
+	CltvExpiryDelta                     uint32 `json:"cltv_expiry_delta"`
+}
+
+// event is a peer or channel event of the node. Type is one of
+// peer_connected, peer_disconnected, channel_pending, channel_ready and
+// channel_closed.
+type event struct {
+	Type       string `json:"type"`
+	NodeID     string `json:"node_id"`
+	Address    string `json:"address"`
+	ChannelID  string `json:"channel_id"`
+	FundingTxo string `json:"funding_txo"`
+}
+
+// interceptedHtlc is an htlc held by the node until lspd forwards or fails
+// it.
+type interceptedHtlc struct {
+	InterceptID                string `json:"intercept_id"`
+	RequestedNextHopScid       uint64 `json:"requested_next_hop_scid,string"`
+	PaymentHash                string `json:"payment_hash"`
+	InboundAmountMsat          uint64 `json:"inbound_amount_msat"`
+	ExpectedOutboundAmountMsat uint64 `json:"expected_outbound_amount_msat"`
+	IncomingCltvExpiry         uint32 `json:"incoming_cltv_expiry"`
+	OutgoingCltvExpiry         uint32 `json:"outgoing_cltv_expiry"`
+}
+
+// forwardHtlcRequest forwards an intercepted htlc. Without a funding txo it
+// is forwarded to the requested next hop with the expected amount.
+type forwardHtlcRequest struct {
+	FundingTxo string `json:"funding_txo,omitempty"`
+	NodeID     string `json:"node_id,omitempty"`
+	AmountMsat uint64 `json:"amount_msat,omitempty"`
+}
+
+type failHtlcRequest struct {
+	FailureCode uint16 `json:"failure_code"`
+}
+
+type customMessage struct {
+	NodeID string `json:"node_id"`
+	Type   uint32 `json:"type"`
+	Data   string `json:"data"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// errNotFound is returned for api calls answered with 404, like the custom
+// message endpoints of sidecars that don't support them.
+var errNotFound = errors.New("not found")
+
+// api calls the http api of the ldk sidecar.
+type api struct {
+	address string
+	apiKey  string
+	client  *http.Client
+}
+
+func newApi(address string, apiKey string) *api {
+	return &api{
+		address: strings.TrimSuffix(address, "/"),
+		apiKey:  apiKey,
+		// Calls are bounded by their context, streams stay open.
+		client: &http.Client{},
+	}
+}
+
+func (a *api) newRequest(ctx context.Context, method string, path string, body interface{}) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.address+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if a.apiKey != "" {
+		req.Header.Set("X-Api-Key", a.apiKey)
+	}
+
+	return req, nil
+}
+
+func (a *api) do(req *http.Request) (*http.Response, error) {
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, errNotFound)
+	}
+
+	var e apiError
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(b, &e) != nil || e.Error == "" {
+		e.Error = strings.TrimSpace(string(b))
+	}
+	return nil, fmt.Errorf("%s %s returned %s: %s", req.Method, req.URL.Path, resp.Status, e.Error)
+}
+
+// call sends the request body as json, and decodes the json response into
+// result, if not nil.
+func (a *api) call(ctx context.Context, method string, path string, body interface{}, result interface{}) error {
+	req, err := a.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if result == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return fmt.Errorf("%s %s: invalid response: %w", method, path, err)
+	}
+
+	return nil
+}
+
+// stream opens a stream of newline delimited json objects. The stream is
+// closed when the context is done.
+func (a *api) stream(ctx context.Context, path string) (*json.Decoder, io.Closer, error) {
+	req, err := a.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := a.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return json.NewDecoder(resp.Body), resp.Body, nil
+}
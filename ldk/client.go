@@ -0,0 +1,626 @@
+// Package ldk runs lspd on an embedded LDK node. lspd doesn't link LDK
+// itself, it drives a sidecar process running ldk-node over a small http
+// api, so small deployments don't need an external lnd or lightningd. The
+// api is documented in the README. Experimental.
+package ldk
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	defaultRpcTimeout = 10 * time.Second
+	writeTimeout      = 60 * time.Second
+)
+
+var pollingInterval = 400 * time.Millisecond
+
+type LdkClient struct {
+	conf           *config.LdkConfig
+	api            *api
+	rpcTimeout     time.Duration
+	peers          *lightning.PeerTracker
+	aliases        *lightning.ChannelAliases
+	listenerCtx    context.Context
+	listenerCancel context.CancelFunc
+	logger         *log.Logger
+}
+
+func NewLdkClient(conf *config.LdkConfig, logger *log.Logger) (*LdkClient, error) {
+	if conf.Address == "" {
+		return nil, fmt.Errorf("missing ldk sidecar address")
+	}
+
+	rpcTimeout := defaultRpcTimeout
+	if conf.RpcTimeout != "" {
+		d, err := time.ParseDuration(conf.RpcTimeout)
+		if err != nil || d <= 0 {
+			logger.Printf("WARN: invalid ldk rpcTimeout '%s', using %v: %v", conf.RpcTimeout, defaultRpcTimeout, err)
+		} else {
+			rpcTimeout = d
+		}
+	}
+
+	return &LdkClient{
+		conf:       conf,
+		api:        newApi(conf.Address, conf.ApiKey),
+		rpcTimeout: rpcTimeout,
+		logger:     logger,
+	}, nil
+}
+
+func (c *LdkClient) Backend() string {
+	return "ldk"
+}
+
+// SetPeerTracker sets the tracker that is kept up to date with the peer
+// events of the sidecar. Call it before StartListeners.
+func (c *LdkClient) SetPeerTracker(peers *lightning.PeerTracker) {
+	c.peers = peers
+}
+
+// SetChannelAliases sets the aliases of the channels of the node, which are
+// refreshed on the channel events of the sidecar. Call it before
+// StartListeners.
+func (c *LdkClient) SetChannelAliases(aliases *lightning.ChannelAliases) {
+	c.aliases = aliases
+}
+
+// read calls the api with the timeout of calls that read from the node.
+func (c *LdkClient) read(ctx context.Context, path string, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.rpcTimeout)
+	defer cancel()
+	return c.api.call(ctx, http.MethodGet, path, nil, result)
+}
+
+// write calls the api with the timeout of calls that change state.
+func (c *LdkClient) write(ctx context.Context, path string, body interface{}, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+	return c.api.call(ctx, http.MethodPost, path, body, result)
+}
+
+func (c *LdkClient) listChannels(ctx context.Context) ([]*channel, error) {
+	var channels []*channel
+	err := c.read(ctx, "/v1/channels", &channels)
+	if err != nil {
+		return nil, err
+	}
+
+	return channels, nil
+}
+
+// peerChannels returns the channels with the peer.
+func (c *LdkClient) peerChannels(ctx context.Context, peerID []byte) ([]*channel, error) {
+	channels, err := c.listChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pubkey := hex.EncodeToString(peerID)
+	var result []*channel
+	for _, ch := range channels {
+		if strings.EqualFold(ch.CounterpartyNodeID, pubkey) {
+			result = append(result, ch)
+		}
+	}
+
+	return result, nil
+}
+
+// findChannel returns the channel with the peer funded by the outpoint.
+func (c *LdkClient) findChannel(ctx context.Context, peerID []byte, channelPoint wire.OutPoint) (*channel, error) {
+	channels, err := c.peerChannels(ctx, peerID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ch := range channels {
+		if ch.FundingTxo == channelPoint.String() {
+			return ch, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no channel found")
+}
+
+func (c *LdkClient) listPeers(ctx context.Context) ([]*peer, error) {
+	var peers []*peer
+	err := c.read(ctx, "/v1/peers", &peers)
+	if err != nil {
+		return nil, err
+	}
+
+	return peers, nil
+}
+
+func (c *LdkClient) getPeer(ctx context.Context, peerID []byte) (*peer, error) {
+	peers, err := c.listPeers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pubkey := hex.EncodeToString(peerID)
+	for _, p := range peers {
+		if strings.EqualFold(p.NodeID, pubkey) {
+			return p, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// syncPeers syncs the peer tracker with the peers connected to the node.
+func (c *LdkClient) syncPeers(ctx context.Context) {
+	if c.peers == nil {
+		return
+	}
+
+	peers, err := c.listPeers(ctx)
+	if err != nil {
+		c.logger.Printf("LDK: listPeers() error: %v", err)
+		c.peers.Unsync()
+		return
+	}
+
+	connected := make(map[string]string)
+	for _, p := range peers {
+		if p.IsConnected {
+			connected[strings.ToLower(p.NodeID)] = p.Address
+		}
+	}
+
+	c.peers.Sync(connected)
+}
+
+func (c *LdkClient) GetInfo() (*lightning.GetInfoResult, error) {
+	var info nodeInfo
+	err := c.read(context.Background(), "/v1/info", &info)
+	if err != nil {
+		c.logger.Printf("LDK: GetInfo() error: %v", err)
+		return nil, err
+	}
+
+	return &lightning.GetInfoResult{
+		Alias:       info.Alias,
+		Pubkey:      info.NodeID,
+		BlockHeight: info.BlockHeight,
+	}, nil
+}
+
+func (c *LdkClient) IsConnected(destination []byte) (bool, error) {
+	if c.peers != nil {
+		if connected, ok := c.peers.IsConnected(destination); ok {
+			return connected, nil
+		}
+	}
+
+	p, err := c.getPeer(context.Background(), destination)
+	if err != nil {
+		c.logger.Printf("LDK: getPeer(%x) error: %v", destination, err)
+		return false, fmt.Errorf("LDK: getPeer(%x) error: %w", destination, err)
+	}
+
+	return p != nil && p.IsConnected, nil
+}
+
+func (c *LdkClient) ConnectPeer(ctx context.Context, peerID []byte, address string) error {
+	err := c.write(ctx, "/v1/peers/connect", &connectRequest{
+		NodeID:  hex.EncodeToString(peerID),
+		Address: address,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("LDK: connect(%x, %s) error: %w", peerID, address, err)
+	}
+
+	return nil
+}
+
+// OpenChannel opens a channel funded by the wallet of the node. The sidecar
+// returns once the funding transaction is broadcast. The sidecar opens
+// channels with the commitment type of its config, it can't be chosen per
+// channel.
+func (c *LdkClient) OpenChannel(req *lightning.OpenChannelRequest) (*wire.OutPoint, error) {
+	if req.CommitmentType != "" {
+		return nil, fmt.Errorf("LDK doesn't support choosing the commitment type of %s channels", req.CommitmentType)
+	}
+
+	var resp openChannelResponse
+	err := c.write(context.Background(), "/v1/channels/open", &openChannelRequest{
+		NodeID:            hex.EncodeToString(req.Destination),
+		ChannelAmountSats: uint64(req.CapacitySat),
+		Announce:          !req.IsPrivate,
+		ZeroConf:          req.IsZeroConf,
+		HtlcMinimumMsat:   uint64(req.MinHtlcMsat),
+		MinConfs:          req.MinConfs,
+		FeeSatPerVByte:    req.FeeSatPerVByte,
+		TargetConf:        req.TargetConf,
+	}, &resp)
+	if err != nil {
+		c.logger.Printf("LDK: openChannel(%x, %v) error: %v", req.Destination, req.CapacitySat, err)
+		return nil, err
+	}
+
+	channelPoint, err := basetypes.ParseOutPoint(resp.FundingTxo)
+	if err != nil {
+		c.logger.Printf("LDK: openChannel(%x, %v) invalid funding_txo '%s': %v", req.Destination, req.CapacitySat, resp.FundingTxo, err)
+		return nil, err
+	}
+
+	return channelPoint, nil
+}
+
+// GetChannel returns the scids of the ready channel. The initial channel id is
+// the alias LDK gave the peer to use in route hints, the confirmed channel id
+// is zero until the funding transaction confirms.
+func (c *LdkClient) GetChannel(peerID []byte, channelPoint wire.OutPoint) (*lightning.GetChannelResult, error) {
+	ch, err := c.findChannel(context.Background(), peerID, channelPoint)
+	if err != nil {
+		c.logger.Printf("LDK: GetChannel(%x, %v) error: %v", peerID, channelPoint, err)
+		return nil, err
+	}
+
+	if !ch.IsChannelReady {
+		return nil, fmt.Errorf("channel not ready")
+	}
+
+	initialChanID := ch.OutboundScidAlias
+	if initialChanID == 0 {
+		initialChanID = ch.ShortChannelID
+	}
+
+	return &lightning.GetChannelResult{
+		InitialChannelID:   basetypes.ShortChannelID(initialChanID),
+		ConfirmedChannelID: basetypes.ShortChannelID(ch.ShortChannelID),
+	}, nil
+}
+
+func (c *LdkClient) GetPeerId(scid *basetypes.ShortChannelID) ([]byte, error) {
+	channels, err := c.listChannels(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	s := uint64(*scid)
+	for _, ch := range channels {
+		if ch.ShortChannelID == s || ch.OutboundScidAlias == s || ch.InboundScidAlias == s {
+			return hex.DecodeString(ch.CounterpartyNodeID)
+		}
+	}
+
+	return nil, nil
+}
+
+func (c *LdkClient) GetNodeChannelCount(ctx context.Context, nodeID []byte) (int, error) {
+	channels, err := c.peerChannels(ctx, nodeID)
+	if err != nil {
+		c.logger.Printf("LDK: GetNodeChannelCount(%x) error: %v", nodeID, err)
+		return 0, err
+	}
+
+	return len(channels), nil
+}
+
+// ListPeerChannels returns the open and pending channels with the peer.
+func (c *LdkClient) ListPeerChannels(ctx context.Context, peerID []byte) ([]*lightning.PeerChannel, error) {
+	channels, err := c.peerChannels(ctx, peerID)
+	if err != nil {
+		c.logger.Printf("LDK: ListPeerChannels(%x) error: %v", peerID, err)
+		return nil, err
+	}
+
+	var result []*lightning.PeerChannel
+	for _, ch := range channels {
+		result = append(result, &lightning.PeerChannel{
+			ChannelPoint: ch.FundingTxo,
+			CapacitySat:  ch.ChannelValueSats,
+			Pending:      !ch.IsChannelReady,
+		})
+	}
+
+	return result, nil
+}
+
+// GetMaxLocalBalanceMsat returns the largest outbound capacity of the usable
+// channels with the peer, which bounds the size of a htlc to the peer.
+func (c *LdkClient) GetMaxLocalBalanceMsat(peerID []byte) (uint64, error) {
+	channels, err := c.peerChannels(context.Background(), peerID)
+	if err != nil {
+		c.logger.Printf("LDK: GetMaxLocalBalanceMsat(%x) error: %v", peerID, err)
+		return 0, err
+	}
+
+	var max uint64
+	for _, ch := range channels {
+		if ch.IsUsable && ch.OutboundCapacityMsat > max {
+			max = ch.OutboundCapacityMsat
+		}
+	}
+
+	return max, nil
+}
+
+// SupportsCommitmentType returns false, LDK opens channels with the
+// commitment type of its config.
+func (c *LdkClient) SupportsCommitmentType(peerID []byte, t lightning.CommitmentType) (bool, error) {
+	return false, nil
+}
+
+func (c *LdkClient) SupportsLargeChannels(peerID []byte) (bool, error) {
+	hasBit, err := c.PeerFeatures(peerID)
+	if err != nil {
+		return false, err
+	}
+
+	return lightning.HasLargeChannelsFeature(hasBit), nil
+}
+
+// PeerFeatures returns the feature bits the peer sent in its init message. If
+// the sidecar doesn't know them, no bits are set.
+func (c *LdkClient) PeerFeatures(peerID []byte) (func(bit uint32) bool, error) {
+	p, err := c.getPeer(context.Background(), peerID)
+	if err != nil {
+		c.logger.Printf("LDK: getPeer(%x) error: %v", peerID, err)
+		return nil, err
+	}
+	if p == nil || !p.IsConnected {
+		return nil, lightning.ErrPeerNotConnected
+	}
+
+	return lightning.FeatureBits(p.Features)
+}
+
+// SetChannelPolicy updates the fees and the cltv delta of the node for the
+// channel. LDK doesn't support htlc limits per channel, so those are ignored.
+func (c *LdkClient) SetChannelPolicy(peerID []byte, channelPoint wire.OutPoint, policy *lightning.ChannelPolicy) error {
+	ch, err := c.findChannel(context.Background(), peerID, channelPoint)
+	if err != nil {
+		c.logger.Printf("LDK: SetChannelPolicy(%x, %v) error: %v", peerID, channelPoint, err)
+		return err
+	}
+
+	err = c.write(context.Background(), "/v1/channels/policy", &channelPolicyRequest{
+		ChannelID:                           ch.ChannelID,
+		CounterpartyNodeID:                  ch.CounterpartyNodeID,
+		ForwardingFeeBaseMsat:               uint64(policy.BaseFeeMsat),
+		ForwardingFeeProportionalMillionths: uint64(policy.FeeRate * 1000000),
+		CltvExpiryDelta:                     policy.TimeLockDelta,
+	}, nil)
+	if err != nil {
+		c.logger.Printf("LDK: SetChannelPolicy(%s) error: %v", ch.ChannelID, err)
+		return err
+	}
+
+	return nil
+}
+
+// GetClosedChannels returns the channels of channelPoints the node doesn't
+// list anymore. LDK forgets channels once their closing transaction is
+// broadcast.
+func (c *LdkClient) GetClosedChannels(ctx context.Context, nodeID string, channelPoints map[string]uint64) (map[string]uint64, error) {
+	r := make(map[string]uint64)
+	if len(channelPoints) == 0 {
+		return r, nil
+	}
+
+	peerID, err := hex.DecodeString(nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node id '%s': %w", nodeID, err)
+	}
+
+	channels, err := c.peerChannels(ctx, peerID)
+	if err != nil {
+		c.logger.Printf("LDK: GetClosedChannels(%s) error: %v", nodeID, err)
+		return nil, err
+	}
+
+	open := make(map[string]struct{})
+	for _, ch := range channels {
+		open[ch.FundingTxo] = struct{}{}
+	}
+
+	for cp, h := range channelPoints {
+		if _, ok := open[cp]; !ok {
+			r[cp] = h
+		}
+	}
+
+	return r, nil
+}
+
+func (c *LdkClient) WaitOnline(peerID []byte, deadline time.Time) error {
+	if c.peers != nil {
+		err := c.peers.WaitOnline(peerID, deadline)
+		if err != lightning.ErrPeerTrackerNotSynced {
+			return err
+		}
+	}
+
+	for {
+		p, err := c.getPeer(context.Background(), peerID)
+		if err == nil && p != nil && p.IsConnected {
+			return nil
+		}
+
+		select {
+		case <-time.After(time.Until(deadline)):
+			return fmt.Errorf("timeout")
+		case <-time.After(pollingInterval):
+		}
+	}
+}
+
+// WaitChannelActive waits until a channel with the peer can forward htlcs.
+func (c *LdkClient) WaitChannelActive(peerID []byte, deadline time.Time) error {
+	for {
+		channels, err := c.peerChannels(context.Background(), peerID)
+		if err == nil {
+			for _, ch := range channels {
+				if ch.IsUsable {
+					return nil
+				}
+			}
+		}
+
+		select {
+		case <-time.After(time.Until(deadline)):
+			return fmt.Errorf("timeout")
+		case <-time.After(pollingInterval):
+		}
+	}
+}
+
+// GetWalletBalance returns the spendable on-chain balance as confirmed, and
+// the rest of the on-chain balance, including the anchor reserve, as
+// unconfirmed.
+func (c *LdkClient) GetWalletBalance() (*lightning.GetWalletBalanceResult, error) {
+	var b balance
+	err := c.read(context.Background(), "/v1/wallet/balance", &b)
+	if err != nil {
+		c.logger.Printf("LDK: GetWalletBalance() error: %v", err)
+		return nil, fmt.Errorf("LDK: GetWalletBalance() error: %w", err)
+	}
+
+	result := &lightning.GetWalletBalanceResult{
+		ConfirmedSat: basetypes.Satoshi(b.SpendableOnchainBalanceSats),
+	}
+	if b.TotalOnchainBalanceSats > b.SpendableOnchainBalanceSats {
+		result.UnconfirmedSat = basetypes.Satoshi(b.TotalOnchainBalanceSats - b.SpendableOnchainBalanceSats)
+	}
+
+	return result, nil
+}
+
+func (c *LdkClient) listForwards(ctx context.Context, since time.Time) ([]*forward, error) {
+	var forwards []*forward
+	err := c.read(ctx, fmt.Sprintf("/v1/forwards?since=%d", since.Unix()), &forwards)
+	if err != nil {
+		return nil, err
+	}
+
+	return forwards, nil
+}
+
+// GetChannelActivity returns the total amount of payments forwarded over the
+// channel in both directions, in millisatoshi. LDK doesn't count payments per
+// channel, so the forwards the sidecar recorded are summed.
+func (c *LdkClient) GetChannelActivity(peerID []byte, channelPoint wire.OutPoint) (uint64, error) {
+	ctx := context.Background()
+	ch, err := c.findChannel(ctx, peerID, channelPoint)
+	if err != nil {
+		c.logger.Printf("LDK: GetChannelActivity(%x, %v) error: %v", peerID, channelPoint, err)
+		return 0, err
+	}
+
+	forwards, err := c.listForwards(ctx, time.Unix(0, 0))
+	if err != nil {
+		c.logger.Printf("LDK: listForwards() error: %v", err)
+		return 0, err
+	}
+
+	isChannel := func(scid uint64) bool {
+		return scid != 0 && (scid == ch.ShortChannelID || scid == ch.OutboundScidAlias || scid == ch.InboundScidAlias)
+	}
+
+	var total uint64
+	for _, f := range forwards {
+		if isChannel(f.PrevScid) {
+			total += f.InboundAmountMsat
+		}
+		if isChannel(f.NextScid) {
+			total += f.OutboundAmountMsat
+		}
+	}
+
+	return total, nil
+}
+
+// CloseChannel cooperatively closes the channel. It returns the txid of the
+// closing transaction, if the sidecar knows it already.
+func (c *LdkClient) CloseChannel(peerID []byte, channelPoint wire.OutPoint) (string, error) {
+	ch, err := c.findChannel(context.Background(), peerID, channelPoint)
+	if err != nil {
+		c.logger.Printf("LDK: CloseChannel(%x, %v) error: %v", peerID, channelPoint, err)
+		return "", err
+	}
+
+	var resp closeChannelResponse
+	err = c.write(context.Background(), "/v1/channels/close", &closeChannelRequest{
+		ChannelID:          ch.ChannelID,
+		CounterpartyNodeID: ch.CounterpartyNodeID,
+	}, &resp)
+	if err != nil {
+		c.logger.Printf("LDK: CloseChannel(%s) error: %v", ch.ChannelID, err)
+		return "", err
+	}
+
+	return resp.ClosingTxid, nil
+}
+
+// ListForwards returns the settled forwards of the node since the given time.
+func (c *LdkClient) ListForwards(ctx context.Context, since time.Time) ([]*lightning.Forward, error) {
+	forwards, err := c.listForwards(ctx, since)
+	if err != nil {
+		c.logger.Printf("LDK: listForwards() error: %v", err)
+		return nil, fmt.Errorf("LDK: listForwards() error: %w", err)
+	}
+
+	var result []*lightning.Forward
+	for _, f := range forwards {
+		timestamp := time.Unix(f.Timestamp, 0)
+		if timestamp.Before(since) {
+			continue
+		}
+
+		result = append(result, &lightning.Forward{
+			Timestamp:     timestamp,
+			ChanIDIn:      basetypes.ShortChannelID(f.PrevScid),
+			ChanIDOut:     basetypes.ShortChannelID(f.NextScid),
+			AmountInMsat:  f.InboundAmountMsat,
+			AmountOutMsat: f.OutboundAmountMsat,
+		})
+	}
+
+	return result, nil
+}
+
+// ListPendingHtlcs returns the htlcs in flight over the channels with the
+// peer.
+func (c *LdkClient) ListPendingHtlcs(ctx context.Context, peerID []byte) ([]*lightning.PendingHtlc, error) {
+	channels, err := c.peerChannels(ctx, peerID)
+	if err != nil {
+		c.logger.Printf("LDK: ListPendingHtlcs(%x) error: %v", peerID, err)
+		return nil, fmt.Errorf("LDK: ListPendingHtlcs(%x) error: %w", peerID, err)
+	}
+
+	var result []*lightning.PendingHtlc
+	for _, ch := range channels {
+		for _, h := range ch.PendingHtlcs {
+			paymentHash, err := hex.DecodeString(h.PaymentHash)
+			if err != nil {
+				c.logger.Printf("LDK: ListPendingHtlcs(%x) invalid payment_hash %s: %v", peerID, h.PaymentHash, err)
+				continue
+			}
+
+			result = append(result, &lightning.PendingHtlc{
+				ChannelPoint: ch.FundingTxo,
+				PaymentHash:  paymentHash,
+				AmountMsat:   h.AmountMsat,
+				Incoming:     h.Incoming,
+			})
+		}
+	}
+
+	return result, nil
+}
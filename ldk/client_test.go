@@ -0,0 +1,96 @@
+package ldk
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testPeer       = "02aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	testFundingTxo = "1111111111111111111111111111111111111111111111111111111111111111:1"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *LdkClient {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := NewLdkClient(&config.LdkConfig{Address: srv.URL, ApiKey: "key"}, log.New(os.Stderr, "", 0))
+	assert.NoError(t, err)
+	return c
+}
+
+func TestGetChannel(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "invalid api key"}`))
+			return
+		}
+
+		w.Write([]byte(`[{
+			"channel_id": "00",
+			"counterparty_node_id": "` + testPeer + `",
+			"funding_txo": "` + testFundingTxo + `",
+			"channel_value_sats": 100000,
+			"outbound_capacity_msat": 5000,
+			"short_channel_id": "870000000000000000",
+			"outbound_scid_alias": "17592186044416000001",
+			"is_channel_ready": true,
+			"is_usable": true
+		}]`))
+	})
+
+	peerID, _ := hex.DecodeString(testPeer)
+	channelPoint, err := basetypes.ParseOutPoint(testFundingTxo)
+	assert.NoError(t, err)
+
+	ch, err := c.GetChannel(peerID, *channelPoint)
+	assert.NoError(t, err)
+	assert.Equal(t, basetypes.ShortChannelID(17592186044416000001), ch.InitialChannelID)
+	assert.Equal(t, basetypes.ShortChannelID(870000000000000000), ch.ConfirmedChannelID)
+
+	scid := basetypes.ShortChannelID(17592186044416000001)
+	peer, err := c.GetPeerId(&scid)
+	assert.NoError(t, err)
+	assert.Equal(t, peerID, peer)
+
+	scid = 1
+	peer, err = c.GetPeerId(&scid)
+	assert.NoError(t, err)
+	assert.Nil(t, peer)
+
+	closed, err := c.GetClosedChannels(context.Background(), testPeer, map[string]uint64{testFundingTxo: 1, "2222222222222222222222222222222222222222222222222222222222222222:0": 2})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"2222222222222222222222222222222222222222222222222222222222222222:0": 2}, closed)
+
+	c.api.apiKey = "other"
+	_, err = c.GetChannel(peerID, *channelPoint)
+	assert.ErrorContains(t, err, "invalid api key")
+}
+
+func TestListForwards(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/forwards", r.URL.Path)
+		json.NewEncoder(w).Encode([]*forward{
+			{Timestamp: 100, PrevScid: 1, NextScid: 2, InboundAmountMsat: 1010, OutboundAmountMsat: 1000},
+			{Timestamp: 200, PrevScid: 2, NextScid: 1, InboundAmountMsat: 2020, OutboundAmountMsat: 2000},
+		})
+	})
+
+	forwards, err := c.ListForwards(context.Background(), time.Unix(150, 0))
+	assert.NoError(t, err)
+	assert.Len(t, forwards, 1)
+	assert.Equal(t, basetypes.ShortChannelID(2), forwards[0].ChanIDIn)
+	assert.Equal(t, uint64(2000), forwards[0].AmountOutMsat)
+}
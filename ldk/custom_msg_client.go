@@ -0,0 +1,137 @@
+package ldk
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/breez/lspd/lightning"
+)
+
+// Number of received custom messages buffered until they are read with Recv.
+const customMsgQueueSize = 10000
+
+// CustomMsgClient receives and sends the custom messages of the node over the
+// custom message endpoints of the sidecar. ldk-node doesn't expose custom
+// messages, so these are only there if the sidecar registers its own custom
+// message handler with LDK.
+type CustomMsgClient struct {
+	client    *LdkClient
+	recvQueue chan *lightning.CustomMessage
+	ctx       context.Context
+	cancel    context.CancelFunc
+	logger    *log.Logger
+}
+
+// NewCustomMsgClient returns a client of the custom messages of the node,
+// received from the sidecar.
+func (c *LdkClient) NewCustomMsgClient() lightning.CustomMsgClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CustomMsgClient{
+		client:    c,
+		recvQueue: make(chan *lightning.CustomMessage, customMsgQueueSize),
+		ctx:       ctx,
+		cancel:    cancel,
+		logger:    c.logger,
+	}
+}
+
+// Start pushes the custom messages of the sidecar to the receive queue until
+// the client is stopped, resubscribing when the stream breaks.
+func (c *CustomMsgClient) Start() error {
+	ctx := c.ctx
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		messages, body, err := c.client.api.stream(ctx, "/v1/custommsgs")
+		if errors.Is(err, errNotFound) {
+			c.logger.Printf("WARN: the ldk sidecar doesn't forward custom messages, LSPS0 is unavailable.")
+			return nil
+		}
+		if err != nil {
+			c.logger.Printf("LDK: custom message stream error: %v", err)
+			<-time.After(time.Second)
+			continue
+		}
+
+		for {
+			var msg customMessage
+			err := messages.Decode(&msg)
+			if err != nil {
+				if ctx.Err() == nil {
+					c.logger.Printf("unexpected error in custom message stream: %v", err)
+				}
+				break
+			}
+
+			m, err := parseCustomMessage(&msg)
+			if err != nil {
+				c.logger.Printf("Invalid custom message from %s: %v", msg.NodeID, err)
+				continue
+			}
+
+			select {
+			case c.recvQueue <- m:
+			default:
+				c.logger.Printf("Custom message queue is full, dropping message of type %d from %s", m.Type, msg.NodeID)
+			}
+		}
+
+		body.Close()
+		<-time.After(time.Second)
+	}
+}
+
+func parseCustomMessage(msg *customMessage) (*lightning.CustomMessage, error) {
+	peerID, err := hex.DecodeString(msg.NodeID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node id: %w", err)
+	}
+
+	data, err := hex.DecodeString(msg.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
+	}
+
+	return &lightning.CustomMessage{
+		PeerID: peerID,
+		Type:   msg.Type,
+		Data:   data,
+	}, nil
+}
+
+func (c *CustomMsgClient) Recv() (*lightning.CustomMessage, error) {
+	select {
+	case msg := <-c.recvQueue:
+		return msg, nil
+	case <-c.ctx.Done():
+		return nil, lightning.ErrCustomMsgClientStopped
+	}
+}
+
+func (c *CustomMsgClient) Send(msg *lightning.CustomMessage) error {
+	if msg.Type > 0xffff {
+		return fmt.Errorf("invalid custom message type %d", msg.Type)
+	}
+
+	err := c.client.write(c.ctx, "/v1/custommsgs", &customMessage{
+		NodeID: hex.EncodeToString(msg.PeerID),
+		Type:   msg.Type,
+		Data:   hex.EncodeToString(msg.Data),
+	}, nil)
+	if err != nil {
+		c.logger.Printf("LDK: send custom message(%x, %d) error: %v", msg.PeerID, msg.Type, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *CustomMsgClient) Stop() {
+	c.cancel()
+}
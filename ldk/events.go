@@ -0,0 +1,74 @@
+package ldk
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+)
+
+// StartListeners follows the peer and channel events of the sidecar.
+func (c *LdkClient) StartListeners() {
+	c.listenerCtx, c.listenerCancel = context.WithCancel(context.Background())
+	go c.listenEvents()
+}
+
+// listenEvents keeps the peer tracker up to date with the connect and
+// disconnect events of the sidecar, and refreshes the channel aliases on
+// channel events.
+func (c *LdkClient) listenEvents() {
+	ctx := c.listenerCtx
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		events, body, err := c.api.stream(ctx, "/v1/events")
+		if err != nil {
+			c.logger.Printf("LDK: events stream error: %v", err)
+			<-time.After(time.Second)
+			continue
+		}
+
+		// Events before the subscription were missed, so sync the connected
+		// peers now.
+		c.syncPeers(ctx)
+		for {
+			var e event
+			err := events.Decode(&e)
+			if err != nil {
+				if ctx.Err() == nil {
+					c.logger.Printf("unexpected error in listenEvents: %v", err)
+				}
+				break
+			}
+
+			c.handleEvent(ctx, &e)
+		}
+
+		body.Close()
+		if c.peers != nil {
+			c.peers.Unsync()
+		}
+		<-time.After(time.Second)
+	}
+}
+
+func (c *LdkClient) handleEvent(ctx context.Context, e *event) {
+	switch e.Type {
+	case "peer_connected", "peer_disconnected":
+		if c.peers != nil {
+			c.peers.SetConnected(e.NodeID, e.Type == "peer_connected", e.Address)
+		}
+	case "channel_pending", "channel_ready", "channel_closed":
+		if c.aliases == nil {
+			return
+		}
+
+		peerID, err := hex.DecodeString(e.NodeID)
+		if err != nil {
+			c.logger.Printf("Invalid node id '%s' in channel event: %v", e.NodeID, err)
+			return
+		}
+		go c.aliases.Refresh(ctx, peerID)
+	}
+}
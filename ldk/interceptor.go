@@ -0,0 +1,209 @@
+package ldk
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/streamwatch"
+)
+
+// LdkHtlcInterceptor passes the htlcs LDK intercepted to the interceptor.
+//
+// LDK forwards an intercepted htlc with the original onion, it can't replace
+// the payload of the next hop. When lspd deducts an opening fee, the client
+// receives less than the onion says, so clients have to accept underpaying
+// htlcs for the payments lspd opens channels for.
+type LdkHtlcInterceptor struct {
+	interceptor   *interceptor.Interceptor
+	config        *config.NodeConfig
+	client        *LdkClient
+	stopRequested bool
+	initWg        sync.WaitGroup
+	doneWg        sync.WaitGroup
+	connected     atomic.Bool
+	watchdog      *streamwatch.Watchdog
+	ctx           context.Context
+	cancel        context.CancelFunc
+	logger        *log.Logger
+}
+
+func NewLdkHtlcInterceptor(
+	conf *config.NodeConfig,
+	client *LdkClient,
+	interceptor *interceptor.Interceptor,
+	logger *log.Logger,
+) (*LdkHtlcInterceptor, error) {
+	i := &LdkHtlcInterceptor{
+		config:      conf,
+		client:      client,
+		interceptor: interceptor,
+		logger:      logger,
+	}
+	i.watchdog = streamwatch.NewWatchdog(conf, "htlc", i.probe, logger)
+
+	i.initWg.Add(1)
+
+	return i, nil
+}
+
+func (i *LdkHtlcInterceptor) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	i.ctx = ctx
+	i.cancel = cancel
+	i.stopRequested = false
+
+	return i.intercept()
+}
+
+func (i *LdkHtlcInterceptor) Stop() error {
+	// Setting stopRequested to true will make the interceptor stop receiving.
+	i.stopRequested = true
+
+	// Wait until all already received htlcs are handled, responses sent back.
+	i.doneWg.Wait()
+
+	// Close the htlc stream.
+	i.cancel()
+	return nil
+}
+
+func (i *LdkHtlcInterceptor) WaitStarted() {
+	i.initWg.Wait()
+}
+
+func (i *LdkHtlcInterceptor) Alive() bool {
+	return i.connected.Load()
+}
+
+// probe checks the sidecar still answers.
+func (i *LdkHtlcInterceptor) probe(ctx context.Context) error {
+	return i.client.api.call(ctx, http.MethodGet, "/v1/info", nil, &nodeInfo{})
+}
+
+func (i *LdkHtlcInterceptor) intercept() error {
+	inited := false
+	defer func() {
+		i.connected.Store(false)
+		if !inited {
+			i.initWg.Done()
+		}
+		i.logger.Printf("LDK intercept(): stopping. Waiting for in-progress interceptions to complete.")
+		i.doneWg.Wait()
+	}()
+
+	for {
+		if i.ctx.Err() != nil {
+			return i.ctx.Err()
+		}
+
+		i.logger.Printf("Connecting LDK HTLC interceptor.")
+		streamCtx, cancelStream := i.watchdog.Watch(i.ctx)
+		htlcs, body, err := i.client.api.stream(streamCtx, "/v1/htlcs/intercepted")
+		if err != nil {
+			cancelStream()
+			i.logger.Printf("LDK: htlc stream error: %v", err)
+			<-time.After(time.Second)
+			continue
+		}
+
+		i.connected.Store(true)
+		if !inited {
+			inited = true
+			i.initWg.Done()
+		}
+
+		for {
+			// Stop receiving if stop if requested. The defer func on top of
+			// this function will assure all htlcs that are currently being
+			// processed will complete.
+			if i.stopRequested {
+				body.Close()
+				cancelStream()
+				return nil
+			}
+
+			var htlc interceptedHtlc
+			err := htlcs.Decode(&htlc)
+			if err != nil {
+				if i.ctx.Err() == nil {
+					i.logger.Printf("unexpected error in htlc stream: %v", err)
+				}
+				break
+			}
+
+			i.watchdog.Touch()
+			i.doneWg.Add(1)
+			streamwatch.Go(i.config.Label(), "htlc_handler", func() {
+				i.resolve(&htlc)
+				i.doneWg.Done()
+			})
+		}
+
+		body.Close()
+		cancelStream()
+		i.connected.Store(false)
+		<-time.After(time.Second)
+	}
+}
+
+func (i *LdkHtlcInterceptor) resolve(htlc *interceptedHtlc) {
+	paymentHash, err := hex.DecodeString(htlc.PaymentHash)
+	if err != nil {
+		i.logger.Printf("LDK: invalid payment hash '%s' of intercepted htlc %s", htlc.PaymentHash, htlc.InterceptID)
+		i.fail(htlc, interceptor.FAILURE_TEMPORARY_CHANNEL_FAILURE)
+		return
+	}
+
+	scid := basetypes.ShortChannelID(htlc.RequestedNextHopScid)
+	interceptResult := i.interceptor.Intercept(&scid, paymentHash, htlc.ExpectedOutboundAmountMsat, htlc.OutgoingCltvExpiry, htlc.IncomingCltvExpiry)
+	switch interceptResult.Action {
+	case interceptor.INTERCEPT_RESUME_WITH_ONION, interceptor.INTERCEPT_RESUME_ON_CHANNEL:
+		i.forwardOnChannel(htlc, interceptResult)
+	case interceptor.INTERCEPT_FAIL_HTLC_WITH_CODE:
+		i.fail(htlc, interceptResult.FailureCode)
+	case interceptor.INTERCEPT_RESUME:
+		fallthrough
+	default:
+		i.forward(htlc, &forwardHtlcRequest{})
+	}
+}
+
+// forwardOnChannel forwards the htlc over the channel in the result, with the
+// amount of the result. Without a channel, the htlc is forwarded to the
+// requested next hop.
+func (i *LdkHtlcInterceptor) forwardOnChannel(htlc *interceptedHtlc, interceptResult interceptor.InterceptResult) {
+	req := &forwardHtlcRequest{}
+	if interceptResult.ChannelPoint != nil {
+		req.FundingTxo = interceptResult.ChannelPoint.String()
+		req.NodeID = hex.EncodeToString(interceptResult.Destination)
+		req.AmountMsat = uint64(interceptResult.AmountMsat)
+	}
+
+	i.forward(htlc, req)
+}
+
+func (i *LdkHtlcInterceptor) forward(htlc *interceptedHtlc, req *forwardHtlcRequest) {
+	path := fmt.Sprintf("/v1/htlcs/%s/forward", url.PathEscape(htlc.InterceptID))
+	err := i.client.write(i.ctx, path, req, nil)
+	if err != nil {
+		i.logger.Printf("LDK: forward htlc %s error: %v", htlc.InterceptID, err)
+	}
+}
+
+func (i *LdkHtlcInterceptor) fail(htlc *interceptedHtlc, code interceptor.InterceptFailureCode) {
+	path := fmt.Sprintf("/v1/htlcs/%s/fail", url.PathEscape(htlc.InterceptID))
+	err := i.client.write(i.ctx, path, &failHtlcRequest{FailureCode: uint16(code)}, nil)
+	if err != nil {
+		i.logger.Printf("LDK: fail htlc %s error: %v", htlc.InterceptID, err)
+	}
+}
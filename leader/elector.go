@@ -0,0 +1,151 @@
+// Package leader elects one lspd replica per node to run the htlc interceptor
+// and the background workers of the node, when several replicas share a
+// database. The others only serve the api. Leadership is a lease in the
+// database that the leader renews. If the leader dies, its lease expires and
+// another replica takes over.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Store keeps the leases. Expiry is measured by the clock of the store, so
+// the clocks of the replicas don't have to agree.
+type Store interface {
+	// Acquire takes the lease for the holder until ttl from now, if the
+	// lease is free, expired or held by the holder already. Returns whether
+	// the holder holds the lease.
+	Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error)
+
+	// Release gives up the lease, if the holder holds it.
+	Release(ctx context.Context, name string, holder string) error
+}
+
+// Elector campaigns for the lease of one node.
+type Elector struct {
+	store  Store
+	name   string
+	holder string
+	ttl    time.Duration
+	logger *log.Logger
+
+	elected     chan struct{}
+	electedOnce sync.Once
+
+	mtx     sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	leading bool
+}
+
+func NewElector(store Store, name string, holder string, ttl time.Duration, logger *log.Logger) *Elector {
+	return &Elector{
+		store:   store,
+		name:    name,
+		holder:  holder,
+		ttl:     ttl,
+		logger:  logger,
+		elected: make(chan struct{}),
+	}
+}
+
+// Elected is closed once this replica is elected.
+func (e *Elector) Elected() <-chan struct{} {
+	return e.elected
+}
+
+// Leading returns whether this replica currently holds the lease.
+func (e *Elector) Leading() bool {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return e.leading
+}
+
+// Start campaigns for the lease until Stop is called, and renews it once it
+// is held. A replica that lost the lease may still have htlcs in flight on
+// the node, so it doesn't campaign again. Start returns an error instead, and
+// lspd stops.
+func (e *Elector) Start() error {
+	e.mtx.Lock()
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	ctx := e.ctx
+	e.mtx.Unlock()
+
+	e.logger.Printf("leader: campaigning for %s as %s", e.name, e.holder)
+	interval := e.ttl / 3
+	var validUntil time.Time
+	for {
+		// The lease is taken by the store after this moment, so it is valid
+		// locally until at most ttl after it.
+		attempt := time.Now()
+		ok, err := e.store.Acquire(ctx, e.name, e.holder, e.ttl)
+		if ctx.Err() != nil {
+			return e.release()
+		}
+
+		leading := e.Leading()
+		switch {
+		case err != nil:
+			e.logger.Printf("leader: failed to renew the lease of %s: %v", e.name, err)
+		case ok && !leading:
+			e.logger.Printf("leader: elected for %s", e.name)
+			e.setLeading(true)
+			e.electedOnce.Do(func() { close(e.elected) })
+			validUntil = attempt.Add(e.ttl)
+		case ok:
+			validUntil = attempt.Add(e.ttl)
+		case leading:
+			e.setLeading(false)
+			return fmt.Errorf("lost the lease of %s to another replica", e.name)
+		}
+
+		// Another replica may take over once the lease expires, so the lease
+		// is given up before that.
+		if leading && err != nil && time.Now().Add(interval).After(validUntil) {
+			e.setLeading(false)
+			return fmt.Errorf("lease of %s expired: %w", e.name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return e.release()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (e *Elector) Stop() {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	if e.cancel != nil {
+		e.cancel()
+	}
+}
+
+// release gives up the lease on shutdown, so another replica doesn't have
+// to wait for it to expire.
+func (e *Elector) release() error {
+	if !e.Leading() {
+		return nil
+	}
+
+	e.setLeading(false)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := e.store.Release(ctx, e.name, e.holder)
+	if err != nil {
+		e.logger.Printf("leader: failed to release the lease of %s: %v", e.name, err)
+	}
+
+	return nil
+}
+
+func (e *Elector) setLeading(leading bool) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.leading = leading
+}
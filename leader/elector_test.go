@@ -0,0 +1,105 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type lease struct {
+	holder  string
+	expires time.Time
+}
+
+type mockStore struct {
+	mtx    sync.Mutex
+	leases map[string]lease
+	down   bool
+}
+
+func (s *mockStore) Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.down {
+		return false, errors.New("database down")
+	}
+
+	l, ok := s.leases[name]
+	if ok && l.holder != holder && l.expires.After(time.Now()) {
+		return false, nil
+	}
+
+	s.leases[name] = lease{holder: holder, expires: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (s *mockStore) Release(ctx context.Context, name string, holder string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.leases[name].holder == holder {
+		delete(s.leases, name)
+	}
+	return nil
+}
+
+func (s *mockStore) setDown(down bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.down = down
+}
+
+func waitElected(t *testing.T, e *Elector) {
+	select {
+	case <-e.Elected():
+	case <-time.After(time.Second):
+		t.Fatalf("%s was not elected", e.holder)
+	}
+}
+
+func TestElectorFailover(t *testing.T) {
+	store := &mockStore{leases: make(map[string]lease)}
+	logger := log.New(io.Discard, "", 0)
+	a := NewElector(store, "node", "a", 60*time.Millisecond, logger)
+	b := NewElector(store, "node", "b", 60*time.Millisecond, logger)
+
+	aDone := make(chan error, 1)
+	go func() { aDone <- a.Start() }()
+	waitElected(t, a)
+	go b.Start()
+	defer b.Stop()
+
+	// The follower waits as long as the leader renews the lease.
+	time.Sleep(150 * time.Millisecond)
+	assert.True(t, a.Leading())
+	assert.False(t, b.Leading())
+
+	// The leader releases the lease when it stops.
+	a.Stop()
+	assert.NoError(t, <-aDone)
+	waitElected(t, b)
+	assert.True(t, b.Leading())
+}
+
+func TestElectorLeaseExpired(t *testing.T) {
+	store := &mockStore{leases: make(map[string]lease)}
+	e := NewElector(store, "node", "a", 60*time.Millisecond, log.New(io.Discard, "", 0))
+	done := make(chan error, 1)
+	go func() { done <- e.Start() }()
+	waitElected(t, e)
+
+	// Without renewals the lease may have been taken by another replica.
+	store.setDown(true)
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+		assert.False(t, e.Leading())
+	case <-time.After(time.Second):
+		t.Fatal("leader kept leading without renewing the lease")
+	}
+}
@@ -0,0 +1,194 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/events"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultConfirmationInterval = time.Minute
+
+	// Funding transactions that didn't confirm in this time are most likely
+	// evicted from the mempool, and are no longer watched.
+	maxConfirmationWait = 14 * 24 * time.Hour
+)
+
+var unconfirmedChannels = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "lspd_unconfirmed_channels",
+	Help: "Channels opened by lspd whose funding is not confirmed yet.",
+}, []string{"node"})
+
+type ConfirmationStore interface {
+	ListChannels(ctx context.Context, lspNodeID []byte, includeClosed bool) ([]*Channel, error)
+	// SetConfirmed records the confirmed scid of the channel. Returns false if
+	// the channel was recorded as confirmed already.
+	SetConfirmed(ctx context.Context, channelPoint string, confirmedChanID basetypes.ShortChannelID, confirmedAt time.Time) (bool, error)
+}
+
+// ConfirmationListener is told when the funding of a channel opened by lspd
+// confirmed.
+type ConfirmationListener interface {
+	ChannelConfirmed(ctx context.Context, peerID []byte, channelPoint string)
+}
+
+// ConfirmationWatcher watches the funding of the channels opened by lspd
+// until it confirms. Confirmed channels are recorded with their confirmed
+// scid, the listeners are told so the channel is no longer treated as
+// zero-conf, and the confirmation is published on the event bus.
+type ConfirmationWatcher struct {
+	client    lightning.Client
+	store     ConfirmationStore
+	bus       *events.Bus
+	listeners []ConfirmationListener
+	node      *config.NodeConfig
+	logger    *log.Logger
+	lspNodeID []byte
+	interval  time.Duration
+	ctx       context.Context
+	cancel    context.CancelFunc
+	mtx       sync.Mutex
+}
+
+func NewConfirmationWatcher(
+	client lightning.Client,
+	store ConfirmationStore,
+	bus *events.Bus,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) *ConfirmationWatcher {
+	lspNodeID, _ := hex.DecodeString(node.NodePubkey)
+	w := &ConfirmationWatcher{
+		client:    client,
+		store:     store,
+		bus:       bus,
+		node:      node,
+		logger:    logger,
+		lspNodeID: lspNodeID,
+		interval:  defaultConfirmationInterval,
+	}
+
+	if node.FundingConfirmation != nil {
+		w.interval = parseDuration(node.FundingConfirmation.Interval, defaultConfirmationInterval, logger)
+	}
+
+	return w
+}
+
+// AddListener adds a listener that is told about confirmed channels. Not
+// safe to call after Start.
+func (w *ConfirmationWatcher) AddListener(l ConfirmationListener) {
+	w.listeners = append(w.listeners, l)
+}
+
+// Start checks the unconfirmed channels periodically. Returns immediately if
+// funding confirmations are not watched for the node.
+func (w *ConfirmationWatcher) Start() error {
+	if w.node.FundingConfirmation == nil {
+		return nil
+	}
+
+	w.mtx.Lock()
+	w.ctx, w.cancel = context.WithCancel(tenant.NewContext(context.Background(), w.node.Tenant))
+	ctx := w.ctx
+	w.mtx.Unlock()
+
+	w.logger.Printf("funding confirmation: watching unconfirmed channels every %v", w.interval)
+	for {
+		w.check(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.interval):
+		}
+	}
+}
+
+func (w *ConfirmationWatcher) Stop() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.cancel != nil {
+		w.cancel()
+	}
+}
+
+func (w *ConfirmationWatcher) check(ctx context.Context) {
+	channels, err := w.store.ListChannels(ctx, w.lspNodeID, false)
+	if err != nil {
+		w.logger.Printf("funding confirmation: ListChannels() error: %v", err)
+		return
+	}
+
+	now := time.Now()
+	unconfirmed := 0
+	for _, c := range channels {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if c.ConfirmedAt != nil || c.OpenedAt == nil || now.Sub(*c.OpenedAt) > maxConfirmationWait {
+			continue
+		}
+
+		if !w.checkChannel(ctx, c, now) {
+			unconfirmed++
+		}
+	}
+
+	unconfirmedChannels.WithLabelValues(w.node.Label()).Set(float64(unconfirmed))
+}
+
+// checkChannel returns whether the funding of the channel confirmed.
+func (w *ConfirmationWatcher) checkChannel(ctx context.Context, c *Channel, now time.Time) bool {
+	peerID, channelPoint, err := parseChannel(c)
+	if err != nil {
+		w.logger.Printf("funding confirmation: %v", err)
+		return false
+	}
+
+	ch, err := w.client.GetChannel(peerID, *channelPoint)
+	if err != nil {
+		w.logger.Printf("funding confirmation: GetChannel(%s, %s) error: %v", c.PeerID, c.ChannelPoint, err)
+		return false
+	}
+
+	if ch.ConfirmedChannelID == 0 {
+		return false
+	}
+
+	updated, err := w.store.SetConfirmed(ctx, c.ChannelPoint, ch.ConfirmedChannelID, now)
+	if err != nil {
+		w.logger.Printf("funding confirmation: %v", err)
+		return false
+	}
+
+	if !updated {
+		return true
+	}
+
+	w.logger.Printf("funding confirmation: channel %s with %s confirmed as %s", c.ChannelPoint, c.PeerID, ch.ConfirmedChannelID.ToString())
+	for _, l := range w.listeners {
+		l.ChannelConfirmed(ctx, peerID, c.ChannelPoint)
+	}
+
+	w.bus.Publish(&events.Event{
+		Kind:           events.FundingConfirmed,
+		Node:           w.node,
+		Time:           now,
+		PeerID:         peerID,
+		ChannelPoint:   c.ChannelPoint,
+		ShortChannelID: ch.ConfirmedChannelID.ToString(),
+	})
+
+	return true
+}
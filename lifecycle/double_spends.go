@@ -0,0 +1,321 @@
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/chain"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/events"
+	"github.com/breez/lspd/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultDoubleSpendInterval = 30 * time.Second
+	doubleSpendWebhookTimeout  = 10 * time.Second
+)
+
+var fundingDoubleSpends = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_funding_double_spends_total",
+	Help: "Unconfirmed funding transactions of channels opened by lspd that were double spent.",
+}, []string{"node"})
+
+// SpendLookup looks up transactions in the mempool and the chain.
+type SpendLookup interface {
+	// TransactionInputs returns the outpoints spent by the transaction, as
+	// txid:vout, and whether it confirmed. Returns
+	// chain.ErrTransactionNotFound if the transaction is neither in the
+	// mempool nor in the chain.
+	TransactionInputs(ctx context.Context, txid string) ([]string, bool, error)
+	// OutpointSpender returns the txid of the transaction in the mempool or
+	// in the chain that spends the outpoint, or an empty string if it is
+	// unspent.
+	OutpointSpender(ctx context.Context, outpoint string) (string, error)
+}
+
+type DoubleSpendStore interface {
+	ListChannels(ctx context.Context, lspNodeID []byte, includeClosed bool) ([]*Channel, error)
+	// SetDoubleSpent records the transaction that double spent the funding
+	// of the channel. Returns false if the channel was recorded as double
+	// spent already.
+	SetDoubleSpent(ctx context.Context, channelPoint string, spendingTxid string, doubleSpentAt time.Time) (bool, error)
+}
+
+// DoubleSpendAlert is posted to the webhook of the operator when the funding
+// of a channel was double spent.
+type DoubleSpendAlert struct {
+	Node         string `json:"node"`
+	PeerID       string `json:"peer_id"`
+	ChannelPoint string `json:"channel_point"`
+	SpendingTxid string `json:"spending_txid"`
+	DetectedAt   string `json:"detected_at"`
+}
+
+// DoubleSpendMonitor watches the mempool and the chain for transactions
+// double spending the inputs of the unconfirmed funding transactions of the
+// channels opened by lspd. Zero-conf channels are used before their funding
+// confirms, so a double spent funding transaction means the payments
+// forwarded over the channel are lost. Double spends are recorded, counted,
+// published on the event bus and posted to the webhook of the operator, and
+// htlcs over the channel are failed from then on.
+type DoubleSpendMonitor struct {
+	lookup     SpendLookup
+	store      DoubleSpendStore
+	bus        *events.Bus
+	node       *config.NodeConfig
+	logger     *log.Logger
+	lspNodeID  []byte
+	interval   time.Duration
+	webhookURL string
+	httpClient *http.Client
+
+	// Inputs of the unconfirmed funding transactions by txid, so their
+	// double spends are found after they were evicted from the mempool.
+	inputs map[string][]string
+	// Funding transactions seen confirmed, which are no longer watched.
+	confirmed map[string]struct{}
+
+	// Channel points and scids of the channels whose funding was double
+	// spent.
+	doubleSpent map[string]struct{}
+	spentMtx    sync.RWMutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	mtx    sync.Mutex
+}
+
+func NewDoubleSpendMonitor(
+	lookup SpendLookup,
+	store DoubleSpendStore,
+	bus *events.Bus,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) *DoubleSpendMonitor {
+	lspNodeID, _ := hex.DecodeString(node.NodePubkey)
+	cfg := node.DoubleSpendMonitor
+	return &DoubleSpendMonitor{
+		lookup:      lookup,
+		store:       store,
+		bus:         bus,
+		node:        node,
+		logger:      logger,
+		lspNodeID:   lspNodeID,
+		interval:    parseDuration(cfg.Interval, defaultDoubleSpendInterval, logger),
+		webhookURL:  cfg.WebhookURL,
+		httpClient:  &http.Client{Timeout: doubleSpendWebhookTimeout},
+		inputs:      make(map[string][]string),
+		confirmed:   make(map[string]struct{}),
+		doubleSpent: make(map[string]struct{}),
+	}
+}
+
+func (m *DoubleSpendMonitor) Start() error {
+	m.mtx.Lock()
+	m.ctx, m.cancel = context.WithCancel(tenant.NewContext(context.Background(), m.node.Tenant))
+	ctx := m.ctx
+	m.mtx.Unlock()
+
+	m.logger.Printf("double spend: watching the funding of unconfirmed channels every %v", m.interval)
+	for {
+		m.check(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(m.interval):
+		}
+	}
+}
+
+func (m *DoubleSpendMonitor) Stop() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// IsDoubleSpent returns whether the funding of the channel, given by its
+// channel point or one of its scids, was double spent.
+func (m *DoubleSpendMonitor) IsDoubleSpent(channel string) bool {
+	m.spentMtx.RLock()
+	defer m.spentMtx.RUnlock()
+	_, ok := m.doubleSpent[channel]
+	return ok
+}
+
+func (m *DoubleSpendMonitor) block(c *Channel) {
+	m.spentMtx.Lock()
+	defer m.spentMtx.Unlock()
+	for _, k := range []string{c.ChannelPoint, c.InitialChanID, c.ConfirmedChanID} {
+		if k != "" {
+			m.doubleSpent[k] = struct{}{}
+		}
+	}
+}
+
+func (m *DoubleSpendMonitor) check(ctx context.Context, now time.Time) {
+	channels, err := m.store.ListChannels(ctx, m.lspNodeID, false)
+	if err != nil {
+		m.logger.Printf("double spend: ListChannels() error: %v", err)
+		return
+	}
+
+	watched := make(map[string]struct{})
+	for _, c := range channels {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Channels recorded as double spent before a restart stay blocked.
+		if c.DoubleSpentBy != "" {
+			m.block(c)
+			continue
+		}
+
+		if c.ConfirmedAt != nil || c.OpenedAt == nil || now.Sub(*c.OpenedAt) > maxConfirmationWait {
+			continue
+		}
+
+		peerID, channelPoint, err := parseChannel(c)
+		if err != nil {
+			m.logger.Printf("double spend: %v", err)
+			continue
+		}
+
+		txid := channelPoint.Hash.String()
+		watched[txid] = struct{}{}
+		if _, ok := m.confirmed[txid]; ok {
+			continue
+		}
+
+		m.checkChannel(ctx, c, peerID, txid, now)
+	}
+
+	for txid := range m.inputs {
+		if _, ok := watched[txid]; !ok {
+			delete(m.inputs, txid)
+		}
+	}
+	for txid := range m.confirmed {
+		if _, ok := watched[txid]; !ok {
+			delete(m.confirmed, txid)
+		}
+	}
+}
+
+// checkChannel checks whether an input of the funding transaction of the
+// channel is spent by another transaction.
+func (m *DoubleSpendMonitor) checkChannel(ctx context.Context, c *Channel, peerID []byte, txid string, now time.Time) {
+	inputs, confirmed, err := m.lookup.TransactionInputs(ctx, txid)
+	switch {
+	case errors.Is(err, chain.ErrTransactionNotFound):
+		inputs = m.inputs[txid]
+		if inputs == nil {
+			m.logger.Printf("double spend: funding transaction of channel %s not found, can't check it for double spends", c.ChannelPoint)
+			return
+		}
+	case err != nil:
+		m.logger.Printf("double spend: TransactionInputs(%s) error: %v", txid, err)
+		return
+	case confirmed:
+		m.confirmed[txid] = struct{}{}
+		delete(m.inputs, txid)
+		return
+	default:
+		m.inputs[txid] = inputs
+	}
+
+	for _, in := range inputs {
+		spender, err := m.lookup.OutpointSpender(ctx, in)
+		if err != nil {
+			m.logger.Printf("double spend: OutpointSpender(%s) error: %v", in, err)
+			continue
+		}
+
+		if spender != "" && spender != txid {
+			m.doubleSpend(ctx, c, peerID, spender, now)
+			return
+		}
+	}
+}
+
+func (m *DoubleSpendMonitor) doubleSpend(ctx context.Context, c *Channel, peerID []byte, spendingTxid string, now time.Time) {
+	// Htlcs over the channel are failed right away, even if recording the
+	// double spend fails.
+	m.block(c)
+	updated, err := m.store.SetDoubleSpent(ctx, c.ChannelPoint, spendingTxid, now)
+	if err != nil {
+		m.logger.Printf("double spend: %v", err)
+	} else if !updated {
+		return
+	}
+
+	fundingDoubleSpends.WithLabelValues(m.node.Label()).Inc()
+	m.logger.Printf(
+		"WARN: double spend: funding of channel %s with %s was double spent by transaction %s. Failing htlcs over the channel.",
+		c.ChannelPoint,
+		c.PeerID,
+		spendingTxid,
+	)
+	m.bus.Publish(&events.Event{
+		Kind:           events.FundingDoubleSpent,
+		Node:           m.node,
+		Time:           now,
+		PeerID:         peerID,
+		ChannelPoint:   c.ChannelPoint,
+		ShortChannelID: c.InitialChanID,
+		SpendingTxID:   spendingTxid,
+	})
+
+	if m.webhookURL == "" {
+		return
+	}
+
+	err = m.alert(ctx, &DoubleSpendAlert{
+		Node:         m.node.Label(),
+		PeerID:       c.PeerID,
+		ChannelPoint: c.ChannelPoint,
+		SpendingTxid: spendingTxid,
+		DetectedAt:   now.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		m.logger.Printf("double spend: failed to post the double spend of channel %s to the webhook: %v", c.ChannelPoint, err)
+	}
+}
+
+// alert posts the double spend to the webhook of the operator.
+func (m *DoubleSpendMonitor) alert(ctx context.Context, alert *DoubleSpendAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, m.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.httpClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package lifecycle
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/chain"
+	"github.com/breez/lspd/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSpendLookup struct {
+	inputs   map[string][]string
+	spenders map[string]string
+}
+
+func (l *fakeSpendLookup) TransactionInputs(_ context.Context, txid string) ([]string, bool, error) {
+	inputs, ok := l.inputs[txid]
+	if !ok {
+		return nil, false, chain.ErrTransactionNotFound
+	}
+
+	return inputs, false, nil
+}
+
+func (l *fakeSpendLookup) OutpointSpender(_ context.Context, outpoint string) (string, error) {
+	return l.spenders[outpoint], nil
+}
+
+type fakeDoubleSpendStore struct {
+	channels []*Channel
+}
+
+func (s *fakeDoubleSpendStore) ListChannels(_ context.Context, _ []byte, _ bool) ([]*Channel, error) {
+	return s.channels, nil
+}
+
+func (s *fakeDoubleSpendStore) SetDoubleSpent(_ context.Context, channelPoint string, spendingTxid string, doubleSpentAt time.Time) (bool, error) {
+	for _, c := range s.channels {
+		if c.ChannelPoint == channelPoint && c.DoubleSpentBy == "" {
+			c.DoubleSpentBy = spendingTxid
+			c.DoubleSpentAt = &doubleSpentAt
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func TestDoubleSpend(t *testing.T) {
+	funding := strings.Repeat("aa", 32)
+	input := strings.Repeat("bb", 32) + ":1"
+	lookup := &fakeSpendLookup{
+		inputs:   map[string][]string{funding: {input}},
+		spenders: map[string]string{input: funding},
+	}
+	now := time.Now()
+	openedAt := now.Add(-time.Minute)
+	store := &fakeDoubleSpendStore{channels: []*Channel{{
+		ChannelPoint:  funding + ":0",
+		PeerID:        strings.Repeat("02", 33),
+		InitialChanID: "1x2x3",
+		OpenedAt:      &openedAt,
+	}}}
+	node := &config.NodeConfig{DoubleSpendMonitor: &config.DoubleSpendMonitorConfig{}}
+	m := NewDoubleSpendMonitor(lookup, store, nil, node, log.New(io.Discard, "", 0))
+
+	// Spent by the funding transaction itself.
+	m.check(context.Background(), now)
+	assert.False(t, m.IsDoubleSpent(funding+":0"))
+
+	// Evicted from the mempool and double spent, found with the inputs seen
+	// before.
+	delete(lookup.inputs, funding)
+	lookup.spenders[input] = strings.Repeat("cc", 32)
+	m.check(context.Background(), now)
+	assert.True(t, m.IsDoubleSpent(funding+":0"))
+	assert.True(t, m.IsDoubleSpent("1x2x3"))
+	assert.Equal(t, strings.Repeat("cc", 32), store.channels[0].DoubleSpentBy)
+
+	// Channels recorded as double spent stay blocked after a restart.
+	m = NewDoubleSpendMonitor(lookup, store, nil, node, log.New(io.Discard, "", 0))
+	m.check(context.Background(), now)
+	assert.True(t, m.IsDoubleSpent("1x2x3"))
+}
@@ -0,0 +1,211 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/events"
+	"github.com/breez/lspd/tenant"
+)
+
+const (
+	defaultLeaseNotifyBefore = 7 * 24 * time.Hour
+	defaultLeaseInterval     = time.Hour
+)
+
+// Lease is the time a channel is guaranteed to stay open until.
+type Lease struct {
+	ChannelPoint string
+	ExpiresAt    time.Time
+}
+
+type LeaseStore interface {
+	ListChannels(ctx context.Context, lspNodeID []byte, includeClosed bool) ([]*Channel, error)
+	// StartLeases starts the lease of the open channels that don't have one
+	// yet, as of the time they were opened.
+	StartLeases(ctx context.Context, lspNodeID []byte, duration time.Duration) (int64, error)
+	SetLeaseNotified(ctx context.Context, channelPoint string, notifiedAt time.Time) error
+	AddLeaseRenewal(ctx context.Context, lspNodeID []byte, paymentHash []byte, channelPoint string, feeMsat uint64) error
+	// RenewLease applies the renewal with the payment hash. Returns nil if
+	// there is no such renewal, or it was applied already.
+	RenewLease(ctx context.Context, lspNodeID []byte, paymentHash []byte, duration time.Duration) (*Lease, error)
+}
+
+// LeaseNotifier tells the client its lease is about to expire.
+type LeaseNotifier interface {
+	NotifyLeaseExpiring(ctx context.Context, pubkey string, channelPoint string, expiresAt time.Time) (bool, error)
+}
+
+// LeaseManager guarantees the channels opened by lspd stay open for the
+// lease duration. It starts the leases of new channels, tells clients before
+// their lease expires, and extends the lease when the renewal payment
+// registered with AddRenewal is forwarded to the client. The Tracker only
+// closes channels whose lease expired.
+type LeaseManager struct {
+	store        LeaseStore
+	notifier     LeaseNotifier
+	node         *config.NodeConfig
+	logger       *log.Logger
+	lspNodeID    []byte
+	duration     time.Duration
+	notifyBefore time.Duration
+	interval     time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+	mtx          sync.Mutex
+}
+
+func NewLeaseManager(
+	store LeaseStore,
+	notifier LeaseNotifier,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) *LeaseManager {
+	lspNodeID, _ := hex.DecodeString(node.NodePubkey)
+	m := &LeaseManager{
+		store:        store,
+		notifier:     notifier,
+		node:         node,
+		logger:       logger,
+		lspNodeID:    lspNodeID,
+		notifyBefore: defaultLeaseNotifyBefore,
+		interval:     defaultLeaseInterval,
+	}
+
+	if cfg := node.ChannelLease; cfg != nil {
+		m.duration = parseDuration(cfg.Duration, 0, logger)
+		m.notifyBefore = parseDuration(cfg.NotifyBefore, defaultLeaseNotifyBefore, logger)
+		m.interval = parseDuration(cfg.Interval, defaultLeaseInterval, logger)
+	}
+
+	return m
+}
+
+// Duration returns the time a lease is extended by with a renewal.
+func (m *LeaseManager) Duration() time.Duration {
+	return m.duration
+}
+
+// RenewalFee returns the fee in millisatoshi for renewing the lease of a
+// channel with the capacity.
+func (m *LeaseManager) RenewalFee(capacitySat uint64) uint64 {
+	cfg := m.node.ChannelLease
+	return cfg.RenewalFeeMsat + capacitySat*1000*uint64(cfg.RenewalFeePpm)/1_000_000
+}
+
+// AddRenewal records the payment with the hash as a renewal of the lease of
+// the channel. The lease is extended once the payment is forwarded to the
+// client with the fee deducted.
+func (m *LeaseManager) AddRenewal(ctx context.Context, paymentHash []byte, channelPoint string, feeMsat uint64) error {
+	return m.store.AddLeaseRenewal(ctx, m.lspNodeID, paymentHash, channelPoint, feeMsat)
+}
+
+// Subscribe extends leases when their renewal payments are forwarded.
+// Renewals are applied like channel opens: when the htlc is forwarded, not
+// when the client settles it.
+func (m *LeaseManager) Subscribe(bus *events.Bus) {
+	bus.Subscribe("channel_leases_"+m.node.Label(), func(e *events.Event) {
+		if e.Node == nil || e.Node.NodePubkey != m.node.NodePubkey {
+			return
+		}
+		if e.Action != "resume_with_onion" || e.Opened || len(e.RegisteredPaymentHash) == 0 {
+			return
+		}
+
+		m.renew(e.RegisteredPaymentHash)
+	}, events.HtlcIntercepted)
+}
+
+func (m *LeaseManager) renew(paymentHash []byte) {
+	lease, err := m.store.RenewLease(tenant.NewContext(context.Background(), m.node.Tenant), m.lspNodeID, paymentHash, m.duration)
+	if err != nil {
+		m.logger.Printf("channel leases: RenewLease(%x) error: %v", paymentHash, err)
+		return
+	}
+	if lease == nil {
+		return
+	}
+
+	m.logger.Printf("channel leases: renewed lease of channel %s until %v with payment %x", lease.ChannelPoint, lease.ExpiresAt, paymentHash)
+}
+
+// Start checks the leases periodically. Returns immediately if the node
+// doesn't lease channels.
+func (m *LeaseManager) Start() error {
+	if m.node.ChannelLease == nil {
+		return nil
+	}
+
+	if m.duration <= 0 {
+		m.logger.Printf("WARN: channel leases: duration has to be positive. Not leasing channels.")
+		return nil
+	}
+
+	m.mtx.Lock()
+	m.ctx, m.cancel = context.WithCancel(tenant.NewContext(context.Background(), m.node.Tenant))
+	ctx := m.ctx
+	m.mtx.Unlock()
+
+	m.logger.Printf("channel leases: leasing channels for %v, notifying clients %v before expiry", m.duration, m.notifyBefore)
+	for {
+		m.check(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(m.interval):
+		}
+	}
+}
+
+func (m *LeaseManager) Stop() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+func (m *LeaseManager) check(ctx context.Context) {
+	started, err := m.store.StartLeases(ctx, m.lspNodeID, m.duration)
+	if err != nil {
+		m.logger.Printf("channel leases: StartLeases() error: %v", err)
+		return
+	}
+	if started > 0 {
+		m.logger.Printf("channel leases: started the lease of %d channels", started)
+	}
+
+	channels, err := m.store.ListChannels(ctx, m.lspNodeID, false)
+	if err != nil {
+		m.logger.Printf("channel leases: ListChannels() error: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, c := range channels {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if c.LeaseExpiresAt == nil || c.LeaseNotifiedAt != nil || now.Before(c.LeaseExpiresAt.Add(-m.notifyBefore)) {
+			continue
+		}
+
+		// Clients without webhooks are not notified, but there is no point
+		// in trying again.
+		_, err := m.notifier.NotifyLeaseExpiring(ctx, c.PeerID, c.ChannelPoint, *c.LeaseExpiresAt)
+		if err != nil {
+			m.logger.Printf("channel leases: NotifyLeaseExpiring(%s) error: %v", c.ChannelPoint, err)
+			continue
+		}
+
+		err = m.store.SetLeaseNotified(ctx, c.ChannelPoint, now)
+		if err != nil {
+			m.logger.Printf("channel leases: SetLeaseNotified(%s) error: %v", c.ChannelPoint, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package lifecycle
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaseKeepsChannelOpen(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	node := &config.NodeConfig{
+		ChannelLifecycle: &config.ChannelLifecycleConfig{MaxInactive: "720h"},
+		ChannelLease:     &config.ChannelLeaseConfig{Duration: "2160h", RenewalFeeMsat: 1000, RenewalFeePpm: 100},
+	}
+	tracker := NewTracker(nil, nil, node, logger)
+
+	now := time.Now()
+	openedAt := now.Add(-1000 * time.Hour)
+	leaseExpiresAt := openedAt.Add(2160 * time.Hour)
+	c := &Channel{OpenedAt: &openedAt, LeaseExpiresAt: &leaseExpiresAt}
+
+	// Unused for longer than maxUnused, but leased.
+	assert.Equal(t, "", tracker.closeReason(c, now))
+	assert.Equal(t, leaseExpiresAt, *tracker.expiresAt(c))
+
+	// Inactive channels are closed once the lease expired.
+	assert.NotEqual(t, "", tracker.closeReason(c, leaseExpiresAt.Add(time.Second)))
+
+	// A channel without a lease is closed as before.
+	c.LeaseExpiresAt = nil
+	assert.NotEqual(t, "", tracker.closeReason(c, now))
+
+	m := NewLeaseManager(nil, nil, node, logger)
+	assert.Equal(t, 2160*time.Hour, m.Duration())
+	assert.Equal(t, uint64(1000+100_000), m.RenewalFee(1_000_000))
+}
@@ -0,0 +1,442 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/tenant"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const defaultCheckInterval = time.Hour
+
+var ErrNotFound = errors.New("channel not found")
+
+// Channel is a channel opened by lspd, with its activity as last seen by the
+// tracker.
+type Channel struct {
+	ChannelPoint    string     `json:"channelPoint"`
+	PeerID          string     `json:"peerId"`
+	InitialChanID   string     `json:"initialChanId"`
+	ConfirmedChanID string     `json:"confirmedChanId,omitempty"`
+	OpenedAt        *time.Time `json:"openedAt,omitempty"`
+	// Time the payment volume of the channel last changed. Nil if it didn't
+	// change since the tracker first saw the channel.
+	LastActivity *time.Time `json:"lastActivity,omitempty"`
+	// Payment volume of the channel as last seen by the tracker.
+	ActivityMsat *uint64    `json:"activityMsat,omitempty"`
+	KeepOpen     bool       `json:"keepOpen"`
+	ClosedAt     *time.Time `json:"closedAt,omitempty"`
+	CloseReason  string     `json:"closeReason,omitempty"`
+	// Time the funding of the channel was seen confirmed. Nil if it is not
+	// confirmed yet, or if funding confirmations are not watched.
+	ConfirmedAt *time.Time `json:"confirmedAt,omitempty"`
+	// Time the lease of the channel expires. The channel is not closed
+	// automatically before. Nil if the node doesn't lease channels.
+	LeaseExpiresAt *time.Time `json:"leaseExpiresAt,omitempty"`
+	// Time the client was told its lease is about to expire.
+	LeaseNotifiedAt *time.Time `json:"leaseNotifiedAt,omitempty"`
+	// Commitment type the channel was opened with. Empty if it is not
+	// known.
+	CommitmentType lightning.CommitmentType `json:"commitmentType,omitempty"`
+	// Transaction that double spent the funding of the channel, and the
+	// time the double spend was seen. Empty if it was not double spent.
+	DoubleSpentBy string     `json:"doubleSpentBy,omitempty"`
+	DoubleSpentAt *time.Time `json:"doubleSpentAt,omitempty"`
+}
+
+type Store interface {
+	ListChannels(ctx context.Context, lspNodeID []byte, includeClosed bool) ([]*Channel, error)
+	SetActivity(ctx context.Context, channelPoint string, activityMsat uint64, lastActivity *time.Time) error
+	SetKeepOpen(ctx context.Context, lspNodeID []byte, channelPoint string, keepOpen bool) error
+	MarkClosed(ctx context.Context, channelPoint string, closedAt time.Time, reason string) error
+}
+
+// ChannelStatus is a channel with the reason it would be closed on the next
+// check, if any.
+type ChannelStatus struct {
+	*Channel
+	Node     string `json:"node"`
+	Grace    bool   `json:"grace"`
+	CloseDue string `json:"closeDue,omitempty"`
+	// Time the channel is closed without further payments. Nil if it is not
+	// closed automatically.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Tracker records the activity of the channels opened by lspd and closes the
+// channels that are no longer used, so their liquidity can be deployed
+// elsewhere. Activity is measured as a change in the payment volume of the
+// channel.
+type Tracker struct {
+	client      lightning.Client
+	store       Store
+	node        *config.NodeConfig
+	logger      *log.Logger
+	interval    time.Duration
+	maxInactive time.Duration
+	maxUnused   time.Duration
+	ctx         context.Context
+	cancel      context.CancelFunc
+	mtx         sync.Mutex
+	checkMtx    sync.Mutex
+}
+
+func NewTracker(
+	client lightning.Client,
+	store Store,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) *Tracker {
+	t := &Tracker{
+		client:   client,
+		store:    store,
+		node:     node,
+		logger:   logger,
+		interval: defaultCheckInterval,
+	}
+
+	cfg := node.ChannelLifecycle
+	if cfg != nil {
+		defaultMaxInactive := time.Duration(node.MaxInactiveDuration) * time.Second
+		t.interval = parseDuration(cfg.Interval, defaultCheckInterval, logger)
+		t.maxInactive = parseDuration(cfg.MaxInactive, defaultMaxInactive, logger)
+		t.maxUnused = parseDuration(cfg.MaxUnused, t.maxInactive, logger)
+	}
+
+	return t
+}
+
+func parseDuration(s string, def time.Duration, logger *log.Logger) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		logger.Printf("WARN: Invalid channel lifecycle duration '%s'. Using default %v", s, def)
+		return def
+	}
+
+	return d
+}
+
+func (t *Tracker) Node() *config.NodeConfig {
+	return t.node
+}
+
+// Start checks the channels periodically. Returns immediately if automatic
+// closes are not configured for the node.
+func (t *Tracker) Start() error {
+	if t.node.ChannelLifecycle == nil {
+		return nil
+	}
+
+	if t.maxInactive <= 0 || t.maxUnused <= 0 {
+		t.logger.Printf("WARN: channel lifecycle: maxInactive and maxUnused have to be positive. Not closing channels.")
+		return nil
+	}
+
+	t.mtx.Lock()
+	t.ctx, t.cancel = context.WithCancel(tenant.NewContext(context.Background(), t.node.Tenant))
+	ctx := t.ctx
+	t.mtx.Unlock()
+
+	t.logger.Printf("channel lifecycle: closing channels inactive for %v or unused for %v, checking every %v (dry run: %v)",
+		t.maxInactive, t.maxUnused, t.interval, t.node.ChannelLifecycle.DryRun)
+	for {
+		t.check(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(t.interval):
+		}
+	}
+}
+
+func (t *Tracker) Stop() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+// Channels returns the open and closed channels of the node.
+func (t *Tracker) Channels(ctx context.Context) ([]*ChannelStatus, error) {
+	channels, err := t.store.ListChannels(ctx, t.lspNodeID(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var result []*ChannelStatus
+	for _, c := range channels {
+		result = append(result, t.status(c, now))
+	}
+
+	return result, nil
+}
+
+// PeerChannels returns the channels of the node with the peer.
+func (t *Tracker) PeerChannels(ctx context.Context, peerID string, includeClosed bool) ([]*ChannelStatus, error) {
+	channels, err := t.store.ListChannels(ctx, t.lspNodeID(), includeClosed)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var result []*ChannelStatus
+	for _, c := range channels {
+		if c.PeerID == peerID {
+			result = append(result, t.status(c, now))
+		}
+	}
+
+	return result, nil
+}
+
+func (t *Tracker) status(c *Channel, now time.Time) *ChannelStatus {
+	status := &ChannelStatus{
+		Channel: c,
+		Node:    t.node.Name,
+		Grace:   t.isGrace(c.PeerID),
+	}
+	if c.ClosedAt == nil && t.node.ChannelLifecycle != nil {
+		status.CloseDue = t.closeReason(c, now)
+		status.ExpiresAt = t.expiresAt(c)
+	}
+
+	return status
+}
+
+// SetKeepOpen excludes the channel from automatic closes, or includes it
+// again.
+func (t *Tracker) SetKeepOpen(ctx context.Context, channelPoint string, keepOpen bool) error {
+	return t.store.SetKeepOpen(ctx, t.lspNodeID(), channelPoint, keepOpen)
+}
+
+// Close cooperatively closes the channel, regardless of its activity.
+func (t *Tracker) Close(ctx context.Context, channelPoint string) (string, error) {
+	channels, err := t.store.ListChannels(ctx, t.lspNodeID(), false)
+	if err != nil {
+		return "", err
+	}
+
+	for _, c := range channels {
+		if c.ChannelPoint == channelPoint {
+			return t.close(ctx, c, "closed by admin")
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+func (t *Tracker) lspNodeID() []byte {
+	lspNodeID, _ := hex.DecodeString(t.node.NodePubkey)
+	return lspNodeID
+}
+
+func (t *Tracker) isGrace(peerID string) bool {
+	if t.node.ChannelLifecycle == nil {
+		return false
+	}
+
+	for _, p := range t.node.ChannelLifecycle.GraceList {
+		if p == peerID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *Tracker) check(ctx context.Context) {
+	t.checkMtx.Lock()
+	defer t.checkMtx.Unlock()
+
+	channels, err := t.store.ListChannels(ctx, t.lspNodeID(), false)
+	if err != nil {
+		t.logger.Printf("channel lifecycle: ListChannels() error: %v", err)
+		return
+	}
+
+	for _, c := range channels {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !t.updateActivity(ctx, c) {
+			continue
+		}
+
+		reason := t.closeReason(c, time.Now())
+		if reason == "" {
+			continue
+		}
+
+		// Htlcs held by clients receiving on hold invoices don't change the
+		// payment volume of the channel until they settle.
+		if t.hasPendingHtlcs(ctx, c) {
+			t.logger.Printf("channel lifecycle: not closing channel %s with %s with htlcs in flight: %s", c.ChannelPoint, c.PeerID, reason)
+			continue
+		}
+
+		if t.node.ChannelLifecycle.DryRun {
+			t.logger.Printf("channel lifecycle: dry run, not closing channel %s with %s: %s", c.ChannelPoint, c.PeerID, reason)
+			continue
+		}
+
+		t.close(ctx, c, reason)
+	}
+}
+
+// updateActivity records a change in the payment volume of the channel.
+// Returns false if the activity of the channel is unknown, because the
+// channel is not active.
+func (t *Tracker) updateActivity(ctx context.Context, c *Channel) bool {
+	peerID, channelPoint, err := parseChannel(c)
+	if err != nil {
+		t.logger.Printf("channel lifecycle: %v", err)
+		return false
+	}
+
+	activity, err := t.client.GetChannelActivity(peerID, *channelPoint)
+	if err != nil {
+		return false
+	}
+
+	if c.ActivityMsat != nil && *c.ActivityMsat == activity {
+		return true
+	}
+
+	// The first time the channel is seen, its volume includes the payment the
+	// channel was opened for, which doesn't count as activity.
+	if c.ActivityMsat != nil {
+		now := time.Now()
+		c.LastActivity = &now
+	}
+	c.ActivityMsat = &activity
+	err = t.store.SetActivity(ctx, c.ChannelPoint, activity, c.LastActivity)
+	if err != nil {
+		t.logger.Printf("channel lifecycle: SetActivity(%s) error: %v", c.ChannelPoint, err)
+	}
+
+	return true
+}
+
+// closeReason returns why the channel is due to be closed, or an empty string
+// if it isn't.
+func (t *Tracker) closeReason(c *Channel, now time.Time) string {
+	if c.KeepOpen || t.isGrace(c.PeerID) {
+		return ""
+	}
+
+	if c.LeaseExpiresAt != nil && now.Before(*c.LeaseExpiresAt) {
+		return ""
+	}
+
+	if c.LastActivity != nil {
+		if now.Sub(*c.LastActivity) > t.maxInactive {
+			return fmt.Sprintf("inactive for more than %v", t.maxInactive)
+		}
+
+		return ""
+	}
+
+	if c.OpenedAt != nil && now.Sub(*c.OpenedAt) > t.maxUnused {
+		return fmt.Sprintf("unused for more than %v", t.maxUnused)
+	}
+
+	return ""
+}
+
+// expiresAt returns the time the channel is due to be closed if it has no
+// more payments, like closeReason, or nil if it isn't closed automatically.
+func (t *Tracker) expiresAt(c *Channel) *time.Time {
+	if c.KeepOpen || t.isGrace(c.PeerID) || t.maxInactive <= 0 || t.maxUnused <= 0 {
+		return nil
+	}
+
+	var expires time.Time
+	switch {
+	case c.LastActivity != nil:
+		expires = c.LastActivity.Add(t.maxInactive)
+	case c.OpenedAt != nil:
+		expires = c.OpenedAt.Add(t.maxUnused)
+	default:
+		return nil
+	}
+
+	if c.LeaseExpiresAt != nil && c.LeaseExpiresAt.After(expires) {
+		expires = *c.LeaseExpiresAt
+	}
+
+	return &expires
+}
+
+func (t *Tracker) close(ctx context.Context, c *Channel, reason string) (string, error) {
+	peerID, channelPoint, err := parseChannel(c)
+	if err != nil {
+		return "", err
+	}
+
+	txid, err := t.client.CloseChannel(peerID, *channelPoint)
+	if err != nil {
+		t.logger.Printf("channel lifecycle: CloseChannel(%s) with %s error: %v", c.ChannelPoint, c.PeerID, err)
+		return "", err
+	}
+
+	t.logger.Printf("channel lifecycle: closed channel %s with %s in tx %s: %s", c.ChannelPoint, c.PeerID, txid, reason)
+	err = t.store.MarkClosed(ctx, c.ChannelPoint, time.Now(), reason)
+	if err != nil {
+		t.logger.Printf("channel lifecycle: MarkClosed(%s) error: %v", c.ChannelPoint, err)
+	}
+
+	return txid, nil
+}
+
+// hasPendingHtlcs returns whether htlcs are in flight over the channel. Errs
+// on the side of not closing the channel.
+func (t *Tracker) hasPendingHtlcs(ctx context.Context, c *Channel) bool {
+	peerID, _, err := parseChannel(c)
+	if err != nil {
+		return true
+	}
+
+	htlcs, err := t.client.ListPendingHtlcs(ctx, peerID)
+	if err != nil {
+		t.logger.Printf("channel lifecycle: ListPendingHtlcs(%s) error: %v", c.PeerID, err)
+		return true
+	}
+
+	for _, h := range htlcs {
+		if h.ChannelPoint == c.ChannelPoint {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseChannel(c *Channel) ([]byte, *wire.OutPoint, error) {
+	peerID, err := hex.DecodeString(c.PeerID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid peer id %s of channel %s: %w", c.PeerID, c.ChannelPoint, err)
+	}
+
+	channelPoint, err := basetypes.ParseOutPoint(c.ChannelPoint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return peerID, channelPoint, nil
+}
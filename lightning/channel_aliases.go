@@ -0,0 +1,187 @@
+package lightning
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/tenant"
+)
+
+// ChannelAlias maps a channel opened by lspd to the alias it was opened with
+// and its real short channel id. RealScid is 0 while the channel is not
+// confirmed.
+type ChannelAlias struct {
+	ChannelPoint string
+	PeerID       []byte
+	Alias        basetypes.ShortChannelID
+	RealScid     basetypes.ShortChannelID
+}
+
+// ChannelAliasStore persists the aliases of the channels of a node.
+type ChannelAliasStore interface {
+	SaveChannelAlias(ctx context.Context, lspNodeID []byte, alias *ChannelAlias) error
+	GetChannelAlias(ctx context.Context, lspNodeID []byte, channelPoint string) (*ChannelAlias, error)
+	ListUnconfirmedAliases(ctx context.Context, lspNodeID []byte, peerID []byte) ([]*ChannelAlias, error)
+	ListChannelAliases(ctx context.Context, lspNodeID []byte) ([]*ChannelAlias, error)
+}
+
+// ChannelAliases keeps the aliases of the channels lspd opens, so htlcs are
+// forwarded over the alias the channel was opened with, which the node keeps
+// recognizing after the channel confirmed. The real scids are refreshed from
+// the channel events of the node. The aliases are stored for the tenant of
+// the node. Nil ChannelAliases store nothing.
+type ChannelAliases struct {
+	lspNodeID []byte
+	tenantID  string
+	client    Client
+	store     ChannelAliasStore
+	logger    *log.Logger
+	mtx       sync.RWMutex
+	scids     map[basetypes.ShortChannelID]struct{}
+}
+
+func NewChannelAliases(lspNodeID []byte, tenantID string, client Client, store ChannelAliasStore, logger *log.Logger) *ChannelAliases {
+	return &ChannelAliases{
+		lspNodeID: lspNodeID,
+		tenantID:  tenantID,
+		client:    client,
+		store:     store,
+		logger:    logger,
+		scids:     make(map[basetypes.ShortChannelID]struct{}),
+	}
+}
+
+// Load loads the stored aliases, so Contains knows the aliases recorded
+// before the last shutdown.
+func (a *ChannelAliases) Load(ctx context.Context) error {
+	aliases, err := a.store.ListChannelAliases(tenant.NewContext(ctx, a.tenantID), a.lspNodeID)
+	if err != nil {
+		return err
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	for _, alias := range aliases {
+		a.scids[alias.Alias] = struct{}{}
+	}
+
+	return nil
+}
+
+// Contains returns whether the scid is the alias of a channel lspd opened.
+func (a *ChannelAliases) Contains(scid basetypes.ShortChannelID) bool {
+	if a == nil {
+		return false
+	}
+
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+	_, ok := a.scids[scid]
+	return ok
+}
+
+// Record stores the alias of the channel as found on the node, and returns
+// the scid to forward htlcs for the channel to: the stored alias if the
+// channel has one, the real scid otherwise.
+func (a *ChannelAliases) Record(ctx context.Context, peerID []byte, channelPoint string, ch *GetChannelResult) basetypes.ShortChannelID {
+	fallback := ch.ConfirmedChannelID
+	if fallback == 0 {
+		fallback = ch.InitialChannelID
+	}
+
+	if a == nil {
+		return fallback
+	}
+
+	ctx = tenant.NewContext(ctx, a.tenantID)
+	stored, err := a.store.GetChannelAlias(ctx, a.lspNodeID, channelPoint)
+	if err != nil {
+		a.logger.Printf("Failed to get the alias of channel %s: %v", channelPoint, err)
+		return fallback
+	}
+
+	alias := &ChannelAlias{
+		ChannelPoint: channelPoint,
+		PeerID:       peerID,
+		Alias:        ch.InitialChannelID,
+		RealScid:     ch.ConfirmedChannelID,
+	}
+	if stored != nil {
+		alias.Alias = stored.Alias
+	} else if ch.InitialChannelID == 0 || ch.InitialChannelID == ch.ConfirmedChannelID {
+		// Opened without an alias.
+		return fallback
+	}
+
+	if stored == nil || (stored.RealScid == 0 && alias.RealScid != 0) {
+		err = a.store.SaveChannelAlias(ctx, a.lspNodeID, alias)
+		if err != nil {
+			a.logger.Printf("Failed to save the alias of channel %s: %v", channelPoint, err)
+		}
+	}
+
+	a.mtx.Lock()
+	a.scids[alias.Alias] = struct{}{}
+	a.mtx.Unlock()
+	return alias.Alias
+}
+
+// RefreshChannel refreshes the channels with the peer of the channel, if the
+// channel was recorded and is not confirmed yet.
+func (a *ChannelAliases) RefreshChannel(ctx context.Context, channelPoint string) {
+	if a == nil {
+		return
+	}
+
+	ctx = tenant.NewContext(ctx, a.tenantID)
+	alias, err := a.store.GetChannelAlias(ctx, a.lspNodeID, channelPoint)
+	if err != nil {
+		a.logger.Printf("Failed to get the alias of channel %s: %v", channelPoint, err)
+		return
+	}
+
+	if alias == nil || alias.RealScid != 0 {
+		return
+	}
+
+	a.Refresh(ctx, alias.PeerID)
+}
+
+// Refresh stores the real scids of the channels with the peer that confirmed
+// since they were recorded. Call it on channel events of the peer.
+func (a *ChannelAliases) Refresh(ctx context.Context, peerID []byte) {
+	if a == nil {
+		return
+	}
+
+	ctx = tenant.NewContext(ctx, a.tenantID)
+	aliases, err := a.store.ListUnconfirmedAliases(ctx, a.lspNodeID, peerID)
+	if err != nil {
+		a.logger.Printf("Failed to list the unconfirmed channel aliases of %x: %v", peerID, err)
+		return
+	}
+
+	for _, alias := range aliases {
+		channelPoint, err := basetypes.ParseOutPoint(alias.ChannelPoint)
+		if err != nil {
+			a.logger.Printf("Invalid channel point %s of alias %s: %v", alias.ChannelPoint, alias.Alias.ToString(), err)
+			continue
+		}
+
+		ch, err := a.client.GetChannel(peerID, *channelPoint)
+		if err != nil || ch.ConfirmedChannelID == 0 {
+			continue
+		}
+
+		alias.RealScid = ch.ConfirmedChannelID
+		err = a.store.SaveChannelAlias(ctx, a.lspNodeID, alias)
+		if err != nil {
+			a.logger.Printf("Failed to save the real scid of channel %s: %v", alias.ChannelPoint, err)
+			continue
+		}
+
+		a.logger.Printf("Channel %s with alias %s confirmed as %s", alias.ChannelPoint, alias.Alias.ToString(), alias.RealScid.ToString())
+	}
+}
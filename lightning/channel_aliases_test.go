@@ -0,0 +1,58 @@
+package lightning
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockAliasStore struct {
+	aliases map[string]*ChannelAlias
+}
+
+func (s *mockAliasStore) SaveChannelAlias(ctx context.Context, lspNodeID []byte, alias *ChannelAlias) error {
+	a := *alias
+	s.aliases[alias.ChannelPoint] = &a
+	return nil
+}
+
+func (s *mockAliasStore) GetChannelAlias(ctx context.Context, lspNodeID []byte, channelPoint string) (*ChannelAlias, error) {
+	return s.aliases[channelPoint], nil
+}
+
+func (s *mockAliasStore) ListUnconfirmedAliases(ctx context.Context, lspNodeID []byte, peerID []byte) ([]*ChannelAlias, error) {
+	return nil, nil
+}
+
+func (s *mockAliasStore) ListChannelAliases(ctx context.Context, lspNodeID []byte) ([]*ChannelAlias, error) {
+	return nil, nil
+}
+
+func TestChannelAliasesRecord(t *testing.T) {
+	store := &mockAliasStore{aliases: make(map[string]*ChannelAlias)}
+	aliases := NewChannelAliases(nil, "", nil, store, log.New(os.Stderr, "", 0))
+	ctx := context.Background()
+	peer := []byte{0x02, 0xaa}
+
+	// Zero conf channel, forwarded over the alias before and after it
+	// confirmed.
+	scid := aliases.Record(ctx, peer, "aa:0", &GetChannelResult{InitialChannelID: 100})
+	assert.Equal(t, uint64(100), uint64(scid))
+	scid = aliases.Record(ctx, peer, "aa:0", &GetChannelResult{InitialChannelID: 100, ConfirmedChannelID: 200})
+	assert.Equal(t, uint64(100), uint64(scid))
+	assert.Equal(t, uint64(200), uint64(store.aliases["aa:0"].RealScid))
+	assert.True(t, aliases.Contains(100))
+	assert.False(t, aliases.Contains(200))
+
+	// Channel without an alias.
+	scid = aliases.Record(ctx, peer, "bb:0", &GetChannelResult{InitialChannelID: 300, ConfirmedChannelID: 300})
+	assert.Equal(t, uint64(300), uint64(scid))
+	assert.Nil(t, store.aliases["bb:0"])
+
+	var disabled *ChannelAliases
+	scid = disabled.Record(ctx, peer, "aa:0", &GetChannelResult{InitialChannelID: 100, ConfirmedChannelID: 200})
+	assert.Equal(t, uint64(200), uint64(scid))
+}
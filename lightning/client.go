@@ -1,6 +1,8 @@
 package lightning
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/breez/lspd/basetypes"
@@ -8,8 +10,9 @@ import (
 )
 
 type GetInfoResult struct {
-	Alias  string
-	Pubkey string
+	Alias       string
+	Pubkey      string
+	BlockHeight uint32
 }
 
 type GetChannelResult struct {
@@ -17,25 +20,126 @@ type GetChannelResult struct {
 	ConfirmedChannelID basetypes.ShortChannelID
 }
 
+type GetWalletBalanceResult struct {
+	ConfirmedSat   basetypes.Satoshi
+	UnconfirmedSat basetypes.Satoshi
+}
+
 type OpenChannelRequest struct {
 	Destination    []byte
-	CapacitySat    uint64
-	MinHtlcMsat    uint64
+	CapacitySat    basetypes.Satoshi
+	MinHtlcMsat    basetypes.MilliSatoshi
 	IsPrivate      bool
 	IsZeroConf     bool
+	CommitmentType CommitmentType
 	MinConfs       *uint32
 	FeeSatPerVByte *float64
 	TargetConf     *uint32
 }
 
+// ChannelPolicy is the routing policy of the node for a channel. FeeRate is
+// the proportional fee, e.g. 0.000001 for 1 ppm.
+type ChannelPolicy struct {
+	BaseFeeMsat   basetypes.MilliSatoshi
+	FeeRate       float64
+	TimeLockDelta uint32
+	MaxHtlcMsat   basetypes.MilliSatoshi
+	// Zero keeps the current minimum.
+	MinHtlcMsat basetypes.MilliSatoshi
+}
+
+// Forward is a settled forward of the node.
+type Forward struct {
+	Timestamp     time.Time
+	ChanIDIn      basetypes.ShortChannelID
+	ChanIDOut     basetypes.ShortChannelID
+	AmountInMsat  uint64
+	AmountOutMsat uint64
+}
+
+// PendingHtlc is an htlc that is in flight over a channel of the node.
+type PendingHtlc struct {
+	ChannelPoint string
+	PaymentHash  []byte
+	AmountMsat   uint64
+	Incoming     bool
+}
+
+// PeerChannel is an open or pending channel with a peer.
+type PeerChannel struct {
+	ChannelPoint string
+	CapacitySat  uint64
+	Pending      bool
+}
+
+// PsbtSigner funds and signs the funding transaction of a channel open with
+// an external wallet. It receives the funding address and amount, and a psbt
+// containing the funding output. It returns the signed psbt.
+type PsbtSigner func(fundingAddress string, amountSat uint64, psbt []byte) ([]byte, error)
+
+// PsbtFunder opens channels funded by an external wallet instead of the node
+// wallet.
+type PsbtFunder interface {
+	OpenChannelPsbt(req *OpenChannelRequest, signer PsbtSigner) (*wire.OutPoint, error)
+}
+
+// Splicer adds funds to existing channels.
+type Splicer interface {
+	// SpliceIn splices amountSat from the node wallet into the open channel
+	// with the peer with the largest local balance. It returns the funding
+	// outpoint of the channel as it was before the splice, or
+	// ErrNoSpliceableChannel if there is no channel with the peer.
+	SpliceIn(peerID []byte, amountSat uint64, feeSatPerVByte *float64) (*wire.OutPoint, error)
+}
+
+// DualFunder opens dual-funded channels, to which the peer may contribute
+// funds, and contributes to the channels peers open to the node according to
+// its funder policy.
+type DualFunder interface {
+	// SetFunderPolicy sets the policy the node contributes to channels
+	// opened by peers with, and the lease rates it advertises as liquidity
+	// ads.
+	SetFunderPolicy(ctx context.Context, policy *FunderPolicy) error
+	// ListChannelFunding returns the funds both sides contributed to the
+	// channels with the peer, by channel point.
+	ListChannelFunding(ctx context.Context, peerID []byte) (map[string]*ChannelFunding, error)
+}
+
+// CoinController funds channel opens and splices with the wallet utxos coin
+// control selects, rather than letting the node select them.
+type CoinController interface {
+	// SetCoinControl sets the coin control of the node. Call it before the
+	// first channel open.
+	SetCoinControl(cc *CoinControl)
+}
+
+var ErrNoSpliceableChannel = errors.New("no channel to splice into")
+
+var ErrPeerNotConnected = errors.New("peer not connected")
+
 type Client interface {
 	GetInfo() (*GetInfoResult, error)
 	IsConnected(destination []byte) (bool, error)
+	ConnectPeer(ctx context.Context, peerID []byte, address string) error
 	OpenChannel(req *OpenChannelRequest) (*wire.OutPoint, error)
 	GetChannel(peerID []byte, channelPoint wire.OutPoint) (*GetChannelResult, error)
 	GetPeerId(scid *basetypes.ShortChannelID) ([]byte, error)
-	GetNodeChannelCount(nodeID []byte) (int, error)
-	GetClosedChannels(nodeID string, channelPoints map[string]uint64) (map[string]uint64, error)
+	GetNodeChannelCount(ctx context.Context, nodeID []byte) (int, error)
+	ListPeerChannels(ctx context.Context, peerID []byte) ([]*PeerChannel, error)
+	GetMaxLocalBalanceMsat(peerID []byte) (uint64, error)
+	// SupportsCommitmentType returns whether both the node and the peer
+	// support channels of the commitment type. With a nil peerID only the
+	// node is checked.
+	SupportsCommitmentType(peerID []byte, t CommitmentType) (bool, error)
+	SupportsLargeChannels(peerID []byte) (bool, error)
+	PeerFeatures(peerID []byte) (func(bit uint32) bool, error)
+	SetChannelPolicy(peerID []byte, channelPoint wire.OutPoint, policy *ChannelPolicy) error
+	GetClosedChannels(ctx context.Context, nodeID string, channelPoints map[string]uint64) (map[string]uint64, error)
 	WaitOnline(peerID []byte, deadline time.Time) error
 	WaitChannelActive(peerID []byte, deadline time.Time) error
+	GetWalletBalance() (*GetWalletBalanceResult, error)
+	GetChannelActivity(peerID []byte, channelPoint wire.OutPoint) (uint64, error)
+	CloseChannel(peerID []byte, channelPoint wire.OutPoint) (string, error)
+	ListForwards(ctx context.Context, since time.Time) ([]*Forward, error)
+	ListPendingHtlcs(ctx context.Context, peerID []byte) ([]*PendingHtlc, error)
 }
@@ -0,0 +1,124 @@
+package lightning
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/breez/lspd/basetypes"
+)
+
+// Estimated size in vbytes of a funding transaction with p2wpkh inputs, a
+// funding output and a change output.
+const (
+	fundingTxOverheadVBytes = 11
+	fundingInputVBytes      = 68
+	fundingOutputsVBytes    = 2 * 43
+)
+
+// Fee rate the funding inputs are selected at when the channel open only has
+// a confirmation target. The node pays the actual fee, the excess is
+// returned as change.
+const selectionFeeSatPerVByte = 50
+
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// CoinControl restricts the wallet utxos the node funds channels with, so
+// utxos the lsp keeps for other purposes, like its treasury, aren't spent by
+// channel opens.
+type CoinControl struct {
+	// lnd wallet account the funding inputs are taken from. Empty is the
+	// default account.
+	Account string
+	// Minimum confirmations of the funding inputs.
+	MinConfs uint32
+	// Utxos never used as funding inputs, as txid:vout.
+	ExcludeUtxos []string
+	// Minimum change the funding inputs leave after the amount and the fee.
+	// Zero allows funding transactions without change output.
+	MinChangeSat uint64
+}
+
+// Utxo is an unspent output of the node wallet.
+type Utxo struct {
+	// The outpoint as txid:vout.
+	Outpoint      string
+	AmountSat     uint64
+	Confirmations uint32
+	// Reserved utxos are locked by the node for another transaction.
+	Reserved bool
+}
+
+// Spendable returns the utxos coin control allows to fund channels with.
+func (c *CoinControl) Spendable(utxos []*Utxo) []*Utxo {
+	var result []*Utxo
+	for _, u := range utxos {
+		if c.allowed(u) && u.Confirmations >= c.MinConfs {
+			result = append(result, u)
+		}
+	}
+
+	return result
+}
+
+// Balance returns the balance of the utxos coin control allows to fund
+// channels with. Utxos with fewer than the minimum confirmations are
+// unconfirmed.
+func (c *CoinControl) Balance(utxos []*Utxo) *GetWalletBalanceResult {
+	result := &GetWalletBalanceResult{}
+	for _, u := range utxos {
+		if !c.allowed(u) {
+			continue
+		}
+
+		if u.Confirmations >= c.MinConfs && u.Confirmations > 0 {
+			result.ConfirmedSat += basetypes.Satoshi(u.AmountSat)
+		} else {
+			result.UnconfirmedSat += basetypes.Satoshi(u.AmountSat)
+		}
+	}
+
+	return result
+}
+
+func (c *CoinControl) allowed(u *Utxo) bool {
+	if u.Reserved {
+		return false
+	}
+
+	for _, o := range c.ExcludeUtxos {
+		if o == u.Outpoint {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SelectUtxos selects the spendable utxos funding amountSat, largest first,
+// until they cover the amount, the fee at the fee rate and the minimum
+// change. Without fee rate the inputs are selected at a high fee rate.
+// Returns ErrInsufficientFunds if the spendable utxos don't cover it.
+func (c *CoinControl) SelectUtxos(utxos []*Utxo, amountSat uint64, feeSatPerVByte *float64) ([]*Utxo, error) {
+	rate := float64(selectionFeeSatPerVByte)
+	if feeSatPerVByte != nil {
+		rate = *feeSatPerVByte
+	}
+
+	spendable := c.Spendable(utxos)
+	sort.SliceStable(spendable, func(i, j int) bool {
+		return spendable[i].AmountSat > spendable[j].AmountSat
+	})
+
+	var selected []*Utxo
+	var total uint64
+	for _, u := range spendable {
+		selected = append(selected, u)
+		total += u.AmountSat
+		vbytes := fundingTxOverheadVBytes + fundingOutputsVBytes + len(selected)*fundingInputVBytes
+		if total >= amountSat+uint64(float64(vbytes)*rate)+c.MinChangeSat {
+			return selected, nil
+		}
+	}
+
+	return nil, ErrInsufficientFunds
+}
@@ -0,0 +1,33 @@
+package lightning
+
+import (
+	"testing"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectUtxos(t *testing.T) {
+	utxos := []*Utxo{
+		{Outpoint: "aa:0", AmountSat: 500_000, Confirmations: 10},
+		{Outpoint: "bb:0", AmountSat: 5_000_000, Confirmations: 10},
+		{Outpoint: "cc:1", AmountSat: 300_000, Confirmations: 10},
+		{Outpoint: "dd:0", AmountSat: 200_000, Confirmations: 1},
+		{Outpoint: "ee:0", AmountSat: 900_000, Confirmations: 10, Reserved: true},
+	}
+	cc := &CoinControl{MinConfs: 3, ExcludeUtxos: []string{"bb:0"}, MinChangeSat: 10_000}
+	rate := 10.0
+
+	// The treasury, reserved and unconfirmed utxos are never selected.
+	selected, err := cc.SelectUtxos(utxos, 700_000, &rate)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Utxo{utxos[0], utxos[2]}, selected)
+
+	// The change has to cover the minimum change too.
+	_, err = cc.SelectUtxos(utxos, 790_000, &rate)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	balance := cc.Balance(utxos)
+	assert.Equal(t, basetypes.Satoshi(800_000), balance.ConfirmedSat)
+	assert.Equal(t, basetypes.Satoshi(200_000), balance.UnconfirmedSat)
+}
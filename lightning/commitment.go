@@ -0,0 +1,104 @@
+package lightning
+
+import (
+	"fmt"
+	"log"
+)
+
+// CommitmentType is the commitment format of a channel. The empty type opens
+// the channel with the default type of the node.
+type CommitmentType string
+
+const (
+	// Commitments without anchor outputs.
+	CommitmentTypeStaticRemoteKey CommitmentType = "static_remote_key"
+	// Commitments with zero fee htlc anchor outputs,
+	// option_anchors_zero_fee_htlc_tx. The only anchor type lnd and cln
+	// open.
+	CommitmentTypeAnchors CommitmentType = "anchors"
+	// Simple taproot channels. Only private channels can be taproot
+	// channels.
+	CommitmentTypeTaproot CommitmentType = "taproot"
+)
+
+// CommitmentTypes are the commitment types clients can request, from the
+// most to the least preferred.
+var CommitmentTypes = []CommitmentType{
+	CommitmentTypeTaproot,
+	CommitmentTypeAnchors,
+	CommitmentTypeStaticRemoteKey,
+}
+
+// Feature bits of option_static_remotekey.
+var staticRemoteKeyFeatureBits = []uint32{12, 13}
+
+// ParseCommitmentType parses a commitment type. The empty string is the
+// default type of the node.
+func ParseCommitmentType(s string) (CommitmentType, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	for _, t := range CommitmentTypes {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown commitment type '%s', use taproot, anchors or static_remote_key", s)
+}
+
+// HasCommitmentTypeFeature returns a function that returns whether the
+// feature bits of the commitment type are set, like HasTaprootFeature.
+func HasCommitmentTypeFeature(t CommitmentType) func(hasBit func(bit uint32) bool) bool {
+	return func(hasBit func(bit uint32) bool) bool {
+		switch t {
+		case CommitmentTypeTaproot:
+			return HasTaprootFeature(hasBit)
+		case CommitmentTypeAnchors:
+			return len(MissingFeatures(hasBit, []Feature{FeatureAnchors})) == 0
+		case CommitmentTypeStaticRemoteKey:
+			for _, bit := range staticRemoteKeyFeatureBits {
+				if hasBit(bit) {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+}
+
+// NegotiateCommitmentType returns the commitment type to open a channel to
+// the peer with: the preferred type if both the node and the peer support it,
+// otherwise the first of anchors and static_remote_key they both support.
+// Returns the empty type, the default of the node, if they support none of
+// them or the node can't tell.
+func NegotiateCommitmentType(client Client, peerID []byte, preferred CommitmentType, private bool, logger *log.Logger) CommitmentType {
+	candidates := []CommitmentType{CommitmentTypeAnchors, CommitmentTypeStaticRemoteKey}
+	if preferred == CommitmentTypeTaproot && !private {
+		logger.Printf("Not opening a taproot channel to %x, the channel is public", peerID)
+	} else if preferred != "" {
+		candidates = append([]CommitmentType{preferred}, candidates...)
+	}
+
+	for i, t := range candidates {
+		if i > 0 && t == candidates[0] {
+			continue
+		}
+
+		supported, err := client.SupportsCommitmentType(peerID, t)
+		if err != nil {
+			logger.Printf("SupportsCommitmentType(%x, %s) error: %v", peerID, t, err)
+			continue
+		}
+		if supported {
+			if t != candidates[0] {
+				logger.Printf("%x doesn't support %s channels, opening %s channel", peerID, preferred, t)
+			}
+			return t
+		}
+	}
+
+	return ""
+}
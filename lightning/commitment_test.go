@@ -0,0 +1,49 @@
+package lightning
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type commitmentClient struct {
+	Client
+	supported map[CommitmentType]bool
+}
+
+func (c *commitmentClient) SupportsCommitmentType(peerID []byte, t CommitmentType) (bool, error) {
+	return c.supported[t], nil
+}
+
+func TestNegotiateCommitmentType(t *testing.T) {
+	logger := log.New(os.Stderr, "", 0)
+	client := &commitmentClient{supported: map[CommitmentType]bool{
+		CommitmentTypeTaproot:         true,
+		CommitmentTypeAnchors:         true,
+		CommitmentTypeStaticRemoteKey: true,
+	}}
+
+	assert.Equal(t, CommitmentTypeTaproot, NegotiateCommitmentType(client, nil, CommitmentTypeTaproot, true, logger))
+	assert.Equal(t, CommitmentTypeAnchors, NegotiateCommitmentType(client, nil, "", true, logger))
+
+	// Public channels are never taproot channels.
+	assert.Equal(t, CommitmentTypeAnchors, NegotiateCommitmentType(client, nil, CommitmentTypeTaproot, false, logger))
+
+	// Peers without anchors get static_remote_key channels.
+	client.supported[CommitmentTypeAnchors] = false
+	assert.Equal(t, CommitmentTypeStaticRemoteKey, NegotiateCommitmentType(client, nil, CommitmentTypeAnchors, true, logger))
+
+	client.supported[CommitmentTypeStaticRemoteKey] = false
+	assert.Equal(t, CommitmentType(""), NegotiateCommitmentType(client, nil, CommitmentTypeAnchors, false, logger))
+}
+
+func TestParseCommitmentType(t *testing.T) {
+	ct, err := ParseCommitmentType("anchors")
+	assert.NoError(t, err)
+	assert.Equal(t, CommitmentTypeAnchors, ct)
+
+	_, err = ParseCommitmentType("legacy")
+	assert.Error(t, err)
+}
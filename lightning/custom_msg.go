@@ -0,0 +1,26 @@
+package lightning
+
+import "errors"
+
+// ErrCustomMsgClientStopped is returned by Recv after the client is stopped.
+var ErrCustomMsgClientStopped = errors.New("custom message client stopped")
+
+// CustomMessage is a BOLT8 custom message exchanged with a peer of the node,
+// with a type of 32768 or higher.
+type CustomMessage struct {
+	PeerID []byte
+	Type   uint32
+	Data   []byte
+}
+
+// CustomMsgClient receives and sends the custom messages of a node. Protocols
+// like LSPS0 are built on top of it.
+type CustomMsgClient interface {
+	// Start keeps receiving custom messages from the node until Stop is
+	// called.
+	Start() error
+	// Recv blocks until a custom message is received, or the client stops.
+	Recv() (*CustomMessage, error)
+	Send(msg *CustomMessage) error
+	Stop()
+}
@@ -0,0 +1,53 @@
+package lightning
+
+// FunderPolicy is how much the node contributes to dual-funded channels
+// opened by peers, and the rates it leases liquidity at.
+type FunderPolicy struct {
+	// "match", "available" or "fixed", with PolicyMod the percentage of the
+	// peer's funds matched, the percentage of the available funds, or the
+	// fixed amount in satoshi.
+	Policy             string
+	PolicyMod          uint64
+	LeasesOnly         bool
+	MinTheirFundingSat uint64
+	MaxTheirFundingSat uint64
+	PerChannelMaxSat   uint64
+	ReserveTankSat     uint64
+	// Rates advertised as liquidity ads. Nil doesn't advertise liquidity.
+	LeaseRates *LeaseRates
+}
+
+// LeaseRates are the rates a node leases liquidity at to peers opening a
+// channel to it, as advertised in its liquidity ad.
+type LeaseRates struct {
+	LeaseFeeBaseSat uint64
+	// Fee in basis points of the leased amount.
+	LeaseFeeBasis uint32
+	// Weight of the funding inputs and outputs of the node the peer pays
+	// the onchain fee of.
+	FundingWeight uint32
+	// Maximum routing fees the node charges over the leased channel for the
+	// lease duration.
+	ChannelFeeMaxBaseMsat                uint32
+	ChannelFeeMaxProportionalThousandths uint32
+}
+
+// Fee returns the fee in satoshi a peer pays for leasing amountSat, opening
+// the channel at the feerate in sat per 1000 weight units: the base fee, the
+// proportional fee and the onchain fee of the funding weight of the node.
+func (r *LeaseRates) Fee(amountSat uint64, feeratePerKw uint64) uint64 {
+	return r.LeaseFeeBaseSat +
+		amountSat*uint64(r.LeaseFeeBasis)/10_000 +
+		uint64(r.FundingWeight)*feeratePerKw/1000
+}
+
+// ChannelFunding is what both sides contributed to the funding of a channel.
+// The peer contributes to dual-funded channels only.
+type ChannelFunding struct {
+	LocalFundsSat  uint64
+	RemoteFundsSat uint64
+	// Lease fee the peer paid the node for liquidity, or the node paid the
+	// peer.
+	LeaseFeeReceivedMsat uint64
+	LeaseFeePaidMsat     uint64
+}
@@ -0,0 +1,15 @@
+package lightning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaseFee(t *testing.T) {
+	rates := &LeaseRates{LeaseFeeBaseSat: 1000, LeaseFeeBasis: 50, FundingWeight: 600}
+
+	// 1000 base + 0.5% of 1000000 + 600 weight at 2500 sat/kw.
+	assert.Equal(t, uint64(7500), rates.Fee(1_000_000, 2500))
+	assert.Equal(t, uint64(1000), rates.Fee(0, 0))
+}
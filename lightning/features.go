@@ -0,0 +1,100 @@
+package lightning
+
+import "encoding/hex"
+
+// Feature bits of simple taproot channels. lnd signals the staging bits
+// until the spec is final.
+var taprootFeatureBits = []uint32{80, 81, 180, 181}
+
+// HasTaprootFeature returns whether one of the simple taproot channels
+// feature bits is set.
+func HasTaprootFeature(hasBit func(bit uint32) bool) bool {
+	for _, bit := range taprootFeatureBits {
+		if hasBit(bit) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Feature bits of option_support_large_channel (wumbo).
+var largeChannelsFeatureBits = []uint32{18, 19}
+
+// Channels above this capacity in satoshi require option_support_large_channel
+// on both sides.
+const MaxStandardChannelCapacitySat = 16777215
+
+// HasLargeChannelsFeature returns whether one of the
+// option_support_large_channel feature bits is set.
+func HasLargeChannelsFeature(hasBit func(bit uint32) bool) bool {
+	for _, bit := range largeChannelsFeatureBits {
+		if hasBit(bit) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FeatureBits parses a hex encoded feature bit vector, as returned by cln,
+// into a lookup function for HasTaprootFeature and HasLargeChannelsFeature.
+func FeatureBits(features string) (func(bit uint32) bool, error) {
+	b, err := hex.DecodeString(features)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(bit uint32) bool {
+		i := len(b) - 1 - int(bit/8)
+		if i < 0 {
+			return false
+		}
+
+		return b[i]&(1<<(bit%8)) != 0
+	}, nil
+}
+
+// Feature is a feature clients have to signal to get a channel.
+type Feature string
+
+const (
+	FeatureZeroConf  Feature = "zero_conf"
+	FeatureScidAlias Feature = "scid_alias"
+	FeatureAnchors   Feature = "anchors"
+)
+
+// Feature bits of option_zeroconf, option_scid_alias and
+// option_anchors_zero_fee_htlc_tx.
+var featureBits = map[Feature][]uint32{
+	FeatureZeroConf:  {50, 51},
+	FeatureScidAlias: {46, 47},
+	FeatureAnchors:   {22, 23},
+}
+
+// IsKnownFeature returns whether the feature can be checked with
+// MissingFeatures.
+func IsKnownFeature(f Feature) bool {
+	_, ok := featureBits[f]
+	return ok
+}
+
+// MissingFeatures returns the required features of which none of the feature
+// bits are set.
+func MissingFeatures(hasBit func(bit uint32) bool, required []Feature) []Feature {
+	var missing []Feature
+	for _, f := range required {
+		set := false
+		for _, bit := range featureBits[f] {
+			if hasBit(bit) {
+				set = true
+				break
+			}
+		}
+		if !set {
+			missing = append(missing, f)
+		}
+	}
+
+	return missing
+}
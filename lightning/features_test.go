@@ -0,0 +1,17 @@
+package lightning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingFeatures(t *testing.T) {
+	// Bits 47 (scid_alias optional) and 50 (zero_conf required).
+	hasBit, err := FeatureBits("04800000000000")
+	assert.NoError(t, err)
+
+	required := []Feature{FeatureZeroConf, FeatureScidAlias, FeatureAnchors}
+	assert.Equal(t, []Feature{FeatureAnchors}, MissingFeatures(hasBit, required))
+	assert.Nil(t, MissingFeatures(hasBit, required[:2]))
+}
@@ -0,0 +1,34 @@
+package lightning
+
+// LightningNode is a lightning node backend lspd provides its services with.
+// The business logic of lspd only uses this interface, so other backends,
+// like LDK node or Eclair, can be added next to LND and CLN without touching
+// it. Besides the interface, a backend needs an htlc interceptor that passes
+// the htlcs of the node to the interceptor.
+//
+// Optional features are implemented by backends that support them, and
+// checked for with a type assertion: PsbtFunder for channels funded by an
+// external wallet, Splicer for splicing into existing channels.
+type LightningNode interface {
+	Client
+
+	// Backend returns the name of the implementation, e.g. lnd or cln.
+	Backend() string
+
+	// SetPeerTracker sets the tracker that is kept up to date with the peer
+	// events of the node. Call it before StartListeners.
+	SetPeerTracker(peers *PeerTracker)
+
+	// SetChannelAliases sets the aliases of the channels of the node, which
+	// are refreshed on the channel events of the node. Call it before
+	// StartListeners.
+	SetChannelAliases(aliases *ChannelAliases)
+
+	// StartListeners starts following the peer and channel events of the
+	// node.
+	StartListeners()
+
+	// NewCustomMsgClient returns a client receiving and sending the custom
+	// messages of the node.
+	NewCustomMsgClient() CustomMsgClient
+}
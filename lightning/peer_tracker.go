@@ -0,0 +1,275 @@
+package lightning
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/events"
+	"github.com/breez/lspd/tenant"
+)
+
+// PeerState is the connectivity of a peer of the node. Since is the time the
+// peer connected or disconnected. Address is the last known address the peer
+// accepts connections on, if any.
+type PeerState struct {
+	Connected bool
+	Since     time.Time
+	Address   string
+}
+
+// PeerStateStore persists the peer states of a node.
+type PeerStateStore interface {
+	GetPeerStates(ctx context.Context, lspNodeID []byte) (map[string]*PeerState, error)
+	SetPeerState(ctx context.Context, lspNodeID []byte, peerID []byte, state *PeerState) error
+}
+
+var ErrPeerTrackerNotSynced = errors.New("peer tracker not synced")
+
+// PeerTracker tracks which peers are connected to the node from the peer
+// events of the node, so whether a client is online is known without asking
+// the node. The tracker is only synced while the node's peer events are
+// subscribed. Events may be missed in between, so callers fall back to asking
+// the node while it is not synced.
+type PeerTracker struct {
+	lspNodeID []byte
+	tenantID  string
+	store     PeerStateStore
+	logger    *log.Logger
+	mtx       sync.Mutex
+	synced    bool
+	peers     map[string]*PeerState
+	waiters   map[string][]chan struct{}
+	bus       *events.Bus
+	node      *config.NodeConfig
+}
+
+func NewPeerTracker(lspNodeID []byte, tenantID string, store PeerStateStore, logger *log.Logger) *PeerTracker {
+	return &PeerTracker{
+		lspNodeID: lspNodeID,
+		tenantID:  tenantID,
+		store:     store,
+		logger:    logger,
+		peers:     make(map[string]*PeerState),
+		waiters:   make(map[string][]chan struct{}),
+	}
+}
+
+// SetEventBus sets the bus peers connecting to the node are published on.
+// Not safe to call after the tracker is synced.
+func (t *PeerTracker) SetEventBus(bus *events.Bus, node *config.NodeConfig) {
+	t.bus = bus
+	t.node = node
+}
+
+// Load loads the persisted peer states, so the time a peer disconnected is
+// known across restarts. The tracker is not synced by loading.
+func (t *PeerTracker) Load(ctx context.Context) error {
+	if t.store == nil {
+		return nil
+	}
+
+	states, err := t.store.GetPeerStates(tenant.NewContext(ctx, t.tenantID), t.lspNodeID)
+	if err != nil {
+		return err
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	for peerID, state := range states {
+		if _, ok := t.peers[peerID]; !ok {
+			t.peers[peerID] = state
+		}
+	}
+
+	return nil
+}
+
+// Sync sets the peers connected to the node, keyed by their hex encoded ids,
+// with the address they accept connections on if known. Call it after
+// (re)subscribing to the peer events. All other peers are disconnected.
+func (t *PeerTracker) Sync(connected map[string]string) {
+	now := time.Now()
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	for peerID, state := range t.peers {
+		if _, ok := connected[peerID]; state.Connected && !ok {
+			t.setLocked(peerID, false, "", now)
+		}
+	}
+	for peerID, address := range connected {
+		t.setLocked(peerID, true, address, now)
+	}
+
+	t.synced = true
+	t.logger.Printf("Peer tracker synced with %d connected peers.", len(connected))
+}
+
+// Unsync marks the tracker as not synced, when the subscription to the peer
+// events of the node breaks. Waiters are woken up, so they can fall back to
+// asking the node.
+func (t *PeerTracker) Unsync() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.synced = false
+	for peerID, waiters := range t.waiters {
+		for _, w := range waiters {
+			close(w)
+		}
+		delete(t.waiters, peerID)
+	}
+}
+
+// SetConnected updates the state of the peer with the hex encoded id from a
+// peer event. The address is the address the peer accepts connections on, or
+// empty if unknown, in which case the last known address is kept.
+func (t *PeerTracker) SetConnected(peerID string, connected bool, address string) {
+	now := time.Now()
+	t.mtx.Lock()
+	changed := t.setLocked(peerID, connected, address, now)
+	t.mtx.Unlock()
+
+	// Peers connected while the tracker was not synced are not published
+	// when it syncs, they may have been connected for a while.
+	if changed && connected {
+		id, _ := hex.DecodeString(peerID)
+		t.bus.Publish(&events.Event{
+			Kind:   events.ClientConnected,
+			Node:   t.node,
+			Time:   now,
+			PeerID: id,
+		})
+	}
+}
+
+// setLocked sets the state of the peer. Returns whether the peer connected or
+// disconnected.
+func (t *PeerTracker) setLocked(peerID string, connected bool, address string, now time.Time) bool {
+	state, ok := t.peers[peerID]
+	if ok && state.Connected == connected && (address == "" || address == state.Address) {
+		return false
+	}
+	changed := !ok || state.Connected != connected
+
+	since := now
+	if ok && state.Connected == connected {
+		since = state.Since
+	}
+	if address == "" && ok {
+		address = state.Address
+	}
+
+	state = &PeerState{Connected: connected, Since: since, Address: address}
+	t.peers[peerID] = state
+	if connected {
+		for _, w := range t.waiters[peerID] {
+			close(w)
+		}
+		delete(t.waiters, peerID)
+	}
+
+	if t.store != nil {
+		go t.persist(peerID, state)
+	}
+
+	return changed
+}
+
+func (t *PeerTracker) persist(peerID string, state *PeerState) {
+	id, err := hex.DecodeString(peerID)
+	if err != nil {
+		t.logger.Printf("Invalid peer id '%s' in peer tracker: %v", peerID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(tenant.NewContext(context.Background(), t.tenantID), 10*time.Second)
+	defer cancel()
+	err = t.store.SetPeerState(ctx, t.lspNodeID, id, state)
+	if err != nil {
+		t.logger.Printf("Failed to persist state of peer %s: %v", peerID, err)
+	}
+}
+
+// IsConnected returns whether the peer is connected. The second return value
+// is false if the tracker is not synced, and the answer can't be trusted.
+func (t *PeerTracker) IsConnected(peerID []byte) (bool, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if !t.synced {
+		return false, false
+	}
+
+	state, ok := t.peers[hex.EncodeToString(peerID)]
+	return ok && state.Connected, true
+}
+
+// GetState returns the last known state of the peer, or nil if the peer was
+// never seen. The state may be outdated if the tracker is not synced.
+func (t *PeerTracker) GetState(peerID []byte) *PeerState {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	state, ok := t.peers[hex.EncodeToString(peerID)]
+	if !ok {
+		return nil
+	}
+
+	s := *state
+	return &s
+}
+
+// WaitOnline waits until the peer is connected, or the deadline passes.
+// Returns ErrPeerTrackerNotSynced if the tracker is not synced, or stops being
+// synced while waiting.
+func (t *PeerTracker) WaitOnline(peerID []byte, deadline time.Time) error {
+	key := hex.EncodeToString(peerID)
+	t.mtx.Lock()
+	if !t.synced {
+		t.mtx.Unlock()
+		return ErrPeerTrackerNotSynced
+	}
+
+	state, ok := t.peers[key]
+	if ok && state.Connected {
+		t.mtx.Unlock()
+		return nil
+	}
+
+	w := make(chan struct{})
+	t.waiters[key] = append(t.waiters[key], w)
+	t.mtx.Unlock()
+
+	select {
+	case <-w:
+		t.mtx.Lock()
+		defer t.mtx.Unlock()
+		if !t.synced {
+			return ErrPeerTrackerNotSynced
+		}
+		return nil
+	case <-time.After(time.Until(deadline)):
+		t.removeWaiter(key, w)
+		return errors.New("deadline exceeded")
+	}
+}
+
+func (t *PeerTracker) removeWaiter(key string, w chan struct{}) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	waiters := t.waiters[key]
+	for i, x := range waiters {
+		if x == w {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+
+	if len(waiters) == 0 {
+		delete(t.waiters, key)
+	} else {
+		t.waiters[key] = waiters
+	}
+}
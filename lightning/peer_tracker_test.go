@@ -0,0 +1,70 @@
+package lightning
+
+import (
+	"encoding/hex"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerTrackerNotSynced(t *testing.T) {
+	tracker := NewPeerTracker(nil, "", nil, log.New(os.Stderr, "", 0))
+	peer, _ := hex.DecodeString("02aa")
+	tracker.SetConnected("02aa", true, "")
+
+	_, ok := tracker.IsConnected(peer)
+	assert.False(t, ok)
+	assert.Equal(t, ErrPeerTrackerNotSynced, tracker.WaitOnline(peer, time.Now().Add(time.Second)))
+}
+
+func TestPeerTrackerSync(t *testing.T) {
+	tracker := NewPeerTracker(nil, "", nil, log.New(os.Stderr, "", 0))
+	peer1, _ := hex.DecodeString("02aa")
+	peer2, _ := hex.DecodeString("02bb")
+	tracker.SetConnected("02aa", true, "")
+	tracker.Sync(map[string]string{"02bb": ""})
+
+	connected, ok := tracker.IsConnected(peer1)
+	assert.True(t, ok)
+	assert.False(t, connected)
+	connected, ok = tracker.IsConnected(peer2)
+	assert.True(t, ok)
+	assert.True(t, connected)
+	assert.False(t, tracker.GetState(peer1).Connected)
+}
+
+func TestPeerTrackerKeepsAddress(t *testing.T) {
+	tracker := NewPeerTracker(nil, "", nil, log.New(os.Stderr, "", 0))
+	peer, _ := hex.DecodeString("02aa")
+	tracker.SetConnected("02aa", true, "1.2.3.4:9735")
+	tracker.SetConnected("02aa", false, "")
+	tracker.Sync(map[string]string{"02aa": ""})
+
+	state := tracker.GetState(peer)
+	assert.True(t, state.Connected)
+	assert.Equal(t, "1.2.3.4:9735", state.Address)
+}
+
+func TestPeerTrackerWaitOnline(t *testing.T) {
+	tracker := NewPeerTracker(nil, "", nil, log.New(os.Stderr, "", 0))
+	peer, _ := hex.DecodeString("02aa")
+	tracker.Sync(nil)
+
+	go func() {
+		<-time.After(10 * time.Millisecond)
+		tracker.SetConnected("02aa", true, "")
+	}()
+	assert.NoError(t, tracker.WaitOnline(peer, time.Now().Add(time.Second)))
+
+	tracker.SetConnected("02aa", false, "")
+	assert.Error(t, tracker.WaitOnline(peer, time.Now().Add(10*time.Millisecond)))
+
+	go func() {
+		<-time.After(10 * time.Millisecond)
+		tracker.Unsync()
+	}()
+	assert.Equal(t, ErrPeerTrackerNotSynced, tracker.WaitOnline(peer, time.Now().Add(time.Second)))
+}
@@ -0,0 +1,120 @@
+package lightning
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// ScidCache caches which peer a short channel id, or alias, belongs to, so
+// resolving the next hop of an htlc doesn't need a call to the node. Unknown
+// scids are cached as well, for a shorter time, so htlcs for unknown channels
+// don't hit the node either. A nil cache caches nothing.
+type ScidCache struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+	mtx         sync.Mutex
+	entries     map[string]*scidCacheEntry
+}
+
+type scidCacheEntry struct {
+	// The peer of the channel, nil if the scid is unknown.
+	peer    []byte
+	expires time.Time
+}
+
+func NewScidCache(ttl time.Duration, negativeTTL time.Duration) *ScidCache {
+	return &ScidCache{
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     make(map[string]*scidCacheEntry),
+	}
+}
+
+// Get returns the cached peer of the scid. ok is false if the scid is not
+// cached, peer is nil if the scid is cached as unknown.
+func (c *ScidCache) Get(scid string) (peer []byte, ok bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	e, ok := c.entries[scid]
+	if !ok {
+		return nil, false
+	}
+
+	if !c.now().Before(e.expires) {
+		delete(c.entries, scid)
+		return nil, false
+	}
+
+	return e.peer, true
+}
+
+// Put caches the peer of the scid, or a nil peer if the scid is unknown.
+func (c *ScidCache) Put(scid string, peer []byte) {
+	if c == nil {
+		return
+	}
+
+	ttl := c.ttl
+	if peer == nil {
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.entries[scid] = &scidCacheEntry{
+		peer:    peer,
+		expires: c.now().Add(ttl),
+	}
+}
+
+// InvalidatePeer removes the scids of the peer, for when its channels change.
+func (c *ScidCache) InvalidatePeer(peerID []byte) {
+	if c == nil {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for scid, e := range c.entries {
+		if bytes.Equal(e.peer, peerID) {
+			delete(c.entries, scid)
+		}
+	}
+}
+
+// InvalidateUnknown removes the scids cached as unknown, for when a channel
+// is opened and an unknown scid may now belong to it.
+func (c *ScidCache) InvalidateUnknown() {
+	if c == nil {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for scid, e := range c.entries {
+		if e.peer == nil {
+			delete(c.entries, scid)
+		}
+	}
+}
+
+// Clear removes all scids, for when channel events may have been missed.
+func (c *ScidCache) Clear() {
+	if c == nil {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.entries = make(map[string]*scidCacheEntry)
+}
@@ -0,0 +1,57 @@
+package lightning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScidCacheExpiry(t *testing.T) {
+	now := time.Now()
+	cache := NewScidCache(time.Minute, time.Second)
+	cache.now = func() time.Time { return now }
+	cache.Put("1x2x3", []byte{0x02, 0xaa})
+	cache.Put("4x5x6", nil)
+
+	peer, ok := cache.Get("1x2x3")
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x02, 0xaa}, peer)
+	peer, ok = cache.Get("4x5x6")
+	assert.True(t, ok)
+	assert.Nil(t, peer)
+
+	now = now.Add(2 * time.Second)
+	_, ok = cache.Get("4x5x6")
+	assert.False(t, ok)
+	_, ok = cache.Get("1x2x3")
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok = cache.Get("1x2x3")
+	assert.False(t, ok)
+}
+
+func TestScidCacheInvalidate(t *testing.T) {
+	cache := NewScidCache(time.Minute, time.Minute)
+	cache.Put("1x2x3", []byte{0x02, 0xaa})
+	cache.Put("1x2x4", []byte{0x02, 0xbb})
+	cache.Put("4x5x6", nil)
+
+	cache.InvalidatePeer([]byte{0x02, 0xaa})
+	_, ok := cache.Get("1x2x3")
+	assert.False(t, ok)
+	_, ok = cache.Get("1x2x4")
+	assert.True(t, ok)
+
+	cache.InvalidateUnknown()
+	_, ok = cache.Get("4x5x6")
+	assert.False(t, ok)
+	_, ok = cache.Get("1x2x4")
+	assert.True(t, ok)
+
+	var disabled *ScidCache
+	disabled.Put("1x2x3", []byte{0x02, 0xaa})
+	_, ok = disabled.Get("1x2x3")
+	assert.False(t, ok)
+}
@@ -0,0 +1,233 @@
+package liquidity
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/chain"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultConsolidationInterval = time.Hour
+	defaultMaxUtxoSat            = 1_000_000
+	defaultMinUtxos              = 10
+	defaultMaxInputs             = 100
+
+	// Estimated size in vbytes of spending a p2wpkh utxo.
+	inputVBytes = 68
+)
+
+var consolidatedUtxos = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_consolidated_utxos_total",
+	Help: "Small wallet utxos consolidated into a single utxo.",
+}, []string{"node"})
+
+// Sweeper spends utxos of the node wallet to a new address of the wallet.
+type Sweeper interface {
+	// ListUtxos returns the unspent outputs of the node wallet.
+	ListUtxos(ctx context.Context) ([]*lightning.Utxo, error)
+	// Sweep spends the utxos, as txid:vout, to a single new output of the
+	// node wallet at the feerate. Returns the txid.
+	Sweep(ctx context.Context, utxos []string, feeSatPerVByte float64) (string, error)
+}
+
+// Consolidator consolidates the small utxos of the node wallet into a single
+// utxo while onchain fees are low. Channel opens then need fewer inputs,
+// which keeps their funding transactions small when fees are high. The utxos
+// coin control keeps from channel opens are never consolidated.
+type Consolidator struct {
+	sweeper      Sweeper
+	feeEstimator chain.FeeEstimator
+	feeStrategy  chain.FeeStrategy
+	coinControl  *lightning.CoinControl
+	node         *config.NodeConfig
+	config       *config.ConsolidationConfig
+	logger       *log.Logger
+	interval     time.Duration
+	maxUtxoSat   uint64
+	minUtxos     int
+	maxInputs    int
+	window       bool
+	windowStart  time.Duration
+	windowEnd    time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+	mtx          sync.Mutex
+}
+
+// NewConsolidator returns the consolidator of the node. With a nil coin
+// control all confirmed utxos can be consolidated. Unconfirmed utxos are
+// never consolidated.
+func NewConsolidator(
+	sweeper Sweeper,
+	feeEstimator chain.FeeEstimator,
+	coinControl *lightning.CoinControl,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) *Consolidator {
+	cfg := node.Consolidation
+	if coinControl == nil {
+		coinControl = &lightning.CoinControl{MinConfs: 1}
+	} else if coinControl.MinConfs == 0 {
+		cc := *coinControl
+		cc.MinConfs = 1
+		coinControl = &cc
+	}
+
+	c := &Consolidator{
+		sweeper:      sweeper,
+		feeEstimator: feeEstimator,
+		feeStrategy:  chain.FeeStrategyEconomy,
+		coinControl:  coinControl,
+		node:         node,
+		config:       cfg,
+		logger:       logger,
+		interval:     parseDuration(cfg.Interval, defaultConsolidationInterval, logger),
+		maxUtxoSat:   defaultMaxUtxoSat,
+		minUtxos:     defaultMinUtxos,
+		maxInputs:    defaultMaxInputs,
+	}
+	if cfg.FeePriority != "" {
+		c.feeStrategy, _ = chain.ParseFeeStrategy(cfg.FeePriority)
+	}
+	if cfg.MaxUtxoSat > 0 {
+		c.maxUtxoSat = cfg.MaxUtxoSat
+	}
+	if cfg.MinUtxos > 0 {
+		c.minUtxos = cfg.MinUtxos
+	}
+	if cfg.MaxInputs > 0 {
+		c.maxInputs = cfg.MaxInputs
+	}
+	if cfg.Window != "" {
+		start, end, err := config.ParseWindow(cfg.Window)
+		if err != nil {
+			logger.Printf("WARN: %v. Consolidating at any time of the day.", err)
+		} else {
+			c.window, c.windowStart, c.windowEnd = true, start, end
+		}
+	}
+
+	return c
+}
+
+func (c *Consolidator) Start() error {
+	c.mtx.Lock()
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	ctx := c.ctx
+	c.mtx.Unlock()
+
+	c.logger.Printf(
+		"consolidation: consolidating utxos up to %v sat at up to %v sat/vbyte, checking every %v",
+		c.maxUtxoSat,
+		c.config.MaxFeeSatPerVByte,
+		c.interval,
+	)
+	for {
+		c.consolidate(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.interval):
+		}
+	}
+}
+
+func (c *Consolidator) Stop() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+// inWindow returns whether now is within the daily consolidation window.
+func (c *Consolidator) inWindow(now time.Time) bool {
+	if !c.window {
+		return true
+	}
+
+	now = now.UTC()
+	sinceMidnight := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+	if c.windowStart < c.windowEnd {
+		return sinceMidnight >= c.windowStart && sinceMidnight < c.windowEnd
+	}
+
+	return sinceMidnight >= c.windowStart || sinceMidnight < c.windowEnd
+}
+
+// smallUtxos returns the spendable utxos up to the maximum utxo value,
+// smallest first, up to the maximum number of inputs. Utxos worth less than
+// the fee of spending them at the feerate are left alone.
+func (c *Consolidator) smallUtxos(utxos []*lightning.Utxo, feeSatPerVByte float64) []*lightning.Utxo {
+	var small []*lightning.Utxo
+	for _, u := range c.coinControl.Spendable(utxos) {
+		if u.AmountSat <= c.maxUtxoSat && float64(u.AmountSat) > inputVBytes*feeSatPerVByte {
+			small = append(small, u)
+		}
+	}
+
+	sort.SliceStable(small, func(i, j int) bool {
+		return small[i].AmountSat < small[j].AmountSat
+	})
+	if len(small) > c.maxInputs {
+		small = small[:c.maxInputs]
+	}
+
+	return small
+}
+
+func (c *Consolidator) consolidate(ctx context.Context, now time.Time) {
+	if !c.inWindow(now) {
+		return
+	}
+
+	fee, err := c.feeEstimator.EstimateFeeRate(ctx, c.feeStrategy)
+	if err != nil {
+		c.logger.Printf("consolidation: EstimateFeeRate() error: %v", err)
+		return
+	}
+	if fee.SatPerVByte > c.config.MaxFeeSatPerVByte {
+		return
+	}
+
+	utxos, err := c.sweeper.ListUtxos(ctx)
+	if err != nil {
+		c.logger.Printf("consolidation: ListUtxos() error: %v", err)
+		return
+	}
+
+	small := c.smallUtxos(utxos, fee.SatPerVByte)
+	if len(small) < c.minUtxos {
+		return
+	}
+
+	var outpoints []string
+	var total uint64
+	for _, u := range small {
+		outpoints = append(outpoints, u.Outpoint)
+		total += u.AmountSat
+	}
+
+	txid, err := c.sweeper.Sweep(ctx, outpoints, fee.SatPerVByte)
+	if err != nil {
+		c.logger.Printf("consolidation: Sweep(%d utxos, %v sat) error: %v", len(outpoints), total, err)
+		return
+	}
+
+	consolidatedUtxos.WithLabelValues(c.node.Label()).Add(float64(len(outpoints)))
+	c.logger.Printf(
+		"consolidation: consolidated %d utxos of %v sat at %v sat/vbyte in transaction %s",
+		len(outpoints),
+		total,
+		fee.SatPerVByte,
+		txid,
+	)
+}
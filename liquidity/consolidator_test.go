@@ -0,0 +1,60 @@
+package liquidity
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/chain"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSweeper struct {
+	utxos []*lightning.Utxo
+	swept [][]string
+}
+
+func (s *mockSweeper) ListUtxos(ctx context.Context) ([]*lightning.Utxo, error) {
+	return s.utxos, nil
+}
+
+func (s *mockSweeper) Sweep(ctx context.Context, utxos []string, feeSatPerVByte float64) (string, error) {
+	s.swept = append(s.swept, utxos)
+	return "txid", nil
+}
+
+func TestConsolidate(t *testing.T) {
+	sweeper := &mockSweeper{utxos: []*lightning.Utxo{
+		{Outpoint: "aa:0", AmountSat: 20_000, Confirmations: 6},
+		{Outpoint: "bb:0", AmountSat: 10_000, Confirmations: 6},
+		{Outpoint: "cc:0", AmountSat: 5_000_000, Confirmations: 6},
+		{Outpoint: "dd:0", AmountSat: 30_000, Confirmations: 0},
+		{Outpoint: "ee:0", AmountSat: 40_000, Confirmations: 6},
+		{Outpoint: "ff:0", AmountSat: 100, Confirmations: 6},
+	}}
+	fees, _ := chain.NewStaticFeeEstimator(2)
+	node := &config.NodeConfig{Consolidation: &config.ConsolidationConfig{
+		MaxFeeSatPerVByte: 3,
+		MinUtxos:          2,
+		Window:            "22:00-04:00",
+	}}
+	cc := &lightning.CoinControl{ExcludeUtxos: []string{"ee:0"}}
+	c := NewConsolidator(sweeper, fees, cc, node, log.New(os.Stderr, "", 0))
+
+	// Outside the window nothing is consolidated.
+	c.consolidate(context.Background(), time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+	assert.Empty(t, sweeper.swept)
+
+	// The large, unconfirmed, excluded and uneconomical utxos are left alone.
+	c.consolidate(context.Background(), time.Date(2023, 1, 1, 23, 0, 0, 0, time.UTC))
+	assert.Equal(t, [][]string{{"bb:0", "aa:0"}}, sweeper.swept)
+
+	// Not above the fee ceiling.
+	node.Consolidation.MaxFeeSatPerVByte = 1
+	c.consolidate(context.Background(), time.Date(2023, 1, 2, 1, 0, 0, 0, time.UTC))
+	assert.Len(t, sweeper.swept, 1)
+}
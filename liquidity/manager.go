@@ -0,0 +1,168 @@
+package liquidity
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/chain"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Estimated size of a channel funding transaction with a single input and
+// change output. Used to reserve the on-chain fee for every channel open.
+const fundingTxVSize = 250
+
+// Balances are cached for this duration, so a burst of RegisterPayment calls
+// doesn't result in a burst of wallet balance calls to the node.
+const refreshInterval = 10 * time.Second
+
+var (
+	confirmedBalanceGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lspd_wallet_confirmed_balance_sat",
+		Help: "Confirmed on-chain wallet balance of the node.",
+	}, []string{"node"})
+	unconfirmedBalanceGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lspd_wallet_unconfirmed_balance_sat",
+		Help: "Unconfirmed on-chain wallet balance of the node.",
+	}, []string{"node"})
+	availableBalanceGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lspd_wallet_available_balance_sat",
+		Help: "On-chain balance available for channel opens after deducting the reserve.",
+	}, []string{"node"})
+)
+
+type Liquidity struct {
+	ConfirmedSat   basetypes.Satoshi `json:"confirmedSat"`
+	UnconfirmedSat basetypes.Satoshi `json:"unconfirmedSat"`
+	ReserveSat     basetypes.Satoshi `json:"reserveSat"`
+	AvailableSat   basetypes.Satoshi `json:"availableSat"`
+	FeeRate        *float64          `json:"feeRate,omitempty"`
+	UpdatedAt      time.Time         `json:"updatedAt"`
+}
+
+// OpenCostSat returns the on-chain amount needed to open a channel with the
+// given capacity at the last known feerate.
+func (l *Liquidity) OpenCostSat(capacitySat basetypes.Satoshi) basetypes.Satoshi {
+	cost := capacitySat
+	if l.FeeRate != nil {
+		cost += basetypes.Satoshi(*l.FeeRate * fundingTxVSize)
+	}
+
+	return cost
+}
+
+// AffordableOpens returns the number of simultaneous channel opens with the
+// given capacity the available balance allows for.
+func (l *Liquidity) AffordableOpens(capacitySat basetypes.Satoshi) uint64 {
+	cost := l.OpenCostSat(capacitySat)
+	if cost == 0 {
+		return 0
+	}
+
+	return uint64(l.AvailableSat / cost)
+}
+
+// Manager tracks the on-chain balance of a node, and decides whether there is
+// enough liquidity left to open new channels while keeping the configured
+// reserve.
+type Manager struct {
+	client       lightning.Client
+	config       *config.NodeConfig
+	feeEstimator chain.FeeEstimator
+	feeStrategy  chain.FeeStrategy
+	logger       *log.Logger
+	mtx          sync.Mutex
+	last         *Liquidity
+}
+
+func NewManager(
+	client lightning.Client,
+	config *config.NodeConfig,
+	feeEstimator chain.FeeEstimator,
+	feeStrategy chain.FeeStrategy,
+	logger *log.Logger,
+) *Manager {
+	return &Manager{
+		client:       client,
+		config:       config,
+		feeEstimator: feeEstimator,
+		feeStrategy:  feeStrategy,
+		logger:       logger,
+	}
+}
+
+func (m *Manager) Node() *config.NodeConfig {
+	return m.config
+}
+
+// Liquidity returns the current liquidity of the node. The result is cached
+// for a short while.
+func (m *Manager) Liquidity() (*Liquidity, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.last != nil && time.Since(m.last.UpdatedAt) < refreshInterval {
+		return m.last, nil
+	}
+
+	balance, err := m.client.GetWalletBalance()
+	if err != nil {
+		return nil, fmt.Errorf("GetWalletBalance() error: %w", err)
+	}
+
+	l := &Liquidity{
+		ConfirmedSat:   balance.ConfirmedSat,
+		UnconfirmedSat: balance.UnconfirmedSat,
+		ReserveSat:     m.config.MinOnchainReserveSat,
+		UpdatedAt:      time.Now(),
+	}
+
+	// Unconfirmed outputs can only be spent if the node is allowed to use
+	// unconfirmed inputs for channel opens.
+	spendable := balance.ConfirmedSat
+	if m.config.MinConfs != nil && *m.config.MinConfs == 0 {
+		spendable += balance.UnconfirmedSat
+	}
+
+	if spendable > l.ReserveSat {
+		l.AvailableSat = spendable - l.ReserveSat
+	}
+
+	if m.feeEstimator != nil {
+		fee, err := m.feeEstimator.EstimateFeeRate(
+			context.Background(),
+			m.feeStrategy,
+		)
+		if err != nil {
+			m.logger.Printf("liquidity: failed to estimate chain fee: %v", err)
+		} else {
+			l.FeeRate = &fee.SatPerVByte
+		}
+	}
+
+	label := m.config.Label()
+	confirmedBalanceGauge.WithLabelValues(label).Set(float64(l.ConfirmedSat))
+	unconfirmedBalanceGauge.WithLabelValues(label).Set(float64(l.UnconfirmedSat))
+	availableBalanceGauge.WithLabelValues(label).Set(float64(l.AvailableSat))
+
+	m.last = l
+	return l, nil
+}
+
+// CanAfford returns whether a channel with the given capacity can be opened
+// without dipping into the reserve.
+func (m *Manager) CanAfford(capacitySat basetypes.Satoshi) (bool, error) {
+	l, err := m.Liquidity()
+	if err != nil {
+		return false, err
+	}
+
+	return l.AffordableOpens(capacitySat) > 0, nil
+}
@@ -0,0 +1,195 @@
+package liquidity
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+)
+
+const (
+	defaultRebalanceInterval = 10 * time.Minute
+	defaultSwapCooldown      = 24 * time.Hour
+)
+
+type SwapChannel struct {
+	ShortChannelID   string
+	LocalBalanceSat  basetypes.Satoshi
+	RemoteBalanceSat basetypes.Satoshi
+	Active           bool
+}
+
+type SwapPeer struct {
+	NodeID       string
+	SwapsAllowed bool
+	Channels     []*SwapChannel
+}
+
+// Swapper performs submarine swaps with channel peers, like the PeerSwap
+// protocol.
+type Swapper interface {
+	ListSwapPeers() ([]*SwapPeer, error)
+	SwapOut(shortChannelID string, amountSat basetypes.Satoshi, asset string) (string, error)
+}
+
+// Rebalancer restores the inbound liquidity of channels with allowlisted
+// peers by swapping out channel balance to the on-chain wallet. Payments to
+// clients drain inbound liquidity from the channels with hubs, while the
+// on-chain wallet is drained by channel opens, so a swap out replenishes both.
+type Rebalancer struct {
+	swapper  Swapper
+	manager  *Manager
+	config   *config.PeerSwapConfig
+	logger   *log.Logger
+	interval time.Duration
+	cooldown time.Duration
+	lastSwap map[string]time.Time
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mtx      sync.Mutex
+}
+
+func NewRebalancer(
+	swapper Swapper,
+	manager *Manager,
+	config *config.PeerSwapConfig,
+	logger *log.Logger,
+) *Rebalancer {
+	interval := parseDuration(config.Interval, defaultRebalanceInterval, logger)
+	cooldown := parseDuration(config.Cooldown, defaultSwapCooldown, logger)
+	return &Rebalancer{
+		swapper:  swapper,
+		manager:  manager,
+		config:   config,
+		logger:   logger,
+		interval: interval,
+		cooldown: cooldown,
+		lastSwap: make(map[string]time.Time),
+	}
+}
+
+func parseDuration(s string, def time.Duration, logger *log.Logger) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		logger.Printf("WARN: Invalid duration '%s'. Using default %v", s, def)
+		return def
+	}
+
+	return d
+}
+
+func (r *Rebalancer) Start() error {
+	r.mtx.Lock()
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	ctx := r.ctx
+	r.mtx.Unlock()
+
+	r.logger.Printf("peerswap: rebalancing channels with %d allowed peers every %v", len(r.config.AllowedPeers), r.interval)
+	for {
+		r.rebalance()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+func (r *Rebalancer) Stop() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+func (r *Rebalancer) isAllowed(nodeID string) bool {
+	for _, p := range r.config.AllowedPeers {
+		if p == nodeID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Rebalancer) rebalance() {
+	// Only swap out while the on-chain wallet is below its target, so swaps
+	// aren't paid for when there is enough liquidity for channel opens.
+	if r.config.TargetOnchainSat > 0 {
+		l, err := r.manager.Liquidity()
+		if err != nil {
+			r.logger.Printf("peerswap: failed to get liquidity: %v", err)
+			return
+		}
+
+		if l.AvailableSat >= r.config.TargetOnchainSat {
+			return
+		}
+	}
+
+	peers, err := r.swapper.ListSwapPeers()
+	if err != nil {
+		r.logger.Printf("peerswap: ListSwapPeers() error: %v", err)
+		return
+	}
+
+	for _, peer := range peers {
+		if !r.isAllowed(peer.NodeID) || !peer.SwapsAllowed {
+			continue
+		}
+
+		for _, c := range peer.Channels {
+			if r.shouldSwapOut(c) {
+				r.swapOut(peer, c)
+			}
+		}
+	}
+}
+
+func (r *Rebalancer) shouldSwapOut(c *SwapChannel) bool {
+	if !c.Active {
+		return false
+	}
+
+	capacity := c.LocalBalanceSat + c.RemoteBalanceSat
+	if capacity == 0 {
+		return false
+	}
+
+	ratio := float64(c.LocalBalanceSat) / float64(capacity)
+	if ratio <= r.config.MaxLocalRatio {
+		return false
+	}
+
+	if c.LocalBalanceSat < r.config.SwapAmountSat {
+		return false
+	}
+
+	last, ok := r.lastSwap[c.ShortChannelID]
+	return !ok || time.Since(last) > r.cooldown
+}
+
+func (r *Rebalancer) swapOut(peer *SwapPeer, c *SwapChannel) {
+	asset := r.config.Asset
+	if asset == "" {
+		asset = "btc"
+	}
+
+	r.lastSwap[c.ShortChannelID] = time.Now()
+	id, err := r.swapper.SwapOut(c.ShortChannelID, r.config.SwapAmountSat, asset)
+	if err != nil {
+		r.logger.Printf("peerswap: SwapOut(%s, %d) with %s error: %v", c.ShortChannelID, r.config.SwapAmountSat, peer.NodeID, err)
+		return
+	}
+
+	r.logger.Printf("peerswap: started swap out %s of %d sat on channel %s with %s (local %d, remote %d)",
+		id, r.config.SwapAmountSat, c.ShortChannelID, peer.NodeID, c.LocalBalanceSat, c.RemoteBalanceSat)
+}
@@ -12,22 +12,29 @@ import (
 	"github.com/breez/lspd/basetypes"
 	"github.com/breez/lspd/config"
 	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/secrets"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/lightningnetwork/lnd/htlcswitch/hop"
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/status"
 )
 
+// Timeout in seconds of an outbound connection attempt to a peer.
+const connectPeerTimeout = 10
+
 type LndClient struct {
 	client              lnrpc.LightningClient
 	routerClient        routerrpc.RouterClient
 	chainNotifierClient chainrpc.ChainNotifierClient
+	walletKitClient     walletrpc.WalletKitClient
 	conn                *grpc.ClientConn
 	listenerCtx         context.Context
 	listenerCancel      context.CancelFunc
@@ -35,10 +42,18 @@ type LndClient struct {
 	chansubs            map[string]map[uint64]chan struct{}
 	submtx              sync.RWMutex
 	index               uint64
+	peers               *lightning.PeerTracker
+	aliases             *lightning.ChannelAliases
+	coinControl         *lightning.CoinControl
+	logger              *log.Logger
 }
 
-func NewLndClient(conf *config.LndConfig) (*LndClient, error) {
-	_, err := hex.DecodeString(conf.Macaroon)
+func NewLndClient(conf *config.LndConfig, resolver *secrets.Resolver, logger *log.Logger) (*LndClient, error) {
+	macaroon, err := resolver.Resolve(context.Background(), conf.Macaroon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load macaroon: %w", err)
+	}
+	_, err = hex.DecodeString(macaroon)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode macaroon: %w", err)
 	}
@@ -49,13 +64,17 @@ func NewLndClient(conf *config.LndConfig) (*LndClient, error) {
 		return nil, fmt.Errorf("credentials: failed to append certificates")
 	}
 	creds := credentials.NewClientTLSFromCert(cp, "")
-	macCred := NewMacaroonCredential(conf.Macaroon)
+	macCred := NewMacaroonCredential(conf.Macaroon, resolver)
 
 	// Address of an LND instance
 	conn, err := grpc.Dial(
 		conf.Address,
 		grpc.WithTransportCredentials(creds),
 		grpc.WithPerRPCCredentials(macCred),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    30 * time.Second,
+			Timeout: 10 * time.Second,
+		}),
 	)
 	if err != nil {
 		log.Fatalf("Failed to connect to LND gRPC: %v", err)
@@ -64,13 +83,16 @@ func NewLndClient(conf *config.LndConfig) (*LndClient, error) {
 	client := lnrpc.NewLightningClient(conn)
 	routerClient := routerrpc.NewRouterClient(conn)
 	chainNotifierClient := chainrpc.NewChainNotifierClient(conn)
+	walletKitClient := walletrpc.NewWalletKitClient(conn)
 	return &LndClient{
 		client:              client,
 		routerClient:        routerClient,
 		chainNotifierClient: chainNotifierClient,
+		walletKitClient:     walletKitClient,
 		conn:                conn,
 		peersubs:            make(map[string]map[uint64]chan struct{}),
 		chansubs:            make(map[string]map[uint64]chan struct{}),
+		logger:              logger,
 	}, nil
 }
 
@@ -82,6 +104,22 @@ func (c *LndClient) Close() {
 	c.conn.Close()
 }
 
+func (c *LndClient) Backend() string {
+	return "lnd"
+}
+
+// SetPeerTracker sets the tracker that is kept up to date with the peer
+// events of the node. Call it before StartListeners.
+func (c *LndClient) SetPeerTracker(peers *lightning.PeerTracker) {
+	c.peers = peers
+}
+
+// SetChannelAliases sets the aliases of the channels of the node, which are
+// refreshed on the channel events of lnd. Call it before StartListeners.
+func (c *LndClient) SetChannelAliases(aliases *lightning.ChannelAliases) {
+	c.aliases = aliases
+}
+
 func (c *LndClient) StartListeners() {
 	c.listenerCtx, c.listenerCancel = context.WithCancel(context.Background())
 	go c.listenPeerEvents()
@@ -100,11 +138,14 @@ func (c *LndClient) listenPeerEvents() {
 			&lnrpc.PeerEventSubscription{},
 		)
 		if err != nil {
-			log.Printf("SubscribePeerEvents: %v", err)
+			c.logger.Printf("SubscribePeerEvents: %v", err)
 			<-time.After(time.Second)
 			continue
 		}
 
+		// Events before the subscription were missed, so sync the connected
+		// peers now.
+		c.syncPeers(ctx)
 		for {
 			if ctx.Err() != nil {
 				return
@@ -114,14 +155,18 @@ func (c *LndClient) listenPeerEvents() {
 			if err != nil {
 				status, ok := status.FromError(err)
 				if ok && status.Code() == codes.Canceled {
-					log.Printf("listenPeerEvents: Got code canceled. Break.")
+					c.logger.Printf("listenPeerEvents: Got code canceled. Break.")
 					break
 				}
 
-				log.Printf("unexpected error in listenPeerEvents: %v", err)
+				c.logger.Printf("unexpected error in listenPeerEvents: %v", err)
 				break
 			}
 
+			if c.peers != nil {
+				c.peers.SetConnected(msg.PubKey, msg.Type == lnrpc.PeerEvent_PEER_ONLINE, "")
+			}
+
 			if msg.Type != lnrpc.PeerEvent_PEER_ONLINE {
 				continue
 			}
@@ -136,10 +181,40 @@ func (c *LndClient) listenPeerEvents() {
 			c.submtx.RUnlock()
 		}
 
+		if c.peers != nil {
+			c.peers.Unsync()
+		}
 		<-time.After(time.Second)
 	}
 }
 
+// syncPeers syncs the peer tracker with the peers connected to the node.
+func (c *LndClient) syncPeers(ctx context.Context) {
+	if c.peers == nil {
+		return
+	}
+
+	peers, err := c.client.ListPeers(ctx, &lnrpc.ListPeersRequest{})
+	if err != nil {
+		c.logger.Printf("syncPeers: client.ListPeers() error: %v", err)
+		c.peers.Unsync()
+		return
+	}
+
+	// The address of an inbound connection is not the address the peer
+	// accepts connections on.
+	connected := make(map[string]string)
+	for _, p := range peers.Peers {
+		address := ""
+		if !p.Inbound {
+			address = p.Address
+		}
+		connected[p.PubKey] = address
+	}
+
+	c.peers.Sync(connected)
+}
+
 func (c *LndClient) listenChannelEvents() {
 	ctx := c.listenerCtx
 	for {
@@ -152,7 +227,7 @@ func (c *LndClient) listenChannelEvents() {
 			&lnrpc.ChannelEventSubscription{},
 		)
 		if err != nil {
-			log.Printf("listenChannelEvents: SubscribeChannelEvents: %v", err)
+			c.logger.Printf("listenChannelEvents: SubscribeChannelEvents: %v", err)
 			<-time.After(time.Second)
 			continue
 		}
@@ -166,14 +241,19 @@ func (c *LndClient) listenChannelEvents() {
 			if err != nil {
 				status, ok := status.FromError(err)
 				if ok && status.Code() == codes.Canceled {
-					log.Printf("listenChannelEvents: Got code canceled. Break.")
+					c.logger.Printf("listenChannelEvents: Got code canceled. Break.")
 					break
 				}
 
-				log.Printf("unexpected error in listenChannelEvents: %v", err)
+				c.logger.Printf("unexpected error in listenChannelEvents: %v", err)
 				break
 			}
 
+			if msg.Type == lnrpc.ChannelEventUpdate_OPEN_CHANNEL && c.aliases != nil {
+				ch := msg.GetOpenChannel()
+				go c.aliases.RefreshChannel(ctx, ch.ChannelPoint)
+			}
+
 			if msg.Type != lnrpc.ChannelEventUpdate_ACTIVE_CHANNEL {
 				continue
 			}
@@ -181,10 +261,14 @@ func (c *LndClient) listenChannelEvents() {
 			ch := msg.GetActiveChannel()
 			point, err := extractChannelPoint(ch)
 			if err != nil {
-				log.Printf("listenChannelEvents: Failed to extract channel point %+v: %v", ch, err)
+				c.logger.Printf("listenChannelEvents: Failed to extract channel point %+v: %v", ch, err)
 				continue
 			}
 
+			if c.aliases != nil {
+				go c.aliases.RefreshChannel(ctx, point)
+			}
+
 			c.submtx.RLock()
 			subs, ok := c.chansubs[point]
 			if ok {
@@ -216,36 +300,62 @@ func extractChannelPoint(cp *lnrpc.ChannelPoint) (string, error) {
 func (c *LndClient) GetInfo() (*lightning.GetInfoResult, error) {
 	info, err := c.client.GetInfo(context.Background(), &lnrpc.GetInfoRequest{})
 	if err != nil {
-		log.Printf("LND: client.GetInfo() error: %v", err)
+		c.logger.Printf("LND: client.GetInfo() error: %v", err)
 		return nil, err
 	}
 
 	return &lightning.GetInfoResult{
-		Alias:  info.Alias,
-		Pubkey: info.IdentityPubkey,
+		Alias:       info.Alias,
+		Pubkey:      info.IdentityPubkey,
+		BlockHeight: info.BlockHeight,
 	}, nil
 }
 
 func (c *LndClient) IsConnected(destination []byte) (bool, error) {
+	if c.peers != nil {
+		if connected, ok := c.peers.IsConnected(destination); ok {
+			return connected, nil
+		}
+	}
+
 	pubkey := hex.EncodeToString(destination)
 
 	r, err := c.client.GetPeerConnected(context.Background(), &lnrpc.GetPeerConnectedRequest{
 		Pubkey: pubkey,
 	})
 	if err != nil {
-		log.Printf("LND: client.GetPeerConnected() error: %v", err)
+		c.logger.Printf("LND: client.GetPeerConnected() error: %v", err)
 		return false, fmt.Errorf("LND: client.GetPeerConnected() error: %w", err)
 	}
 	if r.Connected {
-		log.Printf("LND: destination online: %x", destination)
+		c.logger.Printf("LND: destination online: %x", destination)
 		return true, nil
 	}
 
-	log.Printf("LND: destination offline: %x", destination)
+	c.logger.Printf("LND: destination offline: %x", destination)
 	return false, nil
 }
 
+func (c *LndClient) ConnectPeer(ctx context.Context, peerID []byte, address string) error {
+	_, err := c.client.ConnectPeer(ctx, &lnrpc.ConnectPeerRequest{
+		Addr: &lnrpc.LightningAddress{
+			Pubkey: hex.EncodeToString(peerID),
+			Host:   address,
+		},
+		Timeout: connectPeerTimeout,
+	})
+	if err != nil {
+		return fmt.Errorf("LND: client.ConnectPeer(%x, %s) error: %w", peerID, address, err)
+	}
+
+	return nil
+}
+
 func (c *LndClient) OpenChannel(req *lightning.OpenChannelRequest) (*wire.OutPoint, error) {
+	if c.coinControl != nil {
+		return c.openChannelCoinControl(req)
+	}
+
 	lnReq := &lnrpc.OpenChannelRequest{
 		NodePubkey:         req.Destination,
 		LocalFundingAmount: int64(req.CapacitySat),
@@ -255,6 +365,15 @@ func (c *LndClient) OpenChannel(req *lightning.OpenChannelRequest) (*wire.OutPoi
 		ZeroConf:           req.IsZeroConf,
 	}
 
+	// Request the alias channel type for private zero conf channels, so htlcs
+	// can be forwarded to them before they confirm. lnd rejects it on public
+	// channels.
+	lnReq.ScidAlias = req.IsZeroConf && req.IsPrivate
+
+	if req.CommitmentType != "" {
+		lnReq.CommitmentType = commitmentType(req.CommitmentType)
+	}
+
 	if req.MinConfs != nil {
 		minConfs := *req.MinConfs
 		lnReq.MinConfs = int32(minConfs)
@@ -271,23 +390,117 @@ func (c *LndClient) OpenChannel(req *lightning.OpenChannelRequest) (*wire.OutPoi
 
 	channelPoint, err := c.client.OpenChannelSync(context.Background(), lnReq)
 	if err != nil {
-		log.Printf("LND: client.OpenChannelSync(%x, %v) error: %v", req.Destination, req.CapacitySat, err)
+		c.logger.Printf("LND: client.OpenChannelSync(%x, %v) error: %v", req.Destination, req.CapacitySat, err)
 		return nil, fmt.Errorf("LND: OpenChannel() error: %w", err)
 	}
 
 	result, err := basetypes.NewOutPoint(channelPoint.GetFundingTxidBytes(), channelPoint.OutputIndex)
 	if err != nil {
-		log.Printf("LND: OpenChannel returned invalid outpoint. error: %v", err)
+		c.logger.Printf("LND: OpenChannel returned invalid outpoint. error: %v", err)
 		return nil, err
 	}
 
 	return result, nil
 }
 
+// The value of lnrpc.CommitmentType_SIMPLE_TAPROOT, which is not defined in
+// older lnd versions.
+const commitmentTypeSimpleTaproot lnrpc.CommitmentType = 5
+
+// commitmentType returns the lnd commitment type of t.
+func commitmentType(t lightning.CommitmentType) lnrpc.CommitmentType {
+	switch t {
+	case lightning.CommitmentTypeTaproot:
+		return commitmentTypeSimpleTaproot
+	case lightning.CommitmentTypeStaticRemoteKey:
+		return lnrpc.CommitmentType_STATIC_REMOTE_KEY
+	default:
+		return lnrpc.CommitmentType_ANCHORS
+	}
+}
+
+// channelCommitmentType returns the commitment type of the lnd commitment
+// type, or an empty type for the types lspd doesn't open.
+func channelCommitmentType(t lnrpc.CommitmentType) lightning.CommitmentType {
+	switch t {
+	case commitmentTypeSimpleTaproot:
+		return lightning.CommitmentTypeTaproot
+	case lnrpc.CommitmentType_ANCHORS:
+		return lightning.CommitmentTypeAnchors
+	case lnrpc.CommitmentType_STATIC_REMOTE_KEY:
+		return lightning.CommitmentTypeStaticRemoteKey
+	default:
+		return ""
+	}
+}
+
+// SupportsCommitmentType returns whether both the node and the peer signal
+// support for channels of the commitment type.
+func (c *LndClient) SupportsCommitmentType(peerID []byte, t lightning.CommitmentType) (bool, error) {
+	return c.supportsFeature(peerID, lightning.HasCommitmentTypeFeature(t))
+}
+
+// SupportsLargeChannels returns whether both the node and the peer signal
+// support for channels above the standard capacity limit.
+func (c *LndClient) SupportsLargeChannels(peerID []byte) (bool, error) {
+	return c.supportsFeature(peerID, lightning.HasLargeChannelsFeature)
+}
+
+func (c *LndClient) supportsFeature(peerID []byte, has func(hasBit func(bit uint32) bool) bool) (bool, error) {
+	info, err := c.client.GetInfo(context.Background(), &lnrpc.GetInfoRequest{})
+	if err != nil {
+		c.logger.Printf("client.GetInfo() error: %v", err)
+		return false, err
+	}
+
+	if !has(hasFeature(info.Features)) {
+		return false, nil
+	}
+	if peerID == nil {
+		return true, nil
+	}
+
+	theirs, err := c.PeerFeatures(peerID)
+	if err == lightning.ErrPeerNotConnected {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return has(theirs), nil
+}
+
+// PeerFeatures returns the feature bits the connected peer signals, or
+// ErrPeerNotConnected if it isn't connected.
+func (c *LndClient) PeerFeatures(peerID []byte) (func(bit uint32) bool, error) {
+	peers, err := c.client.ListPeers(context.Background(), &lnrpc.ListPeersRequest{})
+	if err != nil {
+		c.logger.Printf("client.ListPeers() error: %v", err)
+		return nil, err
+	}
+
+	pubkey := hex.EncodeToString(peerID)
+	for _, p := range peers.Peers {
+		if p.PubKey == pubkey {
+			return hasFeature(p.Features), nil
+		}
+	}
+
+	return nil, lightning.ErrPeerNotConnected
+}
+
+func hasFeature(features map[uint32]*lnrpc.Feature) func(bit uint32) bool {
+	return func(bit uint32) bool {
+		_, ok := features[bit]
+		return ok
+	}
+}
+
 func (c *LndClient) GetChannel(peerID []byte, channelPoint wire.OutPoint) (*lightning.GetChannelResult, error) {
 	r, err := c.client.ListChannels(context.Background(), &lnrpc.ListChannelsRequest{Peer: peerID})
 	if err != nil {
-		log.Printf("client.ListChannels(%x) error: %v", peerID, err)
+		c.logger.Printf("client.ListChannels(%x) error: %v", peerID, err)
 		return nil, err
 	}
 
@@ -296,34 +509,34 @@ func (c *LndClient) GetChannel(peerID []byte, channelPoint wire.OutPoint) (*ligh
 		return nil, err
 	}
 
-	for _, c := range r.Channels {
-		log.Printf("getChannel(%x): %v", peerID, c.ChanId)
-		if c.ChannelPoint == channelPointStr && c.Active {
-			confirmedChanId := c.ChanId
-			if c.ZeroConf {
-				confirmedChanId = c.ZeroConfConfirmedScid
+	for _, ch := range r.Channels {
+		c.logger.Printf("getChannel(%x): %v", peerID, ch.ChanId)
+		if ch.ChannelPoint == channelPointStr && ch.Active {
+			confirmedChanId := ch.ChanId
+			if ch.ZeroConf {
+				confirmedChanId = ch.ZeroConfConfirmedScid
 				if confirmedChanId == hop.Source.ToUint64() {
 					confirmedChanId = 0
 				}
 			}
 			return &lightning.GetChannelResult{
-				InitialChannelID:   basetypes.ShortChannelID(c.ChanId),
+				InitialChannelID:   basetypes.ShortChannelID(ch.ChanId),
 				ConfirmedChannelID: basetypes.ShortChannelID(confirmedChanId),
 			}, nil
 		}
 	}
-	log.Printf("No channel found: getChannel(%x)", peerID)
+	c.logger.Printf("No channel found: getChannel(%x)", peerID)
 	return nil, fmt.Errorf("no channel found")
 }
 
-func (c *LndClient) GetNodeChannelCount(nodeID []byte) (int, error) {
+func (c *LndClient) GetNodeChannelCount(ctx context.Context, nodeID []byte) (int, error) {
 	nodeIDStr := hex.EncodeToString(nodeID)
-	listResponse, err := c.client.ListChannels(context.Background(), &lnrpc.ListChannelsRequest{})
+	listResponse, err := c.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
 	if err != nil {
 		return 0, err
 	}
 
-	pendingResponse, err := c.client.PendingChannels(context.Background(), &lnrpc.PendingChannelsRequest{})
+	pendingResponse, err := c.client.PendingChannels(ctx, &lnrpc.PendingChannelsRequest{})
 	if err != nil {
 		return 0, err
 	}
@@ -344,12 +557,75 @@ func (c *LndClient) GetNodeChannelCount(nodeID []byte) (int, error) {
 	return count, nil
 }
 
-func (c *LndClient) GetClosedChannels(nodeID string, channelPoints map[string]uint64) (map[string]uint64, error) {
+// ListPeerChannels returns the open and pending channels with the peer.
+func (c *LndClient) ListPeerChannels(ctx context.Context, peerID []byte) ([]*lightning.PeerChannel, error) {
+	listResponse, err := c.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{
+		Peer: peerID,
+	})
+	if err != nil {
+		c.logger.Printf("client.ListChannels(%x) error: %v", peerID, err)
+		return nil, err
+	}
+
+	pendingResponse, err := c.client.PendingChannels(ctx, &lnrpc.PendingChannelsRequest{})
+	if err != nil {
+		c.logger.Printf("client.PendingChannels() error: %v", err)
+		return nil, err
+	}
+
+	var channels []*lightning.PeerChannel
+	for _, ch := range listResponse.Channels {
+		channels = append(channels, &lightning.PeerChannel{
+			ChannelPoint: ch.ChannelPoint,
+			CapacitySat:  uint64(ch.Capacity),
+		})
+	}
+
+	nodeIDStr := hex.EncodeToString(peerID)
+	for _, p := range pendingResponse.PendingOpenChannels {
+		if p.Channel.RemoteNodePub != nodeIDStr {
+			continue
+		}
+
+		channels = append(channels, &lightning.PeerChannel{
+			ChannelPoint: p.Channel.ChannelPoint,
+			CapacitySat:  uint64(p.Channel.Capacity),
+			Pending:      true,
+		})
+	}
+
+	return channels, nil
+}
+
+// GetMaxLocalBalanceMsat returns the largest local balance of the active
+// channels with the peer, which bounds the size of a htlc to the peer.
+func (c *LndClient) GetMaxLocalBalanceMsat(peerID []byte) (uint64, error) {
+	r, err := c.client.ListChannels(context.Background(), &lnrpc.ListChannelsRequest{
+		ActiveOnly: true,
+		Peer:       peerID,
+	})
+	if err != nil {
+		c.logger.Printf("client.ListChannels(%x) error: %v", peerID, err)
+		return 0, err
+	}
+
+	var max uint64
+	for _, ch := range r.Channels {
+		balance := uint64(ch.LocalBalance) * 1000
+		if balance > max {
+			max = balance
+		}
+	}
+
+	return max, nil
+}
+
+func (c *LndClient) GetClosedChannels(ctx context.Context, nodeID string, channelPoints map[string]uint64) (map[string]uint64, error) {
 	r := make(map[string]uint64)
 	if len(channelPoints) == 0 {
 		return r, nil
 	}
-	waitingCloseChannels, err := c.getWaitingCloseChannels(nodeID)
+	waitingCloseChannels, err := c.getWaitingCloseChannels(ctx, nodeID)
 	if err != nil {
 		return nil, err
 	}
@@ -365,8 +641,8 @@ func (c *LndClient) GetClosedChannels(nodeID string, channelPoints map[string]ui
 	return r, nil
 }
 
-func (c *LndClient) getWaitingCloseChannels(nodeID string) ([]*lnrpc.PendingChannelsResponse_WaitingCloseChannel, error) {
-	pendingResponse, err := c.client.PendingChannels(context.Background(), &lnrpc.PendingChannelsRequest{})
+func (c *LndClient) getWaitingCloseChannels(ctx context.Context, nodeID string) ([]*lnrpc.PendingChannelsResponse_WaitingCloseChannel, error) {
+	pendingResponse, err := c.client.PendingChannels(ctx, &lnrpc.PendingChannelsRequest{})
 	if err != nil {
 		return nil, err
 	}
@@ -526,3 +802,167 @@ func (c *LndClient) WaitChannelActive(peerID []byte, deadline time.Time) error {
 		return fmt.Errorf("deadline exceeded")
 	}
 }
+
+func (c *LndClient) GetWalletBalance() (*lightning.GetWalletBalanceResult, error) {
+	if c.coinControl != nil {
+		return c.coinControlBalance()
+	}
+
+	r, err := c.client.WalletBalance(context.Background(), &lnrpc.WalletBalanceRequest{})
+	if err != nil {
+		c.logger.Printf("LND: client.WalletBalance() error: %v", err)
+		return nil, fmt.Errorf("LND: client.WalletBalance() error: %w", err)
+	}
+
+	return &lightning.GetWalletBalanceResult{
+		ConfirmedSat:   basetypes.Satoshi(r.ConfirmedBalance),
+		UnconfirmedSat: basetypes.Satoshi(r.UnconfirmedBalance),
+	}, nil
+}
+
+// GetChannelActivity returns the total amount of payments settled over the
+// active channel in both directions, in millisatoshi.
+func (c *LndClient) GetChannelActivity(peerID []byte, channelPoint wire.OutPoint) (uint64, error) {
+	r, err := c.client.ListChannels(context.Background(), &lnrpc.ListChannelsRequest{
+		ActiveOnly: true,
+		Peer:       peerID,
+	})
+	if err != nil {
+		c.logger.Printf("client.ListChannels(%x) error: %v", peerID, err)
+		return 0, err
+	}
+
+	channelPointStr := channelPoint.String()
+	for _, ch := range r.Channels {
+		if ch.ChannelPoint == channelPointStr {
+			return uint64(ch.TotalSatoshisSent+ch.TotalSatoshisReceived) * 1000, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no active channel found")
+}
+
+// CloseChannel cooperatively closes the channel. It returns the txid of the
+// closing transaction once it's published.
+func (c *LndClient) CloseChannel(peerID []byte, channelPoint wire.OutPoint) (string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.client.CloseChannel(ctx, &lnrpc.CloseChannelRequest{
+		ChannelPoint: &lnrpc.ChannelPoint{
+			FundingTxid: &lnrpc.ChannelPoint_FundingTxidStr{
+				FundingTxidStr: channelPoint.Hash.String(),
+			},
+			OutputIndex: channelPoint.Index,
+		},
+	})
+	if err != nil {
+		c.logger.Printf("LND: client.CloseChannel(%x, %v) error: %v", peerID, channelPoint.String(), err)
+		return "", fmt.Errorf("LND: CloseChannel() error: %w", err)
+	}
+
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			return "", fmt.Errorf("LND: CloseChannel() stream error: %w", err)
+		}
+
+		if pending := update.GetClosePending(); pending != nil {
+			txid, err := chainhash.NewHash(pending.Txid)
+			if err != nil {
+				return "", fmt.Errorf("LND: invalid closing txid: %w", err)
+			}
+
+			return txid.String(), nil
+		}
+	}
+}
+
+// SetChannelPolicy updates the routing policy of the node for the channel.
+func (c *LndClient) SetChannelPolicy(peerID []byte, channelPoint wire.OutPoint, policy *lightning.ChannelPolicy) error {
+	resp, err := c.client.UpdateChannelPolicy(context.Background(), &lnrpc.PolicyUpdateRequest{
+		Scope: &lnrpc.PolicyUpdateRequest_ChanPoint{
+			ChanPoint: &lnrpc.ChannelPoint{
+				FundingTxid: &lnrpc.ChannelPoint_FundingTxidStr{
+					FundingTxidStr: channelPoint.Hash.String(),
+				},
+				OutputIndex: channelPoint.Index,
+			},
+		},
+		BaseFeeMsat:          int64(policy.BaseFeeMsat),
+		FeeRate:              policy.FeeRate,
+		TimeLockDelta:        policy.TimeLockDelta,
+		MaxHtlcMsat:          uint64(policy.MaxHtlcMsat),
+		MinHtlcMsat:          uint64(policy.MinHtlcMsat),
+		MinHtlcMsatSpecified: policy.MinHtlcMsat > 0,
+	})
+	if err != nil {
+		c.logger.Printf("LND: client.UpdateChannelPolicy(%v) error: %v", channelPoint.String(), err)
+		return fmt.Errorf("LND: UpdateChannelPolicy() error: %w", err)
+	}
+
+	for _, f := range resp.FailedUpdates {
+		return fmt.Errorf("LND: UpdateChannelPolicy(%v) failed: %s", channelPoint.String(), f.UpdateError)
+	}
+
+	return nil
+}
+
+// ListForwards returns the settled forwards of the node since the given time.
+func (c *LndClient) ListForwards(ctx context.Context, since time.Time) ([]*lightning.Forward, error) {
+	var result []*lightning.Forward
+	endTime := uint64(time.Now().Add(time.Hour).Unix())
+	indexOffset := uint32(0)
+	for {
+		resp, err := c.client.ForwardingHistory(ctx, &lnrpc.ForwardingHistoryRequest{
+			StartTime:    uint64(since.Unix()),
+			EndTime:      endTime,
+			IndexOffset:  indexOffset,
+			NumMaxEvents: 10000,
+		})
+		if err != nil {
+			c.logger.Printf("LND: client.ForwardingHistory() error: %v", err)
+			return nil, fmt.Errorf("LND: ForwardingHistory() error: %w", err)
+		}
+
+		for _, e := range resp.ForwardingEvents {
+			result = append(result, &lightning.Forward{
+				Timestamp:     time.Unix(0, int64(e.TimestampNs)),
+				ChanIDIn:      basetypes.ShortChannelID(e.ChanIdIn),
+				ChanIDOut:     basetypes.ShortChannelID(e.ChanIdOut),
+				AmountInMsat:  e.AmtInMsat,
+				AmountOutMsat: e.AmtOutMsat,
+			})
+		}
+
+		if len(resp.ForwardingEvents) == 0 || resp.LastOffsetIndex == indexOffset {
+			return result, nil
+		}
+		indexOffset = resp.LastOffsetIndex
+	}
+}
+
+// ListPendingHtlcs returns the htlcs in flight over the channels with the
+// peer.
+func (c *LndClient) ListPendingHtlcs(ctx context.Context, peerID []byte) ([]*lightning.PendingHtlc, error) {
+	r, err := c.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{
+		Peer: peerID,
+	})
+	if err != nil {
+		c.logger.Printf("client.ListChannels(%x) error: %v", peerID, err)
+		return nil, fmt.Errorf("LND: ListChannels(%x) error: %w", peerID, err)
+	}
+
+	var result []*lightning.PendingHtlc
+	for _, ch := range r.Channels {
+		for _, h := range ch.PendingHtlcs {
+			result = append(result, &lightning.PendingHtlc{
+				ChannelPoint: ch.ChannelPoint,
+				PaymentHash:  h.HashLock,
+				AmountMsat:   uint64(h.Amount) * 1000,
+				Incoming:     h.Incoming,
+			})
+		}
+	}
+
+	return result, nil
+}
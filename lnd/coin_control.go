@@ -0,0 +1,183 @@
+package lnd
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/lightning"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+)
+
+// Confirmation target of the funding transaction when the open has neither a
+// fee rate nor a target, the default of lnd.
+const defaultFundingTargetConf = 6
+
+// SetCoinControl makes the node fund channel opens with the utxos coin
+// control selects, from the wallet account of the coin control. lnd doesn't
+// take inputs for a channel open, so the channel is opened with a psbt
+// funding shim that the wallet funds and signs.
+func (c *LndClient) SetCoinControl(cc *lightning.CoinControl) {
+	c.coinControl = cc
+}
+
+// listUtxos returns the utxos of the wallet account. Outputs leased by lnd
+// aren't listed.
+func (c *LndClient) listUtxos(ctx context.Context, account string) ([]*lightning.Utxo, error) {
+	r, err := c.client.ListUnspent(ctx, &lnrpc.ListUnspentRequest{
+		MinConfs: 0,
+		MaxConfs: math.MaxInt32,
+		Account:  account,
+	})
+	if err != nil {
+		c.logger.Printf("LND: client.ListUnspent(%s) error: %v", account, err)
+		return nil, fmt.Errorf("LND: client.ListUnspent() error: %w", err)
+	}
+
+	var utxos []*lightning.Utxo
+	for _, u := range r.Utxos {
+		if u.Outpoint == nil {
+			continue
+		}
+
+		utxos = append(utxos, &lightning.Utxo{
+			Outpoint:      fmt.Sprintf("%s:%d", outPointTxid(u.Outpoint), u.Outpoint.OutputIndex),
+			AmountSat:     uint64(u.AmountSat),
+			Confirmations: uint32(u.Confirmations),
+		})
+	}
+
+	return utxos, nil
+}
+
+// openChannelCoinControl opens the channel with a psbt funding shim, funded
+// with the selected utxos. The utxos are released if the open fails after
+// they were locked.
+func (c *LndClient) openChannelCoinControl(req *lightning.OpenChannelRequest) (*wire.OutPoint, error) {
+	var locked []*walletrpc.UtxoLease
+	channelPoint, err := c.OpenChannelPsbt(req, func(fundingAddress string, amountSat uint64, psbt []byte) ([]byte, error) {
+		var signed []byte
+		var err error
+		signed, locked, err = c.fundFromWallet(fundingAddress, amountSat, req)
+		return signed, err
+	})
+	if err != nil {
+		c.releaseOutputs(locked)
+		return nil, err
+	}
+
+	return channelPoint, nil
+}
+
+func (c *LndClient) fundFromWallet(
+	fundingAddress string,
+	amountSat uint64,
+	req *lightning.OpenChannelRequest,
+) ([]byte, []*walletrpc.UtxoLease, error) {
+	ctx := context.Background()
+	utxos, err := c.listUtxos(ctx, c.coinControl.Account)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selected, err := c.coinControl.SelectUtxos(utxos, amountSat, req.FeeSatPerVByte)
+	if err != nil {
+		c.logger.Printf("LND: no utxos to fund %v sat to %s: %v", amountSat, fundingAddress, err)
+		return nil, nil, err
+	}
+
+	var inputs []*lnrpc.OutPoint
+	for _, u := range selected {
+		outPoint, err := basetypes.ParseOutPoint(u.Outpoint)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		inputs = append(inputs, &lnrpc.OutPoint{
+			TxidStr:     outPoint.Hash.String(),
+			OutputIndex: outPoint.Index,
+		})
+	}
+
+	fundReq := &walletrpc.FundPsbtRequest{
+		Template: &walletrpc.FundPsbtRequest_Raw{
+			Raw: &walletrpc.TxTemplate{
+				Inputs:  inputs,
+				Outputs: map[string]uint64{fundingAddress: amountSat},
+			},
+		},
+		Account:          c.coinControl.Account,
+		MinConfs:         int32(c.coinControl.MinConfs),
+		SpendUnconfirmed: c.coinControl.MinConfs == 0,
+	}
+	if req.FeeSatPerVByte != nil {
+		fundReq.Fees = &walletrpc.FundPsbtRequest_SatPerVbyte{SatPerVbyte: uint64(*req.FeeSatPerVByte)}
+	} else if req.TargetConf != nil {
+		fundReq.Fees = &walletrpc.FundPsbtRequest_TargetConf{TargetConf: *req.TargetConf}
+	} else {
+		fundReq.Fees = &walletrpc.FundPsbtRequest_TargetConf{TargetConf: defaultFundingTargetConf}
+	}
+
+	funded, err := c.walletKitClient.FundPsbt(ctx, fundReq)
+	if err != nil {
+		c.logger.Printf("LND: walletKitClient.FundPsbt(%s, %v) error: %v", fundingAddress, amountSat, err)
+		return nil, nil, fmt.Errorf("LND: FundPsbt() error: %w", err)
+	}
+
+	if funded.ChangeOutputIndex < 0 && c.coinControl.MinChangeSat > 0 {
+		c.releaseOutputs(funded.LockedUtxos)
+		return nil, nil, fmt.Errorf("LND: funding transaction of %v sat has no change output", amountSat)
+	}
+
+	signed, err := c.walletKitClient.FinalizePsbt(ctx, &walletrpc.FinalizePsbtRequest{
+		FundedPsbt: funded.FundedPsbt,
+		Account:    c.coinControl.Account,
+	})
+	if err != nil {
+		c.logger.Printf("LND: walletKitClient.FinalizePsbt(%s, %v) error: %v", fundingAddress, amountSat, err)
+		c.releaseOutputs(funded.LockedUtxos)
+		return nil, nil, fmt.Errorf("LND: FinalizePsbt() error: %w", err)
+	}
+
+	return signed.SignedPsbt, funded.LockedUtxos, nil
+}
+
+func (c *LndClient) releaseOutputs(leases []*walletrpc.UtxoLease) {
+	for _, l := range leases {
+		_, err := c.walletKitClient.ReleaseOutput(context.Background(), &walletrpc.ReleaseOutputRequest{
+			Id:       l.Id,
+			Outpoint: l.Outpoint,
+		})
+		if err != nil {
+			c.logger.Printf("LND: failed to release output %s:%d: %v", outPointTxid(l.Outpoint), l.Outpoint.GetOutputIndex(), err)
+		}
+	}
+}
+
+// outPointTxid returns the txid of the outpoint, which lnd sets either as
+// string or as bytes.
+func outPointTxid(o *lnrpc.OutPoint) string {
+	if o.GetTxidStr() != "" {
+		return o.GetTxidStr()
+	}
+
+	h, err := chainhash.NewHash(o.GetTxidBytes())
+	if err != nil {
+		return ""
+	}
+
+	return h.String()
+}
+
+func (c *LndClient) coinControlBalance() (*lightning.GetWalletBalanceResult, error) {
+	utxos, err := c.listUtxos(context.Background(), c.coinControl.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.coinControl.Balance(utxos), nil
+}
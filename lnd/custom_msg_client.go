@@ -0,0 +1,114 @@
+package lnd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/breez/lspd/lightning"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Number of received custom messages buffered until they are read with Recv.
+const customMsgQueueSize = 10000
+
+// CustomMsgClient receives and sends the custom messages of the node with
+// SubscribeCustomMessages and SendCustomMessage of lnd. lnd only delivers
+// custom message types it is configured to allow, see the
+// protocol.custom-message option of lnd.
+type CustomMsgClient struct {
+	client    *LndClient
+	recvQueue chan *lightning.CustomMessage
+	ctx       context.Context
+	cancel    context.CancelFunc
+	logger    *log.Logger
+}
+
+// NewCustomMsgClient returns a client of the custom messages of lnd.
+func (c *LndClient) NewCustomMsgClient() lightning.CustomMsgClient {
+	return NewCustomMsgClient(c, c.logger)
+}
+
+func NewCustomMsgClient(client *LndClient, logger *log.Logger) *CustomMsgClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CustomMsgClient{
+		client:    client,
+		recvQueue: make(chan *lightning.CustomMessage, customMsgQueueSize),
+		ctx:       ctx,
+		cancel:    cancel,
+		logger:    logger,
+	}
+}
+
+// Start pushes the custom messages of lnd to the receive queue until the
+// client is stopped, resubscribing when the stream breaks.
+func (c *CustomMsgClient) Start() error {
+	ctx := c.ctx
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		sub, err := c.client.client.SubscribeCustomMessages(
+			ctx,
+			&lnrpc.SubscribeCustomMessagesRequest{},
+		)
+		if err != nil {
+			c.logger.Printf("SubscribeCustomMessages: %v", err)
+			<-time.After(time.Second)
+			continue
+		}
+
+		for {
+			msg, err := sub.Recv()
+			if err != nil {
+				status, ok := status.FromError(err)
+				if !ok || status.Code() != codes.Canceled {
+					c.logger.Printf("unexpected error in SubscribeCustomMessages: %v", err)
+				}
+				break
+			}
+
+			select {
+			case c.recvQueue <- &lightning.CustomMessage{
+				PeerID: msg.Peer,
+				Type:   msg.Type,
+				Data:   msg.Data,
+			}:
+			default:
+				c.logger.Printf("Custom message queue is full, dropping message of type %d from %x", msg.Type, msg.Peer)
+			}
+		}
+
+		<-time.After(time.Second)
+	}
+}
+
+func (c *CustomMsgClient) Recv() (*lightning.CustomMessage, error) {
+	select {
+	case msg := <-c.recvQueue:
+		return msg, nil
+	case <-c.ctx.Done():
+		return nil, lightning.ErrCustomMsgClientStopped
+	}
+}
+
+func (c *CustomMsgClient) Send(msg *lightning.CustomMessage) error {
+	_, err := c.client.client.SendCustomMessage(c.ctx, &lnrpc.SendCustomMessageRequest{
+		Peer: msg.PeerID,
+		Type: msg.Type,
+		Data: msg.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("LND: SendCustomMessage(%x, %d) error: %w", msg.PeerID, msg.Type, err)
+	}
+
+	return nil
+}
+
+func (c *CustomMsgClient) Stop() {
+	c.cancel()
+}
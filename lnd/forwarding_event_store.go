@@ -1,5 +1,7 @@
 package lnd
 
+import "context"
+
 type CopyFromSource interface {
 	Next() bool
 	Values() ([]interface{}, error)
@@ -7,6 +9,6 @@ type CopyFromSource interface {
 }
 
 type ForwardingEventStore interface {
-	LastForwardingEvent() (int64, error)
-	InsertForwardingEvents(rowSrc CopyFromSource) error
+	LastForwardingEvent(ctx context.Context, lspNodeID []byte) (int64, error)
+	InsertForwardingEvents(ctx context.Context, lspNodeID []byte, rowSrc CopyFromSource) error
 }
@@ -7,7 +7,9 @@ import (
 	"log"
 	"time"
 
+	"github.com/breez/lspd/config"
 	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/tenant"
 	"github.com/lightningnetwork/lnd/htlcswitch/hop"
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
@@ -41,17 +43,23 @@ type ForwardingHistorySync struct {
 	client          *LndClient
 	interceptStore  interceptor.InterceptStore
 	forwardingStore ForwardingEventStore
+	config          *config.NodeConfig
+	logger          *log.Logger
 }
 
 func NewForwardingHistorySync(
 	client *LndClient,
 	interceptStore interceptor.InterceptStore,
 	forwardingStore ForwardingEventStore,
+	config *config.NodeConfig,
+	logger *log.Logger,
 ) *ForwardingHistorySync {
 	return &ForwardingHistorySync{
 		client:          client,
 		interceptStore:  interceptStore,
 		forwardingStore: forwardingStore,
+		config:          config,
+		logger:          logger,
 	}
 }
 
@@ -64,7 +72,7 @@ func (s *ForwardingHistorySync) ChannelsSynchronize(ctx context.Context) {
 
 		stream, err := s.client.chainNotifierClient.RegisterBlockEpochNtfn(ctx, &chainrpc.BlockEpoch{})
 		if err != nil {
-			log.Printf("chainNotifierClient.RegisterBlockEpochNtfn(): %v", err)
+			s.logger.Printf("chainNotifierClient.RegisterBlockEpochNtfn(): %v", err)
 			<-time.After(time.Second)
 			continue
 		}
@@ -76,7 +84,7 @@ func (s *ForwardingHistorySync) ChannelsSynchronize(ctx context.Context) {
 
 			_, err := stream.Recv()
 			if err != nil {
-				log.Printf("stream.Recv: %v", err)
+				s.logger.Printf("stream.Recv: %v", err)
 				<-time.After(time.Second)
 				break
 			}
@@ -88,25 +96,31 @@ func (s *ForwardingHistorySync) ChannelsSynchronize(ctx context.Context) {
 				}
 				err = s.ChannelsSynchronizeOnce()
 				lastSync = time.Now()
-				log.Printf("channelsSynchronizeOnce() err: %v", err)
+				s.logger.Printf("channelsSynchronizeOnce() err: %v", err)
 			}
 		}
 	}
 }
 
 func (s *ForwardingHistorySync) ChannelsSynchronizeOnce() error {
-	log.Printf("channelsSynchronizeOnce - begin")
+	s.logger.Printf("channelsSynchronizeOnce - begin")
 	channels, err := s.client.client.ListChannels(context.Background(), &lnrpc.ListChannelsRequest{PrivateOnly: true})
 	if err != nil {
-		log.Printf("ListChannels error: %v", err)
+		s.logger.Printf("ListChannels error: %v", err)
 		return fmt.Errorf("client.ListChannels() error: %w", err)
 	}
-	log.Printf("channelsSynchronizeOnce - received channels")
+	s.logger.Printf("channelsSynchronizeOnce - received channels")
+	lspNodeID, err := hex.DecodeString(s.config.NodePubkey)
+	if err != nil {
+		return fmt.Errorf("hex.DecodeString(%s) error: %w", s.config.NodePubkey, err)
+	}
+
+	ctx := tenant.NewContext(context.Background(), s.config.Tenant)
 	lastUpdate := time.Now()
 	for _, c := range channels.Channels {
 		nodeID, err := hex.DecodeString(c.RemotePubkey)
 		if err != nil {
-			log.Printf("hex.DecodeString in channelsSynchronizeOnce error: %v", err)
+			s.logger.Printf("hex.DecodeString in channelsSynchronizeOnce error: %v", err)
 			continue
 		}
 		confirmedChanId := c.ChanId
@@ -116,13 +130,13 @@ func (s *ForwardingHistorySync) ChannelsSynchronizeOnce() error {
 				confirmedChanId = 0
 			}
 		}
-		err = s.interceptStore.InsertChannel(c.ChanId, confirmedChanId, c.ChannelPoint, nodeID, lastUpdate)
+		err = s.interceptStore.InsertChannel(ctx, lspNodeID, c.ChanId, confirmedChanId, c.ChannelPoint, nodeID, channelCommitmentType(c.CommitmentType), lastUpdate)
 		if err != nil {
-			log.Printf("insertChannel(%v, %v, %x) in channelsSynchronizeOnce error: %v", c.ChanId, c.ChannelPoint, nodeID, err)
+			s.logger.Printf("insertChannel(%v, %v, %x) in channelsSynchronizeOnce error: %v", c.ChanId, c.ChannelPoint, nodeID, err)
 			continue
 		}
 	}
-	log.Printf("channelsSynchronizeOnce - done")
+	s.logger.Printf("channelsSynchronizeOnce - done")
 
 	return nil
 }
@@ -134,7 +148,7 @@ func (s *ForwardingHistorySync) ForwardingHistorySynchronize(ctx context.Context
 		}
 
 		err := s.ForwardingHistorySynchronizeOnce()
-		log.Printf("forwardingHistorySynchronizeOnce() err: %v", err)
+		s.logger.Printf("forwardingHistorySynchronizeOnce() err: %v", err)
 		select {
 		case <-time.After(1 * time.Minute):
 		case <-ctx.Done():
@@ -143,16 +157,22 @@ func (s *ForwardingHistorySync) ForwardingHistorySynchronize(ctx context.Context
 }
 
 func (s *ForwardingHistorySync) ForwardingHistorySynchronizeOnce() error {
-	last, err := s.forwardingStore.LastForwardingEvent()
+	lspNodeID, err := hex.DecodeString(s.config.NodePubkey)
+	if err != nil {
+		return fmt.Errorf("hex.DecodeString(%s) error: %w", s.config.NodePubkey, err)
+	}
+
+	ctx := tenant.NewContext(context.Background(), s.config.Tenant)
+	last, err := s.forwardingStore.LastForwardingEvent(ctx, lspNodeID)
 	if err != nil {
 		return fmt.Errorf("lastForwardingEvent() error: %w", err)
 	}
-	log.Printf("last1: %v", last)
+	s.logger.Printf("last1: %v", last)
 	last = last/1_000_000_000 - 1*3600
 	if last <= 0 {
 		last = 1
 	}
-	log.Printf("last2: %v", last)
+	s.logger.Printf("last2: %v", last)
 	now := time.Now()
 	endTime := uint64(now.Add(time.Hour * 24).Unix())
 	indexOffset := uint32(0)
@@ -164,18 +184,18 @@ func (s *ForwardingHistorySync) ForwardingHistorySynchronizeOnce() error {
 			IndexOffset:  indexOffset,
 		})
 		if err != nil {
-			log.Printf("ForwardingHistory error: %v", err)
+			s.logger.Printf("ForwardingHistory error: %v", err)
 			return fmt.Errorf("client.ForwardingHistory() error: %w", err)
 		}
-		log.Printf("Offset: %v, Events: %v", indexOffset, len(forwardHistory.ForwardingEvents))
+		s.logger.Printf("Offset: %v, Events: %v", indexOffset, len(forwardHistory.ForwardingEvents))
 		if len(forwardHistory.ForwardingEvents) == 0 {
 			break
 		}
 		indexOffset = forwardHistory.LastOffsetIndex
 		cfe := copyFromEvents{events: forwardHistory.ForwardingEvents, idx: -1}
-		err = s.forwardingStore.InsertForwardingEvents(&cfe)
+		err = s.forwardingStore.InsertForwardingEvents(ctx, lspNodeID, &cfe)
 		if err != nil {
-			log.Printf("insertForwardingEvents() error: %v", err)
+			s.logger.Printf("insertForwardingEvents() error: %v", err)
 			return fmt.Errorf("insertForwardingEvents() error: %w", err)
 		}
 	}
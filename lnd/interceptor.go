@@ -5,11 +5,13 @@ import (
 	"context"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/breez/lspd/basetypes"
 	"github.com/breez/lspd/config"
 	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/streamwatch"
 	"github.com/btcsuite/btcd/btcec/v2"
 	sphinx "github.com/lightningnetwork/lightning-onion"
 	"github.com/lightningnetwork/lnd/lnrpc"
@@ -29,8 +31,11 @@ type LndHtlcInterceptor struct {
 	stopRequested bool
 	initWg        sync.WaitGroup
 	doneWg        sync.WaitGroup
+	connected     atomic.Bool
+	watchdog      *streamwatch.Watchdog
 	ctx           context.Context
 	cancel        context.CancelFunc
+	logger        *log.Logger
 }
 
 func NewLndHtlcInterceptor(
@@ -38,13 +43,16 @@ func NewLndHtlcInterceptor(
 	client *LndClient,
 	fwsync *ForwardingHistorySync,
 	interceptor *interceptor.Interceptor,
+	logger *log.Logger,
 ) (*LndHtlcInterceptor, error) {
 	i := &LndHtlcInterceptor{
 		config:      conf,
 		client:      client,
 		fwsync:      fwsync,
 		interceptor: interceptor,
+		logger:      logger,
 	}
+	i.watchdog = streamwatch.NewWatchdog(conf, "htlc", i.probe, logger)
 
 	i.initWg.Add(1)
 
@@ -56,8 +64,8 @@ func (i *LndHtlcInterceptor) Start() error {
 	i.ctx = ctx
 	i.cancel = cancel
 	i.stopRequested = false
-	go i.fwsync.ForwardingHistorySynchronize(ctx)
-	go i.fwsync.ChannelsSynchronize(ctx)
+	streamwatch.Go(i.config.Label(), "forwarding_sync", func() { i.fwsync.ForwardingHistorySynchronize(ctx) })
+	streamwatch.Go(i.config.Label(), "channel_sync", func() { i.fwsync.ChannelsSynchronize(ctx) })
 
 	return i.intercept()
 }
@@ -78,13 +86,24 @@ func (i *LndHtlcInterceptor) WaitStarted() {
 	i.initWg.Wait()
 }
 
+func (i *LndHtlcInterceptor) Alive() bool {
+	return i.connected.Load()
+}
+
+// probe checks lnd still answers on the connection the htlc stream runs on.
+func (i *LndHtlcInterceptor) probe(ctx context.Context) error {
+	_, err := i.client.client.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	return err
+}
+
 func (i *LndHtlcInterceptor) intercept() error {
 	inited := false
 	defer func() {
+		i.connected.Store(false)
 		if !inited {
 			i.initWg.Done()
 		}
-		log.Printf("LND intercept(): stopping. Waiting for in-progress interceptions to complete.")
+		i.logger.Printf("LND intercept(): stopping. Waiting for in-progress interceptions to complete.")
 		i.doneWg.Wait()
 	}()
 
@@ -93,16 +112,20 @@ func (i *LndHtlcInterceptor) intercept() error {
 			return i.ctx.Err()
 		}
 
-		log.Printf("Connecting LND HTLC interceptor.")
-		interceptorClient, err := i.client.routerClient.HtlcInterceptor(i.ctx)
+		i.logger.Printf("Connecting LND HTLC interceptor.")
+		streamCtx, cancelStream := i.watchdog.Watch(i.ctx)
+		interceptorClient, err := i.client.routerClient.HtlcInterceptor(streamCtx)
 		if err != nil {
-			log.Printf("routerClient.HtlcInterceptor(): %v", err)
+			cancelStream()
+			i.logger.Printf("routerClient.HtlcInterceptor(): %v", err)
 			<-time.After(time.Second)
 			continue
 		}
 
+		i.connected.Store(true)
 		for {
 			if i.ctx.Err() != nil {
+				cancelStream()
 				return i.ctx.Err()
 			}
 
@@ -124,18 +147,30 @@ func (i *LndHtlcInterceptor) intercept() error {
 				// the we exit silently.
 				status, ok := status.FromError(err)
 				if ok && status.Code() == codes.Canceled {
-					log.Printf("Got code canceled. Break.")
+					i.logger.Printf("Got code canceled. Break.")
 					break
 				}
 
 				// Otherwise it an unexpected error, we fail the test.
-				log.Printf("unexpected error in interceptor.Recv() %v", err)
+				i.logger.Printf("unexpected error in interceptor.Recv() %v", err)
 				break
 			}
 
+			i.watchdog.Touch()
+			scid := basetypes.ShortChannelID(request.OutgoingRequestedChanId)
+			if i.config.Lnd.InterceptLspdScidsOnly && !i.interceptor.IsLspdScid(scid) {
+				interceptorClient.Send(&routerrpc.ForwardHtlcInterceptResponse{
+					IncomingCircuitKey:      request.IncomingCircuitKey,
+					Action:                  routerrpc.ResolveHoldForwardAction_RESUME,
+					OutgoingAmountMsat:      request.OutgoingAmountMsat,
+					OutgoingRequestedChanId: request.OutgoingRequestedChanId,
+					OnionBlob:               request.OnionBlob,
+				})
+				continue
+			}
+
 			i.doneWg.Add(1)
-			go func() {
-				scid := basetypes.ShortChannelID(request.OutgoingRequestedChanId)
+			streamwatch.Go(i.config.Label(), "htlc_handler", func() {
 				interceptResult := i.interceptor.Intercept(&scid, request.PaymentHash, request.OutgoingAmountMsat, request.OutgoingExpiry, request.IncomingExpiry)
 				switch interceptResult.Action {
 				case interceptor.INTERCEPT_RESUME_WITH_ONION:
@@ -144,7 +179,7 @@ func (i *LndHtlcInterceptor) intercept() error {
 						interceptorClient.Send(&routerrpc.ForwardHtlcInterceptResponse{
 							IncomingCircuitKey:      request.IncomingCircuitKey,
 							Action:                  routerrpc.ResolveHoldForwardAction_RESUME,
-							OutgoingAmountMsat:      interceptResult.AmountMsat,
+							OutgoingAmountMsat:      uint64(interceptResult.AmountMsat),
 							OutgoingRequestedChanId: uint64(interceptResult.ChannelId),
 							OnionBlob:               onion,
 						})
@@ -156,6 +191,15 @@ func (i *LndHtlcInterceptor) intercept() error {
 						})
 					}
 
+				case interceptor.INTERCEPT_RESUME_ON_CHANNEL:
+					interceptorClient.Send(&routerrpc.ForwardHtlcInterceptResponse{
+						IncomingCircuitKey:      request.IncomingCircuitKey,
+						Action:                  routerrpc.ResolveHoldForwardAction_RESUME,
+						OutgoingAmountMsat:      request.OutgoingAmountMsat,
+						OutgoingRequestedChanId: interceptResult.ChannelId,
+						OnionBlob:               request.OnionBlob,
+					})
+
 				case interceptor.INTERCEPT_FAIL_HTLC_WITH_CODE:
 					interceptorClient.Send(&routerrpc.ForwardHtlcInterceptResponse{
 						IncomingCircuitKey: request.IncomingCircuitKey,
@@ -175,9 +219,11 @@ func (i *LndHtlcInterceptor) intercept() error {
 				}
 
 				i.doneWg.Done()
-			}()
+			})
 		}
 
+		cancelStream()
+		i.connected.Store(false)
 		<-time.After(time.Second)
 	}
 }
@@ -190,8 +236,18 @@ func (i *LndHtlcInterceptor) mapFailureCode(original interceptor.InterceptFailur
 		return lnrpc.Failure_TEMPORARY_NODE_FAILURE
 	case interceptor.FAILURE_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS:
 		return lnrpc.Failure_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS
+	case interceptor.FAILURE_PERMANENT_NODE_FAILURE:
+		return lnrpc.Failure_PERMANENT_NODE_FAILURE
+	case interceptor.FAILURE_PERMANENT_CHANNEL_FAILURE:
+		return lnrpc.Failure_PERMANENT_CHANNEL_FAILURE
+	case interceptor.FAILURE_UNKNOWN_NEXT_PEER:
+		return lnrpc.Failure_UNKNOWN_NEXT_PEER
+	case interceptor.FAILURE_EXPIRY_TOO_SOON:
+		return lnrpc.Failure_EXPIRY_TOO_SOON
+	case interceptor.FAILURE_EXPIRY_TOO_FAR:
+		return lnrpc.Failure_EXPIRY_TOO_FAR
 	default:
-		log.Printf("Unknown failure code %v, default to temporary channel failure.", original)
+		i.logger.Printf("Unknown failure code %v, default to temporary channel failure.", original)
 		return lnrpc.Failure_TEMPORARY_CHANNEL_FAILURE
 	}
 }
@@ -203,13 +259,13 @@ func (i *LndHtlcInterceptor) constructOnion(
 ) ([]byte, error) {
 	pubKey, err := btcec.ParsePubKey(interceptResult.Destination)
 	if err != nil {
-		log.Printf("btcec.ParsePubKey(%x): %v", interceptResult.Destination, err)
+		i.logger.Printf("btcec.ParsePubKey(%x): %v", interceptResult.Destination, err)
 		return nil, err
 	}
 
 	sessionKey, err := btcec.NewPrivateKey()
 	if err != nil {
-		log.Printf("btcec.NewPrivateKey(): %v", err)
+		i.logger.Printf("btcec.NewPrivateKey(): %v", err)
 		return nil, err
 	}
 
@@ -225,13 +281,13 @@ func (i *LndHtlcInterceptor) constructOnion(
 	var b bytes.Buffer
 	err = hop.PackHopPayload(&b, uint64(0))
 	if err != nil {
-		log.Printf("hop.PackHopPayload(): %v", err)
+		i.logger.Printf("hop.PackHopPayload(): %v", err)
 		return nil, err
 	}
 
 	payload, err := sphinx.NewHopPayload(nil, b.Bytes())
 	if err != nil {
-		log.Printf("sphinx.NewHopPayload(): %v", err)
+		i.logger.Printf("sphinx.NewHopPayload(): %v", err)
 		return nil, err
 	}
 
@@ -245,13 +301,13 @@ func (i *LndHtlcInterceptor) constructOnion(
 		sphinx.DeterministicPacketFiller,
 	)
 	if err != nil {
-		log.Printf("sphinx.NewOnionPacket(): %v", err)
+		i.logger.Printf("sphinx.NewOnionPacket(): %v", err)
 		return nil, err
 	}
 	var onionBlob bytes.Buffer
 	err = sphinxPacket.Encode(&onionBlob)
 	if err != nil {
-		log.Printf("sphinxPacket.Encode(): %v", err)
+		i.logger.Printf("sphinxPacket.Encode(): %v", err)
 		return nil, err
 	}
 
@@ -2,15 +2,22 @@ package lnd
 
 import (
 	"context"
+
+	"github.com/breez/lspd/secrets"
 )
 
 type MacaroonCredential struct {
 	MacaroonHex string
+	resolver    *secrets.Resolver
 }
 
-func NewMacaroonCredential(hex string) *MacaroonCredential {
+// NewMacaroonCredential returns the credential of the hex encoded macaroon,
+// or of the macaroon the value references in Vault or KMS, which is resolved
+// for every call so a rotated macaroon is picked up.
+func NewMacaroonCredential(hex string, resolver *secrets.Resolver) *MacaroonCredential {
 	return &MacaroonCredential{
 		MacaroonHex: hex,
+		resolver:    resolver,
 	}
 }
 
@@ -19,7 +26,12 @@ func (m *MacaroonCredential) RequireTransportSecurity() bool {
 }
 
 func (m *MacaroonCredential) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	macaroon, err := m.resolver.Resolve(ctx, m.MacaroonHex)
+	if err != nil {
+		return nil, err
+	}
+
 	md := make(map[string]string)
-	md["macaroon"] = m.MacaroonHex
+	md["macaroon"] = macaroon
 	return md, nil
 }
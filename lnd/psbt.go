@@ -0,0 +1,134 @@
+package lnd
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/lightning"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// OpenChannelPsbt opens a channel with a psbt funding shim. lnd creates the
+// funding output, the signer funds and signs the transaction, after which lnd
+// publishes it. If anything fails after the shim was registered, the shim is
+// cancelled so lnd releases the pending channel.
+func (c *LndClient) OpenChannelPsbt(req *lightning.OpenChannelRequest, signer lightning.PsbtSigner) (*wire.OutPoint, error) {
+	pendingChanID := make([]byte, 32)
+	_, err := rand.Read(pendingChanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate pending channel id: %w", err)
+	}
+
+	lnReq := &lnrpc.OpenChannelRequest{
+		NodePubkey:         req.Destination,
+		LocalFundingAmount: int64(req.CapacitySat),
+		PushSat:            0,
+		Private:            req.IsPrivate,
+		CommitmentType:     lnrpc.CommitmentType_ANCHORS,
+		ZeroConf:           req.IsZeroConf,
+		FundingShim: &lnrpc.FundingShim{
+			Shim: &lnrpc.FundingShim_PsbtShim{
+				PsbtShim: &lnrpc.PsbtShim{
+					PendingChanId: pendingChanID,
+				},
+			},
+		},
+	}
+
+	// See OpenChannel.
+	lnReq.ScidAlias = req.IsZeroConf && req.IsPrivate
+
+	if req.CommitmentType != "" {
+		lnReq.CommitmentType = commitmentType(req.CommitmentType)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.client.OpenChannel(ctx, lnReq)
+	if err != nil {
+		c.logger.Printf("LND: client.OpenChannel(%x, %v) psbt error: %v", req.Destination, req.CapacitySat, err)
+		return nil, fmt.Errorf("LND: OpenChannelPsbt() error: %w", err)
+	}
+
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			c.cancelShim(pendingChanID)
+			return nil, fmt.Errorf("LND: OpenChannelPsbt() stream error: %w", err)
+		}
+
+		if fund := update.GetPsbtFund(); fund != nil {
+			err = c.fundPsbt(ctx, pendingChanID, fund, signer)
+			if err != nil {
+				c.cancelShim(pendingChanID)
+				return nil, err
+			}
+
+			continue
+		}
+
+		if pending := update.GetChanPending(); pending != nil {
+			result, err := basetypes.NewOutPoint(pending.Txid, pending.OutputIndex)
+			if err != nil {
+				c.logger.Printf("LND: OpenChannelPsbt returned invalid outpoint. error: %v", err)
+				return nil, err
+			}
+
+			return result, nil
+		}
+	}
+}
+
+func (c *LndClient) fundPsbt(
+	ctx context.Context,
+	pendingChanID []byte,
+	fund *lnrpc.ReadyForPsbtFunding,
+	signer lightning.PsbtSigner,
+) error {
+	signed, err := signer(fund.FundingAddress, uint64(fund.FundingAmount), fund.Psbt)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.FundingStateStep(ctx, &lnrpc.FundingTransitionMsg{
+		Trigger: &lnrpc.FundingTransitionMsg_PsbtVerify{
+			PsbtVerify: &lnrpc.FundingPsbtVerify{
+				PendingChanId: pendingChanID,
+				FundedPsbt:    signed,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("LND: psbt verify error: %w", err)
+	}
+
+	_, err = c.client.FundingStateStep(ctx, &lnrpc.FundingTransitionMsg{
+		Trigger: &lnrpc.FundingTransitionMsg_PsbtFinalize{
+			PsbtFinalize: &lnrpc.FundingPsbtFinalize{
+				PendingChanId: pendingChanID,
+				SignedPsbt:    signed,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("LND: psbt finalize error: %w", err)
+	}
+
+	return nil
+}
+
+func (c *LndClient) cancelShim(pendingChanID []byte) {
+	_, err := c.client.FundingStateStep(context.Background(), &lnrpc.FundingTransitionMsg{
+		Trigger: &lnrpc.FundingTransitionMsg_ShimCancel{
+			ShimCancel: &lnrpc.FundingShimCancel{
+				PendingChanId: pendingChanID,
+			},
+		},
+	})
+	if err != nil {
+		c.logger.Printf("LND: failed to cancel psbt shim %x: %v", pendingChanID, err)
+	}
+}
@@ -0,0 +1,64 @@
+package lnd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/lightning"
+	"github.com/lightningnetwork/lnd/lnrpc"
+)
+
+// Label of the transactions sweeping wallet utxos.
+const sweepLabel = "lspd: consolidation"
+
+// ListUtxos returns the unspent outputs of the default wallet account, the
+// account Sweep spends from.
+func (c *LndClient) ListUtxos(ctx context.Context) ([]*lightning.Utxo, error) {
+	return c.listUtxos(ctx, "")
+}
+
+// Sweep spends the utxos to a new taproot address of the default wallet
+// account with SendCoins.
+func (c *LndClient) Sweep(ctx context.Context, utxos []string, feeSatPerVByte float64) (string, error) {
+	var outpoints []*lnrpc.OutPoint
+	for _, u := range utxos {
+		outPoint, err := basetypes.ParseOutPoint(u)
+		if err != nil {
+			return "", err
+		}
+
+		outpoints = append(outpoints, &lnrpc.OutPoint{
+			TxidStr:     outPoint.Hash.String(),
+			OutputIndex: outPoint.Index,
+		})
+	}
+
+	addr, err := c.client.NewAddress(ctx, &lnrpc.NewAddressRequest{
+		Type: lnrpc.AddressType_TAPROOT_PUBKEY,
+	})
+	if err != nil {
+		c.logger.Printf("LND: client.NewAddress() error: %v", err)
+		return "", fmt.Errorf("LND: NewAddress() error: %w", err)
+	}
+
+	// lnd takes whole sat/vbyte feerates.
+	satPerVByte := uint64(feeSatPerVByte)
+	if satPerVByte == 0 {
+		satPerVByte = 1
+	}
+
+	r, err := c.client.SendCoins(ctx, &lnrpc.SendCoinsRequest{
+		Addr:        addr.Address,
+		SendAll:     true,
+		SatPerVbyte: satPerVByte,
+		Label:       sweepLabel,
+		Outpoints:   outpoints,
+	})
+	if err != nil {
+		c.logger.Printf("LND: client.SendCoins(%s, %d utxos) error: %v", addr.Address, len(utxos), err)
+		return "", fmt.Errorf("LND: SendCoins() error: %w", err)
+	}
+
+	return r.Txid, nil
+}
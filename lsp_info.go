@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/breez/lspd/lightning"
+	lspdrpc "github.com/breez/lspd/rpc"
+	"github.com/breez/lspd/tokens"
+)
+
+// Version of GetLspInfoReply.
+const lspInfoVersion = 1
+
+// GetLspInfo returns the features and limits of the lsp for the token of the
+// client.
+func (s *channelOpenerServer) GetLspInfo(ctx context.Context, in *lspdrpc.GetLspInfoRequest) (*lspdrpc.GetLspInfoReply, error) {
+	node, token, err := s.getNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := s.createOpeningParamsMenu(ctx, node, token)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &lspdrpc.GetLspInfoReply{
+		Version:              lspInfoVersion,
+		Name:                 node.nodeConfig.Name,
+		Pubkey:               node.nodeConfig.NodePubkey,
+		Host:                 node.nodeConfig.Host,
+		Network:              node.nodeConfig.Network,
+		Features:             s.lspFeatures(ctx, node, token),
+		MinPaymentSizeMsat:   uint64(node.nodeConfig.MinPaymentSizeMsat),
+		OpeningFeeParamsMenu: params,
+		LiquidityAd:          liquidityAd(node),
+		CommitmentTypes:      commitmentTypes(node, !tokens.PublicChannels(s.getToken(ctx, node, token), node.nodeConfig)),
+	}
+	if w := node.nodeConfig.Wumbo; w != nil {
+		reply.MaxChannelSizeSat = uint64(w.MaxChannelCapacitySat)
+		reply.MaxHtlcMsat = uint64(w.MaxHtlcMsat)
+	}
+	if ls, ok := s.lsps0Servers[node.nodeConfig.NodePubkey]; ok {
+		reply.LspsVersions = append([]uint32{0}, ls.Protocols()...)
+	}
+
+	return reply, nil
+}
+
+// lspFeatures returns the features of the node for the token, sorted by
+// name. A feature gets a new version when its behavior changes in a way
+// clients have to know about.
+func (s *channelOpenerServer) lspFeatures(ctx context.Context, node *node, token string) []*lspdrpc.LspFeature {
+	tok := s.getToken(ctx, node, token)
+	cfg := node.nodeConfig
+	features := map[string]uint32{
+		// Channels are opened zero conf when a registered payment arrives.
+		"zero_conf": 1,
+		// RegisterPayment assigns fake scids for route hints.
+		"fake_scids": 1,
+		// OpenChannel opens channels in the background, with
+		// GetChannelOpenStatus.
+		"open_channel": 1,
+		// SubscribePaymentEvents streams the events of payments.
+		"payment_events": 1,
+		// RegisterPayment and OpenChannel take the commitment type of the
+		// channel.
+		"commitment_types": 1,
+	}
+	if node.challenger.Required(tok, token) {
+		features["open_channel_challenge"] = 1
+	}
+	if tokens.CommitmentType(tok, cfg) == lightning.CommitmentTypeTaproot {
+		features["taproot_channels"] = 1
+	}
+	if tokens.PublicChannels(tok, cfg) {
+		features["public_channels"] = 1
+	}
+	if _, ok := node.client.(lightning.Splicer); ok && cfg.Splicing != nil {
+		features["splicing"] = 1
+	}
+	if cfg.Wumbo != nil {
+		features["large_channels"] = 1
+	}
+	if len(cfg.PriorityTiers) > 0 {
+		features["priority_tiers"] = 1
+	}
+	if cfg.RequireSignedPayments {
+		features["signed_payments"] = 1
+	}
+	if cfg.PreimageHold {
+		features["preimage_hold"] = 1
+	}
+	// Channels are opened dual-funded, and the lsp contributes to the
+	// dual-funded channels clients open.
+	if _, ok := dualFunder(node); ok {
+		features["dual_funding"] = 1
+		if cfg.DualFunding.LiquidityAds != nil {
+			features["liquidity_ads"] = 1
+		}
+	}
+	// Channels stay open for a lease, renewed with RenewChannelLease.
+	if _, ok := s.leaseManagers[cfg.NodePubkey]; ok {
+		features["channel_leases"] = 1
+	}
+
+	var result []*lspdrpc.LspFeature
+	for name, version := range features {
+		result = append(result, &lspdrpc.LspFeature{Name: name, Version: version})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
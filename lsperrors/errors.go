@@ -0,0 +1,236 @@
+// Package lsperrors defines the errors lspd returns to clients and uses to
+// fail htlcs. Every error belongs to a failure domain and maps to a grpc
+// status code and a bolt 4 failure code, so callers wrap the sentinel errors
+// with context instead of choosing codes and matching error strings
+// themselves.
+package lsperrors
+
+import (
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Domain is the failure domain of an error.
+type Domain string
+
+const (
+	DomainRegistration Domain = "registration"
+	DomainPolicy       Domain = "policy"
+	DomainOpen         Domain = "open"
+	DomainOnion        Domain = "onion"
+	DomainNode         Domain = "node"
+	DomainInternal     Domain = "internal"
+)
+
+// Bolt 4 failure codes htlcs are failed with.
+const (
+	FailureTemporaryChannelFailure          uint16 = 0x1007
+	FailureTemporaryNodeFailure             uint16 = 0x2002
+	FailureIncorrectOrUnknownPaymentDetails uint16 = 0x400F
+	FailurePermanentNodeFailure             uint16 = 0x4002
+	FailurePermanentChannelFailure          uint16 = 0x4008
+	FailureUnknownNextPeer                  uint16 = 0x400A
+	FailureExpiryTooSoon                    uint16 = 0x100E
+	FailureExpiryTooFar                     uint16 = 0x0015
+)
+
+// Error is a sentinel error of a failure domain. Reason is a stable, machine
+// readable identifier of the error, sent to clients in the status details.
+type Error struct {
+	Domain      Domain
+	Reason      string
+	Message     string
+	Code        codes.Code
+	FailureCode uint16
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(domain Domain, reason string, message string, code codes.Code, failureCode uint16) *Error {
+	return &Error{
+		Domain:      domain,
+		Reason:      reason,
+		Message:     message,
+		Code:        code,
+		FailureCode: failureCode,
+	}
+}
+
+// Registration errors are caused by invalid client requests.
+var (
+	ErrNotAuthorized       = newError(DomainRegistration, "NOT_AUTHORIZED", "Not authorized", codes.PermissionDenied, FailureTemporaryChannelFailure)
+	ErrInvalidRequest      = newError(DomainRegistration, "INVALID_REQUEST", "invalid request", codes.InvalidArgument, FailureTemporaryChannelFailure)
+	ErrSignatureRequired   = newError(DomainRegistration, "SIGNATURE_REQUIRED", "signature required", codes.PermissionDenied, FailureTemporaryChannelFailure)
+	ErrInvalidSignature    = newError(DomainRegistration, "INVALID_SIGNATURE", "invalid signature", codes.PermissionDenied, FailureTemporaryChannelFailure)
+	ErrInvalidNonce        = newError(DomainRegistration, "INVALID_NONCE", "invalid nonce", codes.InvalidArgument, FailureTemporaryChannelFailure)
+	ErrNonceReused         = newError(DomainRegistration, "NONCE_REUSED", "nonce already used", codes.AlreadyExists, FailureTemporaryChannelFailure)
+	ErrTimestampOutOfRange = newError(DomainRegistration, "TIMESTAMP_OUT_OF_RANGE", "timestamp out of range", codes.InvalidArgument, FailureTemporaryChannelFailure)
+	ErrInvalidTag          = newError(DomainRegistration, "INVALID_TAG", "invalid tag", codes.InvalidArgument, FailureTemporaryChannelFailure)
+	ErrInvalidFeeParams    = newError(DomainRegistration, "INVALID_FEE_PARAMS", "invalid opening_fee_params", codes.InvalidArgument, FailureTemporaryChannelFailure)
+	ErrPaymentNotFound     = newError(DomainRegistration, "PAYMENT_NOT_FOUND", "payment not found", codes.NotFound, FailureIncorrectOrUnknownPaymentDetails)
+	ErrUnsupported         = newError(DomainRegistration, "UNSUPPORTED", "unsupported", codes.Unimplemented, FailureTemporaryChannelFailure)
+	ErrChallengeRequired   = newError(DomainRegistration, "CHALLENGE_REQUIRED", "open channel challenge required", codes.FailedPrecondition, FailureTemporaryChannelFailure)
+	ErrInvalidChallenge    = newError(DomainRegistration, "INVALID_CHALLENGE", "invalid open channel challenge", codes.PermissionDenied, FailureTemporaryChannelFailure)
+	ErrRateLimited         = newError(DomainRegistration, "RATE_LIMITED", "too many requests", codes.ResourceExhausted, FailureTemporaryChannelFailure)
+	ErrCaveatViolated      = newError(DomainRegistration, "CAVEAT_VIOLATED", "not allowed by the caveats of the macaroon", codes.PermissionDenied, FailureTemporaryChannelFailure)
+)
+
+// Policy errors are requests the lsp declines.
+var (
+	ErrInvalidAmount         = newError(DomainPolicy, "INVALID_AMOUNT", "invalid amount", codes.InvalidArgument, FailureTemporaryChannelFailure)
+	ErrNotEnoughFees         = newError(DomainPolicy, "NOT_ENOUGH_FEES", "not enough fees", codes.InvalidArgument, FailureTemporaryChannelFailure)
+	ErrExceedsMaxCapacity    = newError(DomainPolicy, "EXCEEDS_MAX_CAPACITY", "payment exceeds max channel capacity", codes.InvalidArgument, FailureTemporaryChannelFailure)
+	ErrExceedsMaxHtlc        = newError(DomainPolicy, "EXCEEDS_MAX_HTLC", "payment exceeds max htlc", codes.InvalidArgument, FailureTemporaryChannelFailure)
+	ErrInsufficientLiquidity = newError(DomainPolicy, "INSUFFICIENT_LIQUIDITY", "insufficient liquidity", codes.ResourceExhausted, FailureTemporaryChannelFailure)
+	ErrFeeParamsExpired      = newError(DomainPolicy, "FEE_PARAMS_EXPIRED", "opening_fee_params expired", codes.FailedPrecondition, FailureTemporaryChannelFailure)
+	ErrCltvDeltaTooLow       = newError(DomainPolicy, "CLTV_DELTA_TOO_LOW", "cltv delta too low", codes.InvalidArgument, FailureTemporaryChannelFailure)
+	ErrCircuitBreakerOpen    = newError(DomainPolicy, "CIRCUIT_BREAKER_OPEN", "not opening channels temporarily", codes.Unavailable, FailureTemporaryNodeFailure)
+	ErrBelowMinimum          = newError(DomainPolicy, "BELOW_MINIMUM", "payment below minimum payment size", codes.InvalidArgument, FailureUnknownNextPeer)
+	ErrMissingFeatures       = newError(DomainPolicy, "MISSING_FEATURES", "client doesn't support the features required for a channel", codes.FailedPrecondition, FailureUnknownNextPeer)
+	ErrPolicyDenied          = newError(DomainPolicy, "POLICY_DENIED", "channel open denied by policy", codes.PermissionDenied, FailureTemporaryChannelFailure)
+	ErrExpiryTooFar          = newError(DomainPolicy, "EXPIRY_TOO_FAR", "htlc expires too far in the future for the client", codes.FailedPrecondition, FailureExpiryTooFar)
+	ErrClientIncompatible    = newError(DomainPolicy, "CLIENT_INCOMPATIBLE", "client can't receive payments from this node", codes.FailedPrecondition, FailureUnknownNextPeer)
+	ErrExpiryTooSoon         = newError(DomainPolicy, "EXPIRY_TOO_SOON", "htlc expires too soon to open a channel", codes.FailedPrecondition, FailureExpiryTooSoon)
+	ErrExposureLimit         = newError(DomainPolicy, "EXPOSURE_LIMIT", "too much in flight to the client", codes.ResourceExhausted, FailureTemporaryChannelFailure)
+	ErrZeroConfExposure      = newError(DomainPolicy, "ZERO_CONF_EXPOSURE", "too much in flight over unconfirmed channels", codes.ResourceExhausted, FailureTemporaryChannelFailure)
+	ErrPeerDenied            = newError(DomainPolicy, "PEER_DENIED", "client denied service", codes.PermissionDenied, FailureTemporaryChannelFailure)
+	ErrQuotaExceeded         = newError(DomainPolicy, "QUOTA_EXCEEDED", "channel open quota of the token exceeded", codes.ResourceExhausted, FailureTemporaryChannelFailure)
+	ErrLatencyBudget         = newError(DomainPolicy, "LATENCY_BUDGET_EXCEEDED", "no decision about the htlc within the latency budget", codes.DeadlineExceeded, FailureTemporaryChannelFailure)
+)
+
+// Open errors occur while opening a channel for a client.
+var (
+	ErrOpenFailed         = newError(DomainOpen, "OPEN_FAILED", "failed to open channel", codes.Internal, FailureTemporaryChannelFailure)
+	ErrChannelNotReady    = newError(DomainOpen, "CHANNEL_NOT_READY", "channel not ready", codes.FailedPrecondition, FailureTemporaryChannelFailure)
+	ErrNoEncryptionKey    = newError(DomainOpen, "NO_ENCRYPTION_KEY", "no active encryption key", codes.Unavailable, FailureTemporaryChannelFailure)
+	ErrJobNotFound        = newError(DomainOpen, "JOB_NOT_FOUND", "channel open job not found", codes.NotFound, FailureTemporaryChannelFailure)
+	ErrChannelExists      = newError(DomainOpen, "CHANNEL_EXISTS", "channel already exists", codes.AlreadyExists, FailureTemporaryChannelFailure)
+	ErrFundingDoubleSpent = newError(DomainOpen, "FUNDING_DOUBLE_SPENT", "funding of the channel was double spent", codes.FailedPrecondition, FailurePermanentChannelFailure)
+)
+
+// Onion errors occur while constructing the onion for the client.
+var (
+	ErrOnionConstruction = newError(DomainOnion, "ONION_CONSTRUCTION", "failed to construct onion", codes.Internal, FailureTemporaryChannelFailure)
+)
+
+// Node errors are failed calls to the lightning node.
+var (
+	ErrNodeUnavailable = newError(DomainNode, "NODE_UNAVAILABLE", "lightning node unavailable", codes.Unavailable, FailureTemporaryChannelFailure)
+)
+
+// ErrInternal is an unexpected error, like a failed database call.
+var ErrInternal = newError(DomainInternal, "INTERNAL", "internal error", codes.Internal, FailureTemporaryNodeFailure)
+
+// Wrap wraps err into the sentinel error e, so errors.Is matches e and the
+// message keeps the context of err.
+func Wrap(e *Error, err error) error {
+	if err == nil {
+		return e
+	}
+
+	return &wrapped{sentinel: e, err: err}
+}
+
+type wrapped struct {
+	sentinel *Error
+	err      error
+}
+
+func (w *wrapped) Error() string {
+	return w.sentinel.Message + ": " + w.err.Error()
+}
+
+func (w *wrapped) Unwrap() error {
+	return w.err
+}
+
+func (w *wrapped) Is(target error) bool {
+	return target == w.sentinel
+}
+
+func (w *wrapped) As(target interface{}) bool {
+	e, ok := target.(**Error)
+	if !ok {
+		return false
+	}
+
+	*e = w.sentinel
+	return true
+}
+
+// WithMetadata adds metadata to err, which is sent to clients in the status
+// details, so they can act on the error without parsing the message.
+func WithMetadata(err error, metadata map[string]string) error {
+	return &withMetadata{err: err, metadata: metadata}
+}
+
+type withMetadata struct {
+	err      error
+	metadata map[string]string
+}
+
+func (w *withMetadata) Error() string {
+	return w.err.Error()
+}
+
+func (w *withMetadata) Unwrap() error {
+	return w.err
+}
+
+// From returns the sentinel error err wraps, or nil if it doesn't wrap one.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	return nil
+}
+
+// FailureCode returns the bolt 4 failure code to fail an htlc with because
+// of err, or fallback if err doesn't wrap a sentinel error.
+func FailureCode(err error, fallback uint16) uint16 {
+	if e := From(err); e != nil {
+		return e.FailureCode
+	}
+
+	return fallback
+}
+
+// ToStatus converts an error wrapping a sentinel error to a grpc status error
+// with the reason and domain in the details. The message of ErrInternal is not
+// extended with the wrapped error, so internals are not returned to the
+// client. Other errors are returned unchanged.
+func ToStatus(err error) error {
+	e := From(err)
+	if e == nil {
+		return err
+	}
+
+	message := err.Error()
+	if e == ErrInternal {
+		message = e.Message
+	}
+
+	info := &errdetails.ErrorInfo{
+		Reason: e.Reason,
+		Domain: "lspd." + string(e.Domain),
+	}
+	var m *withMetadata
+	if errors.As(err, &m) {
+		info.Metadata = m.metadata
+	}
+
+	st := status.New(e.Code, message)
+	withDetails, derr := st.WithDetails(info)
+	if derr != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
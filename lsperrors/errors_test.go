@@ -0,0 +1,49 @@
+package lsperrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWrap(t *testing.T) {
+	err := fmt.Errorf("RegisterPayment() error: %w", Wrap(ErrInternal, context.DeadlineExceeded))
+	assert.True(t, errors.Is(err, ErrInternal))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, ErrInternal, From(err))
+}
+
+func TestFailureCode(t *testing.T) {
+	assert.Equal(t, FailureTemporaryNodeFailure, FailureCode(fmt.Errorf("x: %w", ErrCircuitBreakerOpen), 0))
+	assert.Equal(t, uint16(1), FailureCode(errors.New("other"), 1))
+}
+
+func TestToStatus(t *testing.T) {
+	st := status.Convert(ToStatus(fmt.Errorf("checkPayment() error: %w", ErrNotEnoughFees)))
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "checkPayment() error: not enough fees", st.Message())
+	assert.Len(t, st.Details(), 1)
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+	assert.Equal(t, "NOT_ENOUGH_FEES", info.Reason)
+	assert.Equal(t, "lspd.policy", info.Domain)
+
+	st = status.Convert(ToStatus(Wrap(ErrInternal, errors.New("connection refused"))))
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.Equal(t, "internal error", st.Message())
+
+	err := WithMetadata(fmt.Errorf("%w: 01:0", ErrChannelExists), map[string]string{"channel_point": "01:0"})
+	st = status.Convert(ToStatus(err))
+	assert.Equal(t, codes.AlreadyExists, st.Code())
+	assert.Equal(t, "channel already exists: 01:0", st.Message())
+	info = st.Details()[0].(*errdetails.ErrorInfo)
+	assert.Equal(t, map[string]string{"channel_point": "01:0"}, info.Metadata)
+
+	other := errors.New("other")
+	assert.Equal(t, other, ToStatus(other))
+}
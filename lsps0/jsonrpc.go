@@ -0,0 +1,57 @@
+package lsps0
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const jsonRpcVersion = "2.0"
+
+// Error codes of json-rpc 2.0. LSPS0 uses the standard codes for transport
+// errors, the protocols on top of it define their own codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	// The client has too many requests in progress.
+	CodeTooManyRequests = -32000
+)
+
+type request struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Id      *string         `json:"id"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JsonRpc string      `json:"jsonrpc"`
+	Id      *string     `json:"id"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *Error      `json:"error,omitempty"`
+}
+
+// Error is a json-rpc error returned to the client. Handlers return an *Error
+// to fail a request with a specific code, other errors are returned to the
+// client as internal errors.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// NewInvalidParams returns the error for requests with params the method
+// can't handle.
+func NewInvalidParams(format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    CodeInvalidParams,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,325 @@
+// Package lsps0 implements LSPS0, the json-rpc transport of the LSP
+// specifications over BOLT8 custom messages. The LSPS protocols register
+// their methods on the Server of a node.
+package lsps0
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// Custom message type of LSPS0 messages.
+	MessageType = 37913
+
+	// Largest LSPS0 message, the largest BOLT8 message minus the type.
+	maxMessageSize = 65531
+
+	defaultMaxConcurrentRequests = 5
+	defaultRequestTimeout        = 30 * time.Second
+)
+
+var requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_lsps0_requests_total",
+	Help: "LSPS0 requests by method and json-rpc error code, 0 for success.",
+}, []string{"node", "method", "code"})
+
+// Handler handles a request of a method. params is the json object the
+// client sent, the returned result is marshalled to json.
+type Handler func(ctx context.Context, peerID []byte, params json.RawMessage) (interface{}, error)
+
+// Server handles the LSPS0 requests clients send to a node. Requests are
+// handled concurrently, the response to a request carries the id of the
+// request so the client can match them. Clients are limited in the number of
+// requests in progress, and can't reuse the id of a request in progress.
+type Server struct {
+	client                lightning.CustomMsgClient
+	node                  *config.NodeConfig
+	logger                *log.Logger
+	maxConcurrentRequests int
+	requestTimeout        time.Duration
+	methods               map[string]Handler
+	protocols             []uint32
+	inflightMtx           sync.Mutex
+	inflight              map[string]map[string]struct{}
+	ctx                   context.Context
+	cancel                context.CancelFunc
+	mtx                   sync.Mutex
+	wg                    sync.WaitGroup
+}
+
+func NewServer(client lightning.CustomMsgClient, node *config.NodeConfig, logger *log.Logger) *Server {
+	s := &Server{
+		client:                client,
+		node:                  node,
+		logger:                logger,
+		maxConcurrentRequests: defaultMaxConcurrentRequests,
+		requestTimeout:        defaultRequestTimeout,
+		methods:               make(map[string]Handler),
+		inflight:              make(map[string]map[string]struct{}),
+	}
+	if cfg := node.Lsps0; cfg != nil {
+		if cfg.MaxConcurrentRequests > 0 {
+			s.maxConcurrentRequests = cfg.MaxConcurrentRequests
+		}
+		s.requestTimeout = parseDuration(cfg.RequestTimeout, defaultRequestTimeout, logger)
+	}
+
+	s.methods["lsps0.list_protocols"] = s.listProtocols
+	return s
+}
+
+func parseDuration(s string, def time.Duration, logger *log.Logger) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		logger.Printf("WARN: Invalid lsps0 duration '%s'. Using default %v", s, def)
+		return def
+	}
+
+	return d
+}
+
+func (s *Server) Node() *config.NodeConfig {
+	return s.node
+}
+
+// RegisterProtocol registers the methods of an LSPS protocol, like 1 for
+// LSPS1, by their full name, like lsps1.get_info. The protocol is listed in
+// lsps0.list_protocols. Call it before Start.
+func (s *Server) RegisterProtocol(protocol uint32, methods map[string]Handler) {
+	s.protocols = append(s.protocols, protocol)
+	sort.Slice(s.protocols, func(i, j int) bool {
+		return s.protocols[i] < s.protocols[j]
+	})
+	for name, handler := range methods {
+		s.methods[name] = handler
+	}
+}
+
+// Protocols returns the LSPS protocols registered on top of LSPS0.
+func (s *Server) Protocols() []uint32 {
+	return s.protocols
+}
+
+type listProtocolsResponse struct {
+	Protocols []uint32 `json:"protocols"`
+}
+
+func (s *Server) listProtocols(ctx context.Context, peerID []byte, params json.RawMessage) (interface{}, error) {
+	protocols := s.protocols
+	if protocols == nil {
+		protocols = []uint32{}
+	}
+
+	return &listProtocolsResponse{Protocols: protocols}, nil
+}
+
+// Start receives and handles requests, and the custom messages of the node
+// they arrive in, until Stop is called.
+func (s *Server) Start() error {
+	s.mtx.Lock()
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	ctx := s.ctx
+	s.mtx.Unlock()
+
+	go func() {
+		err := s.client.Start()
+		if err != nil {
+			s.logger.Printf("lsps0: custom message client stopped with error: %v", err)
+		}
+	}()
+
+	for {
+		msg, err := s.client.Recv()
+		if errors.Is(err, lightning.ErrCustomMsgClientStopped) || ctx.Err() != nil {
+			s.wg.Wait()
+			return nil
+		}
+		if err != nil {
+			s.logger.Printf("lsps0: Recv() error: %v", err)
+			continue
+		}
+
+		if msg.Type != MessageType {
+			continue
+		}
+
+		s.handleMessage(ctx, msg)
+	}
+}
+
+func (s *Server) Stop() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.client.Stop()
+}
+
+// handleMessage validates the request in the message, and handles valid
+// requests in the background.
+func (s *Server) handleMessage(ctx context.Context, msg *lightning.CustomMessage) {
+	if len(msg.Data) > maxMessageSize {
+		s.respondError(msg.PeerID, nil, "", CodeInvalidRequest, "message too large")
+		return
+	}
+
+	var req request
+	err := json.Unmarshal(msg.Data, &req)
+	if err != nil {
+		s.respondError(msg.PeerID, nil, "", CodeParseError, "parse error")
+		return
+	}
+
+	if req.JsonRpc != jsonRpcVersion || req.Method == "" || req.Id == nil {
+		s.respondError(msg.PeerID, req.Id, req.Method, CodeInvalidRequest, "invalid request")
+		return
+	}
+
+	handler, ok := s.methods[req.Method]
+	if !ok {
+		s.respondError(msg.PeerID, req.Id, req.Method, CodeMethodNotFound, "method not found")
+		return
+	}
+
+	// LSPS0 passes params by name only.
+	params := bytes.TrimSpace(req.Params)
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		params = []byte("{}")
+	}
+	if params[0] != '{' {
+		s.respondError(msg.PeerID, req.Id, req.Method, CodeInvalidParams, "params must be an object")
+		return
+	}
+
+	code, message := s.acquire(msg.PeerID, *req.Id)
+	if code != 0 {
+		s.respondError(msg.PeerID, req.Id, req.Method, code, message)
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.release(msg.PeerID, *req.Id)
+		s.handleRequest(ctx, msg.PeerID, &req, handler, params)
+	}()
+}
+
+// acquire marks the request of the peer as in progress. It returns an error
+// code and message if the peer has too many requests in progress, or a
+// request with the same id.
+func (s *Server) acquire(peerID []byte, id string) (int, string) {
+	s.inflightMtx.Lock()
+	defer s.inflightMtx.Unlock()
+
+	peer := hex.EncodeToString(peerID)
+	ids, ok := s.inflight[peer]
+	if !ok {
+		ids = make(map[string]struct{})
+		s.inflight[peer] = ids
+	}
+	if _, ok := ids[id]; ok {
+		return CodeInvalidRequest, "a request with this id is in progress"
+	}
+	if len(ids) >= s.maxConcurrentRequests {
+		return CodeTooManyRequests, "too many requests in progress"
+	}
+
+	ids[id] = struct{}{}
+	return 0, ""
+}
+
+func (s *Server) release(peerID []byte, id string) {
+	s.inflightMtx.Lock()
+	defer s.inflightMtx.Unlock()
+
+	peer := hex.EncodeToString(peerID)
+	delete(s.inflight[peer], id)
+	if len(s.inflight[peer]) == 0 {
+		delete(s.inflight, peer)
+	}
+}
+
+func (s *Server) handleRequest(ctx context.Context, peerID []byte, req *request, handler Handler, params json.RawMessage) {
+	ctx, cancel := context.WithTimeout(ctx, s.requestTimeout)
+	defer cancel()
+
+	result, err := handler(ctx, peerID, params)
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			s.logger.Printf("lsps0: %s request %s of %x failed: %v", req.Method, *req.Id, peerID, err)
+			rpcErr = &Error{Code: CodeInternalError, Message: "internal error"}
+		}
+		s.respond(peerID, req.Method, &response{
+			JsonRpc: jsonRpcVersion,
+			Id:      req.Id,
+			Error:   rpcErr,
+		})
+		return
+	}
+
+	// A successful response always has a result.
+	if result == nil {
+		result = struct{}{}
+	}
+	s.respond(peerID, req.Method, &response{
+		JsonRpc: jsonRpcVersion,
+		Id:      req.Id,
+		Result:  result,
+	})
+}
+
+func (s *Server) respondError(peerID []byte, id *string, method string, code int, message string) {
+	s.respond(peerID, method, &response{
+		JsonRpc: jsonRpcVersion,
+		Id:      id,
+		Error:   &Error{Code: code, Message: message},
+	})
+}
+
+func (s *Server) respond(peerID []byte, method string, resp *response) {
+	code := 0
+	if resp.Error != nil {
+		code = resp.Error.Code
+	}
+	if _, ok := s.methods[method]; !ok {
+		// Don't create a metric for every method name clients make up.
+		method = "unknown"
+	}
+	requestsTotal.WithLabelValues(s.node.Label(), method, strconv.Itoa(code)).Inc()
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		s.logger.Printf("lsps0: failed to marshal the response to %s of %x: %v", method, peerID, err)
+		return
+	}
+
+	err = s.client.Send(&lightning.CustomMessage{
+		PeerID: peerID,
+		Type:   MessageType,
+		Data:   data,
+	})
+	if err != nil {
+		s.logger.Printf("lsps0: failed to send the response to %s of %x: %v", method, peerID, err)
+	}
+}
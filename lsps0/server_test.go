@@ -0,0 +1,131 @@
+package lsps0
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockClient struct {
+	recv chan *lightning.CustomMessage
+	sent chan *lightning.CustomMessage
+	done chan struct{}
+}
+
+func newMockClient() *mockClient {
+	return &mockClient{
+		recv: make(chan *lightning.CustomMessage, 10),
+		sent: make(chan *lightning.CustomMessage, 10),
+		done: make(chan struct{}),
+	}
+}
+
+func (c *mockClient) Start() error {
+	return nil
+}
+
+func (c *mockClient) Recv() (*lightning.CustomMessage, error) {
+	select {
+	case msg := <-c.recv:
+		return msg, nil
+	case <-c.done:
+		return nil, lightning.ErrCustomMsgClientStopped
+	}
+}
+
+func (c *mockClient) Send(msg *lightning.CustomMessage) error {
+	c.sent <- msg
+	return nil
+}
+
+func (c *mockClient) Stop() {
+	close(c.done)
+}
+
+func (c *mockClient) request(peer byte, data string) {
+	c.recv <- &lightning.CustomMessage{PeerID: []byte{peer}, Type: MessageType, Data: []byte(data)}
+}
+
+func (c *mockClient) response(t *testing.T) map[string]interface{} {
+	select {
+	case msg := <-c.sent:
+		assert.Equal(t, uint32(MessageType), msg.Type)
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(msg.Data, &resp))
+		return resp
+	case <-time.After(time.Second):
+		t.Fatal("no response")
+		return nil
+	}
+}
+
+func errorCode(resp map[string]interface{}) float64 {
+	e, ok := resp["error"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	return e["code"].(float64)
+}
+
+func TestServer(t *testing.T) {
+	client := newMockClient()
+	node := &config.NodeConfig{Lsps0: &config.Lsps0Config{MaxConcurrentRequests: 1}}
+	s := NewServer(client, node, log.New(os.Stderr, "", 0))
+
+	release := make(chan struct{})
+	s.RegisterProtocol(1, map[string]Handler{
+		"lsps1.get_info": func(ctx context.Context, peerID []byte, params json.RawMessage) (interface{}, error) {
+			<-release
+			return map[string]string{"ok": "yes"}, nil
+		},
+		"lsps1.fail": func(ctx context.Context, peerID []byte, params json.RawMessage) (interface{}, error) {
+			return nil, NewInvalidParams("bad")
+		},
+	})
+	go s.Start()
+	defer s.Stop()
+
+	client.request(1, `{"jsonrpc":"2.0","method":"lsps0.list_protocols","id":"a","params":{}}`)
+	resp := client.response(t)
+	assert.Equal(t, "a", resp["id"])
+	assert.Equal(t, map[string]interface{}{"protocols": []interface{}{float64(1)}}, resp["result"])
+
+	client.request(1, `not json`)
+	resp = client.response(t)
+	assert.Nil(t, resp["id"])
+	assert.Equal(t, float64(CodeParseError), errorCode(resp))
+
+	client.request(1, `{"jsonrpc":"2.0","method":"lsps9.nope","id":"b"}`)
+	assert.Equal(t, float64(CodeMethodNotFound), errorCode(client.response(t)))
+
+	client.request(1, `{"jsonrpc":"2.0","method":"lsps1.fail","id":"c","params":[1]}`)
+	assert.Equal(t, float64(CodeInvalidParams), errorCode(client.response(t)))
+
+	client.request(1, `{"jsonrpc":"2.0","method":"lsps1.fail","id":"c"}`)
+	assert.Equal(t, float64(CodeInvalidParams), errorCode(client.response(t)))
+
+	// One request per client at a time, other clients are not affected.
+	client.request(1, `{"jsonrpc":"2.0","method":"lsps1.get_info","id":"d"}`)
+	client.request(1, `{"jsonrpc":"2.0","method":"lsps1.get_info","id":"e"}`)
+	resp = client.response(t)
+	assert.Equal(t, "e", resp["id"])
+	assert.Equal(t, float64(CodeTooManyRequests), errorCode(resp))
+
+	client.request(2, `{"jsonrpc":"2.0","method":"lsps1.get_info","id":"d"}`)
+	release <- struct{}{}
+	release <- struct{}{}
+	ids := map[interface{}]bool{}
+	for i := 0; i < 2; i++ {
+		resp = client.response(t)
+		assert.Equal(t, float64(0), errorCode(resp))
+		ids[resp["id"]] = true
+	}
+	assert.Equal(t, map[interface{}]bool{"d": true}, ids)
+}
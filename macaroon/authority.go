@@ -0,0 +1,191 @@
+package macaroon
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("macaroon not found")
+	ErrRevoked  = errors.New("macaroon revoked")
+	ErrExpired  = errors.New("macaroon expired")
+)
+
+// Record is a minted macaroon stored in the database. The macaroon itself
+// isn't stored, only its identifier and the caveats it was minted with.
+type Record struct {
+	ID        []byte
+	LspNodeID []byte
+
+	// Token is the api token the macaroon authenticates as, so the settings,
+	// fee params and quotas of the token apply to it.
+	Token     string
+	Name      string
+	Caveats   []string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+type Store interface {
+	// GetRootKey returns the root key of the node, or nil if it has none.
+	GetRootKey(ctx context.Context, lspNodeID []byte) ([]byte, error)
+
+	// AddRootKey stores the root key of the node, unless the node has one
+	// already.
+	AddRootKey(ctx context.Context, lspNodeID []byte, rootKey []byte) error
+
+	// Get returns the record of the macaroon, or nil if it doesn't exist.
+	Get(ctx context.Context, id []byte) (*Record, error)
+	List(ctx context.Context) ([]*Record, error)
+	Create(ctx context.Context, r *Record) error
+	Revoke(ctx context.Context, id []byte, at time.Time) error
+}
+
+// Authority mints and verifies the macaroons of the nodes.
+type Authority struct {
+	store Store
+
+	// Root keys never change, so they are cached.
+	mtx      sync.Mutex
+	rootKeys map[string][]byte
+}
+
+func NewAuthority(store Store) *Authority {
+	return &Authority{
+		store:    store,
+		rootKeys: make(map[string][]byte),
+	}
+}
+
+// Mint returns a new macaroon of the node, authenticating as token, with the
+// caveats. The caveats are checked before the macaroon is stored.
+func (a *Authority) Mint(ctx context.Context, lspNodeID []byte, token string, name string, caveats ...string) (string, *Record, error) {
+	_, err := ParseCaveats(caveats)
+	if err != nil {
+		return "", nil, err
+	}
+
+	rootKey, err := a.rootKey(ctx, lspNodeID, true)
+	if err != nil {
+		return "", nil, err
+	}
+
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	if err != nil {
+		return "", nil, fmt.Errorf("rand.Read() error: %w", err)
+	}
+
+	r := &Record{
+		ID:        id,
+		LspNodeID: lspNodeID,
+		Token:     token,
+		Name:      name,
+		Caveats:   caveats,
+		CreatedAt: time.Now().UTC(),
+	}
+	err = a.store.Create(ctx, r)
+	if err != nil {
+		return "", nil, fmt.Errorf("Create() error: %w", err)
+	}
+
+	m := New(rootKey, id)
+	for _, caveat := range caveats {
+		m.AddCaveat(caveat)
+	}
+
+	return m.Encode(), r, nil
+}
+
+// Verify returns the record and the restrictions of a valid macaroon, which
+// wasn't revoked and didn't expire at now. The restrictions include the
+// caveats holders added to the macaroon.
+func (a *Authority) Verify(ctx context.Context, s string, now time.Time) (*Record, *Restrictions, error) {
+	m, err := Decode(s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, err := a.store.Get(ctx, m.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Get(%x) error: %w", m.ID, err)
+	}
+	if r == nil {
+		return nil, nil, ErrNotFound
+	}
+
+	rootKey, err := a.rootKey(ctx, r.LspNodeID, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rootKey == nil || !m.Verify(rootKey) {
+		return nil, nil, ErrInvalid
+	}
+
+	if r.RevokedAt != nil && !now.Before(*r.RevokedAt) {
+		return nil, nil, ErrRevoked
+	}
+
+	restrictions, err := ParseCaveats(m.Caveats)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if restrictions.Expired(now) {
+		return nil, nil, ErrExpired
+	}
+
+	return r, restrictions, nil
+}
+
+func (a *Authority) List(ctx context.Context) ([]*Record, error) {
+	return a.store.List(ctx)
+}
+
+func (a *Authority) Revoke(ctx context.Context, id []byte) error {
+	return a.store.Revoke(ctx, id, time.Now())
+}
+
+// rootKey returns the root key of the node. With create, a root key is
+// generated for nodes without one.
+func (a *Authority) rootKey(ctx context.Context, lspNodeID []byte, create bool) ([]byte, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if rootKey, ok := a.rootKeys[string(lspNodeID)]; ok {
+		return rootKey, nil
+	}
+
+	rootKey, err := a.store.GetRootKey(ctx, lspNodeID)
+	if err != nil {
+		return nil, fmt.Errorf("GetRootKey(%x) error: %w", lspNodeID, err)
+	}
+
+	if rootKey == nil && create {
+		newKey := make([]byte, 32)
+		_, err = rand.Read(newKey)
+		if err != nil {
+			return nil, fmt.Errorf("rand.Read() error: %w", err)
+		}
+
+		// Another lspd instance may have stored a root key in the meantime,
+		// so the stored key is read again.
+		err = a.store.AddRootKey(ctx, lspNodeID, newKey)
+		if err != nil {
+			return nil, fmt.Errorf("AddRootKey(%x) error: %w", lspNodeID, err)
+		}
+
+		rootKey, err = a.store.GetRootKey(ctx, lspNodeID)
+		if err != nil {
+			return nil, fmt.Errorf("GetRootKey(%x) error: %w", lspNodeID, err)
+		}
+	}
+
+	if rootKey != nil {
+		a.rootKeys[string(lspNodeID)] = rootKey
+	}
+
+	return rootKey, nil
+}
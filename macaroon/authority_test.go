@@ -0,0 +1,131 @@
+package macaroon
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDestination = "02aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+type mockStore struct {
+	rootKeys map[string][]byte
+	records  map[string]*Record
+}
+
+func newMockStore() *mockStore {
+	return &mockStore{
+		rootKeys: make(map[string][]byte),
+		records:  make(map[string]*Record),
+	}
+}
+
+func (s *mockStore) GetRootKey(ctx context.Context, lspNodeID []byte) ([]byte, error) {
+	return s.rootKeys[string(lspNodeID)], nil
+}
+
+func (s *mockStore) AddRootKey(ctx context.Context, lspNodeID []byte, rootKey []byte) error {
+	if _, ok := s.rootKeys[string(lspNodeID)]; !ok {
+		s.rootKeys[string(lspNodeID)] = rootKey
+	}
+	return nil
+}
+
+func (s *mockStore) Get(ctx context.Context, id []byte) (*Record, error) {
+	return s.records[string(id)], nil
+}
+
+func (s *mockStore) List(ctx context.Context) ([]*Record, error) {
+	var result []*Record
+	for _, r := range s.records {
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func (s *mockStore) Create(ctx context.Context, r *Record) error {
+	s.records[string(r.ID)] = r
+	return nil
+}
+
+func (s *mockStore) Revoke(ctx context.Context, id []byte, at time.Time) error {
+	r, ok := s.records[string(id)]
+	if !ok {
+		return ErrNotFound
+	}
+	r.RevokedAt = &at
+	return nil
+}
+
+func TestMintVerify(t *testing.T) {
+	ctx := context.Background()
+	a := NewAuthority(newMockStore())
+	now := time.Now()
+	m, record, err := a.Mint(ctx, []byte{1}, "token", "partner",
+		Expires(now.Add(time.Hour)),
+		Methods("OpenChannel", "ChannelInformation"),
+		MaxChannelSat(100_000),
+	)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(m, Prefix))
+
+	r, restrictions, err := a.Verify(ctx, m, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "token", r.Token)
+	assert.True(t, restrictions.AllowMethod("/lspd.ChannelOpener/OpenChannel"))
+	assert.False(t, restrictions.AllowMethod("/lspd.ChannelOpener/RegisterPayment"))
+	assert.True(t, restrictions.AllowChannel([]byte{2}, 100_000))
+	assert.False(t, restrictions.AllowChannel([]byte{2}, 100_001))
+
+	_, _, err = a.Verify(ctx, m, now.Add(time.Hour))
+	assert.ErrorIs(t, err, ErrExpired)
+
+	err = a.Revoke(ctx, record.ID)
+	assert.NoError(t, err)
+	_, _, err = a.Verify(ctx, m, time.Now())
+	assert.ErrorIs(t, err, ErrRevoked)
+}
+
+func TestAttenuate(t *testing.T) {
+	ctx := context.Background()
+	a := NewAuthority(newMockStore())
+	s, _, err := a.Mint(ctx, []byte{1}, "token", "", Methods("OpenChannel", "RegisterPayment"))
+	assert.NoError(t, err)
+
+	// Holders can add caveats, which restrict the macaroon further.
+	m, err := Decode(s)
+	assert.NoError(t, err)
+	m.AddCaveat(Methods("RegisterPayment", "GetInfo"))
+	m.AddCaveat(Destinations(testDestination))
+	_, restrictions, err := a.Verify(ctx, m.Encode(), time.Now())
+	assert.NoError(t, err)
+	assert.False(t, restrictions.AllowMethod("/lspd.ChannelOpener/OpenChannel"))
+	assert.False(t, restrictions.AllowMethod("/lspd.ChannelOpener/GetInfo"))
+	assert.True(t, restrictions.AllowMethod("/lspd.ChannelOpener/RegisterPayment"))
+	destination, _ := hex.DecodeString(testDestination)
+	assert.True(t, restrictions.AllowChannel(destination, 1_000_000))
+	assert.False(t, restrictions.AllowChannel([]byte{2}, 1))
+
+	// But they can't remove them.
+	m.Caveats = m.Caveats[:1]
+	_, _, err = a.Verify(ctx, m.Encode(), time.Now())
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestParseCaveats(t *testing.T) {
+	_, err := ParseCaveats([]string{"unknown=1"})
+	assert.Error(t, err)
+	_, err = ParseCaveats([]string{Destinations("02")})
+	assert.Error(t, err)
+	_, err = ParseCaveats([]string{MaxChannelSat(0)})
+	assert.Error(t, err)
+
+	var r *Restrictions
+	assert.True(t, r.AllowMethod("/lspd.ChannelOpener/OpenChannel"))
+	assert.True(t, r.AllowChannel([]byte{2}, 1_000_000))
+	assert.False(t, r.Expired(time.Now()))
+}
@@ -0,0 +1,145 @@
+package macaroon
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Caveats are key=value conditions. Lists are comma separated.
+const (
+	caveatExpires       = "expires"
+	caveatMethods       = "methods"
+	caveatMaxChannelSat = "max_channel_sat"
+	caveatDestinations  = "destinations"
+)
+
+// Expires restricts the macaroon to be used before t.
+func Expires(t time.Time) string {
+	return caveatExpires + "=" + t.UTC().Format(time.RFC3339)
+}
+
+// Methods restricts the macaroon to the rpcs with the names, like
+// OpenChannel.
+func Methods(methods ...string) string {
+	return caveatMethods + "=" + strings.Join(methods, ",")
+}
+
+// MaxChannelSat restricts the capacity of the channels opened with the
+// macaroon.
+func MaxChannelSat(sat int64) string {
+	return caveatMaxChannelSat + "=" + strconv.FormatInt(sat, 10)
+}
+
+// Destinations restricts the macaroon to open channels to the hex encoded
+// pubkeys.
+func Destinations(pubkeys ...string) string {
+	return caveatDestinations + "=" + strings.Join(pubkeys, ",")
+}
+
+// Restrictions are the caveats of a macaroon combined. A macaroon with
+// repeated caveats, added by holders attenuating it, is restricted by all
+// of them. Nil restrictions allow everything.
+type Restrictions struct {
+	ExpiresAt *time.Time
+
+	// Nil if any method or destination is allowed.
+	Methods      map[string]bool
+	Destinations map[string]bool
+
+	// Zero if channels of any capacity are allowed.
+	MaxChannelSat int64
+}
+
+// ParseCaveats returns the restrictions of the caveats. Unknown caveats are
+// an error, a macaroon must not be accepted with conditions lspd can't
+// check.
+func ParseCaveats(caveats []string) (*Restrictions, error) {
+	r := &Restrictions{}
+	for _, caveat := range caveats {
+		key, value, ok := strings.Cut(caveat, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid caveat '%s'", caveat)
+		}
+
+		switch key {
+		case caveatExpires:
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid caveat '%s': %w", caveat, err)
+			}
+			if r.ExpiresAt == nil || t.Before(*r.ExpiresAt) {
+				r.ExpiresAt = &t
+			}
+		case caveatMethods:
+			r.Methods = intersect(r.Methods, strings.Split(value, ","))
+		case caveatMaxChannelSat:
+			sat, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || sat <= 0 {
+				return nil, fmt.Errorf("invalid caveat '%s'", caveat)
+			}
+			if r.MaxChannelSat == 0 || sat < r.MaxChannelSat {
+				r.MaxChannelSat = sat
+			}
+		case caveatDestinations:
+			pubkeys := strings.Split(strings.ToLower(value), ",")
+			for _, pubkey := range pubkeys {
+				b, err := hex.DecodeString(pubkey)
+				if err != nil || len(b) != 33 {
+					return nil, fmt.Errorf("invalid caveat '%s': invalid pubkey '%s'", caveat, pubkey)
+				}
+			}
+			r.Destinations = intersect(r.Destinations, pubkeys)
+		default:
+			return nil, fmt.Errorf("unknown caveat '%s'", caveat)
+		}
+	}
+
+	return r, nil
+}
+
+// intersect returns the values in both allowed and values. Nil allowed
+// allows any value.
+func intersect(allowed map[string]bool, values []string) map[string]bool {
+	result := make(map[string]bool)
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v != "" && (allowed == nil || allowed[v]) {
+			result[v] = true
+		}
+	}
+
+	return result
+}
+
+// Expired returns whether the macaroon can't be used anymore at now.
+func (r *Restrictions) Expired(now time.Time) bool {
+	return r != nil && r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// AllowMethod returns whether the rpc may be called. The full method is the
+// grpc method, like /lspd.ChannelOpener/OpenChannel.
+func (r *Restrictions) AllowMethod(fullMethod string) bool {
+	if r == nil || r.Methods == nil {
+		return true
+	}
+
+	name := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	return r.Methods[name]
+}
+
+// AllowChannel returns whether a channel of capacitySat may be opened to the
+// destination.
+func (r *Restrictions) AllowChannel(destination []byte, capacitySat int64) bool {
+	if r == nil {
+		return true
+	}
+
+	if r.MaxChannelSat != 0 && capacitySat > r.MaxChannelSat {
+		return false
+	}
+
+	return r.Destinations == nil || r.Destinations[hex.EncodeToString(destination)]
+}
@@ -0,0 +1,100 @@
+// Package macaroon implements bearer credentials in the style of macaroons.
+// A macaroon is bound to an api token and carries caveats restricting what
+// it can be used for. Its signature chains an hmac over the identifier and
+// every caveat, starting from a root key stored in the database. Anyone
+// holding a macaroon can add caveats to it, but not remove them.
+package macaroon
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Prefix distinguishes macaroons from static api tokens.
+const Prefix = "mac1_"
+
+var ErrInvalid = errors.New("invalid macaroon")
+
+type Macaroon struct {
+	ID        []byte
+	Caveats   []string
+	Signature []byte
+}
+
+// encoded is the format of a macaroon after the prefix, as base64 url
+// encoded json.
+type encoded struct {
+	ID        []byte   `json:"i"`
+	Caveats   []string `json:"c,omitempty"`
+	Signature []byte   `json:"s"`
+}
+
+// New returns a macaroon without caveats for the identifier, signed with
+// the root key.
+func New(rootKey []byte, id []byte) *Macaroon {
+	return &Macaroon{
+		ID:        id,
+		Signature: sign(rootKey, id),
+	}
+}
+
+// AddCaveat restricts the macaroon further.
+func (m *Macaroon) AddCaveat(caveat string) {
+	m.Caveats = append(m.Caveats, caveat)
+	m.Signature = sign(m.Signature, []byte(caveat))
+}
+
+// Verify returns whether the macaroon was signed with the root key, and its
+// caveats weren't tampered with.
+func (m *Macaroon) Verify(rootKey []byte) bool {
+	sig := sign(rootKey, m.ID)
+	for _, caveat := range m.Caveats {
+		sig = sign(sig, []byte(caveat))
+	}
+
+	return hmac.Equal(sig, m.Signature)
+}
+
+func (m *Macaroon) Encode() string {
+	// Marshalling a struct of byte slices and strings doesn't fail.
+	b, _ := json.Marshal(&encoded{
+		ID:        m.ID,
+		Caveats:   m.Caveats,
+		Signature: m.Signature,
+	})
+
+	return Prefix + base64.RawURLEncoding.EncodeToString(b)
+}
+
+func Decode(s string) (*Macaroon, error) {
+	if !strings.HasPrefix(s, Prefix) {
+		return nil, ErrInvalid
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(s, Prefix))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	var e encoded
+	err = json.Unmarshal(b, &e)
+	if err != nil || len(e.ID) == 0 || len(e.Signature) != sha256.Size {
+		return nil, ErrInvalid
+	}
+
+	return &Macaroon{
+		ID:        e.ID,
+		Caveats:   e.Caveats,
+		Signature: e.Signature,
+	}, nil
+}
+
+func sign(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// runMacaroon mints and revokes macaroons with the admin api.
+func runMacaroon(args []string) {
+	if len(args) == 0 || (args[0] != "mint" && args[0] != "revoke") {
+		fmt.Fprintln(os.Stderr, "usage: lspd macaroon mint|revoke [flags]")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("macaroon "+args[0], flag.ExitOnError)
+	address := flags.String("admin-address", os.Getenv("ADMIN_LISTEN_ADDRESS"), "host:port or unix:<path> of the lspd admin api")
+	adminToken := flags.String("admin-token", os.Getenv("ADMIN_TOKEN"), "token of the lspd admin api")
+	token := flags.String("token", "", "mint: token the macaroon authenticates as")
+	name := flags.String("name", "", "mint: name of the macaroon")
+	expiry := flags.Duration("expiry", 0, "mint: time the macaroon is valid, unlimited if zero")
+	methods := flags.String("methods", "", "mint: comma separated rpcs the macaroon may call, like OpenChannel, all if empty")
+	maxChannelSat := flags.Int64("max-channel-sat", 0, "mint: maximum capacity of the channels opened with the macaroon, unlimited if zero")
+	destinations := flags.String("destinations", "", "mint: comma separated pubkeys the macaroon may open channels to, all if empty")
+	id := flags.String("id", "", "revoke: id of the macaroon")
+	flags.Parse(args[1:])
+
+	if *address == "" {
+		fmt.Fprintln(os.Stderr, "macaroon: set --admin-address or ADMIN_LISTEN_ADDRESS")
+		os.Exit(1)
+	}
+
+	var path string
+	var req interface{}
+	switch args[0] {
+	case "mint":
+		path = "/macaroons"
+		req = &mintMacaroonRequest{
+			Token:         *token,
+			Name:          *name,
+			Expiry:        int64(*expiry / time.Second),
+			Methods:       splitList(*methods),
+			MaxChannelSat: *maxChannelSat,
+			Destinations:  splitList(*destinations),
+		}
+	case "revoke":
+		path = "/macaroons/revoke"
+		req = &revokeMacaroonRequest{ID: *id}
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "macaroon: %v\n", err)
+		os.Exit(1)
+	}
+
+	client := newAdminClient(*address, *adminToken, 30*time.Second)
+	resp, err := client.Post(path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "macaroon: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "macaroon: unexpected status %s: %s", resp.Status, msg)
+		os.Exit(1)
+	}
+
+	if args[0] == "revoke" {
+		fmt.Printf("macaroon %s revoked\n", *id)
+		return
+	}
+
+	io.Copy(os.Stdout, resp.Body)
+}
+
+// splitList returns the values of a comma separated list.
+func splitList(s string) []string {
+	var result []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
@@ -1,24 +1,39 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"strings"
 	"sync"
 	"syscall"
 
-	"github.com/breez/lspd/chain"
-	"github.com/breez/lspd/cln"
+	"github.com/breez/lspd/alerting"
+	"github.com/breez/lspd/audit"
 	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/events"
+	"github.com/breez/lspd/exposure"
+	"github.com/breez/lspd/funding"
 	"github.com/breez/lspd/interceptor"
-	"github.com/breez/lspd/lnd"
+	"github.com/breez/lspd/leader"
+	"github.com/breez/lspd/lifecycle"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/liquidity"
+	"github.com/breez/lspd/lsps0"
+	"github.com/breez/lspd/macaroon"
 	"github.com/breez/lspd/mempool"
 	"github.com/breez/lspd/notifications"
+	"github.com/breez/lspd/openchannel"
 	"github.com/breez/lspd/postgresql"
+	"github.com/breez/lspd/quota"
+	"github.com/breez/lspd/reconcile"
+	"github.com/breez/lspd/refunds"
+	"github.com/breez/lspd/scoring"
+	"github.com/breez/lspd/systemd"
 	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 func main() {
@@ -31,120 +46,522 @@ func main() {
 		return
 	}
 
-	n := os.Getenv("NODES")
-	var nodes []*config.NodeConfig
-	err := json.Unmarshal([]byte(n), &nodes)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate()
+			return
+		case "cln-plugin":
+			runClnPlugin()
+			return
+		case "install-plugin":
+			runInstallPlugin(os.Args[2:])
+			return
+		case "onion-fixture":
+			runOnionFixture()
+			return
+		case "top":
+			runTop(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "config":
+			runConfig(os.Args[2:])
+			return
+		case "gen-api":
+			runGenApi(os.Args[2:])
+			return
+		case "macaroon":
+			runMacaroon(os.Args[2:])
+			return
+		case "reencrypt-db":
+			runReencryptDb(os.Args[2:])
+			return
+		case "simulate":
+			runSimulate(os.Args[2:])
+			return
+		}
+	}
+
+	nodes, err := loadNodes()
 	if err != nil {
-		log.Fatalf("failed to unmarshal NODES env: %v", err)
+		log.Fatalf("failed to load nodes: %v", err)
 	}
 
-	if len(nodes) == 0 {
-		log.Fatalf("need at least one node configured in NODES.")
+	resolver, err := newSecretResolver()
+	if err != nil {
+		log.Fatalf("failed to set up secrets: %v", err)
 	}
 
-	mempoolUrl := os.Getenv("MEMPOOL_API_BASE_URL")
-	if mempoolUrl == "" {
-		log.Fatalf("No mempool url configured.")
+	// The mempool api is also used to look up the fees of funding
+	// transactions in exports.
+	var mempoolClient *mempool.MempoolClient
+	var transactionFees transactionFees
+	if mempoolUrl := os.Getenv("MEMPOOL_API_BASE_URL"); mempoolUrl != "" {
+		mempoolClient, err = mempool.NewMempoolClient(mempoolUrl)
+		if err != nil {
+			log.Fatalf("failed to initialize mempool client: %v", err)
+		}
+		transactionFees = mempoolClient
 	}
 
-	feeEstimator, err := mempool.NewMempoolClient(mempoolUrl)
+	feeEstimator, feeStrategy := newFeeEstimator(mempoolClient)
+
+	dbCipher, err := databaseCipher()
 	if err != nil {
-		log.Fatalf("failed to initialize mempool client: %v", err)
-	}
-
-	var feeStrategy chain.FeeStrategy
-	envFeeStrategy := os.Getenv("MEMPOOL_PRIORITY")
-	switch strings.ToLower(envFeeStrategy) {
-	case "minimum":
-		feeStrategy = chain.FeeStrategyMinimum
-	case "economy":
-		feeStrategy = chain.FeeStrategyEconomy
-	case "hour":
-		feeStrategy = chain.FeeStrategyHour
-	case "halfhour":
-		feeStrategy = chain.FeeStrategyHalfHour
-	case "fastest":
-		feeStrategy = chain.FeeStrategyFastest
-	default:
-		feeStrategy = chain.FeeStrategyEconomy
-	}
-	log.Printf("using mempool api for fee estimation: %v, fee strategy: %v:%v", mempoolUrl, envFeeStrategy, feeStrategy)
+		log.Fatalf("failed to load database encryption keys: %v", err)
+	}
 
 	databaseUrl := os.Getenv("DATABASE_URL")
-	pool, err := postgresql.PgConnect(databaseUrl)
+	var pool *pgxpool.Pool
+	err = waitFor("postgres", func() error {
+		var err error
+		pool, err = postgresql.PgConnect(databaseUrl)
+		return err
+	})
 	if err != nil {
 		log.Fatalf("pgConnect() error: %v", err)
 	}
 
-	interceptStore := postgresql.NewPostgresInterceptStore(pool)
+	if os.Getenv("AUTO_MIGRATE") == "true" {
+		err = postgresql.Migrate(context.Background(), pool)
+		if err != nil {
+			log.Fatalf("migrate error: %v", err)
+		}
+	}
+
+	interceptStore := postgresql.NewPostgresInterceptStore(pool, dbCipher)
 	forwardingStore := postgresql.NewForwardingEventStore(pool)
 	notificationsStore := postgresql.NewNotificationsStore(pool)
-	notificationService := notifications.NewNotificationService(notificationsStore)
+	tokenStore := postgresql.NewTokenStore(pool, dbCipher)
+	macaroons := macaroon.NewAuthority(postgresql.NewMacaroonStore(pool, dbCipher))
+	statsStore := postgresql.NewStatsStore(pool, dbCipher)
+	channelStore := postgresql.NewChannelStore(pool)
+	reconciliationStore := postgresql.NewReconciliationStore(pool, dbCipher)
+	htlcStore := postgresql.NewHtlcStore(pool, dbCipher)
+	peerStore := postgresql.NewPeerStore(pool)
+	channelAliasStore := postgresql.NewChannelAliasStore(pool)
+	exposureStore := postgresql.NewExposureStore(pool)
+	channelOpenJobStore := postgresql.NewChannelOpenJobStore(pool)
+	scoreStore := postgresql.NewScoreStore(pool)
+	auditStore := postgresql.NewAuditStore(pool)
+	quotaStore := postgresql.NewQuotaStore(pool, dbCipher)
+	refundStore := postgresql.NewRefundStore(pool, dbCipher)
+
+	// Replicas sharing the database elect a leader per node, which runs the
+	// interceptor and the workers of the node. All replicas serve the api.
+	var leaseStore leader.Store
+	if os.Getenv("LEADER_ELECTION") == "true" {
+		leaseStore = postgresql.NewLeaseStore(pool)
+		log.Printf("Leader election enabled, running as replica %s.", replicaID())
+	}
+	pushers, err := newPushers()
+	if err != nil {
+		log.Fatalf("newPushers() error: %v", err)
+	}
+	notificationService := notifications.NewNotificationService(notificationsStore, pushers...)
+
+	// Side effects of what happens on the nodes subscribe to the event bus,
+	// rather than being called by the components they happen in.
+	bus := events.NewBus(log.Default())
+	events.SubscribeMetrics(bus)
+	notificationService.Subscribe(bus)
+	interceptor.SubscribeOpenChannelEmails(bus)
+
+	// Intercepted htlcs are recorded to replay them with `lspd simulate`.
+	var interceptionLog *interceptor.InterceptionLog
+	if path := os.Getenv("INTERCEPTION_LOG"); path != "" {
+		interceptionLog, err = interceptor.OpenInterceptionLog(path)
+		if err != nil {
+			log.Fatalf("failed to open the interception log: %v", err)
+		}
+		defer interceptionLog.Close()
+	}
 
 	var interceptors []interceptor.HtlcInterceptor
+	var electors []*leader.Elector
+	var workers []nodeWorker
+	var nodeInterceptors []*interceptor.Interceptor
+	interceptorsByNode := make(map[string]*interceptor.Interceptor)
+	var circuitBreakers []*interceptor.CircuitBreaker
+	var rebalancers []*liquidity.Rebalancer
+	var consolidators []*liquidity.Consolidator
+	var psbtCoordinators []*funding.Coordinator
+	var channelTrackers []*lifecycle.Tracker
+	channelTrackersByNode := make(map[string]*lifecycle.Tracker)
+	leaseManagers := make(map[string]*lifecycle.LeaseManager)
+	var reconcilers []*reconcile.Reconciler
+	var exposureTrackers []*exposure.Tracker
+	var confirmationWatchers []*lifecycle.ConfirmationWatcher
+	var doubleSpendMonitors []*lifecycle.DoubleSpendMonitor
+	var alerters []*alerting.Alerter
+	var refundManagers []*refunds.Manager
+	liquidityManagers := make(map[string]*liquidity.Manager)
+	openChannelRunners := make(map[string]*openchannel.Runner)
+	var scorers []*scoring.Scorer
+	scorersByNode := make(map[string]*scoring.Scorer)
+	lsps0Servers := make(map[string]*lsps0.Server)
+	var auditLogs []*audit.Log
+	quotaLimiters := make(map[string]*quota.Limiter)
+	htlcStores := &htlcInterceptorStores{
+		interceptStore:  interceptStore,
+		forwardingStore: forwardingStore,
+		htlcStore:       htlcStore,
+	}
 	for _, node := range nodes {
-		var htlcInterceptor interceptor.HtlcInterceptor
-		if node.Lnd != nil {
-			client, err := lnd.NewLndClient(node.Lnd)
-			if err != nil {
-				log.Fatalf("failed to initialize LND client: %v", err)
-			}
+		logger := newNodeLogger(node)
+		if node.ShadowMode {
+			logger.Printf("WARN: shadow mode enabled. Htlcs are only observed and always resumed.")
+		}
 
-			client.StartListeners()
-			fwsync := lnd.NewForwardingHistorySync(client, interceptStore, forwardingStore)
-			interceptor := interceptor.NewInterceptor(client, node, interceptStore, feeEstimator, feeStrategy, notificationService)
-			htlcInterceptor, err = lnd.NewLndHtlcInterceptor(node, client, fwsync, interceptor)
-			if err != nil {
-				log.Fatalf("failed to initialize LND interceptor: %v", err)
+		elector := newNodeElector(leaseStore, node, logger)
+		elected := electedChannel(elector)
+		addWorker := func(w interface{ Start() error }) {
+			workers = append(workers, nodeWorker{elected: elected, start: w.Start})
+		}
+
+		client, err := newLightningNode(node, resolver, logger)
+		if err != nil {
+			log.Fatalf("failed to initialize the client of node %s: %v", node.Label(), err)
+		}
+
+		err = waitFor(fmt.Sprintf("%s node %s", client.Backend(), nodeAddress(node)), func() error {
+			return initNodeInfo(client, node, logger)
+		})
+		if err != nil {
+			log.Fatalf("failed to get %s node info: %v", client.Backend(), err)
+		}
+
+		var psbtFunding *funding.Coordinator
+		if node.PsbtFunding != nil {
+			if funder, ok := client.(lightning.PsbtFunder); ok {
+				psbtFunding = funding.NewCoordinator(funder, node, logger)
+				psbtCoordinators = append(psbtCoordinators, psbtFunding)
+			} else {
+				logger.Printf("WARN: psbtFunding is not supported on %s nodes. Funding channels with the node wallet.", client.Backend())
 			}
 		}
 
-		if node.Cln != nil {
-			client, err := cln.NewClnClient(node.Cln.SocketPath)
-			if err != nil {
-				log.Fatalf("failed to initialize CLN client: %v", err)
+		if _, ok := client.(lightning.Splicer); node.Splicing != nil && !ok {
+			logger.Printf("WARN: splicing is not supported on %s nodes. Opening new channels instead.", client.Backend())
+		}
+
+		if node.CoinControl != nil {
+			if controller, ok := client.(lightning.CoinController); ok {
+				controller.SetCoinControl(coinControl(node))
+			} else {
+				logger.Printf("WARN: coinControl is not supported on %s nodes. The node selects the funding inputs.", client.Backend())
 			}
+		}
 
-			interceptor := interceptor.NewInterceptor(client, node, interceptStore, feeEstimator, feeStrategy, notificationService)
-			htlcInterceptor, err = cln.NewClnHtlcInterceptor(node, client, interceptor)
-			if err != nil {
-				log.Fatalf("failed to initialize CLN interceptor: %v", err)
+		if node.DualFunding != nil {
+			if funder, ok := client.(lightning.DualFunder); ok {
+				err = funder.SetFunderPolicy(context.Background(), funderPolicy(node.DualFunding))
+				if err != nil {
+					logger.Printf("WARN: failed to set the funder policy of the node: %v", err)
+				}
+			} else {
+				logger.Printf("WARN: dualFunding is not supported on %s nodes. Not contributing to channels opened by clients.", client.Backend())
 			}
 		}
 
-		if htlcInterceptor == nil {
-			log.Fatalf("node has to be either cln or lnd")
+		channelTracker := lifecycle.NewTracker(client, channelStore, node, logger)
+		channelTrackers = append(channelTrackers, channelTracker)
+		channelTrackersByNode[node.NodePubkey] = channelTracker
+		addWorker(channelTracker)
+		if node.ChannelLease != nil {
+			leaseManager := lifecycle.NewLeaseManager(channelStore, notificationService, node, logger)
+			leaseManager.Subscribe(bus)
+			leaseManagers[node.NodePubkey] = leaseManager
+			addWorker(leaseManager)
+		}
+		openChannelRunner := openchannel.NewRunner(client, channelOpenJobStore, node, logger)
+		openChannelRunners[node.NodePubkey] = openChannelRunner
+		addWorker(openChannelRunner)
+		reconciler := reconcile.NewReconciler(client, reconciliationStore, node, logger)
+		reconciler.SetEventBus(bus)
+		reconcilers = append(reconcilers, reconciler)
+		addWorker(reconciler)
+		client.SetPeerTracker(newPeerTracker(node, peerStore, bus, logger))
+		aliases := newChannelAliases(node, client, channelAliasStore, logger)
+		client.SetChannelAliases(aliases)
+		client.StartListeners()
+		if node.Lsps0 != nil {
+			lsps0Server := lsps0.NewServer(client.NewCustomMsgClient(), node, logger)
+			lsps0Servers[node.NodePubkey] = lsps0Server
+			addWorker(lsps0Server)
+		}
+		liquidityManager := liquidity.NewManager(client, node, feeEstimator, feeStrategy, logger)
+		liquidityManagers[node.NodePubkey] = liquidityManager
+		if node.PeerSwap != nil {
+			if swapper, ok := client.(liquidity.Swapper); ok {
+				rebalancer := liquidity.NewRebalancer(swapper, liquidityManager, node.PeerSwap, logger)
+				rebalancers = append(rebalancers, rebalancer)
+				addWorker(rebalancer)
+			} else {
+				logger.Printf("WARN: peerSwap is not supported on %s nodes. Not rebalancing.", client.Backend())
+			}
+		}
+		if node.Consolidation != nil {
+			if sweeper, ok := client.(liquidity.Sweeper); ok {
+				var cc *lightning.CoinControl
+				if node.CoinControl != nil {
+					cc = coinControl(node)
+				}
+				consolidator := liquidity.NewConsolidator(sweeper, feeEstimator, cc, node, logger)
+				consolidators = append(consolidators, consolidator)
+				addWorker(consolidator)
+			} else {
+				logger.Printf("WARN: consolidation is not supported on %s nodes. Not consolidating utxos.", client.Backend())
+			}
+		}
+		circuitBreaker := interceptor.NewCircuitBreaker(client, node, feeEstimator, feeStrategy, logger)
+		circuitBreakers = append(circuitBreakers, circuitBreaker)
+		addWorker(circuitBreaker)
+		interceptor := interceptor.NewInterceptor(client, node, interceptStore, tokenStore, feeEstimator, feeStrategy, notificationService, circuitBreaker, psbtFunding, logger)
+		interceptor.SetChannelAliases(aliases)
+		interceptor.SetEventBus(bus)
+		quotaLimiter := quota.NewLimiter(quotaStore, node, logger)
+		quotaLimiters[node.NodePubkey] = quotaLimiter
+		interceptor.SetQuotaLimiter(quotaLimiter)
+		if interceptionLog != nil {
+			interceptor.SetInterceptionLog(interceptionLog)
+		}
+		if elector != nil {
+			interceptor.SetReplicated(true)
+		}
+		confirmationWatcher := lifecycle.NewConfirmationWatcher(client, channelStore, bus, node, logger)
+		confirmationWatchers = append(confirmationWatchers, confirmationWatcher)
+		addWorker(confirmationWatcher)
+		if node.Hodl != nil || node.ZeroConfExposure != nil {
+			exposureTracker := exposure.NewTracker(client, exposureStore, node, logger)
+			exposureTrackers = append(exposureTrackers, exposureTracker)
+			addWorker(exposureTracker)
+			interceptor.SetExposureLimiter(exposureTracker)
+			confirmationWatcher.AddListener(exposureTracker)
+		}
+		if node.DoubleSpendMonitor != nil {
+			if mempoolClient == nil {
+				logger.Printf("WARN: double spend monitoring requires MEMPOOL_API_BASE_URL, not watching funding transactions for double spends.")
+			} else {
+				monitor := lifecycle.NewDoubleSpendMonitor(mempoolClient, channelStore, bus, node, logger)
+				doubleSpendMonitors = append(doubleSpendMonitors, monitor)
+				addWorker(monitor)
+				interceptor.SetDoubleSpendChecker(monitor)
+			}
+		}
+		if node.Refunds != nil {
+			refundManager := refunds.NewManager(client, refundStore, channelStore, node, logger)
+			refundManagers = append(refundManagers, refundManager)
+			addWorker(refundManager)
+			interceptor.SetRefundCredits(refundManager)
+		}
+		if node.Scoring != nil {
+			scorer := scoring.NewScorer(client, scoreStore, channelStore, node, logger)
+			scorers = append(scorers, scorer)
+			scorersByNode[node.NodePubkey] = scorer
+			addWorker(scorer)
+			interceptor.SetScorer(scorer)
+			reconciler.AddListener(scorer)
+		}
+		nodeInterceptors = append(nodeInterceptors, interceptor)
+		interceptorsByNode[node.NodePubkey] = interceptor
+		htlcInterceptor, err := newHtlcInterceptor(node, client, interceptor, htlcStores, logger)
+		if err != nil {
+			log.Fatalf("failed to initialize %s interceptor: %v", client.Backend(), err)
 		}
 
 		interceptors = append(interceptors, htlcInterceptor)
+		if node.Alerting != nil {
+			alerter := alerting.NewAlerter(htlcInterceptor, pool, client, node, logger)
+			alerter.Subscribe(bus)
+			alerters = append(alerters, alerter)
+			addWorker(alerter)
+		}
+		electors = append(electors, elector)
+		if node.AuditLog != nil {
+			auditLog := audit.NewLog(auditStore, node, logger)
+			auditLog.Subscribe(bus)
+			auditLogs = append(auditLogs, auditLog)
+			addWorker(auditLog)
+		}
 	}
 
 	address := os.Getenv("LISTEN_ADDRESS")
+	restAddress := os.Getenv("REST_LISTEN_ADDRESS")
 	certMagicDomain := os.Getenv("CERTMAGIC_DOMAIN")
-	cs := NewChannelOpenerServer(interceptStore)
-	ns := notifications.NewNotificationsServer(notificationsStore)
-	s, err := NewGrpcServer(nodes, address, certMagicDomain, cs, ns)
+	var certReloader *certReloader
+	tlsCertFile := os.Getenv("TLS_CERT_FILE")
+	tlsKeyFile := os.Getenv("TLS_KEY_FILE")
+	if tlsCertFile != "" || tlsKeyFile != "" {
+		if tlsCertFile == "" || tlsKeyFile == "" {
+			log.Fatalf("TLS_CERT_FILE and TLS_KEY_FILE have to be set together")
+		}
+		if certMagicDomain != "" {
+			log.Fatalf("TLS_CERT_FILE cannot be combined with CERTMAGIC_DOMAIN")
+		}
+
+		certReloader, err = NewCertReloader(tlsCertFile, tlsKeyFile)
+		if err != nil {
+			log.Fatalf("failed to load tls certificate: %v", err)
+		}
+	}
+	cs := NewChannelOpenerServer(interceptStore, tokenStore, liquidityManagers, interceptorsByNode, openChannelRunners, scorersByNode, quotaLimiters, lsps0Servers, channelTrackersByNode, leaseManagers)
+	ns := notifications.NewNotificationsServer(notificationsStore, notificationService)
+	s, err := NewGrpcServer(nodes, address, restAddress, certMagicDomain, certReloader, rpcTimeout(), rpcMiddlewareOrder(), tokenStore, macaroons, resolver, cs, ns)
 	if err != nil {
 		log.Fatalf("failed to initialize grpc server: %v", err)
 	}
 
+	reloadConfig := func() error {
+		next, err := loadNodes()
+		if err != nil {
+			return err
+		}
+
+		err = config.Reload(nodes, next)
+		if err != nil {
+			return err
+		}
+
+		s.ReloadTokens()
+		log.Printf("Reloaded the config of %d nodes.", len(nodes))
+		return nil
+	}
+
+	var admin *adminServer
+	adminAddress := os.Getenv("ADMIN_LISTEN_ADDRESS")
+	if adminAddress != "" {
+		adminToken := os.Getenv("ADMIN_TOKEN")
+		if adminToken == "" {
+			log.Fatalf("ADMIN_TOKEN has to be set with ADMIN_LISTEN_ADDRESS")
+		}
+		admin = NewAdminServer(adminAddress, adminToken, nodes, reloadConfig, interceptors, nodeInterceptors, circuitBreakers, liquidityManagers, interceptStore, tokenStore, macaroons, statsStore, psbtCoordinators, channelTrackers, reconcilers, postgresql.NewDebugStore(pool, dbCipher), notificationService, postgresql.NewAccountingStore(pool, dbCipher), transactionFees, scorers, auditLogs)
+	}
+
+	cleanup := newCleanupJob(interceptStore, nodeInterceptors)
+
 	var wg sync.WaitGroup
 	wg.Add(len(interceptors) + 1)
 
+	stopped := make(chan struct{})
+	var stopOnce sync.Once
 	stopInterceptors := func() {
+		stopOnce.Do(func() {
+			close(stopped)
+			err := systemd.Notify(systemd.Stopping)
+			if err != nil {
+				log.Printf("Failed to notify systemd: %v", err)
+			}
+		})
 		for _, interceptor := range interceptors {
 			interceptor.Stop()
 		}
+		// The leases are released once the htlcs in flight are handled.
+		for _, elector := range electors {
+			if elector != nil {
+				elector.Stop()
+			}
+		}
+		for _, rebalancer := range rebalancers {
+			rebalancer.Stop()
+		}
+		for _, consolidator := range consolidators {
+			consolidator.Stop()
+		}
+		for _, tracker := range channelTrackers {
+			tracker.Stop()
+		}
+		for _, manager := range leaseManagers {
+			manager.Stop()
+		}
+		for _, reconciler := range reconcilers {
+			reconciler.Stop()
+		}
+		for _, tracker := range exposureTrackers {
+			tracker.Stop()
+		}
+		for _, watcher := range confirmationWatchers {
+			watcher.Stop()
+		}
+		for _, breaker := range circuitBreakers {
+			breaker.Stop()
+		}
+		for _, monitor := range doubleSpendMonitors {
+			monitor.Stop()
+		}
+		for _, alerter := range alerters {
+			alerter.Stop()
+		}
+		for _, manager := range refundManagers {
+			manager.Stop()
+		}
+		for _, runner := range openChannelRunners {
+			runner.Stop()
+		}
+		for _, scorer := range scorers {
+			scorer.Stop()
+		}
+		for _, server := range lsps0Servers {
+			server.Stop()
+		}
+		for _, auditLog := range auditLogs {
+			auditLog.Stop()
+		}
+		cleanup.Stop()
 	}
 
-	for _, interceptor := range interceptors {
-		i := interceptor
+	stopAdmin := func() {
+		if admin != nil {
+			admin.Stop()
+		}
+	}
+
+	for _, elector := range electors {
+		if elector == nil {
+			continue
+		}
+
+		e := elector
+		wg.Add(1)
 		go func() {
-			err := i.Start()
+			err := e.Start()
 			if err == nil {
-				log.Printf("Interceptor stopped.")
+				log.Printf("Leader election stopped.")
 			} else {
-				log.Printf("FATAL. Interceptor stopped with error: %v", err)
+				log.Printf("FATAL. Leader election stopped with error: %v", err)
+			}
+
+			wg.Done()
+
+			// A replica that lost the lease of a node stops everything, so it
+			// restarts as a follower.
+			s.Stop()
+			stopInterceptors()
+			stopAdmin()
+		}()
+	}
+
+	for n, interceptor := range interceptors {
+		i := interceptor
+		nodeInterceptor := nodeInterceptors[n]
+		elected := electedChannel(electors[n])
+		go func() {
+			select {
+			case <-elected:
+				err := nodeInterceptor.LoadScids(context.Background())
+				if err != nil {
+					log.Printf("Failed to load the scids of node %s: %v", nodeInterceptor.Node().Label(), err)
+				}
+
+				err = i.Start()
+				if err == nil {
+					log.Printf("Interceptor stopped.")
+				} else {
+					log.Printf("FATAL. Interceptor stopped with error: %v", err)
+				}
+			case <-stopped:
 			}
 
 			wg.Done()
@@ -152,11 +569,71 @@ func main() {
 			// If any interceptor stops, stop everything, so we're able to restart using systemd.
 			s.Stop()
 			stopInterceptors()
+			stopAdmin()
+		}()
+	}
+
+	for _, worker := range workers {
+		w := worker
+		go func() {
+			select {
+			case <-w.elected:
+				w.start()
+			case <-stopped:
+			}
+		}()
+	}
+	go cleanup.Start()
+
+	if admin != nil {
+		wg.Add(1)
+		go func() {
+			err := admin.Start()
+			if err == nil {
+				log.Printf("Admin server stopped.")
+			} else {
+				log.Printf("FATAL. Admin server stopped with error: %v", err)
+			}
+
+			wg.Done()
+
+			// If the admin server stops, stop everything else, so we're able to restart using systemd.
+			s.Stop()
+			stopInterceptors()
 		}()
 	}
 
 	go func() {
-		err := s.Start()
+		// Only expose the public api once all interceptors are connected to
+		// their node, so registered payments can actually be handled.
+		// With leader election the api is served by all replicas, whether
+		// they lead a node or not.
+		var err error
+		for i, interceptor := range interceptors {
+			if electors[i] != nil {
+				continue
+			}
+			err = waitStarted(fmt.Sprintf("htlc interceptor of node %s", nodes[i].Label()), interceptor)
+			if err != nil {
+				break
+			}
+		}
+
+		select {
+		case <-stopped:
+		default:
+			if err == nil {
+				// The grpc server starts listening right away, so tell systemd
+				// the service is ready before blocking on it.
+				nerr := systemd.Notify(systemd.Ready)
+				if nerr != nil {
+					log.Printf("Failed to notify systemd: %v", nerr)
+				}
+				go runWatchdog(nodes, interceptors, electors, stopped)
+				err = s.Start()
+			}
+		}
+
 		if err == nil {
 			log.Printf("GRPC server stopped.")
 		} else {
@@ -167,6 +644,19 @@ func main() {
 
 		// If the server stops, stop everything else, so we're able to restart using systemd.
 		stopInterceptors()
+		stopAdmin()
+	}()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Printf("Received SIGHUP. Reloading the nodes config.")
+			err := reloadConfig()
+			if err != nil {
+				log.Printf("Failed to reload the nodes config, keeping the running config: %v", err)
+			}
+		}
 	}()
 
 	c := make(chan os.Signal, 1)
@@ -178,8 +668,108 @@ func main() {
 		// Stop everything gracefully on stop signal
 		s.Stop()
 		stopInterceptors()
+		stopAdmin()
 	}()
 
 	wg.Wait()
+
+	// Let the subscribers handle the events published while stopping.
+	bus.Close()
 	log.Printf("lspd exited")
 }
+
+// loadNodes reads the node configs from the file in NODES_FILE if set,
+// otherwise from the NODES env, and validates them. Only the file can be
+// changed for a reload.
+func loadNodes() ([]*config.NodeConfig, error) {
+	nodes, err := config.Load(os.Getenv("NODES_FILE"))
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.Validate(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// newNodeLogger returns a logger that prefixes every line with the node label.
+func newNodeLogger(node *config.NodeConfig) *log.Logger {
+	return log.New(
+		log.Writer(),
+		fmt.Sprintf("[%s] ", node.Label()),
+		log.Flags()|log.Lmsgprefix,
+	)
+}
+
+// newPeerTracker creates the tracker of the connected peers of the node, with
+// the peer states persisted before the last shutdown. Peers connecting are
+// published on the bus.
+func newPeerTracker(
+	node *config.NodeConfig,
+	store lightning.PeerStateStore,
+	bus *events.Bus,
+	logger *log.Logger,
+) *lightning.PeerTracker {
+	nodeID, err := hex.DecodeString(node.NodePubkey)
+	if err != nil {
+		log.Fatalf("failed to decode node pubkey %s: %v", node.NodePubkey, err)
+	}
+
+	tracker := lightning.NewPeerTracker(nodeID, node.Tenant, store, logger)
+	tracker.SetEventBus(bus, node)
+	err = tracker.Load(context.Background())
+	if err != nil {
+		logger.Printf("Failed to load persisted peer states: %v", err)
+	}
+
+	return tracker
+}
+
+// newChannelAliases creates the aliases of the channels lspd opens on the
+// node, with the aliases recorded before the last shutdown.
+func newChannelAliases(
+	node *config.NodeConfig,
+	client lightning.Client,
+	store lightning.ChannelAliasStore,
+	logger *log.Logger,
+) *lightning.ChannelAliases {
+	nodeID, err := hex.DecodeString(node.NodePubkey)
+	if err != nil {
+		log.Fatalf("failed to decode node pubkey %s: %v", node.NodePubkey, err)
+	}
+
+	aliases := lightning.NewChannelAliases(nodeID, node.Tenant, client, store, logger)
+	err = aliases.Load(context.Background())
+	if err != nil {
+		logger.Printf("Failed to load the channel aliases: %v", err)
+	}
+
+	return aliases
+}
+
+// initNodeInfo sets the name and pubkey of the node if not set in config, so
+// every log line, metric and database row can be attributed to the node.
+func initNodeInfo(
+	client lightning.Client,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) error {
+	info, err := client.GetInfo()
+	if err != nil {
+		return fmt.Errorf("client.GetInfo() error: %w", err)
+	}
+
+	if node.Name == "" {
+		node.Name = info.Alias
+	}
+
+	if node.NodePubkey == "" {
+		node.NodePubkey = info.Pubkey
+	}
+
+	logger.SetPrefix(fmt.Sprintf("[%s] ", node.Label()))
+	return nil
+}
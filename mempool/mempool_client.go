@@ -88,3 +88,120 @@ func (m *MempoolClient) EstimateFeeRate(
 		SatPerVByte: rate,
 	}, nil
 }
+
+type transactionResponse struct {
+	Fee int64 `json:"fee"`
+}
+
+// TransactionFee returns the fee paid by the transaction in satoshi.
+func (m *MempoolClient) TransactionFee(ctx context.Context, txid string) (int64, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"GET",
+		m.apiBaseUrl+"tx/"+txid,
+		nil,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("http.NewRequestWithContext error: %w", err)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("httpClient.Do error: %w", err)
+	}
+
+	defer resp.Body.Close()
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		return 0, fmt.Errorf("error statuscode %v", resp.StatusCode)
+	}
+
+	var body transactionResponse
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return body.Fee, nil
+}
+
+type transactionInputsResponse struct {
+	Vin []struct {
+		Txid string `json:"txid"`
+		Vout uint32 `json:"vout"`
+	} `json:"vin"`
+	Status struct {
+		Confirmed bool `json:"confirmed"`
+	} `json:"status"`
+}
+
+// TransactionInputs returns the outpoints spent by the transaction, as
+// txid:vout, and whether it confirmed. Returns chain.ErrTransactionNotFound
+// if the transaction is neither in the mempool nor in the chain.
+func (m *MempoolClient) TransactionInputs(ctx context.Context, txid string) ([]string, bool, error) {
+	var body transactionInputsResponse
+	err := m.get(ctx, "tx/"+txid, &body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var inputs []string
+	for _, in := range body.Vin {
+		inputs = append(inputs, fmt.Sprintf("%s:%d", in.Txid, in.Vout))
+	}
+
+	return inputs, body.Status.Confirmed, nil
+}
+
+type outspendResponse struct {
+	Spent bool   `json:"spent"`
+	Txid  string `json:"txid"`
+}
+
+// OutpointSpender returns the txid of the transaction in the mempool or in
+// the chain that spends the outpoint, or an empty string if it is unspent.
+func (m *MempoolClient) OutpointSpender(ctx context.Context, outpoint string) (string, error) {
+	parts := strings.Split(outpoint, ":")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid outpoint '%s'", outpoint)
+	}
+
+	var body outspendResponse
+	err := m.get(ctx, "tx/"+parts[0]+"/outspend/"+parts[1], &body)
+	if err != nil {
+		return "", err
+	}
+
+	if !body.Spent {
+		return "", nil
+	}
+
+	return body.Txid, nil
+}
+
+// get decodes the json response of the api path into v.
+func (m *MempoolClient) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", m.apiBaseUrl+path, nil)
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext error: %w", err)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("httpClient.Do error: %w", err)
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return chain.ErrTransactionNotFound
+	}
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		return fmt.Errorf("error statuscode %v", resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return nil
+}
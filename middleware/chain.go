@@ -0,0 +1,95 @@
+// Package middleware contains the middlewares the rpcs of clients run through
+// before their handler, like panic recovery, request logging, metrics and
+// rate limiting. The grpc server chains them with its own authentication, in
+// the order set in the RPC_MIDDLEWARE environment variable.
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/breez/lspd/config"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+)
+
+// DefaultOrder is the chain of middlewares, outermost first, if
+// RPC_MIDDLEWARE is not set.
+const DefaultOrder = "recovery,logging,metrics,auth,ratelimit,deadline"
+
+// Request describes the rpc being handled. The chain puts it into the
+// context before the first middleware, so middlewares further out see what
+// middlewares further in, like authentication, learned about the request.
+type Request struct {
+	Method string
+	Start  time.Time
+
+	// Set by the authentication middleware.
+	Token  string
+	Node   *config.NodeConfig
+	Logger *log.Logger
+}
+
+type requestKey struct{}
+
+// RequestFrom returns the request of the rpc, or nil outside of a chain.
+func RequestFrom(ctx context.Context) *Request {
+	r, _ := ctx.Value(requestKey{}).(*Request)
+	return r
+}
+
+func (r *Request) nodeLabel() string {
+	if r.Node == nil {
+		return ""
+	}
+
+	return r.Node.Label()
+}
+
+// Build returns the middlewares named in order, a comma separated list, from
+// the available middlewares. The required middlewares can't be left out.
+func Build(order string, available map[string]grpc.UnaryServerInterceptor, required ...string) ([]grpc.UnaryServerInterceptor, error) {
+	var chain []grpc.UnaryServerInterceptor
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(order, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		m, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown middleware '%s'", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("middleware '%s' is listed twice", name)
+		}
+
+		seen[name] = true
+		chain = append(chain, m)
+	}
+
+	for _, name := range required {
+		if !seen[name] {
+			return nil, fmt.Errorf("middleware '%s' is required", name)
+		}
+	}
+
+	return chain, nil
+}
+
+// Chain returns an interceptor running the rpc through the middlewares,
+// outermost first.
+func Chain(middlewares ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	chained := grpc_middleware.ChainUnaryServer(middlewares...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = context.WithValue(ctx, requestKey{}, &Request{
+			Method: info.FullMethod,
+			Start:  time.Now(),
+		})
+		return chained(ctx, req, info, handler)
+	}
+}
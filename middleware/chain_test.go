@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/breez/lspd/config"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestBuild(t *testing.T) {
+	var calls []string
+	record := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, name)
+			return handler(ctx, req)
+		}
+	}
+	available := map[string]grpc.UnaryServerInterceptor{
+		"a":    record("a"),
+		"b":    record("b"),
+		"auth": record("auth"),
+	}
+
+	_, err := Build("a,unknown,auth", available, "auth")
+	assert.ErrorContains(t, err, "unknown middleware 'unknown'")
+	_, err = Build("a,a,auth", available, "auth")
+	assert.ErrorContains(t, err, "listed twice")
+	_, err = Build("a,b", available, "auth")
+	assert.ErrorContains(t, err, "'auth' is required")
+
+	chain, err := Build(" b, auth ,a", available, "auth")
+	assert.NoError(t, err)
+	_, err = Chain(chain...)(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/m"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "auth", "a"}, calls)
+}
+
+func TestChainRequest(t *testing.T) {
+	node := &config.NodeConfig{Name: "node"}
+	var outer *Request
+	chain := Chain(
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			resp, err := handler(ctx, req)
+			outer = RequestFrom(ctx)
+			return resp, err
+		},
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			r := RequestFrom(ctx)
+			r.Token = "token"
+			r.Node = node
+			return handler(ctx, req)
+		},
+	)
+
+	_, err := chain(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/lspd.ChannelOpener/OpenChannel"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "/lspd.ChannelOpener/OpenChannel", outer.Method)
+	assert.Equal(t, "token", outer.Token)
+	assert.Equal(t, node, outer.Node)
+	assert.Nil(t, RequestFrom(context.Background()))
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Logging returns a middleware logging every rpc with its result and
+// duration, to the logger of the node once the rpc is authenticated.
+func Logging() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+
+		r := RequestFrom(ctx)
+		if r == nil {
+			return resp, err
+		}
+
+		logger := r.Logger
+		if logger == nil {
+			logger = log.Default()
+		}
+		logger.Printf("rpc %s: %s in %v", r.Method, status.Code(err), time.Since(r.Start).Round(time.Millisecond))
+		return resp, err
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lspd_rpc_requests_total",
+		Help: "Client rpcs handled, by method and grpc status code. The node is empty for unauthenticated rpcs.",
+	}, []string{"node", "method", "code"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "lspd_rpc_request_duration_seconds",
+		Help:    "Duration of client rpcs, by method.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"node", "method"})
+)
+
+// Metrics returns a middleware counting rpcs by result, and observing their
+// duration.
+func Metrics() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+
+		r := RequestFrom(ctx)
+		if r == nil {
+			return resp, err
+		}
+
+		node := r.nodeLabel()
+		requestsTotal.WithLabelValues(node, r.Method, status.Code(err).String()).Inc()
+		requestDuration.WithLabelValues(node, r.Method).Observe(time.Since(r.Start).Seconds())
+		return resp, err
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lsperrors"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultBurst = 10
+
+	// Buckets that filled up again are dropped once there are this many.
+	maxBuckets = 10000
+)
+
+type bucket struct {
+	tokens float64
+	rate   float64
+	burst  float64
+	last   time.Time
+}
+
+// refill returns the tokens of the bucket at now.
+func (b *bucket) refill(now time.Time) float64 {
+	return math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+}
+
+// RateLimiter limits the rate of the rpcs of every token to the rpcRateLimit
+// of its node, with a token bucket per token.
+type RateLimiter struct {
+	mtx     sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow returns whether the token of the node may make another rpc now.
+func (l *RateLimiter) Allow(node *config.NodeConfig, token string) bool {
+	cfg := node.RpcRateLimit
+	if cfg == nil || cfg.RequestsPerSecond <= 0 {
+		return true
+	}
+
+	burst := float64(cfg.Burst)
+	if burst <= 0 {
+		burst = defaultBurst
+	}
+
+	now := l.now()
+	key := node.Label() + "/" + token
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxBuckets {
+			l.prune(now)
+		}
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+
+	// The limit may have changed with a config reload.
+	b.rate = cfg.RequestsPerSecond
+	b.burst = burst
+	b.tokens = b.refill(now)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// prune drops the buckets that are full again, they would be created full.
+func (l *RateLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.refill(now) >= b.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Interceptor is the middleware of the rate limiter. It runs after
+// authentication, rpcs without a token aren't limited.
+func (l *RateLimiter) Interceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	r := RequestFrom(ctx)
+	if r == nil || r.Node == nil {
+		return handler(ctx, req)
+	}
+
+	if !l.Allow(r.Node, r.Token) {
+		return nil, lsperrors.ToStatus(lsperrors.ErrRateLimited)
+	}
+
+	return handler(ctx, req)
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewRateLimiter()
+	l.now = func() time.Time { return now }
+
+	node := &config.NodeConfig{Name: "node"}
+	assert.True(t, l.Allow(node, "token"), "unlimited without config")
+
+	node.RpcRateLimit = &config.RpcRateLimitConfig{RequestsPerSecond: 0.5, Burst: 2}
+	assert.True(t, l.Allow(node, "token"))
+	assert.True(t, l.Allow(node, "token"))
+	assert.False(t, l.Allow(node, "token"))
+	assert.True(t, l.Allow(node, "other"), "tokens have their own bucket")
+
+	now = now.Add(time.Second)
+	assert.False(t, l.Allow(node, "token"))
+	now = now.Add(time.Second)
+	assert.True(t, l.Allow(node, "token"))
+	assert.False(t, l.Allow(node, "token"))
+
+	// The bucket doesn't fill above the burst.
+	now = now.Add(time.Hour)
+	assert.True(t, l.Allow(node, "token"))
+	assert.True(t, l.Allow(node, "token"))
+	assert.False(t, l.Allow(node, "token"))
+
+	l.prune(now.Add(time.Hour))
+	assert.Empty(t, l.buckets)
+}
+
+func TestRateLimiterInterceptor(t *testing.T) {
+	l := NewRateLimiter()
+	node := &config.NodeConfig{RpcRateLimit: &config.RpcRateLimitConfig{RequestsPerSecond: 1, Burst: 1}}
+	chain := Chain(
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			RequestFrom(ctx).Node = node
+			return handler(ctx, req)
+		},
+		l.Interceptor,
+	)
+	call := func() error {
+		_, err := chain(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return err
+	}
+
+	assert.NoError(t, call())
+	err := call()
+	assert.Equal(t, lsperrors.ErrRateLimited.Code, status.Code(err))
+	assert.Equal(t, lsperrors.ErrRateLimited.Message, status.Convert(err).Message())
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+
+	"github.com/breez/lspd/lsperrors"
+	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+)
+
+var panicsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_rpc_panics_total",
+	Help: "Client rpcs that panicked, by method.",
+}, []string{"method"})
+
+// Recovery returns a middleware failing rpcs that panic with an internal
+// error, rather than crashing lspd.
+func Recovery() grpc.UnaryServerInterceptor {
+	return grpc_recovery.UnaryServerInterceptor(
+		grpc_recovery.WithRecoveryHandlerContext(func(ctx context.Context, p interface{}) error {
+			method := ""
+			if r := RequestFrom(ctx); r != nil {
+				method = r.Method
+			}
+
+			log.Printf("ERROR: rpc %s panicked: %v\n%s", method, p, debug.Stack())
+			panicsTotal.WithLabelValues(method).Inc()
+			return lsperrors.ToStatus(lsperrors.ErrInternal)
+		}),
+	)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/secrets"
+	"github.com/breez/lspd/signer"
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+const defaultSecretsCacheTtl = time.Hour
+
+// newSecretResolver returns the resolver of the secrets referenced in the node
+// configs. Secrets are read from Vault if VAULT_ADDR is set, and decrypted
+// with AWS KMS with the aws credentials of the environment. The vault token
+// is renewed in the background.
+func newSecretResolver() (*secrets.Resolver, error) {
+	ttl := defaultSecretsCacheTtl
+	if s := os.Getenv("SECRETS_CACHE_TTL"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid SECRETS_CACHE_TTL '%s'", s)
+		}
+		ttl = d
+	}
+
+	resolver := secrets.NewResolver(ttl)
+	sess, err := session.NewSession(&aws.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aws session: %w", err)
+	}
+	resolver.Register(secrets.SchemeKMS, secrets.NewKMSSource(kms.New(sess)))
+
+	if address := os.Getenv("VAULT_ADDR"); address != "" {
+		vault := secrets.NewVaultSource(secrets.VaultConfig{
+			Address:   address,
+			Namespace: os.Getenv("VAULT_NAMESPACE"),
+			Token:     os.Getenv("VAULT_TOKEN"),
+			RoleID:    os.Getenv("VAULT_ROLE_ID"),
+			SecretID:  os.Getenv("VAULT_SECRET_ID"),
+		})
+		resolver.Register(secrets.SchemeVault, vault)
+		go vault.Run(context.Background())
+	}
+
+	return resolver, nil
+}
+
+// resolvePrivateKey returns the hex encoded private key of the config value,
+// or the private key it references.
+func resolvePrivateKey(ctx context.Context, resolver *secrets.Resolver, value string) ([]byte, error) {
+	s, err := resolver.Resolve(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := hex.DecodeString(s)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("private key must be 32 bytes hex encoded")
+	}
+
+	return key, nil
+}
+
+// newLspdSigner returns the signer of the lspd private key of the node, and
+// the secret open channel challenges are authenticated with. The secret is
+// derived from the lspd private key, so challenges stay valid across
+// restarts. A kms key can't be read, then the secret is derived from the
+// first encryption key instead.
+func newLspdSigner(ctx context.Context, resolver *secrets.Resolver, node *config.NodeConfig) (signer.Signer, []byte, error) {
+	scheme, ref, _ := secrets.ParseReference(node.LspdPrivateKey)
+	if scheme == secrets.SchemeKMSKey {
+		sess, err := session.NewSession(&aws.Config{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create aws session: %w", err)
+		}
+
+		s, err := signer.NewKMS(ctx, kms.New(sess), ref)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(node.EncryptionKeys) == 0 {
+			return nil, nil, fmt.Errorf("a kms lspdPrivateKey needs encryptionKeys")
+		}
+		pk, err := resolvePrivateKey(ctx, resolver, node.EncryptionKeys[0].PrivateKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("encryption key '%s': %w", node.EncryptionKeys[0].ID, err)
+		}
+
+		log.Printf("Node %s signs with kms key %s", node.Label(), ref)
+		return s, challengeSecret(pk), nil
+	}
+
+	pk, err := resolvePrivateKey(ctx, resolver, node.LspdPrivateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("lspdPrivateKey: %w", err)
+	}
+
+	privateKey, _ := btcec.PrivKeyFromBytes(pk)
+	return signer.NewLocal(privateKey), challengeSecret(pk), nil
+}
+
+func challengeSecret(key []byte) []byte {
+	secret := sha256.Sum256(append([]byte("lspd-open-channel-challenge"), key...))
+	return secret[:]
+}
@@ -0,0 +1,197 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	apnsProductionUrl = "https://api.push.apple.com"
+	apnsSandboxUrl    = "https://api.sandbox.push.apple.com"
+
+	// Apple rejects provider tokens older than an hour, and refreshing more
+	// often than every 20 minutes.
+	apnsTokenLifetime = 50 * time.Minute
+)
+
+type ApnsConfig struct {
+	// Path to the .p8 auth key file.
+	KeyFile string
+	// Id of the auth key.
+	KeyID string
+	// Id of the apple developer team.
+	TeamID string
+	// Bundle id of the app.
+	Topic string
+	// Use the development environment, for development builds of the app.
+	Sandbox bool
+}
+
+// ApnsPusher sends push notifications through the Apple Push Notification
+// service, authenticated with a token signed by the auth key.
+type ApnsPusher struct {
+	config   *ApnsConfig
+	key      *ecdsa.PrivateKey
+	baseUrl  string
+	client   *http.Client
+	mtx      sync.Mutex
+	jwt      string
+	issuedAt time.Time
+}
+
+func NewApnsPusher(config *ApnsConfig) (*ApnsPusher, error) {
+	if config.KeyID == "" || config.TeamID == "" || config.Topic == "" {
+		return nil, fmt.Errorf("apns requires a key id, team id and topic")
+	}
+
+	data, err := os.ReadFile(config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apns key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("apns key file is not pem encoded")
+	}
+
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse apns key: %w", err)
+	}
+
+	key, ok := k.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("apns key is not an ecdsa key")
+	}
+
+	baseUrl := apnsProductionUrl
+	if config.Sandbox {
+		baseUrl = apnsSandboxUrl
+	}
+
+	return &ApnsPusher{
+		config:  config,
+		key:     key,
+		baseUrl: baseUrl,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *ApnsPusher) Platform() PushPlatform {
+	return PushPlatform_PUSH_PLATFORM_APNS
+}
+
+type apnsPayload struct {
+	Aps struct {
+		Alert struct {
+			Title string `json:"title"`
+		} `json:"alert"`
+		MutableContent int `json:"mutable-content"`
+	} `json:"aps"`
+	Template    string `json:"template"`
+	PaymentHash string `json:"payment_hash"`
+}
+
+type apnsError struct {
+	Reason string `json:"reason"`
+}
+
+// Push sends a mutable alert, which runs the notification service extension
+// of the app, so it can come online and receive the payment.
+func (p *ApnsPusher) Push(ctx context.Context, token string, paymentHash string) error {
+	jwt, err := p.getJwt()
+	if err != nil {
+		return err
+	}
+
+	var payload apnsPayload
+	payload.Aps.Alert.Title = "Receiving payment"
+	payload.Aps.MutableContent = 1
+	payload.Template = "payment_received"
+	payload.PaymentHash = paymentHash
+	body, err := json.Marshal(&payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode apns payload: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/3/device/%s", p.baseUrl, token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "bearer "+jwt)
+	req.Header.Set("apns-topic", p.config.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("apns-expiration", "0")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("apns request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var e apnsError
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	json.Unmarshal(respBody, &e)
+	if resp.StatusCode == http.StatusGone || e.Reason == "BadDeviceToken" || e.Reason == "Unregistered" {
+		return ErrUnregistered
+	}
+
+	return fmt.Errorf("apns returned status %s: %s", resp.Status, respBody)
+}
+
+// getJwt returns the cached provider token, or signs a new one when it is
+// about to expire.
+func (p *ApnsPusher) getJwt() (string, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	now := time.Now()
+	if p.jwt != "" && now.Before(p.issuedAt.Add(apnsTokenLifetime)) {
+		return p.jwt, nil
+	}
+
+	jwt, err := signJwt(
+		map[string]string{"alg": "ES256", "kid": p.config.KeyID},
+		map[string]interface{}{
+			"iss": p.config.TeamID,
+			"iat": now.Unix(),
+		},
+		func(data []byte) ([]byte, error) {
+			hash := sha256.Sum256(data)
+			r, s, err := ecdsa.Sign(rand.Reader, p.key, hash[:])
+			if err != nil {
+				return nil, err
+			}
+
+			// ES256 signatures are the fixed size r and s concatenated.
+			sig := make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+			return sig, nil
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	p.jwt = jwt
+	p.issuedAt = now
+	return jwt, nil
+}
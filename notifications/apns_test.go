@@ -0,0 +1,84 @@
+package notifications
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestApnsPusher(t *testing.T, url string) (*ApnsPusher, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "key.p8")
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	assert.NoError(t, err)
+
+	p, err := NewApnsPusher(&ApnsConfig{
+		KeyFile: keyFile,
+		KeyID:   "key",
+		TeamID:  "team",
+		Topic:   "com.example.app",
+	})
+	assert.NoError(t, err)
+	p.baseUrl = url
+	return p, key
+}
+
+func Test_Apns_Push(t *testing.T) {
+	var key *ecdsa.PrivateKey
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/3/device/token", r.URL.Path)
+		assert.Equal(t, "com.example.app", r.Header.Get("apns-topic"))
+
+		jwt := strings.TrimPrefix(r.Header.Get("authorization"), "bearer ")
+		parts := strings.Split(jwt, ".")
+		assert.Len(t, parts, 3)
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		assert.NoError(t, err)
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		valid := ecdsa.Verify(&key.PublicKey, hash[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]))
+		assert.True(t, valid)
+
+		var payload apnsPayload
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		assert.NoError(t, err)
+		assert.Equal(t, "abcd", payload.PaymentHash)
+		assert.Equal(t, 1, payload.Aps.MutableContent)
+	}))
+	defer srv.Close()
+
+	p, k := newTestApnsPusher(t, srv.URL)
+	key = k
+	err := p.Push(context.Background(), "token", "abcd")
+	assert.NoError(t, err)
+}
+
+func Test_Apns_Push_Unregistered(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(`{"reason":"Unregistered"}`))
+	}))
+	defer srv.Close()
+
+	p, _ := newTestApnsPusher(t, srv.URL)
+	err := p.Push(context.Background(), "token", "abcd")
+	assert.ErrorIs(t, err, ErrUnregistered)
+}
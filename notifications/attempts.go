@@ -0,0 +1,97 @@
+package notifications
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of notification attempts kept in memory for support.
+const notificationAttemptsSize = 1000
+
+// NotificationAttempt is an attempt to notify a node of a payment. The target
+// is redacted, so it can be shared in support escalations.
+type NotificationAttempt struct {
+	Time        time.Time `json:"time"`
+	Pubkey      string    `json:"pubkey"`
+	PaymentHash string    `json:"paymentHash"`
+	Transport   string    `json:"transport"`
+	Target      string    `json:"target"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+
+	// The tenant the notification was sent for.
+	tenantID string
+}
+
+// notificationAttempts is a ring buffer of the last notification attempts.
+type notificationAttempts struct {
+	mtx      sync.Mutex
+	attempts []*NotificationAttempt
+	next     int
+}
+
+func (a *notificationAttempts) add(attempt *NotificationAttempt) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if len(a.attempts) < notificationAttemptsSize {
+		a.attempts = append(a.attempts, attempt)
+		return
+	}
+
+	a.attempts[a.next] = attempt
+	a.next = (a.next + 1) % notificationAttemptsSize
+}
+
+// list returns the attempts of the tenant for the payment hash still in
+// memory, oldest first.
+func (a *notificationAttempts) list(tenantID string, paymentHash string) []*NotificationAttempt {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	result := []*NotificationAttempt{}
+	for j := 0; j < len(a.attempts); j++ {
+		attempt := a.attempts[(a.next+j)%len(a.attempts)]
+		if attempt.tenantID == tenantID && attempt.PaymentHash == paymentHash {
+			result = append(result, attempt)
+		}
+	}
+
+	return result
+}
+
+// redactWebhook keeps only the scheme and host of a webhook url, as the path
+// and query often contain credentials.
+func redactWebhook(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return "<redacted>"
+	}
+
+	return parsed.Scheme + "://" + parsed.Host + "/<redacted>"
+}
+
+// redactToken keeps only the start of a device token, or the relay of a
+// nostr token.
+func redactToken(platform PushPlatform, token string) string {
+	if platform == PushPlatform_PUSH_PLATFORM_NOSTR {
+		parsed, err := url.Parse(token)
+		if err == nil {
+			if relay, err := url.Parse(parsed.Query().Get("relay")); err == nil && relay.Host != "" {
+				return "nostr:<redacted>?relay=" + relay.Scheme + "://" + relay.Host
+			}
+		}
+
+		return "<redacted>"
+	}
+
+	if len(token) <= 8 {
+		return "<redacted>"
+	}
+
+	return token[:8] + "<redacted>"
+}
+
+func transportName(platform PushPlatform) string {
+	return strings.ToLower(strings.TrimPrefix(platform.String(), "PUSH_PLATFORM_"))
+}
@@ -0,0 +1,37 @@
+package notifications
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationAttemptsRingBuffer(t *testing.T) {
+	var a notificationAttempts
+	for i := 0; i < notificationAttemptsSize+10; i++ {
+		a.add(&NotificationAttempt{PaymentHash: fmt.Sprintf("%d", i%2), Target: fmt.Sprintf("%d", i)})
+	}
+
+	attempts := a.list("", "0")
+	assert.Len(t, attempts, notificationAttemptsSize/2)
+	assert.Equal(t, "10", attempts[0].Target)
+	assert.Equal(t, fmt.Sprintf("%d", notificationAttemptsSize+8), attempts[len(attempts)-1].Target)
+	assert.Empty(t, a.list("", "2"))
+
+	// Attempts of other tenants are not listed.
+	a.add(&NotificationAttempt{PaymentHash: "0", tenantID: "acme"})
+	assert.Len(t, a.list("acme", "0"), 1)
+	assert.Len(t, a.list("", "0"), notificationAttemptsSize/2-1)
+}
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "https://example.com/<redacted>", redactWebhook("https://example.com/hook?key=secret"))
+	assert.Equal(t, "<redacted>", redactWebhook("not a url"))
+	assert.Equal(t, "abcdefgh<redacted>", redactToken(PushPlatform_PUSH_PLATFORM_FCM, "abcdefghijklmnop"))
+	assert.Equal(t, "<redacted>", redactToken(PushPlatform_PUSH_PLATFORM_APNS, "abc"))
+	assert.Equal(t,
+		"nostr:<redacted>?relay=wss://relay.example.com",
+		redactToken(PushPlatform_PUSH_PLATFORM_NOSTR, "nostr:abcd?relay=wss://relay.example.com/path&encryption=nip44"),
+	)
+}
@@ -0,0 +1,212 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	fcmBaseUrl = "https://fcm.googleapis.com"
+	fcmScope   = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+type fcmServiceAccount struct {
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenUri     string `json:"token_uri"`
+}
+
+// FcmPusher sends push notifications through the Firebase Cloud Messaging
+// HTTP v1 api, authenticated with a service account.
+type FcmPusher struct {
+	account     *fcmServiceAccount
+	key         *rsa.PrivateKey
+	baseUrl     string
+	client      *http.Client
+	mtx         sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFcmPusher creates an FcmPusher with the service account json file
+// downloaded from the firebase console.
+func NewFcmPusher(serviceAccountFile string) (*FcmPusher, error) {
+	data, err := os.ReadFile(serviceAccountFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fcm service account file: %w", err)
+	}
+
+	var account fcmServiceAccount
+	err = json.Unmarshal(data, &account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fcm service account file: %w", err)
+	}
+
+	if account.ProjectID == "" || account.ClientEmail == "" || account.TokenUri == "" {
+		return nil, fmt.Errorf("fcm service account file misses project_id, client_email or token_uri")
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("fcm service account private key is not pem encoded")
+	}
+
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fcm service account private key: %w", err)
+	}
+
+	key, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("fcm service account private key is not an rsa key")
+	}
+
+	return &FcmPusher{
+		account: &account,
+		key:     key,
+		baseUrl: fcmBaseUrl,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *FcmPusher) Platform() PushPlatform {
+	return PushPlatform_PUSH_PLATFORM_FCM
+}
+
+type fcmMessage struct {
+	Message struct {
+		Token   string            `json:"token"`
+		Data    map[string]string `json:"data"`
+		Android struct {
+			Priority string `json:"priority"`
+		} `json:"android"`
+	} `json:"message"`
+}
+
+// Push sends a high priority data message, which wakes the app so it can
+// come online and receive the payment.
+func (p *FcmPusher) Push(ctx context.Context, token string, paymentHash string) error {
+	accessToken, err := p.getAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	var msg fcmMessage
+	msg.Message.Token = token
+	msg.Message.Data = map[string]string{
+		"template":     "payment_received",
+		"payment_hash": paymentHash,
+	}
+	msg.Message.Android.Priority = "high"
+	body, err := json.Marshal(&msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode fcm message: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/v1/projects/%s/messages:send", p.baseUrl, p.account.ProjectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fcm request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusNotFound || strings.Contains(string(respBody), "UNREGISTERED") {
+		return ErrUnregistered
+	}
+
+	return fmt.Errorf("fcm returned status %s: %s", resp.Status, respBody)
+}
+
+type fcmTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// getAccessToken returns the cached oauth access token, or exchanges a jwt
+// signed by the service account for a new one when it is about to expire.
+func (p *FcmPusher) getAccessToken(ctx context.Context) (string, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	now := time.Now()
+	if p.accessToken != "" && now.Add(time.Minute).Before(p.expiresAt) {
+		return p.accessToken, nil
+	}
+
+	assertion, err := signJwt(
+		map[string]string{"alg": "RS256", "typ": "JWT", "kid": p.account.PrivateKeyID},
+		map[string]interface{}{
+			"iss":   p.account.ClientEmail,
+			"scope": fcmScope,
+			"aud":   p.account.TokenUri,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		},
+		func(data []byte) ([]byte, error) {
+			hash := sha256.Sum256(data)
+			return rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, hash[:])
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.account.TokenUri, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fcm access token request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("fcm access token request returned status %s: %s", resp.Status, respBody)
+	}
+
+	var t fcmTokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&t)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode fcm access token: %w", err)
+	}
+
+	p.accessToken = t.AccessToken
+	p.expiresAt = now.Add(time.Duration(t.ExpiresIn) * time.Second)
+	return p.accessToken, nil
+}
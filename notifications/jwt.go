@@ -0,0 +1,29 @@
+package notifications
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// signJwt creates a compact jwt of the header and claims, signed with sign.
+func signJwt(header interface{}, claims interface{}, sign func(data []byte) ([]byte, error)) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode jwt header: %w", err)
+	}
+
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode jwt claims: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(h) + "." + enc.EncodeToString(c)
+	sig, err := sign([]byte(unsigned))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign jwt: %w", err)
+	}
+
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
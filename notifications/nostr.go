@@ -0,0 +1,312 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// Encrypted direct message, see nip-04.
+	nostrKindEncryptedDirectMessage = 4
+
+	nostrPublishTimeout = 10 * time.Second
+
+	NostrEncryptionNip04 = "nip04"
+	NostrEncryptionNip44 = "nip44"
+)
+
+type NostrConfig struct {
+	// Hex encoded private key events are signed and encrypted with.
+	PrivateKey string
+	// Relays notifications may be published to. Any wss relay if empty.
+	Relays []string
+}
+
+// NostrPusher publishes push notifications as encrypted direct messages to a
+// nostr relay chosen by the client, for clients that don't want to depend on
+// the push services of Google and Apple.
+type NostrPusher struct {
+	key    *btcec.PrivateKey
+	relays map[string]struct{}
+}
+
+// nostrTarget is a parsed nostr device token.
+type nostrTarget struct {
+	pubkey     *btcec.PublicKey
+	relay      *url.URL
+	encryption string
+}
+
+type nostrEvent struct {
+	ID        string     `json:"id"`
+	Pubkey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"`
+}
+
+func NewNostrPusher(config *NostrConfig) (*NostrPusher, error) {
+	k, err := hex.DecodeString(config.PrivateKey)
+	if err != nil || len(k) != 32 {
+		return nil, fmt.Errorf("nostr private key is not a hex encoded 32 byte key")
+	}
+
+	key, _ := btcec.PrivKeyFromBytes(k)
+	relays := make(map[string]struct{})
+	for _, r := range config.Relays {
+		u, err := parseNostrRelay(r)
+		if err != nil {
+			return nil, err
+		}
+
+		relays[strings.TrimSuffix(u.String(), "/")] = struct{}{}
+	}
+
+	return &NostrPusher{
+		key:    key,
+		relays: relays,
+	}, nil
+}
+
+func (p *NostrPusher) Platform() PushPlatform {
+	return PushPlatform_PUSH_PLATFORM_NOSTR
+}
+
+// ValidateToken checks the token is a valid nostr uri with an allowed relay.
+func (p *NostrPusher) ValidateToken(token string) error {
+	_, err := p.parseToken(token)
+	return err
+}
+
+// Push publishes an encrypted direct message with the payment received
+// payload to the relay in the token, and waits for the relay to accept it.
+func (p *NostrPusher) Push(ctx context.Context, token string, paymentHash string) error {
+	target, err := p.parseToken(token)
+	if err != nil {
+		return err
+	}
+
+	var payload PaymentReceivedPayload
+	payload.Template = "payment_received"
+	payload.Data.PaymentHash = paymentHash
+	message, err := json.Marshal(&payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode nostr payload: %w", err)
+	}
+
+	var content string
+	if target.encryption == NostrEncryptionNip04 {
+		content, err = nip04Encrypt(p.key, target.pubkey, message)
+	} else {
+		content, err = nip44Encrypt(p.key, target.pubkey, message)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encrypt nostr payload: %w", err)
+	}
+
+	event, err := p.signEvent(&nostrEvent{
+		CreatedAt: time.Now().Unix(),
+		Kind:      nostrKindEncryptedDirectMessage,
+		Tags:      [][]string{{"p", hex.EncodeToString(schnorr.SerializePubKey(target.pubkey))}},
+		Content:   content,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, nostrPublishTimeout)
+	defer cancel()
+	return publishNostrEvent(ctx, target.relay, event)
+}
+
+// parseToken parses a token of the form
+// nostr:<hex pubkey>?relay=<relay url>&encryption=<nip04|nip44>.
+func (p *NostrPusher) parseToken(token string) (*nostrTarget, error) {
+	u, err := url.Parse(token)
+	if err != nil || u.Scheme != "nostr" || u.Opaque == "" {
+		return nil, fmt.Errorf("invalid nostr token")
+	}
+
+	k, err := hex.DecodeString(u.Opaque)
+	if err != nil || len(k) != 32 {
+		return nil, fmt.Errorf("invalid nostr pubkey")
+	}
+	pubkey, err := schnorr.ParsePubKey(k)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nostr pubkey: %w", err)
+	}
+
+	query := u.Query()
+	relay, err := parseNostrRelay(query.Get("relay"))
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.relays[strings.TrimSuffix(relay.String(), "/")]; len(p.relays) > 0 && !ok {
+		return nil, fmt.Errorf("nostr relay %s not allowed", relay)
+	}
+
+	encryption := query.Get("encryption")
+	switch encryption {
+	case "":
+		encryption = NostrEncryptionNip44
+	case NostrEncryptionNip04, NostrEncryptionNip44:
+	default:
+		return nil, fmt.Errorf("unsupported nostr encryption %s", encryption)
+	}
+
+	return &nostrTarget{
+		pubkey:     pubkey,
+		relay:      relay,
+		encryption: encryption,
+	}, nil
+}
+
+func parseNostrRelay(relay string) (*url.URL, error) {
+	u, err := url.Parse(relay)
+	if err != nil || u.Scheme != "wss" || u.Host == "" {
+		return nil, fmt.Errorf("invalid nostr relay '%s', expected a wss url", relay)
+	}
+
+	return u, nil
+}
+
+// signEvent sets the pubkey, id and schnorr signature of the event as
+// described in nip-01.
+func (p *NostrPusher) signEvent(event *nostrEvent) (*nostrEvent, error) {
+	event.Pubkey = hex.EncodeToString(schnorr.SerializePubKey(p.key.PubKey()))
+	id, err := nostrEventID(event)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := schnorr.Sign(p.key, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign nostr event: %w", err)
+	}
+
+	event.ID = hex.EncodeToString(id)
+	event.Sig = hex.EncodeToString(sig.Serialize())
+	return event, nil
+}
+
+// nostrEventID returns the sha256 of the serialized event. The serialization
+// must not escape html characters like json.Marshal does.
+func nostrEventID(event *nostrEvent) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode([]interface{}{
+		0,
+		event.Pubkey,
+		event.CreatedAt,
+		event.Kind,
+		event.Tags,
+		event.Content,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize nostr event: %w", err)
+	}
+
+	id := sha256.Sum256(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return id[:], nil
+}
+
+// publishNostrEvent sends the event to the relay and waits for the OK
+// message of the relay for the event.
+func publishNostrEvent(ctx context.Context, relay *url.URL, event *nostrEvent) error {
+	conn, err := dialNostrRelay(ctx, relay)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	err = websocket.JSON.Send(conn, []interface{}{"EVENT", event})
+	if err != nil {
+		return fmt.Errorf("failed to send nostr event to %s: %w", relay, err)
+	}
+
+	for {
+		var msg []json.RawMessage
+		err = websocket.JSON.Receive(conn, &msg)
+		if err != nil {
+			return fmt.Errorf("failed to receive nostr relay message from %s: %w", relay, err)
+		}
+
+		var typ, id string
+		if len(msg) < 3 || json.Unmarshal(msg[0], &typ) != nil || typ != "OK" {
+			continue
+		}
+		if json.Unmarshal(msg[1], &id) != nil || id != event.ID {
+			continue
+		}
+
+		var accepted bool
+		var reason string
+		json.Unmarshal(msg[2], &accepted)
+		if len(msg) > 3 {
+			json.Unmarshal(msg[3], &reason)
+		}
+		if !accepted {
+			return fmt.Errorf("nostr relay %s rejected event: %s", relay, reason)
+		}
+
+		return nil
+	}
+}
+
+// dialNostrRelay opens a websocket connection to the relay, cancelled with
+// the context while connecting.
+func dialNostrRelay(ctx context.Context, relay *url.URL) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(relay.String(), "https://"+relay.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	host := relay.Host
+	if relay.Port() == "" {
+		host = net.JoinHostPort(relay.Hostname(), "443")
+	}
+
+	var d net.Dialer
+	rawConn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nostr relay %s: %w", relay, err)
+	}
+
+	tlsConn := tls.Client(rawConn, &tls.Config{ServerName: relay.Hostname()})
+	if deadline, ok := ctx.Deadline(); ok {
+		tlsConn.SetDeadline(deadline)
+	}
+	err = tlsConn.HandshakeContext(ctx)
+	if err != nil {
+		rawConn.Close()
+		return nil, fmt.Errorf("tls handshake with nostr relay %s failed: %w", relay, err)
+	}
+
+	conn, err := websocket.NewClient(config, tlsConn)
+	if err != nil {
+		tlsConn.Close()
+		return nil, fmt.Errorf("websocket handshake with nostr relay %s failed: %w", relay, err)
+	}
+
+	return conn, nil
+}
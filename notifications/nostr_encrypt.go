@@ -0,0 +1,118 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	nip44Version      = 2
+	nip44MinPlaintext = 1
+	nip44MaxPlaintext = 65535
+)
+
+// nip04Encrypt encrypts the message to the receiver as described in nip-04,
+// with aes-256-cbc keyed by the x coordinate of the ecdh shared point.
+func nip04Encrypt(key *btcec.PrivateKey, receiver *btcec.PublicKey, message []byte) (string, error) {
+	shared := btcec.GenerateSharedSecret(key, receiver)
+	block, err := aes.NewCipher(shared)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+
+	padding := aes.BlockSize - len(message)%aes.BlockSize
+	padded := append(append([]byte{}, message...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+	return base64.StdEncoding.EncodeToString(ciphertext) + "?iv=" + base64.StdEncoding.EncodeToString(iv), nil
+}
+
+// nip44ConversationKey returns the nip-44 v2 conversation key of the sender
+// and receiver, which is the same in both directions.
+func nip44ConversationKey(key *btcec.PrivateKey, receiver *btcec.PublicKey) []byte {
+	shared := btcec.GenerateSharedSecret(key, receiver)
+	return hkdf.Extract(sha256.New, shared, []byte("nip44-v2"))
+}
+
+// nip44Encrypt encrypts the message to the receiver as described in nip-44
+// version 2, with a random nonce.
+func nip44Encrypt(key *btcec.PrivateKey, receiver *btcec.PublicKey, message []byte) (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	return nip44EncryptWithNonce(nip44ConversationKey(key, receiver), nonce, message)
+}
+
+func nip44EncryptWithNonce(conversationKey []byte, nonce []byte, message []byte) (string, error) {
+	if len(message) < nip44MinPlaintext || len(message) > nip44MaxPlaintext {
+		return "", fmt.Errorf("invalid nip44 plaintext length %d", len(message))
+	}
+
+	keys := make([]byte, 76)
+	_, err := io.ReadFull(hkdf.Expand(sha256.New, conversationKey, nonce), keys)
+	if err != nil {
+		return "", err
+	}
+
+	chachaKey, chachaNonce, hmacKey := keys[:32], keys[32:44], keys[44:]
+	padded := make([]byte, 2+nip44PaddedLen(len(message)))
+	binary.BigEndian.PutUint16(padded, uint16(len(message)))
+	copy(padded[2:], message)
+
+	c, err := chacha20.NewUnauthenticatedCipher(chachaKey, chachaNonce)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(padded))
+	c.XORKeyStream(ciphertext, padded)
+
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(nonce)
+	mac.Write(ciphertext)
+
+	payload := make([]byte, 0, 1+len(nonce)+len(ciphertext)+sha256.Size)
+	payload = append(payload, nip44Version)
+	payload = append(payload, nonce...)
+	payload = append(payload, ciphertext...)
+	payload = mac.Sum(payload)
+	return base64.StdEncoding.EncodeToString(payload), nil
+}
+
+// nip44PaddedLen returns the length the plaintext is padded to, so the
+// ciphertext only leaks the rough size of the message.
+func nip44PaddedLen(l int) int {
+	if l <= 32 {
+		return 32
+	}
+
+	nextPower := 1
+	for nextPower < l {
+		nextPower <<= 1
+	}
+
+	chunk := 32
+	if nextPower > 256 {
+		chunk = nextPower / 8
+	}
+
+	return chunk * ((l-1)/chunk + 1)
+}
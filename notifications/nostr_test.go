@@ -0,0 +1,96 @@
+package notifications
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/stretchr/testify/assert"
+)
+
+func hexKey(t *testing.T, s string) *btcec.PrivateKey {
+	b, err := hex.DecodeString(s)
+	assert.NoError(t, err)
+	key, _ := btcec.PrivKeyFromBytes(b)
+	return key
+}
+
+// Test vector from the nip-44 specification.
+func TestNip44Encrypt(t *testing.T) {
+	key1 := hexKey(t, "0000000000000000000000000000000000000000000000000000000000000001")
+	key2 := hexKey(t, "0000000000000000000000000000000000000000000000000000000000000002")
+	conversationKey := nip44ConversationKey(key1, key2.PubKey())
+	assert.Equal(t, "c41c775356fd92eadc63ff5a0dc1da211b268cbea22316767095b2871ea1412d", hex.EncodeToString(conversationKey))
+	assert.Equal(t, conversationKey, nip44ConversationKey(key2, key1.PubKey()))
+
+	nonce, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	payload, err := nip44EncryptWithNonce(conversationKey, nonce, []byte("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, "AgAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABee0G5VSK0/9YypIObAtDKfYEAjD35uVkHyB0F4DwrcNaCXlCWZKaArsGrY6M9wnuTMxWfp1RTN9Xga8no+kF5Vsb", payload)
+
+	_, err = nip44EncryptWithNonce(conversationKey, nonce, []byte{})
+	assert.Error(t, err)
+}
+
+func TestNip44PaddedLen(t *testing.T) {
+	for l, expected := range map[int]int{1: 32, 32: 32, 33: 64, 64: 64, 65: 96, 256: 256, 257: 320, 1024: 1024, 1025: 1280, 65535: 65536} {
+		assert.Equal(t, expected, nip44PaddedLen(l), "length %d", l)
+	}
+}
+
+func TestNostrParseToken(t *testing.T) {
+	p, err := NewNostrPusher(&NostrConfig{
+		PrivateKey: strings.Repeat("01", 32),
+		Relays:     []string{"wss://relay.example.com"},
+	})
+	assert.NoError(t, err)
+
+	pubkey := hex.EncodeToString(schnorr.SerializePubKey(hexKey(t, strings.Repeat("02", 32)).PubKey()))
+	target, err := p.parseToken("nostr:" + pubkey + "?relay=wss://relay.example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, NostrEncryptionNip44, target.encryption)
+	assert.Equal(t, "relay.example.com", target.relay.Host)
+
+	target, err = p.parseToken("nostr:" + pubkey + "?relay=wss://relay.example.com&encryption=nip04")
+	assert.NoError(t, err)
+	assert.Equal(t, NostrEncryptionNip04, target.encryption)
+
+	for _, token := range []string{
+		"",
+		"https://relay.example.com",
+		"nostr:abcd?relay=wss://relay.example.com",
+		"nostr:" + pubkey,
+		"nostr:" + pubkey + "?relay=ws://relay.example.com",
+		"nostr:" + pubkey + "?relay=wss://other.example.com",
+		"nostr:" + pubkey + "?relay=wss://relay.example.com&encryption=nip99",
+	} {
+		assert.Error(t, p.ValidateToken(token), token)
+	}
+}
+
+func TestNostrSignEvent(t *testing.T) {
+	p, err := NewNostrPusher(&NostrConfig{PrivateKey: strings.Repeat("01", 32)})
+	assert.NoError(t, err)
+
+	event, err := p.signEvent(&nostrEvent{
+		CreatedAt: 1700000000,
+		Kind:      nostrKindEncryptedDirectMessage,
+		Tags:      [][]string{{"p", strings.Repeat("ab", 32)}},
+		Content:   "<content>&",
+	})
+	assert.NoError(t, err)
+
+	id, err := nostrEventID(event)
+	assert.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(id), event.ID)
+
+	rawSig, _ := hex.DecodeString(event.Sig)
+	sig, err := schnorr.ParseSignature(rawSig)
+	assert.NoError(t, err)
+	rawPubkey, _ := hex.DecodeString(event.Pubkey)
+	pubkey, err := schnorr.ParsePubKey(rawPubkey)
+	assert.NoError(t, err)
+	assert.True(t, sig.Verify(id, pubkey))
+}
@@ -3,21 +3,60 @@ package notifications
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"time"
+
+	"github.com/breez/lspd/events"
+	"github.com/breez/lspd/tenant"
 )
 
 type NotificationService struct {
-	store Store
+	store         Store
+	pushers       map[PushPlatform]Pusher
+	attempts      notificationAttempts
+	paymentEvents paymentEvents
 }
 
-func NewNotificationService(store Store) *NotificationService {
+func NewNotificationService(store Store, pushers ...Pusher) *NotificationService {
+	p := make(map[PushPlatform]Pusher)
+	for _, pusher := range pushers {
+		p[pusher.Platform()] = pusher
+	}
+
 	return &NotificationService{
-		store: store,
+		store:   store,
+		pushers: p,
 	}
 }
 
+// SupportsPlatform returns whether push notifications are configured for the
+// platform.
+func (s *NotificationService) SupportsPlatform(platform PushPlatform) bool {
+	_, ok := s.pushers[platform]
+	return ok
+}
+
+// ValidateToken checks the device token of the platform, if the pusher of
+// the platform supports it.
+func (s *NotificationService) ValidateToken(platform PushPlatform, token string) error {
+	v, ok := s.pushers[platform].(TokenValidator)
+	if !ok {
+		return nil
+	}
+
+	return v.ValidateToken(token)
+}
+
+// Attempts returns the notification attempts of the tenant for the payment
+// hash still kept in memory, oldest first.
+func (s *NotificationService) Attempts(ctx context.Context, paymentHash string) []*NotificationAttempt {
+	return s.attempts.list(tenant.FromContext(ctx), paymentHash)
+}
+
 type PaymentReceivedPayload struct {
 	Template string `json:"template" binding:"required,eq=payment_received"`
 	Data     struct {
@@ -25,49 +64,253 @@ type PaymentReceivedPayload struct {
 	} `json:"data"`
 }
 
+// PaymentFailedPayload tells the wallet a payment to it was failed by the lsp
+// for the reason, like a payment below the minimum payment size.
+type PaymentFailedPayload struct {
+	Template string `json:"template"`
+	Data     struct {
+		PaymentHash string `json:"payment_hash"`
+		Reason      string `json:"reason"`
+	} `json:"data"`
+}
+
+// ChannelConfirmedPayload tells the wallet the funding of a channel the lsp
+// opened to it confirmed, so the channel no longer depends on trusting the lsp.
+type ChannelConfirmedPayload struct {
+	Template string `json:"template"`
+	Data     struct {
+		ChannelPoint   string `json:"channel_point"`
+		ShortChannelID string `json:"short_channel_id"`
+	} `json:"data"`
+}
+
+// LeaseExpiringPayload tells the wallet the lease of a channel the lsp opened
+// to it is about to expire, after which the channel may be closed if it is not
+// used. The lease is renewed with RenewChannelLease.
+type LeaseExpiringPayload struct {
+	Template string `json:"template"`
+	Data     struct {
+		ChannelPoint string `json:"channel_point"`
+		// Unix time in seconds the lease expires.
+		ExpiresAt int64 `json:"expires_at"`
+	} `json:"data"`
+}
+
 func (s *NotificationService) Notify(
+	ctx context.Context,
 	pubkey string,
 	paymenthash string,
 ) (bool, error) {
-	registrations, err := s.store.GetRegistrations(context.Background(), pubkey)
+	registrations, err := s.store.GetRegistrations(ctx, pubkey)
 	if err != nil {
 		log.Printf("Failed to get notification registrations for %s: %v", pubkey, err)
 		return false, err
 	}
 
-	req := &PaymentReceivedPayload{
-		Template: "payment_received",
-		Data: struct {
-			PaymentHash string "json:\"payment_hash\" binding:\"required\""
-		}{
-			PaymentHash: paymenthash,
-		},
+	var req PaymentReceivedPayload
+	req.Template = "payment_received"
+	req.Data.PaymentHash = paymenthash
+	body, err := json.Marshal(&req)
+	if err != nil {
+		log.Printf("Failed to encode payment notification for %s: %v", pubkey, err)
+		return false, err
 	}
 
-	var buf bytes.Buffer
-	err = json.NewEncoder(&buf).Encode(req)
+	notified := s.postWebhooks(ctx, registrations, pubkey, paymenthash, body)
+	if s.push(ctx, pubkey, paymenthash) {
+		notified = true
+	}
+
+	return notified, nil
+}
+
+// NotifyPaymentFailed tells the webhooks registered for the node that a
+// payment to it was failed for the reason. Push notifications only support
+// received payments, so devices are not notified.
+func (s *NotificationService) NotifyPaymentFailed(
+	ctx context.Context,
+	pubkey string,
+	paymenthash string,
+	reason string,
+) (bool, error) {
+	registrations, err := s.store.GetRegistrations(ctx, pubkey)
 	if err != nil {
-		log.Printf("Failed to encode payment notification for %s: %v", pubkey, err)
+		log.Printf("Failed to get notification registrations for %s: %v", pubkey, err)
+		return false, err
+	}
+
+	var req PaymentFailedPayload
+	req.Template = "payment_failed"
+	req.Data.PaymentHash = paymenthash
+	req.Data.Reason = reason
+	body, err := json.Marshal(&req)
+	if err != nil {
+		log.Printf("Failed to encode payment failed notification for %s: %v", pubkey, err)
+		return false, err
+	}
+
+	return s.postWebhooks(ctx, registrations, pubkey, paymenthash, body), nil
+}
+
+// Subscribe tells the webhooks and the payment event streams about the
+// events on the bus their clients want to know about. The webhooks are those
+// of the tenant of the node.
+func (s *NotificationService) Subscribe(bus *events.Bus) {
+	bus.Subscribe("webhooks", func(e *events.Event) {
+		ctx := context.Background()
+		if e.Node != nil {
+			ctx = tenant.NewContext(ctx, e.Node.Tenant)
+		}
+		s.NotifyChannelConfirmed(ctx, hex.EncodeToString(e.PeerID), e.ChannelPoint, e.ShortChannelID)
+	}, events.FundingConfirmed)
+	bus.Subscribe("payment_events", s.paymentEvents.handle,
+		events.PaymentIntercepted, events.ChannelOpenStarted, events.ChannelOpened, events.FundingConfirmed)
+}
+
+// NotifyChannelConfirmed tells the webhooks registered for the node that the
+// funding of the channel confirmed. Push notifications only support received
+// payments, so devices are not notified.
+func (s *NotificationService) NotifyChannelConfirmed(
+	ctx context.Context,
+	pubkey string,
+	channelPoint string,
+	shortChannelID string,
+) (bool, error) {
+	registrations, err := s.store.GetRegistrations(ctx, pubkey)
+	if err != nil {
+		log.Printf("Failed to get notification registrations for %s: %v", pubkey, err)
 		return false, err
 	}
 
+	var req ChannelConfirmedPayload
+	req.Template = "channel_confirmed"
+	req.Data.ChannelPoint = channelPoint
+	req.Data.ShortChannelID = shortChannelID
+	body, err := json.Marshal(&req)
+	if err != nil {
+		log.Printf("Failed to encode channel confirmed notification for %s: %v", pubkey, err)
+		return false, err
+	}
+
+	return s.postWebhooks(ctx, registrations, pubkey, "", body), nil
+}
+
+// NotifyLeaseExpiring tells the webhooks registered for the node that the
+// lease of the channel expires at the given time.
+func (s *NotificationService) NotifyLeaseExpiring(
+	ctx context.Context,
+	pubkey string,
+	channelPoint string,
+	expiresAt time.Time,
+) (bool, error) {
+	registrations, err := s.store.GetRegistrations(ctx, pubkey)
+	if err != nil {
+		log.Printf("Failed to get notification registrations for %s: %v", pubkey, err)
+		return false, err
+	}
+
+	var req LeaseExpiringPayload
+	req.Template = "lease_expiring"
+	req.Data.ChannelPoint = channelPoint
+	req.Data.ExpiresAt = expiresAt.Unix()
+	body, err := json.Marshal(&req)
+	if err != nil {
+		log.Printf("Failed to encode lease expiring notification for %s: %v", pubkey, err)
+		return false, err
+	}
+
+	return s.postWebhooks(ctx, registrations, pubkey, "", body), nil
+}
+
+// postWebhooks posts the notification body to the webhooks. Returns whether
+// any webhook accepted it.
+func (s *NotificationService) postWebhooks(ctx context.Context, registrations []string, pubkey string, paymenthash string, body []byte) bool {
 	notified := false
 	for _, r := range registrations {
-		resp, err := http.DefaultClient.Post(r, "application/json", &buf)
+		attempt := &NotificationAttempt{
+			Time:        time.Now().UTC(),
+			Pubkey:      pubkey,
+			PaymentHash: paymenthash,
+			Transport:   "webhook",
+			Target:      redactWebhook(r),
+			tenantID:    tenant.FromContext(ctx),
+		}
+		resp, err := http.DefaultClient.Post(r, "application/json", bytes.NewReader(body))
 		if err != nil {
 			log.Printf("Failed to send payment notification for %s to %s: %v", pubkey, r, err)
+			attempt.Error = err.Error()
+			s.attempts.add(attempt)
 			// TODO: Remove subscription?
 			continue
 		}
+		resp.Body.Close()
 
 		if resp.StatusCode != 200 {
 			log.Printf("Got non 200 status code (%s) for payment notification for %s to %s: %v", resp.Status, pubkey, r, err)
+			attempt.Error = resp.Status
+			s.attempts.add(attempt)
 			// TODO: Remove subscription?
 			continue
 		}
 
+		attempt.Success = true
+		s.attempts.add(attempt)
 		notified = true
 	}
 
-	return notified, nil
+	return notified
+}
+
+// push sends a push notification to the device tokens registered for the
+// node. Tokens the push service reports as unregistered are removed.
+func (s *NotificationService) push(ctx context.Context, pubkey string, paymenthash string) bool {
+	if len(s.pushers) == 0 {
+		return false
+	}
+
+	tokens, err := s.store.GetDeviceTokens(ctx, pubkey)
+	if err != nil {
+		log.Printf("Failed to get device tokens for %s: %v", pubkey, err)
+		return false
+	}
+
+	notified := false
+	for _, t := range tokens {
+		pusher, ok := s.pushers[t.Platform]
+		if !ok {
+			log.Printf("No pusher for device token of %s on platform %v", pubkey, t.Platform)
+			continue
+		}
+
+		err = pusher.Push(ctx, t.Token, paymenthash)
+		attempt := &NotificationAttempt{
+			Time:        time.Now().UTC(),
+			Pubkey:      pubkey,
+			PaymentHash: paymenthash,
+			Transport:   transportName(t.Platform),
+			Target:      redactToken(t.Platform, t.Token),
+			Success:     err == nil,
+			tenantID:    tenant.FromContext(ctx),
+		}
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+		s.attempts.add(attempt)
+		if errors.Is(err, ErrUnregistered) {
+			log.Printf("Removing unregistered %v device token of %s", t.Platform, pubkey)
+			err = s.store.RemoveDeviceToken(ctx, t)
+			if err != nil {
+				log.Printf("Failed to remove device token of %s: %v", pubkey, err)
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to push payment notification for %s on platform %v: %v", pubkey, t.Platform, err)
+			continue
+		}
+
+		notified = true
+	}
+
+	return notified
 }
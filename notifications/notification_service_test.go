@@ -0,0 +1,66 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type webhookStore struct {
+	Store
+	urls []string
+}
+
+func (s *webhookStore) GetRegistrations(ctx context.Context, pubkey string) ([]string, error) {
+	return s.urls, nil
+}
+
+func TestNotifyPaymentFailed(t *testing.T) {
+	var received []*PaymentFailedPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p PaymentFailedPayload
+		err := json.NewDecoder(r.Body).Decode(&p)
+		assert.NoError(t, err)
+		received = append(received, &p)
+	}))
+	defer srv.Close()
+
+	s := NewNotificationService(&webhookStore{urls: []string{srv.URL + "/a", srv.URL + "/b"}})
+	notified, err := s.NotifyPaymentFailed(context.Background(), "pubkey", "hash", "below_minimum")
+	assert.NoError(t, err)
+	assert.True(t, notified)
+
+	// Every webhook gets the complete payload.
+	assert.Len(t, received, 2)
+	for _, p := range received {
+		assert.Equal(t, "payment_failed", p.Template)
+		assert.Equal(t, "hash", p.Data.PaymentHash)
+		assert.Equal(t, "below_minimum", p.Data.Reason)
+	}
+	assert.Len(t, s.Attempts(context.Background(), "hash"), 2)
+}
+
+func TestNotifyChannelConfirmed(t *testing.T) {
+	var received []*ChannelConfirmedPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p ChannelConfirmedPayload
+		err := json.NewDecoder(r.Body).Decode(&p)
+		assert.NoError(t, err)
+		received = append(received, &p)
+	}))
+	defer srv.Close()
+
+	s := NewNotificationService(&webhookStore{urls: []string{srv.URL}})
+	notified, err := s.NotifyChannelConfirmed(context.Background(), "pubkey", "txid:0", "1x2x3")
+	assert.NoError(t, err)
+	assert.True(t, notified)
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, "channel_confirmed", received[0].Template)
+		assert.Equal(t, "txid:0", received[0].Data.ChannelPoint)
+		assert.Equal(t, "1x2x3", received[0].Data.ShortChannelID)
+	}
+}
@@ -20,6 +20,117 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type PushPlatform int32
+
+const (
+	PushPlatform_PUSH_PLATFORM_UNKNOWN PushPlatform = 0
+	PushPlatform_PUSH_PLATFORM_FCM     PushPlatform = 1
+	PushPlatform_PUSH_PLATFORM_APNS    PushPlatform = 2
+	PushPlatform_PUSH_PLATFORM_NOSTR   PushPlatform = 3
+)
+
+// Enum value maps for PushPlatform.
+var (
+	PushPlatform_name = map[int32]string{
+		0: "PUSH_PLATFORM_UNKNOWN",
+		1: "PUSH_PLATFORM_FCM",
+		2: "PUSH_PLATFORM_APNS",
+		3: "PUSH_PLATFORM_NOSTR",
+	}
+	PushPlatform_value = map[string]int32{
+		"PUSH_PLATFORM_UNKNOWN": 0,
+		"PUSH_PLATFORM_FCM":     1,
+		"PUSH_PLATFORM_APNS":    2,
+		"PUSH_PLATFORM_NOSTR":   3,
+	}
+)
+
+func (x PushPlatform) Enum() *PushPlatform {
+	p := new(PushPlatform)
+	*p = x
+	return p
+}
+
+func (x PushPlatform) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PushPlatform) Descriptor() protoreflect.EnumDescriptor {
+	return file_notifications_proto_enumTypes[0].Descriptor()
+}
+
+func (PushPlatform) Type() protoreflect.EnumType {
+	return &file_notifications_proto_enumTypes[0]
+}
+
+func (x PushPlatform) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PushPlatform.Descriptor instead.
+func (PushPlatform) EnumDescriptor() ([]byte, []int) {
+	return file_notifications_proto_rawDescGZIP(), []int{0}
+}
+
+type PaymentEventType int32
+
+const (
+	PaymentEventType_PAYMENT_EVENT_UNKNOWN PaymentEventType = 0
+	// An htlc of a registered payment to the node arrived at the lsp.
+	PaymentEventType_PAYMENT_EVENT_PAYMENT_INTERCEPTED PaymentEventType = 1
+	// The lsp started opening a channel for the payment.
+	PaymentEventType_PAYMENT_EVENT_CHANNEL_OPEN_STARTED PaymentEventType = 2
+	// The funding transaction of the channel was broadcast.
+	PaymentEventType_PAYMENT_EVENT_FUNDING_BROADCAST PaymentEventType = 3
+	// The funding transaction of the channel confirmed.
+	PaymentEventType_PAYMENT_EVENT_FUNDING_CONFIRMED PaymentEventType = 4
+)
+
+// Enum value maps for PaymentEventType.
+var (
+	PaymentEventType_name = map[int32]string{
+		0: "PAYMENT_EVENT_UNKNOWN",
+		1: "PAYMENT_EVENT_PAYMENT_INTERCEPTED",
+		2: "PAYMENT_EVENT_CHANNEL_OPEN_STARTED",
+		3: "PAYMENT_EVENT_FUNDING_BROADCAST",
+		4: "PAYMENT_EVENT_FUNDING_CONFIRMED",
+	}
+	PaymentEventType_value = map[string]int32{
+		"PAYMENT_EVENT_UNKNOWN":              0,
+		"PAYMENT_EVENT_PAYMENT_INTERCEPTED":  1,
+		"PAYMENT_EVENT_CHANNEL_OPEN_STARTED": 2,
+		"PAYMENT_EVENT_FUNDING_BROADCAST":    3,
+		"PAYMENT_EVENT_FUNDING_CONFIRMED":    4,
+	}
+)
+
+func (x PaymentEventType) Enum() *PaymentEventType {
+	p := new(PaymentEventType)
+	*p = x
+	return p
+}
+
+func (x PaymentEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PaymentEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_notifications_proto_enumTypes[1].Descriptor()
+}
+
+func (PaymentEventType) Type() protoreflect.EnumType {
+	return &file_notifications_proto_enumTypes[1]
+}
+
+func (x PaymentEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PaymentEventType.Descriptor instead.
+func (PaymentEventType) EnumDescriptor() ([]byte, []int) {
+	return file_notifications_proto_rawDescGZIP(), []int{1}
+}
+
 type SubscribeNotificationsRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -113,6 +224,275 @@ func (*SubscribeNotificationsReply) Descriptor() ([]byte, []int) {
 	return file_notifications_proto_rawDescGZIP(), []int{1}
 }
 
+// Registers a device token to push a notification to when a payment arrives
+// for the node while it is offline. The signature is a compact signature by
+// the node key over the double sha256 of the token. For PUSH_PLATFORM_NOSTR
+// the token is a uri of the form
+// nostr:<hex pubkey>?relay=<relay url>&encryption=<nip04|nip44>, and the
+// notification is published as an encrypted direct message to the pubkey on
+// the relay. The encryption defaults to nip44.
+type RegisterDeviceTokenRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Platform  PushPlatform `protobuf:"varint,1,opt,name=platform,proto3,enum=notifications.PushPlatform" json:"platform,omitempty"`
+	Token     string       `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	Signature []byte       `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (x *RegisterDeviceTokenRequest) Reset() {
+	*x = RegisterDeviceTokenRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_notifications_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RegisterDeviceTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterDeviceTokenRequest) ProtoMessage() {}
+
+func (x *RegisterDeviceTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notifications_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterDeviceTokenRequest.ProtoReflect.Descriptor instead.
+func (*RegisterDeviceTokenRequest) Descriptor() ([]byte, []int) {
+	return file_notifications_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RegisterDeviceTokenRequest) GetPlatform() PushPlatform {
+	if x != nil {
+		return x.Platform
+	}
+	return PushPlatform_PUSH_PLATFORM_UNKNOWN
+}
+
+func (x *RegisterDeviceTokenRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *RegisterDeviceTokenRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type RegisterDeviceTokenReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *RegisterDeviceTokenReply) Reset() {
+	*x = RegisterDeviceTokenReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_notifications_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RegisterDeviceTokenReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterDeviceTokenReply) ProtoMessage() {}
+
+func (x *RegisterDeviceTokenReply) ProtoReflect() protoreflect.Message {
+	mi := &file_notifications_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterDeviceTokenReply.ProtoReflect.Descriptor instead.
+func (*RegisterDeviceTokenReply) Descriptor() ([]byte, []int) {
+	return file_notifications_proto_rawDescGZIP(), []int{3}
+}
+
+// Subscribes to the events of the payments to the node and the channels
+// opened for them, for as long as the stream is open. The signature is a
+// compact signature by the node key over the double sha256 of
+// "subscribe_payment_events:<timestamp>". The timestamp is in unix seconds and
+// has to be within 5 minutes of the time of the lsp. Events that happen while
+// the node isn't subscribed are not sent later.
+type SubscribePaymentEventsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Timestamp int64  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (x *SubscribePaymentEventsRequest) Reset() {
+	*x = SubscribePaymentEventsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_notifications_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubscribePaymentEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribePaymentEventsRequest) ProtoMessage() {}
+
+func (x *SubscribePaymentEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notifications_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribePaymentEventsRequest.ProtoReflect.Descriptor instead.
+func (*SubscribePaymentEventsRequest) Descriptor() ([]byte, []int) {
+	return file_notifications_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SubscribePaymentEventsRequest) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *SubscribePaymentEventsRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type PaymentEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type PaymentEventType `protobuf:"varint,1,opt,name=type,proto3,enum=notifications.PaymentEventType" json:"type,omitempty"`
+	// Time of the event in unix milliseconds.
+	TimestampMs int64 `protobuf:"varint,2,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"`
+	// Set for payment and channel open events.
+	PaymentHash []byte `protobuf:"bytes,3,opt,name=payment_hash,json=paymentHash,proto3" json:"payment_hash,omitempty"`
+	// Amount of the htlc, or the incoming amount of the payment.
+	AmountMsat uint64 `protobuf:"varint,4,opt,name=amount_msat,json=amountMsat,proto3" json:"amount_msat,omitempty"`
+	// Set for channel open and funding events.
+	ChannelPoint string `protobuf:"bytes,5,opt,name=channel_point,json=channelPoint,proto3" json:"channel_point,omitempty"`
+	CapacitySat  uint64 `protobuf:"varint,6,opt,name=capacity_sat,json=capacitySat,proto3" json:"capacity_sat,omitempty"`
+	// Set once the funding confirmed.
+	ShortChannelId string `protobuf:"bytes,7,opt,name=short_channel_id,json=shortChannelId,proto3" json:"short_channel_id,omitempty"`
+}
+
+func (x *PaymentEvent) Reset() {
+	*x = PaymentEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_notifications_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PaymentEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaymentEvent) ProtoMessage() {}
+
+func (x *PaymentEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_notifications_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaymentEvent.ProtoReflect.Descriptor instead.
+func (*PaymentEvent) Descriptor() ([]byte, []int) {
+	return file_notifications_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PaymentEvent) GetType() PaymentEventType {
+	if x != nil {
+		return x.Type
+	}
+	return PaymentEventType_PAYMENT_EVENT_UNKNOWN
+}
+
+func (x *PaymentEvent) GetTimestampMs() int64 {
+	if x != nil {
+		return x.TimestampMs
+	}
+	return 0
+}
+
+func (x *PaymentEvent) GetPaymentHash() []byte {
+	if x != nil {
+		return x.PaymentHash
+	}
+	return nil
+}
+
+func (x *PaymentEvent) GetAmountMsat() uint64 {
+	if x != nil {
+		return x.AmountMsat
+	}
+	return 0
+}
+
+func (x *PaymentEvent) GetChannelPoint() string {
+	if x != nil {
+		return x.ChannelPoint
+	}
+	return ""
+}
+
+func (x *PaymentEvent) GetCapacitySat() uint64 {
+	if x != nil {
+		return x.CapacitySat
+	}
+	return 0
+}
+
+func (x *PaymentEvent) GetShortChannelId() string {
+	if x != nil {
+		return x.ShortChannelId
+	}
+	return ""
+}
+
 var File_notifications_proto protoreflect.FileDescriptor
 
 var file_notifications_proto_rawDesc = []byte{
@@ -125,7 +505,61 @@ var file_notifications_proto_rawDesc = []byte{
 	0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e,
 	0x61, 0x74, 0x75, 0x72, 0x65, 0x22, 0x1d, 0x0a, 0x1b, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69,
 	0x62, 0x65, 0x4e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52,
-	0x65, 0x70, 0x6c, 0x79, 0x32, 0x85, 0x01, 0x0a, 0x0d, 0x4e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63,
+	0x65, 0x70, 0x6c, 0x79, 0x22, 0x89, 0x01, 0x0a, 0x1a, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65,
+	0x72, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x37, 0x0a, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1b, 0x2e, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x50, 0x75, 0x73, 0x68, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f,
+	0x72, 0x6d, 0x52, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x12, 0x14, 0x0a, 0x05,
+	0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b,
+	0x65, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x22, 0x1a, 0x0a, 0x18, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x44, 0x65, 0x76, 0x69,
+	0x63, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x5b, 0x0a, 0x1d,
+	0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1c, 0x0a,
+	0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x1c, 0x0a, 0x09, 0x73,
+	0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09,
+	0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x22, 0x9c, 0x02, 0x0a, 0x0c, 0x50, 0x61,
+	0x79, 0x6d, 0x65, 0x6e, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x33, 0x0a, 0x04, 0x74, 0x79,
+	0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1f, 0x2e, 0x6e, 0x6f, 0x74, 0x69, 0x66,
+	0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12,
+	0x21, 0x0a, 0x0c, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x5f, 0x6d, 0x73, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x4d, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x68, 0x61,
+	0x73, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e,
+	0x74, 0x48, 0x61, 0x73, 0x68, 0x12, 0x1f, 0x0a, 0x0b, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x5f,
+	0x6d, 0x73, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x61, 0x6d, 0x6f, 0x75,
+	0x6e, 0x74, 0x4d, 0x73, 0x61, 0x74, 0x12, 0x23, 0x0a, 0x0d, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65,
+	0x6c, 0x5f, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63,
+	0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x63,
+	0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x5f, 0x73, 0x61, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x0b, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x53, 0x61, 0x74, 0x12, 0x28,
+	0x0a, 0x10, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x5f,
+	0x69, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x43,
+	0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x64, 0x2a, 0x71, 0x0a, 0x0c, 0x50, 0x75, 0x73, 0x68,
+	0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x12, 0x19, 0x0a, 0x15, 0x50, 0x55, 0x53, 0x48,
+	0x5f, 0x50, 0x4c, 0x41, 0x54, 0x46, 0x4f, 0x52, 0x4d, 0x5f, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57,
+	0x4e, 0x10, 0x00, 0x12, 0x15, 0x0a, 0x11, 0x50, 0x55, 0x53, 0x48, 0x5f, 0x50, 0x4c, 0x41, 0x54,
+	0x46, 0x4f, 0x52, 0x4d, 0x5f, 0x46, 0x43, 0x4d, 0x10, 0x01, 0x12, 0x16, 0x0a, 0x12, 0x50, 0x55,
+	0x53, 0x48, 0x5f, 0x50, 0x4c, 0x41, 0x54, 0x46, 0x4f, 0x52, 0x4d, 0x5f, 0x41, 0x50, 0x4e, 0x53,
+	0x10, 0x02, 0x12, 0x17, 0x0a, 0x13, 0x50, 0x55, 0x53, 0x48, 0x5f, 0x50, 0x4c, 0x41, 0x54, 0x46,
+	0x4f, 0x52, 0x4d, 0x5f, 0x4e, 0x4f, 0x53, 0x54, 0x52, 0x10, 0x03, 0x2a, 0xc6, 0x01, 0x0a, 0x10,
+	0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65,
+	0x12, 0x19, 0x0a, 0x15, 0x50, 0x41, 0x59, 0x4d, 0x45, 0x4e, 0x54, 0x5f, 0x45, 0x56, 0x45, 0x4e,
+	0x54, 0x5f, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12, 0x25, 0x0a, 0x21, 0x50,
+	0x41, 0x59, 0x4d, 0x45, 0x4e, 0x54, 0x5f, 0x45, 0x56, 0x45, 0x4e, 0x54, 0x5f, 0x50, 0x41, 0x59,
+	0x4d, 0x45, 0x4e, 0x54, 0x5f, 0x49, 0x4e, 0x54, 0x45, 0x52, 0x43, 0x45, 0x50, 0x54, 0x45, 0x44,
+	0x10, 0x01, 0x12, 0x26, 0x0a, 0x22, 0x50, 0x41, 0x59, 0x4d, 0x45, 0x4e, 0x54, 0x5f, 0x45, 0x56,
+	0x45, 0x4e, 0x54, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x4e, 0x45, 0x4c, 0x5f, 0x4f, 0x50, 0x45, 0x4e,
+	0x5f, 0x53, 0x54, 0x41, 0x52, 0x54, 0x45, 0x44, 0x10, 0x02, 0x12, 0x23, 0x0a, 0x1f, 0x50, 0x41,
+	0x59, 0x4d, 0x45, 0x4e, 0x54, 0x5f, 0x45, 0x56, 0x45, 0x4e, 0x54, 0x5f, 0x46, 0x55, 0x4e, 0x44,
+	0x49, 0x4e, 0x47, 0x5f, 0x42, 0x52, 0x4f, 0x41, 0x44, 0x43, 0x41, 0x53, 0x54, 0x10, 0x03, 0x12,
+	0x23, 0x0a, 0x1f, 0x50, 0x41, 0x59, 0x4d, 0x45, 0x4e, 0x54, 0x5f, 0x45, 0x56, 0x45, 0x4e, 0x54,
+	0x5f, 0x46, 0x55, 0x4e, 0x44, 0x49, 0x4e, 0x47, 0x5f, 0x43, 0x4f, 0x4e, 0x46, 0x49, 0x52, 0x4d,
+	0x45, 0x44, 0x10, 0x04, 0x32, 0xdb, 0x02, 0x0a, 0x0d, 0x4e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63,
 	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x74, 0x0a, 0x16, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72,
 	0x69, 0x62, 0x65, 0x4e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73,
 	0x12, 0x2c, 0x2e, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73,
@@ -133,10 +567,24 @@ var file_notifications_proto_rawDesc = []byte{
 	0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2a,
 	0x2e, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x53,
 	0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x4e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61,
-	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x42, 0x25, 0x5a, 0x23,
-	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62, 0x72, 0x65, 0x65, 0x7a,
-	0x2f, 0x6c, 0x73, 0x70, 0x64, 0x2f, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69,
-	0x6f, 0x6e, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x6b, 0x0a, 0x13,
+	0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x54, 0x6f,
+	0x6b, 0x65, 0x6e, 0x12, 0x29, 0x2e, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x44, 0x65, 0x76, 0x69,
+	0x63, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27,
+	0x2e, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x52,
+	0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x54, 0x6f, 0x6b,
+	0x65, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x67, 0x0a, 0x16, 0x53, 0x75, 0x62,
+	0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x45, 0x76, 0x65,
+	0x6e, 0x74, 0x73, 0x12, 0x2c, 0x2e, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x2e, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x50, 0x61, 0x79,
+	0x6d, 0x65, 0x6e, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1b, 0x2e, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x2e, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x22, 0x00,
+	0x30, 0x01, 0x42, 0x25, 0x5a, 0x23, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
+	0x2f, 0x62, 0x72, 0x65, 0x65, 0x7a, 0x2f, 0x6c, 0x73, 0x70, 0x64, 0x2f, 0x6e, 0x6f, 0x74, 0x69,
+	0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
 }
 
 var (
@@ -151,19 +599,32 @@ func file_notifications_proto_rawDescGZIP() []byte {
 	return file_notifications_proto_rawDescData
 }
 
-var file_notifications_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_notifications_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_notifications_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_notifications_proto_goTypes = []interface{}{
-	(*SubscribeNotificationsRequest)(nil), // 0: notifications.SubscribeNotificationsRequest
-	(*SubscribeNotificationsReply)(nil),   // 1: notifications.SubscribeNotificationsReply
+	(PushPlatform)(0),                     // 0: notifications.PushPlatform
+	(PaymentEventType)(0),                 // 1: notifications.PaymentEventType
+	(*SubscribeNotificationsRequest)(nil), // 2: notifications.SubscribeNotificationsRequest
+	(*SubscribeNotificationsReply)(nil),   // 3: notifications.SubscribeNotificationsReply
+	(*RegisterDeviceTokenRequest)(nil),    // 4: notifications.RegisterDeviceTokenRequest
+	(*RegisterDeviceTokenReply)(nil),      // 5: notifications.RegisterDeviceTokenReply
+	(*SubscribePaymentEventsRequest)(nil), // 6: notifications.SubscribePaymentEventsRequest
+	(*PaymentEvent)(nil),                  // 7: notifications.PaymentEvent
 }
 var file_notifications_proto_depIdxs = []int32{
-	0, // 0: notifications.Notifications.SubscribeNotifications:input_type -> notifications.SubscribeNotificationsRequest
-	1, // 1: notifications.Notifications.SubscribeNotifications:output_type -> notifications.SubscribeNotificationsReply
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: notifications.RegisterDeviceTokenRequest.platform:type_name -> notifications.PushPlatform
+	1, // 1: notifications.PaymentEvent.type:type_name -> notifications.PaymentEventType
+	2, // 2: notifications.Notifications.SubscribeNotifications:input_type -> notifications.SubscribeNotificationsRequest
+	4, // 3: notifications.Notifications.RegisterDeviceToken:input_type -> notifications.RegisterDeviceTokenRequest
+	6, // 4: notifications.Notifications.SubscribePaymentEvents:input_type -> notifications.SubscribePaymentEventsRequest
+	3, // 5: notifications.Notifications.SubscribeNotifications:output_type -> notifications.SubscribeNotificationsReply
+	5, // 6: notifications.Notifications.RegisterDeviceToken:output_type -> notifications.RegisterDeviceTokenReply
+	7, // 7: notifications.Notifications.SubscribePaymentEvents:output_type -> notifications.PaymentEvent
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_notifications_proto_init() }
@@ -196,19 +657,68 @@ func file_notifications_proto_init() {
 				return nil
 			}
 		}
+		file_notifications_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RegisterDeviceTokenRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_notifications_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RegisterDeviceTokenReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_notifications_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubscribePaymentEventsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_notifications_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PaymentEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_notifications_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      2,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_notifications_proto_goTypes,
 		DependencyIndexes: file_notifications_proto_depIdxs,
+		EnumInfos:         file_notifications_proto_enumTypes,
 		MessageInfos:      file_notifications_proto_msgTypes,
 	}.Build()
 	File_notifications_proto = out.File
@@ -23,6 +23,8 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type NotificationsClient interface {
 	SubscribeNotifications(ctx context.Context, in *SubscribeNotificationsRequest, opts ...grpc.CallOption) (*SubscribeNotificationsReply, error)
+	RegisterDeviceToken(ctx context.Context, in *RegisterDeviceTokenRequest, opts ...grpc.CallOption) (*RegisterDeviceTokenReply, error)
+	SubscribePaymentEvents(ctx context.Context, in *SubscribePaymentEventsRequest, opts ...grpc.CallOption) (Notifications_SubscribePaymentEventsClient, error)
 }
 
 type notificationsClient struct {
@@ -42,11 +44,54 @@ func (c *notificationsClient) SubscribeNotifications(ctx context.Context, in *Su
 	return out, nil
 }
 
+func (c *notificationsClient) RegisterDeviceToken(ctx context.Context, in *RegisterDeviceTokenRequest, opts ...grpc.CallOption) (*RegisterDeviceTokenReply, error) {
+	out := new(RegisterDeviceTokenReply)
+	err := c.cc.Invoke(ctx, "/notifications.Notifications/RegisterDeviceToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationsClient) SubscribePaymentEvents(ctx context.Context, in *SubscribePaymentEventsRequest, opts ...grpc.CallOption) (Notifications_SubscribePaymentEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Notifications_ServiceDesc.Streams[0], "/notifications.Notifications/SubscribePaymentEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &notificationsSubscribePaymentEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Notifications_SubscribePaymentEventsClient interface {
+	Recv() (*PaymentEvent, error)
+	grpc.ClientStream
+}
+
+type notificationsSubscribePaymentEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *notificationsSubscribePaymentEventsClient) Recv() (*PaymentEvent, error) {
+	m := new(PaymentEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // NotificationsServer is the server API for Notifications service.
 // All implementations must embed UnimplementedNotificationsServer
 // for forward compatibility
 type NotificationsServer interface {
 	SubscribeNotifications(context.Context, *SubscribeNotificationsRequest) (*SubscribeNotificationsReply, error)
+	RegisterDeviceToken(context.Context, *RegisterDeviceTokenRequest) (*RegisterDeviceTokenReply, error)
+	SubscribePaymentEvents(*SubscribePaymentEventsRequest, Notifications_SubscribePaymentEventsServer) error
 	mustEmbedUnimplementedNotificationsServer()
 }
 
@@ -57,6 +102,12 @@ type UnimplementedNotificationsServer struct {
 func (UnimplementedNotificationsServer) SubscribeNotifications(context.Context, *SubscribeNotificationsRequest) (*SubscribeNotificationsReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SubscribeNotifications not implemented")
 }
+func (UnimplementedNotificationsServer) RegisterDeviceToken(context.Context, *RegisterDeviceTokenRequest) (*RegisterDeviceTokenReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterDeviceToken not implemented")
+}
+func (UnimplementedNotificationsServer) SubscribePaymentEvents(*SubscribePaymentEventsRequest, Notifications_SubscribePaymentEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribePaymentEvents not implemented")
+}
 func (UnimplementedNotificationsServer) mustEmbedUnimplementedNotificationsServer() {}
 
 // UnsafeNotificationsServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +139,45 @@ func _Notifications_SubscribeNotifications_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Notifications_RegisterDeviceToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterDeviceTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationsServer).RegisterDeviceToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notifications.Notifications/RegisterDeviceToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationsServer).RegisterDeviceToken(ctx, req.(*RegisterDeviceTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Notifications_SubscribePaymentEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribePaymentEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NotificationsServer).SubscribePaymentEvents(m, &notificationsSubscribePaymentEventsServer{stream})
+}
+
+type Notifications_SubscribePaymentEventsServer interface {
+	Send(*PaymentEvent) error
+	grpc.ServerStream
+}
+
+type notificationsSubscribePaymentEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *notificationsSubscribePaymentEventsServer) Send(m *PaymentEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Notifications_ServiceDesc is the grpc.ServiceDesc for Notifications service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,7 +189,17 @@ var Notifications_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SubscribeNotifications",
 			Handler:    _Notifications_SubscribeNotifications_Handler,
 		},
+		{
+			MethodName: "RegisterDeviceToken",
+			Handler:    _Notifications_RegisterDeviceToken_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribePaymentEvents",
+			Handler:       _Notifications_SubscribePaymentEvents_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "notifications.proto",
 }
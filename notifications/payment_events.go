@@ -0,0 +1,115 @@
+package notifications
+
+import (
+	"encoding/hex"
+	"log"
+	"sync"
+
+	"github.com/breez/lspd/events"
+)
+
+// Payment events buffered per stream until they are sent. Events for a
+// stream that falls further behind are dropped.
+const paymentEventQueueSize = 100
+
+// paymentEvents delivers the events of their payments and channels to the
+// nodes subscribed with SubscribePaymentEvents.
+type paymentEvents struct {
+	mtx  sync.Mutex
+	subs map[string]map[chan *PaymentEvent]struct{}
+}
+
+// subscriptionKey returns the key of the streams of the node. A node that is
+// a client of multiple tenants only gets the events of the tenant it
+// subscribed with.
+func subscriptionKey(tenant string, pubkey string) string {
+	return tenant + "/" + pubkey
+}
+
+// subscribe returns the channel the events of the node are sent on, and a
+// function that ends the subscription.
+func (p *paymentEvents) subscribe(tenant string, pubkey string) (<-chan *PaymentEvent, func()) {
+	key := subscriptionKey(tenant, pubkey)
+	c := make(chan *PaymentEvent, paymentEventQueueSize)
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.subs == nil {
+		p.subs = make(map[string]map[chan *PaymentEvent]struct{})
+	}
+	if p.subs[key] == nil {
+		p.subs[key] = make(map[chan *PaymentEvent]struct{})
+	}
+	p.subs[key][c] = struct{}{}
+
+	return c, func() {
+		p.mtx.Lock()
+		defer p.mtx.Unlock()
+		delete(p.subs[key], c)
+		if len(p.subs[key]) == 0 {
+			delete(p.subs, key)
+		}
+	}
+}
+
+func (p *paymentEvents) publish(tenant string, pubkey string, e *PaymentEvent) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for c := range p.subs[subscriptionKey(tenant, pubkey)] {
+		select {
+		case c <- e:
+		default:
+			log.Printf("Payment event stream of %s is too slow, dropping %v event", pubkey, e.Type)
+		}
+	}
+}
+
+// handle sends the event on the bus to the streams of the client it is
+// about.
+func (p *paymentEvents) handle(e *events.Event) {
+	if len(e.PeerID) == 0 {
+		return
+	}
+
+	event := newPaymentEvent(e)
+	if event == nil {
+		return
+	}
+
+	tenant := ""
+	if e.Node != nil {
+		tenant = e.Node.Tenant
+	}
+	p.publish(tenant, hex.EncodeToString(e.PeerID), event)
+}
+
+// newPaymentEvent returns the payment event sent to clients for the event on
+// the bus, or nil if clients aren't told about it.
+func newPaymentEvent(e *events.Event) *PaymentEvent {
+	event := &PaymentEvent{
+		TimestampMs:    e.Time.UnixMilli(),
+		PaymentHash:    e.PaymentHash,
+		ChannelPoint:   e.ChannelPoint,
+		ShortChannelId: e.ShortChannelID,
+	}
+	if e.AmountMsat > 0 {
+		event.AmountMsat = uint64(e.AmountMsat)
+	}
+	if e.CapacitySat > 0 {
+		event.CapacitySat = uint64(e.CapacitySat)
+	}
+
+	switch e.Kind {
+	case events.PaymentIntercepted:
+		event.Type = PaymentEventType_PAYMENT_EVENT_PAYMENT_INTERCEPTED
+	case events.ChannelOpenStarted:
+		event.Type = PaymentEventType_PAYMENT_EVENT_CHANNEL_OPEN_STARTED
+	case events.ChannelOpened:
+		event.Type = PaymentEventType_PAYMENT_EVENT_FUNDING_BROADCAST
+	case events.FundingConfirmed:
+		event.Type = PaymentEventType_PAYMENT_EVENT_FUNDING_CONFIRMED
+	default:
+		return nil
+	}
+
+	return event
+}
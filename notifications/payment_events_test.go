@@ -0,0 +1,41 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentEvents(t *testing.T) {
+	var p paymentEvents
+	c, unsubscribe := p.subscribe("", "02aa")
+	other, unsubscribeOther := p.subscribe("acme", "02aa")
+	defer unsubscribeOther()
+
+	now := time.Now()
+	node := &config.NodeConfig{}
+	p.handle(&events.Event{Kind: events.PaymentIntercepted, Node: node, Time: now, PeerID: []byte{2, 0xaa}, PaymentHash: []byte{1}, AmountMsat: 1000})
+	p.handle(&events.Event{Kind: events.HtlcIntercepted, Node: node, Time: now, PeerID: []byte{2, 0xaa}})
+	p.handle(&events.Event{Kind: events.ChannelOpened, Node: node, Time: now, PeerID: []byte{2, 0xbb}})
+	p.handle(&events.Event{Kind: events.FundingConfirmed, Node: node, Time: now, PeerID: []byte{2, 0xaa}, ChannelPoint: "txid:0", ShortChannelID: "1x2x3"})
+
+	assert.Equal(t, &PaymentEvent{
+		Type:        PaymentEventType_PAYMENT_EVENT_PAYMENT_INTERCEPTED,
+		TimestampMs: now.UnixMilli(),
+		PaymentHash: []byte{1},
+		AmountMsat:  1000,
+	}, <-c)
+	e := <-c
+	assert.Equal(t, PaymentEventType_PAYMENT_EVENT_FUNDING_CONFIRMED, e.Type)
+	assert.Equal(t, "1x2x3", e.ShortChannelId)
+	assert.Len(t, c, 0)
+
+	// Events of other tenants are not sent.
+	assert.Len(t, other, 0)
+
+	unsubscribe()
+	assert.Len(t, p.subs, 1)
+}
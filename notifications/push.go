@@ -0,0 +1,22 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnregistered is returned by a Pusher if the device token is no longer
+// valid, like when the app was uninstalled. The token is removed.
+var ErrUnregistered = errors.New("device token unregistered")
+
+// Pusher sends a push notification of a received payment to a mobile device.
+type Pusher interface {
+	Platform() PushPlatform
+	Push(ctx context.Context, token string, paymentHash string) error
+}
+
+// TokenValidator is implemented by a Pusher that can check a device token
+// before it is registered.
+type TokenValidator interface {
+	ValidateToken(token string) error
+}
@@ -6,21 +6,28 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/breez/lspd/lsperrors"
+	"github.com/breez/lspd/tenant"
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
 )
 
-var ErrInvalidSignature = fmt.Errorf("invalid signature")
-var ErrInternal = fmt.Errorf("internal error")
+var ErrInvalidSignature = lsperrors.ErrInvalidSignature
+var ErrInternal = lsperrors.ErrInternal
+var ErrUnsupportedPlatform = fmt.Errorf("%w push platform", lsperrors.ErrUnsupported)
 
 type server struct {
-	store Store
+	store   Store
+	service *NotificationService
 	NotificationsServer
 }
 
-func NewNotificationsServer(store Store) NotificationsServer {
+func NewNotificationsServer(store Store, service *NotificationService) NotificationsServer {
 	return &server{
-		store: store,
+		store:   store,
+		service: service,
 	}
 }
 
@@ -28,18 +35,9 @@ func (s *server) SubscribeNotifications(
 	ctx context.Context,
 	request *SubscribeNotificationsRequest,
 ) (*SubscribeNotificationsReply, error) {
-	first := sha256.Sum256([]byte(request.Url))
-	second := sha256.Sum256(first[:])
-	pubkey, wasCompressed, err := ecdsa.RecoverCompact(
-		request.Signature,
-		second[:],
-	)
+	pubkey, err := recoverPubkey(request.Url, request.Signature)
 	if err != nil {
-		return nil, ErrInvalidSignature
-	}
-
-	if !wasCompressed {
-		return nil, ErrInvalidSignature
+		return nil, err
 	}
 
 	err = s.store.Register(ctx, hex.EncodeToString(pubkey.SerializeCompressed()), request.Url)
@@ -56,3 +54,99 @@ func (s *server) SubscribeNotifications(
 
 	return &SubscribeNotificationsReply{}, nil
 }
+
+func (s *server) RegisterDeviceToken(
+	ctx context.Context,
+	request *RegisterDeviceTokenRequest,
+) (*RegisterDeviceTokenReply, error) {
+	if !s.service.SupportsPlatform(request.Platform) {
+		return nil, ErrUnsupportedPlatform
+	}
+
+	err := s.service.ValidateToken(request.Platform, request.Token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", lsperrors.ErrInvalidRequest, err)
+	}
+
+	pubkey, err := recoverPubkey(request.Token, request.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.RegisterDeviceToken(ctx, hex.EncodeToString(pubkey.SerializeCompressed()), &DeviceToken{
+		Platform: request.Platform,
+		Token:    request.Token,
+	})
+	if err != nil {
+		log.Printf(
+			"failed to register %x for push notifications on platform %v: %v",
+			pubkey.SerializeCompressed(),
+			request.Platform,
+			err,
+		)
+
+		return nil, ErrInternal
+	}
+
+	return &RegisterDeviceTokenReply{}, nil
+}
+
+// Maximum difference between the timestamp of a SubscribePaymentEvents
+// request and the time of the lsp.
+const maxPaymentEventsClockSkew = 5 * time.Minute
+
+// SubscribePaymentEvents streams the events of the payments of the node that
+// signed the request, until the client closes the stream.
+func (s *server) SubscribePaymentEvents(
+	request *SubscribePaymentEventsRequest,
+	stream Notifications_SubscribePaymentEventsServer,
+) error {
+	skew := time.Since(time.Unix(request.Timestamp, 0))
+	if skew > maxPaymentEventsClockSkew || skew < -maxPaymentEventsClockSkew {
+		return lsperrors.ToStatus(fmt.Errorf("%w: timestamp is more than %v off", lsperrors.ErrInvalidRequest, maxPaymentEventsClockSkew))
+	}
+
+	pubkey, err := recoverPubkey(fmt.Sprintf("subscribe_payment_events:%d", request.Timestamp), request.Signature)
+	if err != nil {
+		return lsperrors.ToStatus(err)
+	}
+
+	ctx := stream.Context()
+	events, unsubscribe := s.service.paymentEvents.subscribe(
+		tenant.FromContext(ctx),
+		hex.EncodeToString(pubkey.SerializeCompressed()),
+	)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-events:
+			err = stream.Send(e)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// recoverPubkey returns the node key that signed the message with a compact
+// signature over its double sha256.
+func recoverPubkey(message string, signature []byte) (*btcec.PublicKey, error) {
+	first := sha256.Sum256([]byte(message))
+	second := sha256.Sum256(first[:])
+	pubkey, wasCompressed, err := ecdsa.RecoverCompact(
+		signature,
+		second[:],
+	)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	if !wasCompressed {
+		return nil, ErrInvalidSignature
+	}
+
+	return pubkey, nil
+}
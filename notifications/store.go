@@ -4,7 +4,17 @@ import (
 	"context"
 )
 
+// DeviceToken is a token of a mobile device registered for push
+// notifications.
+type DeviceToken struct {
+	Platform PushPlatform
+	Token    string
+}
+
 type Store interface {
 	Register(ctx context.Context, pubkey string, url string) error
 	GetRegistrations(ctx context.Context, pubkey string) ([]string, error)
+	RegisterDeviceToken(ctx context.Context, pubkey string, token *DeviceToken) error
+	GetDeviceTokens(ctx context.Context, pubkey string) ([]*DeviceToken, error)
+	RemoveDeviceToken(ctx context.Context, token *DeviceToken) error
 }
@@ -0,0 +1,351 @@
+package openchannel
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// Max time of the node calls of a single attempt to open a channel.
+	attemptTimeout = 2 * time.Minute
+
+	// Jobs are failed after this many attempts.
+	maxAttempts = 5
+
+	// Wait before the first retry of a failed attempt, doubled on every
+	// later retry.
+	retryBackoff = 10 * time.Second
+
+	// Interval to look for jobs due, besides right after a job is submitted.
+	pollInterval = 5 * time.Second
+)
+
+const (
+	StatusPending = "pending"
+	StatusOpening = "opening"
+	StatusOpened  = "opened"
+	StatusFailed  = "failed"
+)
+
+var jobsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_channel_open_jobs_total",
+	Help: "Channel open jobs finished, by status.",
+}, []string{"node", "status"})
+
+// Job is a channel open requested by a client with OpenChannel.
+type Job struct {
+	ID     string
+	PeerID []byte
+	// The commitment type requested for the channel, empty for the type
+	// the node prefers. Once the channel is opened, the commitment type it
+	// was opened with.
+	CommitmentType lightning.CommitmentType
+	Status         string
+	Attempts       int
+	// The funding outpoint of the channel, once it is opened. If the client
+	// already had a channel with the node, the outpoint of that channel.
+	ChannelPoint  string
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Done returns whether the job is opened or failed.
+func (j *Job) Done() bool {
+	return j.Status == StatusOpened || j.Status == StatusFailed
+}
+
+type Store interface {
+	// AddJob records a new job.
+	AddJob(ctx context.Context, lspNodeID []byte, job *Job) error
+	// GetJob returns the job with the given id, or nil if it doesn't exist.
+	GetJob(ctx context.Context, lspNodeID []byte, id string) (*Job, error)
+	// ActiveJob returns the newest job for the peer that didn't fail, or nil
+	// if there is none.
+	ActiveJob(ctx context.Context, lspNodeID []byte, peerID []byte) (*Job, error)
+	// ListDue returns the jobs that are not done and due for an attempt.
+	ListDue(ctx context.Context, lspNodeID []byte, now time.Time) ([]*Job, error)
+	// UpdateJob stores the status, attempts, channel point, last error and
+	// next attempt time of the job.
+	UpdateJob(ctx context.Context, job *Job) error
+}
+
+// Runner executes the channel opens requested by clients in the background.
+// Jobs are persisted before they are executed, so a client that lost its
+// connection can ask for the outcome later, and jobs interrupted by a
+// restart are picked up again. Failed attempts are retried with backoff.
+type Runner struct {
+	client    lightning.Client
+	store     Store
+	node      *config.NodeConfig
+	logger    *log.Logger
+	lspNodeID []byte
+	wake      chan struct{}
+	running   map[string]struct{}
+	submitMtx sync.Mutex
+	ctx       context.Context
+	cancel    context.CancelFunc
+	mtx       sync.Mutex
+	wg        sync.WaitGroup
+}
+
+func NewRunner(
+	client lightning.Client,
+	store Store,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) *Runner {
+	lspNodeID, _ := hex.DecodeString(node.NodePubkey)
+	return &Runner{
+		client:    client,
+		store:     store,
+		node:      node,
+		logger:    logger,
+		lspNodeID: lspNodeID,
+		wake:      make(chan struct{}, 1),
+		running:   make(map[string]struct{}),
+	}
+}
+
+func (r *Runner) Node() *config.NodeConfig {
+	return r.node
+}
+
+// Submit creates a job to open a channel of the commitment type to the peer.
+// If a job for the peer is in progress or opened a channel already, that job
+// is returned instead, so a client retrying its request doesn't open a second
+// channel.
+func (r *Runner) Submit(ctx context.Context, peerID []byte, commitmentType lightning.CommitmentType) (*Job, error) {
+	r.submitMtx.Lock()
+	defer r.submitMtx.Unlock()
+
+	job, err := r.store.ActiveJob(ctx, r.lspNodeID, peerID)
+	if err != nil {
+		return nil, err
+	}
+	if job != nil {
+		return job, nil
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job = &Job{
+		ID:             id,
+		PeerID:         peerID,
+		CommitmentType: commitmentType,
+		Status:         StatusPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	err = r.store.AddJob(ctx, r.lspNodeID, job)
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.Printf("channel open: job %s submitted for %x", job.ID, peerID)
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+// Get returns the job with the given id, or nil if it doesn't exist.
+func (r *Runner) Get(ctx context.Context, id string) (*Job, error) {
+	return r.store.GetJob(ctx, r.lspNodeID, id)
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("rand.Read() error: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// Start executes the jobs that are due until Stop is called.
+func (r *Runner) Start() error {
+	r.mtx.Lock()
+	r.ctx, r.cancel = context.WithCancel(tenant.NewContext(context.Background(), r.node.Tenant))
+	ctx := r.ctx
+	r.mtx.Unlock()
+
+	for {
+		r.runDue(ctx)
+		select {
+		case <-ctx.Done():
+			r.wg.Wait()
+			return nil
+		case <-r.wake:
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (r *Runner) Stop() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+func (r *Runner) runDue(ctx context.Context) {
+	jobs, err := r.store.ListDue(ctx, r.lspNodeID, time.Now())
+	if err != nil {
+		r.logger.Printf("channel open: ListDue() error: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		r.mtx.Lock()
+		_, ok := r.running[job.ID]
+		if !ok {
+			r.running[job.ID] = struct{}{}
+		}
+		r.mtx.Unlock()
+		if ok {
+			continue
+		}
+
+		r.wg.Add(1)
+		go func(job *Job) {
+			defer r.wg.Done()
+			r.run(ctx, job)
+
+			r.mtx.Lock()
+			delete(r.running, job.ID)
+			r.mtx.Unlock()
+		}(job)
+	}
+}
+
+// run makes an attempt to open the channel of the job, and records the
+// outcome.
+func (r *Runner) run(ctx context.Context, job *Job) {
+	job.Status = StatusOpening
+	job.Attempts++
+	job.UpdatedAt = time.Now()
+	err := r.store.UpdateJob(ctx, job)
+	if err != nil {
+		r.logger.Printf("channel open: UpdateJob(%s) error: %v", job.ID, err)
+		return
+	}
+
+	channelPoint, err := r.open(ctx, job)
+	if ctx.Err() != nil {
+		// Stopped. The job is picked up again after a restart.
+		return
+	}
+
+	job.UpdatedAt = time.Now()
+	if err == nil {
+		job.Status = StatusOpened
+		job.ChannelPoint = channelPoint
+		job.LastError = ""
+		r.logger.Printf("channel open: job %s for %x opened channel %s", job.ID, job.PeerID, channelPoint)
+	} else {
+		job.LastError = err.Error()
+		if job.Attempts >= maxAttempts {
+			job.Status = StatusFailed
+			r.logger.Printf("channel open: job %s for %x failed after %d attempts: %v", job.ID, job.PeerID, job.Attempts, err)
+		} else {
+			job.Status = StatusPending
+			job.NextAttemptAt = job.UpdatedAt.Add(backoff(job.Attempts))
+			r.logger.Printf("channel open: attempt %d of job %s for %x failed, retrying at %v: %v",
+				job.Attempts, job.ID, job.PeerID, job.NextAttemptAt, err)
+		}
+	}
+
+	if job.Done() {
+		jobsTotal.WithLabelValues(r.node.Label(), job.Status).Inc()
+	}
+
+	err = r.store.UpdateJob(ctx, job)
+	if err != nil {
+		r.logger.Printf("channel open: UpdateJob(%s) error: %v", job.ID, err)
+	}
+}
+
+// ExistingChannel returns the largest open or pending channel with the peer
+// of at least the duplicate channel threshold of the node, or nil if there is
+// none.
+func (r *Runner) ExistingChannel(ctx context.Context, peerID []byte) (*lightning.PeerChannel, error) {
+	channels, err := r.client.ListPeerChannels(ctx, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("ListPeerChannels() error: %w", err)
+	}
+
+	var existing *lightning.PeerChannel
+	for _, ch := range channels {
+		if ch.CapacitySat < r.node.DuplicateChannelThresholdSat {
+			continue
+		}
+		if existing == nil || ch.CapacitySat > existing.CapacitySat {
+			existing = ch
+		}
+	}
+
+	return existing, nil
+}
+
+// backoff returns the wait before the next attempt after the given number
+// of failed attempts.
+func backoff(attempts int) time.Duration {
+	return retryBackoff << (attempts - 1)
+}
+
+// open opens the channel to the peer, unless the peer already has a channel
+// with the node. An earlier attempt may have opened the channel before it was
+// interrupted, in which case the channel is pending already.
+func (r *Runner) open(ctx context.Context, job *Job) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, attemptTimeout)
+	defer cancel()
+
+	existing, err := r.ExistingChannel(ctx, job.PeerID)
+	if err != nil {
+		return "", err
+	}
+
+	if existing != nil {
+		r.logger.Printf("channel open: %x already has channel %s of %v sat, not opening another one",
+			job.PeerID, existing.ChannelPoint, existing.CapacitySat)
+		return existing.ChannelPoint, nil
+	}
+
+	commitmentType := lightning.NegotiateCommitmentType(r.client, job.PeerID, job.CommitmentType, r.node.ChannelPrivate, r.logger)
+	outPoint, err := r.client.OpenChannel(&lightning.OpenChannelRequest{
+		CapacitySat:    r.node.ChannelAmount,
+		Destination:    job.PeerID,
+		TargetConf:     &r.node.TargetConf,
+		MinHtlcMsat:    r.node.MinHtlcMsat,
+		IsPrivate:      r.node.ChannelPrivate,
+		CommitmentType: commitmentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("OpenChannel() error: %w", err)
+	}
+
+	job.CommitmentType = commitmentType
+	return outPoint.String(), nil
+}
@@ -0,0 +1,168 @@
+package openchannel
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockStore struct {
+	jobs []*Job
+}
+
+func (s *mockStore) AddJob(ctx context.Context, lspNodeID []byte, job *Job) error {
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+func (s *mockStore) GetJob(ctx context.Context, lspNodeID []byte, id string) (*Job, error) {
+	for _, j := range s.jobs {
+		if j.ID == id {
+			return j, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *mockStore) ActiveJob(ctx context.Context, lspNodeID []byte, peerID []byte) (*Job, error) {
+	for _, j := range s.jobs {
+		if string(j.PeerID) == string(peerID) && j.Status != StatusFailed {
+			return j, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *mockStore) ListDue(ctx context.Context, lspNodeID []byte, now time.Time) ([]*Job, error) {
+	var due []*Job
+	for _, j := range s.jobs {
+		if !j.Done() && !j.NextAttemptAt.After(now) {
+			due = append(due, j)
+		}
+	}
+	return due, nil
+}
+
+func (s *mockStore) UpdateJob(ctx context.Context, job *Job) error {
+	return nil
+}
+
+type mockClient struct {
+	lightning.Client
+	failures int
+	opened   int
+	channels []*lightning.PeerChannel
+}
+
+func (c *mockClient) ListPeerChannels(ctx context.Context, peerID []byte) ([]*lightning.PeerChannel, error) {
+	return c.channels, nil
+}
+
+// SupportsCommitmentType returns whether the type is an anchors or
+// static_remote_key type, like a node without taproot support.
+func (c *mockClient) SupportsCommitmentType(peerID []byte, t lightning.CommitmentType) (bool, error) {
+	return t != lightning.CommitmentTypeTaproot, nil
+}
+
+func (c *mockClient) OpenChannel(req *lightning.OpenChannelRequest) (*wire.OutPoint, error) {
+	if c.failures > 0 {
+		c.failures--
+		return nil, errors.New("peer not online")
+	}
+
+	c.opened++
+	outPoint := &wire.OutPoint{Index: uint32(c.opened)}
+	c.channels = append(c.channels, &lightning.PeerChannel{
+		ChannelPoint: outPoint.String(),
+		CapacitySat:  uint64(req.CapacitySat),
+		Pending:      true,
+	})
+	return outPoint, nil
+}
+
+func TestRunnerRetries(t *testing.T) {
+	store := &mockStore{}
+	client := &mockClient{failures: 1}
+	runner := NewRunner(client, store, &config.NodeConfig{ChannelPrivate: true}, log.New(os.Stderr, "", 0))
+	ctx := context.Background()
+	peer := []byte{0x02, 0xaa}
+
+	job, err := runner.Submit(ctx, peer, lightning.CommitmentTypeTaproot)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPending, job.Status)
+
+	// A retry by the client gets the same job.
+	again, err := runner.Submit(ctx, peer, "")
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, again.ID)
+
+	runner.run(ctx, job)
+	assert.Equal(t, StatusPending, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "OpenChannel() error: peer not online", job.LastError)
+	assert.True(t, job.NextAttemptAt.After(time.Now()))
+
+	runner.run(ctx, job)
+	assert.Equal(t, StatusOpened, job.Status)
+	assert.Equal(t, (&wire.OutPoint{Index: 1}).String(), job.ChannelPoint)
+	assert.Equal(t, 1, client.opened)
+
+	// The peer doesn't support taproot channels.
+	assert.Equal(t, lightning.CommitmentTypeAnchors, job.CommitmentType)
+}
+
+func TestRunnerFails(t *testing.T) {
+	store := &mockStore{}
+	client := &mockClient{failures: maxAttempts}
+	runner := NewRunner(client, store, &config.NodeConfig{}, log.New(os.Stderr, "", 0))
+	ctx := context.Background()
+	peer := []byte{0x02, 0xaa}
+
+	job, err := runner.Submit(ctx, peer, "")
+	assert.NoError(t, err)
+	for i := 0; i < maxAttempts; i++ {
+		runner.run(ctx, job)
+	}
+	assert.Equal(t, StatusFailed, job.Status)
+
+	// A new request after a failed job starts a new job.
+	next, err := runner.Submit(ctx, peer, "")
+	assert.NoError(t, err)
+	assert.NotEqual(t, job.ID, next.ID)
+}
+
+func TestRunnerExistingChannel(t *testing.T) {
+	store := &mockStore{}
+	client := &mockClient{channels: []*lightning.PeerChannel{
+		{ChannelPoint: "01:0", CapacitySat: 50_000},
+	}}
+	node := &config.NodeConfig{ChannelAmount: 100_000, DuplicateChannelThresholdSat: 100_000}
+	runner := NewRunner(client, store, node, log.New(os.Stderr, "", 0))
+	ctx := context.Background()
+
+	// Channels below the threshold don't count.
+	job, err := runner.Submit(ctx, []byte{0x02, 0xaa}, "")
+	assert.NoError(t, err)
+	runner.run(ctx, job)
+	assert.Equal(t, StatusOpened, job.Status)
+	assert.Equal(t, 1, client.opened)
+
+	existing, err := runner.ExistingChannel(ctx, []byte{0x02, 0xaa})
+	assert.NoError(t, err)
+	assert.Equal(t, job.ChannelPoint, existing.ChannelPoint)
+
+	// A job for a client with a channel gets the existing channel.
+	job = &Job{ID: "x", PeerID: []byte{0x02, 0xaa}}
+	runner.run(ctx, job)
+	assert.Equal(t, StatusOpened, job.Status)
+	assert.Equal(t, existing.ChannelPoint, job.ChannelPoint)
+	assert.Equal(t, 1, client.opened)
+}
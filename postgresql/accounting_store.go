@@ -0,0 +1,176 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/dbcrypt"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// AccountingChannel is a channel opened by lspd with the opening fees
+// collected for it.
+type AccountingChannel struct {
+	ChannelPoint     string    `json:"channelPoint"`
+	PeerID           string    `json:"peerId"`
+	OpenedAt         time.Time `json:"openedAt"`
+	Token            string    `json:"token"`
+	PaymentCount     int64     `json:"paymentCount"`
+	FeeCollectedMsat int64     `json:"feeCollectedMsat"`
+	// The fee of the funding transaction, if known. Not stored in the
+	// database, the caller looks it up.
+	OnchainFeeSat *int64 `json:"onchainFeeSat"`
+}
+
+// AccountingTotals are the fees collected by a node in a period. The routing
+// fees are the fees of all forwards of the node, so they include the opening
+// fees deducted from htlcs forwarded to a new channel.
+type AccountingTotals struct {
+	OpeningFeeMsat int64 `json:"openingFeeMsat"`
+	RoutingFeeMsat int64 `json:"routingFeeMsat"`
+	ForwardCount   int64 `json:"forwardCount"`
+}
+
+// AccountingStore reads the channel opens and fees of the nodes for the
+// financial export.
+type AccountingStore struct {
+	pool   *pgxpool.Pool
+	cipher *dbcrypt.Cipher
+}
+
+func NewAccountingStore(pool *pgxpool.Pool, cipher *dbcrypt.Cipher) *AccountingStore {
+	return &AccountingStore{pool: pool, cipher: cipher}
+}
+
+// ChannelOpens returns the channels opened by the node in [from, to), with
+// the token of the payments the channel was opened for and the fees deducted
+// from their htlcs.
+func (s *AccountingStore) ChannelOpens(ctx context.Context, lspNodeID []byte, from, to time.Time) ([]*AccountingChannel, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT channel_point, nodeid, opened_at
+		 FROM channels
+		 WHERE lsp_nodeid = $1 AND tenant_id = $4 AND opened_at >= $2 AND opened_at < $3
+		 ORDER BY opened_at`,
+		lspNodeID, from.UnixMicro(), to.UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ChannelOpens(%x) error: %w", lspNodeID, err)
+	}
+	defer rows.Close()
+
+	channels := []*AccountingChannel{}
+	byChannelPoint := make(map[string]*AccountingChannel)
+	var txids [][]byte
+	for rows.Next() {
+		var channelPoint *string
+		var peerID []byte
+		var openedAt int64
+		err = rows.Scan(&channelPoint, &peerID, &openedAt)
+		if err != nil {
+			return nil, fmt.Errorf("ChannelOpens(%x) scan error: %w", lspNodeID, err)
+		}
+		if channelPoint == nil {
+			continue
+		}
+
+		c := &AccountingChannel{
+			ChannelPoint: *channelPoint,
+			PeerID:       fmt.Sprintf("%x", peerID),
+			OpenedAt:     time.UnixMicro(openedAt).UTC(),
+		}
+		channels = append(channels, c)
+		byChannelPoint[c.ChannelPoint] = c
+
+		outpoint, err := basetypes.ParseOutPoint(c.ChannelPoint)
+		if err != nil {
+			log.Printf("ChannelOpens: invalid channel point %s: %v", c.ChannelPoint, err)
+			continue
+		}
+		txids = append(txids, outpoint.Hash[:])
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ChannelOpens(%x) rows error: %w", lspNodeID, err)
+	}
+	if len(txids) == 0 {
+		return channels, nil
+	}
+
+	rows, err = s.pool.Query(ctx,
+		`SELECT p.funding_tx_id, p.funding_tx_outnum, p.opening_fee_params->>'token', t.name,
+		        count(DISTINCT p.payment_hash), coalesce(sum(h.amount_in_msat - h.amount_out_msat), 0)::bigint
+		 FROM payments p
+		 LEFT JOIN api_tokens t ON t.token = p.opening_fee_params->>'token' AND t.tenant_id = p.tenant_id
+		 LEFT JOIN htlc_fees h ON h.payment_hash = p.payment_hash AND h.tenant_id = p.tenant_id
+		 WHERE p.lsp_nodeid = $1 AND p.tenant_id = $3 AND p.funding_tx_id = ANY($2)
+		 GROUP BY p.funding_tx_id, p.funding_tx_outnum, p.opening_fee_params->>'token', t.name`,
+		lspNodeID, txids, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ChannelOpens(%x) payments error: %w", lspNodeID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fundingTxID []byte
+		var fundingTxOutnum pgtype.Int4
+		var token, tokenName *string
+		var paymentCount, feeMsat int64
+		err = rows.Scan(&fundingTxID, &fundingTxOutnum, &token, &tokenName, &paymentCount, &feeMsat)
+		if err != nil {
+			return nil, fmt.Errorf("ChannelOpens(%x) payments scan error: %w", lspNodeID, err)
+		}
+
+		cp, err := basetypes.NewOutPoint(fundingTxID, uint32(fundingTxOutnum.Int))
+		if err != nil {
+			continue
+		}
+		c, ok := byChannelPoint[cp.String()]
+		if !ok {
+			continue
+		}
+
+		c.PaymentCount += paymentCount
+		c.FeeCollectedMsat += feeMsat
+		switch {
+		case tokenName != nil:
+			c.Token = *tokenName
+		case token != nil:
+			t, _ := decryptString(s.cipher, *token)
+			c.Token = redactToken(t)
+		}
+	}
+
+	return channels, rows.Err()
+}
+
+// Totals returns the opening fees deducted from htlcs and the routing fees of
+// the forwards of the node in [from, to).
+func (s *AccountingStore) Totals(ctx context.Context, lspNodeID []byte, from, to time.Time) (*AccountingTotals, error) {
+	var totals AccountingTotals
+	err := s.pool.QueryRow(ctx,
+		`SELECT coalesce(sum(amount_in_msat - amount_out_msat), 0)::bigint
+		 FROM htlc_fees
+		 WHERE lsp_nodeid = $1 AND tenant_id = $4 AND created_at >= $2 AND created_at < $3`,
+		lspNodeID, from.UnixMicro(), to.UnixMicro(), tenant.FromContext(ctx),
+	).Scan(&totals.OpeningFeeMsat)
+	if err != nil {
+		return nil, fmt.Errorf("Totals(%x) opening fees error: %w", lspNodeID, err)
+	}
+
+	err = s.pool.QueryRow(ctx,
+		`SELECT count(*), coalesce(sum(amt_msat_in - amt_msat_out), 0)::bigint
+		 FROM forwarding_history
+		 WHERE lsp_nodeid = $1 AND tenant_id = $4 AND "timestamp" >= $2 AND "timestamp" < $3`,
+		lspNodeID, from.UnixNano(), to.UnixNano(), tenant.FromContext(ctx),
+	).Scan(&totals.ForwardCount, &totals.RoutingFeeMsat)
+	if err != nil {
+		return nil, fmt.Errorf("Totals(%x) routing fees error: %w", lspNodeID, err)
+	}
+
+	return &totals, nil
+}
@@ -0,0 +1,187 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/audit"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// AuditStore stores the htlc audit log.
+type AuditStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditStore(pool *pgxpool.Pool) *AuditStore {
+	return &AuditStore{pool: pool}
+}
+
+var auditColumns = []string{
+	"lsp_nodeid",
+	"intercepted_at",
+	"payment_hash",
+	"scid",
+	"amount_msat",
+	"incoming_expiry",
+	"outgoing_expiry",
+	"registered_payment_hash",
+	"peer_id",
+	"action",
+	"failure_code",
+	"channel_point",
+	"channel_opened",
+	"latency_us",
+	"failure_reason",
+	"tenant_id",
+}
+
+func (s *AuditStore) Insert(ctx context.Context, lspNodeID []byte, records []*audit.Record) error {
+	tenantID := tenant.FromContext(ctx)
+	rows := make([][]interface{}, 0, len(records))
+	for _, r := range records {
+		paymentHash, err := hex.DecodeString(r.PaymentHash)
+		if err != nil {
+			return fmt.Errorf("Insert() invalid payment hash %s: %w", r.PaymentHash, err)
+		}
+
+		rows = append(rows, []interface{}{
+			lspNodeID,
+			r.InterceptedAt.UnixMicro(),
+			paymentHash,
+			r.ShortChannelID,
+			r.AmountMsat,
+			int64(r.IncomingExpiry),
+			int64(r.OutgoingExpiry),
+			nullableHex(r.RegisteredPaymentHash),
+			nullableHex(r.PeerID),
+			r.Action,
+			nullableString(r.FailureCode),
+			nullableString(r.ChannelPoint),
+			r.ChannelOpened,
+			r.LatencyMicros,
+			nullableString(r.FailureReason),
+			tenantID,
+		})
+	}
+
+	_, err := s.pool.CopyFrom(ctx, pgx.Identifier{"htlc_audit_log"}, auditColumns, pgx.CopyFromRows(rows))
+	if err != nil {
+		return fmt.Errorf("Insert(%d records) error: %w", len(records), err)
+	}
+
+	return nil
+}
+
+func nullableHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+
+	return b
+}
+
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+func (s *AuditStore) Query(ctx context.Context, lspNodeID []byte, filter *audit.Filter) ([]*audit.Record, error) {
+	var from, to *int64
+	if !filter.From.IsZero() {
+		f := filter.From.UnixMicro()
+		from = &f
+	}
+	if !filter.To.IsZero() {
+		t := filter.To.UnixMicro()
+		to = &t
+	}
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT intercepted_at, payment_hash, scid, amount_msat, incoming_expiry,
+		        outgoing_expiry, registered_payment_hash, peer_id, action,
+		        failure_code, channel_point, channel_opened, latency_us,
+		        failure_reason
+		 FROM htlc_audit_log
+		 WHERE lsp_nodeid = $1 AND tenant_id = $7
+		   AND ($2::bytea IS NULL OR payment_hash = $2 OR registered_payment_hash = $2)
+		   AND ($3::bytea IS NULL OR peer_id = $3)
+		   AND ($4::bigint IS NULL OR intercepted_at >= $4)
+		   AND ($5::bigint IS NULL OR intercepted_at < $5)
+		 ORDER BY intercepted_at DESC, id DESC
+		 LIMIT $6`,
+		lspNodeID, filter.PaymentHash, filter.PeerID, from, to, filter.Limit, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Query(%x) error: %w", lspNodeID, err)
+	}
+	defer rows.Close()
+
+	records := []*audit.Record{}
+	for rows.Next() {
+		var r audit.Record
+		var interceptedAt, incomingExpiry, outgoingExpiry int64
+		var paymentHash, registeredPaymentHash, peerID []byte
+		var failureCode, channelPoint, failureReason pgtype.Varchar
+		err = rows.Scan(
+			&interceptedAt,
+			&paymentHash,
+			&r.ShortChannelID,
+			&r.AmountMsat,
+			&incomingExpiry,
+			&outgoingExpiry,
+			&registeredPaymentHash,
+			&peerID,
+			&r.Action,
+			&failureCode,
+			&channelPoint,
+			&r.ChannelOpened,
+			&r.LatencyMicros,
+			&failureReason,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Query(%x) error: %w", lspNodeID, err)
+		}
+
+		r.InterceptedAt = time.UnixMicro(interceptedAt).UTC()
+		r.PaymentHash = hex.EncodeToString(paymentHash)
+		r.IncomingExpiry = uint32(incomingExpiry)
+		r.OutgoingExpiry = uint32(outgoingExpiry)
+		r.RegisteredPaymentHash = hex.EncodeToString(registeredPaymentHash)
+		r.PeerID = hex.EncodeToString(peerID)
+		if failureCode.Status == pgtype.Present {
+			r.FailureCode = failureCode.String
+		}
+		if channelPoint.Status == pgtype.Present {
+			r.ChannelPoint = channelPoint.String
+		}
+		if failureReason.Status == pgtype.Present {
+			r.FailureReason = failureReason.String
+		}
+		records = append(records, &r)
+	}
+
+	return records, rows.Err()
+}
+
+func (s *AuditStore) DeleteBefore(ctx context.Context, lspNodeID []byte, before time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx,
+		`DELETE FROM htlc_audit_log
+		 WHERE lsp_nodeid = $1 AND tenant_id = $3 AND intercepted_at < $2`,
+		lspNodeID, before.UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("DeleteBefore(%x) error: %w", lspNodeID, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,134 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ChannelAliasStore stores the aliases and real short channel ids of the
+// channels opened by lspd.
+type ChannelAliasStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewChannelAliasStore(pool *pgxpool.Pool) *ChannelAliasStore {
+	return &ChannelAliasStore{pool: pool}
+}
+
+// SaveChannelAlias stores the alias of the channel. The alias of a channel
+// never changes once stored, and a known real scid is never unset.
+func (s *ChannelAliasStore) SaveChannelAlias(ctx context.Context, lspNodeID []byte, alias *lightning.ChannelAlias) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO channel_aliases (lsp_nodeid, channel_point, peer_id, alias_scid, real_scid, updated_at, tenant_id)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, 0::int8), $6, $7)
+		 ON CONFLICT (lsp_nodeid, channel_point) DO UPDATE
+		 SET real_scid = COALESCE(EXCLUDED.real_scid, channel_aliases.real_scid),
+		     updated_at = EXCLUDED.updated_at`,
+		lspNodeID, alias.ChannelPoint, alias.PeerID, int64(alias.Alias), int64(alias.RealScid), time.Now().UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("SaveChannelAlias(%x, %s) error: %w", lspNodeID, alias.ChannelPoint, err)
+	}
+
+	return nil
+}
+
+// GetChannelAlias returns the stored alias of the channel, or nil if none is
+// stored.
+func (s *ChannelAliasStore) GetChannelAlias(ctx context.Context, lspNodeID []byte, channelPoint string) (*lightning.ChannelAlias, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT channel_point, peer_id, alias_scid, real_scid
+		 FROM channel_aliases
+		 WHERE lsp_nodeid = $1 AND channel_point = $2 AND tenant_id = $3`,
+		lspNodeID, channelPoint, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetChannelAlias(%x, %s) error: %w", lspNodeID, channelPoint, err)
+	}
+
+	aliases, err := scanChannelAliases(rows)
+	if err != nil {
+		return nil, fmt.Errorf("GetChannelAlias(%x, %s) error: %w", lspNodeID, channelPoint, err)
+	}
+
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+
+	return aliases[0], nil
+}
+
+// ListUnconfirmedAliases returns the stored aliases of the channels with the
+// peer whose real scid is not known yet.
+func (s *ChannelAliasStore) ListUnconfirmedAliases(ctx context.Context, lspNodeID []byte, peerID []byte) ([]*lightning.ChannelAlias, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT channel_point, peer_id, alias_scid, real_scid
+		 FROM channel_aliases
+		 WHERE lsp_nodeid = $1 AND peer_id = $2 AND tenant_id = $3 AND real_scid IS NULL`,
+		lspNodeID, peerID, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListUnconfirmedAliases(%x, %x) error: %w", lspNodeID, peerID, err)
+	}
+
+	aliases, err := scanChannelAliases(rows)
+	if err != nil {
+		return nil, fmt.Errorf("ListUnconfirmedAliases(%x, %x) error: %w", lspNodeID, peerID, err)
+	}
+
+	return aliases, nil
+}
+
+// ListChannelAliases returns the stored aliases of the channels of the node.
+func (s *ChannelAliasStore) ListChannelAliases(ctx context.Context, lspNodeID []byte) ([]*lightning.ChannelAlias, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT channel_point, peer_id, alias_scid, real_scid
+		 FROM channel_aliases
+		 WHERE lsp_nodeid = $1 AND tenant_id = $2`,
+		lspNodeID, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListChannelAliases(%x) error: %w", lspNodeID, err)
+	}
+
+	aliases, err := scanChannelAliases(rows)
+	if err != nil {
+		return nil, fmt.Errorf("ListChannelAliases(%x) error: %w", lspNodeID, err)
+	}
+
+	return aliases, nil
+}
+
+func scanChannelAliases(rows pgx.Rows) ([]*lightning.ChannelAlias, error) {
+	defer rows.Close()
+	var aliases []*lightning.ChannelAlias
+	for rows.Next() {
+		var channelPoint string
+		var peerID []byte
+		var alias int64
+		var realScid *int64
+		err := rows.Scan(&channelPoint, &peerID, &alias, &realScid)
+		if err != nil {
+			return nil, err
+		}
+
+		a := &lightning.ChannelAlias{
+			ChannelPoint: channelPoint,
+			PeerID:       peerID,
+			Alias:        basetypes.ShortChannelID(alias),
+		}
+		if realScid != nil {
+			a.RealScid = basetypes.ShortChannelID(*realScid)
+		}
+		aliases = append(aliases, a)
+	}
+
+	return aliases, rows.Err()
+}
@@ -0,0 +1,138 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/openchannel"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ChannelOpenJobStore stores the channel opens requested by clients with
+// OpenChannel.
+type ChannelOpenJobStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewChannelOpenJobStore(pool *pgxpool.Pool) *ChannelOpenJobStore {
+	return &ChannelOpenJobStore{pool: pool}
+}
+
+const channelOpenJobColumns = `id, peer_id, commitment_type, status, attempts, channel_point, last_error, next_attempt_at, created_at, updated_at`
+
+func (s *ChannelOpenJobStore) AddJob(ctx context.Context, lspNodeID []byte, job *openchannel.Job) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO channel_open_jobs (id, lsp_nodeid, peer_id, commitment_type, status, attempts, channel_point, last_error, next_attempt_at, created_at, updated_at, tenant_id)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11, $12)`,
+		job.ID, lspNodeID, job.PeerID, string(job.CommitmentType), job.Status, job.Attempts, job.ChannelPoint, job.LastError,
+		job.NextAttemptAt.UnixMicro(), job.CreatedAt.UnixMicro(), job.UpdatedAt.UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("AddJob(%x, %x) error: %w", lspNodeID, job.PeerID, err)
+	}
+
+	return nil
+}
+
+func (s *ChannelOpenJobStore) GetJob(ctx context.Context, lspNodeID []byte, id string) (*openchannel.Job, error) {
+	job, err := scanChannelOpenJob(s.pool.QueryRow(ctx,
+		`SELECT `+channelOpenJobColumns+`
+		 FROM channel_open_jobs
+		 WHERE lsp_nodeid = $1 AND id = $2 AND tenant_id = $3`,
+		lspNodeID, id, tenant.FromContext(ctx),
+	))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetJob(%x, %s) error: %w", lspNodeID, id, err)
+	}
+
+	return job, nil
+}
+
+func (s *ChannelOpenJobStore) ActiveJob(ctx context.Context, lspNodeID []byte, peerID []byte) (*openchannel.Job, error) {
+	job, err := scanChannelOpenJob(s.pool.QueryRow(ctx,
+		`SELECT `+channelOpenJobColumns+`
+		 FROM channel_open_jobs
+		 WHERE lsp_nodeid = $1 AND peer_id = $2 AND tenant_id = $4 AND status <> $3
+		 ORDER BY created_at DESC
+		 LIMIT 1`,
+		lspNodeID, peerID, openchannel.StatusFailed, tenant.FromContext(ctx),
+	))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ActiveJob(%x, %x) error: %w", lspNodeID, peerID, err)
+	}
+
+	return job, nil
+}
+
+func (s *ChannelOpenJobStore) ListDue(ctx context.Context, lspNodeID []byte, now time.Time) ([]*openchannel.Job, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+channelOpenJobColumns+`
+		 FROM channel_open_jobs
+		 WHERE lsp_nodeid = $1 AND tenant_id = $5 AND status IN ($2, $3) AND next_attempt_at <= $4
+		 ORDER BY next_attempt_at`,
+		lspNodeID, openchannel.StatusPending, openchannel.StatusOpening, now.UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListDue(%x) error: %w", lspNodeID, err)
+	}
+	defer rows.Close()
+
+	var jobs []*openchannel.Job
+	for rows.Next() {
+		job, err := scanChannelOpenJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ListDue(%x) error: %w", lspNodeID, err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func (s *ChannelOpenJobStore) UpdateJob(ctx context.Context, job *openchannel.Job) error {
+	_, err := s.pool.Exec(ctx,
+		`UPDATE channel_open_jobs
+		 SET status = $2, attempts = $3, channel_point = NULLIF($4, ''), last_error = NULLIF($5, ''), next_attempt_at = $6, updated_at = $7, commitment_type = NULLIF($8, '')
+		 WHERE id = $1 AND tenant_id = $9`,
+		job.ID, job.Status, job.Attempts, job.ChannelPoint, job.LastError, job.NextAttemptAt.UnixMicro(), job.UpdatedAt.UnixMicro(), string(job.CommitmentType), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("UpdateJob(%s) error: %w", job.ID, err)
+	}
+
+	return nil
+}
+
+func scanChannelOpenJob(row pgx.Row) (*openchannel.Job, error) {
+	var job openchannel.Job
+	var commitmentType, channelPoint, lastError *string
+	var nextAttemptAt, createdAt, updatedAt int64
+	err := row.Scan(&job.ID, &job.PeerID, &commitmentType, &job.Status, &job.Attempts, &channelPoint, &lastError, &nextAttemptAt, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if commitmentType != nil {
+		job.CommitmentType = lightning.CommitmentType(*commitmentType)
+	}
+	if channelPoint != nil {
+		job.ChannelPoint = *channelPoint
+	}
+	if lastError != nil {
+		job.LastError = *lastError
+	}
+	job.NextAttemptAt = time.UnixMicro(nextAttemptAt)
+	job.CreatedAt = time.UnixMicro(createdAt)
+	job.UpdatedAt = time.UnixMicro(updatedAt)
+	return &job, nil
+}
@@ -0,0 +1,270 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/lifecycle"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ChannelStore stores the lifecycle of the channels opened by lspd.
+type ChannelStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewChannelStore(pool *pgxpool.Pool) *ChannelStore {
+	return &ChannelStore{pool: pool}
+}
+
+func (s *ChannelStore) ListChannels(ctx context.Context, lspNodeID []byte, includeClosed bool) ([]*lifecycle.Channel, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT channel_point, nodeid, initial_chanid, confirmed_chanid, opened_at,
+		        last_activity, activity_msat, keep_open, closed_at, close_reason, confirmed_at,
+		        lease_expires_at, lease_notified_at, commitment_type, double_spent_by,
+		        double_spent_at
+		 FROM channels
+		 WHERE lsp_nodeid = $1 AND tenant_id = $3 AND ($2 OR closed_at IS NULL)
+		 ORDER BY opened_at`,
+		lspNodeID, includeClosed, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListChannels(%x) error: %w", lspNodeID, err)
+	}
+	defer rows.Close()
+
+	var channels []*lifecycle.Channel
+	for rows.Next() {
+		var channelPoint string
+		var nodeID []byte
+		var initialChanID int64
+		var confirmedChanID, openedAt, lastActivity, activityMsat, closedAt, confirmedAt *int64
+		var leaseExpiresAt, leaseNotifiedAt, doubleSpentAt *int64
+		var keepOpen bool
+		var closeReason, commitmentType, doubleSpentBy *string
+		err = rows.Scan(
+			&channelPoint,
+			&nodeID,
+			&initialChanID,
+			&confirmedChanID,
+			&openedAt,
+			&lastActivity,
+			&activityMsat,
+			&keepOpen,
+			&closedAt,
+			&closeReason,
+			&confirmedAt,
+			&leaseExpiresAt,
+			&leaseNotifiedAt,
+			&commitmentType,
+			&doubleSpentBy,
+			&doubleSpentAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ListChannels(%x) scan error: %w", lspNodeID, err)
+		}
+
+		initial := basetypes.ShortChannelID(uint64(initialChanID))
+		c := &lifecycle.Channel{
+			ChannelPoint:    channelPoint,
+			PeerID:          hex.EncodeToString(nodeID),
+			InitialChanID:   initial.ToString(),
+			OpenedAt:        fromUnixMicro(openedAt),
+			LastActivity:    fromUnixMicro(lastActivity),
+			KeepOpen:        keepOpen,
+			ClosedAt:        fromUnixMicro(closedAt),
+			ConfirmedAt:     fromUnixMicro(confirmedAt),
+			LeaseExpiresAt:  fromUnixMicro(leaseExpiresAt),
+			LeaseNotifiedAt: fromUnixMicro(leaseNotifiedAt),
+			DoubleSpentAt:   fromUnixMicro(doubleSpentAt),
+		}
+		if confirmedChanID != nil {
+			confirmed := basetypes.ShortChannelID(uint64(*confirmedChanID))
+			c.ConfirmedChanID = confirmed.ToString()
+		}
+		if activityMsat != nil {
+			a := uint64(*activityMsat)
+			c.ActivityMsat = &a
+		}
+		if closeReason != nil {
+			c.CloseReason = *closeReason
+		}
+		if commitmentType != nil {
+			c.CommitmentType = lightning.CommitmentType(*commitmentType)
+		}
+		if doubleSpentBy != nil {
+			c.DoubleSpentBy = *doubleSpentBy
+		}
+
+		channels = append(channels, c)
+	}
+
+	return channels, rows.Err()
+}
+
+func (s *ChannelStore) SetActivity(ctx context.Context, channelPoint string, activityMsat uint64, lastActivity *time.Time) error {
+	var last *int64
+	if lastActivity != nil {
+		l := lastActivity.UnixMicro()
+		last = &l
+	}
+
+	_, err := s.pool.Exec(ctx,
+		`UPDATE channels SET activity_msat = $2, last_activity = $3 WHERE channel_point = $1 AND tenant_id = $4`,
+		channelPoint, int64(activityMsat), last, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("SetActivity(%s) error: %w", channelPoint, err)
+	}
+
+	return nil
+}
+
+func (s *ChannelStore) SetKeepOpen(ctx context.Context, lspNodeID []byte, channelPoint string, keepOpen bool) error {
+	cmdTag, err := s.pool.Exec(ctx,
+		`UPDATE channels SET keep_open = $3 WHERE lsp_nodeid = $1 AND channel_point = $2 AND tenant_id = $4`,
+		lspNodeID, channelPoint, keepOpen, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("SetKeepOpen(%s) error: %w", channelPoint, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return lifecycle.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ChannelStore) MarkClosed(ctx context.Context, channelPoint string, closedAt time.Time, reason string) error {
+	_, err := s.pool.Exec(ctx,
+		`UPDATE channels SET closed_at = $2, close_reason = $3 WHERE channel_point = $1 AND tenant_id = $4`,
+		channelPoint, closedAt.UnixMicro(), reason, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("MarkClosed(%s) error: %w", channelPoint, err)
+	}
+
+	return nil
+}
+
+// SetConfirmed records the confirmed scid of the channel. Returns false if the
+// channel was recorded as confirmed already.
+func (s *ChannelStore) SetConfirmed(ctx context.Context, channelPoint string, confirmedChanID basetypes.ShortChannelID, confirmedAt time.Time) (bool, error) {
+	cmdTag, err := s.pool.Exec(ctx,
+		`UPDATE channels SET confirmed_chanid = $2, confirmed_at = $3
+		 WHERE channel_point = $1 AND tenant_id = $4 AND confirmed_at IS NULL`,
+		channelPoint, int64(confirmedChanID), confirmedAt.UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return false, fmt.Errorf("SetConfirmed(%s) error: %w", channelPoint, err)
+	}
+
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+// SetDoubleSpent records the transaction that double spent the funding of the
+// channel. Returns false if the channel was recorded as double spent already.
+func (s *ChannelStore) SetDoubleSpent(ctx context.Context, channelPoint string, spendingTxid string, doubleSpentAt time.Time) (bool, error) {
+	cmdTag, err := s.pool.Exec(ctx,
+		`UPDATE channels SET double_spent_by = $2, double_spent_at = $3
+		 WHERE channel_point = $1 AND tenant_id = $4 AND double_spent_at IS NULL`,
+		channelPoint, spendingTxid, doubleSpentAt.UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return false, fmt.Errorf("SetDoubleSpent(%s) error: %w", channelPoint, err)
+	}
+
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+// StartLeases starts the lease of the open channels of the node that don't
+// have one yet, as of the time they were opened.
+func (s *ChannelStore) StartLeases(ctx context.Context, lspNodeID []byte, duration time.Duration) (int64, error) {
+	cmdTag, err := s.pool.Exec(ctx,
+		`UPDATE channels SET lease_expires_at = opened_at + $2
+		 WHERE lsp_nodeid = $1 AND tenant_id = $3 AND lease_expires_at IS NULL AND closed_at IS NULL AND opened_at IS NOT NULL`,
+		lspNodeID, duration.Microseconds(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("StartLeases(%x) error: %w", lspNodeID, err)
+	}
+
+	return cmdTag.RowsAffected(), nil
+}
+
+func (s *ChannelStore) SetLeaseNotified(ctx context.Context, channelPoint string, notifiedAt time.Time) error {
+	_, err := s.pool.Exec(ctx,
+		`UPDATE channels SET lease_notified_at = $2 WHERE channel_point = $1 AND tenant_id = $3`,
+		channelPoint, notifiedAt.UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("SetLeaseNotified(%s) error: %w", channelPoint, err)
+	}
+
+	return nil
+}
+
+func (s *ChannelStore) AddLeaseRenewal(ctx context.Context, lspNodeID []byte, paymentHash []byte, channelPoint string, feeMsat uint64) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO lease_renewals (payment_hash, lsp_nodeid, channel_point, fee_msat, created_at, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (payment_hash) DO UPDATE
+		 SET channel_point = EXCLUDED.channel_point, fee_msat = EXCLUDED.fee_msat
+		 WHERE lease_renewals.lsp_nodeid = EXCLUDED.lsp_nodeid AND lease_renewals.tenant_id = EXCLUDED.tenant_id
+		   AND lease_renewals.renewed_at IS NULL`,
+		paymentHash, lspNodeID, channelPoint, int64(feeMsat), time.Now().UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("AddLeaseRenewal(%x) error: %w", paymentHash, err)
+	}
+
+	return nil
+}
+
+// RenewLease extends the lease of the channel of the renewal with the payment
+// hash by the duration, from now or from the current expiry if that is later.
+// Returns nil if there is no such renewal, or it was applied already.
+func (s *ChannelStore) RenewLease(ctx context.Context, lspNodeID []byte, paymentHash []byte, duration time.Duration) (*lifecycle.Lease, error) {
+	var channelPoint string
+	var expiresAt int64
+	err := s.pool.QueryRow(ctx,
+		`WITH r AS (
+		   UPDATE lease_renewals SET renewed_at = $3
+		   WHERE payment_hash = $1 AND lsp_nodeid = $2 AND tenant_id = $5 AND renewed_at IS NULL
+		   RETURNING channel_point
+		 )
+		 UPDATE channels c
+		 SET lease_expires_at = GREATEST(COALESCE(c.lease_expires_at, 0), $3) + $4, lease_notified_at = NULL
+		 FROM r
+		 WHERE c.channel_point = r.channel_point AND c.lsp_nodeid = $2 AND c.tenant_id = $5
+		 RETURNING c.channel_point, c.lease_expires_at`,
+		paymentHash, lspNodeID, time.Now().UnixMicro(), duration.Microseconds(), tenant.FromContext(ctx),
+	).Scan(&channelPoint, &expiresAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("RenewLease(%x) error: %w", paymentHash, err)
+	}
+
+	return &lifecycle.Lease{
+		ChannelPoint: channelPoint,
+		ExpiresAt:    time.UnixMicro(expiresAt).UTC(),
+	}, nil
+}
+
+func fromUnixMicro(t *int64) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	r := time.UnixMicro(*t).UTC()
+	return &r
+}
@@ -0,0 +1,68 @@
+package postgresql_test
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/lifecycle"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/postgresql"
+	"github.com/breez/lspd/postgresql/pgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelLifecycle(t *testing.T) {
+	ctx := context.Background()
+	pool := pgtest.NewDatabase(t)
+	interceptStore := postgresql.NewPostgresInterceptStore(pool, nil)
+	store := postgresql.NewChannelStore(pool)
+	channelPoint := "0505050505050505050505050505050505050505050505050505050505050505:1"
+
+	openedAt := time.Now().Add(-time.Hour)
+	err := interceptStore.InsertChannel(ctx, lspNodeID, 1, 0, channelPoint, destination, lightning.CommitmentTypeTaproot, openedAt)
+	assert.NoError(t, err)
+
+	channels, err := store.ListChannels(ctx, lspNodeID, false)
+	assert.NoError(t, err)
+	if !assert.Len(t, channels, 1) {
+		return
+	}
+	c := channels[0]
+	assert.Equal(t, channelPoint, c.ChannelPoint)
+	assert.Equal(t, hex.EncodeToString(destination), c.PeerID)
+	assert.Equal(t, openedAt.UnixMicro(), c.OpenedAt.UnixMicro())
+	assert.Equal(t, lightning.CommitmentTypeTaproot, c.CommitmentType)
+	assert.Nil(t, c.ActivityMsat)
+	assert.Nil(t, c.LastActivity)
+
+	now := time.Now()
+	err = store.SetActivity(ctx, channelPoint, 1000, &now)
+	assert.NoError(t, err)
+	err = store.SetKeepOpen(ctx, lspNodeID, channelPoint, true)
+	assert.NoError(t, err)
+	err = store.SetKeepOpen(ctx, lspNodeID, "unknown:0", true)
+	assert.ErrorIs(t, err, lifecycle.ErrNotFound)
+
+	channels, err = store.ListChannels(ctx, lspNodeID, false)
+	assert.NoError(t, err)
+	if assert.Len(t, channels, 1) {
+		assert.Equal(t, uint64(1000), *channels[0].ActivityMsat)
+		assert.Equal(t, now.UnixMicro(), channels[0].LastActivity.UnixMicro())
+		assert.True(t, channels[0].KeepOpen)
+	}
+
+	err = store.MarkClosed(ctx, channelPoint, now, "closed by admin")
+	assert.NoError(t, err)
+
+	channels, err = store.ListChannels(ctx, lspNodeID, false)
+	assert.NoError(t, err)
+	assert.Len(t, channels, 0)
+
+	channels, err = store.ListChannels(ctx, lspNodeID, true)
+	assert.NoError(t, err)
+	if assert.Len(t, channels, 1) {
+		assert.Equal(t, "closed by admin", channels[0].CloseReason)
+	}
+}
@@ -0,0 +1,373 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/dbcrypt"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const redacted = "<redacted>"
+
+// PaymentRegistration is the registration of a payment, without the payment
+// secret and with the token redacted.
+type PaymentRegistration struct {
+	LspNodeID          string          `json:"lspNodeId"`
+	Destination        string          `json:"destination"`
+	IncomingAmountMsat int64           `json:"incomingAmountMsat"`
+	OutgoingAmountMsat int64           `json:"outgoingAmountMsat"`
+	Token              string          `json:"token,omitempty"`
+	TokenName          string          `json:"tokenName,omitempty"`
+	OpeningFeeParams   json.RawMessage `json:"openingFeeParams,omitempty"`
+	Tag                json.RawMessage `json:"tag,omitempty"`
+	ChannelPoint       string          `json:"channelPoint,omitempty"`
+}
+
+type HtlcFeeEntry struct {
+	AmountInMsat  int64     `json:"amountInMsat"`
+	AmountOutMsat int64     `json:"amountOutMsat"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// HtlcResolutionEntry is a stored resolution of an htlc, without the
+// resolution itself, which may contain the preimage.
+type HtlcResolutionEntry struct {
+	IncomingScid string    `json:"incomingScid"`
+	HtlcID       int64     `json:"htlcId"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// PreimageStatus tells whether lspd holds the preimage of the payment,
+// without the preimage itself.
+type PreimageStatus struct {
+	CreatedAt time.Time  `json:"createdAt"`
+	ClaimedAt *time.Time `json:"claimedAt,omitempty"`
+}
+
+type ChannelEntry struct {
+	ChannelPoint    string     `json:"channelPoint"`
+	InitialChanID   string     `json:"initialChanId"`
+	ConfirmedChanID string     `json:"confirmedChanId,omitempty"`
+	OpenedAt        *time.Time `json:"openedAt,omitempty"`
+	ClosedAt        *time.Time `json:"closedAt,omitempty"`
+	CloseReason     string     `json:"closeReason,omitempty"`
+}
+
+type PeerStateEntry struct {
+	LspNodeID string    `json:"lspNodeId"`
+	Connected bool      `json:"connected"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Address   string    `json:"address,omitempty"`
+}
+
+// PaymentDebugInfo is everything stored about a payment hash.
+type PaymentDebugInfo struct {
+	Registration    *PaymentRegistration   `json:"registration,omitempty"`
+	HtlcFees        []*HtlcFeeEntry        `json:"htlcFees"`
+	FeeCredit       *FeeCredit             `json:"feeCredit,omitempty"`
+	HtlcResolutions []*HtlcResolutionEntry `json:"htlcResolutions"`
+	Preimage        *PreimageStatus        `json:"preimage,omitempty"`
+	Channel         *ChannelEntry          `json:"channel,omitempty"`
+	PeerStates      []*PeerStateEntry      `json:"peerStates"`
+	AddressHints    []string               `json:"addressHints"`
+}
+
+// DebugStore collects what is stored about a payment for support.
+type DebugStore struct {
+	pool   *pgxpool.Pool
+	cipher *dbcrypt.Cipher
+}
+
+func NewDebugStore(pool *pgxpool.Pool, cipher *dbcrypt.Cipher) *DebugStore {
+	return &DebugStore{pool: pool, cipher: cipher}
+}
+
+// PaymentDebugInfo returns everything stored about the payment hash. Secrets,
+// like the payment secret, the preimage and the token, are not returned.
+func (s *DebugStore) PaymentDebugInfo(ctx context.Context, paymentHash []byte) (*PaymentDebugInfo, error) {
+	info := &PaymentDebugInfo{
+		HtlcFees:        []*HtlcFeeEntry{},
+		HtlcResolutions: []*HtlcResolutionEntry{},
+		PeerStates:      []*PeerStateEntry{},
+		AddressHints:    []string{},
+	}
+
+	tenantID := tenant.FromContext(ctx)
+	var err error
+	info.Registration, err = s.registration(ctx, paymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT amount_in_msat, amount_out_msat, created_at
+		 FROM htlc_fees
+		 WHERE payment_hash = $1 AND tenant_id = $2
+		 ORDER BY created_at`,
+		paymentHash,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("PaymentDebugInfo(%x) htlc fees error: %w", paymentHash, err)
+	}
+	for rows.Next() {
+		var e HtlcFeeEntry
+		var createdAt int64
+		err = rows.Scan(&e.AmountInMsat, &e.AmountOutMsat, &createdAt)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("PaymentDebugInfo(%x) htlc fees scan error: %w", paymentHash, err)
+		}
+		e.CreatedAt = time.UnixMicro(createdAt).UTC()
+		info.HtlcFees = append(info.HtlcFees, &e)
+	}
+	rows.Close()
+
+	var credit FeeCredit
+	var destination []byte
+	var updatedAt int64
+	err = s.pool.QueryRow(ctx,
+		`SELECT destination, promised_fee_msat, deducted_fee_msat, credit_msat, updated_at
+		 FROM fee_credits
+		 WHERE payment_hash = $1 AND tenant_id = $2`,
+		paymentHash,
+		tenantID,
+	).Scan(&destination, &credit.PromisedFeeMsat, &credit.DeductedFeeMsat, &credit.CreditMsat, &updatedAt)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("PaymentDebugInfo(%x) fee credit error: %w", paymentHash, err)
+	}
+	if err == nil {
+		destination, err = decryptBytes(s.cipher, destination)
+		if err != nil {
+			return nil, fmt.Errorf("PaymentDebugInfo(%x) fee credit error: %w", paymentHash, err)
+		}
+
+		credit.PaymentHash = hex.EncodeToString(paymentHash)
+		credit.Destination = hex.EncodeToString(destination)
+		credit.UpdatedAt = time.UnixMicro(updatedAt).UTC()
+		info.FeeCredit = &credit
+	}
+
+	rows, err = s.pool.Query(ctx,
+		`SELECT incoming_scid, htlc_id, created_at
+		 FROM htlc_resolutions
+		 WHERE payment_hash = $1 AND tenant_id = $2
+		 ORDER BY created_at`,
+		paymentHash,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("PaymentDebugInfo(%x) htlc resolutions error: %w", paymentHash, err)
+	}
+	for rows.Next() {
+		var e HtlcResolutionEntry
+		var scid, createdAt int64
+		err = rows.Scan(&scid, &e.HtlcID, &createdAt)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("PaymentDebugInfo(%x) htlc resolutions scan error: %w", paymentHash, err)
+		}
+		id := basetypes.ShortChannelID(uint64(scid))
+		e.IncomingScid = id.ToString()
+		e.CreatedAt = time.UnixMicro(createdAt).UTC()
+		info.HtlcResolutions = append(info.HtlcResolutions, &e)
+	}
+	rows.Close()
+
+	var createdAt int64
+	var claimedAt *int64
+	err = s.pool.QueryRow(ctx,
+		`SELECT created_at, claimed_at
+		 FROM payment_preimages
+		 WHERE payment_hash = $1 AND tenant_id = $2`,
+		paymentHash,
+		tenantID,
+	).Scan(&createdAt, &claimedAt)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("PaymentDebugInfo(%x) preimage error: %w", paymentHash, err)
+	}
+	if err == nil {
+		info.Preimage = &PreimageStatus{
+			CreatedAt: time.UnixMicro(createdAt).UTC(),
+			ClaimedAt: fromUnixMicro(claimedAt),
+		}
+	}
+
+	r := info.Registration
+	if r == nil {
+		return info, nil
+	}
+
+	if r.ChannelPoint != "" {
+		info.Channel, err = s.channel(ctx, r.ChannelPoint)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	destination, _ = hex.DecodeString(r.Destination)
+	rows, err = s.pool.Query(ctx,
+		`SELECT lsp_nodeid, connected, updated_at, address
+		 FROM peer_states
+		 WHERE peer_id = $1 AND tenant_id = $2`,
+		destination,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("PaymentDebugInfo(%x) peer states error: %w", paymentHash, err)
+	}
+	for rows.Next() {
+		var e PeerStateEntry
+		var lspNodeID []byte
+		var address *string
+		err = rows.Scan(&lspNodeID, &e.Connected, &updatedAt, &address)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("PaymentDebugInfo(%x) peer states scan error: %w", paymentHash, err)
+		}
+		e.LspNodeID = hex.EncodeToString(lspNodeID)
+		e.UpdatedAt = time.UnixMicro(updatedAt).UTC()
+		if address != nil {
+			e.Address = *address
+		}
+		info.PeerStates = append(info.PeerStates, &e)
+	}
+	rows.Close()
+
+	rows, err = s.pool.Query(ctx,
+		`SELECT address
+		 FROM peer_address_hints
+		 WHERE peer_id = $1 AND tenant_id = $2
+		 ORDER BY updated_at DESC`,
+		destination,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("PaymentDebugInfo(%x) address hints error: %w", paymentHash, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var address string
+		err = rows.Scan(&address)
+		if err != nil {
+			return nil, fmt.Errorf("PaymentDebugInfo(%x) address hints scan error: %w", paymentHash, err)
+		}
+		info.AddressHints = append(info.AddressHints, address)
+	}
+
+	return info, rows.Err()
+}
+
+func (s *DebugStore) registration(ctx context.Context, paymentHash []byte) (*PaymentRegistration, error) {
+	var (
+		lspNodeID, destination, fundingTxID []byte
+		params, tag                         *string
+		fundingTxOutnum                     pgtype.Int4
+		tokenName                           *string
+	)
+	r := &PaymentRegistration{}
+	err := s.pool.QueryRow(ctx,
+		`SELECT p.lsp_nodeid, p.destination, p.incoming_amount_msat, p.outgoing_amount_msat,
+		        p.opening_fee_params, p.tag, p.funding_tx_id, p.funding_tx_outnum, t.name
+		 FROM payments p
+		 LEFT JOIN api_tokens t ON t.token = p.opening_fee_params->>'token' AND t.tenant_id = p.tenant_id
+		 WHERE p.payment_hash = $1 AND p.tenant_id = $2`,
+		paymentHash,
+		tenant.FromContext(ctx),
+	).Scan(&lspNodeID, &destination, &r.IncomingAmountMsat, &r.OutgoingAmountMsat,
+		&params, &tag, &fundingTxID, &fundingTxOutnum, &tokenName)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("PaymentDebugInfo(%x) registration error: %w", paymentHash, err)
+	}
+
+	destination, err = decryptBytes(s.cipher, destination)
+	if err != nil {
+		return nil, fmt.Errorf("PaymentDebugInfo(%x) registration error: %w", paymentHash, err)
+	}
+
+	r.LspNodeID = hex.EncodeToString(lspNodeID)
+	r.Destination = hex.EncodeToString(destination)
+	if tokenName != nil {
+		r.TokenName = *tokenName
+	}
+	if tag != nil {
+		r.Tag = json.RawMessage(*tag)
+	}
+	if params != nil && *params != "" {
+		var extParams extendedParams
+		err = json.Unmarshal([]byte(*params), &extParams)
+		if err == nil {
+			token, _ := decryptString(s.cipher, extParams.Token)
+			r.Token = redactToken(token)
+			r.OpeningFeeParams, _ = json.Marshal(&extParams.Params)
+		}
+	}
+	if fundingTxID != nil {
+		cp, err := basetypes.NewOutPoint(fundingTxID, uint32(fundingTxOutnum.Int))
+		if err == nil {
+			r.ChannelPoint = cp.String()
+		}
+	}
+
+	return r, nil
+}
+
+func (s *DebugStore) channel(ctx context.Context, channelPoint string) (*ChannelEntry, error) {
+	var initialChanID int64
+	var confirmedChanID, openedAt, closedAt *int64
+	var closeReason *string
+	err := s.pool.QueryRow(ctx,
+		`SELECT initial_chanid, confirmed_chanid, opened_at, closed_at, close_reason
+		 FROM channels
+		 WHERE channel_point = $1 AND tenant_id = $2`,
+		channelPoint,
+		tenant.FromContext(ctx),
+	).Scan(&initialChanID, &confirmedChanID, &openedAt, &closedAt, &closeReason)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("PaymentDebugInfo channel(%s) error: %w", channelPoint, err)
+	}
+
+	initial := basetypes.ShortChannelID(uint64(initialChanID))
+	c := &ChannelEntry{
+		ChannelPoint:  channelPoint,
+		InitialChanID: initial.ToString(),
+		OpenedAt:      fromUnixMicro(openedAt),
+		ClosedAt:      fromUnixMicro(closedAt),
+	}
+	if confirmedChanID != nil {
+		confirmed := basetypes.ShortChannelID(uint64(*confirmedChanID))
+		c.ConfirmedChanID = confirmed.ToString()
+	}
+	if closeReason != nil {
+		c.CloseReason = *closeReason
+	}
+
+	return c, nil
+}
+
+// redactToken keeps only the first characters of a token, enough to tell
+// tokens apart.
+func redactToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	if len(token) <= 8 {
+		return redacted
+	}
+
+	return token[:4] + redacted
+}
@@ -0,0 +1,201 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/breez/lspd/dbcrypt"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// decryptBytes decrypts a value of an encrypted bytea column.
+func decryptBytes(c *dbcrypt.Cipher, value []byte) ([]byte, error) {
+	plaintext, err := c.Decrypt(value)
+	if err != nil {
+		return nil, fmt.Errorf("Decrypt() error: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// decryptString decrypts a value of an encrypted text column.
+func decryptString(c *dbcrypt.Cipher, value string) (string, error) {
+	plaintext, err := c.DecryptString(value)
+	if err != nil {
+		return "", fmt.Errorf("DecryptString() error: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// encryptedColumn is a column holding encrypted values.
+type encryptedColumn struct {
+	name string
+	text bool
+
+	// values selects the distinct values of the column.
+	values string
+
+	// move changes the rows with the value $2 to the value $1.
+	move []string
+}
+
+func simpleColumn(table, column string, text bool) *encryptedColumn {
+	return &encryptedColumn{
+		name:   table + "." + column,
+		text:   text,
+		values: fmt.Sprintf(`SELECT DISTINCT %s FROM %s`, column, table),
+		move:   []string{fmt.Sprintf(`UPDATE %s SET %s = $1 WHERE %s = $2`, table, column, column)},
+	}
+}
+
+var encryptedColumns = []*encryptedColumn{
+	simpleColumn("payments", "destination", false),
+	simpleColumn("payment_preimages", "destination", false),
+	simpleColumn("payment_preimages", "preimage", false),
+	simpleColumn("htlc_resolutions", "resolution", false),
+	simpleColumn("htlc_fees", "destination", false),
+	simpleColumn("fee_credits", "destination", false),
+	simpleColumn("refunds", "destination", false),
+	{
+		// The destination is part of the primary key, nonces that are
+		// stored in both forms are kept once.
+		name:   "payment_nonces.destination",
+		values: `SELECT DISTINCT destination FROM payment_nonces`,
+		move: []string{
+			`UPDATE payment_nonces n SET destination = $1
+			 WHERE destination = $2 AND NOT EXISTS (
+			   SELECT 1 FROM payment_nonces WHERE tenant_id = n.tenant_id AND destination = $1 AND nonce = n.nonce)`,
+			`DELETE FROM payment_nonces WHERE destination = $2`,
+		},
+	},
+	simpleColumn("api_tokens", "token", true),
+	simpleColumn("new_channel_params", "token", true),
+	simpleColumn("macaroons", "token", true),
+	{
+		name:   "payments.opening_fee_params.token",
+		text:   true,
+		values: `SELECT DISTINCT opening_fee_params->>'token' FROM payments WHERE opening_fee_params->>'token' IS NOT NULL`,
+		move: []string{
+			`UPDATE payments SET opening_fee_params = jsonb_set(opening_fee_params, '{token}', to_jsonb($1::text))
+			 WHERE opening_fee_params->>'token' = $2`,
+		},
+	},
+	{
+		// The usage of a token stored in both forms on the same day is
+		// added up.
+		name:   "token_quota_usage.token_key",
+		text:   true,
+		values: `SELECT DISTINCT token_key FROM token_quota_usage`,
+		move: []string{
+			`INSERT INTO token_quota_usage (lsp_nodeid, token_key, day, opens, capacity_sat, tenant_id)
+			 SELECT lsp_nodeid, $1::varchar, day, opens, capacity_sat, tenant_id FROM token_quota_usage WHERE token_key = $2
+			 ON CONFLICT (lsp_nodeid, token_key, day) DO UPDATE
+			 SET opens = token_quota_usage.opens + EXCLUDED.opens,
+			     capacity_sat = token_quota_usage.capacity_sat + EXCLUDED.capacity_sat`,
+			`DELETE FROM token_quota_usage WHERE token_key = $2`,
+		},
+	},
+}
+
+// Reencrypt encrypts the values of all encrypted columns with the current
+// key of the cipher, including values stored before encryption was enabled
+// and values encrypted with old keys. With decrypt, the values are stored
+// unencrypted instead, to disable encryption. Every value is moved in its
+// own transaction, so Reencrypt can be run again after a failure.
+func Reencrypt(ctx context.Context, pool *pgxpool.Pool, cipher *dbcrypt.Cipher, decrypt bool) error {
+	target := cipher
+	if decrypt {
+		target = nil
+	}
+
+	for _, column := range encryptedColumns {
+		moved, err := reencryptColumn(ctx, pool, cipher, target, column)
+		if err != nil {
+			return fmt.Errorf("reencrypt %s error: %w", column.name, err)
+		}
+
+		log.Printf("reencrypt: %s: %d values reencrypted", column.name, moved)
+	}
+
+	return nil
+}
+
+func reencryptColumn(ctx context.Context, pool *pgxpool.Pool, cipher, target *dbcrypt.Cipher, column *encryptedColumn) (int, error) {
+	var values []interface{}
+	rows, err := pool.Query(ctx, column.values)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		if column.text {
+			var v string
+			err = rows.Scan(&v)
+			values = append(values, v)
+		} else {
+			var v []byte
+			err = rows.Scan(&v)
+			values = append(values, v)
+		}
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, old := range values {
+		var value interface{}
+		if column.text {
+			plaintext, err := cipher.DecryptString(old.(string))
+			if err != nil {
+				return moved, err
+			}
+			if v := target.EncryptString(plaintext); v != old.(string) {
+				value = v
+			}
+		} else {
+			plaintext, err := cipher.Decrypt(old.([]byte))
+			if err != nil {
+				return moved, err
+			}
+			if v := target.Encrypt(plaintext); string(v) != string(old.([]byte)) {
+				value = v
+			}
+		}
+		if value == nil {
+			continue
+		}
+
+		err = moveValue(ctx, pool, column, value, old)
+		if err != nil {
+			return moved, err
+		}
+
+		moved++
+	}
+
+	return moved, nil
+}
+
+func moveValue(ctx context.Context, pool *pgxpool.Pool, column *encryptedColumn, value interface{}, old interface{}) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("pgxPool.Begin() error: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, query := range column.move {
+		_, err = tx.Exec(ctx, query, value, old)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
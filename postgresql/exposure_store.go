@@ -0,0 +1,123 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/exposure"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ExposureStore stores the htlcs forwarded to clients until they are
+// resolved.
+type ExposureStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewExposureStore(pool *pgxpool.Pool) *ExposureStore {
+	return &ExposureStore{pool: pool}
+}
+
+func (s *ExposureStore) AddHtlc(ctx context.Context, lspNodeID []byte, htlc *exposure.Htlc) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO htlc_exposure (lsp_nodeid, peer_id, channel_point, payment_hash, amount_msat, created_at, zero_conf, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING id`,
+		lspNodeID, htlc.PeerID, htlc.ChannelPoint, htlc.PaymentHash, int64(htlc.AmountMsat), htlc.CreatedAt.UnixMicro(), htlc.ZeroConf, tenant.FromContext(ctx),
+	).Scan(&htlc.ID)
+	if err != nil {
+		return fmt.Errorf("AddHtlc(%x, %x) error: %w", lspNodeID, htlc.PaymentHash, err)
+	}
+
+	return nil
+}
+
+func (s *ExposureStore) ClientExposure(ctx context.Context, lspNodeID []byte, peerID []byte) (uint64, error) {
+	var total int64
+	err := s.pool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount_msat), 0)::bigint
+		 FROM htlc_exposure
+		 WHERE lsp_nodeid = $1 AND peer_id = $2 AND tenant_id = $3 AND resolved_at IS NULL`,
+		lspNodeID, peerID, tenant.FromContext(ctx),
+	).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("ClientExposure(%x, %x) error: %w", lspNodeID, peerID, err)
+	}
+
+	return uint64(total), nil
+}
+
+func (s *ExposureStore) ListUnresolved(ctx context.Context, lspNodeID []byte) ([]*exposure.Htlc, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, peer_id, channel_point, payment_hash, amount_msat, created_at, zero_conf
+		 FROM htlc_exposure
+		 WHERE lsp_nodeid = $1 AND tenant_id = $2 AND resolved_at IS NULL
+		 ORDER BY created_at, id`,
+		lspNodeID, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListUnresolved(%x) error: %w", lspNodeID, err)
+	}
+	defer rows.Close()
+
+	var htlcs []*exposure.Htlc
+	for rows.Next() {
+		var h exposure.Htlc
+		var amountMsat, createdAt int64
+		err = rows.Scan(&h.ID, &h.PeerID, &h.ChannelPoint, &h.PaymentHash, &amountMsat, &createdAt, &h.ZeroConf)
+		if err != nil {
+			return nil, fmt.Errorf("ListUnresolved(%x) error: %w", lspNodeID, err)
+		}
+
+		h.AmountMsat = uint64(amountMsat)
+		h.CreatedAt = time.UnixMicro(createdAt)
+		htlcs = append(htlcs, &h)
+	}
+
+	return htlcs, rows.Err()
+}
+
+func (s *ExposureStore) Resolve(ctx context.Context, id int64, resolvedAt time.Time) error {
+	_, err := s.pool.Exec(ctx,
+		`UPDATE htlc_exposure
+		 SET resolved_at = $2
+		 WHERE id = $1 AND tenant_id = $3 AND resolved_at IS NULL`,
+		id, resolvedAt.UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("Resolve(%d) error: %w", id, err)
+	}
+
+	return nil
+}
+
+func (s *ExposureStore) ZeroConfExposure(ctx context.Context, lspNodeID []byte, peerID []byte) (uint64, error) {
+	var total int64
+	err := s.pool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount_msat), 0)::bigint
+		 FROM htlc_exposure
+		 WHERE lsp_nodeid = $1 AND tenant_id = $3 AND ($2::bytea IS NULL OR peer_id = $2) AND resolved_at IS NULL AND zero_conf`,
+		lspNodeID, peerID, tenant.FromContext(ctx),
+	).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("ZeroConfExposure(%x, %x) error: %w", lspNodeID, peerID, err)
+	}
+
+	return uint64(total), nil
+}
+
+func (s *ExposureStore) SetConfirmed(ctx context.Context, lspNodeID []byte, channelPoint string) error {
+	_, err := s.pool.Exec(ctx,
+		`UPDATE htlc_exposure
+		 SET zero_conf = false
+		 WHERE lsp_nodeid = $1 AND channel_point = $2 AND tenant_id = $3 AND zero_conf`,
+		lspNodeID, channelPoint, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("SetConfirmed(%x, %s) error: %w", lspNodeID, channelPoint, err)
+	}
+
+	return nil
+}
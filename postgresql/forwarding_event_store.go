@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"github.com/breez/lspd/lnd"
+	"github.com/breez/lspd/tenant"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -18,25 +19,26 @@ func NewForwardingEventStore(pool *pgxpool.Pool) *ForwardingEventStore {
 	return &ForwardingEventStore{pool: pool}
 }
 
-func (s *ForwardingEventStore) LastForwardingEvent() (int64, error) {
+func (s *ForwardingEventStore) LastForwardingEvent(ctx context.Context, lspNodeID []byte) (int64, error) {
 	var last int64
-	err := s.pool.QueryRow(context.Background(),
-		`SELECT coalesce(MAX("timestamp"), 0) AS last FROM forwarding_history`).Scan(&last)
+	err := s.pool.QueryRow(ctx,
+		`SELECT coalesce(MAX("timestamp"), 0) AS last FROM forwarding_history WHERE lsp_nodeid=$1 AND tenant_id=$2`,
+		lspNodeID, tenant.FromContext(ctx)).Scan(&last)
 	if err != nil {
 		return 0, err
 	}
 	return last, nil
 }
 
-func (s *ForwardingEventStore) InsertForwardingEvents(rowSrc lnd.CopyFromSource) error {
+func (s *ForwardingEventStore) InsertForwardingEvents(ctx context.Context, lspNodeID []byte, rowSrc lnd.CopyFromSource) error {
 
-	tx, err := s.pool.Begin(context.Background())
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("pgxPool.Begin() error: %w", err)
 	}
-	defer tx.Rollback(context.Background())
+	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(context.Background(), `
+	_, err = tx.Exec(ctx, `
 	CREATE TEMP TABLE tmp_table ON COMMIT DROP AS
 		SELECT *
 		FROM forwarding_history
@@ -46,7 +48,7 @@ func (s *ForwardingEventStore) InsertForwardingEvents(rowSrc lnd.CopyFromSource)
 		return fmt.Errorf("CREATE TEMP TABLE error: %w", err)
 	}
 
-	count, err := tx.CopyFrom(context.Background(),
+	count, err := tx.CopyFrom(ctx,
 		pgx.Identifier{"tmp_table"},
 		[]string{"timestamp", "chanid_in", "chanid_out", "amt_msat_in", "amt_msat_out"}, rowSrc)
 	if err != nil {
@@ -54,7 +56,12 @@ func (s *ForwardingEventStore) InsertForwardingEvents(rowSrc lnd.CopyFromSource)
 	}
 	log.Printf("count1: %v", count)
 
-	cmdTag, err := tx.Exec(context.Background(), `
+	_, err = tx.Exec(ctx, `UPDATE tmp_table SET lsp_nodeid=$1, tenant_id=$2`, lspNodeID, tenant.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("UPDATE tmp_table error: %w", err)
+	}
+
+	cmdTag, err := tx.Exec(ctx, `
 	INSERT INTO forwarding_history
 		SELECT *
 		FROM tmp_table
@@ -64,5 +71,5 @@ func (s *ForwardingEventStore) InsertForwardingEvents(rowSrc lnd.CopyFromSource)
 		return fmt.Errorf("INSERT INTO forwarding_history error: %w", err)
 	}
 	log.Printf("count2: %v", cmdTag.RowsAffected())
-	return tx.Commit(context.Background())
+	return tx.Commit(ctx)
 }
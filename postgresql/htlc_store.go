@@ -0,0 +1,79 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/dbcrypt"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// HtlcStore stores the resolutions of intercepted htlcs, so htlcs replayed by
+// the node get the same resolution. The resolutions hold the onion payloads
+// and preimages, they are encrypted like the other sensitive columns.
+type HtlcStore struct {
+	pool   *pgxpool.Pool
+	cipher *dbcrypt.Cipher
+}
+
+func NewHtlcStore(pool *pgxpool.Pool, cipher *dbcrypt.Cipher) *HtlcStore {
+	return &HtlcStore{pool: pool, cipher: cipher}
+}
+
+// GetHtlcResolution returns the stored resolution of the htlc, or nil if the
+// htlc wasn't resolved before.
+func (s *HtlcStore) GetHtlcResolution(ctx context.Context, lspNodeID, paymentHash []byte, incomingScid, htlcID uint64) ([]byte, error) {
+	var resolution []byte
+	err := s.pool.QueryRow(ctx,
+		`SELECT resolution
+		 FROM htlc_resolutions
+		 WHERE lsp_nodeid = $1 AND payment_hash = $2 AND incoming_scid = $3 AND htlc_id = $4 AND tenant_id = $5`,
+		lspNodeID, paymentHash, int64(incomingScid), int64(htlcID), tenant.FromContext(ctx),
+	).Scan(&resolution)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetHtlcResolution(%x, %d) error: %w", paymentHash, htlcID, err)
+	}
+
+	resolution, err = decryptBytes(s.cipher, resolution)
+	if err != nil {
+		return nil, fmt.Errorf("GetHtlcResolution(%x, %d) error: %w", paymentHash, htlcID, err)
+	}
+
+	return resolution, nil
+}
+
+// SaveHtlcResolution stores the resolution of the htlc. The first stored
+// resolution is kept.
+func (s *HtlcStore) SaveHtlcResolution(ctx context.Context, lspNodeID, paymentHash []byte, incomingScid, htlcID uint64, resolution []byte) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO htlc_resolutions (lsp_nodeid, payment_hash, incoming_scid, htlc_id, resolution, created_at, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT DO NOTHING`,
+		lspNodeID, paymentHash, int64(incomingScid), int64(htlcID), s.cipher.Encrypt(resolution), time.Now().UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("SaveHtlcResolution(%x, %d) error: %w", paymentHash, htlcID, err)
+	}
+
+	return nil
+}
+
+// DeleteHtlcResolutions removes the resolutions of the tenant stored before
+// the given time.
+func (s *HtlcStore) DeleteHtlcResolutions(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx,
+		`DELETE FROM htlc_resolutions WHERE created_at < $1 AND tenant_id = $2`,
+		before.UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("DeleteHtlcResolutions(%v) error: %w", before, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
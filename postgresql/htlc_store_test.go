@@ -0,0 +1,41 @@
+package postgresql_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/postgresql"
+	"github.com/breez/lspd/postgresql/pgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHtlcResolutions(t *testing.T) {
+	ctx := context.Background()
+	store := postgresql.NewHtlcStore(pgtest.NewDatabase(t), nil)
+	paymentHash := bytes.Repeat([]byte{0x01}, 32)
+
+	resolution, err := store.GetHtlcResolution(ctx, lspNodeID, paymentHash, 123, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, resolution)
+
+	err = store.SaveHtlcResolution(ctx, lspNodeID, paymentHash, 123, 1, []byte{0x0a})
+	assert.NoError(t, err)
+
+	// The first resolution is kept.
+	err = store.SaveHtlcResolution(ctx, lspNodeID, paymentHash, 123, 1, []byte{0x0b})
+	assert.NoError(t, err)
+	resolution, err = store.GetHtlcResolution(ctx, lspNodeID, paymentHash, 123, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x0a}, resolution)
+
+	// Another htlc of the same payment is resolved separately.
+	resolution, err = store.GetHtlcResolution(ctx, lspNodeID, paymentHash, 123, 2)
+	assert.NoError(t, err)
+	assert.Nil(t, resolution)
+
+	deleted, err := store.DeleteHtlcResolutions(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
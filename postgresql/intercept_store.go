@@ -3,12 +3,17 @@ package postgresql
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/big"
 	"time"
 
 	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/dbcrypt"
 	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/tenant"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
@@ -21,14 +26,25 @@ type extendedParams struct {
 }
 
 type PostgresInterceptStore struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	cipher *dbcrypt.Cipher
 }
 
-func NewPostgresInterceptStore(pool *pgxpool.Pool) *PostgresInterceptStore {
-	return &PostgresInterceptStore{pool: pool}
+func NewPostgresInterceptStore(pool *pgxpool.Pool, cipher *dbcrypt.Cipher) *PostgresInterceptStore {
+	return &PostgresInterceptStore{pool: pool, cipher: cipher}
 }
 
-func (s *PostgresInterceptStore) PaymentInfo(htlcPaymentHash []byte) (string, *interceptor.OpeningFeeParams, []byte, []byte, []byte, int64, int64, *wire.OutPoint, *string, error) {
+func (s *PostgresInterceptStore) PaymentInfo(ctx context.Context, htlcPaymentHash []byte) (string, *interceptor.OpeningFeeParams, []byte, []byte, []byte, int64, int64, *wire.OutPoint, *string, error) {
+	return s.paymentInfo(ctx, `(payment_hash=$1 OR sha256('probing-01:' || payment_hash)=$1)`, htlcPaymentHash)
+}
+
+// PaymentInfoByScid returns the registration the fake scid was assigned to,
+// like PaymentInfo.
+func (s *PostgresInterceptStore) PaymentInfoByScid(ctx context.Context, fakeScid uint64) (string, *interceptor.OpeningFeeParams, []byte, []byte, []byte, int64, int64, *wire.OutPoint, *string, error) {
+	return s.paymentInfo(ctx, `fake_scid=$1`, int64(fakeScid))
+}
+
+func (s *PostgresInterceptStore) paymentInfo(ctx context.Context, where string, arg interface{}) (string, *interceptor.OpeningFeeParams, []byte, []byte, []byte, int64, int64, *wire.OutPoint, *string, error) {
 	var (
 		p, tag                                  *string
 		paymentHash, paymentSecret, destination []byte
@@ -36,11 +52,11 @@ func (s *PostgresInterceptStore) PaymentInfo(htlcPaymentHash []byte) (string, *i
 		fundingTxID                             []byte
 		fundingTxOutnum                         pgtype.Int4
 	)
-	err := s.pool.QueryRow(context.Background(),
+	err := s.pool.QueryRow(ctx,
 		`SELECT payment_hash, payment_secret, destination, incoming_amount_msat, outgoing_amount_msat, funding_tx_id, funding_tx_outnum, opening_fee_params, tag
 			FROM payments
-			WHERE payment_hash=$1 OR sha256('probing-01:' || payment_hash)=$1`,
-		htlcPaymentHash).Scan(&paymentHash, &paymentSecret, &destination, &incomingAmountMsat, &outgoingAmountMsat, &fundingTxID, &fundingTxOutnum, &p, &tag)
+			WHERE tenant_id=$2 AND `+where,
+		arg, tenant.FromContext(ctx)).Scan(&paymentHash, &paymentSecret, &destination, &incomingAmountMsat, &outgoingAmountMsat, &fundingTxID, &fundingTxOutnum, &p, &tag)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			err = nil
@@ -64,41 +80,73 @@ func (s *PostgresInterceptStore) PaymentInfo(htlcPaymentHash []byte) (string, *i
 			return "", nil, nil, nil, nil, 0, 0, nil, nil, err
 		}
 	}
+
+	destination, err = decryptBytes(s.cipher, destination)
+	if err != nil {
+		return "", nil, nil, nil, nil, 0, 0, nil, nil, err
+	}
+	extParams.Token, err = decryptString(s.cipher, extParams.Token)
+	if err != nil {
+		return "", nil, nil, nil, nil, 0, 0, nil, nil, err
+	}
+
 	return extParams.Token, &extParams.Params, paymentHash, paymentSecret, destination, incomingAmountMsat, outgoingAmountMsat, cp, tag, nil
 }
 
-func (s *PostgresInterceptStore) SetFundingTx(paymentHash []byte, channelPoint *wire.OutPoint) error {
-	commandTag, err := s.pool.Exec(context.Background(),
+func (s *PostgresInterceptStore) SetFundingTx(ctx context.Context, paymentHash []byte, channelPoint *wire.OutPoint) error {
+	commandTag, err := s.pool.Exec(ctx,
 		`UPDATE payments
 			SET funding_tx_id = $2, funding_tx_outnum = $3
-			WHERE payment_hash=$1`,
-		paymentHash, channelPoint.Hash[:], channelPoint.Index)
+			WHERE payment_hash=$1 AND tenant_id=$4`,
+		paymentHash, channelPoint.Hash[:], channelPoint.Index, tenant.FromContext(ctx))
 	log.Printf("setFundingTx(%x, %s, %d): %s err: %v", paymentHash, channelPoint.Hash.String(), channelPoint.Index, commandTag, err)
 	return err
 }
 
-func (s *PostgresInterceptStore) RegisterPayment(token string, params *interceptor.OpeningFeeParams, destination, paymentHash, paymentSecret []byte, incomingAmountMsat, outgoingAmountMsat int64, tag string) error {
+func (s *PostgresInterceptStore) RegisterPayment(ctx context.Context, token string, lspNodeID []byte, params *interceptor.OpeningFeeParams, destination, paymentHash, paymentSecret []byte, incomingAmountMsat, outgoingAmountMsat int64, tag string, priority string, commitmentType lightning.CommitmentType) error {
 	var t *string
 	if tag != "" {
 		t = &tag
 	}
 
+	var pr *string
+	if priority != "" {
+		pr = &priority
+	}
+
+	var ct *string
+	if commitmentType != "" {
+		c := string(commitmentType)
+		ct = &c
+	}
+
 	p := []byte{}
 	if params != nil {
 		var err error
-		p, err = json.Marshal(extendedParams{Token: token, Params: *params})
+		p, err = json.Marshal(extendedParams{Token: s.cipher.EncryptString(token), Params: *params})
 		if err != nil {
 			log.Printf("Failed to marshal OpeningFeeParams: %v", err)
 			return err
 		}
 	}
 
-	commandTag, err := s.pool.Exec(context.Background(),
+	commandTag, err := s.pool.Exec(ctx,
 		`INSERT INTO
-		payments (destination, payment_hash, payment_secret, incoming_amount_msat, outgoing_amount_msat, tag, opening_fee_params)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		ON CONFLICT DO NOTHING`,
-		destination, paymentHash, paymentSecret, incomingAmountMsat, outgoingAmountMsat, t, p)
+		payments (destination, payment_hash, payment_secret, incoming_amount_msat, outgoing_amount_msat, tag, opening_fee_params, lsp_nodeid, priority, commitment_type, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $11, $12)
+		ON CONFLICT (payment_hash) DO UPDATE SET
+			payment_secret = EXCLUDED.payment_secret,
+			incoming_amount_msat = EXCLUDED.incoming_amount_msat,
+			outgoing_amount_msat = EXCLUDED.outgoing_amount_msat,
+			tag = EXCLUDED.tag,
+			opening_fee_params = EXCLUDED.opening_fee_params,
+			lsp_nodeid = EXCLUDED.lsp_nodeid,
+			priority = EXCLUDED.priority,
+			commitment_type = EXCLUDED.commitment_type
+		WHERE payments.destination = ANY($10)
+			AND payments.tenant_id = EXCLUDED.tenant_id
+			AND payments.funding_tx_id IS NULL`,
+		s.cipher.Encrypt(destination), paymentHash, paymentSecret, incomingAmountMsat, outgoingAmountMsat, t, p, lspNodeID, pr, s.cipher.Candidates(destination), ct, tenant.FromContext(ctx))
 	log.Printf("registerPayment(%x, %x, %x, %v, %v, %v, %s) rows: %v err: %v",
 		destination, paymentHash, paymentSecret, incomingAmountMsat, outgoingAmountMsat, tag, p, commandTag.RowsAffected(), err)
 	if err != nil {
@@ -108,15 +156,233 @@ func (s *PostgresInterceptStore) RegisterPayment(token string, params *intercept
 	return nil
 }
 
-func (s *PostgresInterceptStore) InsertChannel(initialChanID, confirmedChanId uint64, channelPoint string, nodeID []byte, lastUpdate time.Time) error {
+// AssignFakeScid assigns the fake scid to the registered payment, unless it
+// has one already. It returns the fake scid of the payment, or 0 if the fake
+// scid is assigned to another payment, also when another payment got it
+// concurrently.
+func (s *PostgresInterceptStore) AssignFakeScid(ctx context.Context, paymentHash []byte, fakeScid uint64) (uint64, error) {
+	var assigned int64
+	err := s.pool.QueryRow(ctx,
+		`WITH assigned AS (
+			UPDATE payments SET fake_scid = $2
+			WHERE payment_hash = $1 AND tenant_id = $3 AND fake_scid IS NULL
+				AND NOT EXISTS (SELECT 1 FROM payments WHERE fake_scid = $2)
+			RETURNING fake_scid
+		)
+		SELECT fake_scid FROM assigned
+		UNION ALL
+		SELECT fake_scid FROM payments WHERE payment_hash = $1 AND tenant_id = $3 AND fake_scid IS NOT NULL`,
+		paymentHash, int64(fakeScid), tenant.FromContext(ctx)).Scan(&assigned)
+	if err == pgx.ErrNoRows || isUniqueViolation(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("AssignFakeScid(%x, %d) error: %w", paymentHash, fakeScid, err)
+	}
+
+	return uint64(assigned), nil
+}
+
+// isUniqueViolation returns whether the error is the violation of a unique
+// index.
+func isUniqueViolation(err error) bool {
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == "23505"
+}
+
+// ListFakeScids returns the fake scids assigned to payments registered with
+// the node, with the payment hash they are assigned to. The scids of
+// registrations that expired without a channel being opened aren't returned.
+func (s *PostgresInterceptStore) ListFakeScids(ctx context.Context, lspNodeID []byte) (map[uint64][]byte, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT fake_scid, payment_hash FROM payments
+		 WHERE lsp_nodeid = $1 AND tenant_id = $2 AND fake_scid IS NOT NULL
+		   AND (funding_tx_id IS NOT NULL OR opening_fee_params IS NULL
+		     OR (opening_fee_params->>'valid_until')::timestamptz > $3)`,
+		lspNodeID, tenant.FromContext(ctx), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("ListFakeScids(%x) error: %w", lspNodeID, err)
+	}
+	defer rows.Close()
+
+	scids := make(map[uint64][]byte)
+	for rows.Next() {
+		var scid int64
+		var paymentHash []byte
+		err = rows.Scan(&scid, &paymentHash)
+		if err != nil {
+			return nil, fmt.Errorf("ListFakeScids(%x) scan error: %w", lspNodeID, err)
+		}
+		scids[uint64(scid)] = paymentHash
+	}
+
+	return scids, rows.Err()
+}
+
+// CancelPayment deletes the registration of the payment, unless a channel was
+// already opened for it. If destination is set, only a registration for that
+// destination is deleted. Returns whether the registration was deleted.
+func (s *PostgresInterceptStore) CancelPayment(ctx context.Context, paymentHash []byte, destination []byte) (bool, error) {
+	var destinations [][]byte
+	if destination != nil {
+		destinations = s.cipher.Candidates(destination)
+	}
+
+	commandTag, err := s.pool.Exec(ctx,
+		`DELETE FROM payments
+		 WHERE payment_hash = $1 AND tenant_id = $3 AND funding_tx_id IS NULL
+		   AND ($2::bytea[] IS NULL OR destination = ANY($2))`,
+		paymentHash, destinations, tenant.FromContext(ctx))
+	log.Printf("cancelPayment(%x, %x) rows: %v err: %v", paymentHash, destination, commandTag.RowsAffected(), err)
+	if err != nil {
+		return false, fmt.Errorf("cancelPayment(%x, %x) error: %w", paymentHash, destination, err)
+	}
+
+	return commandTag.RowsAffected() > 0, nil
+}
+
+func (s *PostgresInterceptStore) InsertPreimage(ctx context.Context, lspNodeID, destination, paymentHash, preimage []byte) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO payment_preimages (payment_hash, preimage, destination, lsp_nodeid, created_at, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		paymentHash, s.cipher.Encrypt(preimage), s.cipher.Encrypt(destination), lspNodeID, time.Now().UnixMicro(), tenant.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("insertPreimage(%x, %x) error: %w", destination, paymentHash, err)
+	}
+
+	return nil
+}
+
+// ClaimPreimage returns the preimage generated for the destination, or nil if
+// there is none, and whether a channel was opened for the payment, so the
+// preimage can be released.
+func (s *PostgresInterceptStore) ClaimPreimage(ctx context.Context, paymentHash, destination []byte) ([]byte, bool, error) {
+	var preimage []byte
+	var opened bool
+	err := s.pool.QueryRow(ctx,
+		`SELECT pp.preimage, EXISTS(
+		   SELECT 1 FROM payments p
+		   WHERE p.payment_hash = pp.payment_hash AND p.destination = ANY($2)
+		     AND p.tenant_id = pp.tenant_id AND p.funding_tx_id IS NOT NULL)
+		 FROM payment_preimages pp
+		 WHERE pp.payment_hash = $1 AND pp.destination = ANY($2) AND pp.tenant_id = $3`,
+		paymentHash, s.cipher.Candidates(destination), tenant.FromContext(ctx)).Scan(&preimage, &opened)
+	if err == pgx.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("claimPreimage(%x, %x) error: %w", paymentHash, destination, err)
+	}
+
+	preimage, err = decryptBytes(s.cipher, preimage)
+	if err != nil {
+		return nil, false, fmt.Errorf("claimPreimage(%x, %x) error: %w", paymentHash, destination, err)
+	}
+
+	if !opened {
+		return preimage, false, nil
+	}
+
+	_, err = s.pool.Exec(ctx,
+		`UPDATE payment_preimages SET claimed_at = $2
+		 WHERE payment_hash = $1 AND tenant_id = $3 AND claimed_at IS NULL`,
+		paymentHash, time.Now().UnixMicro(), tenant.FromContext(ctx))
+	if err != nil {
+		return nil, false, fmt.Errorf("claimPreimage(%x, %x) update error: %w", paymentHash, destination, err)
+	}
+
+	return preimage, true, nil
+}
+
+// RecordHtlcFee records the fee deducted from an htlc forwarded for a
+// registered payment. If the fees deducted from the htlcs of the payment so
+// far exceed their share of the promised fee, the excess is stored as a credit
+// for the destination. Returns the credit of the payment.
+func (s *PostgresInterceptStore) RecordHtlcFee(ctx context.Context, lspNodeID, destination, paymentHash []byte, amountIn, amountOut basetypes.MilliSatoshi) (int64, error) {
+	amountInMsat, err := amountIn.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("recordHtlcFee(%x) error: %w", paymentHash, err)
+	}
+	amountOutMsat, err := amountOut.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("recordHtlcFee(%x) error: %w", paymentHash, err)
+	}
+
+	tenantID := tenant.FromContext(ctx)
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("pgxPool.Begin() error: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO htlc_fees (payment_hash, lsp_nodeid, destination, amount_in_msat, amount_out_msat, created_at, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		paymentHash, lspNodeID, s.cipher.Encrypt(destination), amountInMsat, amountOutMsat, time.Now().UnixMicro(), tenantID)
+	if err != nil {
+		return 0, fmt.Errorf("recordHtlcFee(%x) insert error: %w", paymentHash, err)
+	}
+
+	var incomingAmountMsat, outgoingAmountMsat, receivedMsat, deductedMsat int64
+	err = tx.QueryRow(ctx,
+		`SELECT p.incoming_amount_msat, p.outgoing_amount_msat,
+		        sum(h.amount_in_msat)::bigint, sum(h.amount_in_msat - h.amount_out_msat)::bigint
+		 FROM payments p
+		 INNER JOIN htlc_fees h ON h.payment_hash = p.payment_hash AND h.tenant_id = p.tenant_id
+		 WHERE p.payment_hash = $1 AND p.tenant_id = $2
+		 GROUP BY p.incoming_amount_msat, p.outgoing_amount_msat`,
+		paymentHash, tenantID).Scan(&incomingAmountMsat, &outgoingAmountMsat, &receivedMsat, &deductedMsat)
+	if err == pgx.ErrNoRows || (err == nil && incomingAmountMsat <= 0) {
+		// Not a registered payment, like a probe.
+		return 0, tx.Commit(ctx)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("recordHtlcFee(%x) select error: %w", paymentHash, err)
+	}
+
+	// The share of the promised fee of the amount received so far.
+	var promised big.Int
+	promised.Mul(big.NewInt(incomingAmountMsat-outgoingAmountMsat), big.NewInt(receivedMsat))
+	promised.Div(&promised, big.NewInt(incomingAmountMsat))
+	credit := deductedMsat - promised.Int64()
+	if credit > 0 {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO fee_credits (payment_hash, lsp_nodeid, destination, promised_fee_msat, deducted_fee_msat, credit_msat, updated_at, tenant_id)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			 ON CONFLICT (payment_hash) DO UPDATE SET
+			   promised_fee_msat = EXCLUDED.promised_fee_msat,
+			   deducted_fee_msat = EXCLUDED.deducted_fee_msat,
+			   credit_msat = EXCLUDED.credit_msat,
+			   updated_at = EXCLUDED.updated_at
+			 WHERE fee_credits.tenant_id = EXCLUDED.tenant_id`,
+			paymentHash, lspNodeID, s.cipher.Encrypt(destination), promised.Int64(), deductedMsat, credit, time.Now().UnixMicro(), tenantID)
+		if err != nil {
+			return 0, fmt.Errorf("recordHtlcFee(%x) credit error: %w", paymentHash, err)
+		}
+	} else {
+		credit = 0
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("recordHtlcFee(%x) commit error: %w", paymentHash, err)
+	}
+
+	return credit, nil
+}
+
+// InsertChannel stores the channel. An empty commitment type keeps the
+// commitment type stored before.
+func (s *PostgresInterceptStore) InsertChannel(ctx context.Context, lspNodeID []byte, initialChanID, confirmedChanId uint64, channelPoint string, nodeID []byte, commitmentType lightning.CommitmentType, lastUpdate time.Time) error {
 
 	query := `INSERT INTO
-	channels (initial_chanid, confirmed_chanid, channel_point, nodeid, last_update)
-	VALUES ($1, NULLIF($2, 0::int8), $3, $4, $5)
-	ON CONFLICT (channel_point) DO UPDATE SET confirmed_chanid=NULLIF($2, 0::int8), last_update=$5`
+	channels (initial_chanid, confirmed_chanid, channel_point, nodeid, last_update, lsp_nodeid, opened_at, commitment_type, tenant_id)
+	VALUES ($1, NULLIF($2, 0::int8), $3, $4, $5, $6, $7, NULLIF($8, ''), $9)
+	ON CONFLICT (channel_point) DO UPDATE SET confirmed_chanid=NULLIF($2, 0::int8), last_update=$5, lsp_nodeid=$6,
+		commitment_type=COALESCE(NULLIF($8, ''), channels.commitment_type)`
 
-	c, err := s.pool.Exec(context.Background(),
-		query, int64(initialChanID), int64(confirmedChanId), channelPoint, nodeID, lastUpdate)
+	c, err := s.pool.Exec(ctx,
+		query, int64(initialChanID), int64(confirmedChanId), channelPoint, nodeID, lastUpdate, lspNodeID, lastUpdate.UnixMicro(), string(commitmentType), tenant.FromContext(ctx))
 	if err != nil {
 		log.Printf("insertChannel(%v, %v, %s, %x) error: %v",
 			initialChanID, confirmedChanId, channelPoint, nodeID, err)
@@ -128,8 +394,8 @@ func (s *PostgresInterceptStore) InsertChannel(initialChanID, confirmedChanId ui
 	return nil
 }
 
-func (s *PostgresInterceptStore) GetFeeParamsSettings(token string) ([]*interceptor.OpeningFeeParamsSetting, error) {
-	rows, err := s.pool.Query(context.Background(), `SELECT validity, params FROM new_channel_params WHERE token=$1`, token)
+func (s *PostgresInterceptStore) GetFeeParamsSettings(ctx context.Context, token string) ([]*interceptor.OpeningFeeParamsSetting, error) {
+	rows, err := s.pool.Query(ctx, `SELECT validity, params FROM new_channel_params WHERE token = ANY($1) AND tenant_id = $2`, s.cipher.StringCandidates(token), tenant.FromContext(ctx))
 	if err != nil {
 		log.Printf("GetFeeParamsSettings(%v) error: %v", token, err)
 		return nil, err
@@ -160,3 +426,175 @@ func (s *PostgresInterceptStore) GetFeeParamsSettings(token string) ([]*intercep
 
 	return settings, nil
 }
+
+// RegisterNonce stores the nonce of a signed payment registration. It returns
+// false if the destination used the nonce before.
+func (s *PostgresInterceptStore) RegisterNonce(ctx context.Context, destination, nonce []byte) (bool, error) {
+	// The nonce may be stored with the destination in another form, if the
+	// encryption key changed.
+	commandTag, err := s.pool.Exec(ctx,
+		`INSERT INTO payment_nonces (destination, nonce, created_at, tenant_id)
+		SELECT $1::bytea, $2::bytea, $3::bigint, $5::varchar
+		WHERE NOT EXISTS (SELECT 1 FROM payment_nonces WHERE destination = ANY($4) AND nonce = $2 AND tenant_id = $5)
+		ON CONFLICT DO NOTHING`,
+		s.cipher.Encrypt(destination), nonce, time.Now().UnixMicro(), s.cipher.Candidates(destination), tenant.FromContext(ctx))
+	if err != nil {
+		log.Printf("registerNonce(%x, %x) error: %v", destination, nonce, err)
+		return false, fmt.Errorf("registerNonce(%x, %x) error: %w", destination, nonce, err)
+	}
+
+	return commandTag.RowsAffected() == 1, nil
+}
+
+// ForgetNonce removes the nonce of a signed request that was not processed,
+// so the request can be retried.
+func (s *PostgresInterceptStore) ForgetNonce(ctx context.Context, destination, nonce []byte) error {
+	_, err := s.pool.Exec(ctx,
+		`DELETE FROM payment_nonces WHERE destination = ANY($1) AND nonce = $2 AND tenant_id = $3`,
+		s.cipher.Candidates(destination), nonce, tenant.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("forgetNonce(%x, %x) error: %w", destination, nonce, err)
+	}
+
+	return nil
+}
+
+// DeleteNonces removes the nonces of all tenants stored before the given
+// time, once their requests can't be accepted anymore.
+func (s *PostgresInterceptStore) DeleteNonces(ctx context.Context, before time.Time) (int64, error) {
+	commandTag, err := s.pool.Exec(ctx,
+		`DELETE FROM payment_nonces WHERE created_at < $1`,
+		before.UnixMicro())
+	if err != nil {
+		return 0, fmt.Errorf("deleteNonces(%v) error: %w", before, err)
+	}
+
+	return commandTag.RowsAffected(), nil
+}
+
+// SaveAddressHints stores the addresses the destination of a registration
+// accepts connections on.
+func (s *PostgresInterceptStore) SaveAddressHints(ctx context.Context, lspNodeID, destination []byte, addresses []string) error {
+	now := time.Now().UnixMicro()
+	for _, address := range addresses {
+		_, err := s.pool.Exec(ctx,
+			`INSERT INTO peer_address_hints (lsp_nodeid, peer_id, address, updated_at, tenant_id)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (lsp_nodeid, peer_id, address) DO UPDATE SET updated_at = EXCLUDED.updated_at`,
+			lspNodeID, destination, address, now, tenant.FromContext(ctx))
+		if err != nil {
+			return fmt.Errorf("saveAddressHints(%x, %s) error: %w", destination, address, err)
+		}
+	}
+
+	return nil
+}
+
+// GetPeerAddresses returns the addresses the peer accepts connections on,
+// from registrations and previous outgoing connections, most recent first.
+func (s *PostgresInterceptStore) GetPeerAddresses(ctx context.Context, lspNodeID, peerID []byte) ([]string, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT address, MAX(updated_at) AS updated_at
+		FROM (
+			SELECT address, updated_at
+			FROM peer_address_hints
+			WHERE lsp_nodeid = $1 AND peer_id = $2 AND tenant_id = $3
+			UNION ALL
+			SELECT address, updated_at
+			FROM peer_states
+			WHERE lsp_nodeid = $1 AND peer_id = $2 AND tenant_id = $3 AND address IS NOT NULL
+		) a
+		GROUP BY address
+		ORDER BY updated_at DESC`,
+		lspNodeID, peerID, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("getPeerAddresses(%x) error: %w", peerID, err)
+	}
+	defer rows.Close()
+
+	var addresses []string
+	for rows.Next() {
+		var address string
+		var updatedAt int64
+		err = rows.Scan(&address, &updatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("getPeerAddresses(%x) scan error: %w", peerID, err)
+		}
+
+		addresses = append(addresses, address)
+	}
+
+	return addresses, rows.Err()
+}
+
+// SaveClientAgent stores the wallet sdk the destination of a registration
+// last registered with.
+func (s *PostgresInterceptStore) SaveClientAgent(ctx context.Context, lspNodeID, destination []byte, agent string) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO client_agents (lsp_nodeid, peer_id, agent, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (lsp_nodeid, peer_id) DO UPDATE SET agent = EXCLUDED.agent, updated_at = EXCLUDED.updated_at`,
+		lspNodeID, destination, agent, time.Now().UnixMicro(), tenant.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("saveClientAgent(%x, %s) error: %w", destination, agent, err)
+	}
+
+	return nil
+}
+
+// GetClientAgent returns the wallet sdk the destination last registered
+// with, or an empty string if it is unknown.
+func (s *PostgresInterceptStore) GetClientAgent(ctx context.Context, lspNodeID, destination []byte) (string, error) {
+	var agent string
+	err := s.pool.QueryRow(ctx,
+		`SELECT agent FROM client_agents WHERE lsp_nodeid = $1 AND peer_id = $2 AND tenant_id = $3`,
+		lspNodeID, destination, tenant.FromContext(ctx)).Scan(&agent)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("getClientAgent(%x) error: %w", destination, err)
+	}
+
+	return agent, nil
+}
+
+// PaymentPriority returns the priority tier the payment was registered with,
+// or an empty string if it has none.
+func (s *PostgresInterceptStore) PaymentPriority(ctx context.Context, paymentHash []byte) (string, error) {
+	var priority *string
+	err := s.pool.QueryRow(ctx,
+		`SELECT priority FROM payments WHERE payment_hash = $1 AND tenant_id = $2`,
+		paymentHash, tenant.FromContext(ctx)).Scan(&priority)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("paymentPriority(%x) error: %w", paymentHash, err)
+	}
+
+	if priority == nil {
+		return "", nil
+	}
+	return *priority, nil
+}
+
+// PaymentCommitmentType returns the commitment type the payment was
+// registered with, or an empty type if it has none.
+func (s *PostgresInterceptStore) PaymentCommitmentType(ctx context.Context, paymentHash []byte) (lightning.CommitmentType, error) {
+	var commitmentType *string
+	err := s.pool.QueryRow(ctx,
+		`SELECT commitment_type FROM payments WHERE payment_hash = $1 AND tenant_id = $2`,
+		paymentHash, tenant.FromContext(ctx)).Scan(&commitmentType)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("paymentCommitmentType(%x) error: %w", paymentHash, err)
+	}
+
+	if commitmentType == nil {
+		return "", nil
+	}
+	return lightning.CommitmentType(*commitmentType), nil
+}
@@ -0,0 +1,252 @@
+package postgresql_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/dbcrypt"
+	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/postgresql"
+	"github.com/breez/lspd/postgresql/pgtest"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	lspNodeID   = bytes.Repeat([]byte{0x02}, 33)
+	destination = bytes.Repeat([]byte{0x03}, 33)
+)
+
+func testParams() *interceptor.OpeningFeeParams {
+	return &interceptor.OpeningFeeParams{
+		MinMsat:              2000000,
+		Proportional:         4000,
+		ValidUntil:           time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		MaxIdleTime:          4320,
+		MaxClientToSelfDelay: 2016,
+		Promise:              "promise",
+	}
+}
+
+func TestRegisterPayment(t *testing.T) {
+	store := postgresql.NewPostgresInterceptStore(pgtest.NewDatabase(t), nil)
+	paymentHash := bytes.Repeat([]byte{0x01}, 32)
+	paymentSecret := bytes.Repeat([]byte{0x04}, 32)
+
+	err := store.RegisterPayment(context.Background(), "token", lspNodeID, testParams(), destination, paymentHash, paymentSecret, 10000000, 8000000, "tag", "urgent", lightning.CommitmentTypeAnchors)
+	assert.NoError(t, err)
+
+	token, params, hash, secret, dest, incoming, outgoing, channelPoint, tag, err := store.PaymentInfo(context.Background(), paymentHash)
+	assert.NoError(t, err)
+	assert.Equal(t, "token", token)
+	assert.Equal(t, "promise", params.Promise)
+	assert.Equal(t, paymentHash, hash)
+	assert.Equal(t, paymentSecret, secret)
+	assert.Equal(t, destination, dest)
+	assert.Equal(t, int64(10000000), incoming)
+	assert.Equal(t, int64(8000000), outgoing)
+	assert.Nil(t, channelPoint)
+	assert.Equal(t, "tag", *tag)
+	priority, err := store.PaymentPriority(context.Background(), paymentHash)
+	assert.NoError(t, err)
+	assert.Equal(t, "urgent", priority)
+	commitmentType, err := store.PaymentCommitmentType(context.Background(), paymentHash)
+	assert.NoError(t, err)
+	assert.Equal(t, lightning.CommitmentTypeAnchors, commitmentType)
+
+	// Registering again updates the registration.
+	err = store.RegisterPayment(context.Background(), "token", lspNodeID, testParams(), destination, paymentHash, paymentSecret, 20000000, 18000000, "", "", "")
+	assert.NoError(t, err)
+	_, _, _, _, _, incoming, _, _, _, err = store.PaymentInfo(context.Background(), paymentHash)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20000000), incoming)
+	priority, err = store.PaymentPriority(context.Background(), paymentHash)
+	assert.NoError(t, err)
+	assert.Equal(t, "", priority)
+	commitmentType, err = store.PaymentCommitmentType(context.Background(), paymentHash)
+	assert.NoError(t, err)
+	assert.Equal(t, lightning.CommitmentType(""), commitmentType)
+
+	// A registration with a channel can't be cancelled.
+	outpoint := wire.NewOutPoint(&chainhash.Hash{0x05}, 1)
+	err = store.SetFundingTx(context.Background(), paymentHash, outpoint)
+	assert.NoError(t, err)
+	_, _, _, _, _, _, _, channelPoint, _, err = store.PaymentInfo(context.Background(), paymentHash)
+	assert.NoError(t, err)
+	assert.Equal(t, outpoint.String(), channelPoint.String())
+
+	cancelled, err := store.CancelPayment(context.Background(), paymentHash, destination)
+	assert.NoError(t, err)
+	assert.False(t, cancelled)
+}
+
+func TestCancelPayment(t *testing.T) {
+	store := postgresql.NewPostgresInterceptStore(pgtest.NewDatabase(t), nil)
+	paymentHash := bytes.Repeat([]byte{0x01}, 32)
+	err := store.RegisterPayment(context.Background(), "token", lspNodeID, testParams(), destination, paymentHash, paymentHash, 10000000, 8000000, "", "", "")
+	assert.NoError(t, err)
+
+	// Only the destination can cancel.
+	cancelled, err := store.CancelPayment(context.Background(), paymentHash, lspNodeID)
+	assert.NoError(t, err)
+	assert.False(t, cancelled)
+
+	cancelled, err = store.CancelPayment(context.Background(), paymentHash, destination)
+	assert.NoError(t, err)
+	assert.True(t, cancelled)
+
+	_, _, hash, _, _, _, _, _, _, err := store.PaymentInfo(context.Background(), paymentHash)
+	assert.NoError(t, err)
+	assert.Nil(t, hash)
+}
+
+func TestClaimPreimageEncrypted(t *testing.T) {
+	pool := pgtest.NewDatabase(t)
+	oldCipher, err := dbcrypt.NewCipher(bytes.Repeat([]byte{0x01}, 32))
+	assert.NoError(t, err)
+	cipher, err := dbcrypt.NewCipher(bytes.Repeat([]byte{0x02}, 32), bytes.Repeat([]byte{0x01}, 32))
+	assert.NoError(t, err)
+	paymentHash := bytes.Repeat([]byte{0x01}, 32)
+	preimage := bytes.Repeat([]byte{0x05}, 32)
+
+	// The payment was registered before the key was rotated.
+	err = postgresql.NewPostgresInterceptStore(pool, oldCipher).RegisterPayment(context.Background(), "token", lspNodeID, testParams(), destination, paymentHash, paymentHash, 10000000, 8000000, "", "", "")
+	assert.NoError(t, err)
+
+	store := postgresql.NewPostgresInterceptStore(pool, cipher)
+	err = store.InsertPreimage(context.Background(), lspNodeID, destination, paymentHash, preimage)
+	assert.NoError(t, err)
+
+	var stored []byte
+	err = pool.QueryRow(context.Background(), `SELECT preimage FROM payment_preimages`).Scan(&stored)
+	assert.NoError(t, err)
+	assert.NotEqual(t, preimage, stored)
+
+	claimed, opened, err := store.ClaimPreimage(context.Background(), paymentHash, destination)
+	assert.NoError(t, err)
+	assert.Equal(t, preimage, claimed)
+	assert.False(t, opened)
+
+	err = store.SetFundingTx(context.Background(), paymentHash, wire.NewOutPoint(&chainhash.Hash{}, 0))
+	assert.NoError(t, err)
+	claimed, opened, err = store.ClaimPreimage(context.Background(), paymentHash, destination)
+	assert.NoError(t, err)
+	assert.Equal(t, preimage, claimed)
+	assert.True(t, opened)
+}
+
+func TestRegisterNonce(t *testing.T) {
+	store := postgresql.NewPostgresInterceptStore(pgtest.NewDatabase(t), nil)
+	nonce := bytes.Repeat([]byte{0x01}, 16)
+
+	unused, err := store.RegisterNonce(context.Background(), destination, nonce)
+	assert.NoError(t, err)
+	assert.True(t, unused)
+
+	unused, err = store.RegisterNonce(context.Background(), destination, nonce)
+	assert.NoError(t, err)
+	assert.False(t, unused)
+
+	// Only nonces stored before the given time are deleted.
+	deleted, err := store.DeleteNonces(context.Background(), time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+	deleted, err = store.DeleteNonces(context.Background(), time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	unused, err = store.RegisterNonce(context.Background(), destination, nonce)
+	assert.NoError(t, err)
+	assert.True(t, unused)
+}
+
+func TestClientAgent(t *testing.T) {
+	store := postgresql.NewPostgresInterceptStore(pgtest.NewDatabase(t), nil)
+	ctx := context.Background()
+
+	agent, err := store.GetClientAgent(ctx, lspNodeID, destination)
+	assert.NoError(t, err)
+	assert.Equal(t, "", agent)
+
+	assert.NoError(t, store.SaveClientAgent(ctx, lspNodeID, destination, "breez-sdk/0.2.1"))
+	assert.NoError(t, store.SaveClientAgent(ctx, lspNodeID, destination, "breez-sdk/0.3.0"))
+	agent, err = store.GetClientAgent(ctx, lspNodeID, destination)
+	assert.NoError(t, err)
+	assert.Equal(t, "breez-sdk/0.3.0", agent)
+}
+
+func TestRecordHtlcFee(t *testing.T) {
+	pool := pgtest.NewDatabase(t)
+	store := postgresql.NewPostgresInterceptStore(pool, nil)
+	paymentHash := bytes.Repeat([]byte{0x01}, 32)
+	err := store.RegisterPayment(context.Background(), "token", lspNodeID, testParams(), destination, paymentHash, paymentHash, 3000, 2000, "", "", "")
+	assert.NoError(t, err)
+
+	// Two parts within their share of the promised fee of 1000 msat.
+	credit, err := store.RecordHtlcFee(context.Background(), lspNodeID, destination, paymentHash, 1500, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), credit)
+
+	// The second part deducts 1 msat more than promised.
+	credit, err = store.RecordHtlcFee(context.Background(), lspNodeID, destination, paymentHash, 1500, 999)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), credit)
+
+	report, err := postgresql.NewStatsStore(pool, nil).FeeReport(context.Background(), lspNodeID, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), report.HtlcCount)
+	assert.Equal(t, int64(3000), report.ReceivedMsat)
+	assert.Equal(t, int64(1001), report.DeductedFeeMsat)
+	assert.Equal(t, int64(1), report.CreditMsat)
+	if assert.Len(t, report.Credits, 1) {
+		assert.Equal(t, int64(1000), report.Credits[0].PromisedFeeMsat)
+	}
+}
+
+func TestAssignFakeScid(t *testing.T) {
+	store := postgresql.NewPostgresInterceptStore(pgtest.NewDatabase(t), nil)
+	ctx := context.Background()
+	paymentHash1 := bytes.Repeat([]byte{0x01}, 32)
+	paymentHash2 := bytes.Repeat([]byte{0x05}, 32)
+	paymentSecret := bytes.Repeat([]byte{0x04}, 32)
+	for _, hash := range [][]byte{paymentHash1, paymentHash2} {
+		err := store.RegisterPayment(ctx, "token", lspNodeID, testParams(), destination, hash, paymentSecret, 10000000, 8000000, "", "", "")
+		assert.NoError(t, err)
+	}
+
+	scid, err := store.AssignFakeScid(ctx, paymentHash1, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), scid)
+
+	// The payment keeps its fake scid.
+	scid, err = store.AssignFakeScid(ctx, paymentHash1, 200)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), scid)
+
+	// A fake scid is only assigned once.
+	scid, err = store.AssignFakeScid(ctx, paymentHash2, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), scid)
+
+	_, _, hash, _, _, _, _, _, _, err := store.PaymentInfoByScid(ctx, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, paymentHash1, hash)
+
+	// The scids of expired registrations aren't listed.
+	expired := testParams()
+	expired.ValidUntil = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	paymentHash3 := bytes.Repeat([]byte{0x06}, 32)
+	err = store.RegisterPayment(ctx, "token", lspNodeID, expired, destination, paymentHash3, paymentSecret, 10000000, 8000000, "", "", "")
+	assert.NoError(t, err)
+	scid, err = store.AssignFakeScid(ctx, paymentHash3, 300)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(300), scid)
+
+	scids, err := store.ListFakeScids(ctx, lspNodeID)
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint64][]byte{100: paymentHash1}, scids)
+}
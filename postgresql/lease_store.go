@@ -0,0 +1,48 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// LeaseStore stores the leases of the replicas leading a node. Expiry is
+// computed with the clock of the database.
+type LeaseStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewLeaseStore(pool *pgxpool.Pool) *LeaseStore {
+	return &LeaseStore{pool: pool}
+}
+
+func (s *LeaseStore) Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	commandTag, err := s.pool.Exec(ctx,
+		`INSERT INTO leader_leases (name, holder, expires_at)
+		 VALUES ($1, $2, (extract(epoch FROM clock_timestamp()) * 1000000)::bigint + $3)
+		 ON CONFLICT (name) DO UPDATE
+		 SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		 WHERE leader_leases.holder = EXCLUDED.holder
+		    OR leader_leases.expires_at < (extract(epoch FROM clock_timestamp()) * 1000000)::bigint`,
+		name, holder, ttl.Microseconds(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("Acquire(%s, %s) error: %w", name, holder, err)
+	}
+
+	return commandTag.RowsAffected() == 1, nil
+}
+
+func (s *LeaseStore) Release(ctx context.Context, name string, holder string) error {
+	_, err := s.pool.Exec(ctx,
+		`DELETE FROM leader_leases WHERE name = $1 AND holder = $2`,
+		name, holder,
+	)
+	if err != nil {
+		return fmt.Errorf("Release(%s, %s) error: %w", name, holder, err)
+	}
+
+	return nil
+}
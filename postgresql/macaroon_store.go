@@ -0,0 +1,181 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/dbcrypt"
+	"github.com/breez/lspd/macaroon"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type MacaroonStore struct {
+	pool   *pgxpool.Pool
+	cipher *dbcrypt.Cipher
+}
+
+func NewMacaroonStore(pool *pgxpool.Pool, cipher *dbcrypt.Cipher) *MacaroonStore {
+	return &MacaroonStore{pool: pool, cipher: cipher}
+}
+
+const macaroonColumns = `id, lsp_nodeid, token, name, caveats, created_at, revoked_at`
+
+func scanMacaroon(cipher *dbcrypt.Cipher, row pgx.Row) (*macaroon.Record, error) {
+	var (
+		r         macaroon.Record
+		createdAt int64
+		revokedAt pgtype.Int8
+	)
+	err := row.Scan(&r.ID, &r.LspNodeID, &r.Token, &r.Name, &r.Caveats, &createdAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Token, err = decryptString(cipher, r.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	r.CreatedAt = time.UnixMicro(createdAt).UTC()
+	if revokedAt.Status == pgtype.Present {
+		t := time.UnixMicro(revokedAt.Int).UTC()
+		r.RevokedAt = &t
+	}
+
+	return &r, nil
+}
+
+func (s *MacaroonStore) GetRootKey(ctx context.Context, lspNodeID []byte) ([]byte, error) {
+	var rootKey []byte
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT root_key
+		 FROM public.macaroon_root_keys
+		 WHERE lsp_nodeid = $1 AND tenant_id = $2`,
+		lspNodeID,
+		tenant.FromContext(ctx),
+	).Scan(&rootKey)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return rootKey, nil
+}
+
+func (s *MacaroonStore) AddRootKey(ctx context.Context, lspNodeID []byte, rootKey []byte) error {
+	_, err := s.pool.Exec(
+		ctx,
+		`INSERT INTO public.macaroon_root_keys (lsp_nodeid, root_key, created_at, tenant_id)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (lsp_nodeid) DO NOTHING`,
+		lspNodeID,
+		rootKey,
+		time.Now().UnixMicro(),
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("INSERT INTO macaroon_root_keys error: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MacaroonStore) Get(ctx context.Context, id []byte) (*macaroon.Record, error) {
+	row := s.pool.QueryRow(
+		ctx,
+		`SELECT `+macaroonColumns+`
+		 FROM public.macaroons
+		 WHERE id = $1 AND tenant_id = $2`,
+		id,
+		tenant.FromContext(ctx),
+	)
+	r, err := scanMacaroon(s.cipher, row)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanMacaroon() error: %w", err)
+	}
+
+	return r, nil
+}
+
+func (s *MacaroonStore) List(ctx context.Context) ([]*macaroon.Record, error) {
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT `+macaroonColumns+`
+		 FROM public.macaroons
+		 WHERE tenant_id = $1
+		 ORDER BY created_at`,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*macaroon.Record
+	for rows.Next() {
+		r, err := scanMacaroon(s.cipher, rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanMacaroon() error: %w", err)
+		}
+
+		result = append(result, r)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *MacaroonStore) Create(ctx context.Context, r *macaroon.Record) error {
+	caveats := r.Caveats
+	if caveats == nil {
+		caveats = []string{}
+	}
+
+	_, err := s.pool.Exec(
+		ctx,
+		`INSERT INTO public.macaroons (id, lsp_nodeid, token, name, caveats, created_at, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		r.ID,
+		r.LspNodeID,
+		s.cipher.EncryptString(r.Token),
+		r.Name,
+		caveats,
+		r.CreatedAt.UnixMicro(),
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("INSERT INTO macaroons error: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MacaroonStore) Revoke(ctx context.Context, id []byte, at time.Time) error {
+	cmdTag, err := s.pool.Exec(
+		ctx,
+		`UPDATE public.macaroons
+		 SET revoked_at = LEAST(COALESCE(revoked_at, $2), $2)
+		 WHERE id = $1 AND tenant_id = $3`,
+		id,
+		at.UnixMicro(),
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("UPDATE macaroons error: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return macaroon.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package postgresql
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+type migration struct {
+	version int64
+	name    string
+}
+
+// Migrate applies all embedded migrations that were not applied yet. The
+// applied version is tracked in the schema_migrations table, in the same
+// format as golang-migrate, so databases migrated with the migrate cli before
+// can be migrated with lspd and vice versa.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(ctx,
+		`CREATE TABLE IF NOT EXISTS public.schema_migrations (
+			version bigint NOT NULL PRIMARY KEY,
+			dirty boolean NOT NULL
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int64
+	var dirty bool
+	err = pool.QueryRow(ctx,
+		`SELECT version, dirty FROM public.schema_migrations LIMIT 1`,
+	).Scan(&current, &dirty)
+	if err != nil && err != pgx.ErrNoRows {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	if dirty {
+		return fmt.Errorf("database is in a dirty state at version %d, fix it manually", current)
+	}
+
+	ms, err := upMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range ms {
+		if m.version <= current {
+			continue
+		}
+
+		err = applyMigration(ctx, pool, m)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("migrate: applied %s", m.name)
+	}
+
+	return nil
+}
+
+func upMigrations() ([]*migration, error) {
+	names, err := fs.Glob(migrations, "migrations/*.up.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	var ms []*migration
+	for _, name := range names {
+		base := strings.TrimPrefix(name, "migrations/")
+		version, err := strconv.ParseInt(strings.SplitN(base, "_", 2)[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration name '%s': %w", base, err)
+		}
+
+		ms = append(ms, &migration{version: version, name: name})
+	}
+
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].version < ms[j].version
+	})
+	return ms, nil
+}
+
+func applyMigration(ctx context.Context, pool *pgxpool.Pool, m *migration) error {
+	sql, err := migrations.ReadFile(m.name)
+	if err != nil {
+		return fmt.Errorf("failed to read migration '%s': %w", m.name, err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("pgxPool.Begin() error: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, string(sql))
+	if err != nil {
+		return fmt.Errorf("failed to apply migration '%s': %w", m.name, err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM public.schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("failed to update schema version: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO public.schema_migrations (version, dirty) VALUES ($1, false)`,
+		m.version)
+	if err != nil {
+		return fmt.Errorf("failed to update schema version: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,4 @@
+DROP INDEX forwarding_history_lsp_nodeid_idx;
+ALTER TABLE public.forwarding_history DROP COLUMN lsp_nodeid;
+ALTER TABLE public.channels DROP COLUMN lsp_nodeid;
+ALTER TABLE public.payments DROP COLUMN lsp_nodeid;
@@ -0,0 +1,4 @@
+ALTER TABLE public.payments ADD lsp_nodeid bytea NULL;
+ALTER TABLE public.channels ADD lsp_nodeid bytea NULL;
+ALTER TABLE public.forwarding_history ADD lsp_nodeid bytea NULL;
+CREATE INDEX forwarding_history_lsp_nodeid_idx ON public.forwarding_history (lsp_nodeid);
@@ -0,0 +1,2 @@
+DROP INDEX public.api_tokens_lsp_nodeid_idx;
+DROP TABLE public.api_tokens;
@@ -0,0 +1,10 @@
+CREATE TABLE public.api_tokens (
+	token varchar PRIMARY KEY,
+	lsp_nodeid bytea NOT NULL,
+	name varchar NOT NULL,
+	additional_channel_capacity bigint NULL,
+	time_lock_delta bigint NULL,
+	created_at bigint NOT NULL,
+	disabled_at bigint NULL
+);
+CREATE INDEX api_tokens_lsp_nodeid_idx ON public.api_tokens (lsp_nodeid);
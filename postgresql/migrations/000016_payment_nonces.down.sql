@@ -0,0 +1,2 @@
+DROP INDEX public.payment_nonces_created_at_idx;
+DROP TABLE public.payment_nonces;
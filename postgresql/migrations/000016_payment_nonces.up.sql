@@ -0,0 +1,7 @@
+CREATE TABLE public.payment_nonces (
+	destination bytea NOT NULL,
+	nonce bytea NOT NULL,
+	created_at bigint NOT NULL,
+	PRIMARY KEY (destination, nonce)
+);
+CREATE INDEX payment_nonces_created_at_idx ON public.payment_nonces (created_at);
@@ -0,0 +1 @@
+ALTER TABLE public.api_tokens ADD COLUMN taproot_channels boolean NULL;
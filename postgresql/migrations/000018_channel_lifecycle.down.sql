@@ -0,0 +1,7 @@
+DROP INDEX public.channels_lsp_nodeid_idx;
+ALTER TABLE public.channels DROP COLUMN close_reason;
+ALTER TABLE public.channels DROP COLUMN closed_at;
+ALTER TABLE public.channels DROP COLUMN keep_open;
+ALTER TABLE public.channels DROP COLUMN activity_msat;
+ALTER TABLE public.channels DROP COLUMN last_activity;
+ALTER TABLE public.channels DROP COLUMN opened_at;
@@ -0,0 +1,8 @@
+ALTER TABLE public.channels ADD COLUMN opened_at bigint NULL;
+ALTER TABLE public.channels ADD COLUMN last_activity bigint NULL;
+ALTER TABLE public.channels ADD COLUMN activity_msat bigint NULL;
+ALTER TABLE public.channels ADD COLUMN keep_open boolean NOT NULL DEFAULT false;
+ALTER TABLE public.channels ADD COLUMN closed_at bigint NULL;
+ALTER TABLE public.channels ADD COLUMN close_reason varchar NULL;
+UPDATE public.channels SET opened_at = (extract(epoch from last_update) * 1000000)::bigint;
+CREATE INDEX channels_lsp_nodeid_idx ON public.channels (lsp_nodeid);
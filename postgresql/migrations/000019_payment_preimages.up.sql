@@ -0,0 +1,9 @@
+CREATE TABLE public.payment_preimages (
+	payment_hash bytea NOT NULL,
+	preimage bytea NOT NULL,
+	destination bytea NOT NULL,
+	lsp_nodeid bytea NOT NULL,
+	created_at bigint NOT NULL,
+	claimed_at bigint NULL,
+	CONSTRAINT payment_preimages_pkey PRIMARY KEY (payment_hash)
+);
@@ -0,0 +1,2 @@
+DROP TABLE public.fee_credits;
+DROP TABLE public.htlc_fees;
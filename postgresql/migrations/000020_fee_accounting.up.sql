@@ -0,0 +1,21 @@
+CREATE TABLE public.htlc_fees (
+	id bigserial PRIMARY KEY,
+	payment_hash bytea NOT NULL,
+	lsp_nodeid bytea NOT NULL,
+	destination bytea NOT NULL,
+	amount_in_msat bigint NOT NULL,
+	amount_out_msat bigint NOT NULL,
+	created_at bigint NOT NULL
+);
+CREATE INDEX htlc_fees_payment_hash_idx ON public.htlc_fees (payment_hash);
+
+CREATE TABLE public.fee_credits (
+	payment_hash bytea PRIMARY KEY,
+	lsp_nodeid bytea NOT NULL,
+	destination bytea NOT NULL,
+	promised_fee_msat bigint NOT NULL,
+	deducted_fee_msat bigint NOT NULL,
+	credit_msat bigint NOT NULL,
+	updated_at bigint NOT NULL
+);
+CREATE INDEX fee_credits_destination_idx ON public.fee_credits (destination);
@@ -0,0 +1,2 @@
+DROP INDEX public.htlc_resolutions_created_at_idx;
+DROP TABLE public.htlc_resolutions;
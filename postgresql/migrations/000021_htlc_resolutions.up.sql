@@ -0,0 +1,10 @@
+CREATE TABLE public.htlc_resolutions (
+	lsp_nodeid bytea NOT NULL,
+	payment_hash bytea NOT NULL,
+	incoming_scid bigint NOT NULL,
+	htlc_id bigint NOT NULL,
+	resolution bytea NOT NULL,
+	created_at bigint NOT NULL,
+	PRIMARY KEY (lsp_nodeid, payment_hash, incoming_scid, htlc_id)
+);
+CREATE INDEX htlc_resolutions_created_at_idx ON public.htlc_resolutions (created_at);
@@ -0,0 +1,3 @@
+DROP INDEX public.notification_device_tokens_platform_token_key;
+DROP INDEX public.notification_device_tokens_pubkey_idx;
+DROP TABLE public.notification_device_tokens;
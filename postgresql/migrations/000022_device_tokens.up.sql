@@ -0,0 +1,11 @@
+CREATE TABLE public.notification_device_tokens (
+	id bigserial PRIMARY KEY,
+	pubkey bytea NOT NULL,
+	platform int NOT NULL,
+	token varchar NOT NULL,
+	created_at bigint NOT NULL,
+	refreshed_at bigint NOT NULL
+);
+
+CREATE INDEX notification_device_tokens_pubkey_idx ON public.notification_device_tokens (pubkey);
+CREATE UNIQUE INDEX notification_device_tokens_platform_token_key ON public.notification_device_tokens (platform, token);
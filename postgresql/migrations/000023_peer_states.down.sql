@@ -0,0 +1 @@
+DROP TABLE public.peer_states;
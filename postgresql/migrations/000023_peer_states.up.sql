@@ -0,0 +1,7 @@
+CREATE TABLE public.peer_states (
+	lsp_nodeid bytea NOT NULL,
+	peer_id bytea NOT NULL,
+	connected boolean NOT NULL,
+	updated_at bigint NOT NULL,
+	CONSTRAINT peer_states_pkey PRIMARY KEY (lsp_nodeid, peer_id)
+);
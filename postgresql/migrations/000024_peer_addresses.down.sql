@@ -0,0 +1,2 @@
+DROP TABLE public.peer_address_hints;
+ALTER TABLE public.peer_states DROP COLUMN address;
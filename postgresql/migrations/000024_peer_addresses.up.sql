@@ -0,0 +1,9 @@
+ALTER TABLE public.peer_states ADD COLUMN address varchar NULL;
+
+CREATE TABLE public.peer_address_hints (
+	lsp_nodeid bytea NOT NULL,
+	peer_id bytea NOT NULL,
+	address varchar NOT NULL,
+	updated_at bigint NOT NULL,
+	CONSTRAINT peer_address_hints_pkey PRIMARY KEY (lsp_nodeid, peer_id, address)
+);
@@ -0,0 +1 @@
+DROP TABLE public.client_agents;
@@ -0,0 +1,7 @@
+CREATE TABLE public.client_agents (
+	lsp_nodeid bytea NOT NULL,
+	peer_id bytea NOT NULL,
+	agent varchar NOT NULL,
+	updated_at bigint NOT NULL,
+	CONSTRAINT client_agents_pkey PRIMARY KEY (lsp_nodeid, peer_id)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE public.channel_aliases (
+	lsp_nodeid bytea NOT NULL,
+	channel_point varchar NOT NULL,
+	peer_id bytea NOT NULL,
+	alias_scid int8 NOT NULL,
+	real_scid int8 NULL,
+	updated_at bigint NOT NULL,
+	CONSTRAINT channel_aliases_pkey PRIMARY KEY (lsp_nodeid, channel_point)
+);
+CREATE INDEX channel_aliases_peer_id_idx ON public.channel_aliases (lsp_nodeid, peer_id);
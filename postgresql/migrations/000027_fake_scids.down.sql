@@ -0,0 +1,2 @@
+DROP INDEX public.payments_fake_scid_idx;
+ALTER TABLE public.payments DROP COLUMN fake_scid;
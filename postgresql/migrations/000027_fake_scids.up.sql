@@ -0,0 +1,2 @@
+ALTER TABLE public.payments ADD fake_scid int8 NULL;
+CREATE UNIQUE INDEX payments_fake_scid_idx ON public.payments (fake_scid);
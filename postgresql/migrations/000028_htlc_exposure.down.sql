@@ -0,0 +1,2 @@
+DROP INDEX public.htlc_exposure_unresolved_idx;
+DROP TABLE public.htlc_exposure;
@@ -0,0 +1,12 @@
+CREATE TABLE public.htlc_exposure (
+	id bigserial NOT NULL,
+	lsp_nodeid bytea NOT NULL,
+	peer_id bytea NOT NULL,
+	channel_point varchar NOT NULL,
+	payment_hash bytea NOT NULL,
+	amount_msat bigint NOT NULL,
+	created_at bigint NOT NULL,
+	resolved_at bigint NULL,
+	CONSTRAINT htlc_exposure_pkey PRIMARY KEY (id)
+);
+CREATE INDEX htlc_exposure_unresolved_idx ON public.htlc_exposure (lsp_nodeid, peer_id) WHERE resolved_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX public.htlc_exposure_zero_conf_idx;
+ALTER TABLE public.htlc_exposure DROP COLUMN zero_conf;
@@ -0,0 +1,2 @@
+ALTER TABLE public.htlc_exposure ADD zero_conf boolean NOT NULL DEFAULT false;
+CREATE INDEX htlc_exposure_zero_conf_idx ON public.htlc_exposure (lsp_nodeid, peer_id) WHERE resolved_at IS NULL AND zero_conf;
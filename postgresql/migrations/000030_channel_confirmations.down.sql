@@ -0,0 +1 @@
+ALTER TABLE public.channels DROP COLUMN confirmed_at;
@@ -0,0 +1,2 @@
+ALTER TABLE public.channels ADD confirmed_at bigint NULL;
+UPDATE public.channels SET confirmed_at = (extract(epoch from last_update) * 1000000)::bigint WHERE confirmed_chanid IS NOT NULL;
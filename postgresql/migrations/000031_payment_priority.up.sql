@@ -0,0 +1 @@
+ALTER TABLE public.payments ADD priority varchar;
@@ -0,0 +1,3 @@
+DROP INDEX public.channel_open_jobs_due_idx;
+DROP INDEX public.channel_open_jobs_peer_idx;
+DROP TABLE public.channel_open_jobs;
@@ -0,0 +1,16 @@
+CREATE TABLE public.channel_open_jobs (
+	id varchar NOT NULL,
+	lsp_nodeid bytea NOT NULL,
+	peer_id bytea NOT NULL,
+	taproot boolean NOT NULL,
+	status varchar NOT NULL,
+	attempts integer NOT NULL,
+	channel_point varchar NULL,
+	last_error varchar NULL,
+	next_attempt_at bigint NOT NULL,
+	created_at bigint NOT NULL,
+	updated_at bigint NOT NULL,
+	CONSTRAINT channel_open_jobs_pkey PRIMARY KEY (id)
+);
+CREATE INDEX channel_open_jobs_peer_idx ON public.channel_open_jobs (lsp_nodeid, peer_id);
+CREATE INDEX channel_open_jobs_due_idx ON public.channel_open_jobs (lsp_nodeid, next_attempt_at) WHERE status IN ('pending', 'opening');
@@ -0,0 +1,3 @@
+DROP INDEX public.peer_score_events_created_at_idx;
+DROP INDEX public.peer_score_events_ref_idx;
+DROP TABLE public.peer_score_events;
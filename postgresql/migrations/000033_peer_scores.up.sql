@@ -0,0 +1,13 @@
+CREATE TABLE public.peer_score_events (
+	id bigserial NOT NULL,
+	lsp_nodeid bytea NOT NULL,
+	peer_id bytea NOT NULL,
+	kind varchar NOT NULL,
+	ref varchar NOT NULL,
+	penalty double precision NOT NULL,
+	created_at bigint NOT NULL,
+	reset boolean NOT NULL DEFAULT false,
+	CONSTRAINT peer_score_events_pkey PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX peer_score_events_ref_idx ON public.peer_score_events (lsp_nodeid, peer_id, kind, ref);
+CREATE INDEX peer_score_events_created_at_idx ON public.peer_score_events (lsp_nodeid, created_at) WHERE NOT reset;
@@ -0,0 +1 @@
+ALTER TABLE public.api_tokens DROP COLUMN public_channels;
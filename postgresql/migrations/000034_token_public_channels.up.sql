@@ -0,0 +1 @@
+ALTER TABLE public.api_tokens ADD COLUMN public_channels boolean NULL;
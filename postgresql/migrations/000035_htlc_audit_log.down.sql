@@ -0,0 +1,6 @@
+DROP TRIGGER htlc_audit_log_append_only ON public.htlc_audit_log;
+DROP FUNCTION public.htlc_audit_log_append_only();
+DROP INDEX public.htlc_audit_log_peer_id_idx;
+DROP INDEX public.htlc_audit_log_payment_hash_idx;
+DROP INDEX public.htlc_audit_log_intercepted_at_idx;
+DROP TABLE public.htlc_audit_log;
@@ -0,0 +1,31 @@
+CREATE TABLE public.htlc_audit_log (
+	id bigserial NOT NULL,
+	lsp_nodeid bytea NOT NULL,
+	intercepted_at bigint NOT NULL,
+	payment_hash bytea NOT NULL,
+	scid varchar NOT NULL,
+	amount_msat bigint NOT NULL,
+	incoming_expiry bigint NOT NULL,
+	outgoing_expiry bigint NOT NULL,
+	registered_payment_hash bytea NULL,
+	peer_id bytea NULL,
+	action varchar NOT NULL,
+	failure_code varchar NULL,
+	channel_point varchar NULL,
+	channel_opened boolean NOT NULL,
+	latency_us bigint NOT NULL,
+	CONSTRAINT htlc_audit_log_pkey PRIMARY KEY (id)
+);
+CREATE INDEX htlc_audit_log_intercepted_at_idx ON public.htlc_audit_log (lsp_nodeid, intercepted_at);
+CREATE INDEX htlc_audit_log_payment_hash_idx ON public.htlc_audit_log (payment_hash);
+CREATE INDEX htlc_audit_log_peer_id_idx ON public.htlc_audit_log (peer_id);
+
+-- Records are never changed, they are only deleted after the retention.
+CREATE FUNCTION public.htlc_audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'htlc_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER htlc_audit_log_append_only
+	BEFORE UPDATE ON public.htlc_audit_log
+	FOR EACH ROW EXECUTE FUNCTION public.htlc_audit_log_append_only();
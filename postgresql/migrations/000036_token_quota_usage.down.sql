@@ -0,0 +1 @@
+DROP TABLE public.token_quota_usage;
@@ -0,0 +1,8 @@
+CREATE TABLE public.token_quota_usage (
+	lsp_nodeid bytea NOT NULL,
+	token_key varchar NOT NULL,
+	day date NOT NULL,
+	opens bigint NOT NULL,
+	capacity_sat bigint NOT NULL,
+	CONSTRAINT token_quota_usage_pkey PRIMARY KEY (lsp_nodeid, token_key, day)
+);
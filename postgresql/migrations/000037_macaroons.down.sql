@@ -0,0 +1,2 @@
+DROP TABLE public.macaroons;
+DROP TABLE public.macaroon_root_keys;
@@ -0,0 +1,17 @@
+CREATE TABLE public.macaroon_root_keys (
+	lsp_nodeid bytea NOT NULL,
+	root_key bytea NOT NULL,
+	created_at bigint NOT NULL,
+	CONSTRAINT macaroon_root_keys_pkey PRIMARY KEY (lsp_nodeid)
+);
+
+CREATE TABLE public.macaroons (
+	id bytea NOT NULL,
+	lsp_nodeid bytea NOT NULL,
+	token varchar NOT NULL,
+	name varchar NOT NULL,
+	caveats varchar[] NOT NULL,
+	created_at bigint NOT NULL,
+	revoked_at bigint NULL,
+	CONSTRAINT macaroons_pkey PRIMARY KEY (id)
+);
@@ -0,0 +1 @@
+ALTER TABLE public.htlc_audit_log DROP COLUMN failure_reason;
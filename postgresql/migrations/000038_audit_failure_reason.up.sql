@@ -0,0 +1 @@
+ALTER TABLE public.htlc_audit_log ADD COLUMN failure_reason varchar NULL;
@@ -0,0 +1,13 @@
+DELETE FROM public.notification_subscriptions WHERE tenant_id <> '';
+DROP INDEX public.notification_subscriptions_tenant_id_pubkey_idx;
+DROP INDEX public.notification_subscriptions_tenant_id_pubkey_url_key;
+ALTER TABLE public.notification_subscriptions DROP COLUMN tenant_id;
+CREATE INDEX notification_subscriptions_pubkey_idx ON public.notification_subscriptions (pubkey);
+CREATE UNIQUE INDEX notification_subscriptions_pubkey_url_key ON public.notification_subscriptions (pubkey, url);
+
+DELETE FROM public.notification_device_tokens WHERE tenant_id <> '';
+DROP INDEX public.notification_device_tokens_tenant_id_pubkey_idx;
+DROP INDEX public.notification_device_tokens_tenant_id_platform_token_key;
+ALTER TABLE public.notification_device_tokens DROP COLUMN tenant_id;
+CREATE INDEX notification_device_tokens_pubkey_idx ON public.notification_device_tokens (pubkey);
+CREATE UNIQUE INDEX notification_device_tokens_platform_token_key ON public.notification_device_tokens (platform, token);
@@ -0,0 +1,11 @@
+ALTER TABLE public.notification_subscriptions ADD tenant_id varchar NOT NULL DEFAULT '';
+DROP INDEX public.notification_subscriptions_pubkey_idx;
+DROP INDEX public.notification_subscriptions_pubkey_url_key;
+CREATE INDEX notification_subscriptions_tenant_id_pubkey_idx ON public.notification_subscriptions (tenant_id, pubkey);
+CREATE UNIQUE INDEX notification_subscriptions_tenant_id_pubkey_url_key ON public.notification_subscriptions (tenant_id, pubkey, url);
+
+ALTER TABLE public.notification_device_tokens ADD tenant_id varchar NOT NULL DEFAULT '';
+DROP INDEX public.notification_device_tokens_pubkey_idx;
+DROP INDEX public.notification_device_tokens_platform_token_key;
+CREATE INDEX notification_device_tokens_tenant_id_pubkey_idx ON public.notification_device_tokens (tenant_id, pubkey);
+CREATE UNIQUE INDEX notification_device_tokens_tenant_id_platform_token_key ON public.notification_device_tokens (tenant_id, platform, token);
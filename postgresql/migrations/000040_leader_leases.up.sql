@@ -0,0 +1,5 @@
+CREATE TABLE public.leader_leases (
+	name varchar PRIMARY KEY,
+	holder varchar NOT NULL,
+	expires_at bigint NOT NULL
+);
@@ -0,0 +1,3 @@
+DROP TABLE public.lease_renewals;
+ALTER TABLE public.channels DROP COLUMN lease_notified_at;
+ALTER TABLE public.channels DROP COLUMN lease_expires_at;
@@ -0,0 +1,12 @@
+ALTER TABLE public.channels ADD COLUMN lease_expires_at bigint NULL;
+ALTER TABLE public.channels ADD COLUMN lease_notified_at bigint NULL;
+CREATE TABLE public.lease_renewals (
+	payment_hash bytea NOT NULL,
+	lsp_nodeid bytea NOT NULL,
+	channel_point varchar NOT NULL,
+	fee_msat bigint NOT NULL,
+	created_at bigint NOT NULL,
+	renewed_at bigint NULL,
+	CONSTRAINT lease_renewals_pkey PRIMARY KEY (payment_hash)
+);
+CREATE INDEX lease_renewals_channel_point_idx ON public.lease_renewals (channel_point);
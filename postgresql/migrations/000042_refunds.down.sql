@@ -0,0 +1,2 @@
+DROP TABLE public.refund_credits;
+DROP TABLE public.refunds;
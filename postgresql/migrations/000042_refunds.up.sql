@@ -0,0 +1,19 @@
+CREATE TABLE public.refunds (
+	payment_hash bytea NOT NULL,
+	lsp_nodeid bytea NOT NULL,
+	destination bytea NOT NULL,
+	channel_point varchar NOT NULL,
+	fee_msat bigint NOT NULL,
+	remaining_msat bigint NOT NULL,
+	created_at bigint NOT NULL,
+	CONSTRAINT refunds_pkey PRIMARY KEY (payment_hash)
+);
+CREATE INDEX refunds_destination_idx ON public.refunds (lsp_nodeid, destination);
+
+CREATE TABLE public.refund_credits (
+	payment_hash bytea NOT NULL,
+	refund_payment_hash bytea NOT NULL,
+	amount_msat bigint NOT NULL,
+	created_at bigint NOT NULL,
+	CONSTRAINT refund_credits_pkey PRIMARY KEY (payment_hash, refund_payment_hash)
+);
@@ -0,0 +1,7 @@
+ALTER TABLE public.channel_open_jobs ADD COLUMN taproot boolean NOT NULL DEFAULT false;
+UPDATE public.channel_open_jobs SET taproot = true WHERE commitment_type = 'taproot';
+ALTER TABLE public.channel_open_jobs ALTER COLUMN taproot DROP DEFAULT;
+ALTER TABLE public.channel_open_jobs DROP COLUMN commitment_type;
+ALTER TABLE public.channels DROP COLUMN commitment_type;
+ALTER TABLE public.payments DROP COLUMN commitment_type;
+ALTER TABLE public.api_tokens DROP COLUMN commitment_type;
@@ -0,0 +1,6 @@
+ALTER TABLE public.api_tokens ADD COLUMN commitment_type varchar NULL;
+ALTER TABLE public.payments ADD COLUMN commitment_type varchar NULL;
+ALTER TABLE public.channels ADD COLUMN commitment_type varchar NULL;
+ALTER TABLE public.channel_open_jobs ADD COLUMN commitment_type varchar NULL;
+UPDATE public.channel_open_jobs SET commitment_type = 'taproot' WHERE taproot;
+ALTER TABLE public.channel_open_jobs DROP COLUMN taproot;
@@ -0,0 +1,2 @@
+ALTER TABLE public.channels DROP COLUMN double_spent_at;
+ALTER TABLE public.channels DROP COLUMN double_spent_by;
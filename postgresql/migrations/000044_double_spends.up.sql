@@ -0,0 +1,2 @@
+ALTER TABLE public.channels ADD COLUMN double_spent_by varchar NULL;
+ALTER TABLE public.channels ADD COLUMN double_spent_at bigint NULL;
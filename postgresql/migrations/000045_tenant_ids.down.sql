@@ -0,0 +1,28 @@
+DELETE FROM public.payment_nonces WHERE tenant_id <> '';
+ALTER TABLE public.payment_nonces DROP CONSTRAINT payment_nonces_pkey;
+ALTER TABLE public.payment_nonces ADD CONSTRAINT payment_nonces_pkey PRIMARY KEY (destination, nonce);
+
+ALTER TABLE public.refund_credits DROP COLUMN tenant_id;
+ALTER TABLE public.refunds DROP COLUMN tenant_id;
+ALTER TABLE public.lease_renewals DROP COLUMN tenant_id;
+ALTER TABLE public.token_quota_usage DROP COLUMN tenant_id;
+ALTER TABLE public.client_agents DROP COLUMN tenant_id;
+ALTER TABLE public.peer_score_events DROP COLUMN tenant_id;
+ALTER TABLE public.peer_address_hints DROP COLUMN tenant_id;
+ALTER TABLE public.peer_states DROP COLUMN tenant_id;
+ALTER TABLE public.htlc_audit_log DROP COLUMN tenant_id;
+ALTER TABLE public.htlc_exposure DROP COLUMN tenant_id;
+ALTER TABLE public.htlc_resolutions DROP COLUMN tenant_id;
+ALTER TABLE public.forwarding_history DROP COLUMN tenant_id;
+ALTER TABLE public.channel_open_jobs DROP COLUMN tenant_id;
+ALTER TABLE public.channel_aliases DROP COLUMN tenant_id;
+ALTER TABLE public.channels DROP COLUMN tenant_id;
+ALTER TABLE public.fee_credits DROP COLUMN tenant_id;
+ALTER TABLE public.htlc_fees DROP COLUMN tenant_id;
+ALTER TABLE public.payment_preimages DROP COLUMN tenant_id;
+ALTER TABLE public.payment_nonces DROP COLUMN tenant_id;
+ALTER TABLE public.payments DROP COLUMN tenant_id;
+ALTER TABLE public.macaroons DROP COLUMN tenant_id;
+ALTER TABLE public.macaroon_root_keys DROP COLUMN tenant_id;
+ALTER TABLE public.new_channel_params DROP COLUMN tenant_id;
+ALTER TABLE public.api_tokens DROP COLUMN tenant_id;
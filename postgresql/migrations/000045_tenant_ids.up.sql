@@ -0,0 +1,27 @@
+ALTER TABLE public.api_tokens ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.new_channel_params ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.macaroon_root_keys ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.macaroons ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.payments ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.payment_nonces ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.payment_preimages ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.htlc_fees ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.fee_credits ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.channels ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.channel_aliases ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.channel_open_jobs ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.forwarding_history ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.htlc_resolutions ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.htlc_exposure ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.htlc_audit_log ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.peer_states ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.peer_address_hints ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.peer_score_events ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.client_agents ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.token_quota_usage ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.lease_renewals ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.refunds ADD tenant_id varchar NOT NULL DEFAULT '';
+ALTER TABLE public.refund_credits ADD tenant_id varchar NOT NULL DEFAULT '';
+
+ALTER TABLE public.payment_nonces DROP CONSTRAINT payment_nonces_pkey;
+ALTER TABLE public.payment_nonces ADD CONSTRAINT payment_nonces_pkey PRIMARY KEY (tenant_id, destination, nonce);
@@ -5,9 +5,13 @@ import (
 	"encoding/hex"
 	"time"
 
+	"github.com/breez/lspd/notifications"
+	"github.com/breez/lspd/tenant"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+// NotificationsStore keeps the notification subscriptions of the tenant of
+// the context.
 type NotificationsStore struct {
 	pool *pgxpool.Pool
 }
@@ -29,9 +33,10 @@ func (s *NotificationsStore) Register(
 	now := time.Now().UnixMicro()
 	_, err = s.pool.Exec(
 		ctx,
-		`INSERT INTO public.notification_subscriptions (pubkey, url, created_at, refreshed_at)
-		 values ($1, $2, $3, $4)
-		 ON CONFLICT (pubkey, url) DO UPDATE SET refreshed_at = $4`,
+		`INSERT INTO public.notification_subscriptions (tenant_id, pubkey, url, created_at, refreshed_at)
+		 values ($1, $2, $3, $4, $5)
+		 ON CONFLICT (tenant_id, pubkey, url) DO UPDATE SET refreshed_at = $5`,
+		tenant.FromContext(ctx),
 		pk,
 		url,
 		now,
@@ -54,7 +59,8 @@ func (s *NotificationsStore) GetRegistrations(
 		ctx,
 		`SELECT url 
 		 FROM public.notification_subscriptions
-		 WHERE pubkey = $1`,
+		 WHERE tenant_id = $1 AND pubkey = $2`,
+		tenant.FromContext(ctx),
 		pk,
 	)
 	if err != nil {
@@ -74,3 +80,91 @@ func (s *NotificationsStore) GetRegistrations(
 
 	return result, nil
 }
+
+// RegisterDeviceToken stores the device token for the node. A token that was
+// registered for another node of the tenant before moves to this node, since
+// the device runs a new node now.
+func (s *NotificationsStore) RegisterDeviceToken(
+	ctx context.Context,
+	pubkey string,
+	token *notifications.DeviceToken,
+) error {
+	pk, err := hex.DecodeString(pubkey)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixMicro()
+	_, err = s.pool.Exec(
+		ctx,
+		`INSERT INTO public.notification_device_tokens (tenant_id, pubkey, platform, token, created_at, refreshed_at)
+		 values ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (tenant_id, platform, token) DO UPDATE SET pubkey = $2, refreshed_at = $6`,
+		tenant.FromContext(ctx),
+		pk,
+		int32(token.Platform),
+		token.Token,
+		now,
+		now,
+	)
+
+	return err
+}
+
+func (s *NotificationsStore) GetDeviceTokens(
+	ctx context.Context,
+	pubkey string,
+) ([]*notifications.DeviceToken, error) {
+	pk, err := hex.DecodeString(pubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT platform, token
+		 FROM public.notification_device_tokens
+		 WHERE tenant_id = $1 AND pubkey = $2`,
+		tenant.FromContext(ctx),
+		pk,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*notifications.DeviceToken
+	for rows.Next() {
+		var platform int32
+		var token string
+		err = rows.Scan(&platform, &token)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &notifications.DeviceToken{
+			Platform: notifications.PushPlatform(platform),
+			Token:    token,
+		})
+	}
+
+	return result, rows.Err()
+}
+
+// RemoveDeviceToken removes a device token the push service reported as no
+// longer valid.
+func (s *NotificationsStore) RemoveDeviceToken(
+	ctx context.Context,
+	token *notifications.DeviceToken,
+) error {
+	_, err := s.pool.Exec(
+		ctx,
+		`DELETE FROM public.notification_device_tokens
+		 WHERE tenant_id = $1 AND platform = $2 AND token = $3`,
+		tenant.FromContext(ctx),
+		int32(token.Platform),
+		token.Token,
+	)
+
+	return err
+}
@@ -0,0 +1,84 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PeerStore stores whether the peers of the lsp nodes are connected, so the
+// time a client went offline survives restarts.
+type PeerStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPeerStore(pool *pgxpool.Pool) *PeerStore {
+	return &PeerStore{pool: pool}
+}
+
+// GetPeerStates returns the stored peer states of the node, keyed by the hex
+// encoded peer id.
+func (s *PeerStore) GetPeerStates(ctx context.Context, lspNodeID []byte) (map[string]*lightning.PeerState, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT peer_id, connected, updated_at, address
+		 FROM peer_states
+		 WHERE lsp_nodeid = $1 AND tenant_id = $2`,
+		lspNodeID, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetPeerStates(%x) error: %w", lspNodeID, err)
+	}
+	defer rows.Close()
+
+	states := make(map[string]*lightning.PeerState)
+	for rows.Next() {
+		var peerID []byte
+		var connected bool
+		var updatedAt int64
+		var address *string
+		err = rows.Scan(&peerID, &connected, &updatedAt, &address)
+		if err != nil {
+			return nil, fmt.Errorf("GetPeerStates(%x) scan error: %w", lspNodeID, err)
+		}
+
+		state := &lightning.PeerState{
+			Connected: connected,
+			Since:     time.UnixMicro(updatedAt),
+		}
+		if address != nil {
+			state.Address = *address
+		}
+		states[hex.EncodeToString(peerID)] = state
+	}
+
+	return states, rows.Err()
+}
+
+// SetPeerState stores the state of the peer, unless a more recent state is
+// stored already.
+func (s *PeerStore) SetPeerState(ctx context.Context, lspNodeID []byte, peerID []byte, state *lightning.PeerState) error {
+	var address *string
+	if state.Address != "" {
+		address = &state.Address
+	}
+
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO peer_states (lsp_nodeid, peer_id, connected, updated_at, address, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (lsp_nodeid, peer_id) DO UPDATE
+		 SET connected = EXCLUDED.connected, updated_at = EXCLUDED.updated_at,
+		     address = COALESCE(EXCLUDED.address, peer_states.address)
+		 WHERE peer_states.updated_at <= EXCLUDED.updated_at`,
+		lspNodeID, peerID, state.Connected, state.Since.UnixMicro(), address, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("SetPeerState(%x, %x) error: %w", lspNodeID, peerID, err)
+	}
+
+	return nil
+}
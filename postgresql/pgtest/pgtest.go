@@ -0,0 +1,148 @@
+// Package pgtest provides throwaway postgres databases for tests of the store
+// layer. Every database runs in its own docker container, with the lspd
+// migrations applied, and is removed when the test finishes.
+package pgtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/postgresql"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	image    = "postgres:15"
+	password = "pgpassword"
+	pgPort   = nat.Port("5432/tcp")
+)
+
+// NewDatabase starts a postgres container and returns a connection pool to
+// its migrated database. The test is skipped if docker is not available.
+func NewDatabase(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Skipf("docker not available: %v", err)
+	}
+	t.Cleanup(func() { cli.Close() })
+
+	_, err = cli.Ping(ctx)
+	if err != nil {
+		t.Skipf("docker not available: %v", err)
+	}
+
+	err = pullImage(ctx, cli)
+	if err != nil {
+		t.Fatalf("failed to pull %s: %v", image, err)
+	}
+
+	createResp, err := cli.ContainerCreate(ctx, &container.Config{
+		Image: image,
+		Env: []string{
+			"POSTGRES_DB=postgres",
+			"POSTGRES_PASSWORD=" + password,
+			"POSTGRES_USER=postgres",
+		},
+		ExposedPorts: nat.PortSet{pgPort: struct{}{}},
+	}, &container.HostConfig{
+		// Let docker pick a free port on the host.
+		PortBindings: nat.PortMap{
+			pgPort: []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: ""}},
+		},
+		// The data is thrown away with the container.
+		Tmpfs: map[string]string{"/var/lib/postgresql/data": "rw"},
+	}, nil, nil, "")
+	if err != nil {
+		t.Fatalf("failed to create postgres container: %v", err)
+	}
+
+	id := createResp.ID
+	t.Cleanup(func() {
+		err := cli.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{
+			Force:         true,
+			RemoveVolumes: true,
+		})
+		if err != nil {
+			t.Logf("failed to remove postgres container %s: %v", id, err)
+		}
+	})
+
+	err = cli.ContainerStart(ctx, id, types.ContainerStartOptions{})
+	if err != nil {
+		t.Fatalf("failed to start postgres container: %v", err)
+	}
+
+	inspect, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		t.Fatalf("failed to inspect postgres container: %v", err)
+	}
+	bindings := inspect.NetworkSettings.Ports[pgPort]
+	if len(bindings) == 0 {
+		t.Fatalf("postgres container has no port binding")
+	}
+
+	url := fmt.Sprintf("postgres://postgres:%s@127.0.0.1:%s/postgres", password, bindings[0].HostPort)
+	pool, err := connect(ctx, url)
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	err = postgresql.Migrate(ctx, pool)
+	if err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	return pool
+}
+
+func pullImage(ctx context.Context, cli *client.Client) error {
+	_, _, err := cli.ImageInspectWithRaw(ctx, image)
+	if err == nil {
+		return nil
+	}
+	if !client.IsErrNotFound(err) {
+		return err
+	}
+
+	r, err := cli.ImagePull(ctx, image, types.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = io.Copy(io.Discard, r)
+	return err
+}
+
+// connect waits for postgres to accept connections. The server only listens
+// on tcp once the database is initialized.
+func connect(ctx context.Context, url string) (*pgxpool.Pool, error) {
+	for {
+		pool, err := pgxpool.Connect(ctx, url)
+		if err == nil {
+			err = pool.Ping(ctx)
+			if err == nil {
+				return pool, nil
+			}
+			pool.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("postgres not ready: %w", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
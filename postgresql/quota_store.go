@@ -0,0 +1,53 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/dbcrypt"
+	"github.com/breez/lspd/quota"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// QuotaStore stores the daily usage of the quotas of the tokens.
+type QuotaStore struct {
+	pool   *pgxpool.Pool
+	cipher *dbcrypt.Cipher
+}
+
+func NewQuotaStore(pool *pgxpool.Pool, cipher *dbcrypt.Cipher) *QuotaStore {
+	return &QuotaStore{pool: pool, cipher: cipher}
+}
+
+func (s *QuotaStore) GetUsage(ctx context.Context, lspNodeID []byte, key string, since time.Time) (*quota.Usage, error) {
+	var usage quota.Usage
+	err := s.pool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(opens), 0)::bigint, COALESCE(SUM(capacity_sat), 0)::bigint
+		 FROM token_quota_usage
+		 WHERE lsp_nodeid = $1 AND token_key = ANY($2) AND day >= $3::date AND tenant_id = $4`,
+		lspNodeID, s.cipher.StringCandidates(key), since.UTC().Format("2006-01-02"), tenant.FromContext(ctx),
+	).Scan(&usage.Opens, &usage.CapacitySat)
+	if err != nil {
+		return nil, fmt.Errorf("GetUsage(%x, %s) error: %w", lspNodeID, key, err)
+	}
+
+	return &usage, nil
+}
+
+func (s *QuotaStore) AddUsage(ctx context.Context, lspNodeID []byte, key string, day time.Time, usage *quota.Usage) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO token_quota_usage (lsp_nodeid, token_key, day, opens, capacity_sat, tenant_id)
+		 VALUES ($1, $2, $3::date, $4, $5, $6)
+		 ON CONFLICT (lsp_nodeid, token_key, day) DO UPDATE
+		 SET opens = token_quota_usage.opens + EXCLUDED.opens,
+		     capacity_sat = token_quota_usage.capacity_sat + EXCLUDED.capacity_sat`,
+		lspNodeID, s.cipher.EncryptString(key), day.UTC().Format("2006-01-02"), usage.Opens, usage.CapacitySat, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("AddUsage(%x, %s) error: %w", lspNodeID, key, err)
+	}
+
+	return nil
+}
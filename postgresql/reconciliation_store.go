@@ -0,0 +1,142 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/dbcrypt"
+	"github.com/breez/lspd/reconcile"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ReconciliationStore reads the channels opened and payments intercepted by
+// lspd for the reconciler.
+type ReconciliationStore struct {
+	pool   *pgxpool.Pool
+	cipher *dbcrypt.Cipher
+}
+
+func NewReconciliationStore(pool *pgxpool.Pool, cipher *dbcrypt.Cipher) *ReconciliationStore {
+	return &ReconciliationStore{pool: pool, cipher: cipher}
+}
+
+func (s *ReconciliationStore) ListChannels(ctx context.Context, lspNodeID []byte) ([]*reconcile.Channel, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT channel_point, nodeid, initial_chanid, confirmed_chanid, opened_at
+		 FROM channels
+		 WHERE lsp_nodeid = $1 AND tenant_id = $2`,
+		lspNodeID, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListChannels(%x) error: %w", lspNodeID, err)
+	}
+	defer rows.Close()
+
+	var channels []*reconcile.Channel
+	for rows.Next() {
+		var c reconcile.Channel
+		var initialChanID int64
+		var confirmedChanID, openedAt *int64
+		err = rows.Scan(&c.ChannelPoint, &c.PeerID, &initialChanID, &confirmedChanID, &openedAt)
+		if err != nil {
+			return nil, fmt.Errorf("ListChannels(%x) scan error: %w", lspNodeID, err)
+		}
+
+		c.InitialChanID = basetypes.ShortChannelID(uint64(initialChanID))
+		if confirmedChanID != nil {
+			c.ConfirmedChanID = basetypes.ShortChannelID(uint64(*confirmedChanID))
+		}
+		if openedAt != nil {
+			c.OpenedAt = time.UnixMicro(*openedAt)
+		}
+		channels = append(channels, &c)
+	}
+
+	return channels, rows.Err()
+}
+
+func (s *ReconciliationStore) ListOpenedPayments(ctx context.Context, lspNodeID []byte, channelPoints []string) ([]*reconcile.Payment, error) {
+	var txids [][]byte
+	for _, cp := range channelPoints {
+		outpoint, err := basetypes.ParseOutPoint(cp)
+		if err != nil {
+			log.Printf("ListOpenedPayments: invalid channel point %s: %v", cp, err)
+			continue
+		}
+
+		txids = append(txids, outpoint.Hash[:])
+	}
+
+	// Refund credits forwarded with a payment lower the fee it promised.
+	rows, err := s.pool.Query(ctx,
+		`SELECT p.payment_hash, p.destination, p.opening_fee_params, p.funding_tx_id, p.funding_tx_outnum,
+		        p.incoming_amount_msat,
+		        p.outgoing_amount_msat + coalesce((SELECT sum(c.amount_msat) FROM refund_credits c WHERE c.payment_hash = p.payment_hash AND c.tenant_id = p.tenant_id), 0)::bigint,
+		        count(h.id), coalesce(sum(h.amount_in_msat - h.amount_out_msat), 0)::bigint
+		 FROM payments p
+		 LEFT JOIN htlc_fees h ON h.payment_hash = p.payment_hash AND h.tenant_id = p.tenant_id
+		 WHERE p.lsp_nodeid = $1 AND p.tenant_id = $3 AND p.funding_tx_id = ANY($2)
+		   AND NOT EXISTS (SELECT 1 FROM lease_renewals r WHERE r.payment_hash = p.payment_hash AND r.tenant_id = p.tenant_id)
+		 GROUP BY p.payment_hash`,
+		lspNodeID, txids, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListOpenedPayments(%x) error: %w", lspNodeID, err)
+	}
+	defer rows.Close()
+
+	var payments []*reconcile.Payment
+	for rows.Next() {
+		var p reconcile.Payment
+		var params *string
+		var fundingTxID []byte
+		var fundingTxOutnum pgtype.Int4
+		err = rows.Scan(
+			&p.PaymentHash,
+			&p.Destination,
+			&params,
+			&fundingTxID,
+			&fundingTxOutnum,
+			&p.IncomingAmountMsat,
+			&p.OutgoingAmountMsat,
+			&p.HtlcCount,
+			&p.DeductedFeeMsat,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ListOpenedPayments(%x) scan error: %w", lspNodeID, err)
+		}
+
+		cp, err := basetypes.NewOutPoint(fundingTxID, uint32(fundingTxOutnum.Int))
+		if err != nil {
+			continue
+		}
+		p.ChannelPoint = cp.String()
+
+		p.Destination, err = decryptBytes(s.cipher, p.Destination)
+		if err != nil {
+			return nil, fmt.Errorf("ListOpenedPayments(%x) error: %w", lspNodeID, err)
+		}
+
+		if params != nil && *params != "" {
+			var extParams extendedParams
+			err = json.Unmarshal([]byte(*params), &extParams)
+			if err != nil {
+				log.Printf("ListOpenedPayments: failed to unmarshal opening fee params of %x: %v", p.PaymentHash, err)
+			}
+			p.Token, err = decryptString(s.cipher, extParams.Token)
+			if err != nil {
+				return nil, fmt.Errorf("ListOpenedPayments(%x) error: %w", lspNodeID, err)
+			}
+		}
+
+		payments = append(payments, &p)
+	}
+
+	return payments, rows.Err()
+}
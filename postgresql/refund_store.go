@@ -0,0 +1,161 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/dbcrypt"
+	"github.com/breez/lspd/refunds"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// RefundStore stores the refunds of the opening fees of failed channel opens,
+// and the credit applied from them to later payments.
+type RefundStore struct {
+	pool   *pgxpool.Pool
+	cipher *dbcrypt.Cipher
+}
+
+func NewRefundStore(pool *pgxpool.Pool, cipher *dbcrypt.Cipher) *RefundStore {
+	return &RefundStore{pool: pool, cipher: cipher}
+}
+
+func (s *RefundStore) AddRefunds(ctx context.Context, lspNodeID []byte, channelPoint string, createdAt time.Time) ([]*refunds.Refund, error) {
+	outpoint, err := basetypes.ParseOutPoint(channelPoint)
+	if err != nil {
+		return nil, fmt.Errorf("AddRefunds(%s) error: %w", channelPoint, err)
+	}
+
+	rows, err := s.pool.Query(ctx,
+		`INSERT INTO refunds (payment_hash, lsp_nodeid, destination, channel_point, fee_msat, remaining_msat, created_at, tenant_id)
+		 SELECT p.payment_hash, p.lsp_nodeid, p.destination, $4, f.fee_msat, f.fee_msat, $5, p.tenant_id
+		 FROM payments p
+		 INNER JOIN (
+		   SELECT payment_hash, sum(amount_in_msat - amount_out_msat)::bigint AS fee_msat
+		   FROM htlc_fees
+		   WHERE tenant_id = $6
+		   GROUP BY payment_hash
+		 ) f ON f.payment_hash = p.payment_hash
+		 WHERE p.lsp_nodeid = $1 AND p.tenant_id = $6 AND p.funding_tx_id = $2 AND p.funding_tx_outnum = $3 AND f.fee_msat > 0
+		 ON CONFLICT (payment_hash) DO NOTHING
+		 RETURNING payment_hash, destination, fee_msat`,
+		lspNodeID, outpoint.Hash[:], int32(outpoint.Index), channelPoint, createdAt.UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("AddRefunds(%s) error: %w", channelPoint, err)
+	}
+	defer rows.Close()
+
+	var result []*refunds.Refund
+	for rows.Next() {
+		var feeMsat int64
+		r := &refunds.Refund{ChannelPoint: channelPoint, CreatedAt: createdAt}
+		err = rows.Scan(&r.PaymentHash, &r.Destination, &feeMsat)
+		if err != nil {
+			return nil, fmt.Errorf("AddRefunds(%s) scan error: %w", channelPoint, err)
+		}
+
+		r.Destination, err = decryptBytes(s.cipher, r.Destination)
+		if err != nil {
+			return nil, fmt.Errorf("AddRefunds(%s) error: %w", channelPoint, err)
+		}
+
+		r.FeeMsat = uint64(feeMsat)
+		r.RemainingMsat = r.FeeMsat
+		result = append(result, r)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *RefundStore) ApplyRefunds(ctx context.Context, lspNodeID []byte, destination []byte, paymentHash []byte, maxMsat uint64) (uint64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("pgxPool.Begin() error: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Htlcs of the same payment get the credit applied the first time.
+	tenantID := tenant.FromContext(ctx)
+	var applied int64
+	err = tx.QueryRow(ctx,
+		`SELECT coalesce(sum(amount_msat), 0)::bigint FROM refund_credits WHERE payment_hash = $1 AND tenant_id = $2`,
+		paymentHash, tenantID,
+	).Scan(&applied)
+	if err != nil {
+		return 0, fmt.Errorf("ApplyRefunds(%x) error: %w", paymentHash, err)
+	}
+	if applied > 0 || maxMsat == 0 {
+		return uint64(applied), nil
+	}
+
+	rows, err := tx.Query(ctx,
+		`SELECT payment_hash, remaining_msat
+		 FROM refunds
+		 WHERE lsp_nodeid = $1 AND tenant_id = $3 AND destination = ANY($2) AND remaining_msat > 0
+		 ORDER BY created_at
+		 FOR UPDATE`,
+		lspNodeID, s.cipher.Candidates(destination), tenantID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("ApplyRefunds(%x) select error: %w", paymentHash, err)
+	}
+
+	type remaining struct {
+		paymentHash []byte
+		msat        int64
+	}
+	var owed []remaining
+	for rows.Next() {
+		var r remaining
+		err = rows.Scan(&r.paymentHash, &r.msat)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("ApplyRefunds(%x) scan error: %w", paymentHash, err)
+		}
+		owed = append(owed, r)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("ApplyRefunds(%x) select error: %w", paymentHash, err)
+	}
+
+	now := time.Now().UnixMicro()
+	left := int64(maxMsat)
+	for _, r := range owed {
+		if left == 0 {
+			break
+		}
+
+		amount := r.msat
+		if amount > left {
+			amount = left
+		}
+		_, err = tx.Exec(ctx,
+			`UPDATE refunds SET remaining_msat = remaining_msat - $2 WHERE payment_hash = $1 AND tenant_id = $3`,
+			r.paymentHash, amount, tenantID)
+		if err != nil {
+			return 0, fmt.Errorf("ApplyRefunds(%x) update error: %w", paymentHash, err)
+		}
+
+		_, err = tx.Exec(ctx,
+			`INSERT INTO refund_credits (payment_hash, refund_payment_hash, amount_msat, created_at, tenant_id)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			paymentHash, r.paymentHash, amount, now, tenantID)
+		if err != nil {
+			return 0, fmt.Errorf("ApplyRefunds(%x) insert error: %w", paymentHash, err)
+		}
+
+		left -= amount
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("ApplyRefunds(%x) commit error: %w", paymentHash, err)
+	}
+
+	return maxMsat - uint64(left), nil
+}
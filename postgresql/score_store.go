@@ -0,0 +1,77 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/scoring"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ScoreStore stores the events lowering the scores of clients.
+type ScoreStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewScoreStore(pool *pgxpool.Pool) *ScoreStore {
+	return &ScoreStore{pool: pool}
+}
+
+func (s *ScoreStore) AddEvent(ctx context.Context, lspNodeID []byte, e *scoring.Event) (bool, error) {
+	tag, err := s.pool.Exec(ctx,
+		`INSERT INTO peer_score_events (lsp_nodeid, peer_id, kind, ref, penalty, created_at, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (lsp_nodeid, peer_id, kind, ref) DO NOTHING`,
+		lspNodeID, e.PeerID, e.Kind, e.Ref, e.Penalty, e.CreatedAt.UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return false, fmt.Errorf("AddEvent(%x, %s, %s) error: %w", e.PeerID, e.Kind, e.Ref, err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *ScoreStore) ListPenalties(ctx context.Context, lspNodeID []byte, peerID []byte, since time.Time) ([]*scoring.Penalty, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT peer_id, kind, COUNT(*), SUM(penalty), MAX(created_at)
+		 FROM peer_score_events
+		 WHERE lsp_nodeid = $1 AND tenant_id = $4 AND ($2::bytea IS NULL OR peer_id = $2) AND created_at >= $3 AND NOT reset
+		 GROUP BY peer_id, kind`,
+		lspNodeID, peerID, since.UnixMicro(), tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListPenalties(%x, %x) error: %w", lspNodeID, peerID, err)
+	}
+	defer rows.Close()
+
+	var penalties []*scoring.Penalty
+	for rows.Next() {
+		var p scoring.Penalty
+		var lastEventAt int64
+		err = rows.Scan(&p.PeerID, &p.Kind, &p.Count, &p.Total, &lastEventAt)
+		if err != nil {
+			return nil, fmt.Errorf("ListPenalties(%x, %x) error: %w", lspNodeID, peerID, err)
+		}
+
+		p.LastEventAt = time.UnixMicro(lastEventAt)
+		penalties = append(penalties, &p)
+	}
+
+	return penalties, rows.Err()
+}
+
+func (s *ScoreStore) Reset(ctx context.Context, lspNodeID []byte, peerID []byte) error {
+	_, err := s.pool.Exec(ctx,
+		`UPDATE peer_score_events
+		 SET reset = true
+		 WHERE lsp_nodeid = $1 AND peer_id = $2 AND tenant_id = $3 AND NOT reset`,
+		lspNodeID, peerID, tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("Reset(%x, %x) error: %w", lspNodeID, peerID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/dbcrypt"
+	"github.com/breez/lspd/tenant"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type ForwardingStats struct {
+	Count      int64 `json:"count"`
+	VolumeMsat int64 `json:"volumeMsat"`
+	FeesMsat   int64 `json:"feesMsat"`
+}
+
+// StatsStore aggregates operational statistics for the admin api.
+type StatsStore struct {
+	pool   *pgxpool.Pool
+	cipher *dbcrypt.Cipher
+}
+
+func NewStatsStore(pool *pgxpool.Pool, cipher *dbcrypt.Cipher) *StatsStore {
+	return &StatsStore{pool: pool, cipher: cipher}
+}
+
+// ForwardingStats returns the number, volume and fees of the forwards of the
+// node since the given time.
+func (s *StatsStore) ForwardingStats(ctx context.Context, lspNodeID []byte, since time.Time) (*ForwardingStats, error) {
+	var stats ForwardingStats
+	err := s.pool.QueryRow(ctx,
+		`SELECT count(*), coalesce(sum(amt_msat_out), 0)::bigint, coalesce(sum(amt_msat_in - amt_msat_out), 0)::bigint
+		 FROM forwarding_history
+		 WHERE lsp_nodeid = $1 AND tenant_id = $2 AND "timestamp" >= $3`,
+		lspNodeID, tenant.FromContext(ctx), since.UnixNano(),
+	).Scan(&stats.Count, &stats.VolumeMsat, &stats.FeesMsat)
+	if err != nil {
+		return nil, fmt.Errorf("ForwardingStats(%x) error: %w", lspNodeID, err)
+	}
+
+	return &stats, nil
+}
+
+// UnconfirmedChannelCount returns the number of channels opened by the node
+// that are not confirmed yet.
+func (s *StatsStore) UnconfirmedChannelCount(ctx context.Context, lspNodeID []byte) (int64, error) {
+	var count int64
+	err := s.pool.QueryRow(ctx,
+		`SELECT count(*)
+		 FROM channels
+		 WHERE lsp_nodeid = $1 AND tenant_id = $2 AND confirmed_chanid IS NULL`,
+		lspNodeID, tenant.FromContext(ctx),
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("UnconfirmedChannelCount(%x) error: %w", lspNodeID, err)
+	}
+
+	return count, nil
+}
+
+type FeeCredit struct {
+	PaymentHash     string    `json:"paymentHash"`
+	Destination     string    `json:"destination"`
+	PromisedFeeMsat int64     `json:"promisedFeeMsat"`
+	DeductedFeeMsat int64     `json:"deductedFeeMsat"`
+	CreditMsat      int64     `json:"creditMsat"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+type FeeRefund struct {
+	PaymentHash   string    `json:"paymentHash"`
+	Destination   string    `json:"destination"`
+	ChannelPoint  string    `json:"channelPoint"`
+	FeeMsat       int64     `json:"feeMsat"`
+	RemainingMsat int64     `json:"remainingMsat"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type FeeReport struct {
+	HtlcCount       int64        `json:"htlcCount"`
+	ReceivedMsat    int64        `json:"receivedMsat"`
+	DeductedFeeMsat int64        `json:"deductedFeeMsat"`
+	CreditMsat      int64        `json:"creditMsat"`
+	Credits         []*FeeCredit `json:"credits"`
+	RefundedMsat    int64        `json:"refundedMsat"`
+	Refunds         []*FeeRefund `json:"refunds"`
+}
+
+// FeeReport returns the fees deducted from the htlcs forwarded for registered
+// payments since the given time, the credits of the clients from whose
+// payments more than the promised fee was deducted, and the fees refunded
+// for failed channel opens.
+func (s *StatsStore) FeeReport(ctx context.Context, lspNodeID []byte, since time.Time) (*FeeReport, error) {
+	report := &FeeReport{Credits: []*FeeCredit{}, Refunds: []*FeeRefund{}}
+	err := s.pool.QueryRow(ctx,
+		`SELECT count(*), coalesce(sum(amount_in_msat), 0)::bigint, coalesce(sum(amount_in_msat - amount_out_msat), 0)::bigint
+		 FROM htlc_fees
+		 WHERE lsp_nodeid = $1 AND tenant_id = $2 AND created_at >= $3`,
+		lspNodeID, tenant.FromContext(ctx), since.UnixMicro(),
+	).Scan(&report.HtlcCount, &report.ReceivedMsat, &report.DeductedFeeMsat)
+	if err != nil {
+		return nil, fmt.Errorf("FeeReport(%x) error: %w", lspNodeID, err)
+	}
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT payment_hash, destination, promised_fee_msat, deducted_fee_msat, credit_msat, updated_at
+		 FROM fee_credits
+		 WHERE lsp_nodeid = $1 AND tenant_id = $2 AND updated_at >= $3
+		 ORDER BY updated_at DESC`,
+		lspNodeID, tenant.FromContext(ctx), since.UnixMicro(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("FeeReport(%x) credits error: %w", lspNodeID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var paymentHash, destination []byte
+		var updatedAt int64
+		c := &FeeCredit{}
+		err = rows.Scan(&paymentHash, &destination, &c.PromisedFeeMsat, &c.DeductedFeeMsat, &c.CreditMsat, &updatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("FeeReport(%x) scan error: %w", lspNodeID, err)
+		}
+
+		destination, err = decryptBytes(s.cipher, destination)
+		if err != nil {
+			return nil, fmt.Errorf("FeeReport(%x) error: %w", lspNodeID, err)
+		}
+
+		c.PaymentHash = hex.EncodeToString(paymentHash)
+		c.Destination = hex.EncodeToString(destination)
+		c.UpdatedAt = time.UnixMicro(updatedAt).UTC()
+		report.CreditMsat += c.CreditMsat
+		report.Credits = append(report.Credits, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("FeeReport(%x) credits error: %w", lspNodeID, err)
+	}
+
+	err = s.feeRefunds(ctx, lspNodeID, since, report)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (s *StatsStore) feeRefunds(ctx context.Context, lspNodeID []byte, since time.Time, report *FeeReport) error {
+	rows, err := s.pool.Query(ctx,
+		`SELECT payment_hash, destination, channel_point, fee_msat, remaining_msat, created_at
+		 FROM refunds
+		 WHERE lsp_nodeid = $1 AND tenant_id = $2 AND created_at >= $3
+		 ORDER BY created_at DESC`,
+		lspNodeID, tenant.FromContext(ctx), since.UnixMicro(),
+	)
+	if err != nil {
+		return fmt.Errorf("FeeReport(%x) refunds error: %w", lspNodeID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var paymentHash, destination []byte
+		var createdAt int64
+		r := &FeeRefund{}
+		err = rows.Scan(&paymentHash, &destination, &r.ChannelPoint, &r.FeeMsat, &r.RemainingMsat, &createdAt)
+		if err != nil {
+			return fmt.Errorf("FeeReport(%x) scan error: %w", lspNodeID, err)
+		}
+
+		destination, err = decryptBytes(s.cipher, destination)
+		if err != nil {
+			return fmt.Errorf("FeeReport(%x) error: %w", lspNodeID, err)
+		}
+
+		r.PaymentHash = hex.EncodeToString(paymentHash)
+		r.Destination = hex.EncodeToString(destination)
+		r.CreatedAt = time.UnixMicro(createdAt).UTC()
+		report.RefundedMsat += r.FeeMsat
+		report.Refunds = append(report.Refunds, r)
+	}
+
+	return rows.Err()
+}
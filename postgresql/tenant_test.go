@@ -0,0 +1,88 @@
+package postgresql_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/postgresql"
+	"github.com/breez/lspd/postgresql/pgtest"
+	"github.com/breez/lspd/tenant"
+	"github.com/breez/lspd/tokens"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantPayments(t *testing.T) {
+	store := postgresql.NewPostgresInterceptStore(pgtest.NewDatabase(t), nil)
+	acme := tenant.NewContext(context.Background(), "acme")
+	other := tenant.NewContext(context.Background(), "other")
+	paymentHash := bytes.Repeat([]byte{0x01}, 32)
+	paymentSecret := bytes.Repeat([]byte{0x04}, 32)
+
+	err := store.RegisterPayment(acme, "token", lspNodeID, testParams(), destination, paymentHash, paymentSecret, 10000000, 8000000, "", "", "")
+	assert.NoError(t, err)
+
+	_, _, hash, _, _, _, _, _, _, err := store.PaymentInfo(acme, paymentHash)
+	assert.NoError(t, err)
+	assert.Equal(t, paymentHash, hash)
+
+	_, _, hash, _, _, _, _, _, _, err = store.PaymentInfo(other, paymentHash)
+	assert.NoError(t, err)
+	assert.Nil(t, hash)
+
+	// Another tenant can't take over or cancel the registration.
+	err = store.RegisterPayment(other, "token", lspNodeID, testParams(), destination, paymentHash, paymentSecret, 20000000, 18000000, "", "", "")
+	assert.NoError(t, err)
+	cancelled, err := store.CancelPayment(other, paymentHash, destination)
+	assert.NoError(t, err)
+	assert.False(t, cancelled)
+	_, _, _, _, _, incoming, _, _, _, err := store.PaymentInfo(acme, paymentHash)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10000000), incoming)
+
+	// Nonces are only spent within the tenant.
+	nonce := bytes.Repeat([]byte{0x01}, 16)
+	for _, ctx := range []context.Context{acme, other} {
+		unused, err := store.RegisterNonce(ctx, destination, nonce)
+		assert.NoError(t, err)
+		assert.True(t, unused)
+	}
+}
+
+func TestTenantTokens(t *testing.T) {
+	store := postgresql.NewTokenStore(pgtest.NewDatabase(t), nil)
+	acme := tenant.NewContext(context.Background(), "acme")
+	other := tenant.NewContext(context.Background(), "other")
+
+	err := store.Create(acme, &tokens.Token{
+		Token:     "acme-token",
+		LspNodeID: lspNodeID,
+		Name:      "acme",
+		CreatedAt: time.Now(),
+	}, []*tokens.FeeParams{{Validity: time.Hour, MinMsat: 2000000}})
+	assert.NoError(t, err)
+
+	token, err := store.Get(acme, "acme-token")
+	assert.NoError(t, err)
+	assert.NotNil(t, token)
+
+	token, err = store.Get(other, "acme-token")
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+
+	ts, err := store.List(other)
+	assert.NoError(t, err)
+	assert.Empty(t, ts)
+
+	params, err := store.GetFeeParams(other, "acme-token")
+	assert.NoError(t, err)
+	assert.Empty(t, params)
+
+	// Another tenant can't rotate or disable the token.
+	assert.ErrorIs(t, store.Rotate(other, "acme-token", "other-token", time.Now()), tokens.ErrNotFound)
+	assert.ErrorIs(t, store.Disable(other, "acme-token", time.Now()), tokens.ErrNotFound)
+	token, err = store.Get(acme, "acme-token")
+	assert.NoError(t, err)
+	assert.True(t, token.Active())
+}
@@ -0,0 +1,307 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/breez/lspd/dbcrypt"
+	"github.com/breez/lspd/tenant"
+	"github.com/breez/lspd/tokens"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// feeParams is the format of the params column in new_channel_params.
+type feeParams struct {
+	MinMsat              uint64 `json:"min_msat,string"`
+	Proportional         uint32 `json:"proportional"`
+	MaxIdleTime          uint32 `json:"max_idle_time"`
+	MaxClientToSelfDelay uint32 `json:"max_client_to_self_delay"`
+}
+
+type TokenStore struct {
+	pool   *pgxpool.Pool
+	cipher *dbcrypt.Cipher
+}
+
+func NewTokenStore(pool *pgxpool.Pool, cipher *dbcrypt.Cipher) *TokenStore {
+	return &TokenStore{pool: pool, cipher: cipher}
+}
+
+const tokenColumns = `token, lsp_nodeid, name, additional_channel_capacity, time_lock_delta, taproot_channels, public_channels, commitment_type, created_at, disabled_at`
+
+func scanToken(cipher *dbcrypt.Cipher, row pgx.Row) (*tokens.Token, error) {
+	var (
+		t                         tokens.Token
+		additionalChannelCapacity pgtype.Int8
+		timeLockDelta             pgtype.Int8
+		taprootChannels           pgtype.Bool
+		publicChannels            pgtype.Bool
+		commitmentType            pgtype.Varchar
+		createdAt                 int64
+		disabledAt                pgtype.Int8
+	)
+	err := row.Scan(&t.Token, &t.LspNodeID, &t.Name, &additionalChannelCapacity, &timeLockDelta, &taprootChannels, &publicChannels, &commitmentType, &createdAt, &disabledAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if additionalChannelCapacity.Status == pgtype.Present {
+		t.AdditionalChannelCapacity = &additionalChannelCapacity.Int
+	}
+	if timeLockDelta.Status == pgtype.Present {
+		d := uint32(timeLockDelta.Int)
+		t.TimeLockDelta = &d
+	}
+	if taprootChannels.Status == pgtype.Present {
+		t.TaprootChannels = &taprootChannels.Bool
+	}
+	if publicChannels.Status == pgtype.Present {
+		t.PublicChannels = &publicChannels.Bool
+	}
+	if commitmentType.Status == pgtype.Present {
+		t.CommitmentType = &commitmentType.String
+	}
+	t.Token, err = decryptString(cipher, t.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	t.CreatedAt = time.UnixMicro(createdAt).UTC()
+	if disabledAt.Status == pgtype.Present {
+		d := time.UnixMicro(disabledAt.Int).UTC()
+		t.DisabledAt = &d
+	}
+
+	return &t, nil
+}
+
+func (s *TokenStore) Get(ctx context.Context, token string) (*tokens.Token, error) {
+	row := s.pool.QueryRow(
+		ctx,
+		`SELECT `+tokenColumns+`
+		 FROM public.api_tokens
+		 WHERE token = ANY($1) AND tenant_id = $2`,
+		s.cipher.StringCandidates(token),
+		tenant.FromContext(ctx),
+	)
+	t, err := scanToken(s.cipher, row)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanToken() error: %w", err)
+	}
+
+	return t, nil
+}
+
+func (s *TokenStore) List(ctx context.Context) ([]*tokens.Token, error) {
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT `+tokenColumns+`
+		 FROM public.api_tokens
+		 WHERE tenant_id = $1
+		 ORDER BY created_at`,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*tokens.Token
+	for rows.Next() {
+		t, err := scanToken(s.cipher, rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanToken() error: %w", err)
+		}
+
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *TokenStore) GetFeeParams(ctx context.Context, token string) ([]*tokens.FeeParams, error) {
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT validity, params
+		 FROM public.new_channel_params
+		 WHERE token = ANY($1) AND tenant_id = $2
+		 ORDER BY validity`,
+		s.cipher.StringCandidates(token),
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*tokens.FeeParams
+	for rows.Next() {
+		var validity int64
+		var param string
+		err = rows.Scan(&validity, &param)
+		if err != nil {
+			return nil, err
+		}
+
+		var p feeParams
+		err = json.Unmarshal([]byte(param), &p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal fee param '%v': %w", param, err)
+		}
+
+		result = append(result, &tokens.FeeParams{
+			Validity:             time.Second * time.Duration(validity),
+			MinMsat:              p.MinMsat,
+			Proportional:         p.Proportional,
+			MaxIdleTime:          p.MaxIdleTime,
+			MaxClientToSelfDelay: p.MaxClientToSelfDelay,
+		})
+	}
+
+	return result, rows.Err()
+}
+
+func (s *TokenStore) Create(ctx context.Context, t *tokens.Token, params []*tokens.FeeParams) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("pgxPool.Begin() error: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var disabledAt *int64
+	if t.DisabledAt != nil {
+		d := t.DisabledAt.UnixMicro()
+		disabledAt = &d
+	}
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO public.api_tokens (`+tokenColumns+`, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		s.cipher.EncryptString(t.Token),
+		t.LspNodeID,
+		t.Name,
+		t.AdditionalChannelCapacity,
+		t.TimeLockDelta,
+		t.TaprootChannels,
+		t.PublicChannels,
+		t.CommitmentType,
+		t.CreatedAt.UnixMicro(),
+		disabledAt,
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("INSERT INTO api_tokens error: %w", err)
+	}
+
+	for _, p := range params {
+		param, err := json.Marshal(&feeParams{
+			MinMsat:              p.MinMsat,
+			Proportional:         p.Proportional,
+			MaxIdleTime:          p.MaxIdleTime,
+			MaxClientToSelfDelay: p.MaxClientToSelfDelay,
+		})
+		if err != nil {
+			return fmt.Errorf("json.Marshal() error: %w", err)
+		}
+
+		_, err = tx.Exec(
+			ctx,
+			`INSERT INTO public.new_channel_params (token, validity, params, tenant_id)
+			 VALUES ($1, $2, $3::jsonb, $4)`,
+			s.cipher.EncryptString(t.Token),
+			int64(p.Validity/time.Second),
+			string(param),
+			tenant.FromContext(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("INSERT INTO new_channel_params error: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *TokenStore) Rotate(ctx context.Context, oldToken string, newToken string, disableAt time.Time) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("pgxPool.Begin() error: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	tenantID := tenant.FromContext(ctx)
+	cmdTag, err := tx.Exec(
+		ctx,
+		`INSERT INTO public.api_tokens (`+tokenColumns+`, tenant_id)
+		 SELECT $2, lsp_nodeid, name, additional_channel_capacity, time_lock_delta, taproot_channels, public_channels, commitment_type, $3, NULL, tenant_id
+		 FROM public.api_tokens
+		 WHERE token = ANY($1) AND tenant_id = $4 AND (disabled_at IS NULL OR disabled_at > $3)`,
+		s.cipher.StringCandidates(oldToken),
+		s.cipher.EncryptString(newToken),
+		now.UnixMicro(),
+		tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("INSERT INTO api_tokens error: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return tokens.ErrNotFound
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO public.new_channel_params (token, validity, params, tenant_id)
+		 SELECT $2, validity, params, tenant_id
+		 FROM public.new_channel_params
+		 WHERE token = ANY($1) AND tenant_id = $3`,
+		s.cipher.StringCandidates(oldToken),
+		s.cipher.EncryptString(newToken),
+		tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("INSERT INTO new_channel_params error: %w", err)
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE public.api_tokens
+		 SET disabled_at = $2
+		 WHERE token = ANY($1) AND tenant_id = $3`,
+		s.cipher.StringCandidates(oldToken),
+		disableAt.UnixMicro(),
+		tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("UPDATE api_tokens error: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *TokenStore) Disable(ctx context.Context, token string, at time.Time) error {
+	cmdTag, err := s.pool.Exec(
+		ctx,
+		`UPDATE public.api_tokens
+		 SET disabled_at = LEAST(COALESCE(disabled_at, $2), $2)
+		 WHERE token = ANY($1) AND tenant_id = $3`,
+		s.cipher.StringCandidates(token),
+		at.UnixMicro(),
+		tenant.FromContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("UPDATE api_tokens error: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return tokens.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/breez/lspd/notifications"
+)
+
+// newPushers creates the push notification services configured in the
+// environment.
+func newPushers() ([]notifications.Pusher, error) {
+	var pushers []notifications.Pusher
+	if f := os.Getenv("FCM_SERVICE_ACCOUNT_FILE"); f != "" {
+		fcm, err := notifications.NewFcmPusher(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize fcm: %w", err)
+		}
+
+		log.Printf("Sending push notifications with fcm")
+		pushers = append(pushers, fcm)
+	}
+
+	if f := os.Getenv("APNS_KEY_FILE"); f != "" {
+		apns, err := notifications.NewApnsPusher(&notifications.ApnsConfig{
+			KeyFile: f,
+			KeyID:   os.Getenv("APNS_KEY_ID"),
+			TeamID:  os.Getenv("APNS_TEAM_ID"),
+			Topic:   os.Getenv("APNS_TOPIC"),
+			Sandbox: os.Getenv("APNS_SANDBOX") == "true",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize apns: %w", err)
+		}
+
+		log.Printf("Sending push notifications with apns")
+		pushers = append(pushers, apns)
+	}
+
+	if k := os.Getenv("NOSTR_PRIVATE_KEY"); k != "" {
+		var relays []string
+		if r := os.Getenv("NOSTR_RELAYS"); r != "" {
+			relays = strings.Split(r, ",")
+		}
+
+		nostr, err := notifications.NewNostrPusher(&notifications.NostrConfig{
+			PrivateKey: k,
+			Relays:     relays,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize nostr: %w", err)
+		}
+
+		log.Printf("Sending push notifications with nostr")
+		pushers = append(pushers, nostr)
+	}
+
+	return pushers, nil
+}
@@ -0,0 +1,142 @@
+// Package quota limits the channels opened per day and per week for every
+// token, so a single partner can't spend the funds of the lsp on channels.
+package quota
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/breez/lspd/tokens"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Weekly quotas count the usage of this many days, including today.
+const weekDays = 7
+
+var exceededTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_quota_exceeded_total",
+	Help: "Channel opens denied because the quota of the token was exceeded.",
+}, []string{"node"})
+
+// Usage is the number and total capacity of the channels opened for a token.
+type Usage struct {
+	Opens       int64
+	CapacitySat int64
+}
+
+type Store interface {
+	// GetUsage returns the usage of the token recorded for the days since
+	// the given day, inclusive.
+	GetUsage(ctx context.Context, lspNodeID []byte, key string, since time.Time) (*Usage, error)
+	// AddUsage adds to the usage of the token on the day. The usage may be
+	// negative, to release a reservation.
+	AddUsage(ctx context.Context, lspNodeID []byte, key string, day time.Time, usage *Usage) error
+}
+
+// Reservation is the usage reserved for a channel open, released if the
+// channel is not opened after all.
+type Reservation struct {
+	key         string
+	day         time.Time
+	capacitySat int64
+}
+
+// Limiter enforces the quotas of the tokens of a node. The usage is recorded
+// per day in the database, so restarts don't reset it.
+type Limiter struct {
+	store     Store
+	node      *config.NodeConfig
+	logger    *log.Logger
+	lspNodeID []byte
+	mtx       sync.Mutex
+}
+
+func NewLimiter(store Store, node *config.NodeConfig, logger *log.Logger) *Limiter {
+	lspNodeID, _ := hex.DecodeString(node.NodePubkey)
+	return &Limiter{
+		store:     store,
+		node:      node,
+		logger:    logger,
+		lspNodeID: lspNodeID,
+	}
+}
+
+// Reserve reserves the usage of a channel of capacitySat opened for token t.
+// Returns lsperrors.ErrQuotaExceeded if the channel would exceed the quota of
+// the token. Returns a nil reservation if the token has no quota. Safe to
+// call on a nil limiter.
+func (l *Limiter) Reserve(ctx context.Context, t *tokens.Token, token string, capacitySat int64) (*Reservation, error) {
+	if l == nil {
+		return nil, nil
+	}
+
+	cfg, key := tokens.Quota(t, token, l.node)
+	if cfg == nil {
+		return nil, nil
+	}
+
+	// Reservations are made one at a time, so concurrent opens can't
+	// exceed the quota together.
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	daily, err := l.store.GetUsage(ctx, l.lspNodeID, key, today)
+	if err != nil {
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, err)
+	}
+	weekly, err := l.store.GetUsage(ctx, l.lspNodeID, key, today.AddDate(0, 0, 1-weekDays))
+	if err != nil {
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, err)
+	}
+
+	if reason := exceeded(cfg, daily, weekly, capacitySat); reason != "" {
+		l.logger.Printf("quota: channel of %v sat for token %s exceeds the %s quota", capacitySat, key, reason)
+		exceededTotal.WithLabelValues(l.node.Label()).Inc()
+		return nil, fmt.Errorf("%w: %s", lsperrors.ErrQuotaExceeded, reason)
+	}
+
+	err = l.store.AddUsage(ctx, l.lspNodeID, key, today, &Usage{Opens: 1, CapacitySat: capacitySat})
+	if err != nil {
+		return nil, lsperrors.Wrap(lsperrors.ErrInternal, err)
+	}
+
+	return &Reservation{key: key, day: today, capacitySat: capacitySat}, nil
+}
+
+// exceeded returns the quota a channel of capacitySat would exceed, or empty
+// if it's within the quota.
+func exceeded(cfg *config.QuotaConfig, daily *Usage, weekly *Usage, capacitySat int64) string {
+	switch {
+	case cfg.DailyOpens > 0 && daily.Opens+1 > cfg.DailyOpens:
+		return "daily opens"
+	case cfg.WeeklyOpens > 0 && weekly.Opens+1 > cfg.WeeklyOpens:
+		return "weekly opens"
+	case cfg.DailySat > 0 && daily.CapacitySat+capacitySat > cfg.DailySat:
+		return "daily capacity"
+	case cfg.WeeklySat > 0 && weekly.CapacitySat+capacitySat > cfg.WeeklySat:
+		return "weekly capacity"
+	default:
+		return ""
+	}
+}
+
+// Release releases the reservation, when the channel was not opened. Safe to
+// call with a nil reservation.
+func (l *Limiter) Release(ctx context.Context, r *Reservation) {
+	if l == nil || r == nil {
+		return
+	}
+
+	err := l.store.AddUsage(ctx, l.lspNodeID, r.key, r.day, &Usage{Opens: -1, CapacitySat: -r.capacitySat})
+	if err != nil {
+		l.logger.Printf("quota: failed to release the reservation of %v sat for token %s: %v", r.capacitySat, r.key, err)
+	}
+}
@@ -0,0 +1,89 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/breez/lspd/tokens"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockStore struct {
+	usage map[string]map[time.Time]*Usage
+}
+
+func (s *mockStore) GetUsage(ctx context.Context, lspNodeID []byte, key string, since time.Time) (*Usage, error) {
+	total := &Usage{}
+	for day, u := range s.usage[key] {
+		if !day.Before(since) {
+			total.Opens += u.Opens
+			total.CapacitySat += u.CapacitySat
+		}
+	}
+
+	return total, nil
+}
+
+func (s *mockStore) AddUsage(ctx context.Context, lspNodeID []byte, key string, day time.Time, usage *Usage) error {
+	if s.usage[key] == nil {
+		s.usage[key] = make(map[time.Time]*Usage)
+	}
+	u, ok := s.usage[key][day]
+	if !ok {
+		u = &Usage{}
+		s.usage[key][day] = u
+	}
+
+	u.Opens += usage.Opens
+	u.CapacitySat += usage.CapacitySat
+	return nil
+}
+
+func TestLimiter(t *testing.T) {
+	store := &mockStore{usage: make(map[string]map[time.Time]*Usage)}
+	node := &config.NodeConfig{
+		Quotas: map[string]*config.QuotaConfig{
+			"partner": {DailyOpens: 2, WeeklySat: 250_000},
+		},
+	}
+	l := NewLimiter(store, node, log.New(os.Stderr, "", 0))
+	ctx := context.Background()
+	partner := &tokens.Token{Name: "partner"}
+
+	// Tokens without a quota are not limited.
+	r, err := l.Reserve(ctx, nil, "other", 1_000_000)
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+
+	r1, err := l.Reserve(ctx, partner, "token1", 100_000)
+	assert.NoError(t, err)
+	_, err = l.Reserve(ctx, partner, "token2", 200_000)
+	assert.True(t, errors.Is(err, lsperrors.ErrQuotaExceeded))
+
+	// Usage older than a week doesn't count.
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	store.AddUsage(ctx, nil, "partner", today.AddDate(0, 0, -7), &Usage{Opens: 5, CapacitySat: 1_000_000})
+	_, err = l.Reserve(ctx, partner, "token1", 100_000)
+	assert.NoError(t, err)
+	_, err = l.Reserve(ctx, partner, "token1", 1)
+	assert.True(t, errors.Is(err, lsperrors.ErrQuotaExceeded))
+
+	// Released reservations don't count.
+	l.Release(ctx, r1)
+	_, err = l.Reserve(ctx, partner, "token1", 10_000)
+	assert.NoError(t, err)
+
+	// Usage of earlier days of the week counts towards the weekly quota.
+	l.Release(ctx, &Reservation{key: "partner", day: today, capacitySat: 10_000})
+	store.AddUsage(ctx, nil, "partner", today.AddDate(0, 0, -3), &Usage{Opens: 1, CapacitySat: 100_000})
+	_, err = l.Reserve(ctx, partner, "token1", 60_000)
+	assert.True(t, errors.Is(err, lsperrors.ErrQuotaExceeded))
+	_, err = l.Reserve(ctx, partner, "token1", 50_000)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,558 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/events"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultInterval     = time.Hour
+	defaultLookback     = 7 * 24 * time.Hour
+	defaultSettleWindow = time.Hour
+
+	// Forwards of a payment may happen shortly before the channel is
+	// recorded as opened, when the htlcs are resumed right after the open.
+	openedAtSlack = 10 * time.Minute
+)
+
+const (
+	// The forwards of the payment add up to the registered amount and the
+	// promised fee was collected.
+	StatusSettled = "settled"
+	// Only some of the forwards of the payment were found.
+	StatusPartial = "partial"
+	// No forwards of the payment were found.
+	StatusUnsettled = "unsettled"
+	// Less than the promised fee was collected from the forwards of the
+	// payment.
+	StatusMissingFee = "missing_fee"
+)
+
+var (
+	reconciledPayments = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lspd_reconciled_payments",
+		Help: "Payments lspd opened a channel for within the reconciliation lookback, by reconciliation status.",
+	}, []string{"node", "status"})
+	missingFeeMsat = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lspd_reconciliation_missing_fee_msat",
+		Help: "Promised fees not collected from the forwards of payments within the reconciliation lookback.",
+	}, []string{"node"})
+)
+
+// Channel is a channel opened by lspd.
+type Channel struct {
+	ChannelPoint    string
+	PeerID          []byte
+	InitialChanID   basetypes.ShortChannelID
+	ConfirmedChanID basetypes.ShortChannelID
+	OpenedAt        time.Time
+}
+
+// Payment is a registered payment a channel was opened for, with the fees
+// deducted from its htlcs by the interceptor.
+type Payment struct {
+	PaymentHash        []byte
+	Destination        []byte
+	Token              string
+	ChannelPoint       string
+	IncomingAmountMsat int64
+	OutgoingAmountMsat int64
+	HtlcCount          int64
+	DeductedFeeMsat    int64
+}
+
+type Store interface {
+	// ListChannels returns the channels opened by lspd on the node.
+	ListChannels(ctx context.Context, lspNodeID []byte) ([]*Channel, error)
+	// ListOpenedPayments returns the registered payments the channels with
+	// the given channel points were opened for.
+	ListOpenedPayments(ctx context.Context, lspNodeID []byte, channelPoints []string) ([]*Payment, error)
+}
+
+// UnsettledListener is told about payments a channel was opened for that
+// didn't settle within the settle window.
+type UnsettledListener interface {
+	PaymentUnsettled(ctx context.Context, peerID []byte, paymentHash string)
+}
+
+// Revenue is the revenue from the forwards to the channels of a client, or
+// to the channels opened for the payments registered with a token.
+type Revenue struct {
+	Key           string `json:"key"`
+	ForwardCount  int64  `json:"forwardCount"`
+	ForwardedMsat int64  `json:"forwardedMsat"`
+	FeeMsat       int64  `json:"feeMsat"`
+}
+
+// PaymentResult is the reconciliation of a payment lspd opened a channel for
+// with the forwards of the node to that channel.
+type PaymentResult struct {
+	PaymentHash        string `json:"paymentHash"`
+	Destination        string `json:"destination"`
+	Token              string `json:"token"`
+	ChannelPoint       string `json:"channelPoint"`
+	Status             string `json:"status"`
+	IncomingAmountMsat int64  `json:"incomingAmountMsat"`
+	PromisedFeeMsat    int64  `json:"promisedFeeMsat"`
+	// Htlcs of the payment and the fee deducted from them as recorded by the
+	// interceptor.
+	InterceptedHtlcCount int64 `json:"interceptedHtlcCount"`
+	InterceptedFeeMsat   int64 `json:"interceptedFeeMsat"`
+	ForwardCount         int64 `json:"forwardCount"`
+	ForwardedMsat        int64 `json:"forwardedMsat"`
+	// Fee collected from the forwards of the payment as reported by the node.
+	CollectedFeeMsat int64 `json:"collectedFeeMsat"`
+	// Share of the promised fee of the amount forwarded.
+	ExpectedFeeMsat int64 `json:"expectedFeeMsat"`
+	MissingFeeMsat  int64 `json:"missingFeeMsat"`
+}
+
+// Report is the result of the last reconciliation of a node.
+type Report struct {
+	Node          string           `json:"node"`
+	NodePubkey    string           `json:"nodePubkey"`
+	Since         time.Time        `json:"since"`
+	UpdatedAt     time.Time        `json:"updatedAt"`
+	ForwardCount  int64            `json:"forwardCount"`
+	RevenueMsat   int64            `json:"revenueMsat"`
+	StatusCounts  map[string]int64 `json:"statusCounts"`
+	ByToken       []*Revenue       `json:"byToken"`
+	ByClient      []*Revenue       `json:"byClient"`
+	Discrepancies []*PaymentResult `json:"discrepancies"`
+
+	settled []*PaymentResult
+}
+
+// Reconciler periodically cross-references the forwards reported by the node
+// with the channels opened and htlcs intercepted by lspd. It attributes the
+// fees of forwards to lspd channels to the client and to the token the
+// channel was opened for, and finds payments that settled without the fee
+// promised at registration, like when htlcs were resumed without
+// interception.
+type Reconciler struct {
+	client       lightning.Client
+	store        Store
+	node         *config.NodeConfig
+	logger       *log.Logger
+	interval     time.Duration
+	lookback     time.Duration
+	settleWindow time.Duration
+	listeners    []UnsettledListener
+	bus          *events.Bus
+	published    map[string]time.Time
+	ctx          context.Context
+	cancel       context.CancelFunc
+	mtx          sync.Mutex
+	report       *Report
+}
+
+func NewReconciler(
+	client lightning.Client,
+	store Store,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) *Reconciler {
+	r := &Reconciler{
+		client:       client,
+		store:        store,
+		node:         node,
+		logger:       logger,
+		interval:     defaultInterval,
+		lookback:     defaultLookback,
+		settleWindow: defaultSettleWindow,
+		published:    make(map[string]time.Time),
+	}
+
+	cfg := node.Reconciliation
+	if cfg != nil {
+		r.interval = parseDuration(cfg.Interval, defaultInterval, logger)
+		r.lookback = parseDuration(cfg.Lookback, defaultLookback, logger)
+		r.settleWindow = parseDuration(cfg.SettleWindow, defaultSettleWindow, logger)
+	}
+
+	return r
+}
+
+func parseDuration(s string, def time.Duration, logger *log.Logger) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		logger.Printf("WARN: Invalid reconciliation duration '%s'. Using default %v", s, def)
+		return def
+	}
+
+	return d
+}
+
+func (r *Reconciler) Node() *config.NodeConfig {
+	return r.node
+}
+
+// AddListener adds a listener that is told about unsettled payments. Not
+// safe to call after Start.
+func (r *Reconciler) AddListener(l UnsettledListener) {
+	r.listeners = append(r.listeners, l)
+}
+
+// SetEventBus sets the bus settled payments are published on.
+func (r *Reconciler) SetEventBus(bus *events.Bus) {
+	r.bus = bus
+}
+
+// Start reconciles periodically. Returns immediately if reconciliation is
+// not configured for the node.
+func (r *Reconciler) Start() error {
+	if r.node.Reconciliation == nil {
+		return nil
+	}
+
+	r.mtx.Lock()
+	r.ctx, r.cancel = context.WithCancel(tenant.NewContext(context.Background(), r.node.Tenant))
+	ctx := r.ctx
+	r.mtx.Unlock()
+
+	r.logger.Printf("reconciliation: reconciling forwards of the last %v every %v", r.lookback, r.interval)
+	for {
+		err := r.Reconcile(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Printf("reconciliation failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+func (r *Reconciler) Stop() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// Report returns the result of the last reconciliation, or nil if there was
+// none yet.
+func (r *Reconciler) Report() *Report {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.report
+}
+
+// Reconcile reconciles the forwards of the lookback period once.
+func (r *Reconciler) Reconcile(ctx context.Context) error {
+	now := time.Now()
+	since := now.Add(-r.lookback)
+	lspNodeID, err := hex.DecodeString(r.node.NodePubkey)
+	if err != nil {
+		return err
+	}
+
+	channels, err := r.store.ListChannels(ctx, lspNodeID)
+	if err != nil {
+		return err
+	}
+
+	var channelPoints []string
+	for _, c := range channels {
+		if !c.OpenedAt.Before(since) {
+			channelPoints = append(channelPoints, c.ChannelPoint)
+		}
+	}
+
+	var payments []*Payment
+	if len(channelPoints) > 0 {
+		payments, err = r.store.ListOpenedPayments(ctx, lspNodeID, channelPoints)
+		if err != nil {
+			return err
+		}
+	}
+
+	forwards, err := r.client.ListForwards(ctx, since.Add(-openedAtSlack))
+	if err != nil {
+		return err
+	}
+
+	report := reconcile(channels, payments, forwards, since, r.settleWindow)
+	report.Node = r.node.Name
+	report.NodePubkey = r.node.NodePubkey
+	report.UpdatedAt = now
+	for _, d := range report.Discrepancies {
+		if d.Status == StatusMissingFee {
+			r.logger.Printf("reconciliation: payment %s to %s forwarded over channel %s without the promised fee: expected %d msat, collected %d msat",
+				d.PaymentHash, d.Destination, d.ChannelPoint, d.ExpectedFeeMsat, d.CollectedFeeMsat)
+		}
+	}
+
+	r.notifyUnsettled(ctx, channels, report.Discrepancies, now)
+	r.publishSettled(channels, report.settled, since)
+
+	label := r.node.Label()
+	var missing int64
+	for _, d := range report.Discrepancies {
+		missing += d.MissingFeeMsat
+	}
+	missingFeeMsat.WithLabelValues(label).Set(float64(missing))
+	for _, status := range []string{StatusSettled, StatusPartial, StatusUnsettled, StatusMissingFee} {
+		reconciledPayments.WithLabelValues(label, status).Set(float64(report.StatusCounts[status]))
+	}
+
+	r.mtx.Lock()
+	r.report = report
+	r.mtx.Unlock()
+	return nil
+}
+
+// notifyUnsettled tells the listeners about the unsettled payments whose
+// settle window passed. Payments of channels opened more recently may still
+// settle.
+func (r *Reconciler) notifyUnsettled(ctx context.Context, channels []*Channel, results []*PaymentResult, now time.Time) {
+	if len(r.listeners) == 0 {
+		return
+	}
+
+	openedAt := make(map[string]time.Time)
+	for _, c := range channels {
+		openedAt[c.ChannelPoint] = c.OpenedAt
+	}
+
+	for _, result := range results {
+		if result.Status != StatusUnsettled {
+			continue
+		}
+
+		opened, ok := openedAt[result.ChannelPoint]
+		if !ok || now.Sub(opened) < r.settleWindow {
+			continue
+		}
+
+		peerID, err := hex.DecodeString(result.Destination)
+		if err != nil {
+			continue
+		}
+
+		for _, l := range r.listeners {
+			l.PaymentUnsettled(ctx, peerID, result.PaymentHash)
+		}
+	}
+}
+
+// publishSettled publishes the payments that settled since the last
+// reconciliation. Payments are published again after a restart, as long as
+// they are within the lookback.
+func (r *Reconciler) publishSettled(channels []*Channel, results []*PaymentResult, since time.Time) {
+	for paymentHash, openedAt := range r.published {
+		if openedAt.Before(since) {
+			delete(r.published, paymentHash)
+		}
+	}
+
+	openedAt := make(map[string]time.Time)
+	for _, c := range channels {
+		openedAt[c.ChannelPoint] = c.OpenedAt
+	}
+
+	for _, result := range results {
+		if _, ok := r.published[result.PaymentHash]; ok {
+			continue
+		}
+		r.published[result.PaymentHash] = openedAt[result.ChannelPoint]
+
+		peerID, _ := hex.DecodeString(result.Destination)
+		paymentHash, _ := hex.DecodeString(result.PaymentHash)
+		r.bus.Publish(&events.Event{
+			Kind:         events.PaymentSettled,
+			Node:         r.node,
+			PeerID:       peerID,
+			PaymentHash:  paymentHash,
+			ChannelPoint: result.ChannelPoint,
+			AmountMsat:   result.IncomingAmountMsat,
+		})
+	}
+}
+
+// reconcile attributes the forwards since the given time to the channels
+// opened by lspd, and matches the forwards within the settle window after a
+// channel was opened with the payment it was opened for.
+func reconcile(
+	channels []*Channel,
+	payments []*Payment,
+	forwards []*lightning.Forward,
+	since time.Time,
+	settleWindow time.Duration,
+) *Report {
+	sort.Slice(forwards, func(i, j int) bool {
+		return forwards[i].Timestamp.Before(forwards[j].Timestamp)
+	})
+
+	byChanID := make(map[basetypes.ShortChannelID]*Channel)
+	byChannelPoint := make(map[string]*Channel)
+	for _, c := range channels {
+		byChannelPoint[c.ChannelPoint] = c
+		if c.InitialChanID != 0 {
+			byChanID[c.InitialChanID] = c
+		}
+		if c.ConfirmedChanID != 0 {
+			byChanID[c.ConfirmedChanID] = c
+		}
+	}
+
+	// The token of a channel is the token of the payment it was opened for.
+	tokens := make(map[string]string)
+	for _, p := range payments {
+		tokens[p.ChannelPoint] = p.Token
+	}
+
+	report := &Report{
+		Since:         since,
+		StatusCounts:  make(map[string]int64),
+		Discrepancies: []*PaymentResult{},
+	}
+	byToken := make(map[string]*Revenue)
+	byClient := make(map[string]*Revenue)
+	forwardsByChannel := make(map[string][]*lightning.Forward)
+	for _, f := range forwards {
+		c, ok := byChanID[f.ChanIDOut]
+		if !ok {
+			continue
+		}
+
+		forwardsByChannel[c.ChannelPoint] = append(forwardsByChannel[c.ChannelPoint], f)
+		if f.Timestamp.Before(since) {
+			continue
+		}
+
+		fee := int64(f.AmountInMsat) - int64(f.AmountOutMsat)
+		report.ForwardCount++
+		report.RevenueMsat += fee
+		addRevenue(byClient, hex.EncodeToString(c.PeerID), f, fee)
+		addRevenue(byToken, tokens[c.ChannelPoint], f, fee)
+	}
+
+	report.ByToken = sortedRevenue(byToken)
+	report.ByClient = sortedRevenue(byClient)
+	for _, p := range payments {
+		c, ok := byChannelPoint[p.ChannelPoint]
+		if !ok || c.OpenedAt.Before(since) {
+			continue
+		}
+
+		result := reconcilePayment(p, c, forwardsByChannel[p.ChannelPoint], settleWindow)
+		report.StatusCounts[result.Status]++
+		if result.Status == StatusSettled {
+			report.settled = append(report.settled, result)
+		} else {
+			report.Discrepancies = append(report.Discrepancies, result)
+		}
+	}
+
+	return report
+}
+
+func addRevenue(revenue map[string]*Revenue, key string, f *lightning.Forward, fee int64) {
+	r, ok := revenue[key]
+	if !ok {
+		r = &Revenue{Key: key}
+		revenue[key] = r
+	}
+
+	r.ForwardCount++
+	r.ForwardedMsat += int64(f.AmountOutMsat)
+	r.FeeMsat += fee
+}
+
+func sortedRevenue(revenue map[string]*Revenue) []*Revenue {
+	result := make([]*Revenue, 0, len(revenue))
+	for _, r := range revenue {
+		result = append(result, r)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].FeeMsat != result[j].FeeMsat {
+			return result[i].FeeMsat > result[j].FeeMsat
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// reconcilePayment matches the first forwards to the channel within the
+// settle window after it was opened with the payment, until they add up to
+// the registered incoming amount. The fee collected from them is compared
+// with the share of the promised fee of the amount forwarded, allowing for
+// rounding of 1 msat per forward.
+func reconcilePayment(p *Payment, c *Channel, forwards []*lightning.Forward, settleWindow time.Duration) *PaymentResult {
+	result := &PaymentResult{
+		PaymentHash:          hex.EncodeToString(p.PaymentHash),
+		Destination:          hex.EncodeToString(p.Destination),
+		Token:                p.Token,
+		ChannelPoint:         p.ChannelPoint,
+		IncomingAmountMsat:   p.IncomingAmountMsat,
+		PromisedFeeMsat:      p.IncomingAmountMsat - p.OutgoingAmountMsat,
+		InterceptedHtlcCount: p.HtlcCount,
+		InterceptedFeeMsat:   p.DeductedFeeMsat,
+	}
+
+	from := c.OpenedAt.Add(-openedAtSlack)
+	to := c.OpenedAt.Add(settleWindow)
+	for _, f := range forwards {
+		if f.Timestamp.Before(from) || f.Timestamp.After(to) {
+			continue
+		}
+		if result.ForwardedMsat >= p.IncomingAmountMsat {
+			break
+		}
+
+		result.ForwardCount++
+		result.ForwardedMsat += int64(f.AmountInMsat)
+		result.CollectedFeeMsat += int64(f.AmountInMsat) - int64(f.AmountOutMsat)
+	}
+
+	switch {
+	case result.ForwardCount == 0:
+		result.Status = StatusUnsettled
+		return result
+	case result.ForwardedMsat < p.IncomingAmountMsat:
+		result.Status = StatusPartial
+	default:
+		result.Status = StatusSettled
+	}
+
+	if p.IncomingAmountMsat > 0 {
+		forwarded := result.ForwardedMsat
+		if forwarded > p.IncomingAmountMsat {
+			forwarded = p.IncomingAmountMsat
+		}
+
+		var expected big.Int
+		expected.Mul(big.NewInt(result.PromisedFeeMsat), big.NewInt(forwarded))
+		expected.Div(&expected, big.NewInt(p.IncomingAmountMsat))
+		result.ExpectedFeeMsat = expected.Int64()
+	}
+
+	if missing := result.ExpectedFeeMsat - result.CollectedFeeMsat; missing > result.ForwardCount {
+		result.MissingFeeMsat = missing
+		result.Status = StatusMissingFee
+	}
+
+	return result
+}
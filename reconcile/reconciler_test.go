@@ -0,0 +1,81 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/lightning"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcile(t *testing.T) {
+	now := time.Now()
+	since := now.Add(-24 * time.Hour)
+	channels := []*Channel{
+		{ChannelPoint: "a:0", PeerID: []byte{1}, InitialChanID: 1, ConfirmedChanID: 11, OpenedAt: now.Add(-3 * time.Hour)},
+		{ChannelPoint: "b:0", PeerID: []byte{2}, InitialChanID: 2, OpenedAt: now.Add(-2 * time.Hour)},
+		{ChannelPoint: "c:0", PeerID: []byte{1}, InitialChanID: 3, OpenedAt: now.Add(-time.Hour)},
+	}
+	payments := []*Payment{
+		// Forwarded in two parts with the promised fee.
+		{PaymentHash: []byte{1}, Token: "t1", ChannelPoint: "a:0", IncomingAmountMsat: 100_000, OutgoingAmountMsat: 90_000, HtlcCount: 2, DeductedFeeMsat: 10_000},
+		// Forwarded without deducting the fee.
+		{PaymentHash: []byte{2}, Token: "t2", ChannelPoint: "b:0", IncomingAmountMsat: 100_000, OutgoingAmountMsat: 90_000},
+		// Never forwarded.
+		{PaymentHash: []byte{3}, Token: "t1", ChannelPoint: "c:0", IncomingAmountMsat: 100_000, OutgoingAmountMsat: 90_000},
+	}
+	forwards := []*lightning.Forward{
+		{Timestamp: now.Add(-3 * time.Hour), ChanIDIn: 100, ChanIDOut: 1, AmountInMsat: 50_001, AmountOutMsat: 45_000},
+		{Timestamp: now.Add(-3*time.Hour + time.Second), ChanIDIn: 100, ChanIDOut: 1, AmountInMsat: 49_999, AmountOutMsat: 45_000},
+		// A later routing fee over the confirmed channel id.
+		{Timestamp: now.Add(-time.Hour), ChanIDIn: 100, ChanIDOut: 11, AmountInMsat: 1_010, AmountOutMsat: 1_000},
+		{Timestamp: now.Add(-2 * time.Hour), ChanIDIn: 100, ChanIDOut: 2, AmountInMsat: 100_000, AmountOutMsat: 99_990},
+		// Not to a channel opened by lspd.
+		{Timestamp: now.Add(-2 * time.Hour), ChanIDIn: 100, ChanIDOut: basetypes.ShortChannelID(200), AmountInMsat: 1_000, AmountOutMsat: 999},
+	}
+
+	report := reconcile(channels, payments, forwards, since, time.Hour)
+	assert.Equal(t, int64(4), report.ForwardCount)
+	assert.Equal(t, int64(10_000+10+10), report.RevenueMsat)
+	assert.Equal(t, map[string]int64{StatusSettled: 1, StatusMissingFee: 1, StatusUnsettled: 1}, report.StatusCounts)
+
+	assert.Len(t, report.ByToken, 2)
+	assert.Equal(t, "t1", report.ByToken[0].Key)
+	assert.Equal(t, int64(10_010), report.ByToken[0].FeeMsat)
+	assert.Equal(t, int64(3), report.ByToken[0].ForwardCount)
+	assert.Equal(t, "t2", report.ByToken[1].Key)
+	assert.Equal(t, int64(10), report.ByToken[1].FeeMsat)
+
+	assert.Len(t, report.ByClient, 2)
+	assert.Equal(t, "01", report.ByClient[0].Key)
+	assert.Equal(t, int64(10_010), report.ByClient[0].FeeMsat)
+
+	assert.Len(t, report.Discrepancies, 2)
+	missing := report.Discrepancies[0]
+	assert.Equal(t, "02", missing.PaymentHash)
+	assert.Equal(t, StatusMissingFee, missing.Status)
+	assert.Equal(t, int64(10_000), missing.ExpectedFeeMsat)
+	assert.Equal(t, int64(10), missing.CollectedFeeMsat)
+	assert.Equal(t, int64(9_990), missing.MissingFeeMsat)
+	assert.Equal(t, StatusUnsettled, report.Discrepancies[1].Status)
+}
+
+func TestReconcilePaymentPartial(t *testing.T) {
+	now := time.Now()
+	c := &Channel{ChannelPoint: "a:0", InitialChanID: 1, OpenedAt: now}
+	p := &Payment{ChannelPoint: "a:0", IncomingAmountMsat: 100_000, OutgoingAmountMsat: 90_000}
+
+	// Outside of the settle window.
+	result := reconcilePayment(p, c, []*lightning.Forward{
+		{Timestamp: now.Add(2 * time.Hour), ChanIDOut: 1, AmountInMsat: 100_000, AmountOutMsat: 90_000},
+	}, time.Hour)
+	assert.Equal(t, StatusUnsettled, result.Status)
+
+	result = reconcilePayment(p, c, []*lightning.Forward{
+		{Timestamp: now, ChanIDOut: 1, AmountInMsat: 40_000, AmountOutMsat: 36_000},
+	}, time.Hour)
+	assert.Equal(t, StatusPartial, result.Status)
+	assert.Equal(t, int64(4_000), result.ExpectedFeeMsat)
+	assert.Equal(t, int64(0), result.MissingFeeMsat)
+}
@@ -0,0 +1,244 @@
+package refunds
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lifecycle"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// Funding transactions that didn't confirm in this time are most likely
+	// evicted from the mempool, and the nodes forget the channel.
+	defaultFailAfter = 14 * 24 * time.Hour
+	defaultInterval  = time.Hour
+
+	// Reason failed channels are marked closed with.
+	closeReason = "funding failed"
+)
+
+var (
+	refundedFees = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lspd_refunded_fees_msat_total",
+		Help: "Opening fees of failed channel opens credited to clients.",
+	}, []string{"node"})
+	appliedCredits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lspd_refund_credits_applied_msat_total",
+		Help: "Refund credits forwarded to clients with their next channel open.",
+	}, []string{"node"})
+)
+
+// Refund is the opening fee deducted from a payment whose channel open
+// failed. The remainder is owed to the client.
+type Refund struct {
+	PaymentHash   []byte
+	Destination   []byte
+	ChannelPoint  string
+	FeeMsat       uint64
+	RemainingMsat uint64
+	CreatedAt     time.Time
+}
+
+type Store interface {
+	// AddRefunds records refunds of the fees deducted from the payments the
+	// channel was opened for. Returns the refunds added. Payments refunded
+	// already are skipped.
+	AddRefunds(ctx context.Context, lspNodeID []byte, channelPoint string, createdAt time.Time) ([]*Refund, error)
+	// ApplyRefunds applies up to maxMsat of the remaining refunds of the
+	// destination to the payment, oldest first. Returns the credit applied
+	// to the payment, including the credit applied before.
+	ApplyRefunds(ctx context.Context, lspNodeID []byte, destination []byte, paymentHash []byte, maxMsat uint64) (uint64, error)
+}
+
+// ChannelStore lists the channels opened by lspd.
+type ChannelStore interface {
+	ListChannels(ctx context.Context, lspNodeID []byte, includeClosed bool) ([]*lifecycle.Channel, error)
+	MarkClosed(ctx context.Context, channelPoint string, closedAt time.Time, reason string) error
+}
+
+// Manager refunds the opening fee of channel opens that failed after the fee
+// was deducted from the payment, because the funding transaction never
+// confirmed. Refunds are credited to the client, and the credit is forwarded
+// to the client with its next channel open, by deducting it from the opening
+// fee.
+type Manager struct {
+	client    lightning.Client
+	store     Store
+	channels  ChannelStore
+	node      *config.NodeConfig
+	logger    *log.Logger
+	lspNodeID []byte
+	failAfter time.Duration
+	interval  time.Duration
+	ctx       context.Context
+	cancel    context.CancelFunc
+	mtx       sync.Mutex
+}
+
+func NewManager(
+	client lightning.Client,
+	store Store,
+	channels ChannelStore,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) *Manager {
+	lspNodeID, _ := hex.DecodeString(node.NodePubkey)
+	m := &Manager{
+		client:    client,
+		store:     store,
+		channels:  channels,
+		node:      node,
+		logger:    logger,
+		lspNodeID: lspNodeID,
+		failAfter: defaultFailAfter,
+		interval:  defaultInterval,
+	}
+
+	if cfg := node.Refunds; cfg != nil {
+		m.failAfter = parseDuration(cfg.FailAfter, defaultFailAfter, logger)
+		m.interval = parseDuration(cfg.Interval, defaultInterval, logger)
+	}
+
+	return m
+}
+
+func parseDuration(s string, def time.Duration, logger *log.Logger) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		logger.Printf("WARN: Invalid refunds duration '%s'. Using default %v", s, def)
+		return def
+	}
+
+	return d
+}
+
+// Apply applies the refunds owed to the client to the payment, up to the
+// opening fee of the payment. Returns the credit to forward to the client on
+// top of the registered amount. With a zero fee only the credit applied to the
+// payment before is returned, for htlcs of the payment forwarded after the
+// open.
+func (m *Manager) Apply(ctx context.Context, peerID []byte, paymentHash []byte, feeMsat uint64) uint64 {
+	credit, err := m.store.ApplyRefunds(ctx, m.lspNodeID, peerID, paymentHash, feeMsat)
+	if err != nil {
+		m.logger.Printf("refunds: ApplyRefunds(%x, %x) error: %v", peerID, paymentHash, err)
+		return 0
+	}
+
+	if credit > 0 && feeMsat > 0 {
+		m.logger.Printf("refunds: applied %v msat of refunds to payment %x to %x", credit, paymentHash, peerID)
+		appliedCredits.WithLabelValues(m.node.Label()).Add(float64(credit))
+	}
+
+	return credit
+}
+
+// Start checks for failed channel opens periodically. Returns immediately if
+// refunds are not configured for the node.
+func (m *Manager) Start() error {
+	if m.node.Refunds == nil {
+		return nil
+	}
+
+	m.mtx.Lock()
+	m.ctx, m.cancel = context.WithCancel(tenant.NewContext(context.Background(), m.node.Tenant))
+	ctx := m.ctx
+	m.mtx.Unlock()
+
+	m.logger.Printf("refunds: refunding the fees of channels unconfirmed after %v, checking every %v", m.failAfter, m.interval)
+	for {
+		m.check(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(m.interval):
+		}
+	}
+}
+
+func (m *Manager) Stop() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// check refunds the fees of the channels whose funding didn't confirm within
+// failAfter of the open. The channels are marked closed, so they are only
+// refunded once.
+func (m *Manager) check(ctx context.Context) {
+	channels, err := m.channels.ListChannels(ctx, m.lspNodeID, false)
+	if err != nil {
+		m.logger.Printf("refunds: ListChannels() error: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, c := range channels {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !m.failed(c, now) {
+			continue
+		}
+
+		refunds, err := m.store.AddRefunds(ctx, m.lspNodeID, c.ChannelPoint, now)
+		if err != nil {
+			m.logger.Printf("refunds: AddRefunds(%s) error: %v", c.ChannelPoint, err)
+			continue
+		}
+
+		for _, r := range refunds {
+			m.logger.Printf("refunds: channel %s with %s failed, credited the fee of %v msat of payment %x",
+				c.ChannelPoint, c.PeerID, r.FeeMsat, r.PaymentHash)
+			refundedFees.WithLabelValues(m.node.Label()).Add(float64(r.FeeMsat))
+		}
+
+		err = m.channels.MarkClosed(ctx, c.ChannelPoint, now, closeReason)
+		if err != nil {
+			m.logger.Printf("refunds: MarkClosed(%s) error: %v", c.ChannelPoint, err)
+		}
+	}
+}
+
+// failed returns whether the funding of the channel didn't confirm within
+// failAfter of the open. The node is asked as well, in case the confirmation
+// wasn't recorded.
+func (m *Manager) failed(c *lifecycle.Channel, now time.Time) bool {
+	if c.ConfirmedAt != nil || c.ConfirmedChanID != "" || c.OpenedAt == nil || now.Sub(*c.OpenedAt) < m.failAfter {
+		return false
+	}
+
+	peerID, err := hex.DecodeString(c.PeerID)
+	if err != nil {
+		return false
+	}
+	channelPoint, err := basetypes.ParseOutPoint(c.ChannelPoint)
+	if err != nil {
+		m.logger.Printf("refunds: invalid channel point %s: %v", c.ChannelPoint, err)
+		return false
+	}
+
+	// The node doesn't find channels it forgot, or with offline peers on
+	// lnd, which are treated as unconfirmed.
+	ch, err := m.client.GetChannel(peerID, *channelPoint)
+	if err == nil && ch.ConfirmedChannelID != 0 {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,103 @@
+package refunds
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lifecycle"
+	"github.com/breez/lspd/lightning"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockStore struct {
+	refunds []*Refund
+	credits map[string]uint64
+}
+
+func (s *mockStore) AddRefunds(ctx context.Context, lspNodeID []byte, channelPoint string, createdAt time.Time) ([]*Refund, error) {
+	r := &Refund{PaymentHash: []byte(channelPoint), ChannelPoint: channelPoint, FeeMsat: 2000, RemainingMsat: 2000, CreatedAt: createdAt}
+	s.refunds = append(s.refunds, r)
+	return []*Refund{r}, nil
+}
+
+func (s *mockStore) ApplyRefunds(ctx context.Context, lspNodeID []byte, destination []byte, paymentHash []byte, maxMsat uint64) (uint64, error) {
+	if applied, ok := s.credits[string(paymentHash)]; ok || maxMsat == 0 {
+		return applied, nil
+	}
+
+	var applied uint64
+	for _, r := range s.refunds {
+		amount := r.RemainingMsat
+		if amount > maxMsat-applied {
+			amount = maxMsat - applied
+		}
+		r.RemainingMsat -= amount
+		applied += amount
+	}
+	s.credits[string(paymentHash)] = applied
+	return applied, nil
+}
+
+type mockChannels struct {
+	channels []*lifecycle.Channel
+	closed   []string
+}
+
+func (c *mockChannels) ListChannels(ctx context.Context, lspNodeID []byte, includeClosed bool) ([]*lifecycle.Channel, error) {
+	return c.channels, nil
+}
+
+func (c *mockChannels) MarkClosed(ctx context.Context, channelPoint string, closedAt time.Time, reason string) error {
+	c.closed = append(c.closed, channelPoint)
+	return nil
+}
+
+type mockClient struct {
+	lightning.Client
+	confirmed map[string]bool
+}
+
+func (c *mockClient) GetChannel(peerID []byte, channelPoint wire.OutPoint) (*lightning.GetChannelResult, error) {
+	if c.confirmed[channelPoint.String()] {
+		return &lightning.GetChannelResult{ConfirmedChannelID: 1}, nil
+	}
+	return nil, errors.New("no channel found")
+}
+
+func TestRefundFailedOpens(t *testing.T) {
+	failed := "0000000000000000000000000000000000000000000000000000000000000001:0"
+	confirmedOnNode := "0000000000000000000000000000000000000000000000000000000000000002:0"
+	recent := "0000000000000000000000000000000000000000000000000000000000000003:0"
+	confirmed := "0000000000000000000000000000000000000000000000000000000000000004:0"
+
+	now := time.Now()
+	old := now.Add(-15 * 24 * time.Hour)
+	channels := &mockChannels{channels: []*lifecycle.Channel{
+		{ChannelPoint: failed, PeerID: "02aa", OpenedAt: &old},
+		{ChannelPoint: confirmedOnNode, PeerID: "02aa", OpenedAt: &old},
+		{ChannelPoint: recent, PeerID: "02aa", OpenedAt: &now},
+		{ChannelPoint: confirmed, PeerID: "02aa", OpenedAt: &old, ConfirmedChanID: "800000x1x0"},
+	}}
+	client := &mockClient{confirmed: map[string]bool{confirmedOnNode: true}}
+	store := &mockStore{credits: make(map[string]uint64)}
+	node := &config.NodeConfig{Refunds: &config.RefundsConfig{}}
+	m := NewManager(client, store, channels, node, log.New(os.Stderr, "", 0))
+
+	m.check(context.Background())
+	assert.Len(t, store.refunds, 1)
+	assert.Equal(t, []string{failed}, channels.closed)
+
+	// The credit is applied up to the fee of the next open, and again to
+	// later htlcs of the same payment.
+	ctx := context.Background()
+	assert.Equal(t, uint64(1500), m.Apply(ctx, []byte{0x02, 0xaa}, []byte{1}, 1500))
+	assert.Equal(t, uint64(1500), m.Apply(ctx, []byte{0x02, 0xaa}, []byte{1}, 0))
+	assert.Equal(t, uint64(500), m.Apply(ctx, []byte{0x02, 0xaa}, []byte{2}, 1500))
+	assert.Equal(t, uint64(0), m.Apply(ctx, []byte{0x02, 0xaa}, []byte{3}, 1500))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/leader"
+)
+
+const defaultLeaseTTL = 15 * time.Second
+
+// leaseTTL returns how long a replica leads a node without renewing its
+// lease. A dead leader is replaced after at most this long.
+func leaseTTL() time.Duration {
+	s := os.Getenv("LEADER_LEASE_TTL")
+	if s == "" {
+		return defaultLeaseTTL
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 3*time.Second {
+		log.Printf("WARN: Invalid LEADER_LEASE_TTL '%s'. Using default %v", s, defaultLeaseTTL)
+		return defaultLeaseTTL
+	}
+
+	return d
+}
+
+// replicaID identifies this replica in the leases. Every replica needs its
+// own id.
+func replicaID() string {
+	if id := os.Getenv("REPLICA_ID"); id != "" {
+		return id
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "lspd"
+	}
+
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// newNodeElector returns the elector of the replica leading the node, or nil
+// if there is no leader election.
+func newNodeElector(store leader.Store, node *config.NodeConfig, logger *log.Logger) *leader.Elector {
+	if store == nil {
+		return nil
+	}
+
+	return leader.NewElector(store, "node/"+node.NodePubkey, replicaID(), leaseTTL(), logger)
+}
+
+// electedChannel returns a channel that is closed once this replica leads
+// the node. Without leader election every replica leads all its nodes.
+func electedChannel(e *leader.Elector) <-chan struct{} {
+	if e == nil {
+		c := make(chan struct{})
+		close(c)
+		return c
+	}
+
+	return e.Elected()
+}
+
+// nodeWorker is a component that runs in the background for a node. With
+// leader election, it only runs on the replica leading the node.
+type nodeWorker struct {
+	elected <-chan struct{}
+	start   func() error
+}
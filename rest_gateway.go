@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	lspdrpc "github.com/breez/lspd/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Maximum size of a rest request body.
+const maxRestBodySize = 1 << 20
+
+type restMethod struct {
+	newRequest func() proto.Message
+	call       func(ctx context.Context, req proto.Message) (proto.Message, error)
+}
+
+// newRestGateway returns a REST+JSON front end for the ChannelOpener service,
+// for clients that cannot use grpc, like browsers or clients behind proxies
+// that only pass plain https. Every method is a POST to /v1/<MethodName> with
+// the json encoded request message as body. The bearer token goes in the
+// Authorization header, as with grpc.
+func (s *grpcServer) newRestGateway(tlsConfig *tls.Config) *http.Server {
+	mux := http.NewServeMux()
+	for name, method := range s.restMethods() {
+		mux.HandleFunc("/v1/"+name, s.restHandler(name, method))
+	}
+
+	return &http.Server{
+		Addr:      s.restAddress,
+		Handler:   mux,
+		TLSConfig: tlsConfig,
+	}
+}
+
+// restMethods returns the ChannelOpener methods served by the REST gateway,
+// by method name.
+func (s *grpcServer) restMethods() map[string]restMethod {
+	return map[string]restMethod{
+		"ChannelInformation": {
+			newRequest: func() proto.Message { return &lspdrpc.ChannelInformationRequest{} },
+			call: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+				return s.c.ChannelInformation(ctx, req.(*lspdrpc.ChannelInformationRequest))
+			},
+		},
+		"OpenChannel": {
+			newRequest: func() proto.Message { return &lspdrpc.OpenChannelRequest{} },
+			call: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+				return s.c.OpenChannel(ctx, req.(*lspdrpc.OpenChannelRequest))
+			},
+		},
+		"GetChannelOpenStatus": {
+			newRequest: func() proto.Message { return &lspdrpc.GetChannelOpenStatusRequest{} },
+			call: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+				return s.c.GetChannelOpenStatus(ctx, req.(*lspdrpc.GetChannelOpenStatusRequest))
+			},
+		},
+		"RegisterPayment": {
+			newRequest: func() proto.Message { return &lspdrpc.RegisterPaymentRequest{} },
+			call: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+				return s.c.RegisterPayment(ctx, req.(*lspdrpc.RegisterPaymentRequest))
+			},
+		},
+		"CheckChannels": {
+			newRequest: func() proto.Message { return &lspdrpc.Encrypted{} },
+			call: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+				return s.c.CheckChannels(ctx, req.(*lspdrpc.Encrypted))
+			},
+		},
+		"CancelPayment": {
+			newRequest: func() proto.Message { return &lspdrpc.CancelPaymentRequest{} },
+			call: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+				return s.c.CancelPayment(ctx, req.(*lspdrpc.CancelPaymentRequest))
+			},
+		},
+		"GeneratePaymentHash": {
+			newRequest: func() proto.Message { return &lspdrpc.GeneratePaymentHashRequest{} },
+			call: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+				return s.c.GeneratePaymentHash(ctx, req.(*lspdrpc.GeneratePaymentHashRequest))
+			},
+		},
+		"ClaimPreimage": {
+			newRequest: func() proto.Message { return &lspdrpc.ClaimPreimageRequest{} },
+			call: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+				return s.c.ClaimPreimage(ctx, req.(*lspdrpc.ClaimPreimageRequest))
+			},
+		},
+		"GetLspInfo": {
+			newRequest: func() proto.Message { return &lspdrpc.GetLspInfoRequest{} },
+			call: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+				return s.c.GetLspInfo(ctx, req.(*lspdrpc.GetLspInfoRequest))
+			},
+		},
+		"ListClientChannels": {
+			newRequest: func() proto.Message { return &lspdrpc.ListClientChannelsRequest{} },
+			call: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+				return s.c.ListClientChannels(ctx, req.(*lspdrpc.ListClientChannelsRequest))
+			},
+		},
+		"RenewChannelLease": {
+			newRequest: func() proto.Message { return &lspdrpc.RenewChannelLeaseRequest{} },
+			call: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+				return s.c.RenewChannelLease(ctx, req.(*lspdrpc.RenewChannelLeaseRequest))
+			},
+		},
+	}
+}
+
+func (s *grpcServer) serveRestGateway() {
+	var err error
+	if s.rest.TLSConfig != nil {
+		err = s.rest.ListenAndServeTLS("", "")
+	} else {
+		err = s.rest.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Printf("REST gateway stopped with error: %v", err)
+	}
+}
+
+// restHandler runs the method through the middleware chain of the grpc
+// server, as the grpc method of the same name.
+func (s *grpcServer) restHandler(name string, method restMethod) http.HandlerFunc {
+	info := &grpc.UnaryServerInfo{
+		FullMethod: fmt.Sprintf("/%s/%s", lspdrpc.ChannelOpener_ServiceDesc.ServiceName, name),
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Allow browser clients on other origins. They authenticate with the
+		// Authorization header rather than cookies.
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			writeRestError(w, status.New(codes.Unimplemented, "method not allowed"))
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRestBodySize))
+		if err != nil {
+			writeRestError(w, status.New(codes.InvalidArgument, "failed to read request body"))
+			return
+		}
+
+		req := method.newRequest()
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, req)
+		if err != nil {
+			writeRestError(w, status.Newf(codes.InvalidArgument, "invalid request: %v", err))
+			return
+		}
+
+		ctx := metadata.NewIncomingContext(r.Context(), metadata.Pairs("authorization", r.Header.Get("Authorization")))
+		reply, err := s.chain(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return method.call(ctx, req.(proto.Message))
+		})
+		if err != nil {
+			writeRestError(w, status.Convert(err))
+			return
+		}
+
+		b, err := protojson.Marshal(reply.(proto.Message))
+		if err != nil {
+			writeRestError(w, status.New(codes.Internal, "failed to encode reply"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	}
+}
+
+// writeRestError writes the grpc status as json, with the http status code
+// grpc-gateway uses for the grpc code.
+func writeRestError(w http.ResponseWriter, st *status.Status) {
+	b, err := protojson.Marshal(st.Proto())
+	if err != nil {
+		b = []byte(`{"code":13,"message":"failed to encode error"}`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatusFromCode(st.Code()))
+	w.Write(b)
+}
+
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -20,6 +20,60 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ClientChannelState int32
+
+const (
+	ClientChannelState_CLIENT_CHANNEL_UNKNOWN ClientChannelState = 0
+	// The funding transaction of the channel is not confirmed yet, and the
+	// channel can't be used until the node considers it open.
+	ClientChannelState_CLIENT_CHANNEL_PENDING ClientChannelState = 1
+	ClientChannelState_CLIENT_CHANNEL_OPEN    ClientChannelState = 2
+	ClientChannelState_CLIENT_CHANNEL_CLOSED  ClientChannelState = 3
+)
+
+// Enum value maps for ClientChannelState.
+var (
+	ClientChannelState_name = map[int32]string{
+		0: "CLIENT_CHANNEL_UNKNOWN",
+		1: "CLIENT_CHANNEL_PENDING",
+		2: "CLIENT_CHANNEL_OPEN",
+		3: "CLIENT_CHANNEL_CLOSED",
+	}
+	ClientChannelState_value = map[string]int32{
+		"CLIENT_CHANNEL_UNKNOWN": 0,
+		"CLIENT_CHANNEL_PENDING": 1,
+		"CLIENT_CHANNEL_OPEN":    2,
+		"CLIENT_CHANNEL_CLOSED":  3,
+	}
+)
+
+func (x ClientChannelState) Enum() *ClientChannelState {
+	p := new(ClientChannelState)
+	*p = x
+	return p
+}
+
+func (x ClientChannelState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ClientChannelState) Descriptor() protoreflect.EnumDescriptor {
+	return file_lspd_proto_enumTypes[0].Descriptor()
+}
+
+func (ClientChannelState) Type() protoreflect.EnumType {
+	return &file_lspd_proto_enumTypes[0]
+}
+
+func (x ClientChannelState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ClientChannelState.Descriptor instead.
+func (ClientChannelState) EnumDescriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{0}
+}
+
 type ChannelInformationRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -105,6 +159,18 @@ type ChannelInformationReply struct {
 	// Deprecated: Do not use.
 	ChannelMinimumFeeMsat int64               `protobuf:"varint,13,opt,name=channel_minimum_fee_msat,json=channelMinimumFeeMsat,proto3" json:"channel_minimum_fee_msat,omitempty"`
 	OpeningFeeParamsMenu  []*OpeningFeeParams `protobuf:"bytes,14,rep,name=opening_fee_params_menu,json=openingFeeParamsMenu,proto3" json:"opening_fee_params_menu,omitempty"`
+	// Id of the key lsp_pubkey belongs to. Clients pass it as key_id along
+	// with the blobs they encrypt with lsp_pubkey, because the key is rotated
+	// over time.
+	LspKeyId string `protobuf:"bytes,15,opt,name=lsp_key_id,json=lspKeyId,proto3" json:"lsp_key_id,omitempty"`
+	// The smallest payment in millisatoshi a channel is opened for. Smaller
+	// payments are rejected. No minimum if 0.
+	MinPaymentSizeMsat uint64 `protobuf:"varint,16,opt,name=min_payment_size_msat,json=minPaymentSizeMsat,proto3" json:"min_payment_size_msat,omitempty"`
+	// The priority tiers a payment can be registered with.
+	PriorityTiers []*PriorityTier `protobuf:"bytes,17,rep,name=priority_tiers,json=priorityTiers,proto3" json:"priority_tiers,omitempty"`
+	// The challenge to solve before calling OpenChannel. Not set if
+	// OpenChannel doesn't require a challenge for the token of the client.
+	OpenChannelChallenge *OpenChannelChallenge `protobuf:"bytes,18,opt,name=open_channel_challenge,json=openChannelChallenge,proto3" json:"open_channel_challenge,omitempty"`
 }
 
 func (x *ChannelInformationReply) Reset() {
@@ -240,22 +306,52 @@ func (x *ChannelInformationReply) GetOpeningFeeParamsMenu() []*OpeningFeeParams
 	return nil
 }
 
-type OpeningFeeParams struct {
+func (x *ChannelInformationReply) GetLspKeyId() string {
+	if x != nil {
+		return x.LspKeyId
+	}
+	return ""
+}
+
+func (x *ChannelInformationReply) GetMinPaymentSizeMsat() uint64 {
+	if x != nil {
+		return x.MinPaymentSizeMsat
+	}
+	return 0
+}
+
+func (x *ChannelInformationReply) GetPriorityTiers() []*PriorityTier {
+	if x != nil {
+		return x.PriorityTiers
+	}
+	return nil
+}
+
+func (x *ChannelInformationReply) GetOpenChannelChallenge() *OpenChannelChallenge {
+	if x != nil {
+		return x.OpenChannelChallenge
+	}
+	return nil
+}
+
+type ListClientChannelsRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	MinMsat      uint64 `protobuf:"varint,1,opt,name=min_msat,json=minMsat,proto3" json:"min_msat,omitempty"`
-	Proportional uint32 `protobuf:"varint,2,opt,name=proportional,proto3" json:"proportional,omitempty"`
-	ValidUntil   string `protobuf:"bytes,3,opt,name=valid_until,json=validUntil,proto3" json:"valid_until,omitempty"`
-	// The channel can be closed if not used this duration in blocks.
-	MaxIdleTime          uint32 `protobuf:"varint,4,opt,name=max_idle_time,json=maxIdleTime,proto3" json:"max_idle_time,omitempty"`
-	MaxClientToSelfDelay uint32 `protobuf:"varint,5,opt,name=max_client_to_self_delay,json=maxClientToSelfDelay,proto3" json:"max_client_to_self_delay,omitempty"`
-	Promise              string `protobuf:"bytes,6,opt,name=promise,proto3" json:"promise,omitempty"`
+	// Encrypted ListClientChannelsInformation, like the blob of
+	// RegisterPaymentRequest.
+	Blob []byte `protobuf:"bytes,1,opt,name=blob,proto3" json:"blob,omitempty"`
+	// Signature of the destination node key over the sha256 hash of the
+	// decrypted blob, in 64 byte compact format. Required.
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	// Id of the lsp key the blob is encrypted with, the lsp_key_id of
+	// ChannelInformationReply. If empty, all valid keys are tried.
+	KeyId string `protobuf:"bytes,3,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
 }
 
-func (x *OpeningFeeParams) Reset() {
-	*x = OpeningFeeParams{}
+func (x *ListClientChannelsRequest) Reset() {
+	*x = ListClientChannelsRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_lspd_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -263,13 +359,13 @@ func (x *OpeningFeeParams) Reset() {
 	}
 }
 
-func (x *OpeningFeeParams) String() string {
+func (x *ListClientChannelsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*OpeningFeeParams) ProtoMessage() {}
+func (*ListClientChannelsRequest) ProtoMessage() {}
 
-func (x *OpeningFeeParams) ProtoReflect() protoreflect.Message {
+func (x *ListClientChannelsRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_lspd_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -281,64 +377,49 @@ func (x *OpeningFeeParams) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use OpeningFeeParams.ProtoReflect.Descriptor instead.
-func (*OpeningFeeParams) Descriptor() ([]byte, []int) {
+// Deprecated: Use ListClientChannelsRequest.ProtoReflect.Descriptor instead.
+func (*ListClientChannelsRequest) Descriptor() ([]byte, []int) {
 	return file_lspd_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *OpeningFeeParams) GetMinMsat() uint64 {
-	if x != nil {
-		return x.MinMsat
-	}
-	return 0
-}
-
-func (x *OpeningFeeParams) GetProportional() uint32 {
-	if x != nil {
-		return x.Proportional
-	}
-	return 0
-}
-
-func (x *OpeningFeeParams) GetValidUntil() string {
-	if x != nil {
-		return x.ValidUntil
-	}
-	return ""
-}
-
-func (x *OpeningFeeParams) GetMaxIdleTime() uint32 {
+func (x *ListClientChannelsRequest) GetBlob() []byte {
 	if x != nil {
-		return x.MaxIdleTime
+		return x.Blob
 	}
-	return 0
+	return nil
 }
 
-func (x *OpeningFeeParams) GetMaxClientToSelfDelay() uint32 {
+func (x *ListClientChannelsRequest) GetSignature() []byte {
 	if x != nil {
-		return x.MaxClientToSelfDelay
+		return x.Signature
 	}
-	return 0
+	return nil
 }
 
-func (x *OpeningFeeParams) GetPromise() string {
+func (x *ListClientChannelsRequest) GetKeyId() string {
 	if x != nil {
-		return x.Promise
+		return x.KeyId
 	}
 	return ""
 }
 
-type OpenChannelRequest struct {
+type ListClientChannelsInformation struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	/// The identity pubkey of the Lightning node
-	Pubkey string `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	// The node to list the channels of.
+	Destination []byte `protobuf:"bytes,1,opt,name=destination,proto3" json:"destination,omitempty"`
+	// Unix timestamp in seconds of the request.
+	Timestamp int64 `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Random value unique for every signed request of the destination.
+	Nonce []byte `protobuf:"bytes,3,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// Also list the channels that were closed.
+	IncludeClosed bool `protobuf:"varint,4,opt,name=include_closed,json=includeClosed,proto3" json:"include_closed,omitempty"`
 }
 
-func (x *OpenChannelRequest) Reset() {
-	*x = OpenChannelRequest{}
+func (x *ListClientChannelsInformation) Reset() {
+	*x = ListClientChannelsInformation{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_lspd_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -346,13 +427,13 @@ func (x *OpenChannelRequest) Reset() {
 	}
 }
 
-func (x *OpenChannelRequest) String() string {
+func (x *ListClientChannelsInformation) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*OpenChannelRequest) ProtoMessage() {}
+func (*ListClientChannelsInformation) ProtoMessage() {}
 
-func (x *OpenChannelRequest) ProtoReflect() protoreflect.Message {
+func (x *ListClientChannelsInformation) ProtoReflect() protoreflect.Message {
 	mi := &file_lspd_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -364,31 +445,50 @@ func (x *OpenChannelRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use OpenChannelRequest.ProtoReflect.Descriptor instead.
-func (*OpenChannelRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ListClientChannelsInformation.ProtoReflect.Descriptor instead.
+func (*ListClientChannelsInformation) Descriptor() ([]byte, []int) {
 	return file_lspd_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *OpenChannelRequest) GetPubkey() string {
+func (x *ListClientChannelsInformation) GetDestination() []byte {
 	if x != nil {
-		return x.Pubkey
+		return x.Destination
 	}
-	return ""
+	return nil
 }
 
-type OpenChannelReply struct {
+func (x *ListClientChannelsInformation) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ListClientChannelsInformation) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *ListClientChannelsInformation) GetIncludeClosed() bool {
+	if x != nil {
+		return x.IncludeClosed
+	}
+	return false
+}
+
+type ListClientChannelsReply struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	/// The transaction hash
-	TxHash string `protobuf:"bytes,1,opt,name=tx_hash,proto3" json:"tx_hash,omitempty"`
-	/// The output index
-	OutputIndex uint32 `protobuf:"varint,2,opt,name=output_index,proto3" json:"output_index,omitempty"`
+	// The channels the lsp opened to the destination, oldest first.
+	Channels []*ClientChannel `protobuf:"bytes,1,rep,name=channels,proto3" json:"channels,omitempty"`
 }
 
-func (x *OpenChannelReply) Reset() {
-	*x = OpenChannelReply{}
+func (x *ListClientChannelsReply) Reset() {
+	*x = ListClientChannelsReply{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_lspd_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -396,13 +496,13 @@ func (x *OpenChannelReply) Reset() {
 	}
 }
 
-func (x *OpenChannelReply) String() string {
+func (x *ListClientChannelsReply) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*OpenChannelReply) ProtoMessage() {}
+func (*ListClientChannelsReply) ProtoMessage() {}
 
-func (x *OpenChannelReply) ProtoReflect() protoreflect.Message {
+func (x *ListClientChannelsReply) ProtoReflect() protoreflect.Message {
 	mi := &file_lspd_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -414,35 +514,54 @@ func (x *OpenChannelReply) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use OpenChannelReply.ProtoReflect.Descriptor instead.
-func (*OpenChannelReply) Descriptor() ([]byte, []int) {
+// Deprecated: Use ListClientChannelsReply.ProtoReflect.Descriptor instead.
+func (*ListClientChannelsReply) Descriptor() ([]byte, []int) {
 	return file_lspd_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *OpenChannelReply) GetTxHash() string {
-	if x != nil {
-		return x.TxHash
-	}
-	return ""
-}
-
-func (x *OpenChannelReply) GetOutputIndex() uint32 {
+func (x *ListClientChannelsReply) GetChannels() []*ClientChannel {
 	if x != nil {
-		return x.OutputIndex
+		return x.Channels
 	}
-	return 0
+	return nil
 }
 
-type RegisterPaymentRequest struct {
+// A channel the lsp opened to the client.
+type ClientChannel struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Blob []byte `protobuf:"bytes,3,opt,name=blob,proto3" json:"blob,omitempty"`
-}
-
-func (x *RegisterPaymentRequest) Reset() {
-	*x = RegisterPaymentRequest{}
+	ChannelPoint string `protobuf:"bytes,1,opt,name=channel_point,json=channelPoint,proto3" json:"channel_point,omitempty"`
+	// Capacity of the channel in satoshi. 0 if the node doesn't know the
+	// channel anymore.
+	CapacitySat uint64             `protobuf:"varint,2,opt,name=capacity_sat,json=capacitySat,proto3" json:"capacity_sat,omitempty"`
+	State       ClientChannelState `protobuf:"varint,3,opt,name=state,proto3,enum=lspd.ClientChannelState" json:"state,omitempty"`
+	// The alias scid of the channel, used in route hints.
+	AliasScid string `protobuf:"bytes,4,opt,name=alias_scid,json=aliasScid,proto3" json:"alias_scid,omitempty"`
+	// The scid of the confirmed funding transaction, if it is known.
+	ConfirmedScid string `protobuf:"bytes,5,opt,name=confirmed_scid,json=confirmedScid,proto3" json:"confirmed_scid,omitempty"`
+	// Unix time in seconds the channel was opened.
+	OpenedAt int64 `protobuf:"varint,6,opt,name=opened_at,json=openedAt,proto3" json:"opened_at,omitempty"`
+	// Unix time in seconds the lsp may close the channel if it has no more
+	// payments. Payments over the channel extend it. 0 if the channel is not
+	// closed automatically.
+	ExpiresAt int64 `protobuf:"varint,7,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// Unix time in seconds the lsp closed the channel, 0 if it didn't.
+	ClosedAt int64 `protobuf:"varint,8,opt,name=closed_at,json=closedAt,proto3" json:"closed_at,omitempty"`
+	// Unix time in seconds the lease of the channel expires. The channel is
+	// not closed before. 0 if the lsp doesn't lease channels.
+	LeaseExpiresAt int64 `protobuf:"varint,9,opt,name=lease_expires_at,json=leaseExpiresAt,proto3" json:"lease_expires_at,omitempty"`
+	// Satoshi the client contributed to the channel, if it was opened
+	// dual-funded.
+	ClientFundingSat uint64 `protobuf:"varint,10,opt,name=client_funding_sat,json=clientFundingSat,proto3" json:"client_funding_sat,omitempty"`
+	// The commitment type the channel was opened with: taproot, anchors or
+	// static_remote_key. Empty if it is not known.
+	CommitmentType string `protobuf:"bytes,11,opt,name=commitment_type,json=commitmentType,proto3" json:"commitment_type,omitempty"`
+}
+
+func (x *ClientChannel) Reset() {
+	*x = ClientChannel{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_lspd_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -450,13 +569,13 @@ func (x *RegisterPaymentRequest) Reset() {
 	}
 }
 
-func (x *RegisterPaymentRequest) String() string {
+func (x *ClientChannel) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterPaymentRequest) ProtoMessage() {}
+func (*ClientChannel) ProtoMessage() {}
 
-func (x *RegisterPaymentRequest) ProtoReflect() protoreflect.Message {
+func (x *ClientChannel) ProtoReflect() protoreflect.Message {
 	mi := &file_lspd_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -468,26 +587,106 @@ func (x *RegisterPaymentRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterPaymentRequest.ProtoReflect.Descriptor instead.
-func (*RegisterPaymentRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ClientChannel.ProtoReflect.Descriptor instead.
+func (*ClientChannel) Descriptor() ([]byte, []int) {
 	return file_lspd_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *RegisterPaymentRequest) GetBlob() []byte {
+func (x *ClientChannel) GetChannelPoint() string {
 	if x != nil {
-		return x.Blob
+		return x.ChannelPoint
 	}
-	return nil
+	return ""
 }
 
-type RegisterPaymentReply struct {
+func (x *ClientChannel) GetCapacitySat() uint64 {
+	if x != nil {
+		return x.CapacitySat
+	}
+	return 0
+}
+
+func (x *ClientChannel) GetState() ClientChannelState {
+	if x != nil {
+		return x.State
+	}
+	return ClientChannelState_CLIENT_CHANNEL_UNKNOWN
+}
+
+func (x *ClientChannel) GetAliasScid() string {
+	if x != nil {
+		return x.AliasScid
+	}
+	return ""
+}
+
+func (x *ClientChannel) GetConfirmedScid() string {
+	if x != nil {
+		return x.ConfirmedScid
+	}
+	return ""
+}
+
+func (x *ClientChannel) GetOpenedAt() int64 {
+	if x != nil {
+		return x.OpenedAt
+	}
+	return 0
+}
+
+func (x *ClientChannel) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *ClientChannel) GetClosedAt() int64 {
+	if x != nil {
+		return x.ClosedAt
+	}
+	return 0
+}
+
+func (x *ClientChannel) GetLeaseExpiresAt() int64 {
+	if x != nil {
+		return x.LeaseExpiresAt
+	}
+	return 0
+}
+
+func (x *ClientChannel) GetClientFundingSat() uint64 {
+	if x != nil {
+		return x.ClientFundingSat
+	}
+	return 0
+}
+
+func (x *ClientChannel) GetCommitmentType() string {
+	if x != nil {
+		return x.CommitmentType
+	}
+	return ""
+}
+
+type RenewChannelLeaseRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	// Encrypted RenewChannelLeaseInformation, like the blob of
+	// RegisterPaymentRequest.
+	Blob []byte `protobuf:"bytes,1,opt,name=blob,proto3" json:"blob,omitempty"`
+	// Signature of the destination node key over the sha256 hash of the
+	// decrypted blob, in 64 byte compact format. Required.
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	// Id of the lsp key the blob is encrypted with, the lsp_key_id of
+	// ChannelInformationReply. If empty, all valid keys are tried.
+	KeyId string `protobuf:"bytes,3,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
 }
 
-func (x *RegisterPaymentReply) Reset() {
-	*x = RegisterPaymentReply{}
+func (x *RenewChannelLeaseRequest) Reset() {
+	*x = RenewChannelLeaseRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_lspd_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -495,13 +694,13 @@ func (x *RegisterPaymentReply) Reset() {
 	}
 }
 
-func (x *RegisterPaymentReply) String() string {
+func (x *RenewChannelLeaseRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterPaymentReply) ProtoMessage() {}
+func (*RenewChannelLeaseRequest) ProtoMessage() {}
 
-func (x *RegisterPaymentReply) ProtoReflect() protoreflect.Message {
+func (x *RenewChannelLeaseRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_lspd_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -513,27 +712,57 @@ func (x *RegisterPaymentReply) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterPaymentReply.ProtoReflect.Descriptor instead.
-func (*RegisterPaymentReply) Descriptor() ([]byte, []int) {
+// Deprecated: Use RenewChannelLeaseRequest.ProtoReflect.Descriptor instead.
+func (*RenewChannelLeaseRequest) Descriptor() ([]byte, []int) {
 	return file_lspd_proto_rawDescGZIP(), []int{6}
 }
 
-type PaymentInformation struct {
+func (x *RenewChannelLeaseRequest) GetBlob() []byte {
+	if x != nil {
+		return x.Blob
+	}
+	return nil
+}
+
+func (x *RenewChannelLeaseRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *RenewChannelLeaseRequest) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+// Registers a payment to the destination that renews the lease of the
+// channel. The renewal fee is deducted from the payment when it is forwarded
+// over the channel, like the opening fee of a registered payment, and the
+// lease is extended once it is forwarded.
+type RenewChannelLeaseInformation struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	PaymentHash        []byte            `protobuf:"bytes,1,opt,name=payment_hash,json=paymentHash,proto3" json:"payment_hash,omitempty"`
-	PaymentSecret      []byte            `protobuf:"bytes,2,opt,name=payment_secret,json=paymentSecret,proto3" json:"payment_secret,omitempty"`
-	Destination        []byte            `protobuf:"bytes,3,opt,name=destination,proto3" json:"destination,omitempty"`
-	IncomingAmountMsat int64             `protobuf:"varint,4,opt,name=incoming_amount_msat,json=incomingAmountMsat,proto3" json:"incoming_amount_msat,omitempty"`
-	OutgoingAmountMsat int64             `protobuf:"varint,5,opt,name=outgoing_amount_msat,json=outgoingAmountMsat,proto3" json:"outgoing_amount_msat,omitempty"`
-	Tag                string            `protobuf:"bytes,6,opt,name=tag,proto3" json:"tag,omitempty"`
-	OpeningFeeParams   *OpeningFeeParams `protobuf:"bytes,7,opt,name=opening_fee_params,json=openingFeeParams,proto3" json:"opening_fee_params,omitempty"`
-}
-
-func (x *PaymentInformation) Reset() {
-	*x = PaymentInformation{}
+	Destination []byte `protobuf:"bytes,1,opt,name=destination,proto3" json:"destination,omitempty"`
+	// Unix timestamp in seconds of the request.
+	Timestamp int64 `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Random value unique for every signed request of the destination.
+	Nonce []byte `protobuf:"bytes,3,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// The channel to renew the lease of.
+	ChannelPoint string `protobuf:"bytes,4,opt,name=channel_point,json=channelPoint,proto3" json:"channel_point,omitempty"`
+	// The payment hash and secret of an invoice of the destination.
+	PaymentHash   []byte `protobuf:"bytes,5,opt,name=payment_hash,json=paymentHash,proto3" json:"payment_hash,omitempty"`
+	PaymentSecret []byte `protobuf:"bytes,6,opt,name=payment_secret,json=paymentSecret,proto3" json:"payment_secret,omitempty"`
+	// Amount of the payment arriving at the lsp, including the renewal fee.
+	IncomingAmountMsat int64 `protobuf:"varint,7,opt,name=incoming_amount_msat,json=incomingAmountMsat,proto3" json:"incoming_amount_msat,omitempty"`
+}
+
+func (x *RenewChannelLeaseInformation) Reset() {
+	*x = RenewChannelLeaseInformation{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_lspd_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -541,13 +770,13 @@ func (x *PaymentInformation) Reset() {
 	}
 }
 
-func (x *PaymentInformation) String() string {
+func (x *RenewChannelLeaseInformation) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PaymentInformation) ProtoMessage() {}
+func (*RenewChannelLeaseInformation) ProtoMessage() {}
 
-func (x *PaymentInformation) ProtoReflect() protoreflect.Message {
+func (x *RenewChannelLeaseInformation) ProtoReflect() protoreflect.Message {
 	mi := &file_lspd_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -559,70 +788,78 @@ func (x *PaymentInformation) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PaymentInformation.ProtoReflect.Descriptor instead.
-func (*PaymentInformation) Descriptor() ([]byte, []int) {
+// Deprecated: Use RenewChannelLeaseInformation.ProtoReflect.Descriptor instead.
+func (*RenewChannelLeaseInformation) Descriptor() ([]byte, []int) {
 	return file_lspd_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *PaymentInformation) GetPaymentHash() []byte {
+func (x *RenewChannelLeaseInformation) GetDestination() []byte {
 	if x != nil {
-		return x.PaymentHash
+		return x.Destination
 	}
 	return nil
 }
 
-func (x *PaymentInformation) GetPaymentSecret() []byte {
+func (x *RenewChannelLeaseInformation) GetTimestamp() int64 {
 	if x != nil {
-		return x.PaymentSecret
+		return x.Timestamp
 	}
-	return nil
+	return 0
 }
 
-func (x *PaymentInformation) GetDestination() []byte {
+func (x *RenewChannelLeaseInformation) GetNonce() []byte {
 	if x != nil {
-		return x.Destination
+		return x.Nonce
 	}
 	return nil
 }
 
-func (x *PaymentInformation) GetIncomingAmountMsat() int64 {
+func (x *RenewChannelLeaseInformation) GetChannelPoint() string {
 	if x != nil {
-		return x.IncomingAmountMsat
+		return x.ChannelPoint
 	}
-	return 0
+	return ""
 }
 
-func (x *PaymentInformation) GetOutgoingAmountMsat() int64 {
+func (x *RenewChannelLeaseInformation) GetPaymentHash() []byte {
 	if x != nil {
-		return x.OutgoingAmountMsat
+		return x.PaymentHash
 	}
-	return 0
+	return nil
 }
 
-func (x *PaymentInformation) GetTag() string {
+func (x *RenewChannelLeaseInformation) GetPaymentSecret() []byte {
 	if x != nil {
-		return x.Tag
+		return x.PaymentSecret
 	}
-	return ""
+	return nil
 }
 
-func (x *PaymentInformation) GetOpeningFeeParams() *OpeningFeeParams {
+func (x *RenewChannelLeaseInformation) GetIncomingAmountMsat() int64 {
 	if x != nil {
-		return x.OpeningFeeParams
+		return x.IncomingAmountMsat
 	}
-	return nil
+	return 0
 }
 
-type Encrypted struct {
+type RenewChannelLeaseReply struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	// The renewal fee deducted from the payment.
+	FeeMsat uint64 `protobuf:"varint,1,opt,name=fee_msat,json=feeMsat,proto3" json:"fee_msat,omitempty"`
+	// Amount forwarded to the destination.
+	OutgoingAmountMsat int64 `protobuf:"varint,2,opt,name=outgoing_amount_msat,json=outgoingAmountMsat,proto3" json:"outgoing_amount_msat,omitempty"`
+	// Time in seconds the lease is extended by, from its current expiry or
+	// from the time of the payment if that is later.
+	LeaseDuration int64 `protobuf:"varint,3,opt,name=lease_duration,json=leaseDuration,proto3" json:"lease_duration,omitempty"`
+	// Unix time in seconds the lease currently expires.
+	LeaseExpiresAt int64 `protobuf:"varint,4,opt,name=lease_expires_at,json=leaseExpiresAt,proto3" json:"lease_expires_at,omitempty"`
 }
 
-func (x *Encrypted) Reset() {
-	*x = Encrypted{}
+func (x *RenewChannelLeaseReply) Reset() {
+	*x = RenewChannelLeaseReply{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_lspd_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -630,13 +867,13 @@ func (x *Encrypted) Reset() {
 	}
 }
 
-func (x *Encrypted) String() string {
+func (x *RenewChannelLeaseReply) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Encrypted) ProtoMessage() {}
+func (*RenewChannelLeaseReply) ProtoMessage() {}
 
-func (x *Encrypted) ProtoReflect() protoreflect.Message {
+func (x *RenewChannelLeaseReply) ProtoReflect() protoreflect.Message {
 	mi := &file_lspd_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -648,30 +885,47 @@ func (x *Encrypted) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Encrypted.ProtoReflect.Descriptor instead.
-func (*Encrypted) Descriptor() ([]byte, []int) {
+// Deprecated: Use RenewChannelLeaseReply.ProtoReflect.Descriptor instead.
+func (*RenewChannelLeaseReply) Descriptor() ([]byte, []int) {
 	return file_lspd_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *Encrypted) GetData() []byte {
+func (x *RenewChannelLeaseReply) GetFeeMsat() uint64 {
 	if x != nil {
-		return x.Data
+		return x.FeeMsat
 	}
-	return nil
+	return 0
 }
 
-type Signed struct {
+func (x *RenewChannelLeaseReply) GetOutgoingAmountMsat() int64 {
+	if x != nil {
+		return x.OutgoingAmountMsat
+	}
+	return 0
+}
+
+func (x *RenewChannelLeaseReply) GetLeaseDuration() int64 {
+	if x != nil {
+		return x.LeaseDuration
+	}
+	return 0
+}
+
+func (x *RenewChannelLeaseReply) GetLeaseExpiresAt() int64 {
+	if x != nil {
+		return x.LeaseExpiresAt
+	}
+	return 0
+}
+
+type GetLspInfoRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
-
-	Data      []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
-	Pubkey    []byte `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
-	Signature []byte `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
 }
 
-func (x *Signed) Reset() {
-	*x = Signed{}
+func (x *GetLspInfoRequest) Reset() {
+	*x = GetLspInfoRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_lspd_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -679,13 +933,13 @@ func (x *Signed) Reset() {
 	}
 }
 
-func (x *Signed) String() string {
+func (x *GetLspInfoRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Signed) ProtoMessage() {}
+func (*GetLspInfoRequest) ProtoMessage() {}
 
-func (x *Signed) ProtoReflect() protoreflect.Message {
+func (x *GetLspInfoRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_lspd_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -697,59 +951,206 @@ func (x *Signed) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Signed.ProtoReflect.Descriptor instead.
-func (*Signed) Descriptor() ([]byte, []int) {
+// Deprecated: Use GetLspInfoRequest.ProtoReflect.Descriptor instead.
+func (*GetLspInfoRequest) Descriptor() ([]byte, []int) {
 	return file_lspd_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *Signed) GetData() []byte {
+// The features and limits of the lsp for the token of the client, so clients
+// can adapt to the lsp rather than assume what it supports. Fields and
+// features are only ever added. A change in the meaning of a field bumps
+// version, a change in the behavior of a feature bumps the version of the
+// feature.
+type GetLspInfoReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Version of the reply. Currently 1.
+	Version uint32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Name    string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// The identity pubkey of the Lightning node
+	Pubkey string `protobuf:"bytes,3,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	// The network location of the lightning node
+	Host string `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+	// The bitcoin network of the node: mainnet, testnet, signet or regtest.
+	// Empty if not configured.
+	Network string `protobuf:"bytes,5,opt,name=network,proto3" json:"network,omitempty"`
+	// The features the lsp supports, sorted by name.
+	Features []*LspFeature `protobuf:"bytes,6,rep,name=features,proto3" json:"features,omitempty"`
+	// The LSPS specifications the lsp implements over lightning custom
+	// messages, like 0 for LSPS0. Empty if the node doesn't speak LSPS.
+	LspsVersions []uint32 `protobuf:"varint,7,rep,packed,name=lsps_versions,json=lspsVersions,proto3" json:"lsps_versions,omitempty"`
+	// The largest channel in satoshi opened for a payment. No limit if 0.
+	MaxChannelSizeSat uint64 `protobuf:"varint,8,opt,name=max_channel_size_sat,json=maxChannelSizeSat,proto3" json:"max_channel_size_sat,omitempty"`
+	// The largest htlc in millisatoshi forwarded over channels opened by the
+	// lsp. No limit if 0.
+	MaxHtlcMsat uint64 `protobuf:"varint,9,opt,name=max_htlc_msat,json=maxHtlcMsat,proto3" json:"max_htlc_msat,omitempty"`
+	// The smallest payment in millisatoshi a channel is opened for. No minimum
+	// if 0.
+	MinPaymentSizeMsat uint64 `protobuf:"varint,10,opt,name=min_payment_size_msat,json=minPaymentSizeMsat,proto3" json:"min_payment_size_msat,omitempty"`
+	// The current fee menu, as in ChannelInformationReply.
+	OpeningFeeParamsMenu []*OpeningFeeParams `protobuf:"bytes,11,rep,name=opening_fee_params_menu,json=openingFeeParamsMenu,proto3" json:"opening_fee_params_menu,omitempty"`
+	// The liquidity the lsp leases to clients opening dual-funded channels to
+	// it. Not set if the lsp doesn't advertise liquidity.
+	LiquidityAd *LiquidityAd `protobuf:"bytes,12,opt,name=liquidity_ad,json=liquidityAd,proto3" json:"liquidity_ad,omitempty"`
+	// The commitment types of channels the lsp can open, from the most to the
+	// least preferred. One of them can be requested with RegisterPayment and
+	// OpenChannel.
+	CommitmentTypes []string `protobuf:"bytes,13,rep,name=commitment_types,json=commitmentTypes,proto3" json:"commitment_types,omitempty"`
+}
+
+func (x *GetLspInfoReply) Reset() {
+	*x = GetLspInfoReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetLspInfoReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLspInfoReply) ProtoMessage() {}
+
+func (x *GetLspInfoReply) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLspInfoReply.ProtoReflect.Descriptor instead.
+func (*GetLspInfoReply) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetLspInfoReply) GetVersion() uint32 {
 	if x != nil {
-		return x.Data
+		return x.Version
 	}
-	return nil
+	return 0
 }
 
-func (x *Signed) GetPubkey() []byte {
+func (x *GetLspInfoReply) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GetLspInfoReply) GetPubkey() string {
 	if x != nil {
 		return x.Pubkey
 	}
+	return ""
+}
+
+func (x *GetLspInfoReply) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *GetLspInfoReply) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *GetLspInfoReply) GetFeatures() []*LspFeature {
+	if x != nil {
+		return x.Features
+	}
 	return nil
 }
 
-func (x *Signed) GetSignature() []byte {
+func (x *GetLspInfoReply) GetLspsVersions() []uint32 {
 	if x != nil {
-		return x.Signature
+		return x.LspsVersions
 	}
 	return nil
 }
 
-type CheckChannelsRequest struct {
+func (x *GetLspInfoReply) GetMaxChannelSizeSat() uint64 {
+	if x != nil {
+		return x.MaxChannelSizeSat
+	}
+	return 0
+}
+
+func (x *GetLspInfoReply) GetMaxHtlcMsat() uint64 {
+	if x != nil {
+		return x.MaxHtlcMsat
+	}
+	return 0
+}
+
+func (x *GetLspInfoReply) GetMinPaymentSizeMsat() uint64 {
+	if x != nil {
+		return x.MinPaymentSizeMsat
+	}
+	return 0
+}
+
+func (x *GetLspInfoReply) GetOpeningFeeParamsMenu() []*OpeningFeeParams {
+	if x != nil {
+		return x.OpeningFeeParamsMenu
+	}
+	return nil
+}
+
+func (x *GetLspInfoReply) GetLiquidityAd() *LiquidityAd {
+	if x != nil {
+		return x.LiquidityAd
+	}
+	return nil
+}
+
+func (x *GetLspInfoReply) GetCommitmentTypes() []string {
+	if x != nil {
+		return x.CommitmentTypes
+	}
+	return nil
+}
+
+// A feature of the lsp, like zero_conf or taproot_channels. Clients only rely
+// on a feature if they support its version.
+type LspFeature struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	EncryptPubkey        []byte            `protobuf:"bytes,1,opt,name=encrypt_pubkey,json=encryptPubkey,proto3" json:"encrypt_pubkey,omitempty"`
-	FakeChannels         map[string]uint64 `protobuf:"bytes,2,rep,name=fake_channels,json=fakeChannels,proto3" json:"fake_channels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
-	WaitingCloseChannels map[string]uint64 `protobuf:"bytes,3,rep,name=waiting_close_channels,json=waitingCloseChannels,proto3" json:"waiting_close_channels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version uint32 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
 }
 
-func (x *CheckChannelsRequest) Reset() {
-	*x = CheckChannelsRequest{}
+func (x *LspFeature) Reset() {
+	*x = LspFeature{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_lspd_proto_msgTypes[10]
+		mi := &file_lspd_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *CheckChannelsRequest) String() string {
+func (x *LspFeature) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CheckChannelsRequest) ProtoMessage() {}
+func (*LspFeature) ProtoMessage() {}
 
-func (x *CheckChannelsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_lspd_proto_msgTypes[10]
+func (x *LspFeature) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -760,58 +1161,146 @@ func (x *CheckChannelsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CheckChannelsRequest.ProtoReflect.Descriptor instead.
-func (*CheckChannelsRequest) Descriptor() ([]byte, []int) {
-	return file_lspd_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use LspFeature.ProtoReflect.Descriptor instead.
+func (*LspFeature) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *CheckChannelsRequest) GetEncryptPubkey() []byte {
+func (x *LspFeature) GetName() string {
 	if x != nil {
-		return x.EncryptPubkey
+		return x.Name
 	}
-	return nil
+	return ""
 }
 
-func (x *CheckChannelsRequest) GetFakeChannels() map[string]uint64 {
+func (x *LspFeature) GetVersion() uint32 {
 	if x != nil {
-		return x.FakeChannels
+		return x.Version
 	}
-	return nil
+	return 0
 }
 
-func (x *CheckChannelsRequest) GetWaitingCloseChannels() map[string]uint64 {
+// The rates the lsp leases liquidity at, as in its liquidity ad. The lease fee
+// in satoshi for leasing an amount is lease_fee_base_sat + amount *
+// lease_fee_basis / 10000 + funding_weight * feerate_per_kw / 1000, where
+// feerate_per_kw is the feerate of the funding transaction.
+type LiquidityAd struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	LeaseFeeBaseSat uint64 `protobuf:"varint,1,opt,name=lease_fee_base_sat,json=leaseFeeBaseSat,proto3" json:"lease_fee_base_sat,omitempty"`
+	LeaseFeeBasis   uint32 `protobuf:"varint,2,opt,name=lease_fee_basis,json=leaseFeeBasis,proto3" json:"lease_fee_basis,omitempty"`
+	FundingWeight   uint32 `protobuf:"varint,3,opt,name=funding_weight,json=fundingWeight,proto3" json:"funding_weight,omitempty"`
+	// Maximum routing fees the lsp charges over the leased channel for the
+	// duration of the lease.
+	ChannelFeeMaxBaseMsat                uint32 `protobuf:"varint,4,opt,name=channel_fee_max_base_msat,json=channelFeeMaxBaseMsat,proto3" json:"channel_fee_max_base_msat,omitempty"`
+	ChannelFeeMaxProportionalThousandths uint32 `protobuf:"varint,5,opt,name=channel_fee_max_proportional_thousandths,json=channelFeeMaxProportionalThousandths,proto3" json:"channel_fee_max_proportional_thousandths,omitempty"`
+}
+
+func (x *LiquidityAd) Reset() {
+	*x = LiquidityAd{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LiquidityAd) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LiquidityAd) ProtoMessage() {}
+
+func (x *LiquidityAd) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LiquidityAd.ProtoReflect.Descriptor instead.
+func (*LiquidityAd) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *LiquidityAd) GetLeaseFeeBaseSat() uint64 {
 	if x != nil {
-		return x.WaitingCloseChannels
+		return x.LeaseFeeBaseSat
 	}
-	return nil
+	return 0
 }
 
-type CheckChannelsReply struct {
+func (x *LiquidityAd) GetLeaseFeeBasis() uint32 {
+	if x != nil {
+		return x.LeaseFeeBasis
+	}
+	return 0
+}
+
+func (x *LiquidityAd) GetFundingWeight() uint32 {
+	if x != nil {
+		return x.FundingWeight
+	}
+	return 0
+}
+
+func (x *LiquidityAd) GetChannelFeeMaxBaseMsat() uint32 {
+	if x != nil {
+		return x.ChannelFeeMaxBaseMsat
+	}
+	return 0
+}
+
+func (x *LiquidityAd) GetChannelFeeMaxProportionalThousandths() uint32 {
+	if x != nil {
+		return x.ChannelFeeMaxProportionalThousandths
+	}
+	return 0
+}
+
+// A proof of work challenge guarding OpenChannel. Clients solve it by finding
+// a nonce for which sha256(challenge || pubkey || nonce) starts with
+// difficulty zero bits, where pubkey is the 33 byte identity pubkey of the
+// node the channel is opened to.
+type OpenChannelChallenge struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	NotFakeChannels map[string]uint64 `protobuf:"bytes,1,rep,name=not_fake_channels,json=notFakeChannels,proto3" json:"not_fake_channels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
-	ClosedChannels  map[string]uint64 `protobuf:"bytes,2,rep,name=closed_channels,json=closedChannels,proto3" json:"closed_channels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Challenge []byte `protobuf:"bytes,1,opt,name=challenge,proto3" json:"challenge,omitempty"`
+	// The number of leading zero bits of the hash.
+	Difficulty uint32 `protobuf:"varint,2,opt,name=difficulty,proto3" json:"difficulty,omitempty"`
+	// Unix time in seconds the challenge expires.
+	ExpiresAt int64 `protobuf:"varint,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// Site key of the captcha clients may solve instead of the proof of work.
+	// Empty if captchas are not accepted.
+	CaptchaSiteKey string `protobuf:"bytes,4,opt,name=captcha_site_key,json=captchaSiteKey,proto3" json:"captcha_site_key,omitempty"`
 }
 
-func (x *CheckChannelsReply) Reset() {
-	*x = CheckChannelsReply{}
+func (x *OpenChannelChallenge) Reset() {
+	*x = OpenChannelChallenge{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_lspd_proto_msgTypes[11]
+		mi := &file_lspd_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *CheckChannelsReply) String() string {
+func (x *OpenChannelChallenge) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CheckChannelsReply) ProtoMessage() {}
+func (*OpenChannelChallenge) ProtoMessage() {}
 
-func (x *CheckChannelsReply) ProtoReflect() protoreflect.Message {
-	mi := &file_lspd_proto_msgTypes[11]
+func (x *OpenChannelChallenge) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -822,33 +1311,1565 @@ func (x *CheckChannelsReply) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CheckChannelsReply.ProtoReflect.Descriptor instead.
-func (*CheckChannelsReply) Descriptor() ([]byte, []int) {
-	return file_lspd_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use OpenChannelChallenge.ProtoReflect.Descriptor instead.
+func (*OpenChannelChallenge) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *CheckChannelsReply) GetNotFakeChannels() map[string]uint64 {
+func (x *OpenChannelChallenge) GetChallenge() []byte {
 	if x != nil {
-		return x.NotFakeChannels
+		return x.Challenge
 	}
 	return nil
 }
 
-func (x *CheckChannelsReply) GetClosedChannels() map[string]uint64 {
+func (x *OpenChannelChallenge) GetDifficulty() uint32 {
 	if x != nil {
-		return x.ClosedChannels
+		return x.Difficulty
 	}
-	return nil
+	return 0
 }
 
-var File_lspd_proto protoreflect.FileDescriptor
+func (x *OpenChannelChallenge) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
 
-var file_lspd_proto_rawDesc = []byte{
-	0x0a, 0x0a, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x04, 0x6c, 0x73,
-	0x70, 0x64, 0x22, 0x33, 0x0a, 0x19, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x6e, 0x66,
-	0x6f, 0x72, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+func (x *OpenChannelChallenge) GetCaptchaSiteKey() string {
+	if x != nil {
+		return x.CaptchaSiteKey
+	}
+	return ""
+}
+
+// A priority tier sets how fast the funding transaction of the channel opened
+// for a payment confirms, for a fee on top of the opening fee.
+type PriorityTier struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the tier: economy, normal or urgent.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Fee in millisatoshi charged on top of the opening fee. The difference
+	// between the incoming and outgoing amount of the payment must cover both.
+	ExtraFeeMsat uint64 `protobuf:"varint,2,opt,name=extra_fee_msat,json=extraFeeMsat,proto3" json:"extra_fee_msat,omitempty"`
+}
+
+func (x *PriorityTier) Reset() {
+	*x = PriorityTier{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PriorityTier) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriorityTier) ProtoMessage() {}
+
+func (x *PriorityTier) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriorityTier.ProtoReflect.Descriptor instead.
+func (*PriorityTier) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PriorityTier) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PriorityTier) GetExtraFeeMsat() uint64 {
+	if x != nil {
+		return x.ExtraFeeMsat
+	}
+	return 0
+}
+
+type OpeningFeeParams struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MinMsat      uint64 `protobuf:"varint,1,opt,name=min_msat,json=minMsat,proto3" json:"min_msat,omitempty"`
+	Proportional uint32 `protobuf:"varint,2,opt,name=proportional,proto3" json:"proportional,omitempty"`
+	ValidUntil   string `protobuf:"bytes,3,opt,name=valid_until,json=validUntil,proto3" json:"valid_until,omitempty"`
+	// The channel can be closed if not used this duration in blocks.
+	MaxIdleTime          uint32 `protobuf:"varint,4,opt,name=max_idle_time,json=maxIdleTime,proto3" json:"max_idle_time,omitempty"`
+	MaxClientToSelfDelay uint32 `protobuf:"varint,5,opt,name=max_client_to_self_delay,json=maxClientToSelfDelay,proto3" json:"max_client_to_self_delay,omitempty"`
+	Promise              string `protobuf:"bytes,6,opt,name=promise,proto3" json:"promise,omitempty"`
+}
+
+func (x *OpeningFeeParams) Reset() {
+	*x = OpeningFeeParams{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OpeningFeeParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpeningFeeParams) ProtoMessage() {}
+
+func (x *OpeningFeeParams) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpeningFeeParams.ProtoReflect.Descriptor instead.
+func (*OpeningFeeParams) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *OpeningFeeParams) GetMinMsat() uint64 {
+	if x != nil {
+		return x.MinMsat
+	}
+	return 0
+}
+
+func (x *OpeningFeeParams) GetProportional() uint32 {
+	if x != nil {
+		return x.Proportional
+	}
+	return 0
+}
+
+func (x *OpeningFeeParams) GetValidUntil() string {
+	if x != nil {
+		return x.ValidUntil
+	}
+	return ""
+}
+
+func (x *OpeningFeeParams) GetMaxIdleTime() uint32 {
+	if x != nil {
+		return x.MaxIdleTime
+	}
+	return 0
+}
+
+func (x *OpeningFeeParams) GetMaxClientToSelfDelay() uint32 {
+	if x != nil {
+		return x.MaxClientToSelfDelay
+	}
+	return 0
+}
+
+func (x *OpeningFeeParams) GetPromise() string {
+	if x != nil {
+		return x.Promise
+	}
+	return ""
+}
+
+type OpenChannelRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	/// The identity pubkey of the Lightning node
+	Pubkey string `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	/// The open_channel_challenge of ChannelInformationReply, if set.
+	Challenge []byte `protobuf:"bytes,2,opt,name=challenge,proto3" json:"challenge,omitempty"`
+	/// The nonce solving the challenge.
+	Nonce []byte `protobuf:"bytes,3,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	/// The token of the solved captcha, instead of the challenge and nonce.
+	CaptchaToken string `protobuf:"bytes,4,opt,name=captcha_token,proto3" json:"captcha_token,omitempty"`
+	/// Commitment type of the channel, like commitment_type of
+	/// PaymentInformation.
+	CommitmentType string `protobuf:"bytes,5,opt,name=commitment_type,proto3" json:"commitment_type,omitempty"`
+}
+
+func (x *OpenChannelRequest) Reset() {
+	*x = OpenChannelRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OpenChannelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpenChannelRequest) ProtoMessage() {}
+
+func (x *OpenChannelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpenChannelRequest.ProtoReflect.Descriptor instead.
+func (*OpenChannelRequest) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *OpenChannelRequest) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *OpenChannelRequest) GetChallenge() []byte {
+	if x != nil {
+		return x.Challenge
+	}
+	return nil
+}
+
+func (x *OpenChannelRequest) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *OpenChannelRequest) GetCaptchaToken() string {
+	if x != nil {
+		return x.CaptchaToken
+	}
+	return ""
+}
+
+func (x *OpenChannelRequest) GetCommitmentType() string {
+	if x != nil {
+		return x.CommitmentType
+	}
+	return ""
+}
+
+type OpenChannelReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	/// The transaction hash
+	TxHash string `protobuf:"bytes,1,opt,name=tx_hash,proto3" json:"tx_hash,omitempty"`
+	/// The output index
+	OutputIndex uint32 `protobuf:"varint,2,opt,name=output_index,proto3" json:"output_index,omitempty"`
+	/// The id of the channel open job, to get its status with
+	/// GetChannelOpenStatus. The channel is opened in the background, tx_hash
+	/// and output_index are only set if the job is opened already.
+	JobId string `protobuf:"bytes,3,opt,name=job_id,proto3" json:"job_id,omitempty"`
+}
+
+func (x *OpenChannelReply) Reset() {
+	*x = OpenChannelReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OpenChannelReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpenChannelReply) ProtoMessage() {}
+
+func (x *OpenChannelReply) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpenChannelReply.ProtoReflect.Descriptor instead.
+func (*OpenChannelReply) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *OpenChannelReply) GetTxHash() string {
+	if x != nil {
+		return x.TxHash
+	}
+	return ""
+}
+
+func (x *OpenChannelReply) GetOutputIndex() uint32 {
+	if x != nil {
+		return x.OutputIndex
+	}
+	return 0
+}
+
+func (x *OpenChannelReply) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type GetChannelOpenStatusRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	/// The id of the job returned by OpenChannel
+	JobId string `protobuf:"bytes,1,opt,name=job_id,proto3" json:"job_id,omitempty"`
+}
+
+func (x *GetChannelOpenStatusRequest) Reset() {
+	*x = GetChannelOpenStatusRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetChannelOpenStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetChannelOpenStatusRequest) ProtoMessage() {}
+
+func (x *GetChannelOpenStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetChannelOpenStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetChannelOpenStatusRequest) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GetChannelOpenStatusRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type GetChannelOpenStatusReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	JobId string `protobuf:"bytes,1,opt,name=job_id,proto3" json:"job_id,omitempty"`
+	/// The identity pubkey of the Lightning node the channel is opened to
+	Pubkey string `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	/// One of pending, opening, opened or failed. Failed attempts are retried,
+	/// a job only fails after its last attempt.
+	Status string `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	/// The transaction hash, once opened. Empty if the node already had a
+	/// channel with the client.
+	TxHash string `protobuf:"bytes,4,opt,name=tx_hash,proto3" json:"tx_hash,omitempty"`
+	/// The output index, once opened
+	OutputIndex uint32 `protobuf:"varint,5,opt,name=output_index,proto3" json:"output_index,omitempty"`
+	/// The number of attempts made to open the channel
+	Attempts uint32 `protobuf:"varint,6,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	/// The error of the last failed attempt
+	Error string `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *GetChannelOpenStatusReply) Reset() {
+	*x = GetChannelOpenStatusReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetChannelOpenStatusReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetChannelOpenStatusReply) ProtoMessage() {}
+
+func (x *GetChannelOpenStatusReply) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetChannelOpenStatusReply.ProtoReflect.Descriptor instead.
+func (*GetChannelOpenStatusReply) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *GetChannelOpenStatusReply) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *GetChannelOpenStatusReply) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *GetChannelOpenStatusReply) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *GetChannelOpenStatusReply) GetTxHash() string {
+	if x != nil {
+		return x.TxHash
+	}
+	return ""
+}
+
+func (x *GetChannelOpenStatusReply) GetOutputIndex() uint32 {
+	if x != nil {
+		return x.OutputIndex
+	}
+	return 0
+}
+
+func (x *GetChannelOpenStatusReply) GetAttempts() uint32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *GetChannelOpenStatusReply) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type RegisterPaymentRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Blob []byte `protobuf:"bytes,3,opt,name=blob,proto3" json:"blob,omitempty"`
+	// Signature of the destination node key over the sha256 hash of the
+	// decrypted blob, in 64 byte compact format.
+	Signature []byte `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
+	// Id of the lsp key the blob is encrypted with, the lsp_key_id of
+	// ChannelInformationReply. If empty, all valid keys are tried.
+	KeyId string `protobuf:"bytes,5,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+}
+
+func (x *RegisterPaymentRequest) Reset() {
+	*x = RegisterPaymentRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RegisterPaymentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterPaymentRequest) ProtoMessage() {}
+
+func (x *RegisterPaymentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterPaymentRequest.ProtoReflect.Descriptor instead.
+func (*RegisterPaymentRequest) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *RegisterPaymentRequest) GetBlob() []byte {
+	if x != nil {
+		return x.Blob
+	}
+	return nil
+}
+
+func (x *RegisterPaymentRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *RegisterPaymentRequest) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+type RegisterPaymentReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Short channel id lspd assigned to the payment. Put it in the route hint
+	// of the invoice, so htlcs are matched to the registration by the scid
+	// rather than the payment hash.
+	FakeScid uint64 `protobuf:"varint,1,opt,name=fake_scid,json=fakeScid,proto3" json:"fake_scid,omitempty"`
+}
+
+func (x *RegisterPaymentReply) Reset() {
+	*x = RegisterPaymentReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RegisterPaymentReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterPaymentReply) ProtoMessage() {}
+
+func (x *RegisterPaymentReply) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterPaymentReply.ProtoReflect.Descriptor instead.
+func (*RegisterPaymentReply) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *RegisterPaymentReply) GetFakeScid() uint64 {
+	if x != nil {
+		return x.FakeScid
+	}
+	return 0
+}
+
+type PaymentInformation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PaymentHash        []byte            `protobuf:"bytes,1,opt,name=payment_hash,json=paymentHash,proto3" json:"payment_hash,omitempty"`
+	PaymentSecret      []byte            `protobuf:"bytes,2,opt,name=payment_secret,json=paymentSecret,proto3" json:"payment_secret,omitempty"`
+	Destination        []byte            `protobuf:"bytes,3,opt,name=destination,proto3" json:"destination,omitempty"`
+	IncomingAmountMsat int64             `protobuf:"varint,4,opt,name=incoming_amount_msat,json=incomingAmountMsat,proto3" json:"incoming_amount_msat,omitempty"`
+	OutgoingAmountMsat int64             `protobuf:"varint,5,opt,name=outgoing_amount_msat,json=outgoingAmountMsat,proto3" json:"outgoing_amount_msat,omitempty"`
+	Tag                string            `protobuf:"bytes,6,opt,name=tag,proto3" json:"tag,omitempty"`
+	OpeningFeeParams   *OpeningFeeParams `protobuf:"bytes,7,opt,name=opening_fee_params,json=openingFeeParams,proto3" json:"opening_fee_params,omitempty"`
+	// Unix timestamp in seconds of the registration. Required for signed
+	// registrations.
+	Timestamp int64 `protobuf:"varint,8,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Random value unique for every signed registration of the destination.
+	Nonce []byte `protobuf:"bytes,9,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// Addresses the destination accepts connections on, as host:port. If the
+	// destination is offline when the payment arrives, lspd tries to connect
+	// to these addresses before notifying it or failing the payment.
+	AddressHints []string `protobuf:"bytes,10,rep,name=address_hints,json=addressHints,proto3" json:"address_hints,omitempty"`
+	// Name and version of the wallet sdk of the destination, like
+	// breez-sdk/0.4.2. lspd adapts to known quirks of the sdk version.
+	ClientAgent string `protobuf:"bytes,11,opt,name=client_agent,json=clientAgent,proto3" json:"client_agent,omitempty"`
+	// Priority tier of the funding transaction of the channel opened for the
+	// payment, one of the tiers in ChannelInformationReply. Empty for the
+	// default feerate of the lsp.
+	Priority string `protobuf:"bytes,12,opt,name=priority,proto3" json:"priority,omitempty"`
+	// Commitment type of the channel opened for the payment, one of the
+	// commitment_types of GetLspInfoReply. If the destination doesn't support
+	// it, the channel is opened with anchors or static_remote_key. Empty for
+	// the commitment type of the token.
+	CommitmentType string `protobuf:"bytes,13,opt,name=commitment_type,json=commitmentType,proto3" json:"commitment_type,omitempty"`
+}
+
+func (x *PaymentInformation) Reset() {
+	*x = PaymentInformation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PaymentInformation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaymentInformation) ProtoMessage() {}
+
+func (x *PaymentInformation) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaymentInformation.ProtoReflect.Descriptor instead.
+func (*PaymentInformation) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *PaymentInformation) GetPaymentHash() []byte {
+	if x != nil {
+		return x.PaymentHash
+	}
+	return nil
+}
+
+func (x *PaymentInformation) GetPaymentSecret() []byte {
+	if x != nil {
+		return x.PaymentSecret
+	}
+	return nil
+}
+
+func (x *PaymentInformation) GetDestination() []byte {
+	if x != nil {
+		return x.Destination
+	}
+	return nil
+}
+
+func (x *PaymentInformation) GetIncomingAmountMsat() int64 {
+	if x != nil {
+		return x.IncomingAmountMsat
+	}
+	return 0
+}
+
+func (x *PaymentInformation) GetOutgoingAmountMsat() int64 {
+	if x != nil {
+		return x.OutgoingAmountMsat
+	}
+	return 0
+}
+
+func (x *PaymentInformation) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *PaymentInformation) GetOpeningFeeParams() *OpeningFeeParams {
+	if x != nil {
+		return x.OpeningFeeParams
+	}
+	return nil
+}
+
+func (x *PaymentInformation) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *PaymentInformation) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *PaymentInformation) GetAddressHints() []string {
+	if x != nil {
+		return x.AddressHints
+	}
+	return nil
+}
+
+func (x *PaymentInformation) GetClientAgent() string {
+	if x != nil {
+		return x.ClientAgent
+	}
+	return ""
+}
+
+func (x *PaymentInformation) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *PaymentInformation) GetCommitmentType() string {
+	if x != nil {
+		return x.CommitmentType
+	}
+	return ""
+}
+
+type CancelPaymentRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Encrypted CancelPaymentInformation, like the blob of
+	// RegisterPaymentRequest.
+	Blob []byte `protobuf:"bytes,1,opt,name=blob,proto3" json:"blob,omitempty"`
+	// Signature of the destination node key over the sha256 hash of the
+	// decrypted blob, in 64 byte compact format. Required.
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	// Id of the lsp key the blob is encrypted with, the lsp_key_id of
+	// ChannelInformationReply. If empty, all valid keys are tried.
+	KeyId string `protobuf:"bytes,3,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+}
+
+func (x *CancelPaymentRequest) Reset() {
+	*x = CancelPaymentRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CancelPaymentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelPaymentRequest) ProtoMessage() {}
+
+func (x *CancelPaymentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelPaymentRequest.ProtoReflect.Descriptor instead.
+func (*CancelPaymentRequest) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *CancelPaymentRequest) GetBlob() []byte {
+	if x != nil {
+		return x.Blob
+	}
+	return nil
+}
+
+func (x *CancelPaymentRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *CancelPaymentRequest) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+type CancelPaymentReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *CancelPaymentReply) Reset() {
+	*x = CancelPaymentReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CancelPaymentReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelPaymentReply) ProtoMessage() {}
+
+func (x *CancelPaymentReply) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelPaymentReply.ProtoReflect.Descriptor instead.
+func (*CancelPaymentReply) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{24}
+}
+
+type CancelPaymentInformation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PaymentHash []byte `protobuf:"bytes,1,opt,name=payment_hash,json=paymentHash,proto3" json:"payment_hash,omitempty"`
+	Destination []byte `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"`
+	// Unix timestamp in seconds of the cancellation.
+	Timestamp int64 `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Random value unique for every signed request of the destination.
+	Nonce []byte `protobuf:"bytes,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+}
+
+func (x *CancelPaymentInformation) Reset() {
+	*x = CancelPaymentInformation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CancelPaymentInformation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelPaymentInformation) ProtoMessage() {}
+
+func (x *CancelPaymentInformation) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelPaymentInformation.ProtoReflect.Descriptor instead.
+func (*CancelPaymentInformation) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *CancelPaymentInformation) GetPaymentHash() []byte {
+	if x != nil {
+		return x.PaymentHash
+	}
+	return nil
+}
+
+func (x *CancelPaymentInformation) GetDestination() []byte {
+	if x != nil {
+		return x.Destination
+	}
+	return nil
+}
+
+func (x *CancelPaymentInformation) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *CancelPaymentInformation) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+type GeneratePaymentHashRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Encrypted GeneratePaymentHashInformation, like the blob of
+	// RegisterPaymentRequest.
+	Blob []byte `protobuf:"bytes,1,opt,name=blob,proto3" json:"blob,omitempty"`
+	// Signature of the destination node key over the sha256 hash of the
+	// decrypted blob, in 64 byte compact format. Required.
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	// Id of the lsp key the blob is encrypted with, the lsp_key_id of
+	// ChannelInformationReply. If empty, all valid keys are tried.
+	KeyId string `protobuf:"bytes,3,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+}
+
+func (x *GeneratePaymentHashRequest) Reset() {
+	*x = GeneratePaymentHashRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GeneratePaymentHashRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GeneratePaymentHashRequest) ProtoMessage() {}
+
+func (x *GeneratePaymentHashRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GeneratePaymentHashRequest.ProtoReflect.Descriptor instead.
+func (*GeneratePaymentHashRequest) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *GeneratePaymentHashRequest) GetBlob() []byte {
+	if x != nil {
+		return x.Blob
+	}
+	return nil
+}
+
+func (x *GeneratePaymentHashRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *GeneratePaymentHashRequest) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+type GeneratePaymentHashReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Payment hash generated by the lsp, to be used in the invoice of the
+	// destination and registered with RegisterPayment. The preimage is released
+	// to the destination with ClaimPreimage.
+	PaymentHash []byte `protobuf:"bytes,1,opt,name=payment_hash,json=paymentHash,proto3" json:"payment_hash,omitempty"`
+}
+
+func (x *GeneratePaymentHashReply) Reset() {
+	*x = GeneratePaymentHashReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[27]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GeneratePaymentHashReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GeneratePaymentHashReply) ProtoMessage() {}
+
+func (x *GeneratePaymentHashReply) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[27]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GeneratePaymentHashReply.ProtoReflect.Descriptor instead.
+func (*GeneratePaymentHashReply) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *GeneratePaymentHashReply) GetPaymentHash() []byte {
+	if x != nil {
+		return x.PaymentHash
+	}
+	return nil
+}
+
+type GeneratePaymentHashInformation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Destination []byte `protobuf:"bytes,1,opt,name=destination,proto3" json:"destination,omitempty"`
+	// Unix timestamp in seconds of the request.
+	Timestamp int64 `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Random value unique for every signed request of the destination.
+	Nonce []byte `protobuf:"bytes,3,opt,name=nonce,proto3" json:"nonce,omitempty"`
+}
+
+func (x *GeneratePaymentHashInformation) Reset() {
+	*x = GeneratePaymentHashInformation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[28]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GeneratePaymentHashInformation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GeneratePaymentHashInformation) ProtoMessage() {}
+
+func (x *GeneratePaymentHashInformation) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[28]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GeneratePaymentHashInformation.ProtoReflect.Descriptor instead.
+func (*GeneratePaymentHashInformation) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *GeneratePaymentHashInformation) GetDestination() []byte {
+	if x != nil {
+		return x.Destination
+	}
+	return nil
+}
+
+func (x *GeneratePaymentHashInformation) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *GeneratePaymentHashInformation) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+type ClaimPreimageRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Encrypted ClaimPreimageInformation, like the blob of
+	// RegisterPaymentRequest.
+	Blob []byte `protobuf:"bytes,1,opt,name=blob,proto3" json:"blob,omitempty"`
+	// Signature of the destination node key over the sha256 hash of the
+	// decrypted blob, in 64 byte compact format. Required.
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	// Id of the lsp key the blob is encrypted with, the lsp_key_id of
+	// ChannelInformationReply. If empty, all valid keys are tried.
+	KeyId string `protobuf:"bytes,3,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+}
+
+func (x *ClaimPreimageRequest) Reset() {
+	*x = ClaimPreimageRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[29]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClaimPreimageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClaimPreimageRequest) ProtoMessage() {}
+
+func (x *ClaimPreimageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[29]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClaimPreimageRequest.ProtoReflect.Descriptor instead.
+func (*ClaimPreimageRequest) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ClaimPreimageRequest) GetBlob() []byte {
+	if x != nil {
+		return x.Blob
+	}
+	return nil
+}
+
+func (x *ClaimPreimageRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *ClaimPreimageRequest) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+type ClaimPreimageReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Preimage []byte `protobuf:"bytes,1,opt,name=preimage,proto3" json:"preimage,omitempty"`
+}
+
+func (x *ClaimPreimageReply) Reset() {
+	*x = ClaimPreimageReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[30]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClaimPreimageReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClaimPreimageReply) ProtoMessage() {}
+
+func (x *ClaimPreimageReply) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[30]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClaimPreimageReply.ProtoReflect.Descriptor instead.
+func (*ClaimPreimageReply) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ClaimPreimageReply) GetPreimage() []byte {
+	if x != nil {
+		return x.Preimage
+	}
+	return nil
+}
+
+type ClaimPreimageInformation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PaymentHash []byte `protobuf:"bytes,1,opt,name=payment_hash,json=paymentHash,proto3" json:"payment_hash,omitempty"`
+	Destination []byte `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"`
+	// Unix timestamp in seconds of the request.
+	Timestamp int64 `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Random value unique for every signed request of the destination.
+	Nonce []byte `protobuf:"bytes,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+}
+
+func (x *ClaimPreimageInformation) Reset() {
+	*x = ClaimPreimageInformation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[31]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClaimPreimageInformation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClaimPreimageInformation) ProtoMessage() {}
+
+func (x *ClaimPreimageInformation) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[31]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClaimPreimageInformation.ProtoReflect.Descriptor instead.
+func (*ClaimPreimageInformation) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ClaimPreimageInformation) GetPaymentHash() []byte {
+	if x != nil {
+		return x.PaymentHash
+	}
+	return nil
+}
+
+func (x *ClaimPreimageInformation) GetDestination() []byte {
+	if x != nil {
+		return x.Destination
+	}
+	return nil
+}
+
+func (x *ClaimPreimageInformation) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ClaimPreimageInformation) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+type Encrypted struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	// Id of the lsp key the data is encrypted with. If empty, all valid keys
+	// are tried.
+	KeyId string `protobuf:"bytes,2,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+}
+
+func (x *Encrypted) Reset() {
+	*x = Encrypted{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[32]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Encrypted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Encrypted) ProtoMessage() {}
+
+func (x *Encrypted) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[32]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Encrypted.ProtoReflect.Descriptor instead.
+func (*Encrypted) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *Encrypted) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Encrypted) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+type Signed struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data      []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Pubkey    []byte `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	Signature []byte `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (x *Signed) Reset() {
+	*x = Signed{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[33]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Signed) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Signed) ProtoMessage() {}
+
+func (x *Signed) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[33]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Signed.ProtoReflect.Descriptor instead.
+func (*Signed) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *Signed) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Signed) GetPubkey() []byte {
+	if x != nil {
+		return x.Pubkey
+	}
+	return nil
+}
+
+func (x *Signed) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type CheckChannelsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	EncryptPubkey        []byte            `protobuf:"bytes,1,opt,name=encrypt_pubkey,json=encryptPubkey,proto3" json:"encrypt_pubkey,omitempty"`
+	FakeChannels         map[string]uint64 `protobuf:"bytes,2,rep,name=fake_channels,json=fakeChannels,proto3" json:"fake_channels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	WaitingCloseChannels map[string]uint64 `protobuf:"bytes,3,rep,name=waiting_close_channels,json=waitingCloseChannels,proto3" json:"waiting_close_channels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+}
+
+func (x *CheckChannelsRequest) Reset() {
+	*x = CheckChannelsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[34]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CheckChannelsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckChannelsRequest) ProtoMessage() {}
+
+func (x *CheckChannelsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[34]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckChannelsRequest.ProtoReflect.Descriptor instead.
+func (*CheckChannelsRequest) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *CheckChannelsRequest) GetEncryptPubkey() []byte {
+	if x != nil {
+		return x.EncryptPubkey
+	}
+	return nil
+}
+
+func (x *CheckChannelsRequest) GetFakeChannels() map[string]uint64 {
+	if x != nil {
+		return x.FakeChannels
+	}
+	return nil
+}
+
+func (x *CheckChannelsRequest) GetWaitingCloseChannels() map[string]uint64 {
+	if x != nil {
+		return x.WaitingCloseChannels
+	}
+	return nil
+}
+
+type CheckChannelsReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NotFakeChannels map[string]uint64 `protobuf:"bytes,1,rep,name=not_fake_channels,json=notFakeChannels,proto3" json:"not_fake_channels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	ClosedChannels  map[string]uint64 `protobuf:"bytes,2,rep,name=closed_channels,json=closedChannels,proto3" json:"closed_channels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+}
+
+func (x *CheckChannelsReply) Reset() {
+	*x = CheckChannelsReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_lspd_proto_msgTypes[35]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CheckChannelsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckChannelsReply) ProtoMessage() {}
+
+func (x *CheckChannelsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_lspd_proto_msgTypes[35]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckChannelsReply.ProtoReflect.Descriptor instead.
+func (*CheckChannelsReply) Descriptor() ([]byte, []int) {
+	return file_lspd_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *CheckChannelsReply) GetNotFakeChannels() map[string]uint64 {
+	if x != nil {
+		return x.NotFakeChannels
+	}
+	return nil
+}
+
+func (x *CheckChannelsReply) GetClosedChannels() map[string]uint64 {
+	if x != nil {
+		return x.ClosedChannels
+	}
+	return nil
+}
+
+var File_lspd_proto protoreflect.FileDescriptor
+
+var file_lspd_proto_rawDesc = []byte{
+	0x0a, 0x0a, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x04, 0x6c, 0x73,
+	0x70, 0x64, 0x22, 0x33, 0x0a, 0x19, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x6e, 0x66,
+	0x6f, 0x72, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
 	0x16, 0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x22, 0xd4, 0x04, 0x0a, 0x17, 0x43, 0x68, 0x61, 0x6e,
+	0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x22, 0xb2, 0x06, 0x0a, 0x17, 0x43, 0x68, 0x61, 0x6e,
 	0x6e, 0x65, 0x6c, 0x49, 0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65,
 	0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
 	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65,
@@ -885,131 +2906,440 @@ var file_lspd_proto_rawDesc = []byte{
 	0x61, 0x72, 0x61, 0x6d, 0x73, 0x5f, 0x6d, 0x65, 0x6e, 0x75, 0x18, 0x0e, 0x20, 0x03, 0x28, 0x0b,
 	0x32, 0x16, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x4f, 0x70, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x46,
 	0x65, 0x65, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x52, 0x14, 0x6f, 0x70, 0x65, 0x6e, 0x69, 0x6e,
-	0x67, 0x46, 0x65, 0x65, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x4d, 0x65, 0x6e, 0x75, 0x22, 0xe8,
-	0x01, 0x0a, 0x10, 0x4f, 0x70, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x46, 0x65, 0x65, 0x50, 0x61, 0x72,
-	0x61, 0x6d, 0x73, 0x12, 0x19, 0x0a, 0x08, 0x6d, 0x69, 0x6e, 0x5f, 0x6d, 0x73, 0x61, 0x74, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x6d, 0x69, 0x6e, 0x4d, 0x73, 0x61, 0x74, 0x12, 0x22,
-	0x0a, 0x0c, 0x70, 0x72, 0x6f, 0x70, 0x6f, 0x72, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x70, 0x72, 0x6f, 0x70, 0x6f, 0x72, 0x74, 0x69, 0x6f, 0x6e,
-	0x61, 0x6c, 0x12, 0x1f, 0x0a, 0x0b, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x5f, 0x75, 0x6e, 0x74, 0x69,
-	0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x55, 0x6e,
-	0x74, 0x69, 0x6c, 0x12, 0x22, 0x0a, 0x0d, 0x6d, 0x61, 0x78, 0x5f, 0x69, 0x64, 0x6c, 0x65, 0x5f,
-	0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x6d, 0x61, 0x78, 0x49,
-	0x64, 0x6c, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x36, 0x0a, 0x18, 0x6d, 0x61, 0x78, 0x5f, 0x63,
-	0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x74, 0x6f, 0x5f, 0x73, 0x65, 0x6c, 0x66, 0x5f, 0x64, 0x65,
-	0x6c, 0x61, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x14, 0x6d, 0x61, 0x78, 0x43, 0x6c,
-	0x69, 0x65, 0x6e, 0x74, 0x54, 0x6f, 0x53, 0x65, 0x6c, 0x66, 0x44, 0x65, 0x6c, 0x61, 0x79, 0x12,
-	0x18, 0x0a, 0x07, 0x70, 0x72, 0x6f, 0x6d, 0x69, 0x73, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x07, 0x70, 0x72, 0x6f, 0x6d, 0x69, 0x73, 0x65, 0x22, 0x2c, 0x0a, 0x12, 0x4f, 0x70, 0x65,
-	0x6e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
-	0x16, 0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x22, 0x50, 0x0a, 0x10, 0x4f, 0x70, 0x65, 0x6e, 0x43,
+	0x67, 0x46, 0x65, 0x65, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x4d, 0x65, 0x6e, 0x75, 0x12, 0x1c,
+	0x0a, 0x0a, 0x6c, 0x73, 0x70, 0x5f, 0x6b, 0x65, 0x79, 0x5f, 0x69, 0x64, 0x18, 0x0f, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x6c, 0x73, 0x70, 0x4b, 0x65, 0x79, 0x49, 0x64, 0x12, 0x31, 0x0a, 0x15,
+	0x6d, 0x69, 0x6e, 0x5f, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x73, 0x69, 0x7a, 0x65,
+	0x5f, 0x6d, 0x73, 0x61, 0x74, 0x18, 0x10, 0x20, 0x01, 0x28, 0x04, 0x52, 0x12, 0x6d, 0x69, 0x6e,
+	0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x69, 0x7a, 0x65, 0x4d, 0x73, 0x61, 0x74, 0x12,
+	0x39, 0x0a, 0x0e, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x5f, 0x74, 0x69, 0x65, 0x72,
+	0x73, 0x18, 0x11, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x50,
+	0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x54, 0x69, 0x65, 0x72, 0x52, 0x0d, 0x70, 0x72, 0x69,
+	0x6f, 0x72, 0x69, 0x74, 0x79, 0x54, 0x69, 0x65, 0x72, 0x73, 0x12, 0x50, 0x0a, 0x16, 0x6f, 0x70,
+	0x65, 0x6e, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x5f, 0x63, 0x68, 0x61, 0x6c, 0x6c,
+	0x65, 0x6e, 0x67, 0x65, 0x18, 0x12, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x6c, 0x73, 0x70,
+	0x64, 0x2e, 0x4f, 0x70, 0x65, 0x6e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x43, 0x68, 0x61,
+	0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x52, 0x14, 0x6f, 0x70, 0x65, 0x6e, 0x43, 0x68, 0x61, 0x6e,
+	0x6e, 0x65, 0x6c, 0x43, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x22, 0x64, 0x0a, 0x19,
+	0x4c, 0x69, 0x73, 0x74, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65,
+	0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x6c, 0x6f,
+	0x62, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x12, 0x1c, 0x0a,
+	0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x6b,
+	0x65, 0x79, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6b, 0x65, 0x79,
+	0x49, 0x64, 0x22, 0x9c, 0x01, 0x0a, 0x1d, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x6c, 0x69, 0x65, 0x6e,
+	0x74, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x49, 0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x20, 0x0a, 0x0b, 0x64, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x74, 0x69,
+	0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x69, 0x6e,
+	0x63, 0x6c, 0x75, 0x64, 0x65, 0x5f, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x0d, 0x69, 0x6e, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x43, 0x6c, 0x6f, 0x73, 0x65,
+	0x64, 0x22, 0x4a, 0x0a, 0x17, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43,
+	0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x2f, 0x0a, 0x08,
+	0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13,
+	0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x68, 0x61, 0x6e,
+	0x6e, 0x65, 0x6c, 0x52, 0x08, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x22, 0xa7, 0x03,
+	0x0a, 0x0d, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x12,
+	0x23, 0x0a, 0x0d, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x5f, 0x70, 0x6f, 0x69, 0x6e, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x50,
+	0x6f, 0x69, 0x6e, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79,
+	0x5f, 0x73, 0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x63, 0x61, 0x70, 0x61,
+	0x63, 0x69, 0x74, 0x79, 0x53, 0x61, 0x74, 0x12, 0x2e, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43, 0x6c,
+	0x69, 0x65, 0x6e, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x65,
+	0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x61, 0x6c, 0x69, 0x61, 0x73,
+	0x5f, 0x73, 0x63, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x61, 0x6c, 0x69,
+	0x61, 0x73, 0x53, 0x63, 0x69, 0x64, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x72,
+	0x6d, 0x65, 0x64, 0x5f, 0x73, 0x63, 0x69, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x65, 0x64, 0x53, 0x63, 0x69, 0x64, 0x12, 0x1b, 0x0a,
+	0x09, 0x6f, 0x70, 0x65, 0x6e, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x08, 0x6f, 0x70, 0x65, 0x6e, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x65, 0x78,
+	0x70, 0x69, 0x72, 0x65, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09,
+	0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x6c, 0x6f,
+	0x73, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x63, 0x6c,
+	0x6f, 0x73, 0x65, 0x64, 0x41, 0x74, 0x12, 0x28, 0x0a, 0x10, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x5f,
+	0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0e, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x45, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41, 0x74,
+	0x12, 0x2c, 0x0a, 0x12, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x66, 0x75, 0x6e, 0x64, 0x69,
+	0x6e, 0x67, 0x5f, 0x73, 0x61, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x04, 0x52, 0x10, 0x63, 0x6c,
+	0x69, 0x65, 0x6e, 0x74, 0x46, 0x75, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x53, 0x61, 0x74, 0x12, 0x27,
+	0x0a, 0x0f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x74, 0x79, 0x70,
+	0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x6d,
+	0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x22, 0x63, 0x0a, 0x18, 0x52, 0x65, 0x6e, 0x65, 0x77,
+	0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61,
+	0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x6b, 0x65, 0x79, 0x5f, 0x69, 0x64, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6b, 0x65, 0x79, 0x49, 0x64, 0x22, 0x95, 0x02, 0x0a,
+	0x1c, 0x52, 0x65, 0x6e, 0x65, 0x77, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4c, 0x65, 0x61,
+	0x73, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x20, 0x0a,
+	0x0b, 0x64, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x14, 0x0a,
+	0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f,
+	0x6e, 0x63, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x5f, 0x70,
+	0x6f, 0x69, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x68, 0x61, 0x6e,
+	0x6e, 0x65, 0x6c, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61, 0x79, 0x6d,
+	0x65, 0x6e, 0x74, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b,
+	0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68, 0x12, 0x25, 0x0a, 0x0e, 0x70,
+	0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x0d, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x65, 0x63, 0x72,
+	0x65, 0x74, 0x12, 0x30, 0x0a, 0x14, 0x69, 0x6e, 0x63, 0x6f, 0x6d, 0x69, 0x6e, 0x67, 0x5f, 0x61,
+	0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x6d, 0x73, 0x61, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x12, 0x69, 0x6e, 0x63, 0x6f, 0x6d, 0x69, 0x6e, 0x67, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74,
+	0x4d, 0x73, 0x61, 0x74, 0x22, 0xb6, 0x01, 0x0a, 0x16, 0x52, 0x65, 0x6e, 0x65, 0x77, 0x43, 0x68,
+	0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12,
+	0x19, 0x0a, 0x08, 0x66, 0x65, 0x65, 0x5f, 0x6d, 0x73, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x07, 0x66, 0x65, 0x65, 0x4d, 0x73, 0x61, 0x74, 0x12, 0x30, 0x0a, 0x14, 0x6f, 0x75,
+	0x74, 0x67, 0x6f, 0x69, 0x6e, 0x67, 0x5f, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x6d, 0x73,
+	0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x12, 0x6f, 0x75, 0x74, 0x67, 0x6f, 0x69,
+	0x6e, 0x67, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x4d, 0x73, 0x61, 0x74, 0x12, 0x25, 0x0a, 0x0e,
+	0x6c, 0x65, 0x61, 0x73, 0x65, 0x5f, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x44, 0x75, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x28, 0x0a, 0x10, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x5f, 0x65, 0x78, 0x70,
+	0x69, 0x72, 0x65, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x6c,
+	0x65, 0x61, 0x73, 0x65, 0x45, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41, 0x74, 0x22, 0x13, 0x0a,
+	0x11, 0x47, 0x65, 0x74, 0x4c, 0x73, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x22, 0x90, 0x04, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x4c, 0x73, 0x70, 0x49, 0x6e, 0x66,
+	0x6f, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x12, 0x12, 0x0a, 0x04,
+	0x68, 0x6f, 0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x6f, 0x73, 0x74,
+	0x12, 0x18, 0x0a, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x12, 0x2c, 0x0a, 0x08, 0x66, 0x65,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x6c,
+	0x73, 0x70, 0x64, 0x2e, 0x4c, 0x73, 0x70, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x08,
+	0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x6c, 0x73, 0x70, 0x73,
+	0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0d, 0x52,
+	0x0c, 0x6c, 0x73, 0x70, 0x73, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x2f, 0x0a,
+	0x14, 0x6d, 0x61, 0x78, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x5f, 0x73, 0x69, 0x7a,
+	0x65, 0x5f, 0x73, 0x61, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x04, 0x52, 0x11, 0x6d, 0x61, 0x78,
+	0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x53, 0x69, 0x7a, 0x65, 0x53, 0x61, 0x74, 0x12, 0x22,
+	0x0a, 0x0d, 0x6d, 0x61, 0x78, 0x5f, 0x68, 0x74, 0x6c, 0x63, 0x5f, 0x6d, 0x73, 0x61, 0x74, 0x18,
+	0x09, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x6d, 0x61, 0x78, 0x48, 0x74, 0x6c, 0x63, 0x4d, 0x73,
+	0x61, 0x74, 0x12, 0x31, 0x0a, 0x15, 0x6d, 0x69, 0x6e, 0x5f, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e,
+	0x74, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x5f, 0x6d, 0x73, 0x61, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x12, 0x6d, 0x69, 0x6e, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x69, 0x7a,
+	0x65, 0x4d, 0x73, 0x61, 0x74, 0x12, 0x4d, 0x0a, 0x17, 0x6f, 0x70, 0x65, 0x6e, 0x69, 0x6e, 0x67,
+	0x5f, 0x66, 0x65, 0x65, 0x5f, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x5f, 0x6d, 0x65, 0x6e, 0x75,
+	0x18, 0x0b, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x4f, 0x70,
+	0x65, 0x6e, 0x69, 0x6e, 0x67, 0x46, 0x65, 0x65, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x52, 0x14,
+	0x6f, 0x70, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x46, 0x65, 0x65, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73,
+	0x4d, 0x65, 0x6e, 0x75, 0x12, 0x34, 0x0a, 0x0c, 0x6c, 0x69, 0x71, 0x75, 0x69, 0x64, 0x69, 0x74,
+	0x79, 0x5f, 0x61, 0x64, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x6c, 0x73, 0x70,
+	0x64, 0x2e, 0x4c, 0x69, 0x71, 0x75, 0x69, 0x64, 0x69, 0x74, 0x79, 0x41, 0x64, 0x52, 0x0b, 0x6c,
+	0x69, 0x71, 0x75, 0x69, 0x64, 0x69, 0x74, 0x79, 0x41, 0x64, 0x12, 0x29, 0x0a, 0x10, 0x63, 0x6f,
+	0x6d, 0x6d, 0x69, 0x74, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x18, 0x0d,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x0f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x6d, 0x65, 0x6e, 0x74,
+	0x54, 0x79, 0x70, 0x65, 0x73, 0x22, 0x3a, 0x0a, 0x0a, 0x4c, 0x73, 0x70, 0x46, 0x65, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x22, 0x9b, 0x02, 0x0a, 0x0b, 0x4c, 0x69, 0x71, 0x75, 0x69, 0x64, 0x69, 0x74, 0x79, 0x41,
+	0x64, 0x12, 0x2b, 0x0a, 0x12, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x5f, 0x66, 0x65, 0x65, 0x5f, 0x62,
+	0x61, 0x73, 0x65, 0x5f, 0x73, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x6c,
+	0x65, 0x61, 0x73, 0x65, 0x46, 0x65, 0x65, 0x42, 0x61, 0x73, 0x65, 0x53, 0x61, 0x74, 0x12, 0x26,
+	0x0a, 0x0f, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x5f, 0x66, 0x65, 0x65, 0x5f, 0x62, 0x61, 0x73, 0x69,
+	0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0d, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x46, 0x65,
+	0x65, 0x42, 0x61, 0x73, 0x69, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x66, 0x75, 0x6e, 0x64, 0x69, 0x6e,
+	0x67, 0x5f, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0d,
+	0x66, 0x75, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x57, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x38, 0x0a,
+	0x19, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x5f, 0x66, 0x65, 0x65, 0x5f, 0x6d, 0x61, 0x78,
+	0x5f, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x6d, 0x73, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x15, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x46, 0x65, 0x65, 0x4d, 0x61, 0x78, 0x42,
+	0x61, 0x73, 0x65, 0x4d, 0x73, 0x61, 0x74, 0x12, 0x56, 0x0a, 0x28, 0x63, 0x68, 0x61, 0x6e, 0x6e,
+	0x65, 0x6c, 0x5f, 0x66, 0x65, 0x65, 0x5f, 0x6d, 0x61, 0x78, 0x5f, 0x70, 0x72, 0x6f, 0x70, 0x6f,
+	0x72, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x5f, 0x74, 0x68, 0x6f, 0x75, 0x73, 0x61, 0x6e, 0x64,
+	0x74, 0x68, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x24, 0x63, 0x68, 0x61, 0x6e, 0x6e,
+	0x65, 0x6c, 0x46, 0x65, 0x65, 0x4d, 0x61, 0x78, 0x50, 0x72, 0x6f, 0x70, 0x6f, 0x72, 0x74, 0x69,
+	0x6f, 0x6e, 0x61, 0x6c, 0x54, 0x68, 0x6f, 0x75, 0x73, 0x61, 0x6e, 0x64, 0x74, 0x68, 0x73, 0x22,
+	0x9d, 0x01, 0x0a, 0x14, 0x4f, 0x70, 0x65, 0x6e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x43,
+	0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x68, 0x61, 0x6c,
+	0x6c, 0x65, 0x6e, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x63, 0x68, 0x61,
+	0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x64, 0x69, 0x66, 0x66, 0x69, 0x63,
+	0x75, 0x6c, 0x74, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x64, 0x69, 0x66, 0x66,
+	0x69, 0x63, 0x75, 0x6c, 0x74, 0x79, 0x12, 0x1d, 0x0a, 0x0a, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65,
+	0x73, 0x5f, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x65, 0x78, 0x70, 0x69,
+	0x72, 0x65, 0x73, 0x41, 0x74, 0x12, 0x28, 0x0a, 0x10, 0x63, 0x61, 0x70, 0x74, 0x63, 0x68, 0x61,
+	0x5f, 0x73, 0x69, 0x74, 0x65, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0e, 0x63, 0x61, 0x70, 0x74, 0x63, 0x68, 0x61, 0x53, 0x69, 0x74, 0x65, 0x4b, 0x65, 0x79, 0x22,
+	0x48, 0x0a, 0x0c, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x54, 0x69, 0x65, 0x72, 0x12,
+	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x12, 0x24, 0x0a, 0x0e, 0x65, 0x78, 0x74, 0x72, 0x61, 0x5f, 0x66, 0x65, 0x65,
+	0x5f, 0x6d, 0x73, 0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x65, 0x78, 0x74,
+	0x72, 0x61, 0x46, 0x65, 0x65, 0x4d, 0x73, 0x61, 0x74, 0x22, 0xe8, 0x01, 0x0a, 0x10, 0x4f, 0x70,
+	0x65, 0x6e, 0x69, 0x6e, 0x67, 0x46, 0x65, 0x65, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x12, 0x19,
+	0x0a, 0x08, 0x6d, 0x69, 0x6e, 0x5f, 0x6d, 0x73, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x07, 0x6d, 0x69, 0x6e, 0x4d, 0x73, 0x61, 0x74, 0x12, 0x22, 0x0a, 0x0c, 0x70, 0x72, 0x6f,
+	0x70, 0x6f, 0x72, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x0c, 0x70, 0x72, 0x6f, 0x70, 0x6f, 0x72, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x12, 0x1f, 0x0a,
+	0x0b, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x5f, 0x75, 0x6e, 0x74, 0x69, 0x6c, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x55, 0x6e, 0x74, 0x69, 0x6c, 0x12, 0x22,
+	0x0a, 0x0d, 0x6d, 0x61, 0x78, 0x5f, 0x69, 0x64, 0x6c, 0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x6d, 0x61, 0x78, 0x49, 0x64, 0x6c, 0x65, 0x54, 0x69,
+	0x6d, 0x65, 0x12, 0x36, 0x0a, 0x18, 0x6d, 0x61, 0x78, 0x5f, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74,
+	0x5f, 0x74, 0x6f, 0x5f, 0x73, 0x65, 0x6c, 0x66, 0x5f, 0x64, 0x65, 0x6c, 0x61, 0x79, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x14, 0x6d, 0x61, 0x78, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x54,
+	0x6f, 0x53, 0x65, 0x6c, 0x66, 0x44, 0x65, 0x6c, 0x61, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x72,
+	0x6f, 0x6d, 0x69, 0x73, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x72, 0x6f,
+	0x6d, 0x69, 0x73, 0x65, 0x22, 0xb0, 0x01, 0x0a, 0x12, 0x4f, 0x70, 0x65, 0x6e, 0x43, 0x68, 0x61,
+	0x6e, 0x6e, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x70,
+	0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x75, 0x62,
+	0x6b, 0x65, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x24, 0x0a, 0x0d, 0x63, 0x61, 0x70, 0x74, 0x63,
+	0x68, 0x61, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d,
+	0x63, 0x61, 0x70, 0x74, 0x63, 0x68, 0x61, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x28, 0x0a,
+	0x0f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x6d, 0x65,
+	0x6e, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x22, 0x68, 0x0a, 0x10, 0x4f, 0x70, 0x65, 0x6e, 0x43,
 	0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x74,
 	0x78, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x74, 0x78,
 	0x5f, 0x68, 0x61, 0x73, 0x68, 0x12, 0x22, 0x0a, 0x0c, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x5f,
 	0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x6f, 0x75, 0x74,
-	0x70, 0x75, 0x74, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x22, 0x2c, 0x0a, 0x16, 0x52, 0x65, 0x67,
-	0x69, 0x73, 0x74, 0x65, 0x72, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x18, 0x03, 0x20, 0x01, 0x28,
-	0x0c, 0x52, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x22, 0x16, 0x0a, 0x14, 0x52, 0x65, 0x67, 0x69, 0x73,
-	0x74, 0x65, 0x72, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22,
-	0xbc, 0x02, 0x0a, 0x12, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x72,
-	0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e,
+	0x70, 0x75, 0x74, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x16, 0x0a, 0x06, 0x6a, 0x6f, 0x62,
+	0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6a, 0x6f, 0x62, 0x5f, 0x69,
+	0x64, 0x22, 0x35, 0x0a, 0x1b, 0x47, 0x65, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4f,
+	0x70, 0x65, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x16, 0x0a, 0x06, 0x6a, 0x6f, 0x62, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x6a, 0x6f, 0x62, 0x5f, 0x69, 0x64, 0x22, 0xd3, 0x01, 0x0a, 0x19, 0x47, 0x65, 0x74,
+	0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4f, 0x70, 0x65, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x6a, 0x6f, 0x62, 0x5f, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6a, 0x6f, 0x62, 0x5f, 0x69, 0x64, 0x12, 0x16,
+	0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x18,
+	0x0a, 0x07, 0x74, 0x78, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x74, 0x78, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x12, 0x22, 0x0a, 0x0c, 0x6f, 0x75, 0x74, 0x70,
+	0x75, 0x74, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c,
+	0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x1a, 0x0a, 0x08,
+	0x61, 0x74, 0x74, 0x65, 0x6d, 0x70, 0x74, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08,
+	0x61, 0x74, 0x74, 0x65, 0x6d, 0x70, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x61,
+	0x0a, 0x16, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x6c, 0x6f, 0x62,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x12, 0x1c, 0x0a, 0x09,
+	0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x6b, 0x65,
+	0x79, 0x5f, 0x69, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6b, 0x65, 0x79, 0x49,
+	0x64, 0x22, 0x33, 0x0a, 0x14, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x50, 0x61, 0x79,
+	0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x1b, 0x0a, 0x09, 0x66, 0x61, 0x6b,
+	0x65, 0x5f, 0x73, 0x63, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x66, 0x61,
+	0x6b, 0x65, 0x53, 0x63, 0x69, 0x64, 0x22, 0xfd, 0x03, 0x0a, 0x12, 0x50, 0x61, 0x79, 0x6d, 0x65,
+	0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x21, 0x0a,
+	0x0c, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x0b, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68,
+	0x12, 0x25, 0x0a, 0x0e, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x73, 0x65, 0x63, 0x72,
+	0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0d, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e,
+	0x74, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x64, 0x65, 0x73, 0x74, 0x69,
+	0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x64, 0x65,
+	0x73, 0x74, 0x69, 0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x30, 0x0a, 0x14, 0x69, 0x6e, 0x63,
+	0x6f, 0x6d, 0x69, 0x6e, 0x67, 0x5f, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x6d, 0x73, 0x61,
+	0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x12, 0x69, 0x6e, 0x63, 0x6f, 0x6d, 0x69, 0x6e,
+	0x67, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x4d, 0x73, 0x61, 0x74, 0x12, 0x30, 0x0a, 0x14, 0x6f,
+	0x75, 0x74, 0x67, 0x6f, 0x69, 0x6e, 0x67, 0x5f, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x6d,
+	0x73, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x12, 0x6f, 0x75, 0x74, 0x67, 0x6f,
+	0x69, 0x6e, 0x67, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x4d, 0x73, 0x61, 0x74, 0x12, 0x10, 0x0a,
+	0x03, 0x74, 0x61, 0x67, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x74, 0x61, 0x67, 0x12,
+	0x44, 0x0a, 0x12, 0x6f, 0x70, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x5f, 0x66, 0x65, 0x65, 0x5f, 0x70,
+	0x61, 0x72, 0x61, 0x6d, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x6c, 0x73,
+	0x70, 0x64, 0x2e, 0x4f, 0x70, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x46, 0x65, 0x65, 0x50, 0x61, 0x72,
+	0x61, 0x6d, 0x73, 0x52, 0x10, 0x6f, 0x70, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x46, 0x65, 0x65, 0x50,
+	0x61, 0x72, 0x61, 0x6d, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x09, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x61, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x5f, 0x68, 0x69, 0x6e, 0x74, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x0c, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x48, 0x69, 0x6e, 0x74, 0x73, 0x12, 0x21,
+	0x0a, 0x0c, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x18, 0x0b,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x41, 0x67, 0x65, 0x6e,
+	0x74, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x0c, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12, 0x27, 0x0a,
+	0x0f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65,
+	0x18, 0x0d, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x6d, 0x65,
+	0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x22, 0x5f, 0x0a, 0x14, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c,
+	0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x62, 0x6c,
+	0x6f, 0x62, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x12, 0x15, 0x0a, 0x06, 0x6b, 0x65, 0x79, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x6b, 0x65, 0x79, 0x49, 0x64, 0x22, 0x14, 0x0a, 0x12, 0x43, 0x61, 0x6e, 0x63, 0x65,
+	0x6c, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x93, 0x01,
+	0x0a, 0x18, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x49,
+	0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61,
+	0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x0b, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68, 0x12, 0x20, 0x0a,
+	0x0b, 0x64, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x14, 0x0a,
+	0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f,
+	0x6e, 0x63, 0x65, 0x22, 0x65, 0x0a, 0x1a, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x50,
+	0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x04, 0x62, 0x6c, 0x6f, 0x62, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75,
+	0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x6b, 0x65, 0x79, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x6b, 0x65, 0x79, 0x49, 0x64, 0x22, 0x3d, 0x0a, 0x18, 0x47, 0x65,
+	0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73,
+	0x68, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e,
 	0x74, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x70, 0x61,
-	0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68, 0x12, 0x25, 0x0a, 0x0e, 0x70, 0x61, 0x79,
-	0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x0c, 0x52, 0x0d, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74,
-	0x12, 0x20, 0x0a, 0x0b, 0x64, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x61, 0x74, 0x69,
-	0x6f, 0x6e, 0x12, 0x30, 0x0a, 0x14, 0x69, 0x6e, 0x63, 0x6f, 0x6d, 0x69, 0x6e, 0x67, 0x5f, 0x61,
-	0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x6d, 0x73, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03,
-	0x52, 0x12, 0x69, 0x6e, 0x63, 0x6f, 0x6d, 0x69, 0x6e, 0x67, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74,
-	0x4d, 0x73, 0x61, 0x74, 0x12, 0x30, 0x0a, 0x14, 0x6f, 0x75, 0x74, 0x67, 0x6f, 0x69, 0x6e, 0x67,
-	0x5f, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x6d, 0x73, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01,
-	0x28, 0x03, 0x52, 0x12, 0x6f, 0x75, 0x74, 0x67, 0x6f, 0x69, 0x6e, 0x67, 0x41, 0x6d, 0x6f, 0x75,
-	0x6e, 0x74, 0x4d, 0x73, 0x61, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x74, 0x61, 0x67, 0x18, 0x06, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x03, 0x74, 0x61, 0x67, 0x12, 0x44, 0x0a, 0x12, 0x6f, 0x70, 0x65, 0x6e,
-	0x69, 0x6e, 0x67, 0x5f, 0x66, 0x65, 0x65, 0x5f, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x18, 0x07,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x4f, 0x70, 0x65, 0x6e,
-	0x69, 0x6e, 0x67, 0x46, 0x65, 0x65, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x52, 0x10, 0x6f, 0x70,
-	0x65, 0x6e, 0x69, 0x6e, 0x67, 0x46, 0x65, 0x65, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x22, 0x1f,
-	0x0a, 0x09, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x64,
-	0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22,
-	0x52, 0x0a, 0x06, 0x53, 0x69, 0x67, 0x6e, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74,
-	0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x16, 0x0a,
-	0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x70,
-	0x75, 0x62, 0x6b, 0x65, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75,
-	0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74,
-	0x75, 0x72, 0x65, 0x22, 0x86, 0x03, 0x0a, 0x14, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x43, 0x68, 0x61,
-	0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x25, 0x0a, 0x0e,
-	0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x5f, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x0c, 0x52, 0x0d, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x50, 0x75, 0x62,
-	0x6b, 0x65, 0x79, 0x12, 0x51, 0x0a, 0x0d, 0x66, 0x61, 0x6b, 0x65, 0x5f, 0x63, 0x68, 0x61, 0x6e,
+	0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68, 0x22, 0x76, 0x0a, 0x1e, 0x47, 0x65, 0x6e,
+	0x65, 0x72, 0x61, 0x74, 0x65, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68,
+	0x49, 0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x20, 0x0a, 0x0b, 0x64,
+	0x65, 0x73, 0x74, 0x69, 0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x0b, 0x64, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a,
+	0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x6e,
+	0x6f, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63,
+	0x65, 0x22, 0x5f, 0x0a, 0x14, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x50, 0x72, 0x65, 0x69, 0x6d, 0x61,
+	0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x6c, 0x6f,
+	0x62, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x12, 0x1c, 0x0a,
+	0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x6b,
+	0x65, 0x79, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6b, 0x65, 0x79,
+	0x49, 0x64, 0x22, 0x30, 0x0a, 0x12, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x50, 0x72, 0x65, 0x69, 0x6d,
+	0x61, 0x67, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x65, 0x69,
+	0x6d, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x70, 0x72, 0x65, 0x69,
+	0x6d, 0x61, 0x67, 0x65, 0x22, 0x93, 0x01, 0x0a, 0x18, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x50, 0x72,
+	0x65, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x68, 0x61, 0x73,
+	0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74,
+	0x48, 0x61, 0x73, 0x68, 0x12, 0x20, 0x0a, 0x0b, 0x64, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x74, 0x69,
+	0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x22, 0x36, 0x0a, 0x09, 0x45, 0x6e,
+	0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x15, 0x0a, 0x06, 0x6b,
+	0x65, 0x79, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6b, 0x65, 0x79,
+	0x49, 0x64, 0x22, 0x52, 0x0a, 0x06, 0x53, 0x69, 0x67, 0x6e, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04,
+	0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61,
+	0x12, 0x16, 0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67,
+	0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x22, 0x86, 0x03, 0x0a, 0x14, 0x43, 0x68, 0x65, 0x63, 0x6b,
+	0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x25, 0x0a, 0x0e, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x5f, 0x70, 0x75, 0x62, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0d, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74,
+	0x50, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x12, 0x51, 0x0a, 0x0d, 0x66, 0x61, 0x6b, 0x65, 0x5f, 0x63,
+	0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2c, 0x2e,
+	0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65,
+	0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x46, 0x61, 0x6b, 0x65, 0x43, 0x68,
+	0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0c, 0x66, 0x61, 0x6b,
+	0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x12, 0x6a, 0x0a, 0x16, 0x77, 0x61, 0x69,
+	0x74, 0x69, 0x6e, 0x67, 0x5f, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x6e,
+	0x65, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x34, 0x2e, 0x6c, 0x73, 0x70, 0x64,
+	0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x57, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x43, 0x6c, 0x6f,
+	0x73, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x14, 0x77, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x43, 0x68, 0x61,
+	0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x1a, 0x3f, 0x0a, 0x11, 0x46, 0x61, 0x6b, 0x65, 0x43, 0x68, 0x61,
+	0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x47, 0x0a, 0x19, 0x57, 0x61, 0x69, 0x74, 0x69, 0x6e,
+	0x67, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22,
+	0xcd, 0x02, 0x0a, 0x12, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c,
+	0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x59, 0x0a, 0x11, 0x6e, 0x6f, 0x74, 0x5f, 0x66, 0x61,
+	0x6b, 0x65, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x2d, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x43, 0x68,
+	0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x2e, 0x4e, 0x6f, 0x74, 0x46,
+	0x61, 0x6b, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x52, 0x0f, 0x6e, 0x6f, 0x74, 0x46, 0x61, 0x6b, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c,
+	0x73, 0x12, 0x55, 0x0a, 0x0f, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x64, 0x5f, 0x63, 0x68, 0x61, 0x6e,
 	0x6e, 0x65, 0x6c, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2c, 0x2e, 0x6c, 0x73, 0x70,
 	0x64, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x46, 0x61, 0x6b, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e,
-	0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0c, 0x66, 0x61, 0x6b, 0x65, 0x43, 0x68,
-	0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x12, 0x6a, 0x0a, 0x16, 0x77, 0x61, 0x69, 0x74, 0x69, 0x6e,
-	0x67, 0x5f, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73,
-	0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x34, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43, 0x68,
-	0x65, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x2e, 0x57, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x43,
-	0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x14, 0x77, 0x61,
-	0x69, 0x74, 0x69, 0x6e, 0x67, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65,
-	0x6c, 0x73, 0x1a, 0x3f, 0x0a, 0x11, 0x46, 0x61, 0x6b, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65,
-	0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c,
-	0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
-	0x02, 0x38, 0x01, 0x1a, 0x47, 0x0a, 0x19, 0x57, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x43, 0x6c,
-	0x6f, 0x73, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
-	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
-	0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xcd, 0x02, 0x0a,
-	0x12, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x52, 0x65,
-	0x70, 0x6c, 0x79, 0x12, 0x59, 0x0a, 0x11, 0x6e, 0x6f, 0x74, 0x5f, 0x66, 0x61, 0x6b, 0x65, 0x5f,
-	0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2d,
-	0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x6e, 0x6e,
-	0x65, 0x6c, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x2e, 0x4e, 0x6f, 0x74, 0x46, 0x61, 0x6b, 0x65,
-	0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0f, 0x6e,
-	0x6f, 0x74, 0x46, 0x61, 0x6b, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x12, 0x55,
-	0x0a, 0x0f, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x64, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c,
-	0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2c, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43,
-	0x68, 0x65, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x52, 0x65, 0x70, 0x6c,
-	0x79, 0x2e, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x64, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73,
-	0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0e, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x64, 0x43, 0x68, 0x61,
-	0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x1a, 0x42, 0x0a, 0x14, 0x4e, 0x6f, 0x74, 0x46, 0x61, 0x6b, 0x65,
-	0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a,
-	0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12,
-	0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05,
-	0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x41, 0x0a, 0x13, 0x43, 0x6c, 0x6f,
-	0x73, 0x65, 0x64, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x65, 0x70, 0x6c, 0x79, 0x2e, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x64, 0x43, 0x68, 0x61, 0x6e, 0x6e,
+	0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0e, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x64,
+	0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x1a, 0x42, 0x0a, 0x14, 0x4e, 0x6f, 0x74, 0x46,
+	0x61, 0x6b, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
 	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
 	0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x32, 0xae, 0x02, 0x0a,
-	0x0d, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4f, 0x70, 0x65, 0x6e, 0x65, 0x72, 0x12, 0x56,
-	0x0a, 0x12, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61,
-	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1f, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43, 0x68, 0x61, 0x6e,
-	0x6e, 0x65, 0x6c, 0x49, 0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43, 0x68, 0x61,
-	0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52,
-	0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x41, 0x0a, 0x0b, 0x4f, 0x70, 0x65, 0x6e, 0x43, 0x68,
-	0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x12, 0x18, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x4f, 0x70, 0x65,
-	0x6e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
-	0x16, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x4f, 0x70, 0x65, 0x6e, 0x43, 0x68, 0x61, 0x6e, 0x6e,
-	0x65, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x4d, 0x0a, 0x0f, 0x52, 0x65, 0x67,
-	0x69, 0x73, 0x74, 0x65, 0x72, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x1c, 0x2e, 0x6c,
-	0x73, 0x70, 0x64, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x50, 0x61, 0x79, 0x6d,
-	0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x6c, 0x73, 0x70,
-	0x64, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e,
-	0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x33, 0x0a, 0x0d, 0x43, 0x68, 0x65, 0x63,
-	0x6b, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x12, 0x0f, 0x2e, 0x6c, 0x73, 0x70, 0x64,
-	0x2e, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x1a, 0x0f, 0x2e, 0x6c, 0x73, 0x70,
-	0x64, 0x2e, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x22, 0x00, 0x42, 0x3a, 0x0a,
-	0x14, 0x69, 0x6f, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x73, 0x65,
-	0x72, 0x76, 0x69, 0x63, 0x65, 0x42, 0x09, 0x4c, 0x73, 0x70, 0x64, 0x50, 0x72, 0x6f, 0x74, 0x6f,
-	0x50, 0x01, 0x5a, 0x15, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62,
-	0x72, 0x65, 0x65, 0x7a, 0x2f, 0x6c, 0x73, 0x70, 0x64, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x33,
+	0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x41, 0x0a, 0x13,
+	0x43, 0x6c, 0x6f, 0x73, 0x65, 0x64, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x2a,
+	0x80, 0x01, 0x0a, 0x12, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65,
+	0x6c, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1a, 0x0a, 0x16, 0x43, 0x4c, 0x49, 0x45, 0x4e, 0x54,
+	0x5f, 0x43, 0x48, 0x41, 0x4e, 0x4e, 0x45, 0x4c, 0x5f, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e,
+	0x10, 0x00, 0x12, 0x1a, 0x0a, 0x16, 0x43, 0x4c, 0x49, 0x45, 0x4e, 0x54, 0x5f, 0x43, 0x48, 0x41,
+	0x4e, 0x4e, 0x45, 0x4c, 0x5f, 0x50, 0x45, 0x4e, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x17,
+	0x0a, 0x13, 0x43, 0x4c, 0x49, 0x45, 0x4e, 0x54, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x4e, 0x45, 0x4c,
+	0x5f, 0x4f, 0x50, 0x45, 0x4e, 0x10, 0x02, 0x12, 0x19, 0x0a, 0x15, 0x43, 0x4c, 0x49, 0x45, 0x4e,
+	0x54, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x4e, 0x45, 0x4c, 0x5f, 0x43, 0x4c, 0x4f, 0x53, 0x45, 0x44,
+	0x10, 0x03, 0x32, 0xe6, 0x06, 0x0a, 0x0d, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4f, 0x70,
+	0x65, 0x6e, 0x65, 0x72, 0x12, 0x56, 0x0a, 0x12, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49,
+	0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1f, 0x2e, 0x6c, 0x73, 0x70,
+	0x64, 0x2e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x6c, 0x73,
+	0x70, 0x64, 0x2e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x6e, 0x66, 0x6f, 0x72, 0x6d,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x41, 0x0a, 0x0b,
+	0x4f, 0x70, 0x65, 0x6e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x12, 0x18, 0x2e, 0x6c, 0x73,
+	0x70, 0x64, 0x2e, 0x4f, 0x70, 0x65, 0x6e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x4f, 0x70, 0x65,
+	0x6e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12,
+	0x5c, 0x0a, 0x14, 0x47, 0x65, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4f, 0x70, 0x65,
+	0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x21, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x47,
+	0x65, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4f, 0x70, 0x65, 0x6e, 0x53, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x6c, 0x73, 0x70,
+	0x64, 0x2e, 0x47, 0x65, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4f, 0x70, 0x65, 0x6e,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x4d, 0x0a,
+	0x0f, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74,
+	0x12, 0x1c, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72,
+	0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a,
+	0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x50, 0x61,
+	0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x33, 0x0a, 0x0d,
+	0x43, 0x68, 0x65, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x12, 0x0f, 0x2e,
+	0x6c, 0x73, 0x70, 0x64, 0x2e, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x1a, 0x0f,
+	0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x45, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x22,
+	0x00, 0x12, 0x47, 0x0a, 0x0d, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x50, 0x61, 0x79, 0x6d, 0x65,
+	0x6e, 0x74, 0x12, 0x1a, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c,
+	0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18,
+	0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x50, 0x61, 0x79, 0x6d,
+	0x65, 0x6e, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x59, 0x0a, 0x13, 0x47, 0x65,
+	0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73,
+	0x68, 0x12, 0x20, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74,
+	0x65, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72,
+	0x61, 0x74, 0x65, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68, 0x52, 0x65,
+	0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x47, 0x0a, 0x0d, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x50, 0x72,
+	0x65, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x12, 0x1a, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43, 0x6c,
+	0x61, 0x69, 0x6d, 0x50, 0x72, 0x65, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x18, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x50,
+	0x72, 0x65, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x3e,
+	0x0a, 0x0a, 0x47, 0x65, 0x74, 0x4c, 0x73, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x17, 0x2e, 0x6c,
+	0x73, 0x70, 0x64, 0x2e, 0x47, 0x65, 0x74, 0x4c, 0x73, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x47, 0x65, 0x74,
+	0x4c, 0x73, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x56,
+	0x0a, 0x12, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x68, 0x61, 0x6e,
+	0x6e, 0x65, 0x6c, 0x73, 0x12, 0x1f, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x4c, 0x69, 0x73, 0x74,
+	0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x4c, 0x69, 0x73,
+	0x74, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x52,
+	0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x53, 0x0a, 0x11, 0x52, 0x65, 0x6e, 0x65, 0x77, 0x43,
+	0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x12, 0x1e, 0x2e, 0x6c, 0x73,
+	0x70, 0x64, 0x2e, 0x52, 0x65, 0x6e, 0x65, 0x77, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4c,
+	0x65, 0x61, 0x73, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x6c, 0x73,
+	0x70, 0x64, 0x2e, 0x52, 0x65, 0x6e, 0x65, 0x77, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x4c,
+	0x65, 0x61, 0x73, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x42, 0x3a, 0x0a, 0x14, 0x69,
+	0x6f, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x2e, 0x6c, 0x73, 0x70, 0x64, 0x2e, 0x73, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x42, 0x09, 0x4c, 0x73, 0x70, 0x64, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01,
+	0x5a, 0x15, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62, 0x72, 0x65,
+	0x65, 0x7a, 0x2f, 0x6c, 0x73, 0x70, 0x64, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -1024,45 +3354,92 @@ func file_lspd_proto_rawDescGZIP() []byte {
 	return file_lspd_proto_rawDescData
 }
 
-var file_lspd_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_lspd_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_lspd_proto_msgTypes = make([]protoimpl.MessageInfo, 40)
 var file_lspd_proto_goTypes = []interface{}{
-	(*ChannelInformationRequest)(nil), // 0: lspd.ChannelInformationRequest
-	(*ChannelInformationReply)(nil),   // 1: lspd.ChannelInformationReply
-	(*OpeningFeeParams)(nil),          // 2: lspd.OpeningFeeParams
-	(*OpenChannelRequest)(nil),        // 3: lspd.OpenChannelRequest
-	(*OpenChannelReply)(nil),          // 4: lspd.OpenChannelReply
-	(*RegisterPaymentRequest)(nil),    // 5: lspd.RegisterPaymentRequest
-	(*RegisterPaymentReply)(nil),      // 6: lspd.RegisterPaymentReply
-	(*PaymentInformation)(nil),        // 7: lspd.PaymentInformation
-	(*Encrypted)(nil),                 // 8: lspd.Encrypted
-	(*Signed)(nil),                    // 9: lspd.Signed
-	(*CheckChannelsRequest)(nil),      // 10: lspd.CheckChannelsRequest
-	(*CheckChannelsReply)(nil),        // 11: lspd.CheckChannelsReply
-	nil,                               // 12: lspd.CheckChannelsRequest.FakeChannelsEntry
-	nil,                               // 13: lspd.CheckChannelsRequest.WaitingCloseChannelsEntry
-	nil,                               // 14: lspd.CheckChannelsReply.NotFakeChannelsEntry
-	nil,                               // 15: lspd.CheckChannelsReply.ClosedChannelsEntry
+	(ClientChannelState)(0),                // 0: lspd.ClientChannelState
+	(*ChannelInformationRequest)(nil),      // 1: lspd.ChannelInformationRequest
+	(*ChannelInformationReply)(nil),        // 2: lspd.ChannelInformationReply
+	(*ListClientChannelsRequest)(nil),      // 3: lspd.ListClientChannelsRequest
+	(*ListClientChannelsInformation)(nil),  // 4: lspd.ListClientChannelsInformation
+	(*ListClientChannelsReply)(nil),        // 5: lspd.ListClientChannelsReply
+	(*ClientChannel)(nil),                  // 6: lspd.ClientChannel
+	(*RenewChannelLeaseRequest)(nil),       // 7: lspd.RenewChannelLeaseRequest
+	(*RenewChannelLeaseInformation)(nil),   // 8: lspd.RenewChannelLeaseInformation
+	(*RenewChannelLeaseReply)(nil),         // 9: lspd.RenewChannelLeaseReply
+	(*GetLspInfoRequest)(nil),              // 10: lspd.GetLspInfoRequest
+	(*GetLspInfoReply)(nil),                // 11: lspd.GetLspInfoReply
+	(*LspFeature)(nil),                     // 12: lspd.LspFeature
+	(*LiquidityAd)(nil),                    // 13: lspd.LiquidityAd
+	(*OpenChannelChallenge)(nil),           // 14: lspd.OpenChannelChallenge
+	(*PriorityTier)(nil),                   // 15: lspd.PriorityTier
+	(*OpeningFeeParams)(nil),               // 16: lspd.OpeningFeeParams
+	(*OpenChannelRequest)(nil),             // 17: lspd.OpenChannelRequest
+	(*OpenChannelReply)(nil),               // 18: lspd.OpenChannelReply
+	(*GetChannelOpenStatusRequest)(nil),    // 19: lspd.GetChannelOpenStatusRequest
+	(*GetChannelOpenStatusReply)(nil),      // 20: lspd.GetChannelOpenStatusReply
+	(*RegisterPaymentRequest)(nil),         // 21: lspd.RegisterPaymentRequest
+	(*RegisterPaymentReply)(nil),           // 22: lspd.RegisterPaymentReply
+	(*PaymentInformation)(nil),             // 23: lspd.PaymentInformation
+	(*CancelPaymentRequest)(nil),           // 24: lspd.CancelPaymentRequest
+	(*CancelPaymentReply)(nil),             // 25: lspd.CancelPaymentReply
+	(*CancelPaymentInformation)(nil),       // 26: lspd.CancelPaymentInformation
+	(*GeneratePaymentHashRequest)(nil),     // 27: lspd.GeneratePaymentHashRequest
+	(*GeneratePaymentHashReply)(nil),       // 28: lspd.GeneratePaymentHashReply
+	(*GeneratePaymentHashInformation)(nil), // 29: lspd.GeneratePaymentHashInformation
+	(*ClaimPreimageRequest)(nil),           // 30: lspd.ClaimPreimageRequest
+	(*ClaimPreimageReply)(nil),             // 31: lspd.ClaimPreimageReply
+	(*ClaimPreimageInformation)(nil),       // 32: lspd.ClaimPreimageInformation
+	(*Encrypted)(nil),                      // 33: lspd.Encrypted
+	(*Signed)(nil),                         // 34: lspd.Signed
+	(*CheckChannelsRequest)(nil),           // 35: lspd.CheckChannelsRequest
+	(*CheckChannelsReply)(nil),             // 36: lspd.CheckChannelsReply
+	nil,                                    // 37: lspd.CheckChannelsRequest.FakeChannelsEntry
+	nil,                                    // 38: lspd.CheckChannelsRequest.WaitingCloseChannelsEntry
+	nil,                                    // 39: lspd.CheckChannelsReply.NotFakeChannelsEntry
+	nil,                                    // 40: lspd.CheckChannelsReply.ClosedChannelsEntry
 }
 var file_lspd_proto_depIdxs = []int32{
-	2,  // 0: lspd.ChannelInformationReply.opening_fee_params_menu:type_name -> lspd.OpeningFeeParams
-	2,  // 1: lspd.PaymentInformation.opening_fee_params:type_name -> lspd.OpeningFeeParams
-	12, // 2: lspd.CheckChannelsRequest.fake_channels:type_name -> lspd.CheckChannelsRequest.FakeChannelsEntry
-	13, // 3: lspd.CheckChannelsRequest.waiting_close_channels:type_name -> lspd.CheckChannelsRequest.WaitingCloseChannelsEntry
-	14, // 4: lspd.CheckChannelsReply.not_fake_channels:type_name -> lspd.CheckChannelsReply.NotFakeChannelsEntry
-	15, // 5: lspd.CheckChannelsReply.closed_channels:type_name -> lspd.CheckChannelsReply.ClosedChannelsEntry
-	0,  // 6: lspd.ChannelOpener.ChannelInformation:input_type -> lspd.ChannelInformationRequest
-	3,  // 7: lspd.ChannelOpener.OpenChannel:input_type -> lspd.OpenChannelRequest
-	5,  // 8: lspd.ChannelOpener.RegisterPayment:input_type -> lspd.RegisterPaymentRequest
-	8,  // 9: lspd.ChannelOpener.CheckChannels:input_type -> lspd.Encrypted
-	1,  // 10: lspd.ChannelOpener.ChannelInformation:output_type -> lspd.ChannelInformationReply
-	4,  // 11: lspd.ChannelOpener.OpenChannel:output_type -> lspd.OpenChannelReply
-	6,  // 12: lspd.ChannelOpener.RegisterPayment:output_type -> lspd.RegisterPaymentReply
-	8,  // 13: lspd.ChannelOpener.CheckChannels:output_type -> lspd.Encrypted
-	10, // [10:14] is the sub-list for method output_type
-	6,  // [6:10] is the sub-list for method input_type
-	6,  // [6:6] is the sub-list for extension type_name
-	6,  // [6:6] is the sub-list for extension extendee
-	0,  // [0:6] is the sub-list for field type_name
+	16, // 0: lspd.ChannelInformationReply.opening_fee_params_menu:type_name -> lspd.OpeningFeeParams
+	15, // 1: lspd.ChannelInformationReply.priority_tiers:type_name -> lspd.PriorityTier
+	14, // 2: lspd.ChannelInformationReply.open_channel_challenge:type_name -> lspd.OpenChannelChallenge
+	6,  // 3: lspd.ListClientChannelsReply.channels:type_name -> lspd.ClientChannel
+	0,  // 4: lspd.ClientChannel.state:type_name -> lspd.ClientChannelState
+	12, // 5: lspd.GetLspInfoReply.features:type_name -> lspd.LspFeature
+	16, // 6: lspd.GetLspInfoReply.opening_fee_params_menu:type_name -> lspd.OpeningFeeParams
+	13, // 7: lspd.GetLspInfoReply.liquidity_ad:type_name -> lspd.LiquidityAd
+	16, // 8: lspd.PaymentInformation.opening_fee_params:type_name -> lspd.OpeningFeeParams
+	37, // 9: lspd.CheckChannelsRequest.fake_channels:type_name -> lspd.CheckChannelsRequest.FakeChannelsEntry
+	38, // 10: lspd.CheckChannelsRequest.waiting_close_channels:type_name -> lspd.CheckChannelsRequest.WaitingCloseChannelsEntry
+	39, // 11: lspd.CheckChannelsReply.not_fake_channels:type_name -> lspd.CheckChannelsReply.NotFakeChannelsEntry
+	40, // 12: lspd.CheckChannelsReply.closed_channels:type_name -> lspd.CheckChannelsReply.ClosedChannelsEntry
+	1,  // 13: lspd.ChannelOpener.ChannelInformation:input_type -> lspd.ChannelInformationRequest
+	17, // 14: lspd.ChannelOpener.OpenChannel:input_type -> lspd.OpenChannelRequest
+	19, // 15: lspd.ChannelOpener.GetChannelOpenStatus:input_type -> lspd.GetChannelOpenStatusRequest
+	21, // 16: lspd.ChannelOpener.RegisterPayment:input_type -> lspd.RegisterPaymentRequest
+	33, // 17: lspd.ChannelOpener.CheckChannels:input_type -> lspd.Encrypted
+	24, // 18: lspd.ChannelOpener.CancelPayment:input_type -> lspd.CancelPaymentRequest
+	27, // 19: lspd.ChannelOpener.GeneratePaymentHash:input_type -> lspd.GeneratePaymentHashRequest
+	30, // 20: lspd.ChannelOpener.ClaimPreimage:input_type -> lspd.ClaimPreimageRequest
+	10, // 21: lspd.ChannelOpener.GetLspInfo:input_type -> lspd.GetLspInfoRequest
+	3,  // 22: lspd.ChannelOpener.ListClientChannels:input_type -> lspd.ListClientChannelsRequest
+	7,  // 23: lspd.ChannelOpener.RenewChannelLease:input_type -> lspd.RenewChannelLeaseRequest
+	2,  // 24: lspd.ChannelOpener.ChannelInformation:output_type -> lspd.ChannelInformationReply
+	18, // 25: lspd.ChannelOpener.OpenChannel:output_type -> lspd.OpenChannelReply
+	20, // 26: lspd.ChannelOpener.GetChannelOpenStatus:output_type -> lspd.GetChannelOpenStatusReply
+	22, // 27: lspd.ChannelOpener.RegisterPayment:output_type -> lspd.RegisterPaymentReply
+	33, // 28: lspd.ChannelOpener.CheckChannels:output_type -> lspd.Encrypted
+	25, // 29: lspd.ChannelOpener.CancelPayment:output_type -> lspd.CancelPaymentReply
+	28, // 30: lspd.ChannelOpener.GeneratePaymentHash:output_type -> lspd.GeneratePaymentHashReply
+	31, // 31: lspd.ChannelOpener.ClaimPreimage:output_type -> lspd.ClaimPreimageReply
+	11, // 32: lspd.ChannelOpener.GetLspInfo:output_type -> lspd.GetLspInfoReply
+	5,  // 33: lspd.ChannelOpener.ListClientChannels:output_type -> lspd.ListClientChannelsReply
+	9,  // 34: lspd.ChannelOpener.RenewChannelLease:output_type -> lspd.RenewChannelLeaseReply
+	24, // [24:35] is the sub-list for method output_type
+	13, // [13:24] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_lspd_proto_init() }
@@ -1083,8 +3460,20 @@ func file_lspd_proto_init() {
 				return nil
 			}
 		}
-		file_lspd_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ChannelInformationReply); i {
+		file_lspd_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ChannelInformationReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListClientChannelsRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1095,7 +3484,151 @@ func file_lspd_proto_init() {
 				return nil
 			}
 		}
-		file_lspd_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+		file_lspd_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListClientChannelsInformation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListClientChannelsReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClientChannel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RenewChannelLeaseRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RenewChannelLeaseInformation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RenewChannelLeaseReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetLspInfoRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetLspInfoReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LspFeature); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LiquidityAd); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OpenChannelChallenge); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PriorityTier); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*OpeningFeeParams); i {
 			case 0:
 				return &v.state
@@ -1107,7 +3640,7 @@ func file_lspd_proto_init() {
 				return nil
 			}
 		}
-		file_lspd_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+		file_lspd_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*OpenChannelRequest); i {
 			case 0:
 				return &v.state
@@ -1119,7 +3652,7 @@ func file_lspd_proto_init() {
 				return nil
 			}
 		}
-		file_lspd_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+		file_lspd_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*OpenChannelReply); i {
 			case 0:
 				return &v.state
@@ -1131,7 +3664,31 @@ func file_lspd_proto_init() {
 				return nil
 			}
 		}
-		file_lspd_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+		file_lspd_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetChannelOpenStatusRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetChannelOpenStatusReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*RegisterPaymentRequest); i {
 			case 0:
 				return &v.state
@@ -1143,7 +3700,7 @@ func file_lspd_proto_init() {
 				return nil
 			}
 		}
-		file_lspd_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+		file_lspd_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*RegisterPaymentReply); i {
 			case 0:
 				return &v.state
@@ -1155,7 +3712,7 @@ func file_lspd_proto_init() {
 				return nil
 			}
 		}
-		file_lspd_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+		file_lspd_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*PaymentInformation); i {
 			case 0:
 				return &v.state
@@ -1167,7 +3724,115 @@ func file_lspd_proto_init() {
 				return nil
 			}
 		}
-		file_lspd_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+		file_lspd_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CancelPaymentRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CancelPaymentReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CancelPaymentInformation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[26].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GeneratePaymentHashRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[27].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GeneratePaymentHashReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[28].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GeneratePaymentHashInformation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[29].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClaimPreimageRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[30].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClaimPreimageReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[31].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClaimPreimageInformation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_lspd_proto_msgTypes[32].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Encrypted); i {
 			case 0:
 				return &v.state
@@ -1179,7 +3844,7 @@ func file_lspd_proto_init() {
 				return nil
 			}
 		}
-		file_lspd_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+		file_lspd_proto_msgTypes[33].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Signed); i {
 			case 0:
 				return &v.state
@@ -1191,7 +3856,7 @@ func file_lspd_proto_init() {
 				return nil
 			}
 		}
-		file_lspd_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+		file_lspd_proto_msgTypes[34].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*CheckChannelsRequest); i {
 			case 0:
 				return &v.state
@@ -1203,7 +3868,7 @@ func file_lspd_proto_init() {
 				return nil
 			}
 		}
-		file_lspd_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+		file_lspd_proto_msgTypes[35].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*CheckChannelsReply); i {
 			case 0:
 				return &v.state
@@ -1221,13 +3886,14 @@ func file_lspd_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_lspd_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   16,
+			NumEnums:      1,
+			NumMessages:   40,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_lspd_proto_goTypes,
 		DependencyIndexes: file_lspd_proto_depIdxs,
+		EnumInfos:         file_lspd_proto_enumTypes,
 		MessageInfos:      file_lspd_proto_msgTypes,
 	}.Build()
 	File_lspd_proto = out.File
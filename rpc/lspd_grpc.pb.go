@@ -24,8 +24,15 @@ const _ = grpc.SupportPackageIsVersion7
 type ChannelOpenerClient interface {
 	ChannelInformation(ctx context.Context, in *ChannelInformationRequest, opts ...grpc.CallOption) (*ChannelInformationReply, error)
 	OpenChannel(ctx context.Context, in *OpenChannelRequest, opts ...grpc.CallOption) (*OpenChannelReply, error)
+	GetChannelOpenStatus(ctx context.Context, in *GetChannelOpenStatusRequest, opts ...grpc.CallOption) (*GetChannelOpenStatusReply, error)
 	RegisterPayment(ctx context.Context, in *RegisterPaymentRequest, opts ...grpc.CallOption) (*RegisterPaymentReply, error)
 	CheckChannels(ctx context.Context, in *Encrypted, opts ...grpc.CallOption) (*Encrypted, error)
+	CancelPayment(ctx context.Context, in *CancelPaymentRequest, opts ...grpc.CallOption) (*CancelPaymentReply, error)
+	GeneratePaymentHash(ctx context.Context, in *GeneratePaymentHashRequest, opts ...grpc.CallOption) (*GeneratePaymentHashReply, error)
+	ClaimPreimage(ctx context.Context, in *ClaimPreimageRequest, opts ...grpc.CallOption) (*ClaimPreimageReply, error)
+	GetLspInfo(ctx context.Context, in *GetLspInfoRequest, opts ...grpc.CallOption) (*GetLspInfoReply, error)
+	ListClientChannels(ctx context.Context, in *ListClientChannelsRequest, opts ...grpc.CallOption) (*ListClientChannelsReply, error)
+	RenewChannelLease(ctx context.Context, in *RenewChannelLeaseRequest, opts ...grpc.CallOption) (*RenewChannelLeaseReply, error)
 }
 
 type channelOpenerClient struct {
@@ -54,6 +61,15 @@ func (c *channelOpenerClient) OpenChannel(ctx context.Context, in *OpenChannelRe
 	return out, nil
 }
 
+func (c *channelOpenerClient) GetChannelOpenStatus(ctx context.Context, in *GetChannelOpenStatusRequest, opts ...grpc.CallOption) (*GetChannelOpenStatusReply, error) {
+	out := new(GetChannelOpenStatusReply)
+	err := c.cc.Invoke(ctx, "/lspd.ChannelOpener/GetChannelOpenStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *channelOpenerClient) RegisterPayment(ctx context.Context, in *RegisterPaymentRequest, opts ...grpc.CallOption) (*RegisterPaymentReply, error) {
 	out := new(RegisterPaymentReply)
 	err := c.cc.Invoke(ctx, "/lspd.ChannelOpener/RegisterPayment", in, out, opts...)
@@ -72,14 +88,75 @@ func (c *channelOpenerClient) CheckChannels(ctx context.Context, in *Encrypted,
 	return out, nil
 }
 
+func (c *channelOpenerClient) CancelPayment(ctx context.Context, in *CancelPaymentRequest, opts ...grpc.CallOption) (*CancelPaymentReply, error) {
+	out := new(CancelPaymentReply)
+	err := c.cc.Invoke(ctx, "/lspd.ChannelOpener/CancelPayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *channelOpenerClient) GeneratePaymentHash(ctx context.Context, in *GeneratePaymentHashRequest, opts ...grpc.CallOption) (*GeneratePaymentHashReply, error) {
+	out := new(GeneratePaymentHashReply)
+	err := c.cc.Invoke(ctx, "/lspd.ChannelOpener/GeneratePaymentHash", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *channelOpenerClient) ClaimPreimage(ctx context.Context, in *ClaimPreimageRequest, opts ...grpc.CallOption) (*ClaimPreimageReply, error) {
+	out := new(ClaimPreimageReply)
+	err := c.cc.Invoke(ctx, "/lspd.ChannelOpener/ClaimPreimage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *channelOpenerClient) GetLspInfo(ctx context.Context, in *GetLspInfoRequest, opts ...grpc.CallOption) (*GetLspInfoReply, error) {
+	out := new(GetLspInfoReply)
+	err := c.cc.Invoke(ctx, "/lspd.ChannelOpener/GetLspInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *channelOpenerClient) ListClientChannels(ctx context.Context, in *ListClientChannelsRequest, opts ...grpc.CallOption) (*ListClientChannelsReply, error) {
+	out := new(ListClientChannelsReply)
+	err := c.cc.Invoke(ctx, "/lspd.ChannelOpener/ListClientChannels", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *channelOpenerClient) RenewChannelLease(ctx context.Context, in *RenewChannelLeaseRequest, opts ...grpc.CallOption) (*RenewChannelLeaseReply, error) {
+	out := new(RenewChannelLeaseReply)
+	err := c.cc.Invoke(ctx, "/lspd.ChannelOpener/RenewChannelLease", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChannelOpenerServer is the server API for ChannelOpener service.
 // All implementations must embed UnimplementedChannelOpenerServer
 // for forward compatibility
 type ChannelOpenerServer interface {
 	ChannelInformation(context.Context, *ChannelInformationRequest) (*ChannelInformationReply, error)
 	OpenChannel(context.Context, *OpenChannelRequest) (*OpenChannelReply, error)
+	GetChannelOpenStatus(context.Context, *GetChannelOpenStatusRequest) (*GetChannelOpenStatusReply, error)
 	RegisterPayment(context.Context, *RegisterPaymentRequest) (*RegisterPaymentReply, error)
 	CheckChannels(context.Context, *Encrypted) (*Encrypted, error)
+	CancelPayment(context.Context, *CancelPaymentRequest) (*CancelPaymentReply, error)
+	GeneratePaymentHash(context.Context, *GeneratePaymentHashRequest) (*GeneratePaymentHashReply, error)
+	ClaimPreimage(context.Context, *ClaimPreimageRequest) (*ClaimPreimageReply, error)
+	GetLspInfo(context.Context, *GetLspInfoRequest) (*GetLspInfoReply, error)
+	ListClientChannels(context.Context, *ListClientChannelsRequest) (*ListClientChannelsReply, error)
+	RenewChannelLease(context.Context, *RenewChannelLeaseRequest) (*RenewChannelLeaseReply, error)
 	mustEmbedUnimplementedChannelOpenerServer()
 }
 
@@ -93,12 +170,33 @@ func (UnimplementedChannelOpenerServer) ChannelInformation(context.Context, *Cha
 func (UnimplementedChannelOpenerServer) OpenChannel(context.Context, *OpenChannelRequest) (*OpenChannelReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method OpenChannel not implemented")
 }
+func (UnimplementedChannelOpenerServer) GetChannelOpenStatus(context.Context, *GetChannelOpenStatusRequest) (*GetChannelOpenStatusReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChannelOpenStatus not implemented")
+}
 func (UnimplementedChannelOpenerServer) RegisterPayment(context.Context, *RegisterPaymentRequest) (*RegisterPaymentReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RegisterPayment not implemented")
 }
 func (UnimplementedChannelOpenerServer) CheckChannels(context.Context, *Encrypted) (*Encrypted, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CheckChannels not implemented")
 }
+func (UnimplementedChannelOpenerServer) CancelPayment(context.Context, *CancelPaymentRequest) (*CancelPaymentReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelPayment not implemented")
+}
+func (UnimplementedChannelOpenerServer) GeneratePaymentHash(context.Context, *GeneratePaymentHashRequest) (*GeneratePaymentHashReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GeneratePaymentHash not implemented")
+}
+func (UnimplementedChannelOpenerServer) ClaimPreimage(context.Context, *ClaimPreimageRequest) (*ClaimPreimageReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClaimPreimage not implemented")
+}
+func (UnimplementedChannelOpenerServer) GetLspInfo(context.Context, *GetLspInfoRequest) (*GetLspInfoReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLspInfo not implemented")
+}
+func (UnimplementedChannelOpenerServer) ListClientChannels(context.Context, *ListClientChannelsRequest) (*ListClientChannelsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListClientChannels not implemented")
+}
+func (UnimplementedChannelOpenerServer) RenewChannelLease(context.Context, *RenewChannelLeaseRequest) (*RenewChannelLeaseReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewChannelLease not implemented")
+}
 func (UnimplementedChannelOpenerServer) mustEmbedUnimplementedChannelOpenerServer() {}
 
 // UnsafeChannelOpenerServer may be embedded to opt out of forward compatibility for this service.
@@ -148,6 +246,24 @@ func _ChannelOpener_OpenChannel_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ChannelOpener_GetChannelOpenStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChannelOpenStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChannelOpenerServer).GetChannelOpenStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lspd.ChannelOpener/GetChannelOpenStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChannelOpenerServer).GetChannelOpenStatus(ctx, req.(*GetChannelOpenStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ChannelOpener_RegisterPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RegisterPaymentRequest)
 	if err := dec(in); err != nil {
@@ -184,6 +300,114 @@ func _ChannelOpener_CheckChannels_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ChannelOpener_CancelPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelPaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChannelOpenerServer).CancelPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lspd.ChannelOpener/CancelPayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChannelOpenerServer).CancelPayment(ctx, req.(*CancelPaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChannelOpener_GeneratePaymentHash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GeneratePaymentHashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChannelOpenerServer).GeneratePaymentHash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lspd.ChannelOpener/GeneratePaymentHash",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChannelOpenerServer).GeneratePaymentHash(ctx, req.(*GeneratePaymentHashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChannelOpener_ClaimPreimage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClaimPreimageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChannelOpenerServer).ClaimPreimage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lspd.ChannelOpener/ClaimPreimage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChannelOpenerServer).ClaimPreimage(ctx, req.(*ClaimPreimageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChannelOpener_GetLspInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLspInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChannelOpenerServer).GetLspInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lspd.ChannelOpener/GetLspInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChannelOpenerServer).GetLspInfo(ctx, req.(*GetLspInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChannelOpener_ListClientChannels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListClientChannelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChannelOpenerServer).ListClientChannels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lspd.ChannelOpener/ListClientChannels",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChannelOpenerServer).ListClientChannels(ctx, req.(*ListClientChannelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChannelOpener_RenewChannelLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewChannelLeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChannelOpenerServer).RenewChannelLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lspd.ChannelOpener/RenewChannelLease",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChannelOpenerServer).RenewChannelLease(ctx, req.(*RenewChannelLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ChannelOpener_ServiceDesc is the grpc.ServiceDesc for ChannelOpener service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -199,6 +423,10 @@ var ChannelOpener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "OpenChannel",
 			Handler:    _ChannelOpener_OpenChannel_Handler,
 		},
+		{
+			MethodName: "GetChannelOpenStatus",
+			Handler:    _ChannelOpener_GetChannelOpenStatus_Handler,
+		},
 		{
 			MethodName: "RegisterPayment",
 			Handler:    _ChannelOpener_RegisterPayment_Handler,
@@ -207,6 +435,30 @@ var ChannelOpener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CheckChannels",
 			Handler:    _ChannelOpener_CheckChannels_Handler,
 		},
+		{
+			MethodName: "CancelPayment",
+			Handler:    _ChannelOpener_CancelPayment_Handler,
+		},
+		{
+			MethodName: "GeneratePaymentHash",
+			Handler:    _ChannelOpener_GeneratePaymentHash_Handler,
+		},
+		{
+			MethodName: "ClaimPreimage",
+			Handler:    _ChannelOpener_ClaimPreimage_Handler,
+		},
+		{
+			MethodName: "GetLspInfo",
+			Handler:    _ChannelOpener_GetLspInfo_Handler,
+		},
+		{
+			MethodName: "ListClientChannels",
+			Handler:    _ChannelOpener_ListClientChannels_Handler,
+		},
+		{
+			MethodName: "RenewChannelLease",
+			Handler:    _ChannelOpener_RenewChannelLease_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "lspd.proto",
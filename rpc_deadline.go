@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/breez/lspd/lsperrors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultRpcTimeout = 30 * time.Second
+
+// rpcTimeout returns the max duration of a client rpc from RPC_TIMEOUT.
+func rpcTimeout() time.Duration {
+	s := os.Getenv("RPC_TIMEOUT")
+	if s == "" {
+		return defaultRpcTimeout
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		log.Printf("WARN: Invalid RPC_TIMEOUT '%s'. Using default %v", s, defaultRpcTimeout)
+		return defaultRpcTimeout
+	}
+
+	return d
+}
+
+// deadlineMiddleware runs the handler of a client rpc with the server side
+// timeout, or the deadline of the client if that comes first. The context is
+// passed into the database and node calls of the handler, so a slow backend
+// fails the rpc with DEADLINE_EXCEEDED rather than keeping the handler
+// waiting.
+func (s *grpcServer) deadlineMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.rpcTimeout)
+	defer cancel()
+	return handler(ctx, req)
+}
+
+// statusMiddleware converts the errors of the handler to their grpc status.
+// It runs innermost, so it sees whether the deadline passed.
+func statusMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, lsperrors.ToStatus(deadlineError(ctx, err))
+	}
+
+	return resp, nil
+}
+
+// deadlineError returns the error of an rpc that ran out of time or was
+// cancelled with the matching grpc status.
+func deadlineError(ctx context.Context, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
+		return status.Error(codes.Canceled, "request cancelled")
+	}
+
+	return err
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/breez/lspd/lsperrors"
+	"github.com/breez/lspd/middleware"
+	"google.golang.org/grpc"
+)
+
+// rpcMiddlewareOrder returns the chain of middlewares of client rpcs from
+// RPC_MIDDLEWARE, a comma separated list of middleware names, outermost
+// first.
+func rpcMiddlewareOrder() string {
+	order := os.Getenv("RPC_MIDDLEWARE")
+	if order == "" {
+		return middleware.DefaultOrder
+	}
+
+	return order
+}
+
+// rpcMiddlewares returns the middlewares client rpcs can run through, by the
+// name used in RPC_MIDDLEWARE. A new middleware is added here.
+func (s *grpcServer) rpcMiddlewares() map[string]grpc.UnaryServerInterceptor {
+	return map[string]grpc.UnaryServerInterceptor{
+		"recovery":  middleware.Recovery(),
+		"logging":   middleware.Logging(),
+		"metrics":   middleware.Metrics(),
+		"auth":      s.authMiddleware,
+		"ratelimit": middleware.NewRateLimiter().Interceptor,
+		"deadline":  s.deadlineMiddleware,
+	}
+}
+
+// newMiddlewareChain returns the chain of the middlewares in order, which
+// has to include auth. Errors are converted to their grpc status last.
+func (s *grpcServer) newMiddlewareChain(order string) (grpc.UnaryServerInterceptor, error) {
+	middlewares, err := middleware.Build(order, s.rpcMiddlewares(), "auth")
+	if err != nil {
+		return nil, fmt.Errorf("invalid RPC_MIDDLEWARE '%s': %w", order, err)
+	}
+
+	return middleware.Chain(append(middlewares, statusMiddleware)...), nil
+}
+
+// authMiddleware fails rpcs without a valid bearer token, and rpcs the
+// macaroon they were authenticated with doesn't allow. The token and its
+// node are put into the context for the handler, and into the request for
+// the middlewares.
+func (s *grpcServer) authMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, ok := s.authenticate(ctx)
+	if !ok {
+		return nil, lsperrors.ToStatus(lsperrors.ErrNotAuthorized)
+	}
+
+	nc := ctx.Value(contextKey("node")).(*nodeContext)
+	if !nc.restrictions.AllowMethod(info.FullMethod) {
+		return nil, lsperrors.ToStatus(lsperrors.ErrCaveatViolated)
+	}
+
+	if r := middleware.RequestFrom(ctx); r != nil {
+		r.Token = nc.token
+		r.Node = nc.node.nodeConfig
+		r.Logger = nc.node.logger
+	}
+
+	return handler(ctx, req)
+}
+
+// streamAuthMiddleware authenticates streaming rpcs like authMiddleware does
+// unary rpcs. Streams don't run through the other middlewares. Their context
+// is cancelled when the server stops.
+func (s *grpcServer) streamAuthMiddleware(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, ok := s.authenticate(ss.Context())
+	if !ok {
+		return lsperrors.ToStatus(lsperrors.ErrNotAuthorized)
+	}
+
+	nc := ctx.Value(contextKey("node")).(*nodeContext)
+	if !nc.restrictions.AllowMethod(info.FullMethod) {
+		return lsperrors.ToStatus(lsperrors.ErrCaveatViolated)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopping:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream is a stream with the context of its authenticated
+// node.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,418 @@
+package scoring
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lifecycle"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/breez/lspd/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// Scores start at the maximum and drop by the penalty of every event.
+	maxScore = 100
+
+	defaultUnsettledPenalty  = 10
+	defaultEarlyClosePenalty = 25
+	defaultEarlyCloseWindow  = 72 * time.Hour
+	defaultMemory            = 30 * 24 * time.Hour
+	defaultInterval          = 10 * time.Minute
+)
+
+// Kinds of events lowering the score of a client.
+const (
+	// A payment a channel was opened for didn't settle.
+	EventUnsettled = "unsettled"
+	// The client closed a channel soon after it was opened.
+	EventEarlyClose = "early_close"
+	// A payment of the client was probed.
+	EventProbe = "probe"
+)
+
+var (
+	scoreEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lspd_score_events_total",
+		Help: "Events lowering the score of clients, by kind.",
+	}, []string{"node", "kind"})
+	deniedClients = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lspd_score_denied_total",
+		Help: "Requests and htlcs of clients denied because of their score.",
+	}, []string{"node"})
+)
+
+// Event is an event lowering the score of a client.
+type Event struct {
+	PeerID []byte
+	Kind   string
+	// Identifies the event, like the payment hash or the channel point, so
+	// it is only counted once.
+	Ref       string
+	Penalty   float64
+	CreatedAt time.Time
+}
+
+// Penalty is the sum of the penalties of the events of a kind of a client.
+type Penalty struct {
+	PeerID      []byte
+	Kind        string
+	Count       int64
+	Total       float64
+	LastEventAt time.Time
+}
+
+type Store interface {
+	// AddEvent records the event. Returns false if an event of the same kind
+	// and ref was recorded for the client already.
+	AddEvent(ctx context.Context, lspNodeID []byte, e *Event) (bool, error)
+	// ListPenalties returns the penalties of the events since the given time
+	// that were not reset, of the client, or of all clients if peerID is nil.
+	ListPenalties(ctx context.Context, lspNodeID []byte, peerID []byte, since time.Time) ([]*Penalty, error)
+	// Reset excludes the events recorded for the client so far from its
+	// score.
+	Reset(ctx context.Context, lspNodeID []byte, peerID []byte) error
+}
+
+// ChannelStore lists the channels opened by lspd.
+type ChannelStore interface {
+	ListChannels(ctx context.Context, lspNodeID []byte, includeClosed bool) ([]*lifecycle.Channel, error)
+}
+
+// Score is the score of a client.
+type Score struct {
+	Node        string    `json:"node"`
+	PeerID      string    `json:"peerId"`
+	Score       float64   `json:"score"`
+	Unsettled   int64     `json:"unsettled"`
+	EarlyCloses int64     `json:"earlyCloses"`
+	Probes      int64     `json:"probes"`
+	Denied      bool      `json:"denied"`
+	LastEventAt time.Time `json:"lastEventAt"`
+}
+
+// Scorer scores clients by their behavior, and denies service to clients
+// whose score dropped below the minimum of the node. Every event lowers the
+// score of the client by its penalty, until the event is older than the
+// memory of the scorer or the score is reset. A nil Scorer allows everyone.
+type Scorer struct {
+	client           lightning.Client
+	store            Store
+	channels         ChannelStore
+	node             *config.NodeConfig
+	logger           *log.Logger
+	lspNodeID        []byte
+	earlyCloseWindow time.Duration
+	memory           time.Duration
+	interval         time.Duration
+	ctx              context.Context
+	cancel           context.CancelFunc
+	mtx              sync.Mutex
+}
+
+func NewScorer(
+	client lightning.Client,
+	store Store,
+	channels ChannelStore,
+	node *config.NodeConfig,
+	logger *log.Logger,
+) *Scorer {
+	lspNodeID, _ := hex.DecodeString(node.NodePubkey)
+	s := &Scorer{
+		client:           client,
+		store:            store,
+		channels:         channels,
+		node:             node,
+		logger:           logger,
+		lspNodeID:        lspNodeID,
+		earlyCloseWindow: defaultEarlyCloseWindow,
+		memory:           defaultMemory,
+		interval:         defaultInterval,
+	}
+
+	if cfg := node.Scoring; cfg != nil {
+		s.earlyCloseWindow = parseDuration(cfg.EarlyCloseWindow, defaultEarlyCloseWindow, logger)
+		s.memory = parseDuration(cfg.Memory, defaultMemory, logger)
+		s.interval = parseDuration(cfg.Interval, defaultInterval, logger)
+	}
+
+	return s
+}
+
+func parseDuration(s string, def time.Duration, logger *log.Logger) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		logger.Printf("WARN: Invalid scoring duration '%s'. Using default %v", s, def)
+		return def
+	}
+
+	return d
+}
+
+func (s *Scorer) Node() *config.NodeConfig {
+	return s.node
+}
+
+func (s *Scorer) penalty(kind string) float64 {
+	cfg := s.node.Scoring
+	if cfg == nil {
+		return 0
+	}
+
+	switch kind {
+	case EventUnsettled:
+		if cfg.UnsettledPenalty > 0 {
+			return cfg.UnsettledPenalty
+		}
+		return defaultUnsettledPenalty
+	case EventEarlyClose:
+		if cfg.EarlyClosePenalty > 0 {
+			return cfg.EarlyClosePenalty
+		}
+		return defaultEarlyClosePenalty
+	case EventProbe:
+		return cfg.ProbePenalty
+	}
+
+	return 0
+}
+
+// Record records an event of the client. Events of the same kind and ref are
+// only counted once.
+func (s *Scorer) Record(ctx context.Context, peerID []byte, kind string, ref string) {
+	if s == nil {
+		return
+	}
+
+	e := &Event{
+		PeerID:    peerID,
+		Kind:      kind,
+		Ref:       ref,
+		Penalty:   s.penalty(kind),
+		CreatedAt: time.Now(),
+	}
+	added, err := s.store.AddEvent(ctx, s.lspNodeID, e)
+	if err != nil {
+		s.logger.Printf("scoring: AddEvent(%x, %s, %s) error: %v", peerID, kind, ref, err)
+		return
+	}
+
+	if added {
+		s.logger.Printf("scoring: %s event %s of %x, penalty %v", kind, ref, peerID, e.Penalty)
+		scoreEvents.WithLabelValues(s.node.Label(), kind).Inc()
+	}
+}
+
+// RecordProbe records a probe for a payment of the client.
+func (s *Scorer) RecordProbe(ctx context.Context, peerID []byte, paymentHash []byte) {
+	s.Record(ctx, peerID, EventProbe, hex.EncodeToString(paymentHash))
+}
+
+// PaymentUnsettled records a payment a channel was opened for that didn't
+// settle.
+func (s *Scorer) PaymentUnsettled(ctx context.Context, peerID []byte, paymentHash string) {
+	s.Record(ctx, peerID, EventUnsettled, paymentHash)
+}
+
+// Check returns lsperrors.ErrPeerDenied if the score of the client is below
+// the minimum. Clients are allowed if their score can't be determined.
+func (s *Scorer) Check(ctx context.Context, peerID []byte) error {
+	if s == nil || s.node.Scoring == nil {
+		return nil
+	}
+
+	scores, err := s.scores(ctx, peerID)
+	if err != nil {
+		s.logger.Printf("scoring: allowing %x, failed to get score: %v", peerID, err)
+		return nil
+	}
+
+	if len(scores) == 0 || !scores[0].Denied {
+		return nil
+	}
+
+	if s.node.Scoring.DryRun {
+		s.logger.Printf("scoring: dry run, not denying %x with score %v", peerID, scores[0].Score)
+		return nil
+	}
+
+	s.logger.Printf("scoring: denying %x with score %v", peerID, scores[0].Score)
+	deniedClients.WithLabelValues(s.node.Label()).Inc()
+	return fmt.Errorf("%w: score %v below %v", lsperrors.ErrPeerDenied, scores[0].Score, s.node.Scoring.MinScore)
+}
+
+// Scores returns the scores of the clients with events within the memory,
+// lowest first.
+func (s *Scorer) Scores(ctx context.Context) ([]*Score, error) {
+	return s.scores(ctx, nil)
+}
+
+// Reset resets the score of the client to the maximum.
+func (s *Scorer) Reset(ctx context.Context, peerID []byte) error {
+	err := s.store.Reset(ctx, s.lspNodeID, peerID)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Printf("scoring: reset score of %x", peerID)
+	return nil
+}
+
+func (s *Scorer) scores(ctx context.Context, peerID []byte) ([]*Score, error) {
+	penalties, err := s.store.ListPenalties(ctx, s.lspNodeID, peerID, time.Now().Add(-s.memory))
+	if err != nil {
+		return nil, err
+	}
+
+	return scores(s.node, penalties), nil
+}
+
+func scores(node *config.NodeConfig, penalties []*Penalty) []*Score {
+	byPeer := make(map[string]*Score)
+	for _, p := range penalties {
+		key := hex.EncodeToString(p.PeerID)
+		score, ok := byPeer[key]
+		if !ok {
+			score = &Score{Node: node.Name, PeerID: key, Score: maxScore}
+			byPeer[key] = score
+		}
+
+		score.Score -= p.Total
+		if p.LastEventAt.After(score.LastEventAt) {
+			score.LastEventAt = p.LastEventAt
+		}
+		switch p.Kind {
+		case EventUnsettled:
+			score.Unsettled += p.Count
+		case EventEarlyClose:
+			score.EarlyCloses += p.Count
+		case EventProbe:
+			score.Probes += p.Count
+		}
+	}
+
+	result := make([]*Score, 0, len(byPeer))
+	for _, score := range byPeer {
+		if score.Score < 0 {
+			score.Score = 0
+		}
+		if node.Scoring != nil {
+			score.Denied = score.Score < node.Scoring.MinScore
+		}
+		result = append(result, score)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score < result[j].Score
+		}
+		return result[i].PeerID < result[j].PeerID
+	})
+	return result
+}
+
+// Start checks for channels closed by clients soon after they were opened
+// periodically. Returns immediately if scoring is not configured for the
+// node.
+func (s *Scorer) Start() error {
+	if s.node.Scoring == nil {
+		return nil
+	}
+
+	s.mtx.Lock()
+	s.ctx, s.cancel = context.WithCancel(tenant.NewContext(context.Background(), s.node.Tenant))
+	ctx := s.ctx
+	s.mtx.Unlock()
+
+	s.logger.Printf("scoring: denying clients with a score below %v, checking for early closes every %v (dry run: %v)",
+		s.node.Scoring.MinScore, s.interval, s.node.Scoring.DryRun)
+	for {
+		s.checkEarlyCloses(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+func (s *Scorer) Stop() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// checkEarlyCloses records the channels opened within the early close window
+// that are no longer open or pending on the node. Channels closed by lspd are
+// marked closed in the channel store, so these were closed by the client.
+func (s *Scorer) checkEarlyCloses(ctx context.Context) {
+	channels, err := s.channels.ListChannels(ctx, s.lspNodeID, false)
+	if err != nil {
+		s.logger.Printf("scoring: ListChannels() error: %v", err)
+		return
+	}
+
+	now := time.Now()
+	peerChannels := make(map[string]map[string]bool)
+	for _, c := range channels {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if c.OpenedAt == nil || now.Sub(*c.OpenedAt) > s.earlyCloseWindow {
+			continue
+		}
+
+		open, ok := peerChannels[c.PeerID]
+		if !ok {
+			open, err = s.openChannels(ctx, c.PeerID)
+			if err != nil {
+				s.logger.Printf("scoring: %v", err)
+				continue
+			}
+			peerChannels[c.PeerID] = open
+		}
+
+		if open[c.ChannelPoint] {
+			continue
+		}
+
+		peerID, _ := hex.DecodeString(c.PeerID)
+		s.Record(ctx, peerID, EventEarlyClose, c.ChannelPoint)
+	}
+}
+
+// openChannels returns the channel points of the open and pending channels
+// with the peer.
+func (s *Scorer) openChannels(ctx context.Context, peer string) (map[string]bool, error) {
+	peerID, err := hex.DecodeString(peer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer id %s: %w", peer, err)
+	}
+
+	channels, err := s.client.ListPeerChannels(ctx, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("ListPeerChannels(%s) error: %w", peer, err)
+	}
+
+	open := make(map[string]bool)
+	for _, ch := range channels {
+		open[ch.ChannelPoint] = true
+	}
+
+	return open, nil
+}
@@ -0,0 +1,125 @@
+package scoring
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lifecycle"
+	"github.com/breez/lspd/lightning"
+	"github.com/breez/lspd/lsperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockStore struct {
+	events []*Event
+}
+
+func (s *mockStore) AddEvent(ctx context.Context, lspNodeID []byte, e *Event) (bool, error) {
+	for _, existing := range s.events {
+		if string(existing.PeerID) == string(e.PeerID) && existing.Kind == e.Kind && existing.Ref == e.Ref {
+			return false, nil
+		}
+	}
+	s.events = append(s.events, e)
+	return true, nil
+}
+
+func (s *mockStore) ListPenalties(ctx context.Context, lspNodeID []byte, peerID []byte, since time.Time) ([]*Penalty, error) {
+	var penalties []*Penalty
+	for _, e := range s.events {
+		if peerID != nil && string(e.PeerID) != string(peerID) {
+			continue
+		}
+		penalties = append(penalties, &Penalty{PeerID: e.PeerID, Kind: e.Kind, Count: 1, Total: e.Penalty, LastEventAt: e.CreatedAt})
+	}
+	return penalties, nil
+}
+
+func (s *mockStore) Reset(ctx context.Context, lspNodeID []byte, peerID []byte) error {
+	var events []*Event
+	for _, e := range s.events {
+		if string(e.PeerID) != string(peerID) {
+			events = append(events, e)
+		}
+	}
+	s.events = events
+	return nil
+}
+
+type mockChannels struct {
+	channels []*lifecycle.Channel
+}
+
+func (c *mockChannels) ListChannels(ctx context.Context, lspNodeID []byte, includeClosed bool) ([]*lifecycle.Channel, error) {
+	return c.channels, nil
+}
+
+type mockClient struct {
+	lightning.Client
+	channels []*lightning.PeerChannel
+}
+
+func (c *mockClient) ListPeerChannels(ctx context.Context, peerID []byte) ([]*lightning.PeerChannel, error) {
+	return c.channels, nil
+}
+
+func TestCheck(t *testing.T) {
+	store := &mockStore{}
+	node := &config.NodeConfig{Scoring: &config.ScoringConfig{MinScore: 85}}
+	scorer := NewScorer(nil, store, nil, node, log.New(os.Stderr, "", 0))
+	ctx := context.Background()
+	peer := []byte{0x02, 0xaa}
+
+	scorer.PaymentUnsettled(ctx, peer, "01")
+	scorer.PaymentUnsettled(ctx, peer, "01")
+	assert.NoError(t, scorer.Check(ctx, peer))
+
+	scorer.PaymentUnsettled(ctx, peer, "02")
+	scorer.RecordProbe(ctx, peer, []byte{0x03})
+	err := scorer.Check(ctx, peer)
+	assert.True(t, errors.Is(err, lsperrors.ErrPeerDenied))
+
+	scores, err := scorer.Scores(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, scores, 1)
+	assert.Equal(t, float64(80), scores[0].Score)
+	assert.Equal(t, int64(2), scores[0].Unsettled)
+	assert.Equal(t, int64(1), scores[0].Probes)
+
+	// Other clients are not affected.
+	assert.NoError(t, scorer.Check(ctx, []byte{0x02, 0xbb}))
+
+	assert.NoError(t, scorer.Reset(ctx, peer))
+	assert.NoError(t, scorer.Check(ctx, peer))
+
+	// A nil scorer allows everyone.
+	var none *Scorer
+	assert.NoError(t, none.Check(ctx, peer))
+}
+
+func TestCheckEarlyCloses(t *testing.T) {
+	store := &mockStore{}
+	now := time.Now()
+	old := now.Add(-30 * 24 * time.Hour)
+	peer := "02aa"
+	channels := &mockChannels{channels: []*lifecycle.Channel{
+		{ChannelPoint: "a:0", PeerID: peer, OpenedAt: &now},
+		{ChannelPoint: "b:0", PeerID: peer, OpenedAt: &now},
+		// Closes long after the open don't count.
+		{ChannelPoint: "c:0", PeerID: peer, OpenedAt: &old},
+	}}
+	client := &mockClient{channels: []*lightning.PeerChannel{{ChannelPoint: "a:0"}}}
+	node := &config.NodeConfig{Scoring: &config.ScoringConfig{}}
+	scorer := NewScorer(client, store, channels, node, log.New(os.Stderr, "", 0))
+
+	scorer.checkEarlyCloses(context.Background())
+	assert.Len(t, store.events, 1)
+	assert.Equal(t, EventEarlyClose, store.events[0].Kind)
+	assert.Equal(t, "b:0", store.events[0].Ref)
+	assert.Equal(t, float64(defaultEarlyClosePenalty), store.events[0].Penalty)
+}
@@ -0,0 +1,43 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+type kmsDecrypter interface {
+	DecryptWithContext(ctx aws.Context, input *kms.DecryptInput, opts ...request.Option) (*kms.DecryptOutput, error)
+}
+
+// KMSSource decrypts values encrypted with AWS KMS. References are the base64
+// encoded ciphertext blob, like the output of
+// `aws kms encrypt --key-id <key> --plaintext fileb://<file>`. The ciphertext
+// names the key, the credentials of lspd need kms:Decrypt on it.
+type KMSSource struct {
+	client kmsDecrypter
+}
+
+func NewKMSSource(client *kms.KMS) *KMSSource {
+	return &KMSSource{client: client}
+}
+
+func (s *KMSSource) Fetch(ctx context.Context, ref string) (string, error) {
+	blob, err := base64.StdEncoding.DecodeString(ref)
+	if err != nil {
+		return "", fmt.Errorf("kms ciphertext must be base64 encoded: %w", err)
+	}
+
+	out, err := s.client.DecryptWithContext(ctx, &kms.DecryptInput{
+		CiphertextBlob: blob,
+	})
+	if err != nil {
+		return "", fmt.Errorf("kms.Decrypt() error: %w", err)
+	}
+
+	return string(out.Plaintext), nil
+}
@@ -0,0 +1,138 @@
+// Package secrets loads the secrets referenced in the node configs, like the
+// lspd private key and the lnd macaroon, from HashiCorp Vault or AWS KMS, so
+// they don't have to be stored in plaintext env variables or config files.
+//
+// A config value like vault:secret/data/lspd#privateKey is a reference to the
+// privateKey field of the Vault secret at secret/data/lspd, and
+// awskms:<base64 ciphertext> is a value encrypted with AWS KMS. Other values
+// are used as they are.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SchemeVault references a field of a secret in Vault.
+	SchemeVault = "vault"
+
+	// SchemeKMS is a value encrypted with AWS KMS.
+	SchemeKMS = "awskms"
+
+	// SchemeKMSKey references an asymmetric secp256k1 AWS KMS key, by key
+	// id, arn or alias. Only the lspd private key can be one, the key never
+	// leaves KMS, lspd has the signatures it needs made by KMS.
+	SchemeKMSKey = "awskms-key"
+)
+
+var ErrNoSource = errors.New("no source configured for secret reference")
+
+// Source fetches the secrets of a scheme.
+type Source interface {
+	// Fetch returns the secret the reference, the part after the scheme,
+	// points to.
+	Fetch(ctx context.Context, ref string) (string, error)
+}
+
+// ParseReference splits a config value into the scheme and reference, if it
+// is a reference to a secret.
+func ParseReference(value string) (scheme string, ref string, ok bool) {
+	scheme, ref, ok = strings.Cut(value, ":")
+	if !ok {
+		return "", "", false
+	}
+
+	switch scheme {
+	case SchemeVault, SchemeKMS, SchemeKMSKey:
+		return scheme, ref, ref != ""
+	}
+
+	return "", "", false
+}
+
+// IsReference returns whether the config value references a secret.
+func IsReference(value string) bool {
+	_, _, ok := ParseReference(value)
+	return ok
+}
+
+type cachedSecret struct {
+	value     string
+	fetchedAt time.Time
+}
+
+// Resolver resolves references with the registered sources. Secrets are
+// cached for the ttl, then fetched again, so secrets changed in Vault are
+// picked up without a restart. If fetching fails, the cached secret is used
+// until it succeeds, so an outage of Vault doesn't take lspd down.
+type Resolver struct {
+	ttl     time.Duration
+	sources map[string]Source
+
+	mtx   sync.Mutex
+	cache map[string]*cachedSecret
+	now   func() time.Time
+}
+
+func NewResolver(ttl time.Duration) *Resolver {
+	return &Resolver{
+		ttl:     ttl,
+		sources: make(map[string]Source),
+		cache:   make(map[string]*cachedSecret),
+		now:     time.Now,
+	}
+}
+
+// Register sets the source of the secrets of the scheme.
+func (r *Resolver) Register(scheme string, source Source) {
+	r.sources[scheme] = source
+}
+
+// Resolve returns the secret the value references, or the value itself if it
+// isn't a reference. A nil Resolver only passes values through.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, ref, ok := ParseReference(value)
+	if !ok {
+		return value, nil
+	}
+	if scheme == SchemeKMSKey {
+		return "", fmt.Errorf("%s references a key, not a secret", scheme)
+	}
+
+	var source Source
+	if r != nil {
+		source = r.sources[scheme]
+	}
+	if source == nil {
+		return "", fmt.Errorf("%w: %s", ErrNoSource, scheme)
+	}
+
+	r.mtx.Lock()
+	cached := r.cache[value]
+	r.mtx.Unlock()
+	now := r.now()
+	if cached != nil && now.Sub(cached.fetchedAt) < r.ttl {
+		return cached.value, nil
+	}
+
+	secret, err := source.Fetch(ctx, ref)
+	if err != nil {
+		if cached != nil {
+			log.Printf("secrets: failed to refresh %s secret, using the cached secret: %v", scheme, err)
+			return cached.value, nil
+		}
+
+		return "", fmt.Errorf("fetch %s secret error: %w", scheme, err)
+	}
+
+	r.mtx.Lock()
+	r.cache[value] = &cachedSecret{value: secret, fetchedAt: now}
+	r.mtx.Unlock()
+	return secret, nil
+}
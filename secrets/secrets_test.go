@@ -0,0 +1,81 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	scheme, ref, ok := ParseReference("vault:secret/data/lspd#privateKey")
+	assert.True(t, ok)
+	assert.Equal(t, SchemeVault, scheme)
+	assert.Equal(t, "secret/data/lspd#privateKey", ref)
+
+	assert.True(t, IsReference("awskms-key:alias/lspd"))
+	assert.False(t, IsReference("0201036c6e64"))
+	assert.False(t, IsReference("vault:"))
+}
+
+func TestVaultResolver(t *testing.T) {
+	macaroon := "0201"
+	up := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up || r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		assert.Equal(t, "/v1/secret/data/lspd", r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     map[string]interface{}{"macaroon": macaroon},
+				"metadata": map[string]interface{}{"version": 1},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	r := NewResolver(time.Minute)
+	r.now = func() time.Time { return now }
+	r.Register(SchemeVault, NewVaultSource(VaultConfig{Address: srv.URL, Token: "token"}))
+
+	value, err := r.Resolve(ctx, "vault:secret/data/lspd#macaroon")
+	assert.NoError(t, err)
+	assert.Equal(t, "0201", value)
+
+	value, err = r.Resolve(ctx, "0202")
+	assert.NoError(t, err)
+	assert.Equal(t, "0202", value)
+
+	_, err = r.Resolve(ctx, "vault:secret/data/lspd#missing")
+	assert.Error(t, err)
+
+	// Secrets are cached for the ttl, then fetched again.
+	macaroon = "0203"
+	value, _ = r.Resolve(ctx, "vault:secret/data/lspd#macaroon")
+	assert.Equal(t, "0201", value)
+	now = now.Add(time.Minute)
+	value, _ = r.Resolve(ctx, "vault:secret/data/lspd#macaroon")
+	assert.Equal(t, "0203", value)
+
+	// The cached secret is used while vault is unavailable.
+	up = false
+	now = now.Add(time.Minute)
+	value, err = r.Resolve(ctx, "vault:secret/data/lspd#macaroon")
+	assert.NoError(t, err)
+	assert.Equal(t, "0203", value)
+
+	_, err = r.Resolve(ctx, "awskms:AQID")
+	assert.ErrorIs(t, err, ErrNoSource)
+	var none *Resolver
+	_, err = none.Resolve(ctx, "vault:secret/data/lspd#macaroon")
+	assert.ErrorIs(t, err, ErrNoSource)
+}
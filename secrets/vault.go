@@ -0,0 +1,256 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Time to wait before retrying to renew or log in after a failure.
+const vaultRetryInterval = time.Minute
+
+type VaultConfig struct {
+	// Address of the Vault server, like https://vault.example.com:8200.
+	Address string
+
+	// Vault Enterprise namespace, if any.
+	Namespace string
+
+	// Token to authenticate with. Ignored if RoleID is set.
+	Token string
+
+	// Log in with the AppRole auth method instead of a token.
+	RoleID   string
+	SecretID string
+}
+
+// VaultSource reads secrets from the KV secrets engine of Vault, version 1
+// or 2. References are the api path of the secret and the field, like
+// secret/data/lspd#privateKey. The token is renewed before it expires, or
+// obtained again with AppRole when it can't be renewed.
+type VaultSource struct {
+	config VaultConfig
+	client *http.Client
+
+	mtx       sync.Mutex
+	token     string
+	ttl       time.Duration
+	renewable bool
+}
+
+func NewVaultSource(config VaultConfig) *VaultSource {
+	return &VaultSource{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+		token:  config.Token,
+	}
+}
+
+type vaultAuth struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int64  `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+}
+
+type vaultResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Auth   *vaultAuth             `json:"auth"`
+	Errors []string               `json:"errors"`
+}
+
+type vaultError struct {
+	status int
+	errors []string
+}
+
+func (e *vaultError) Error() string {
+	return fmt.Sprintf("vault returned status %d: %s", e.status, strings.Join(e.errors, "; "))
+}
+
+func (s *VaultSource) call(ctx context.Context, method string, path string, token string, body interface{}) (*vaultResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	url := strings.TrimRight(s.config.Address, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if s.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.config.Namespace)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result vaultResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &vaultError{status: resp.StatusCode, errors: result.Errors}
+	}
+
+	return &result, nil
+}
+
+// login obtains a token with AppRole, or looks up the lifetime of the
+// configured token.
+func (s *VaultSource) login(ctx context.Context) error {
+	if s.config.RoleID == "" {
+		resp, err := s.call(ctx, http.MethodGet, "auth/token/lookup-self", s.config.Token, nil)
+		if err != nil {
+			return fmt.Errorf("token lookup-self error: %w", err)
+		}
+
+		ttl, _ := resp.Data["ttl"].(float64)
+		renewable, _ := resp.Data["renewable"].(bool)
+		s.setToken(s.config.Token, time.Duration(ttl)*time.Second, renewable)
+		return nil
+	}
+
+	resp, err := s.call(ctx, http.MethodPost, "auth/approle/login", "", map[string]string{
+		"role_id":   s.config.RoleID,
+		"secret_id": s.config.SecretID,
+	})
+	if err != nil {
+		return fmt.Errorf("approle login error: %w", err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return fmt.Errorf("approle login returned no token")
+	}
+
+	s.setToken(resp.Auth.ClientToken, time.Duration(resp.Auth.LeaseDuration)*time.Second, resp.Auth.Renewable)
+	return nil
+}
+
+func (s *VaultSource) setToken(token string, ttl time.Duration, renewable bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.token = token
+	s.ttl = ttl
+	s.renewable = renewable
+}
+
+func (s *VaultSource) currentToken() string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.token
+}
+
+// renew extends the lifetime of the token. If the token can't be renewed, a
+// new one is obtained with AppRole.
+func (s *VaultSource) renew(ctx context.Context) error {
+	s.mtx.Lock()
+	renewable := s.renewable
+	s.mtx.Unlock()
+	if renewable {
+		resp, err := s.call(ctx, http.MethodPost, "auth/token/renew-self", s.currentToken(), map[string]string{})
+		if err == nil && resp.Auth != nil {
+			s.setToken(s.currentToken(), time.Duration(resp.Auth.LeaseDuration)*time.Second, resp.Auth.Renewable)
+			return nil
+		}
+		if s.config.RoleID == "" {
+			return fmt.Errorf("token renew-self error: %w", err)
+		}
+	}
+
+	return s.login(ctx)
+}
+
+// Run keeps the token valid until the context is done. Tokens without a ttl
+// don't expire, then Run returns once it looked the token up.
+func (s *VaultSource) Run(ctx context.Context) {
+	err := s.login(ctx)
+	for {
+		if err != nil {
+			log.Printf("secrets: failed to renew the vault token, retrying in %v: %v", vaultRetryInterval, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(vaultRetryInterval):
+			}
+
+			err = s.renew(ctx)
+			continue
+		}
+
+		s.mtx.Lock()
+		ttl := s.ttl
+		s.mtx.Unlock()
+		if ttl <= 0 {
+			return
+		}
+
+		// Renew after two thirds of the lifetime, leaving time to retry.
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ttl * 2 / 3):
+		}
+
+		err = s.renew(ctx)
+	}
+}
+
+func (s *VaultSource) Fetch(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("vault reference must be <path>#<field>, got '%s'", ref)
+	}
+
+	if s.currentToken() == "" && s.config.RoleID != "" {
+		err := s.login(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	resp, err := s.call(ctx, http.MethodGet, path, s.currentToken(), nil)
+	if e, ok := err.(*vaultError); ok && e.status == http.StatusForbidden && s.config.RoleID != "" {
+		// The token expired, while lspd was suspended for example.
+		err = s.login(ctx)
+		if err != nil {
+			return "", err
+		}
+		resp, err = s.call(ctx, http.MethodGet, path, s.currentToken(), nil)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read %s error: %w", path, err)
+	}
+
+	data := resp.Data
+	// Secrets of the KV version 2 engine are nested with their metadata.
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+
+	value, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s has no string field '%s'", path, field)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,120 @@
+package signer
+
+import (
+	"context"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+type kmsClient interface {
+	GetPublicKeyWithContext(ctx aws.Context, input *kms.GetPublicKeyInput, opts ...request.Option) (*kms.GetPublicKeyOutput, error)
+	SignWithContext(ctx aws.Context, input *kms.SignInput, opts ...request.Option) (*kms.SignOutput, error)
+}
+
+type kmsSigner struct {
+	client kmsClient
+	keyID  string
+	pubKey *btcec.PublicKey
+}
+
+// NewKMS returns a signer signing with the asymmetric AWS KMS key, which has
+// to be an ECC_SECG_P256K1 key with key usage SIGN_VERIFY. The credentials of
+// lspd need kms:GetPublicKey and kms:Sign on the key.
+func NewKMS(ctx context.Context, client *kms.KMS, keyID string) (Signer, error) {
+	return newKMS(ctx, client, keyID)
+}
+
+func newKMS(ctx context.Context, client kmsClient, keyID string) (*kmsSigner, error) {
+	out, err := client.GetPublicKeyWithContext(ctx, &kms.GetPublicKeyInput{
+		KeyId: aws.String(keyID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kms.GetPublicKey(%s) error: %w", keyID, err)
+	}
+
+	pubKey, err := parseSubjectPublicKeyInfo(out.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key of kms key %s: %w", keyID, err)
+	}
+
+	return &kmsSigner{
+		client: client,
+		keyID:  keyID,
+		pubKey: pubKey,
+	}, nil
+}
+
+// parseSubjectPublicKeyInfo parses the DER encoded public key returned by
+// KMS. x509.ParsePKIXPublicKey doesn't know the secp256k1 curve.
+func parseSubjectPublicKeyInfo(der []byte) (*btcec.PublicKey, error) {
+	var spki struct {
+		Algorithm asn1.RawValue
+		PublicKey asn1.BitString
+	}
+	rest, err := asn1.Unmarshal(der, &spki)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data after public key")
+	}
+
+	return btcec.ParsePubKey(spki.PublicKey.Bytes)
+}
+
+func (s *kmsSigner) PubKey() *btcec.PublicKey {
+	return s.pubKey
+}
+
+func (s *kmsSigner) SignCompact(ctx context.Context, hash []byte) ([]byte, error) {
+	out, err := s.client.SignWithContext(ctx, &kms.SignInput{
+		KeyId:            aws.String(s.keyID),
+		Message:          hash,
+		MessageType:      aws.String(kms.MessageTypeDigest),
+		SigningAlgorithm: aws.String(kms.SigningAlgorithmSpecEcdsaSha256),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kms.Sign(%s) error: %w", s.keyID, err)
+	}
+
+	var sig struct {
+		R, S *big.Int
+	}
+	_, err = asn1.Unmarshal(out.Signature, &sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kms signature: %w", err)
+	}
+
+	var r, sv btcec.ModNScalar
+	if r.SetByteSlice(sig.R.Bytes()) || sv.SetByteSlice(sig.S.Bytes()) {
+		return nil, fmt.Errorf("invalid kms signature: overflow")
+	}
+
+	// KMS doesn't normalize the signature to the low s form signatures of
+	// lspd always have.
+	if sv.IsOverHalfOrder() {
+		sv.Negate()
+	}
+
+	// The compact signature carries the recovery id KMS doesn't return, so
+	// find the one recovering the public key of the key.
+	compact := make([]byte, 65)
+	r.PutBytesUnchecked(compact[1:33])
+	sv.PutBytesUnchecked(compact[33:65])
+	for recoveryID := byte(0); recoveryID < 4; recoveryID++ {
+		compact[0] = 27 + 4 + recoveryID
+		pubKey, _, err := ecdsa.RecoverCompact(compact, hash)
+		if err == nil && pubKey.IsEqual(s.pubKey) {
+			return compact, nil
+		}
+	}
+
+	return nil, fmt.Errorf("kms signature doesn't match the public key of %s", s.keyID)
+}
@@ -0,0 +1,87 @@
+package signer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockKms signs like KMS: DER signatures, which may have a high s.
+type mockKms struct {
+	privateKey *btcec.PrivateKey
+	highS      bool
+}
+
+func (m *mockKms) GetPublicKeyWithContext(ctx aws.Context, input *kms.GetPublicKeyInput, opts ...request.Option) (*kms.GetPublicKeyOutput, error) {
+	spki, err := asn1.Marshal(struct {
+		Algorithm struct {
+			Algorithm asn1.ObjectIdentifier
+			Curve     asn1.ObjectIdentifier
+		}
+		PublicKey asn1.BitString
+	}{
+		Algorithm: struct {
+			Algorithm asn1.ObjectIdentifier
+			Curve     asn1.ObjectIdentifier
+		}{
+			Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1},
+			Curve:     asn1.ObjectIdentifier{1, 3, 132, 0, 10},
+		},
+		PublicKey: asn1.BitString{
+			Bytes:     m.privateKey.PubKey().SerializeUncompressed(),
+			BitLength: 65 * 8,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kms.GetPublicKeyOutput{KeyId: input.KeyId, PublicKey: spki}, nil
+}
+
+func (m *mockKms) SignWithContext(ctx aws.Context, input *kms.SignInput, opts ...request.Option) (*kms.SignOutput, error) {
+	compact, err := ecdsa.SignCompact(m.privateKey, input.Message, true)
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(big.Int).SetBytes(compact[1:33])
+	s := new(big.Int).SetBytes(compact[33:65])
+	if m.highS {
+		s.Sub(btcec.S256().N, s)
+	}
+
+	der, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		return nil, err
+	}
+
+	return &kms.SignOutput{KeyId: input.KeyId, Signature: der}, nil
+}
+
+func TestKmsSignCompact(t *testing.T) {
+	privateKey, err := btcec.NewPrivateKey()
+	assert.NoError(t, err)
+	hash := sha256.Sum256([]byte("opening fee params"))
+	expected, err := ecdsa.SignCompact(privateKey, hash[:], true)
+	assert.NoError(t, err)
+
+	for _, highS := range []bool{false, true} {
+		s, err := newKMS(context.Background(), &mockKms{privateKey: privateKey, highS: highS}, "alias/lspd")
+		assert.NoError(t, err)
+		assert.True(t, privateKey.PubKey().IsEqual(s.PubKey()))
+
+		sig, err := s.SignCompact(context.Background(), hash[:])
+		assert.NoError(t, err)
+		assert.Equal(t, expected, sig)
+	}
+}
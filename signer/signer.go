@@ -0,0 +1,38 @@
+// Package signer signs with the lspd private key, the key that identifies the
+// LSP to clients. The key is either held by lspd, or by AWS KMS, in which case
+// it never leaves KMS.
+package signer
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+type Signer interface {
+	// PubKey returns the public key of the signing key.
+	PubKey() *btcec.PublicKey
+
+	// SignCompact signs the hash and returns a compact signature the public
+	// key can be recovered from, like ecdsa.SignCompact with compressed
+	// public keys.
+	SignCompact(ctx context.Context, hash []byte) ([]byte, error)
+}
+
+type localSigner struct {
+	privateKey *btcec.PrivateKey
+}
+
+// NewLocal returns a signer signing with the private key in memory.
+func NewLocal(privateKey *btcec.PrivateKey) Signer {
+	return &localSigner{privateKey: privateKey}
+}
+
+func (s *localSigner) PubKey() *btcec.PublicKey {
+	return s.privateKey.PubKey()
+}
+
+func (s *localSigner) SignCompact(ctx context.Context, hash []byte) ([]byte, error) {
+	return ecdsa.SignCompact(s.privateKey, hash, true)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/interceptor"
+)
+
+// runSimulate replays the htlcs of an interception log against the node
+// configs, and prints what lspd would decide about them now next to what it
+// did. Nothing is opened, and neither the nodes nor the database are used,
+// so fee and policy changes can be tried out safely.
+func runSimulate(args []string) {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	logFile := flags.String("log", os.Getenv("INTERCEPTION_LOG"), "interception log to replay")
+	file := flags.String("file", os.Getenv("NODES_FILE"), "json, yaml or toml file with the node configs, the NODES env is used if empty")
+	nodeName := flags.String("node", "", "only replay the htlcs of this node")
+	changed := flags.Bool("changed", false, "only print htlcs of which the decision changed")
+	verbose := flags.Bool("verbose", false, "print the log of the interceptor")
+	flags.Parse(args)
+
+	if *logFile == "" {
+		fmt.Fprintln(os.Stderr, "simulate: set --log or INTERCEPTION_LOG")
+		os.Exit(2)
+	}
+
+	nodes, err := config.Load(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "simulate: failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(*logFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "simulate: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	var out io.Writer = io.Discard
+	if *verbose {
+		out = os.Stderr
+	}
+
+	simulators := make(map[string]*interceptor.Simulator)
+	var total, different, skipped int
+	actions := make(map[string]int)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tNODE\tPAYMENT HASH\tAMOUNT (MSAT)\tRECORDED\tSIMULATED\t")
+	err = interceptor.ReadInterceptionLog(f, func(h *interceptor.RecordedHtlc) error {
+		if *nodeName != "" && h.Node != *nodeName && nodeLabelName(h.Node) != *nodeName {
+			return nil
+		}
+
+		sim, ok := simulators[h.Node]
+		if !ok {
+			node := simulationNode(nodes, h.Node)
+			if node == nil {
+				skipped++
+				return nil
+			}
+
+			sim = interceptor.NewSimulator(node, log.New(out, "["+h.Node+"] ", log.LstdFlags))
+			simulators[h.Node] = sim
+		}
+
+		decision, err := sim.Simulate(h)
+		if err != nil {
+			return fmt.Errorf("htlc %s: %w", h.PaymentHash, err)
+		}
+
+		total++
+		actions[decision.Action]++
+		recorded := "-"
+		if h.Decision != nil {
+			recorded = h.Decision.String()
+		}
+		marker := ""
+		if recorded != decision.String() {
+			different++
+			marker = "*"
+		} else if *changed {
+			return nil
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			h.Time.Local().Format("2006-01-02 15:04:05"), h.Node, shorten(h.PaymentHash),
+			h.AmountMsat, recorded, decision.String(), marker)
+		return nil
+	})
+	w.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "simulate: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n%d htlcs replayed, %d decided differently", total, different)
+	var names []string
+	for action := range actions {
+		names = append(names, action)
+	}
+	sort.Strings(names)
+	for _, action := range names {
+		fmt.Printf(", %d %s", actions[action], action)
+	}
+	fmt.Println()
+	if skipped > 0 {
+		fmt.Printf("%d htlcs skipped, their node is not in the config\n", skipped)
+	}
+}
+
+// simulationNode returns the config of the node with the label recorded in
+// the interception log. Offline the pubkey of a node is only known if it is
+// in the config, so nodes are matched by name as well.
+func simulationNode(nodes []*config.NodeConfig, label string) *config.NodeConfig {
+	for _, node := range nodes {
+		if node.Label() == label {
+			return node
+		}
+	}
+
+	name := nodeLabelName(label)
+	for _, node := range nodes {
+		if node.Name != "" && node.Name == name {
+			return node
+		}
+	}
+
+	return nil
+}
+
+// nodeLabelName returns the name part of a node label, name/pubkey.
+func nodeLabelName(label string) string {
+	name, _, _ := strings.Cut(label, "/")
+	return name
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/breez/lspd/interceptor"
+)
+
+const (
+	defaultStartupTimeout = 5 * time.Minute
+	maxStartupBackoff     = 30 * time.Second
+)
+
+// startupTimeout returns the maximum duration to wait for a single dependency
+// (database, lightning node, cln plugin) to become available on startup.
+func startupTimeout() time.Duration {
+	s := os.Getenv("STARTUP_TIMEOUT")
+	if s == "" {
+		return defaultStartupTimeout
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		log.Printf("WARN: Invalid STARTUP_TIMEOUT '%s'. Using default %v", s, defaultStartupTimeout)
+		return defaultStartupTimeout
+	}
+
+	return d
+}
+
+// waitFor calls check until it succeeds. It retries with exponential backoff
+// until the startup timeout expires, logging the status of every attempt.
+func waitFor(name string, check func() error) error {
+	timeout := startupTimeout()
+	deadline := time.Now().Add(timeout)
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err := check()
+		if err == nil {
+			log.Printf("startup: %s is ready.", name)
+			return nil
+		}
+
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("%s not ready after %v: %w", name, timeout, err)
+		}
+
+		log.Printf("startup: waiting for %s (attempt %d, retry in %v): %v", name, attempt, backoff, err)
+		<-time.After(backoff)
+		backoff *= 2
+		if backoff > maxStartupBackoff {
+			backoff = maxStartupBackoff
+		}
+	}
+}
+
+// waitStarted waits until the interceptor is connected to the node, or the
+// startup timeout expires.
+func waitStarted(name string, i interceptor.HtlcInterceptor) error {
+	timeout := startupTimeout()
+	done := make(chan struct{})
+	go func() {
+		i.WaitStarted()
+		close(done)
+	}()
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-done:
+			log.Printf("startup: %s is ready.", name)
+			return nil
+		case <-deadline:
+			return fmt.Errorf("%s not ready after %v", name, timeout)
+		case <-time.After(10 * time.Second):
+			log.Printf("startup: waiting for %s.", name)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package streamwatch
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var goroutines = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "lspd_goroutines",
+	Help: "Running goroutines per node and subsystem, to spot goroutine leaks.",
+}, []string{"node", "subsystem"})
+
+// Go runs f in a new goroutine, counted in the goroutines of the subsystem
+// while it runs.
+func Go(node string, subsystem string, f func()) {
+	g := goroutines.WithLabelValues(node, subsystem)
+	g.Inc()
+	go func() {
+		defer g.Dec()
+		f()
+	}()
+}
@@ -0,0 +1,131 @@
+package streamwatch
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultIdleTimeout  = time.Minute
+	defaultProbeTimeout = 10 * time.Second
+)
+
+var idleReconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lspd_stream_idle_reconnects_total",
+	Help: "Streams re-established by the watchdog after being idle with a failing probe.",
+}, []string{"node", "stream"})
+
+// Probe checks whether the connection a stream runs on is still alive.
+type Probe func(ctx context.Context) error
+
+// Watchdog detects streams that look connected, but no longer receive
+// anything. A stream may be idle for a long time when there is nothing to
+// receive, so when the stream has been idle for the idle timeout, the
+// connection is probed. If the probe fails, the stream is cancelled, so its
+// owner re-establishes it.
+type Watchdog struct {
+	node         string
+	stream       string
+	idleTimeout  time.Duration
+	probeTimeout time.Duration
+	probe        Probe
+	lastActivity atomic.Int64
+	logger       *log.Logger
+}
+
+func NewWatchdog(
+	conf *config.NodeConfig,
+	stream string,
+	probe Probe,
+	logger *log.Logger,
+) *Watchdog {
+	idleTimeout := defaultIdleTimeout
+	probeTimeout := defaultProbeTimeout
+	if conf.StreamWatchdog != nil {
+		idleTimeout = parseDuration(conf.StreamWatchdog.IdleTimeout, idleTimeout, logger)
+		probeTimeout = parseDuration(conf.StreamWatchdog.ProbeTimeout, probeTimeout, logger)
+	}
+
+	return &Watchdog{
+		node:         conf.Label(),
+		stream:       stream,
+		idleTimeout:  idleTimeout,
+		probeTimeout: probeTimeout,
+		probe:        probe,
+		logger:       logger,
+	}
+}
+
+func parseDuration(s string, def time.Duration, logger *log.Logger) time.Duration {
+	if s == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		logger.Printf("WARN: invalid stream watchdog duration '%s', using %v: %v", s, def, err)
+		return def
+	}
+
+	return d
+}
+
+// Touch records that the stream received something.
+func (w *Watchdog) Touch() {
+	w.lastActivity.Store(time.Now().UnixNano())
+}
+
+// Watch returns the context to open a new stream with. The context is
+// cancelled when the stream is idle and the probe fails, or when the returned
+// cancel function is called.
+func (w *Watchdog) Watch(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	w.Touch()
+	if w.idleTimeout <= 0 {
+		return ctx, cancel
+	}
+
+	Go(w.node, "stream_watchdog", func() {
+		w.watch(ctx, cancel)
+	})
+	return ctx, cancel
+}
+
+func (w *Watchdog) watch(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(w.idleTimeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		idle := time.Since(time.Unix(0, w.lastActivity.Load()))
+		if idle < w.idleTimeout {
+			continue
+		}
+
+		probeCtx, probeCancel := context.WithTimeout(ctx, w.probeTimeout)
+		err := w.probe(probeCtx)
+		probeCancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			w.Touch()
+			continue
+		}
+
+		w.logger.Printf("%s stream idle for %v and probe failed: %v. Re-establishing the stream.", w.stream, idle.Round(time.Second), err)
+		idleReconnects.WithLabelValues(w.node, w.stream).Inc()
+		cancel()
+		return
+	}
+}
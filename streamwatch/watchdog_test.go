@@ -0,0 +1,81 @@
+package streamwatch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestWatchdog(probe Probe) *Watchdog {
+	conf := &config.NodeConfig{
+		Name: "test",
+		StreamWatchdog: &config.StreamWatchdogConfig{
+			IdleTimeout:  "40ms",
+			ProbeTimeout: "10ms",
+		},
+	}
+	return NewWatchdog(conf, "htlc", probe, log.New(os.Stderr, "", 0))
+}
+
+func TestWatchdogCancelsWhenProbeFails(t *testing.T) {
+	w := newTestWatchdog(func(ctx context.Context) error {
+		return fmt.Errorf("connection dead")
+	})
+
+	ctx, cancel := w.Watch(context.Background())
+	defer cancel()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("stream was not cancelled")
+	}
+}
+
+func TestWatchdogKeepsStreamWhenProbeSucceeds(t *testing.T) {
+	var probes atomic.Int32
+	w := newTestWatchdog(func(ctx context.Context) error {
+		probes.Add(1)
+		return nil
+	})
+
+	ctx, cancel := w.Watch(context.Background())
+	defer cancel()
+	<-time.After(200 * time.Millisecond)
+	assert.NoError(t, ctx.Err())
+	assert.True(t, probes.Load() > 0)
+}
+
+func TestWatchdogNoProbeWhileActive(t *testing.T) {
+	w := newTestWatchdog(func(ctx context.Context) error {
+		return fmt.Errorf("connection dead")
+	})
+
+	ctx, cancel := w.Watch(context.Background())
+	defer cancel()
+	for j := 0; j < 20; j++ {
+		w.Touch()
+		<-time.After(10 * time.Millisecond)
+	}
+	assert.NoError(t, ctx.Err())
+}
+
+func TestWatchdogDisabled(t *testing.T) {
+	conf := &config.NodeConfig{
+		StreamWatchdog: &config.StreamWatchdogConfig{IdleTimeout: "0"},
+	}
+	w := NewWatchdog(conf, "htlc", func(ctx context.Context) error {
+		return fmt.Errorf("connection dead")
+	}, log.New(os.Stderr, "", 0))
+
+	ctx, cancel := w.Watch(context.Background())
+	defer cancel()
+	<-time.After(50 * time.Millisecond)
+	assert.NoError(t, ctx.Err())
+}
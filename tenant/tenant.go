@@ -0,0 +1,32 @@
+// Package tenant carries the LSP operator an rpc or a notification is for.
+// In multi-tenant mode one lspd serves several independent operators, each
+// with their own nodes, tokens and fees. Every row is stored with the tenant
+// id of the context it was written in, and only read back in the context of
+// the same tenant. The empty id is the tenant of a single operator setup.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+type contextKey struct{}
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Valid returns whether id can be used as a tenant id.
+func Valid(id string) bool {
+	return id == "" || idPattern.MatchString(id)
+}
+
+// NewContext returns a context for the tenant.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant of the context, the default tenant if it
+// has none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,23 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", FromContext(ctx))
+	assert.Equal(t, "acme", FromContext(NewContext(ctx, "acme")))
+}
+
+func TestValid(t *testing.T) {
+	for _, id := range []string{"", "acme", "lsp-2", "a_b"} {
+		assert.True(t, Valid(id), id)
+	}
+	for _, id := range []string{"Acme", "-acme", "a b", "acme/1"} {
+		assert.False(t, Valid(id), id)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const certPollInterval = 30 * time.Second
+
+// certReloader serves a certificate loaded from disk, and loads it again on
+// SIGHUP or when the certificate or key file changes, so certificates can be
+// rotated without restarting lspd.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+	mtx      sync.RWMutex
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		done:     make(chan struct{}),
+	}
+
+	err := r.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// TLSConfig returns a tls config that always serves the latest certificate.
+func (r *certReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mtx.RLock()
+			defer r.mtx.RUnlock()
+			return r.cert, nil
+		},
+	}
+}
+
+// Start reloads the certificate on SIGHUP and on file changes, until Stop is
+// called.
+func (r *certReloader) Start() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(certPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-hup:
+			log.Printf("Received SIGHUP. Reloading tls certificate.")
+			err := r.reload()
+			if err != nil {
+				log.Printf("Failed to reload tls certificate, keeping the current one: %v", err)
+			}
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+
+			log.Printf("Tls certificate changed on disk. Reloading.")
+			err := r.reload()
+			if err != nil {
+				log.Printf("Failed to reload tls certificate, keeping the current one: %v", err)
+			}
+		}
+	}
+}
+
+func (r *certReloader) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+	})
+}
+
+func (r *certReloader) reload() error {
+	modTimes, err := r.statFiles()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("tls.LoadX509KeyPair(%s, %s) error: %w", r.certFile, r.keyFile, err)
+	}
+
+	r.mtx.Lock()
+	r.cert = &cert
+	r.modTimes = modTimes
+	r.mtx.Unlock()
+	log.Printf("Loaded tls certificate %s", r.certFile)
+	return nil
+}
+
+func (r *certReloader) changed() bool {
+	modTimes, err := r.statFiles()
+	if err != nil {
+		return false
+	}
+
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return modTimes != r.modTimes
+}
+
+func (r *certReloader) statFiles() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, f := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(f)
+		if err != nil {
+			return modTimes, fmt.Errorf("os.Stat(%s) error: %w", f, err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
@@ -0,0 +1,228 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/formula"
+	"github.com/breez/lspd/lightning"
+)
+
+var ErrNotFound = errors.New("token not found")
+
+// Token is an api token stored in the database. Nil settings fall back to
+// the configuration of the node the token belongs to.
+type Token struct {
+	Token                     string
+	LspNodeID                 []byte
+	Name                      string
+	AdditionalChannelCapacity *int64
+	TimeLockDelta             *uint32
+	TaprootChannels           *bool
+	PublicChannels            *bool
+	CommitmentType            *string
+	CreatedAt                 time.Time
+	DisabledAt                *time.Time
+}
+
+// FeeParams is an entry in the opening fee params menu of a token.
+type FeeParams struct {
+	Validity             time.Duration
+	MinMsat              uint64
+	Proportional         uint32
+	MaxIdleTime          uint32
+	MaxClientToSelfDelay uint32
+}
+
+type Store interface {
+	// Get returns the token, or nil if it doesn't exist. Disabled tokens are
+	// returned as well.
+	Get(ctx context.Context, token string) (*Token, error)
+	List(ctx context.Context) ([]*Token, error)
+	GetFeeParams(ctx context.Context, token string) ([]*FeeParams, error)
+	Create(ctx context.Context, token *Token, feeParams []*FeeParams) error
+
+	// Rotate creates newToken with the settings and fee params of oldToken
+	// and disables oldToken at disableAt.
+	Rotate(ctx context.Context, oldToken string, newToken string, disableAt time.Time) error
+	Disable(ctx context.Context, token string, at time.Time) error
+}
+
+// Active returns whether the token can currently be used to authenticate.
+func (t *Token) Active() bool {
+	return t.DisabledAt == nil || time.Now().Before(*t.DisabledAt)
+}
+
+// AdditionalChannelCapacity returns the channel capacity in satoshi added on
+// top of the incoming payment amount for channels opened for token t.
+func AdditionalChannelCapacity(t *Token, node *config.NodeConfig) int64 {
+	if t == nil || t.AdditionalChannelCapacity == nil {
+		return node.AdditionalChannelCapacity
+	}
+
+	return *t.AdditionalChannelCapacity
+}
+
+// ChannelCapacity returns the capacity in satoshi of a channel opened for a
+// payment of incomingAmountMsat for token t, computed by the capacity formula
+// of the token, its stored name, or the default formula. Without a formula,
+// or if the formula yields less than the payment amount, the capacity is the
+// payment amount plus the additional channel capacity of the token.
+func ChannelCapacity(t *Token, token string, node *config.NodeConfig, incomingAmountMsat int64) int64 {
+	paymentSat := incomingAmountMsat / 1000
+	additional := AdditionalChannelCapacity(t, node)
+	fallback := paymentSat + additional
+
+	cfg, ok := node.CapacityFormulas[token]
+	if !ok && t != nil && t.Name != "" {
+		cfg, ok = node.CapacityFormulas[t.Name]
+	}
+	if !ok {
+		cfg, ok = node.CapacityFormulas["default"]
+	}
+	if !ok || cfg == nil {
+		return fallback
+	}
+
+	var capacity float64
+	if cfg.Expression != "" {
+		// The expression is validated with the config.
+		expr, err := formula.Parse(cfg.Expression, config.CapacityFormulaVars...)
+		if err != nil {
+			return fallback
+		}
+		capacity = expr.Eval(map[string]float64{
+			"payment":    float64(paymentSat),
+			"additional": float64(additional),
+		})
+	} else {
+		for _, tier := range cfg.Tiers {
+			if tier != nil && (tier.UpToSat == 0 || paymentSat <= tier.UpToSat) {
+				capacity = float64(tier.CapacitySat)
+				break
+			}
+		}
+	}
+
+	if math.IsNaN(capacity) || math.IsInf(capacity, 0) || capacity < float64(paymentSat) {
+		return fallback
+	}
+
+	return int64(math.Ceil(capacity))
+}
+
+// TimeLockDelta returns the minimum timelock delta for opening a channel for
+// token t.
+func TimeLockDelta(t *Token, node *config.NodeConfig) uint32 {
+	if t == nil || t.TimeLockDelta == nil {
+		return node.TimeLockDelta
+	}
+
+	return *t.TimeLockDelta
+}
+
+// CommitmentType returns the commitment type of the channels opened for token
+// t, if both the node and the client support it. The commitment type of the
+// token takes precedence over its taprootChannels setting, which takes
+// precedence over the settings of the node. Empty if the node opens channels
+// with the type it prefers.
+func CommitmentType(t *Token, node *config.NodeConfig) lightning.CommitmentType {
+	if t != nil && t.CommitmentType != nil {
+		return lightning.CommitmentType(*t.CommitmentType)
+	}
+
+	nodeType := lightning.CommitmentType(node.CommitmentType)
+	if nodeType == "" && node.TaprootChannels {
+		nodeType = lightning.CommitmentTypeTaproot
+	}
+	if t == nil || t.TaprootChannels == nil {
+		return nodeType
+	}
+
+	if *t.TaprootChannels {
+		return lightning.CommitmentTypeTaproot
+	}
+	if nodeType == lightning.CommitmentTypeTaproot {
+		return ""
+	}
+
+	return nodeType
+}
+
+// PublicChannels returns whether the channels opened for payments of token t
+// should be announced to the network.
+func PublicChannels(t *Token, node *config.NodeConfig) bool {
+	if t == nil || t.PublicChannels == nil {
+		return node.PublicChannels
+	}
+
+	return *t.PublicChannels
+}
+
+// ChannelPolicy returns the forwarding policy to set on channels opened for
+// token t: the policy configured for the token, for the name of the stored
+// token, or the default policy, completed with the settings of the node.
+// Returns nil if no policy is configured for the token.
+func ChannelPolicy(t *Token, token string, node *config.NodeConfig) *lightning.ChannelPolicy {
+	cfg, ok := node.ChannelPolicies[token]
+	if !ok && t != nil && t.Name != "" {
+		cfg, ok = node.ChannelPolicies[t.Name]
+	}
+	if !ok {
+		cfg, ok = node.ChannelPolicies["default"]
+	}
+	if !ok || cfg == nil {
+		return nil
+	}
+
+	policy := &lightning.ChannelPolicy{
+		BaseFeeMsat:   node.BaseFeeMsat,
+		FeeRate:       node.FeeRate,
+		TimeLockDelta: TimeLockDelta(t, node),
+		MinHtlcMsat:   basetypes.MilliSatoshi(cfg.MinHtlcMsat),
+		MaxHtlcMsat:   basetypes.MilliSatoshi(cfg.MaxHtlcMsat),
+	}
+	if cfg.BaseFeeMsat != nil {
+		policy.BaseFeeMsat = basetypes.MilliSatoshi(*cfg.BaseFeeMsat)
+	}
+	if cfg.FeeRate != nil {
+		policy.FeeRate = *cfg.FeeRate
+	}
+	if cfg.TimeLockDelta != nil {
+		policy.TimeLockDelta = *cfg.TimeLockDelta
+	}
+	if policy.MaxHtlcMsat == 0 && node.Wumbo != nil {
+		policy.MaxHtlcMsat = node.Wumbo.MaxHtlcMsat
+	}
+
+	return policy
+}
+
+// Quota returns the quota of the channels opened for token t: the quota
+// configured for the token, for the name of the stored token, or the default
+// quota. The key identifies the counters of the token, the name of the
+// stored token if it has one, so rotating a token doesn't reset its usage.
+// Returns nil if no quota is configured for the token.
+func Quota(t *Token, token string, node *config.NodeConfig) (*config.QuotaConfig, string) {
+	key := token
+	if t != nil && t.Name != "" {
+		key = t.Name
+	}
+
+	cfg, ok := node.Quotas[token]
+	if !ok && t != nil && t.Name != "" {
+		cfg, ok = node.Quotas[t.Name]
+	}
+	if !ok {
+		cfg, ok = node.Quotas["default"]
+	}
+	if !ok || cfg == nil {
+		return nil, ""
+	}
+
+	return cfg, key
+}
@@ -0,0 +1,89 @@
+package tokens
+
+import (
+	"testing"
+
+	"github.com/breez/lspd/basetypes"
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/lightning"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelPolicy(t *testing.T) {
+	baseFee := uint64(2000)
+	delta := uint32(72)
+	node := &config.NodeConfig{
+		BaseFeeMsat:   1000,
+		FeeRate:       0.000001,
+		TimeLockDelta: 40,
+		Wumbo:         &config.WumboConfig{MaxHtlcMsat: 5_000_000},
+		ChannelPolicies: map[string]*config.ChannelPolicyConfig{
+			"partner": {BaseFeeMsat: &baseFee, TimeLockDelta: &delta, MinHtlcMsat: 1000},
+		},
+	}
+
+	assert.Nil(t, ChannelPolicy(nil, "other", node))
+
+	// Stored tokens match by name.
+	policy := ChannelPolicy(&Token{Name: "partner"}, "secret", node)
+	if assert.NotNil(t, policy) {
+		assert.Equal(t, basetypes.MilliSatoshi(2000), policy.BaseFeeMsat)
+		assert.Equal(t, 0.000001, policy.FeeRate)
+		assert.Equal(t, uint32(72), policy.TimeLockDelta)
+		assert.Equal(t, basetypes.MilliSatoshi(1000), policy.MinHtlcMsat)
+		assert.Equal(t, basetypes.MilliSatoshi(5_000_000), policy.MaxHtlcMsat)
+	}
+
+	node.ChannelPolicies["default"] = &config.ChannelPolicyConfig{}
+	policy = ChannelPolicy(nil, "other", node)
+	if assert.NotNil(t, policy) {
+		assert.Equal(t, basetypes.MilliSatoshi(1000), policy.BaseFeeMsat)
+		assert.Equal(t, uint32(40), policy.TimeLockDelta)
+	}
+}
+
+func TestChannelCapacity(t *testing.T) {
+	node := &config.NodeConfig{
+		AdditionalChannelCapacity: 100_000,
+		CapacityFormulas: map[string]*config.CapacityFormulaConfig{
+			"default": {Expression: "min(max(payment * 2, 100000), 5000000)"},
+			"partner": {Tiers: []*config.CapacityTierConfig{
+				{UpToSat: 10_000, CapacitySat: 50_000},
+				{UpToSat: 1_000_000, CapacitySat: 2_000_000},
+			}},
+		},
+	}
+
+	assert.Equal(t, int64(100_000), ChannelCapacity(nil, "other", node, 10_000_000))
+	assert.Equal(t, int64(400_000), ChannelCapacity(nil, "other", node, 200_000_000))
+	assert.Equal(t, int64(5_000_000), ChannelCapacity(nil, "other", node, 4_000_000_000))
+
+	// The capacity never drops below the payment amount.
+	assert.Equal(t, int64(6_100_000), ChannelCapacity(nil, "other", node, 6_000_000_000))
+
+	partner := &Token{Name: "partner"}
+	assert.Equal(t, int64(50_000), ChannelCapacity(partner, "secret", node, 10_000_000))
+	assert.Equal(t, int64(2_000_000), ChannelCapacity(partner, "secret", node, 500_000_000))
+	// Payments beyond the last tier fall back to the additional capacity.
+	assert.Equal(t, int64(3_100_000), ChannelCapacity(partner, "secret", node, 3_000_000_000))
+
+	delete(node.CapacityFormulas, "default")
+	assert.Equal(t, int64(110_000), ChannelCapacity(nil, "other", node, 10_000_000))
+}
+
+func TestCommitmentType(t *testing.T) {
+	taproot := true
+	noTaproot := false
+	anchors := "anchors"
+	node := &config.NodeConfig{TaprootChannels: true}
+
+	assert.Equal(t, lightning.CommitmentTypeTaproot, CommitmentType(nil, node))
+	assert.Equal(t, lightning.CommitmentType(""), CommitmentType(&Token{TaprootChannels: &noTaproot}, node))
+	assert.Equal(t, lightning.CommitmentTypeAnchors, CommitmentType(&Token{TaprootChannels: &taproot, CommitmentType: &anchors}, node))
+
+	// The commitment type of the node takes precedence over its
+	// taprootChannels.
+	node.CommitmentType = "static_remote_key"
+	assert.Equal(t, lightning.CommitmentTypeStaticRemoteKey, CommitmentType(nil, node))
+	assert.Equal(t, lightning.CommitmentTypeTaproot, CommitmentType(&Token{TaprootChannels: &taproot}, node))
+}
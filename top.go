@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	clearScreen = "\033[H\033[2J"
+	colorRed    = "\033[31m"
+	colorReset  = "\033[0m"
+)
+
+// runTop continuously displays the dashboard of the admin api in the
+// terminal, for operators monitoring lspd over ssh.
+func runTop(args []string) {
+	flags := flag.NewFlagSet("top", flag.ExitOnError)
+	address := flags.String("admin-address", os.Getenv("ADMIN_LISTEN_ADDRESS"), "host:port or unix:<path> of the lspd admin api")
+	token := flags.String("admin-token", os.Getenv("ADMIN_TOKEN"), "token of the lspd admin api")
+	interval := flags.Duration("interval", 2*time.Second, "refresh interval")
+	htlcs := flags.Int("htlcs", 10, "number of recent htlcs and failures to show per node")
+	flags.Parse(args)
+
+	if *address == "" {
+		fmt.Fprintln(os.Stderr, "top: set --admin-address or ADMIN_LISTEN_ADDRESS")
+		os.Exit(1)
+	}
+
+	client := newAdminClient(*address, *token, *interval)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		var b bytes.Buffer
+		d, err := fetchDashboard(client)
+		if err != nil {
+			fmt.Fprintf(&b, "%slspd top - %s\n\n", clearScreen, *address)
+			fmt.Fprintf(&b, "%sfailed to get dashboard: %v%s\n", colorRed, err, colorReset)
+		} else {
+			renderDashboard(&b, *address, d, *htlcs)
+		}
+		os.Stdout.Write(b.Bytes())
+
+		select {
+		case <-c:
+			fmt.Println()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func fetchDashboard(client *adminClient) (*dashboard, error) {
+	resp, err := client.Get("/dashboard")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var d dashboard
+	err = json.NewDecoder(resp.Body).Decode(&d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode dashboard: %w", err)
+	}
+
+	return &d, nil
+}
+
+func renderDashboard(b *bytes.Buffer, address string, d *dashboard, htlcs int) {
+	fmt.Fprintf(b, "%slspd top - %s - %s\n\n", clearScreen, address, d.GeneratedAt.Local().Format(time.RFC1123))
+
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tSTREAM\tOPENING\tUNCONFIRMED\tFWD TODAY\tVOLUME (SAT)\tFEES (SAT)\tAVAILABLE (SAT)\tOPENS LEFT\tBREAKER")
+	for _, n := range d.Nodes {
+		stream := "up"
+		if !n.InterceptorConnected {
+			stream = "DOWN"
+		}
+
+		count, volume, fees := "-", "-", "-"
+		if n.Today != nil {
+			count = fmt.Sprint(n.Today.Count)
+			volume = fmt.Sprint(n.Today.VolumeMsat / 1000)
+			fees = fmt.Sprint(n.Today.FeesMsat / 1000)
+		}
+
+		available, opens := "-", "-"
+		if n.Wallet != nil && n.Wallet.Liquidity != nil {
+			available = fmt.Sprint(n.Wallet.Liquidity.AvailableSat)
+			opens = fmt.Sprint(n.Wallet.AffordableOpens)
+		}
+
+		breaker := "-"
+		if n.CircuitBreaker != nil {
+			breaker = "closed"
+			if n.CircuitBreaker.Open {
+				breaker = "OPEN"
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			n.Node, stream, n.PendingOpens.InProgress, n.PendingOpens.Unconfirmed,
+			count, volume, fees, available, opens, breaker)
+	}
+	w.Flush()
+
+	fmt.Fprintf(b, "\nALERTS\n")
+	if len(d.Alerts) == 0 {
+		fmt.Fprintln(b, "none")
+	}
+	for _, a := range d.Alerts {
+		fmt.Fprintf(b, "%s%s: %s%s\n", colorRed, a.Node, a.Message, colorReset)
+	}
+
+	for _, n := range d.Nodes {
+		var failures []string
+		fmt.Fprintf(b, "\nRECENT HTLCS %s\n", n.Node)
+		w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tPAYMENT HASH\tSCID\tAMOUNT (MSAT)\tACTION\tDURATION")
+		for j, h := range n.RecentHtlcs {
+			line := fmt.Sprintf("%s\t%s\t%s\t%d\t%s\t%.2fs",
+				h.Time.Local().Format("15:04:05"), shorten(h.PaymentHash), h.Scid,
+				h.AmountMsat, h.Action, h.Duration)
+			if j < htlcs {
+				fmt.Fprintln(w, line)
+			}
+			if h.FailureCode != "" && len(failures) < htlcs {
+				failures = append(failures, line+"\t"+h.FailureCode)
+			}
+		}
+		w.Flush()
+
+		fmt.Fprintf(b, "\nRECENT FAILURES %s\n", n.Node)
+		w = tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tPAYMENT HASH\tSCID\tAMOUNT (MSAT)\tACTION\tDURATION\tFAILURE")
+		for _, f := range failures {
+			fmt.Fprintln(w, f)
+		}
+		w.Flush()
+	}
+}
+
+// shorten abbreviates a hex string for display.
+func shorten(s string) string {
+	if len(s) <= 16 {
+		return s
+	}
+
+	return s[:8] + ".." + s[len(s)-8:]
+}
@@ -0,0 +1,40 @@
+package main
+
+import "runtime/debug"
+
+// version is set at build time with -ldflags "-X main.version=<version>".
+var version string
+
+// buildVersion returns the version of the binary: the version set at build
+// time, or else the vcs revision it was built from.
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	revision := ""
+	modified := false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+
+	if revision == "" {
+		return "unknown"
+	}
+
+	if modified {
+		return revision + "-dirty"
+	}
+
+	return revision
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/breez/lspd/config"
+	"github.com/breez/lspd/interceptor"
+	"github.com/breez/lspd/leader"
+	"github.com/breez/lspd/systemd"
+)
+
+// runWatchdog pings the systemd watchdog as long as all interceptors have an
+// open htlc stream with their node. If an interceptor stays disconnected for
+// longer than WatchdogSec, systemd restarts lspd. Interceptors of nodes led
+// by another replica don't run, and are not checked.
+func runWatchdog(
+	nodes []*config.NodeConfig,
+	interceptors []interceptor.HtlcInterceptor,
+	electors []*leader.Elector,
+	stopped <-chan struct{},
+) {
+	interval := systemd.WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+
+	log.Printf("systemd watchdog enabled with interval %v.", interval)
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopped:
+			return
+		case <-ticker.C:
+		}
+
+		alive := true
+		for i, interceptor := range interceptors {
+			if electors[i] != nil && !electors[i].Leading() {
+				continue
+			}
+			if !interceptor.Alive() {
+				log.Printf("watchdog: htlc interceptor of node %s is not connected.", nodes[i].Label())
+				alive = false
+			}
+		}
+
+		if !alive {
+			continue
+		}
+
+		err := systemd.Notify(systemd.Watchdog)
+		if err != nil {
+			log.Printf("watchdog: failed to notify systemd: %v", err)
+		}
+	}
+}